@@ -0,0 +1,145 @@
+// Command kube-mcp-tools serves the example tools from one binary, so a
+// cluster needs one image, and can run one Deployment, rather than one of
+// each per tool:
+//
+//	kube-mcp-tools list              list the tools
+//	kube-mcp-tools serve explain     serve kubectl-explain as its own binary would
+//	kube-mcp-tools serve time dns    serve time-tool and dns-tool on one port
+//	kube-mcp-tools serve all         serve every tool
+//
+// Served together, each tool is mounted under its name, so kubectl-explain
+// answers on /explain/v1/explain and /explain/v1/mcp, while /livez, /readyz,
+// /healthz/verbose and /metrics cover the whole process. Settings are read
+// as by the tools' own binaries, from flags, the environment and the config
+// file, and apply to every tool served. Write bare flags before the
+// subcommand as --name=true, e.g. --read-only=true serve all.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	cranetool "crane-tool"
+	helmtool "helm-tool"
+	kubeinfotool "kube-info-tool"
+	kustomizetool "kustomize-tool"
+	manifestvalidate "manifest-validate"
+	querytool "query-tool"
+	timetool "time-tool"
+	weathertool "weather-tool"
+
+	hashtool "github.com/atippey/kube-mcp/examples/hash-tool"
+	kubectlexplain "github.com/atippey/kube-mcp/examples/kubectl-explain"
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	dnstool "github.com/mcp-k8s/dns-tool"
+)
+
+// tool is a tool the binary can serve.
+type tool struct {
+	name        string // the subcommand operand and path prefix
+	description string
+	new         func() (*toolserver.Server, error)
+}
+
+var tools = []tool{
+	{"crane", "list the images running in the cluster and inspect images", cranetool.New},
+	{"dns", "DNS lookups and checks of Service records", dnstool.New},
+	{"explain", "documentation of Kubernetes resource fields", kubectlexplain.New},
+	{"hash", "hashes, digests, encodings and JWTs", hashtool.New},
+	{"helm", "Helm releases: their values, manifests, notes and history", helmtool.New},
+	{"kube-info", "namespaces, pods, logs, quotas and network policies", kubeinfotool.New},
+	{"kustomize", "kustomize builds, overlays and patch previews", kustomizetool.New},
+	{"query", "JSONPath and jq queries of documents and cluster objects", querytool.New},
+	{"time", "time formatting, conversion and CronJob previews", timetool.New},
+	{"validate", "validation of manifests against the cluster's schema, with dry runs", manifestvalidate.New},
+	{"weather", "current and historical weather", weathertool.New},
+}
+
+const usage = `usage: kube-mcp-tools list
+       kube-mcp-tools serve all | TOOL...`
+
+func main() {
+	if err := run(config.Args()); err != nil {
+		slog.Error("kube-mcp-tools failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "list":
+		for _, t := range tools {
+			fmt.Printf("%-10s %s\n", t.name, t.description)
+		}
+		return nil
+	case "serve":
+		selected, err := selectTools(args[1:])
+		if err != nil {
+			return err
+		}
+		s, err := server(selected)
+		if err != nil {
+			return err
+		}
+		return s.Run()
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+// selectTools returns the named tools, in the order of tools, or every tool
+// for "all".
+func selectTools(names []string) ([]tool, error) {
+	if len(names) == 0 {
+		return nil, errors.New(usage)
+	}
+	if slices.Equal(names, []string{"all"}) {
+		return tools, nil
+	}
+	var selected []tool
+	for _, t := range tools {
+		if slices.Contains(names, t.name) {
+			selected = append(selected, t)
+		}
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(tools, func(t tool) bool { return t.name == name }) {
+			return nil, fmt.Errorf("unknown tool %q: use all or one of %s", name, toolNames())
+		}
+	}
+	return selected, nil
+}
+
+func toolNames() string {
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// server returns the server for the selected tools: a single tool's own
+// server, exactly as its binary serves it, or a server with each tool
+// mounted under its name.
+func server(selected []tool) (*toolserver.Server, error) {
+	if len(selected) == 1 {
+		return selected[0].new()
+	}
+	s := toolserver.New("kube-mcp-tools")
+	for _, t := range selected {
+		ts, err := t.new()
+		if err != nil {
+			return nil, fmt.Errorf("starting %s: %w", t.name, err)
+		}
+		s.Mount(t.name, ts)
+	}
+	return s, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSelectTools(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+		ok   bool
+	}{
+		{[]string{"explain"}, []string{"explain"}, true},
+		{[]string{"time", "dns", "time"}, []string{"dns", "time"}, true},
+		{[]string{"all"}, []string{"crane", "dns", "explain", "hash", "helm", "kube-info", "kustomize", "query", "time", "validate", "weather"}, true},
+		{[]string{"time", "tides"}, nil, false},
+		{nil, nil, false},
+	}
+	for _, tt := range tests {
+		selected, err := selectTools(tt.args)
+		var got []string
+		for _, tool := range selected {
+			got = append(got, tool.name)
+		}
+		if (err == nil) != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("selectTools(%q) = %q, %v; want %q, ok %t", tt.args, got, err, tt.want, tt.ok)
+		}
+	}
+}
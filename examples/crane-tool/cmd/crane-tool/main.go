@@ -0,0 +1,22 @@
+// Command crane-tool serves crane-tool on its own. kube-mcp-tools serves it together
+// with the other example tools.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	cranetool "crane-tool"
+)
+
+func main() {
+	s, err := cranetool.New()
+	if err != nil {
+		slog.Error("starting crane-tool", "err", err)
+		os.Exit(1)
+	}
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
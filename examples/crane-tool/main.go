@@ -1,22 +1,70 @@
-package main
+// Package cranetool implements crane-tool, which lists the images running
+// in a cluster and inspects images in their registries.
+package cranetool
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
 	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
-var clientset *kubernetes.Clientset
+// kubeClient is loaded on first use; without a cluster, only /inspect works.
+var kubeClient = kube.New("crane-tool")
+
+// registryTransport is used for registry requests so they are counted in
+// /metrics and go through the egress proxy, trusting the CA bundle.
+var registryTransport = toolserver.InstrumentTransport("registry", toolserver.Outbound(remote.DefaultTransport))
+
+// registryPool bounds the image inspections running at once, each of which
+// holds manifests and config blobs in memory.
+var registryPool = toolserver.NewPool("registry", 4, 32)
+
+// registryError reports a failed registry request, with a formatted message
+// followed by err: INVALID_ARGUMENT for a malformed image reference,
+// NOT_FOUND for a missing repository, tag or digest, FORBIDDEN when the
+// registry refuses the tool's credentials, and otherwise an upstream error,
+// retryable if the registry is throttling.
+func registryError(err error, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	var badName *name.ErrBadName
+	if errors.As(err, &badName) {
+		return toolserver.NewError(toolserver.CodeInvalidArgument, "%s: %v", msg, err)
+	}
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		switch terr.StatusCode {
+		case http.StatusNotFound:
+			return toolserver.NewError(toolserver.CodeNotFound, "%s: %v", msg, err)
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return toolserver.NewError(toolserver.CodeForbidden, "%s: %v", msg, err)
+		case http.StatusTooManyRequests:
+			e := toolserver.UpstreamError(err, format, args...)
+			e.Retryable = true
+			return e
+		}
+	}
+	return toolserver.UpstreamError(err, format, args...)
+}
+
+// inspectCacheTTL is how long inspections are reused: short, since tags
+// such as latest move.
+const inspectCacheTTL = time.Minute
+
+// staleMaxAge is how long answers are kept to give, marked stale, while the
+// cluster or a registry cannot be reached.
+const staleMaxAge = 15 * time.Minute
 
 // --- /images types ---
 
@@ -33,9 +81,9 @@ type ImageInfo struct {
 }
 
 type ImagesResponse struct {
-	Images []ImageInfo `json:"images"`
-	Count  int         `json:"count"`
-	Error  string      `json:"error,omitempty"`
+	Images       []ImageInfo `json:"images"`
+	Count        int         `json:"count"`
+	RetriedTimes int         `json:"retriedTimes,omitempty"` // API calls retried after transient errors
 }
 
 // --- /inspect types ---
@@ -44,6 +92,18 @@ type InspectRequest struct {
 	Image string `json:"image"`
 }
 
+// maxInspectBatch is the most images an /inspect-batch call inspects;
+// registryPool's queue has room for them all.
+const maxInspectBatch = 20
+
+type InspectBatchRequest struct {
+	Images []string `json:"images"`
+}
+
+// InspectBatchResponse has an inspection per image, null for the images
+// that failed, which errors lists.
+type InspectBatchResponse = toolserver.Batch[*InspectResponse]
+
 type LayerInfo struct {
 	Digest    string `json:"digest"`
 	Size      int64  `json:"size"`
@@ -73,68 +133,33 @@ type InspectResponse struct {
 	Layers    []LayerInfo  `json:"layers"`
 	TotalSize int64        `json:"totalSize"`
 	Created   string       `json:"created"`
-	Error     string       `json:"error,omitempty"`
-}
-
-func main() {
-	// Initialize Kubernetes client
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		kubeconfig := os.Getenv("KUBECONFIG")
-		if kubeconfig == "" {
-			home, _ := os.UserHomeDir()
-			kubeconfig = home + "/.kube/config"
-		}
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
-		if err != nil {
-			log.Printf("Warning: could not load kubeconfig: %v (cluster /images endpoint will not work)", err)
-		}
-	}
-
-	if config != nil {
-		clientset, err = kubernetes.NewForConfig(config)
-		if err != nil {
-			log.Printf("Warning: could not create kubernetes client: %v", err)
-		}
-	}
-
-	http.HandleFunc("/health", handleHealth)
-	http.HandleFunc("/images", handleImages)
-	http.HandleFunc("/inspect", handleInspect)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	log.Printf("Starting crane-tool server on :%s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
-	}
 }
 
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+// New returns the crane-tool server. It connects to the cluster from inside
+// it, or else with the kubeconfig, on first use; without either, only
+// /inspect works.
+func New() (*toolserver.Server, error) {
+	s := toolserver.New("crane-tool")
+	s.AddDiagnostics("kubernetes", kubeClient.Diagnostics)
+	s.AddWarmup("kubernetes", kubeClient.Warm)
+	s.CheckRBAC(kubeClient.Allowed)
+	s.AddReadinessCheck("registry", toolserver.ResolveCheck(name.DefaultRegistry))
+	toolserver.Register(s, "/images", listImages,
+		toolserver.Name("crane-images"), toolserver.Describe("List container images running in the Kubernetes cluster."),
+		toolserver.ServeStale(staleMaxAge), toolserver.RBAC("list pods"))
+	toolserver.Register(s, "/inspect", inspectImage,
+		toolserver.Name("crane-inspect"), toolserver.Describe("Inspect a container image from its registry."),
+		toolserver.Cache(inspectCacheTTL), toolserver.ServeStale(staleMaxAge))
+	toolserver.Register(s, "/inspect-batch", inspectBatch,
+		toolserver.Name("crane-inspect-batch"), toolserver.Describe("Inspect several container images at once, reporting the ones that fail separately."))
+
+	return s, nil
 }
 
-func handleImages(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
-		return
-	}
-
-	if clientset == nil {
-		json.NewEncoder(w).Encode(ImagesResponse{Error: "kubernetes client not available"})
-		return
-	}
-
-	var req ImagesRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(ImagesResponse{Error: "invalid request body"})
-		return
+func listImages(ctx context.Context, req ImagesRequest) (ImagesResponse, error) {
+	clientset, err := kubeClient.Clientset()
+	if err != nil {
+		return ImagesResponse{}, err
 	}
 
 	namespace := req.Namespace
@@ -142,10 +167,12 @@ func handleImages(w http.ResponseWriter, r *http.Request) {
 		namespace = ""
 	}
 
-	pods, err := clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
+	ctx = kube.CountRetries(ctx)
+	pods, err := kube.Retry(ctx, func(ctx context.Context) (*corev1.PodList, error) {
+		return clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	})
 	if err != nil {
-		json.NewEncoder(w).Encode(ImagesResponse{Error: fmt.Sprintf("failed to list pods: %v", err)})
-		return
+		return ImagesResponse{}, kube.Errorf(err, "failed to list pods")
 	}
 
 	if req.Format == "pods" {
@@ -164,13 +191,20 @@ func handleImages(w http.ResponseWriter, r *http.Request) {
 		if images == nil {
 			images = []ImageInfo{}
 		}
-		json.NewEncoder(w).Encode(ImagesResponse{Images: images, Count: len(images)})
-		return
+		return ImagesResponse{Images: images, Count: len(images), RetriedTimes: kube.Retried(ctx)}, nil
 	}
 
 	// Default: unique format - deduplicate by image reference
+	images := uniqueImages(pods.Items)
+	return ImagesResponse{Images: images, Count: len(images), RetriedTimes: kube.Retried(ctx)}, nil
+}
+
+// uniqueImages returns one entry per image reference across pods, with the
+// pods that run it. This is the hot path of /images on large clusters; see
+// BenchmarkUniqueImages.
+func uniqueImages(pods []corev1.Pod) []ImageInfo {
 	imageMap := make(map[string]*ImageInfo)
-	for _, pod := range pods.Items {
+	for _, pod := range pods {
 		for _, container := range pod.Spec.Containers {
 			key := container.Image
 			if existing, ok := imageMap[key]; ok {
@@ -203,37 +237,41 @@ func handleImages(w http.ResponseWriter, r *http.Request) {
 	if images == nil {
 		images = []ImageInfo{}
 	}
-
-	json.NewEncoder(w).Encode(ImagesResponse{Images: images, Count: len(images)})
+	return images
 }
 
-func handleInspect(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
-		return
+// inspectBatch inspects the images concurrently, as far as registryPool
+// allows.
+func inspectBatch(ctx context.Context, req InspectBatchRequest) (InspectBatchResponse, error) {
+	if len(req.Images) == 0 {
+		return InspectBatchResponse{}, toolserver.BadRequest("images is required")
 	}
-
-	var req InspectRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(InspectResponse{Error: "invalid request body"})
-		return
+	if len(req.Images) > maxInspectBatch {
+		return InspectBatchResponse{}, toolserver.BadRequest("at most %d images per request", maxInspectBatch)
 	}
+	return toolserver.RunBatch(ctx, len(req.Images), nil, func(ctx context.Context, i int) (*InspectResponse, error) {
+		resp, err := inspectImage(ctx, InspectRequest{Image: req.Images[i]})
+		if err != nil {
+			return nil, err
+		}
+		return &resp, nil
+	})
+}
 
+func inspectImage(ctx context.Context, req InspectRequest) (InspectResponse, error) {
 	if req.Image == "" {
-		json.NewEncoder(w).Encode(InspectResponse{Error: "image is required"})
-		return
+		return InspectResponse{}, toolserver.BadRequest("image is required")
+	}
+	release, err := registryPool.Acquire(ctx)
+	if err != nil {
+		return InspectResponse{}, err
 	}
+	defer release()
 
 	// Get the image descriptor
-	desc, err := crane.Get(req.Image)
+	desc, err := crane.Get(req.Image, crane.WithContext(ctx), crane.WithTransport(registryTransport))
 	if err != nil {
-		json.NewEncoder(w).Encode(InspectResponse{
-			Image: req.Image,
-			Error: fmt.Sprintf("failed to fetch image: %v", err),
-		})
-		return
+		return InspectResponse{}, registryError(err, "failed to fetch image")
 	}
 
 	resp := InspectResponse{
@@ -246,8 +284,7 @@ func handleInspect(w http.ResponseWriter, r *http.Request) {
 	img, err := desc.Image()
 	if err != nil {
 		// Might be an index, return what we have
-		json.NewEncoder(w).Encode(resp)
-		return
+		return resp, nil
 	}
 
 	// Get manifest
@@ -315,5 +352,5 @@ func handleInspect(w http.ResponseWriter, r *http.Request) {
 		resp.Digest = strings.TrimPrefix(resp.Digest, "sha256:")
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	return resp, nil
 }
@@ -0,0 +1,122 @@
+package cranetool
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestServer(t *testing.T) *tooltest.Server {
+	pod := func(name, image string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: name},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+		}
+	}
+	tooltest.FakeKube(t, kubeClient, pod("frontend", "nginx:1.27"), pod("backend", "ghcr.io/acme/api:v2"))
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tooltest.NewServer(t, s)
+}
+
+func TestHandlers(t *testing.T) {
+	registry := tooltest.Registry(t)
+	img, err := mutate.ConfigFile(empty.Image, &v1.ConfigFile{
+		OS:           "linux",
+		Architecture: "arm64",
+		Config:       v1.Config{Cmd: []string{"/server"}, User: "65532", Labels: map[string]string{"team": "web"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := tooltest.PushImage(t, registry+"/acme/api:v2", img)
+	tooltest.PushImage(t, registry+"/acme/random:latest", nil)
+
+	tooltest.Run(t, newTestServer(t), []tooltest.Case{
+		{Name: "images by pod", Path: "/images", Body: `{"namespace":"web","format":"pods"}`, Golden: "images-pods"},
+		{Name: "unique images", Path: "/images", Body: `{}`, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out ImagesResponse
+			resp.Decode(&out)
+			if out.Count != 2 {
+				t.Errorf("count = %d, want 2", out.Count)
+			}
+		}},
+		{Name: "inspect", Path: "/inspect", Body: InspectRequest{Image: registry + "/acme/api:v2"}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out InspectResponse
+			resp.Decode(&out)
+			if out.Digest != digest.String() || out.Platform.Architecture != "arm64" || out.Config.User != "65532" || out.Config.Labels["team"] != "web" {
+				t.Errorf("response = %+v", out)
+			}
+		}},
+		{Name: "inspect layers", Path: "/inspect", Body: InspectRequest{Image: registry + "/acme/random:latest"}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out InspectResponse
+			resp.Decode(&out)
+			if len(out.Layers) != 1 || out.TotalSize != out.Layers[0].Size {
+				t.Errorf("layers = %+v, total %d", out.Layers, out.TotalSize)
+			}
+		}},
+		{Name: "inspect without image", Path: "/inspect", Body: `{}`, Code: toolserver.CodeInvalidArgument},
+		{Name: "missing tag", Path: "/inspect", Body: InspectRequest{Image: registry + "/acme/api:v3"}, Code: toolserver.CodeNotFound},
+		{Name: "malformed reference", Path: "/inspect", Body: `{"image":"UPPER/case"}`, Code: toolserver.CodeInvalidArgument},
+		{Name: "inspect batch", Path: "/inspect-batch", Body: InspectBatchRequest{Images: []string{registry + "/acme/api:v2", registry + "/acme/api:v3"}}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out InspectBatchResponse
+			resp.Decode(&out)
+			if len(out.Results) != 2 || out.Results[0] == nil || out.Results[0].Digest != digest.String() || out.Results[1] != nil {
+				t.Errorf("results = %+v", out.Results)
+			}
+			if !out.Partial || len(out.Errors) != 1 || out.Errors[0].Index != 1 || out.Errors[0].Code != toolserver.CodeNotFound {
+				t.Errorf("errors = %+v, partial %v; want the missing tag", out.Errors, out.Partial)
+			}
+		}},
+		{Name: "inspect batch all failing", Path: "/inspect-batch", Body: InspectBatchRequest{Images: []string{registry + "/acme/api:v3", registry + "/acme/api:v4"}}, Code: toolserver.CodeNotFound},
+		{Name: "inspect batch without images", Path: "/inspect-batch", Body: `{}`, Code: toolserver.CodeInvalidArgument},
+	})
+}
+
+// benchmarkPods returns n pods in deployments of 10 replicas, each with its
+// app's image and a sidecar image shared by every pod, as in a cluster with
+// a service mesh.
+func benchmarkPods(n int) []corev1.Pod {
+	pods := make([]corev1.Pod, n)
+	for i := range pods {
+		app := i / 10
+		pods[i] = corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: fmt.Sprintf("team-%d", app%50), Name: fmt.Sprintf("app-%d-%d", app, i%10)},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app", Image: fmt.Sprintf("registry.example.com/app-%d:v1", app)},
+				{Name: "proxy", Image: "registry.example.com/mesh/proxy:1.24"},
+			}},
+		}
+	}
+	return pods
+}
+
+func BenchmarkUniqueImages(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		pods := benchmarkPods(n)
+		b.Run(fmt.Sprintf("pods=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				uniqueImages(pods)
+			}
+		})
+	}
+}
+
+// TestUniqueImagesAllocs guards against regressions that BenchmarkUniqueImages
+// would show, with headroom over the baseline in docs/BENCHMARKS.md.
+func TestUniqueImagesAllocs(t *testing.T) {
+	pods := benchmarkPods(1000)
+	if allocs := testing.AllocsPerRun(10, func() { uniqueImages(pods) }); allocs > 800 {
+		t.Errorf("uniqueImages of 1000 pods: %.0f allocations, budget 800", allocs)
+	}
+}
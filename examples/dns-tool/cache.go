@@ -0,0 +1,198 @@
+package dnstool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/miekg/dns"
+)
+
+// defaultCacheEntries bounds the lookup cache; DNS_CACHE_ENTRIES=0 disables it.
+const defaultCacheEntries = 10000
+
+type cacheKey struct {
+	name      string
+	qtype     uint16
+	server    string
+	transport string
+	dnssec    bool
+}
+
+type cacheEntry struct {
+	result  queryResult
+	stored  time.Time
+	expires time.Time
+}
+
+// dnsCache holds replies until their TTL runs out. Positive answers live for
+// the lowest answer TTL; NXDOMAIN and NODATA for the SOA negative TTL
+// (RFC 2308). Other failures are never cached.
+type dnsCache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+	max     int
+	hits    uint64
+	misses  uint64
+}
+
+type CacheStats struct {
+	Enabled bool   `json:"enabled"`
+	Entries int    `json:"entries"`
+	Max     int    `json:"max"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+var lookupCache = newDNSCache(cacheSizeFromConfig())
+
+func cacheSizeFromConfig() int {
+	n := config.Int("DNS_CACHE_ENTRIES", defaultCacheEntries)
+	if n < 0 {
+		slog.Warn("invalid DNS_CACHE_ENTRIES", "value", n, "using", defaultCacheEntries)
+		return defaultCacheEntries
+	}
+	return n
+}
+
+func newDNSCache(size int) *dnsCache {
+	return &dnsCache{entries: make(map[cacheKey]cacheEntry), max: size}
+}
+
+// cachedQuery answers from the cache when possible and otherwise sends the
+// query and stores the reply. The bool reports a cache hit.
+func (c *dnsCache) cachedQuery(ctx context.Context, name string, qtype uint16, server string, opts queryOptions, bypass bool) (*queryResult, bool, error) {
+	key := cacheKey{name: dns.CanonicalName(name), qtype: qtype, server: server, transport: opts.Transport, dnssec: opts.DNSSEC}
+
+	if c.max > 0 && !bypass {
+		if result, ok := c.get(key); ok {
+			if result.Msg.Rcode != dns.RcodeSuccess {
+				return result, true, fmt.Errorf("lookup %s: %s", name, dns.RcodeToString[result.Msg.Rcode])
+			}
+			return result, true, nil
+		}
+	}
+
+	result, err := query(ctx, name, qtype, server, opts)
+	if c.max > 0 {
+		c.put(key, result)
+	}
+	return result, false, err
+}
+
+func (c *dnsCache) get(key cacheKey) (*queryResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	now := time.Now()
+	if ok && !now.Before(entry.expires) {
+		delete(c.entries, key)
+		cacheEntries.Set(float64(len(c.entries)))
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		cacheRequests.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+	c.hits++
+	cacheRequests.WithLabelValues("hit").Inc()
+
+	// Hand back a copy with TTLs aged by the time spent in the cache, as a
+	// caching resolver would.
+	result := entry.result
+	result.Msg = entry.result.Msg.Copy()
+	age := uint32(now.Sub(entry.stored) / time.Second)
+	for _, section := range [][]dns.RR{result.Msg.Answer, result.Msg.Ns, result.Msg.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			if rr.Header().Ttl > age {
+				rr.Header().Ttl -= age
+			} else {
+				rr.Header().Ttl = 0
+			}
+		}
+	}
+	result.Attempts = 0
+	result.Duration = 0
+	result.RTT = 0
+	return &result, true
+}
+
+func (c *dnsCache) put(key cacheKey, result *queryResult) {
+	if result == nil || result.Msg == nil || (result.Truncated && result.Transport == "udp") {
+		return
+	}
+	ttl, ok := cacheTTL(result.Msg)
+	if !ok || ttl == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= c.max {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) >= c.max {
+		// Still full: drop an arbitrary entry rather than track recency.
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = cacheEntry{result: *result, stored: now, expires: now.Add(ttl)}
+	cacheEntries.Set(float64(len(c.entries)))
+}
+
+// cacheTTL returns how long msg may be cached and whether it may be at all.
+func cacheTTL(msg *dns.Msg) (time.Duration, bool) {
+	switch msg.Rcode {
+	case dns.RcodeSuccess:
+		if len(msg.Answer) > 0 {
+			lowest := msg.Answer[0].Header().Ttl
+			for _, rr := range msg.Answer[1:] {
+				lowest = minTTL(lowest, rr.Header().Ttl)
+			}
+			return time.Duration(lowest) * time.Second, true
+		}
+		fallthrough // NODATA
+	case dns.RcodeNameError:
+		for _, rr := range msg.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				return time.Duration(minTTL(soa.Hdr.Ttl, soa.Minttl)) * time.Second, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func minTTL(a, b uint32) uint32 {
+	if b < a {
+		return b
+	}
+	return a
+}
+
+func (c *dnsCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Enabled: c.max > 0, Entries: len(c.entries), Max: c.max, Hits: c.hits, Misses: c.misses}
+}
+
+func handleCacheStats(w http.ResponseWriter, r *http.Request) {
+	toolserver.WriteJSON(w, http.StatusOK, lookupCache.stats())
+}
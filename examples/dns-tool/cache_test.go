@@ -0,0 +1,96 @@
+package dnstool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestCacheAgesTTLs(t *testing.T) {
+	server, queries := zoneNameserver(t, testSOA("example.test."), testA("web.example.test.", "192.0.2.10"))
+	c := newDNSCache(10)
+	opts := defaultQueryOptions()
+
+	tests := []struct {
+		name    string
+		age     time.Duration
+		wantHit bool
+		wantTTL uint32
+	}{
+		{name: "fresh", wantHit: true, wantTTL: 300},
+		{name: "aged", age: 100 * time.Second, wantHit: true, wantTTL: 200},
+		{name: "expired", age: 300 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clear(c.entries)
+			if _, cached, err := c.cachedQuery(context.Background(), "web.example.test.", dns.TypeA, server, opts, false); err != nil || cached {
+				t.Fatalf("first query: cached %v, %v", cached, err)
+			}
+			// Back-date the entry, as if it had been stored age ago.
+			for k, e := range c.entries {
+				e.stored, e.expires = e.stored.Add(-tt.age), e.expires.Add(-tt.age)
+				c.entries[k] = e
+			}
+			before := queries.Load()
+			result, cached, err := c.cachedQuery(context.Background(), "web.example.test.", dns.TypeA, server, opts, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cached != tt.wantHit || (queries.Load() == before) != tt.wantHit {
+				t.Fatalf("cached %v after %d queries, want a hit: %v", cached, queries.Load()-before, tt.wantHit)
+			}
+			if ttl := result.Msg.Answer[0].Header().Ttl; tt.wantHit && ttl != tt.wantTTL {
+				t.Errorf("TTL = %d, want %d", ttl, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestCacheNegativeAnswers(t *testing.T) {
+	server, queries := zoneNameserver(t, testSOA("example.test."), testA("web.example.test.", "192.0.2.10"))
+
+	tests := []struct {
+		name      string
+		qname     string
+		qtype     uint16
+		wantRcode int
+	}{
+		{name: "NXDOMAIN", qname: "gone.example.test.", qtype: dns.TypeA, wantRcode: dns.RcodeNameError},
+		{name: "NODATA", qname: "web.example.test.", qtype: dns.TypeAAAA, wantRcode: dns.RcodeSuccess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newDNSCache(10)
+			c.cachedQuery(context.Background(), tt.qname, tt.qtype, server, defaultQueryOptions(), false)
+			entry, ok := c.entries[cacheKey{name: tt.qname, qtype: tt.qtype, server: server, transport: "udp"}]
+			if !ok {
+				t.Fatalf("entries = %v, want the negative answer", c.entries)
+			}
+			// The negative TTL is min(SOA TTL, SOA MINIMUM).
+			if ttl := entry.expires.Sub(entry.stored); ttl != 60*time.Second {
+				t.Errorf("cached for %v, want 1m0s", ttl)
+			}
+
+			before := queries.Load()
+			result, cached, _ := c.cachedQuery(context.Background(), tt.qname, tt.qtype, server, defaultQueryOptions(), false)
+			if !cached || queries.Load() != before {
+				t.Fatalf("cached %v after %d queries, want a hit", cached, queries.Load()-before)
+			}
+			if result.Msg.Rcode != tt.wantRcode || negativeAnswer(result.Msg, tt.qtype) == nil {
+				t.Errorf("rcode %s, want a negative %s", dns.RcodeToString[result.Msg.Rcode], dns.RcodeToString[tt.wantRcode])
+			}
+		})
+	}
+}
+
+func TestCacheSkipsNegativeAnswersWithoutSOA(t *testing.T) {
+	server, _ := zoneNameserver(t, nil)
+	c := newDNSCache(10)
+	c.cachedQuery(context.Background(), "gone.example.test.", dns.TypeA, server, defaultQueryOptions(), false)
+	if len(c.entries) != 0 {
+		t.Errorf("entries = %v, want none without an SOA to bound them", c.entries)
+	}
+}
@@ -0,0 +1,22 @@
+// Command dns-tool serves dns-tool on its own. kube-mcp-tools serves it together
+// with the other example tools.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	dnstool "github.com/mcp-k8s/dns-tool"
+)
+
+func main() {
+	s, err := dnstool.New()
+	if err != nil {
+		slog.Error("starting dns-tool", "err", err)
+		os.Exit(1)
+	}
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,157 @@
+package dnstool
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// defaultCompareResolvers are used when a compare request names none. The
+// empty entry is the pod's own resolver, i.e. cluster DNS.
+var defaultCompareResolvers = []string{"", "8.8.8.8", "1.1.1.1", "9.9.9.9"}
+
+// lookupPool bounds the nameserver queries that comparisons, which take any
+// number of nameservers, have in flight across calls.
+var lookupPool = toolserver.NewPool("dns-lookup", 16, 256)
+
+type CompareRequest struct {
+	Hostname    string   `json:"hostname"`
+	Type        string   `json:"type"`
+	Nameservers []string `json:"nameservers"` // "" means the system resolver
+}
+
+type ResolverResult struct {
+	Nameserver string   `json:"nameserver"`
+	Records    []string `json:"records"`
+	Rcode      string   `json:"rcode,omitempty"`
+	TTL        int      `json:"ttl"`
+	LatencyMs  float64  `json:"latencyMs"`
+	Differs    bool     `json:"differs"` // answer set differs from the most common one
+	Error      string   `json:"error,omitempty"`
+}
+
+type CompareResponse struct {
+	Hostname   string           `json:"hostname"`
+	Type       string           `json:"type"`
+	Consistent bool             `json:"consistent"`
+	Consensus  []string         `json:"consensus"` // most common answer set
+	Fastest    string           `json:"fastest,omitempty"`
+	Slowest    string           `json:"slowest,omitempty"`
+	Results    []ResolverResult `json:"results"`
+}
+
+func compare(ctx context.Context, req CompareRequest) (CompareResponse, error) {
+	if req.Hostname == "" {
+		return CompareResponse{}, toolserver.BadRequest("hostname is required")
+	}
+	if req.Type == "" {
+		req.Type = "A"
+	}
+	if _, ok := supportedTypes[req.Type]; !ok {
+		return CompareResponse{}, toolserver.BadRequest("unsupported record type: %s", req.Type)
+	}
+
+	nameservers := req.Nameservers
+	if len(nameservers) == 0 {
+		nameservers = defaultCompareResolvers
+	}
+
+	return compareResolvers(ctx, req.Hostname, req.Type, nameservers), nil
+}
+
+// compareResolvers runs the lookup against every nameserver in parallel,
+// as far as lookupPool allows. Nameservers it has no room for fail.
+func compareResolvers(ctx context.Context, hostname, recordType string, nameservers []string) CompareResponse {
+	results := make([]ResolverResult, len(nameservers))
+	var wg sync.WaitGroup
+	for i, ns := range nameservers {
+		wg.Add(1)
+		go func(i int, ns string) {
+			defer wg.Done()
+			release, err := lookupPool.Acquire(ctx)
+			if err != nil {
+				results[i] = ResolverResult{Nameserver: ns, Error: err.Error()}
+				return
+			}
+			defer release()
+			start := time.Now()
+			lr, err := performLookup(ctx, LookupRequest{Hostname: hostname, Type: recordType, Nameserver: ns})
+			records := slices.Clone(lr.Records)
+			slices.Sort(records)
+			server := lr.Nameserver
+			if server == "" {
+				server = ns
+			}
+			results[i] = ResolverResult{
+				Nameserver: server,
+				Records:    records,
+				Rcode:      lr.Rcode,
+				TTL:        lr.TTL,
+				LatencyMs:  float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, ns)
+	}
+	wg.Wait()
+
+	return summarizeComparison(hostname, recordType, results)
+}
+
+// summarizeComparison finds the most common answer set among successful
+// results and marks the resolvers that disagree with it. Failed lookups
+// count as disagreeing.
+func summarizeComparison(hostname, recordType string, results []ResolverResult) CompareResponse {
+	resp := CompareResponse{
+		Hostname:  hostname,
+		Type:      recordType,
+		Consensus: []string{},
+		Results:   results,
+	}
+
+	counts := make(map[string]int)
+	var consensusKey string
+	for _, res := range results {
+		if res.Error != "" {
+			continue
+		}
+		key := strings.Join(res.Records, "\n")
+		counts[key]++
+		if counts[key] > counts[consensusKey] || (counts[key] == counts[consensusKey] && key < consensusKey) {
+			consensusKey = key
+		}
+	}
+	if len(counts) > 0 && consensusKey != "" {
+		resp.Consensus = strings.Split(consensusKey, "\n")
+	}
+
+	var fastest, slowest *ResolverResult
+	resp.Consistent = true
+	for i := range results {
+		res := &results[i]
+		if res.Error != "" || strings.Join(res.Records, "\n") != consensusKey {
+			res.Differs = true
+			resp.Consistent = false
+		}
+		if res.Error != "" {
+			continue
+		}
+		if fastest == nil || res.LatencyMs < fastest.LatencyMs {
+			fastest = res
+		}
+		if slowest == nil || res.LatencyMs > slowest.LatencyMs {
+			slowest = res
+		}
+	}
+	if fastest != nil {
+		resp.Fastest = fastest.Nameserver
+		resp.Slowest = slowest.Nameserver
+	}
+
+	return resp
+}
@@ -0,0 +1,131 @@
+package dnstool
+
+import (
+	"crypto"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+	"github.com/miekg/dns"
+)
+
+// zoneKey is a zone's key-signing key, which signs all of its records.
+type zoneKey struct {
+	dnskey *dns.DNSKEY
+	signer crypto.Signer
+}
+
+func newZoneKey(t *testing.T, zone string) zoneKey {
+	t.Helper()
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     dns.ZONE | dns.SEP,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zoneKey{dnskey: key, signer: priv.(crypto.Signer)}
+}
+
+// sign returns the RRSIG of rrs, valid from inception to expiration.
+func (k zoneKey) sign(t *testing.T, inception, expiration time.Time, rrs ...dns.RR) *dns.RRSIG {
+	t.Helper()
+	sig := &dns.RRSIG{
+		Algorithm:  k.dnskey.Algorithm,
+		Inception:  uint32(inception.Unix()),
+		Expiration: uint32(expiration.Unix()),
+		KeyTag:     k.dnskey.KeyTag(),
+		SignerName: k.dnskey.Hdr.Name,
+	}
+	if err := sig.Sign(k.signer, rrs); err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func testA(name, ip string) *dns.A {
+	return &dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300}, A: net.ParseIP(ip)}
+}
+
+// signedNameserver serves a root zone signed with a key it makes the
+// validator's trust anchor, secure.test, delegated from it with a DS
+// record, and insecure.test, signed but without one. Its address is
+// returned.
+func signedNameserver(t *testing.T) string {
+	t.Helper()
+	now := time.Now()
+	from, until := now.Add(-time.Hour), now.Add(time.Hour)
+
+	root := newZoneKey(t, ".")
+	secure := newZoneKey(t, "secure.test.")
+	insecure := newZoneKey(t, "insecure.test.")
+	anchors := rootAnchors
+	rootAnchors = map[uint16]string{root.dnskey.KeyTag(): root.dnskey.ToDS(dns.SHA256).Digest}
+	t.Cleanup(func() { rootAnchors = anchors })
+
+	ds := secure.dnskey.ToDS(dns.SHA256)
+	www := testA("www.secure.test.", "192.0.2.1")
+	forged := testA("forged.secure.test.", "192.0.2.66")
+	expired := testA("expired.secure.test.", "192.0.2.2")
+	insecureWWW := testA("www.insecure.test.", "192.0.2.3")
+	unsigned := testA("unsigned.secure.test.", "192.0.2.4")
+	records := []dns.RR{
+		root.dnskey, root.sign(t, from, until, root.dnskey),
+		ds, root.sign(t, from, until, ds),
+		secure.dnskey, secure.sign(t, from, until, secure.dnskey),
+		www, secure.sign(t, from, until, www),
+		// The signature is of another address.
+		forged, secure.sign(t, from, until, testA("forged.secure.test.", "192.0.2.1")),
+		expired, secure.sign(t, now.Add(-2*time.Hour), now.Add(-time.Hour), expired),
+		unsigned,
+		insecure.dnskey, insecure.sign(t, from, until, insecure.dnskey),
+		insecureWWW, insecure.sign(t, from, until, insecureWWW),
+	}
+	server, _ := zoneNameserver(t, testSOA("."), records...)
+	return server
+}
+
+func TestLookupDNSSEC(t *testing.T) {
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := tooltest.NewServer(t, s)
+	nameserver := signedNameserver(t)
+
+	tests := []struct {
+		hostname   string
+		wantStatus string
+		wantReason string
+		wantChain  []string
+	}{
+		{hostname: "www.secure.test", wantStatus: dnssecSecure, wantChain: []string{".", "secure.test."}},
+		{hostname: "forged.secure.test", wantStatus: dnssecBogus, wantReason: "bad signature"},
+		{hostname: "expired.secure.test", wantStatus: dnssecBogus, wantReason: "expired or not yet valid"},
+		{hostname: "unsigned.secure.test", wantStatus: dnssecInsecure, wantReason: "no RRSIG"},
+		{hostname: "www.insecure.test", wantStatus: dnssecInsecure, wantReason: "no DS record for insecure.test."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hostname, func(t *testing.T) {
+			resp := srv.Post("/lookup", LookupRequest{Hostname: tt.hostname, Nameserver: nameserver, DNSSEC: true, BypassCache: true})
+			var out LookupResponse
+			resp.Decode(&out)
+			if out.DNSSEC == nil || len(out.DNSSEC.RRsets) != 1 {
+				t.Fatalf("response = %s", resp.Body)
+			}
+			st := out.DNSSEC.RRsets[0]
+			if out.DNSSEC.Status != tt.wantStatus || st.Status != tt.wantStatus || !strings.Contains(st.Reason, tt.wantReason) {
+				t.Errorf("status %s, rrset %+v; want %s, reason %q", out.DNSSEC.Status, st, tt.wantStatus, tt.wantReason)
+			}
+			if tt.wantChain != nil && !slices.Equal(out.DNSSEC.Chain, tt.wantChain) {
+				t.Errorf("chain = %v, want %v", out.DNSSEC.Chain, tt.wantChain)
+			}
+		})
+	}
+}
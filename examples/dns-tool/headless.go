@@ -0,0 +1,225 @@
+package dnstool
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/miekg/dns"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type HeadlessRequest struct {
+	// Either Hostname (e.g. db.prod.svc.cluster.local) or Service and
+	// Namespace identify the service.
+	Hostname      string `json:"hostname"`
+	Service       string `json:"service"`
+	Namespace     string `json:"namespace"`
+	ClusterDomain string `json:"clusterDomain"`
+	Nameserver    string `json:"nameserver"`
+}
+
+// HeadlessAddress is one endpoint address, from DNS, the API server, or both.
+type HeadlessAddress struct {
+	Address  string `json:"address"`
+	InDNS    bool   `json:"inDns"`
+	Pod      string `json:"pod,omitempty"`
+	Node     string `json:"node,omitempty"`
+	Hostname string `json:"hostname,omitempty"` // endpoint hostname, e.g. StatefulSet pod name
+	Ready    *bool  `json:"ready,omitempty"`
+	// Finding is empty when DNS and the API server agree: "missing-from-dns"
+	// for a ready endpoint with no record, "not-an-endpoint" for a record with
+	// no backing endpoint, "not-ready" for an unready endpoint that is
+	// correctly left out of DNS, and "not-ready-in-dns" when it is not.
+	Finding string `json:"finding,omitempty"`
+}
+
+type HeadlessResponse struct {
+	FQDN         string            `json:"fqdn"`
+	Service      string            `json:"service,omitempty"`
+	Namespace    string            `json:"namespace,omitempty"`
+	Nameserver   string            `json:"nameserver,omitempty"`
+	Correlated   bool              `json:"correlated"` // endpoints were read from the API server
+	Addresses    []HeadlessAddress `json:"addresses"`
+	MissingPods  []string          `json:"missingPods"` // ready pods with no DNS record
+	Unknown      []string          `json:"unknown"`     // DNS addresses with no endpoint
+	Error        string            `json:"error,omitempty"`
+	RetriedTimes int               `json:"retriedTimes,omitempty"` // API calls retried after transient errors
+}
+
+func headless(ctx context.Context, req HeadlessRequest) (HeadlessResponse, error) {
+	if req.ClusterDomain == "" {
+		req.ClusterDomain = defaultClusterDomain
+	}
+	domain := strings.TrimSuffix(req.ClusterDomain, ".")
+
+	switch {
+	case req.Service != "":
+		if req.Namespace == "" {
+			req.Namespace = "default"
+		}
+		req.Hostname = fmt.Sprintf("%s.%s.svc.%s.", req.Service, req.Namespace, domain)
+	case req.Hostname != "":
+		req.Service, req.Namespace = parseServiceName(req.Hostname, domain)
+	default:
+		return HeadlessResponse{}, toolserver.BadRequest("hostname or service is required")
+	}
+
+	server, err := resolveNameserver(req.Nameserver, "udp")
+	if err != nil {
+		return HeadlessResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	resp := HeadlessResponse{
+		FQDN:        dns.Fqdn(req.Hostname),
+		Service:     req.Service,
+		Namespace:   req.Namespace,
+		Nameserver:  server,
+		Addresses:   []HeadlessAddress{},
+		MissingPods: []string{},
+		Unknown:     []string{},
+	}
+
+	inDNS, err := resolveAllAddresses(ctx, resp.FQDN, server)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp, nil
+	}
+
+	clientset, kerr := kubeClient.ForNamespace(req.Namespace)
+	if kerr != nil || req.Service == "" {
+		for _, addr := range inDNS {
+			resp.Addresses = append(resp.Addresses, HeadlessAddress{Address: addr, InDNS: true})
+		}
+		return resp, nil
+	}
+
+	ctx = kube.CountRetries(ctx)
+	svc, err := kube.Retry(ctx, func(ctx context.Context) (*corev1.Service, error) {
+		return clientset.CoreV1().Services(req.Namespace).Get(ctx, req.Service, metav1.GetOptions{})
+	})
+	resp.RetriedTimes = kube.Retried(ctx)
+	if err != nil {
+		resp.Error = fmt.Sprintf("failed to get service: %v", err)
+		return resp, nil
+	}
+	if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		resp.Error = fmt.Sprintf("service %s/%s is not headless (clusterIP %s)", svc.Namespace, svc.Name, svc.Spec.ClusterIP)
+		return resp, nil
+	}
+	sliceList, err := kube.Retry(ctx, func(ctx context.Context) (*discoveryv1.EndpointSliceList, error) {
+		return clientset.DiscoveryV1().EndpointSlices(req.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: discoveryv1.LabelServiceName + "=" + req.Service,
+		})
+	})
+	resp.RetriedTimes = kube.Retried(ctx)
+	if err != nil {
+		resp.Error = fmt.Sprintf("failed to list endpoint slices: %v", err)
+		return resp, nil
+	}
+
+	resp.Correlated = true
+	correlateEndpoints(&resp, inDNS, sliceList.Items, svc.Spec.PublishNotReadyAddresses)
+	return resp, nil
+}
+
+// parseServiceName extracts service and namespace from
+// <service>.<namespace>.svc.<domain>; both are empty for other names.
+func parseServiceName(hostname, domain string) (string, string) {
+	suffix := ".svc." + domain
+	name := strings.TrimSuffix(strings.ToLower(hostname), ".")
+	if !strings.HasSuffix(name, suffix) {
+		return "", ""
+	}
+	parts := strings.Split(strings.TrimSuffix(name, suffix), ".")
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// resolveAllAddresses returns every A and AAAA answer for name. NXDOMAIN and
+// NODATA are not errors; they yield no addresses.
+func resolveAllAddresses(ctx context.Context, name, server string) ([]string, error) {
+	var addrs []string
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		result, err := query(ctx, name, qtype, server, defaultQueryOptions())
+		if result.Msg == nil {
+			return nil, err
+		}
+		if negativeAnswer(result.Msg, qtype) == nil && err != nil {
+			return nil, err
+		}
+		for _, rr := range result.Msg.Answer {
+			switch v := rr.(type) {
+			case *dns.A:
+				addrs = append(addrs, v.A.String())
+			case *dns.AAAA:
+				addrs = append(addrs, v.AAAA.String())
+			}
+		}
+	}
+	slices.Sort(addrs)
+	return slices.Compact(addrs), nil
+}
+
+// correlateEndpoints matches DNS addresses against endpoint addresses. A
+// ready endpoint (or any endpoint when the service publishes not-ready
+// addresses) is expected in DNS.
+func correlateEndpoints(resp *HeadlessResponse, inDNS []string, endpointSlices []discoveryv1.EndpointSlice, publishNotReady bool) {
+	seen := make(map[string]bool)
+	for _, es := range endpointSlices {
+		for _, ep := range es.Endpoints {
+			ready := ep.Conditions.Ready == nil || *ep.Conditions.Ready
+			for _, addr := range ep.Addresses {
+				if seen[addr] {
+					continue
+				}
+				seen[addr] = true
+
+				a := HeadlessAddress{Address: addr, InDNS: slices.Contains(inDNS, addr), Ready: &ready}
+				if ep.TargetRef != nil && ep.TargetRef.Kind == "Pod" {
+					a.Pod = ep.TargetRef.Name
+				}
+				if ep.NodeName != nil {
+					a.Node = *ep.NodeName
+				}
+				if ep.Hostname != nil {
+					a.Hostname = *ep.Hostname
+				}
+
+				expected := ready || publishNotReady
+				switch {
+				case expected && !a.InDNS:
+					a.Finding = "missing-from-dns"
+					resp.MissingPods = append(resp.MissingPods, podOrAddress(a))
+				case !expected && a.InDNS:
+					a.Finding = "not-ready-in-dns"
+				case !expected:
+					a.Finding = "not-ready"
+				}
+				resp.Addresses = append(resp.Addresses, a)
+			}
+		}
+	}
+
+	for _, addr := range inDNS {
+		if !seen[addr] {
+			resp.Addresses = append(resp.Addresses, HeadlessAddress{Address: addr, InDNS: true, Finding: "not-an-endpoint"})
+			resp.Unknown = append(resp.Unknown, addr)
+		}
+	}
+	slices.SortFunc(resp.Addresses, func(a, b HeadlessAddress) int { return strings.Compare(a.Address, b.Address) })
+}
+
+func podOrAddress(a HeadlessAddress) string {
+	if a.Pod != "" {
+		return a.Pod
+	}
+	return a.Address
+}
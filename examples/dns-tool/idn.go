@@ -0,0 +1,46 @@
+package dnstool
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// idnProfile maps and validates internationalized names the way browsers do
+// (UTS #46, non-transitional), but still allows underscores so service
+// labels like _dmarc or _http._tcp keep working.
+var idnProfile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.BidiRule(),
+	idna.StrictDomainName(false),
+)
+
+// normalizeHostname returns the ASCII (punycode) form used on the wire and
+// the Unicode form for display. Plain ASCII names without xn-- labels are
+// returned unchanged in both.
+func normalizeHostname(name string) (ascii, unicode string, err error) {
+	if isASCII(name) && !strings.Contains(strings.ToLower(name), "xn--") {
+		return name, name, nil
+	}
+
+	ascii, err = idnProfile.ToASCII(name)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid internationalized hostname %q: %v", name, err)
+	}
+	unicode, err = idnProfile.ToUnicode(ascii)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid internationalized hostname %q: %v", name, err)
+	}
+	return ascii, unicode, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package dnstool
+
+import "testing"
+
+func TestNormalizeHostname(t *testing.T) {
+	tests := []struct {
+		name        string
+		wantASCII   string
+		wantUnicode string
+		wantErr     bool
+	}{
+		{name: "web.example.test", wantASCII: "web.example.test", wantUnicode: "web.example.test"},
+		{name: "_dmarc.example.com", wantASCII: "_dmarc.example.com", wantUnicode: "_dmarc.example.com"},
+		{name: "bücher.example", wantASCII: "xn--bcher-kva.example", wantUnicode: "bücher.example"},
+		{name: "Bücher.Example", wantASCII: "xn--bcher-kva.example", wantUnicode: "bücher.example"},
+		{name: "XN--BCHER-KVA.example", wantASCII: "xn--bcher-kva.example", wantUnicode: "bücher.example"},
+		{name: "☕.example", wantASCII: "xn--53h.example", wantUnicode: "☕.example"},
+		{name: "xn--zz.example", wantErr: true},
+		{name: "aא.example", wantErr: true}, // mixes left-to-right and right-to-left
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ascii, unicode, err := normalizeHostname(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("normalizeHostname = %q, %q, want an error", ascii, unicode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ascii != tt.wantASCII || unicode != tt.wantUnicode {
+				t.Errorf("normalizeHostname = %q, %q, want %q, %q", ascii, unicode, tt.wantASCII, tt.wantUnicode)
+			}
+		})
+	}
+}
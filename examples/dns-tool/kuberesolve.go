@@ -0,0 +1,225 @@
+package dnstool
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/miekg/dns"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const defaultClusterDomain = "cluster.local"
+
+type KubeResolveRequest struct {
+	Service       string `json:"service"`
+	Namespace     string `json:"namespace"`
+	ClusterDomain string `json:"clusterDomain"`
+	Nameserver    string `json:"nameserver"`
+}
+
+// NameCheck is the result of resolving one expected name and comparing the
+// answer with what the API server says it should be.
+type NameCheck struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Expected []string `json:"expected"`
+	Records  []string `json:"records"`
+	Missing  []string `json:"missing,omitempty"` // expected but not in DNS
+	Stale    []string `json:"stale,omitempty"`   // in DNS but not expected
+	OK       bool     `json:"ok"`
+	Error    string   `json:"error,omitempty"`
+}
+
+type KubeResolveResponse struct {
+	Service      string      `json:"service,omitempty"`
+	Namespace    string      `json:"namespace,omitempty"`
+	FQDN         string      `json:"fqdn,omitempty"`
+	Headless     bool        `json:"headless"`
+	Nameserver   string      `json:"nameserver,omitempty"`
+	Checks       []NameCheck `json:"checks,omitempty"`
+	Stale        bool        `json:"stale"`                  // at least one check disagrees with the API server
+	RetriedTimes int         `json:"retriedTimes,omitempty"` // API calls retried after transient errors
+}
+
+func kubeResolve(ctx context.Context, req KubeResolveRequest) (KubeResolveResponse, error) {
+	if req.Service == "" {
+		return KubeResolveResponse{}, toolserver.BadRequest("service is required")
+	}
+	if req.Namespace == "" {
+		req.Namespace = "default"
+	}
+	clientset, err := kubeClient.ForNamespace(req.Namespace)
+	if err != nil {
+		return KubeResolveResponse{}, err
+	}
+	if req.ClusterDomain == "" {
+		req.ClusterDomain = defaultClusterDomain
+	}
+
+	server, err := resolveNameserver(req.Nameserver, "udp")
+	if err != nil {
+		return KubeResolveResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	ctx = kube.CountRetries(ctx)
+	svc, err := kube.Retry(ctx, func(ctx context.Context) (*corev1.Service, error) {
+		return clientset.CoreV1().Services(req.Namespace).Get(ctx, req.Service, metav1.GetOptions{})
+	})
+	if err != nil {
+		return KubeResolveResponse{}, kube.Errorf(err, "failed to get service")
+	}
+
+	sliceList, err := kube.Retry(ctx, func(ctx context.Context) (*discoveryv1.EndpointSliceList, error) {
+		return clientset.DiscoveryV1().EndpointSlices(req.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: discoveryv1.LabelServiceName + "=" + req.Service,
+		})
+	})
+	if err != nil {
+		return KubeResolveResponse{}, kube.Errorf(err, "failed to list endpoint slices")
+	}
+
+	fqdn := fmt.Sprintf("%s.%s.svc.%s.", svc.Name, svc.Namespace, strings.TrimSuffix(req.ClusterDomain, "."))
+	resp := KubeResolveResponse{
+		Service:      svc.Name,
+		Namespace:    svc.Namespace,
+		FQDN:         fqdn,
+		Headless:     svc.Spec.ClusterIP == corev1.ClusterIPNone,
+		Nameserver:   server,
+		RetriedTimes: kube.Retried(ctx),
+	}
+
+	for _, expected := range expectedServiceRecords(svc, sliceList.Items, fqdn) {
+		check := checkName(ctx, expected, server)
+		if !check.OK {
+			resp.Stale = true
+		}
+		resp.Checks = append(resp.Checks, check)
+	}
+
+	return resp, nil
+}
+
+// expectedServiceRecords derives what CoreDNS should serve for a service:
+// the ClusterIP (or every ready endpoint address when headless), a per-pod
+// record for headless endpoints with a hostname, and one SRV record set per
+// named port.
+func expectedServiceRecords(svc *corev1.Service, endpointSlices []discoveryv1.EndpointSlice, fqdn string) []NameCheck {
+	var checks []NameCheck
+	headless := svc.Spec.ClusterIP == corev1.ClusterIPNone
+
+	if headless {
+		var v4, v6 []string
+		for _, es := range endpointSlices {
+			for _, ep := range es.Endpoints {
+				if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+					continue
+				}
+				for _, addr := range ep.Addresses {
+					if es.AddressType == discoveryv1.AddressTypeIPv6 {
+						v6 = append(v6, addr)
+					} else {
+						v4 = append(v4, addr)
+					}
+				}
+				if ep.Hostname != nil && *ep.Hostname != "" && len(ep.Addresses) > 0 {
+					qtype := "A"
+					if es.AddressType == discoveryv1.AddressTypeIPv6 {
+						qtype = "AAAA"
+					}
+					checks = append(checks, NameCheck{Name: *ep.Hostname + "." + fqdn, Type: qtype, Expected: ep.Addresses})
+				}
+			}
+		}
+		checks = append([]NameCheck{{Name: fqdn, Type: "A", Expected: v4}}, checks...)
+		if len(v6) > 0 {
+			checks = append(checks, NameCheck{Name: fqdn, Type: "AAAA", Expected: v6})
+		}
+	} else if svc.Spec.Type != corev1.ServiceTypeExternalName {
+		for _, ip := range svc.Spec.ClusterIPs {
+			qtype := "A"
+			if strings.Contains(ip, ":") {
+				qtype = "AAAA"
+			}
+			checks = append(checks, NameCheck{Name: fqdn, Type: qtype, Expected: []string{ip}})
+		}
+	}
+
+	for _, port := range svc.Spec.Ports {
+		if port.Name == "" {
+			continue
+		}
+		name := fmt.Sprintf("_%s._%s.%s", port.Name, strings.ToLower(string(port.Protocol)), fqdn)
+		var expected []string
+		if headless {
+			for _, es := range endpointSlices {
+				for _, ep := range es.Endpoints {
+					if ep.Hostname == nil || *ep.Hostname == "" {
+						continue
+					}
+					if p := slicePort(es, port.Name); p != 0 {
+						expected = append(expected, fmt.Sprintf("%d %s.%s", p, *ep.Hostname, fqdn))
+					}
+				}
+			}
+		} else {
+			expected = []string{fmt.Sprintf("%d %s", port.Port, fqdn)}
+		}
+		checks = append(checks, NameCheck{Name: name, Type: "SRV", Expected: expected})
+	}
+
+	return checks
+}
+
+func slicePort(es discoveryv1.EndpointSlice, name string) int32 {
+	for _, p := range es.Ports {
+		if p.Name != nil && *p.Name == name && p.Port != nil {
+			return *p.Port
+		}
+	}
+	return 0
+}
+
+// checkName resolves check.Name and diffs the answer against check.Expected.
+// SRV answers are compared as "port target"; priority and weight vary by
+// CoreDNS configuration and are ignored.
+func checkName(ctx context.Context, check NameCheck, server string) NameCheck {
+	check.Records = []string{}
+	if check.Expected == nil {
+		check.Expected = []string{}
+	}
+
+	result, err := query(ctx, check.Name, dns.StringToType[check.Type], server, defaultQueryOptions())
+	if err != nil {
+		check.Error = err.Error()
+	} else {
+		for _, rr := range result.Msg.Answer {
+			switch v := rr.(type) {
+			case *dns.A:
+				check.Records = append(check.Records, v.A.String())
+			case *dns.AAAA:
+				check.Records = append(check.Records, v.AAAA.String())
+			case *dns.SRV:
+				check.Records = append(check.Records, fmt.Sprintf("%d %s", v.Port, v.Target))
+			}
+		}
+	}
+
+	for _, want := range check.Expected {
+		if !slices.Contains(check.Records, want) {
+			check.Missing = append(check.Missing, want)
+		}
+	}
+	for _, got := range check.Records {
+		if !slices.Contains(check.Expected, got) {
+			check.Stale = append(check.Stale, got)
+		}
+	}
+	check.OK = check.Error == "" && len(check.Missing) == 0 && len(check.Stale) == 0
+	return check
+}
@@ -0,0 +1,35 @@
+package dnstool
+
+import (
+	"context"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// maxBatchLookups is the most lookups a /lookup-batch call makes.
+const maxBatchLookups = 50
+
+type LookupBatchRequest struct {
+	Lookups []LookupRequest `json:"lookups"`
+}
+
+// LookupBatchResponse has a lookup response per request, in order; those
+// that failed are listed in errors too. NXDOMAIN and NODATA answers are
+// answers, not failures, as for /lookup.
+type LookupBatchResponse = toolserver.Batch[LookupResponse]
+
+// batchLookup makes the lookups concurrently. A lookup fails as it would
+// for /lookup: with INVALID_ARGUMENT if it was not sent, e.g. for an
+// unsupported type, and UPSTREAM_ERROR or UPSTREAM_TIMEOUT if the query
+// failed.
+func batchLookup(ctx context.Context, req LookupBatchRequest) (LookupBatchResponse, error) {
+	if len(req.Lookups) == 0 {
+		return LookupBatchResponse{}, toolserver.BadRequest("lookups is required")
+	}
+	if len(req.Lookups) > maxBatchLookups {
+		return LookupBatchResponse{}, toolserver.BadRequest("at most %d lookups per request", maxBatchLookups)
+	}
+	return toolserver.RunBatch(ctx, len(req.Lookups), nil, func(ctx context.Context, i int) (LookupResponse, error) {
+		return lookup(ctx, req.Lookups[i])
+	})
+}
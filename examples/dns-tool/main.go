@@ -1,135 +1,245 @@
-package main
+// Package dnstool implements dns-tool, which runs DNS lookups and checks
+// the records Kubernetes publishes for Services.
+package dnstool
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
 	"net"
-	"net/http"
-	"os"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/miekg/dns"
 )
 
 type LookupRequest struct {
-	Hostname string `json:"hostname"`
-	Type     string `json:"type"` // A, AAAA, MX, TXT, CNAME
+	Hostname   string `json:"hostname"`
+	Type       string `json:"type"`       // A, AAAA, MX, TXT, CNAME, PTR, NS, SOA, CAA
+	Nameserver string `json:"nameserver"` // host or host:port; defaults to the first resolv.conf server
+
+	TimeoutMs     int    `json:"timeoutMs"`     // per-attempt timeout, default 5000
+	Retries       *int   `json:"retries"`       // extra attempts on timeout/network errors, default 1; 0 for none
+	Transport     string `json:"transport"`     // udp (default), tcp, dot or doh
+	TLSServerName string `json:"tlsServerName"` // DoT certificate name when nameserver is an IP
+	TCPFallback   *bool  `json:"tcpFallback"`   // retry truncated UDP answers over TCP, default true
+	DNSSEC        bool   `json:"dnssec"`        // request signatures and validate them up to the root
+	BypassCache   bool   `json:"bypassCache"`   // always query upstream, still refreshing the cache
+	ProbeWildcard bool   `json:"probeWildcard"` // query a random sibling name to detect unsigned wildcards
 }
 
 type LookupResponse struct {
-	Hostname string   `json:"hostname"`
-	Type     string   `json:"type"`
-	Records  []string `json:"records"`
-	TTL      int      `json:"ttl"`
-	Error    string   `json:"error,omitempty"`
+	Hostname   string          `json:"hostname"`
+	ASCII      string          `json:"ascii,omitempty"`   // punycode form sent on the wire, for IDNs
+	Unicode    string          `json:"unicode,omitempty"` // display form, for IDNs
+	Type       string          `json:"type"`
+	Nameserver string          `json:"nameserver,omitempty"` // server that answered, as host:port
+	Transport  string          `json:"transport,omitempty"`  // transport of the final answer
+	Attempts   int             `json:"attempts,omitempty"`
+	Truncated  bool            `json:"truncated,omitempty"` // the UDP answer had the TC bit set
+	Cached     bool            `json:"cached"`              // answered from the lookup cache; TTLs are aged
+	Rcode      string          `json:"rcode,omitempty"`     // NOERROR, NXDOMAIN, SERVFAIL, ...
+	LatencyMs  float64         `json:"latencyMs"`           // whole query, retries and TCP fallback included
+	RTTMs      float64         `json:"rttMs,omitempty"`     // round trip of the exchange that answered
+	ReplyBytes int             `json:"replyBytes,omitempty"`
+	Records    []string        `json:"records"`
+	TTL        int             `json:"ttl"`
+	SOA        *SOARecord      `json:"soa,omitempty"`
+	CAA        []CAARecord     `json:"caa,omitempty"`
+	DNSSEC     *DNSSECResult   `json:"dnssec,omitempty"`
+	Negative   *NegativeAnswer `json:"negative,omitempty"` // set for NXDOMAIN and NODATA answers
+	Wildcard   *WildcardInfo   `json:"wildcard,omitempty"`
 }
 
-func main() {
-	http.HandleFunc("/health", handleHealth)
-	http.HandleFunc("/lookup", handleLookup)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	log.Printf("Starting dns-tool server on :%s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
-	}
+type SOARecord struct {
+	Primary    string `json:"primary"`
+	Mailbox    string `json:"mailbox"`
+	Serial     uint32 `json:"serial"`
+	Refresh    uint32 `json:"refresh"`
+	Retry      uint32 `json:"retry"`
+	Expire     uint32 `json:"expire"`
+	MinimumTTL uint32 `json:"minimumTtl"`
 }
 
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+type CAARecord struct {
+	Flag  uint8  `json:"flag"`
+	Tag   string `json:"tag"` // issue, issuewild, iodef
+	Value string `json:"value"`
 }
 
-func handleLookup(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req LookupRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(LookupResponse{Error: "invalid request body"})
-		return
-	}
+// New returns the dns-tool server. The Kubernetes client, used by
+// /kube-resolve and /headless, is optional.
+func New() (*toolserver.Server, error) {
+	s := toolserver.New("dns-tool")
+	s.AddReadinessCheck("nameserver", nameserverReady)
+	s.AddDiagnostics("nameserver", nameserverDiagnostics)
+	s.AddDiagnostics("kubernetes", kubeClient.Diagnostics)
+	toolserver.Register(s, "/lookup", lookup,
+		toolserver.Name("dns-tool"), toolserver.Describe("Perform DNS lookups for hostnames."))
+	toolserver.Register(s, "/lookup-batch", batchLookup,
+		toolserver.Name("dns-lookup-batch"), toolserver.Describe("Perform several DNS lookups at once, reporting the ones that fail separately."))
+	toolserver.Register(s, "/compare", compare,
+		toolserver.Name("dns-compare"), toolserver.Describe("Run the same DNS lookup against several resolvers and report which ones disagree."))
+	toolserver.Register(s, "/kube-resolve", kubeResolve,
+		toolserver.Describe("Check the DNS records Kubernetes should publish for a Service against its EndpointSlices."),
+		toolserver.RBAC("get services", "list endpointslices.discovery.k8s.io"))
+	toolserver.Register(s, "/headless", headless,
+		toolserver.Name("headless-endpoints"), toolserver.Describe("Match every A/AAAA answer for a headless Service name to its EndpointSlice endpoint."),
+		toolserver.RBAC("get services", "list endpointslices.discovery.k8s.io"))
+	toolserver.Register(s, "/search-path", searchPath,
+		toolserver.Name("dns-search-path"), toolserver.Describe("Show the sequence of queries a pod's stub resolver issues for a short name."))
+	toolserver.Register(s, "/propagation", propagation,
+		toolserver.Name("dns-propagation"), toolserver.Describe("Query each authoritative nameserver for a record and the zone's SOA serial."))
+	s.HandleFunc("/monitor", handleMonitor)
+	s.HandleFunc("/cache", handleCacheStats)
+
+	return s, nil
+}
 
+func lookup(ctx context.Context, req LookupRequest) (LookupResponse, error) {
 	if req.Hostname == "" {
-		json.NewEncoder(w).Encode(LookupResponse{Error: "hostname is required"})
-		return
+		return LookupResponse{}, toolserver.BadRequest("hostname is required")
 	}
 	if req.Type == "" {
 		req.Type = "A"
 	}
+	return performLookup(ctx, req)
+}
 
-	resp := performLookup(req.Hostname, req.Type)
-	json.NewEncoder(w).Encode(resp)
+// supportedTypes maps the record types accepted in requests to DNS query types.
+var supportedTypes = map[string]uint16{
+	"A":     dns.TypeA,
+	"AAAA":  dns.TypeAAAA,
+	"MX":    dns.TypeMX,
+	"TXT":   dns.TypeTXT,
+	"CNAME": dns.TypeCNAME,
+	"PTR":   dns.TypePTR,
+	"NS":    dns.TypeNS,
+	"SOA":   dns.TypeSOA,
+	"CAA":   dns.TypeCAA,
 }
 
-func performLookup(hostname, recordType string) LookupResponse {
+// performLookup runs a lookup. NXDOMAIN and NODATA are answers, with
+// Negative set. It fails with INVALID_ARGUMENT for a request it cannot
+// send, INTERNAL without a system nameserver to send it to, and
+// UPSTREAM_ERROR, or UPSTREAM_TIMEOUT, if the query fails or the server
+// answers with another error code, e.g. SERVFAIL.
+func performLookup(ctx context.Context, req LookupRequest) (LookupResponse, error) {
+	hostname, recordType := req.Hostname, req.Type
 	resp := LookupResponse{
 		Hostname: hostname,
 		Type:     recordType,
-		TTL:      300, // Dummy TTL as net package doesn't provide it
+		Records:  []string{},
 	}
 
-	var err error
-	switch recordType {
-	case "A":
-		var ips []net.IP
-		ips, err = net.LookupIP(hostname)
-		if err == nil {
-			for _, ip := range ips {
-				if ip.To4() != nil {
-					resp.Records = append(resp.Records, ip.String())
-				}
-			}
-		}
-	case "AAAA":
-		var ips []net.IP
-		ips, err = net.LookupIP(hostname)
-		if err == nil {
-			for _, ip := range ips {
-				if ip.To4() == nil {
-					resp.Records = append(resp.Records, ip.String())
-				}
-			}
-		}
-	case "MX":
-		var mxs []*net.MX
-		mxs, err = net.LookupMX(hostname)
-		if err == nil {
-			for _, mx := range mxs {
-				resp.Records = append(resp.Records, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
-			}
+	qtype, ok := supportedTypes[recordType]
+	if !ok {
+		return resp, toolserver.BadRequest("unsupported record type: %s", recordType)
+	}
+
+	opts, err := newQueryOptions(req.TimeoutMs, req.Retries, req.Transport, req.TCPFallback)
+	if err != nil {
+		return resp, toolserver.BadRequest("%v", err)
+	}
+	opts.DNSSEC = req.DNSSEC
+	opts.TLSServerName = req.TLSServerName
+
+	server, err := resolveNameserver(req.Nameserver, opts.Transport)
+	switch {
+	case err != nil && req.Nameserver == "":
+		return resp, toolserver.NewError(toolserver.CodeInternal, "%v", err)
+	case err != nil:
+		return resp, toolserver.BadRequest("%v", err)
+	}
+	resp.Nameserver = server
+
+	name := hostname
+	if recordType == "PTR" {
+		if net.ParseIP(hostname) == nil {
+			return resp, toolserver.BadRequest("PTR lookups require an IP address, got: %s", hostname)
 		}
-	case "TXT":
-		var txts []string
-		txts, err = net.LookupTXT(hostname)
-		if err == nil {
-			resp.Records = txts
+		name, _ = dns.ReverseAddr(hostname)
+	} else {
+		ascii, unicode, err := normalizeHostname(hostname)
+		if err != nil {
+			return resp, toolserver.BadRequest("%v", err)
 		}
-	case "CNAME":
-		var cname string
-		cname, err = net.LookupCNAME(hostname)
-		if err == nil {
-			resp.Records = append(resp.Records, cname)
+		if ascii != unicode {
+			resp.ASCII, resp.Unicode = ascii, unicode
 		}
-	default:
-		resp.Error = fmt.Sprintf("unsupported record type: %s", recordType)
-		return resp
+		name = ascii
 	}
 
-	if err != nil {
-		resp.Error = err.Error()
+	result, cached, err := lookupCache.cachedQuery(ctx, name, qtype, server, opts, req.BypassCache)
+	resp.Cached = cached
+	resp.Transport = result.Transport
+	resp.Attempts = result.Attempts
+	resp.Truncated = result.Truncated
+	resp.LatencyMs = float64(result.Duration.Microseconds()) / 1000
+	if result.Msg != nil {
+		resp.Rcode = dns.RcodeToString[result.Msg.Rcode]
+		resp.RTTMs = float64(result.RTT.Microseconds()) / 1000
+		resp.ReplyBytes = result.Size
+		resp.Negative = negativeAnswer(result.Msg, qtype)
+	}
+	switch {
+	case err != nil && result.Msg != nil && result.Msg.Rcode == dns.RcodeNameError:
+		return resp, nil // the name does not exist, which is an answer
+	case err != nil:
+		return resp, toolserver.UpstreamError(err, "%s lookup of %s failed", recordType, hostname)
 	}
 
-	// Ensure records is not nil for JSON output
-	if resp.Records == nil {
-		resp.Records = []string{}
+	collectAnswers(&resp, result.Msg.Answer, qtype)
+	resp.Wildcard = detectWildcard(ctx, result.Msg.Answer, qtype, server, opts, req.ProbeWildcard)
+	if req.DNSSEC {
+		resp.DNSSEC = newValidator(ctx, server, opts).validate(result.Msg.Answer)
 	}
+	return resp, nil
+}
+
+// collectAnswers fills resp from the answer section, keeping only records of
+// the queried type (a CNAME chain may precede them) and reporting the lowest
+// TTL among them, which is how long the whole set may be cached.
+func collectAnswers(resp *LookupResponse, answers []dns.RR, qtype uint16) {
+	first := true
+	for _, rr := range answers {
+		hdr := rr.Header()
+		if hdr.Rrtype != qtype {
+			continue
+		}
+		if first || int(hdr.Ttl) < resp.TTL {
+			resp.TTL = int(hdr.Ttl)
+			first = false
+		}
 
-	return resp
+		switch v := rr.(type) {
+		case *dns.A:
+			resp.Records = append(resp.Records, v.A.String())
+		case *dns.AAAA:
+			resp.Records = append(resp.Records, v.AAAA.String())
+		case *dns.MX:
+			resp.Records = append(resp.Records, fmt.Sprintf("%d %s", v.Preference, v.Mx))
+		case *dns.TXT:
+			resp.Records = append(resp.Records, strings.Join(v.Txt, ""))
+		case *dns.CNAME:
+			resp.Records = append(resp.Records, v.Target)
+		case *dns.PTR:
+			resp.Records = append(resp.Records, v.Ptr)
+		case *dns.NS:
+			resp.Records = append(resp.Records, v.Ns)
+		case *dns.SOA:
+			resp.SOA = &SOARecord{
+				Primary:    v.Ns,
+				Mailbox:    v.Mbox,
+				Serial:     v.Serial,
+				Refresh:    v.Refresh,
+				Retry:      v.Retry,
+				Expire:     v.Expire,
+				MinimumTTL: v.Minttl,
+			}
+			resp.Records = append(resp.Records, fmt.Sprintf("%s %s %d %d %d %d %d", v.Ns, v.Mbox, v.Serial, v.Refresh, v.Retry, v.Expire, v.Minttl))
+		case *dns.CAA:
+			resp.CAA = append(resp.CAA, CAARecord{Flag: v.Flag, Tag: v.Tag, Value: v.Value})
+			resp.Records = append(resp.Records, fmt.Sprintf("%d %s %q", v.Flag, v.Tag, v.Value))
+		}
+	}
 }
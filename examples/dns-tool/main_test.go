@@ -0,0 +1,236 @@
+package dnstool
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+	"github.com/miekg/dns"
+)
+
+// testNameserver serves A records for every name under example.test, but
+// servfail.example.test, on a local UDP port and returns its address. Other
+// names do not exist, for the negative TTL of an example.test SOA.
+func testNameserver(tb testing.TB) string {
+	tb.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		q := req.Question[0]
+		switch {
+		case q.Name == "servfail.example.test.":
+			m.Rcode = dns.RcodeServerFailure
+		case q.Qtype == dns.TypeA && strings.HasSuffix(q.Name, ".example.test."):
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+				A:   net.IPv4(192, 0, 2, 10),
+			})
+		default:
+			m.Rcode = dns.RcodeNameError
+			m.Ns = append(m.Ns, testSOA("example.test."))
+		}
+		w.WriteMsg(m)
+	})}
+	go srv.ActivateAndServe()
+	tb.Cleanup(func() { srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+// testSOA returns the SOA of zone, with a TTL of 5m and a negative TTL of
+// 1m.
+func testSOA(zone string) *dns.SOA {
+	return &dns.SOA{
+		Hdr:    dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 300},
+		Ns:     dns.Fqdn("ns." + strings.TrimSuffix(zone, ".")),
+		Mbox:   dns.Fqdn("hostmaster." + strings.TrimSuffix(zone, ".")),
+		Serial: 1, Refresh: 3600, Retry: 600, Expire: 86400, Minttl: 60,
+	}
+}
+
+// zoneNameserver serves records on a local UDP port and returns its
+// address, with the queries it has answered. A name it has no records of
+// the queried type for is NODATA if it has others and NXDOMAIN if not,
+// either with soa, if not nil, in the authority section.
+func zoneNameserver(tb testing.TB, soa *dns.SOA, records ...dns.RR) (string, *atomic.Int64) {
+	tb.Helper()
+	type key struct {
+		name  string
+		qtype uint16
+	}
+	answers := make(map[key][]dns.RR)
+	names := make(map[string]bool)
+	for _, rr := range records {
+		qtype := rr.Header().Rrtype
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			qtype = sig.TypeCovered
+		}
+		name := dns.CanonicalName(rr.Header().Name)
+		answers[key{name, qtype}] = append(answers[key{name, qtype}], rr)
+		names[name] = true
+	}
+
+	var queries atomic.Int64
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		queries.Add(1)
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Authoritative = true
+		q := req.Question[0]
+		m.Answer = answers[key{dns.CanonicalName(q.Name), q.Qtype}]
+		if len(m.Answer) == 0 {
+			if !names[dns.CanonicalName(q.Name)] {
+				m.Rcode = dns.RcodeNameError
+			}
+			if soa != nil {
+				m.Ns = []dns.RR{soa}
+			}
+		}
+		w.WriteMsg(m)
+	})}
+	go srv.ActivateAndServe()
+	tb.Cleanup(func() { srv.Shutdown() })
+	return pc.LocalAddr().String(), &queries
+}
+
+// silentNameserver returns the address of a local UDP port that never
+// answers.
+func silentNameserver(tb testing.TB) string {
+	tb.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { pc.Close() })
+	return pc.LocalAddr().String()
+}
+
+// lookupBatch returns a JSON-RPC batch of n dns-tool lookups.
+func lookupBatch(n int, nameserver string, bypassCache bool) []byte {
+	calls := make([]map[string]any, n)
+	for i := range calls {
+		calls[i] = map[string]any{
+			"jsonrpc": "2.0", "id": i, "method": "dns-tool",
+			"params": LookupRequest{Hostname: fmt.Sprintf("host-%d.example.test", i), Nameserver: nameserver, BypassCache: bypassCache},
+		}
+	}
+	data, _ := json.Marshal(calls)
+	return data
+}
+
+func TestLookup(t *testing.T) {
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nameserver := testNameserver(t)
+	noRetries := 0
+	tooltest.Run(t, tooltest.NewServer(t, s), []tooltest.Case{
+		{Name: "answer", Path: "/lookup", Body: LookupRequest{Hostname: "web.example.test", Nameserver: nameserver}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out LookupResponse
+			resp.Decode(&out)
+			if len(out.Records) != 1 || out.Records[0] != "192.0.2.10" || out.TTL != 300 {
+				t.Errorf("response = %+v", out)
+			}
+		}},
+		{Name: "nxdomain", Path: "/lookup", Body: LookupRequest{Hostname: "web.example.invalid", Nameserver: nameserver}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out LookupResponse
+			resp.Decode(&out)
+			if out.Rcode != "NXDOMAIN" || len(out.Records) != 0 {
+				t.Errorf("response = %+v", out)
+			}
+		}},
+		{Name: "unsupported type", Path: "/lookup", Body: LookupRequest{Hostname: "web.example.test", Type: "SRV", Nameserver: nameserver}, Code: toolserver.CodeInvalidArgument},
+		{Name: "unsupported transport", Path: "/lookup", Body: LookupRequest{Hostname: "web.example.test", Transport: "quic", Nameserver: nameserver}, Code: toolserver.CodeInvalidArgument},
+		{Name: "PTR of a hostname", Path: "/lookup", Body: LookupRequest{Hostname: "web.example.test", Type: "PTR", Nameserver: nameserver}, Code: toolserver.CodeInvalidArgument},
+		{Name: "servfail", Path: "/lookup", Body: LookupRequest{Hostname: "servfail.example.test", Nameserver: nameserver}, Code: toolserver.CodeUpstreamError},
+		{Name: "timeout", Path: "/lookup", Body: LookupRequest{Hostname: "web.example.test", TimeoutMs: 50, Nameserver: silentNameserver(t)}, Code: toolserver.CodeUpstreamTimeout},
+		{Name: "timeout without retries", Path: "/lookup", Body: LookupRequest{Hostname: "web.example.test", TimeoutMs: 50, Retries: &noRetries, Nameserver: silentNameserver(t)}, Code: toolserver.CodeUpstreamTimeout, Check: func(t *testing.T, resp *tooltest.Response) {
+			if e := resp.Error(); !strings.Contains(e.Message, "after 1 attempt(s)") {
+				t.Errorf("message = %q, want a single attempt", e.Message)
+			}
+		}},
+		{Name: "batch", Path: "/lookup-batch", Body: LookupBatchRequest{Lookups: []LookupRequest{
+			{Hostname: "web.example.test", Nameserver: nameserver},
+			{Hostname: "web.example.test", Type: "SRV", Nameserver: nameserver},
+			{Hostname: "db.example.test", Nameserver: nameserver},
+		}}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out LookupBatchResponse
+			resp.Decode(&out)
+			if len(out.Results) != 3 || len(out.Results[0].Records) != 1 || len(out.Results[2].Records) != 1 {
+				t.Errorf("results = %+v", out.Results)
+			}
+			if !out.Partial || len(out.Errors) != 1 || out.Errors[0].Index != 1 || out.Errors[0].Code != toolserver.CodeInvalidArgument {
+				t.Errorf("errors = %+v, partial %v; want the unsupported type", out.Errors, out.Partial)
+			}
+		}},
+		{Name: "batch nxdomain twice", Path: "/lookup-batch", Body: LookupBatchRequest{Lookups: []LookupRequest{
+			{Hostname: "gone.example.invalid", Nameserver: nameserver},
+			{Hostname: "gone.example.invalid", Nameserver: nameserver},
+		}}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out LookupBatchResponse
+			resp.Decode(&out)
+			if out.Partial || len(out.Errors) != 0 {
+				t.Errorf("errors = %+v, partial %v; want none", out.Errors, out.Partial)
+			}
+			for i, r := range out.Results {
+				if r.Rcode != "NXDOMAIN" || r.Negative == nil {
+					t.Errorf("results[%d] = %+v", i, r)
+				}
+			}
+		}},
+		{Name: "batch nxdomain cached", Path: "/lookup-batch", Body: LookupBatchRequest{Lookups: []LookupRequest{
+			{Hostname: "gone.example.invalid", Nameserver: nameserver},
+		}}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out LookupBatchResponse
+			resp.Decode(&out)
+			if len(out.Errors) != 0 || len(out.Results) != 1 || !out.Results[0].Cached || out.Results[0].Rcode != "NXDOMAIN" {
+				t.Errorf("response = %+v; want a cached NXDOMAIN answer", out)
+			}
+		}},
+		{Name: "batch all failing", Path: "/lookup-batch", Body: LookupBatchRequest{Lookups: []LookupRequest{
+			{Hostname: "web.example.test", Type: "SRV", Nameserver: nameserver},
+		}}, Code: toolserver.CodeInvalidArgument},
+	})
+}
+
+// BenchmarkLookupBatch measures a JSON-RPC batch of lookups, which the
+// server runs concurrently, against a local nameserver: uncached, so every
+// lookup is a round trip, and answered from the lookup cache.
+func BenchmarkLookupBatch(b *testing.B) {
+	s, err := New()
+	if err != nil {
+		b.Fatal(err)
+	}
+	srv := tooltest.NewServer(b, s)
+	nameserver := testNameserver(b)
+	for _, bc := range []struct {
+		name   string
+		bypass bool
+	}{{"uncached", true}, {"cached", false}} {
+		for _, n := range []int{10, 100} {
+			batch := lookupBatch(n, nameserver, bc.bypass)
+			srv.Post("/rpc", batch) // warm the cache
+			b.Run(fmt.Sprintf("%s/lookups=%d", bc.name, n), func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					if resp := srv.Post("/rpc", batch); resp.StatusCode != 200 {
+						b.Fatalf("status %d: %s", resp.StatusCode, resp.Body)
+					}
+				}
+			})
+		}
+	}
+}
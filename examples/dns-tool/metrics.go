@@ -0,0 +1,65 @@
+package dnstool
+
+import (
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Query metrics are recorded for every query the tool sends, including the
+// DNSKEY/DS lookups made during DNSSEC validation, so /metrics can back a
+// DNS latency SLO for each upstream resolver.
+var (
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dns_tool_query_duration_seconds",
+		Help:    "Time to answer a DNS query, including retries and TCP fallback.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"server", "transport", "rcode"})
+
+	responseSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dns_tool_response_size_bytes",
+		Help:    "Size of DNS replies on the wire.",
+		Buckets: prometheus.ExponentialBuckets(64, 2, 8), // 64B .. 8KiB
+	}, []string{"server", "transport"})
+
+	queryAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dns_tool_query_attempts_total",
+		Help: "Packets sent, counting retries and TCP fallback.",
+	}, []string{"server", "transport"})
+
+	truncatedReplies = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dns_tool_truncated_replies_total",
+		Help: "UDP replies with the TC bit set.",
+	}, []string{"server"})
+)
+
+// rcodeLabel is the response code name, or "error" when no reply arrived.
+func rcodeLabel(result *queryResult) string {
+	if result == nil || result.Msg == nil {
+		return "error"
+	}
+	return dns.RcodeToString[result.Msg.Rcode]
+}
+
+func observeQuery(server string, result *queryResult) {
+	queryDuration.WithLabelValues(server, result.Transport, rcodeLabel(result)).Observe(result.Duration.Seconds())
+	queryAttempts.WithLabelValues(server, result.Transport).Add(float64(result.Attempts))
+	if result.Msg != nil {
+		responseSize.WithLabelValues(server, result.Transport).Observe(float64(result.Size))
+	}
+	if result.Truncated {
+		truncatedReplies.WithLabelValues(server).Inc()
+	}
+}
+
+var (
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dns_tool_cache_requests_total",
+		Help: "Lookup cache requests by result (hit or miss).",
+	}, []string{"result"})
+
+	cacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "dns_tool_cache_entries",
+		Help: "Replies currently held in the lookup cache.",
+	})
+)
@@ -0,0 +1,116 @@
+package dnstool
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+const (
+	defaultMonitorInterval = 5 * time.Second
+	minMonitorInterval     = 500 * time.Millisecond
+)
+
+type MonitorRequest struct {
+	LookupRequest
+	IntervalMs int `json:"intervalMs"` // time between lookups, default 5000, minimum 500
+	Count      int `json:"count"`      // stop after this many lookups; 0 runs until the client disconnects
+}
+
+// MonitorEvent is each lookup's line of NDJSON, or the data of its
+// server-sent event. Event, also the server-sent event's name, is "change"
+// when the answer differs from the previous successful one, "failure" when
+// the lookup failed, and "result" otherwise.
+type MonitorEvent struct {
+	Event     string    `json:"event"`
+	Seq       int       `json:"seq"`
+	Time      time.Time `json:"time"`
+	Records   []string  `json:"records"`
+	Previous  []string  `json:"previous,omitempty"` // prior answer, on change events
+	TTL       int       `json:"ttl"`
+	Rcode     string    `json:"rcode,omitempty"`
+	LatencyMs float64   `json:"latencyMs"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// handleMonitor streams lookups as NDJSON, or as server-sent events for
+// clients that accept them, so it is registered as a plain handler rather
+// than with toolserver.Register.
+func handleMonitor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		toolserver.WriteError(w, toolserver.Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+
+	var req MonitorRequest
+	if err := toolserver.DecodeJSON(w, r, &req); err != nil {
+		toolserver.WriteError(w, err)
+		return
+	}
+	if req.Hostname == "" {
+		toolserver.WriteError(w, toolserver.BadRequest("hostname is required"))
+		return
+	}
+	if req.Type == "" {
+		req.Type = "A"
+	}
+
+	interval := defaultMonitorInterval
+	if req.IntervalMs > 0 {
+		interval = max(time.Duration(req.IntervalMs)*time.Millisecond, minMonitorInterval)
+	}
+	// Every tick must reach the resolver, or changes would hide behind the TTL.
+	req.BypassCache = true
+
+	stream, err := toolserver.NewStreamWriter(w, r)
+	if err != nil {
+		toolserver.WriteError(w, err)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var previous []string
+	havePrevious := false
+	for seq := 1; req.Count == 0 || seq <= req.Count; seq++ {
+		lr, err := performLookup(r.Context(), req.LookupRequest)
+		records := slices.Clone(lr.Records)
+		slices.Sort(records)
+
+		ev := MonitorEvent{
+			Seq:       seq,
+			Time:      time.Now().UTC(),
+			Records:   records,
+			TTL:       lr.TTL,
+			Rcode:     lr.Rcode,
+			LatencyMs: lr.LatencyMs,
+		}
+		ev.Event = "result"
+		switch {
+		case err != nil:
+			ev.Event, ev.Error = "failure", err.Error()
+		case havePrevious && !slices.Equal(previous, records):
+			ev.Event = "change"
+			ev.Previous = previous
+		}
+		if err == nil {
+			previous, havePrevious = records, true
+		}
+
+		if err := stream.Send(ev.Event, ev); err != nil {
+			return
+		}
+
+		if req.Count != 0 && seq == req.Count {
+			return
+		}
+		select {
+		case <-stream.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package dnstool
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// NegativeAnswer describes an answer that proves the record does not exist.
+// NXDOMAIN means the name has no records of any type; NODATA means the name
+// exists but has none of the queried type.
+type NegativeAnswer struct {
+	Kind          string `json:"kind"` // NXDOMAIN or NODATA
+	Zone          string `json:"zone,omitempty"`
+	SOAMinimumTTL uint32 `json:"soaMinimumTtl,omitempty"`
+	// NegativeTTL is how long resolvers may cache the negative answer:
+	// min(SOA TTL, SOA MINIMUM) per RFC 2308.
+	NegativeTTL uint32 `json:"negativeTtl"`
+}
+
+// WildcardInfo reports whether an answer was synthesized from a wildcard.
+type WildcardInfo struct {
+	Wildcard bool   `json:"wildcard"`
+	Owner    string `json:"owner,omitempty"`    // the wildcard record, e.g. *.example.com.
+	Evidence string `json:"evidence,omitempty"` // rrsig or probe
+}
+
+// negativeAnswer classifies msg as NXDOMAIN or NODATA for qtype. It returns
+// nil for positive answers and for failures such as SERVFAIL.
+func negativeAnswer(msg *dns.Msg, qtype uint16) *NegativeAnswer {
+	var neg *NegativeAnswer
+	switch msg.Rcode {
+	case dns.RcodeNameError:
+		neg = &NegativeAnswer{Kind: "NXDOMAIN"}
+	case dns.RcodeSuccess:
+		for _, rr := range msg.Answer {
+			if rr.Header().Rrtype == qtype {
+				return nil
+			}
+		}
+		neg = &NegativeAnswer{Kind: "NODATA"}
+	default:
+		return nil
+	}
+
+	for _, rr := range msg.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			neg.Zone = soa.Hdr.Name
+			neg.SOAMinimumTTL = soa.Minttl
+			neg.NegativeTTL = minTTL(soa.Hdr.Ttl, soa.Minttl)
+			break
+		}
+	}
+	return neg
+}
+
+// detectWildcard decides whether the qtype records in answers came from a
+// wildcard. Signed answers are definitive: an RRSIG whose label count is
+// lower than its owner's marks a wildcard expansion (RFC 4035 5.3.4).
+// Otherwise, when probe is set, a random sibling of the owner is queried and
+// an identical answer is taken as evidence of a wildcard at the parent.
+func detectWildcard(ctx context.Context, answers []dns.RR, qtype uint16, server string, opts queryOptions, probe bool) *WildcardInfo {
+	var owner string
+	for _, rr := range answers {
+		if rr.Header().Rrtype == qtype {
+			owner = rr.Header().Name
+			break
+		}
+	}
+	if owner == "" {
+		return nil
+	}
+
+	labels := dns.SplitDomainName(owner)
+	for _, rr := range answers {
+		sig, ok := rr.(*dns.RRSIG)
+		if !ok || sig.TypeCovered != qtype || !strings.EqualFold(sig.Hdr.Name, owner) {
+			continue
+		}
+		if int(sig.Labels) < len(labels) {
+			return &WildcardInfo{
+				Wildcard: true,
+				Owner:    "*." + dns.Fqdn(strings.Join(labels[len(labels)-int(sig.Labels):], ".")),
+				Evidence: "rrsig",
+			}
+		}
+		return &WildcardInfo{Wildcard: false, Evidence: "rrsig"}
+	}
+
+	if !probe || len(labels) < 2 || labels[0] == "*" {
+		return nil
+	}
+
+	parent := dns.Fqdn(strings.Join(labels[1:], "."))
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	probeResult, err := query(ctx, "wildcard-probe-"+hex.EncodeToString(nonce)+"."+parent, qtype, server, opts)
+	if err != nil {
+		return &WildcardInfo{Wildcard: false, Evidence: "probe"}
+	}
+
+	want, got := LookupResponse{}, LookupResponse{}
+	collectAnswers(&want, answers, qtype)
+	collectAnswers(&got, probeResult.Msg.Answer, qtype)
+	slices.Sort(want.Records)
+	slices.Sort(got.Records)
+	if len(got.Records) > 0 && slices.Equal(want.Records, got.Records) {
+		return &WildcardInfo{Wildcard: true, Owner: "*." + parent, Evidence: "probe"}
+	}
+	return &WildcardInfo{Wildcard: false, Evidence: "probe"}
+}
@@ -0,0 +1,62 @@
+package dnstool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestNegativeAnswer(t *testing.T) {
+	soa := testSOA("example.test.")
+	msg := func(rcode int, answer, ns []dns.RR) *dns.Msg {
+		return &dns.Msg{MsgHdr: dns.MsgHdr{Rcode: rcode}, Answer: answer, Ns: ns}
+	}
+	tests := []struct {
+		name string
+		msg  *dns.Msg
+		want *NegativeAnswer
+	}{
+		{name: "answer", msg: msg(dns.RcodeSuccess, []dns.RR{testA("web.example.test.", "192.0.2.10")}, nil)},
+		{name: "NXDOMAIN", msg: msg(dns.RcodeNameError, nil, []dns.RR{soa}), want: &NegativeAnswer{Kind: "NXDOMAIN", Zone: "example.test.", SOAMinimumTTL: 60, NegativeTTL: 60}},
+		{name: "NXDOMAIN without SOA", msg: msg(dns.RcodeNameError, nil, nil), want: &NegativeAnswer{Kind: "NXDOMAIN"}},
+		{name: "NODATA", msg: msg(dns.RcodeSuccess, nil, []dns.RR{soa}), want: &NegativeAnswer{Kind: "NODATA", Zone: "example.test.", SOAMinimumTTL: 60, NegativeTTL: 60}},
+		{name: "CNAME without the type", msg: msg(dns.RcodeSuccess, []dns.RR{&dns.CNAME{Hdr: dns.RR_Header{Name: "www.example.test.", Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 300}, Target: "web.example.test."}}, []dns.RR{soa}), want: &NegativeAnswer{Kind: "NODATA", Zone: "example.test.", SOAMinimumTTL: 60, NegativeTTL: 60}},
+		{name: "SERVFAIL", msg: msg(dns.RcodeServerFailure, nil, nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := negativeAnswer(tt.msg, dns.TypeA)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("negativeAnswer = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectWildcardFromRRSIG(t *testing.T) {
+	answer := testA("web.example.test.", "192.0.2.10")
+	sig := func(labels uint8) *dns.RRSIG {
+		return &dns.RRSIG{Hdr: dns.RR_Header{Name: "web.example.test.", Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 300}, TypeCovered: dns.TypeA, Labels: labels}
+	}
+	tests := []struct {
+		name    string
+		answers []dns.RR
+		want    *WildcardInfo
+	}{
+		{name: "unsigned"},
+		{name: "signed", answers: []dns.RR{answer, sig(3)}, want: &WildcardInfo{Evidence: "rrsig"}},
+		{name: "expanded", answers: []dns.RR{answer, sig(2)}, want: &WildcardInfo{Wildcard: true, Owner: "*.example.test.", Evidence: "rrsig"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.answers == nil {
+				tt.answers = []dns.RR{answer}
+			}
+			got := detectWildcard(context.Background(), tt.answers, dns.TypeA, "", defaultQueryOptions(), false)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("detectWildcard = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package dnstool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/miekg/dns"
+)
+
+type PropagationRequest struct {
+	Hostname   string `json:"hostname"`
+	Type       string `json:"type"`
+	Nameserver string `json:"nameserver"` // recursive resolver used to discover the zone and its servers
+}
+
+// AuthoritativeResult is one authoritative server's view of the record.
+type AuthoritativeResult struct {
+	Nameserver    string   `json:"nameserver"` // NS host name
+	Address       string   `json:"address"`    // address that was queried
+	Authoritative bool     `json:"authoritative"`
+	Records       []string `json:"records"`
+	Serial        uint32   `json:"serial,omitempty"`
+	Rcode         string   `json:"rcode,omitempty"`
+	LatencyMs     float64  `json:"latencyMs"`
+	Differs       bool     `json:"differs"` // records or serial disagree with the majority
+	Error         string   `json:"error,omitempty"`
+}
+
+type PropagationResponse struct {
+	Hostname   string                `json:"hostname"`
+	Type       string                `json:"type"`
+	Zone       string                `json:"zone,omitempty"`
+	Consistent bool                  `json:"consistent"`
+	Consensus  []string              `json:"consensus"`
+	Serials    []uint32              `json:"serials"` // distinct SOA serials seen, ascending
+	Results    []AuthoritativeResult `json:"results"`
+	Error      string                `json:"error,omitempty"`
+}
+
+func propagation(ctx context.Context, req PropagationRequest) (PropagationResponse, error) {
+	if req.Hostname == "" {
+		return PropagationResponse{}, toolserver.BadRequest("hostname is required")
+	}
+	if req.Type == "" {
+		req.Type = "A"
+	}
+	qtype, ok := supportedTypes[req.Type]
+	if !ok || req.Type == "PTR" {
+		return PropagationResponse{}, toolserver.BadRequest("unsupported record type: %s", req.Type)
+	}
+
+	resolver, err := resolveNameserver(req.Nameserver, "udp")
+	if err != nil {
+		return PropagationResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	name, _, err := normalizeHostname(req.Hostname)
+	if err != nil {
+		return PropagationResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	return checkPropagation(ctx, dns.Fqdn(name), req.Type, qtype, resolver), nil
+}
+
+func checkPropagation(ctx context.Context, name, recordType string, qtype uint16, resolver string) PropagationResponse {
+	resp := PropagationResponse{
+		Hostname:  name,
+		Type:      recordType,
+		Consensus: []string{},
+		Serials:   []uint32{},
+		Results:   []AuthoritativeResult{},
+	}
+
+	zone, err := findZone(ctx, name, resolver)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	resp.Zone = zone
+
+	servers, err := authoritativeServers(ctx, zone, resolver)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	resp.Results = make([]AuthoritativeResult, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func(i int, s AuthoritativeResult) {
+			defer wg.Done()
+			resp.Results[i] = queryAuthoritative(ctx, s, name, zone, qtype)
+		}(i, s)
+	}
+	wg.Wait()
+
+	summarizePropagation(&resp)
+	return resp
+}
+
+// findZone returns the apex of the zone containing name by walking up the
+// labels until one owns an SOA record. The authority section is not trusted
+// for this because a CNAME at name makes it describe the target's zone.
+func findZone(ctx context.Context, name, resolver string) (string, error) {
+	var lastErr error
+	for off, end := 0, false; !end; off, end = dns.NextLabel(name, off) {
+		candidate := name[off:]
+		result, err := query(ctx, candidate, dns.TypeSOA, resolver, defaultQueryOptions())
+		if result.Msg == nil {
+			return "", err
+		}
+		lastErr = err
+		for _, rr := range result.Msg.Answer {
+			if soa, ok := rr.(*dns.SOA); ok && dns.CanonicalName(soa.Hdr.Name) == dns.CanonicalName(candidate) {
+				return candidate, nil
+			}
+		}
+	}
+	if lastErr != nil {
+		return "", fmt.Errorf("no SOA record found for %s or its parents: %w", name, lastErr)
+	}
+	return "", fmt.Errorf("no SOA record found for %s or its parents", name)
+}
+
+// authoritativeServers lists every address of every NS host for zone.
+func authoritativeServers(ctx context.Context, zone, resolver string) ([]AuthoritativeResult, error) {
+	result, err := query(ctx, zone, dns.TypeNS, resolver, defaultQueryOptions())
+	if err != nil {
+		return nil, fmt.Errorf("NS lookup for %s: %w", zone, err)
+	}
+
+	var servers []AuthoritativeResult
+	for _, rr := range result.Msg.Answer {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		addrs, err := lookupAddresses(ctx, ns.Ns, resolver)
+		if err != nil || len(addrs) == 0 {
+			servers = append(servers, AuthoritativeResult{Nameserver: ns.Ns, Records: []string{}, Error: fmt.Sprintf("resolving %s: no addresses", ns.Ns)})
+			continue
+		}
+		for _, addr := range addrs {
+			servers = append(servers, AuthoritativeResult{Nameserver: ns.Ns, Address: net.JoinHostPort(addr, "53")})
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no NS records for %s", zone)
+	}
+	slices.SortFunc(servers, func(a, b AuthoritativeResult) int {
+		return strings.Compare(a.Nameserver+a.Address, b.Nameserver+b.Address)
+	})
+	return servers, nil
+}
+
+func lookupAddresses(ctx context.Context, host, resolver string) ([]string, error) {
+	var addrs []string
+	var lastErr error
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		result, err := query(ctx, host, qtype, resolver, defaultQueryOptions())
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, rr := range result.Msg.Answer {
+			switch v := rr.(type) {
+			case *dns.A:
+				addrs = append(addrs, v.A.String())
+			case *dns.AAAA:
+				addrs = append(addrs, v.AAAA.String())
+			}
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, lastErr
+	}
+	return addrs, nil
+}
+
+// queryAuthoritative asks one server directly, without recursion, for the
+// record and the zone's SOA serial.
+func queryAuthoritative(ctx context.Context, s AuthoritativeResult, name, zone string, qtype uint16) AuthoritativeResult {
+	if s.Address == "" {
+		return s
+	}
+	s.Records = []string{}
+	opts := defaultQueryOptions()
+	opts.NoRecursion = true
+
+	result, err := query(ctx, name, qtype, s.Address, opts)
+	s.LatencyMs = float64(result.Duration.Microseconds()) / 1000
+	if result.Msg != nil {
+		s.Rcode = dns.RcodeToString[result.Msg.Rcode]
+		s.Authoritative = result.Msg.Authoritative
+	}
+	if err != nil && result.Msg == nil {
+		s.Error = err.Error()
+		return s
+	}
+	if result.Msg.Rcode == dns.RcodeSuccess {
+		answers := LookupResponse{Records: []string{}}
+		collectAnswers(&answers, result.Msg.Answer, qtype)
+		s.Records = answers.Records
+		slices.Sort(s.Records)
+	}
+
+	if soaResult, err := query(ctx, zone, dns.TypeSOA, s.Address, opts); err == nil {
+		for _, rr := range soaResult.Msg.Answer {
+			if soa, ok := rr.(*dns.SOA); ok {
+				s.Serial = soa.Serial
+			}
+		}
+	}
+	if !s.Authoritative {
+		s.Error = "server did not answer authoritatively (lame delegation)"
+	}
+	return s
+}
+
+// summarizePropagation marks servers whose answer or serial differs from the
+// most common one. A server that failed always counts as differing.
+func summarizePropagation(resp *PropagationResponse) {
+	answerCounts := make(map[string]int)
+	serialCounts := make(map[uint32]int)
+	var consensusKey string
+	var consensusSerial uint32
+	for _, res := range resp.Results {
+		if res.Error != "" {
+			continue
+		}
+		key := strings.Join(res.Records, "\n")
+		answerCounts[key]++
+		if answerCounts[key] > answerCounts[consensusKey] || (answerCounts[key] == answerCounts[consensusKey] && key < consensusKey) {
+			consensusKey = key
+		}
+		if res.Serial != 0 {
+			if serialCounts[res.Serial] == 0 {
+				resp.Serials = append(resp.Serials, res.Serial)
+			}
+			serialCounts[res.Serial]++
+			if serialCounts[res.Serial] > serialCounts[consensusSerial] || (serialCounts[res.Serial] == serialCounts[consensusSerial] && res.Serial > consensusSerial) {
+				consensusSerial = res.Serial
+			}
+		}
+	}
+	slices.Sort(resp.Serials)
+	if consensusKey != "" {
+		resp.Consensus = strings.Split(consensusKey, "\n")
+	}
+
+	resp.Consistent = true
+	for i := range resp.Results {
+		res := &resp.Results[i]
+		if res.Error != "" || strings.Join(res.Records, "\n") != consensusKey || (res.Serial != 0 && res.Serial != consensusSerial) {
+			res.Differs = true
+			resp.Consistent = false
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package dnstool
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSummarizePropagation(t *testing.T) {
+	result := func(ns string, serial uint32, records ...string) AuthoritativeResult {
+		return AuthoritativeResult{Nameserver: ns, Authoritative: true, Serial: serial, Records: records}
+	}
+	tests := []struct {
+		name           string
+		results        []AuthoritativeResult
+		wantConsistent bool
+		wantConsensus  []string
+		wantSerials    []uint32
+		wantDiffers    []bool
+	}{
+		{
+			name:           "in sync",
+			results:        []AuthoritativeResult{result("a.", 7, "192.0.2.1"), result("b.", 7, "192.0.2.1")},
+			wantConsistent: true,
+			wantConsensus:  []string{"192.0.2.1"},
+			wantSerials:    []uint32{7},
+			wantDiffers:    []bool{false, false},
+		},
+		{
+			name:          "stale server",
+			results:       []AuthoritativeResult{result("a.", 8, "192.0.2.2"), result("b.", 7, "192.0.2.1"), result("c.", 8, "192.0.2.2")},
+			wantConsensus: []string{"192.0.2.2"},
+			wantSerials:   []uint32{7, 8},
+			wantDiffers:   []bool{false, true, false},
+		},
+		{
+			name:          "failed server",
+			results:       []AuthoritativeResult{result("a.", 7, "192.0.2.1"), {Nameserver: "b.", Error: "i/o timeout"}},
+			wantConsensus: []string{"192.0.2.1"},
+			wantSerials:   []uint32{7},
+			wantDiffers:   []bool{false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := PropagationResponse{Consensus: []string{}, Serials: []uint32{}, Results: tt.results}
+			summarizePropagation(&resp)
+			var differs []bool
+			for _, r := range resp.Results {
+				differs = append(differs, r.Differs)
+			}
+			if resp.Consistent != tt.wantConsistent || !slices.Equal(resp.Consensus, tt.wantConsensus) || !slices.Equal(resp.Serials, tt.wantSerials) || !slices.Equal(differs, tt.wantDiffers) {
+				t.Errorf("consistent %v, consensus %v, serials %v, differs %v; want %v, %v, %v, %v",
+					resp.Consistent, resp.Consensus, resp.Serials, differs, tt.wantConsistent, tt.wantConsensus, tt.wantSerials, tt.wantDiffers)
+			}
+		})
+	}
+}
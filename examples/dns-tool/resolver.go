@@ -0,0 +1,318 @@
+package dnstool
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/miekg/dns"
+)
+
+const (
+	resolvConfPath = "/etc/resolv.conf"
+
+	// Defaults mirror glibc's resolv.conf defaults (timeout:5 attempts:2).
+	defaultTimeout = 5 * time.Second
+	defaultRetries = 1
+
+	// Endpoints used for encrypted transports when no nameserver is given.
+	defaultDoHEndpoint = "https://cloudflare-dns.com/dns-query"
+	defaultDoTEndpoint = "1.1.1.1:853"
+	defaultDoTName     = "cloudflare-dns.com"
+
+	dohMediaType = "application/dns-message"
+)
+
+// dohTransport carries DNS-over-HTTPS queries; it is shared so connections
+// are reused across queries.
+var dohTransport = toolserver.InstrumentTransport("doh", nil)
+
+// queryOptions controls how a query is sent. Build it with newQueryOptions
+// or defaultQueryOptions so defaults are applied.
+type queryOptions struct {
+	Timeout       time.Duration
+	Retries       int
+	Transport     string // "udp" (default), "tcp", "dot" or "doh"
+	NoTCPFallback bool   // keep a truncated UDP answer instead of retrying over TCP
+	DNSSEC        bool   // set the DO bit so signatures are returned
+	TLSServerName string // certificate name to verify for DoT; defaults to the server host
+	NoRecursion   bool   // clear RD, for querying authoritative servers directly
+}
+
+// queryResult is a reply plus the details of how it was obtained.
+type queryResult struct {
+	Msg       *dns.Msg
+	Transport string // transport that produced Msg
+	Attempts  int
+	Truncated bool          // the UDP reply had the TC bit set
+	RTT       time.Duration // round trip of the exchange that produced Msg
+	Duration  time.Duration // wall time for the whole query, retries included
+	Size      int           // wire size of Msg in bytes
+}
+
+// newQueryOptions builds options from request fields, applying defaults and
+// validating the retries and transport. A nil retries is the default; an
+// explicit 0 sends a single attempt.
+func newQueryOptions(timeoutMs int, retries *int, transport string, tcpFallback *bool) (queryOptions, error) {
+	opts := queryOptions{
+		Timeout:   defaultTimeout,
+		Retries:   defaultRetries,
+		Transport: transport,
+	}
+	if timeoutMs > 0 {
+		opts.Timeout = time.Duration(timeoutMs) * time.Millisecond
+	}
+	if retries != nil {
+		if *retries < 0 {
+			return opts, fmt.Errorf("retries must not be negative, got: %d", *retries)
+		}
+		opts.Retries = *retries
+	}
+	if tcpFallback != nil {
+		opts.NoTCPFallback = !*tcpFallback
+	}
+	switch opts.Transport {
+	case "":
+		opts.Transport = "udp"
+	case "udp", "tcp", "dot", "doh":
+	default:
+		return opts, fmt.Errorf("unsupported transport: %s", transport)
+	}
+	return opts, nil
+}
+
+// defaultQueryOptions is UDP with TCP fallback and the default timeout and
+// retries.
+func defaultQueryOptions() queryOptions {
+	opts, _ := newQueryOptions(0, nil, "", nil)
+	return opts
+}
+
+// systemNameserver returns the first nameserver from resolv.conf as host:port.
+func systemNameserver() (string, error) {
+	conf, err := dns.ClientConfigFromFile(resolvConfPath)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", resolvConfPath, err)
+	}
+	if len(conf.Servers) == 0 {
+		return "", fmt.Errorf("no nameservers in %s", resolvConfPath)
+	}
+	return net.JoinHostPort(conf.Servers[0], conf.Port), nil
+}
+
+// nameserverReady is the readiness check for the system nameserver: it
+// must answer a query, with any response code.
+func nameserverReady(ctx context.Context) error {
+	server, err := systemNameserver()
+	if err != nil {
+		return err
+	}
+	opts := defaultQueryOptions()
+	opts.Retries = 0
+	_, err = query(ctx, ".", dns.TypeNS, server, opts)
+	return err
+}
+
+// nameserverDiagnostics reports the system nameserver and the lookup cache
+// in /healthz/verbose.
+func nameserverDiagnostics() map[string]any {
+	details := map[string]any{"cache": lookupCache.stats()}
+	if server, err := systemNameserver(); err == nil {
+		details["server"] = server
+	}
+	return details
+}
+
+// resolveNameserver normalizes a requested nameserver for transport: a DoH
+// URL, or host:port with the transport's default port. Without a nameserver,
+// udp/tcp use the system resolver and dot/doh a public encrypted resolver.
+func resolveNameserver(nameserver, transport string) (string, error) {
+	switch transport {
+	case "doh":
+		if nameserver == "" {
+			return defaultDoHEndpoint, nil
+		}
+		u, err := url.Parse(nameserver)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return "", fmt.Errorf("DoH nameserver must be an https URL, got: %s", nameserver)
+		}
+		return nameserver, nil
+	case "dot":
+		if nameserver == "" {
+			return defaultDoTEndpoint, nil
+		}
+		if _, _, err := net.SplitHostPort(nameserver); err == nil {
+			return nameserver, nil
+		}
+		return net.JoinHostPort(nameserver, "853"), nil
+	}
+
+	if nameserver == "" {
+		return systemNameserver()
+	}
+	if _, _, err := net.SplitHostPort(nameserver); err == nil {
+		return nameserver, nil
+	}
+	return net.JoinHostPort(nameserver, "53"), nil
+}
+
+// query sends a query for name/qtype to server, recursive unless
+// opts.NoRecursion is set. Timeouts and network errors are retried up to
+// opts.Retries times; a truncated UDP reply is re-sent over TCP unless
+// fallback is disabled. Non-success response codes are reported as errors
+// alongside the reply. Every call is recorded in the /metrics histograms and
+// traced as a span.
+func query(ctx context.Context, name string, qtype uint16, server string, opts queryOptions) (result *queryResult, err error) {
+	start := time.Now()
+	ctx, span := toolserver.StartSpan(ctx, "dns "+dns.TypeToString[qtype])
+	span.SetAttr("dns.question.name", dns.Fqdn(name))
+	span.SetAttr("dns.question.type", dns.TypeToString[qtype])
+	span.SetAttr("server.address", server)
+	defer func() {
+		span.SetAttr("network.transport", result.Transport)
+		span.SetAttr("dns.attempts", result.Attempts)
+		if result.Msg != nil {
+			span.SetAttr("dns.response_code", dns.RcodeToString[result.Msg.Rcode])
+		}
+		span.SetError(err)
+		span.End()
+
+		result.Duration = time.Since(start)
+		if result.Msg != nil {
+			result.Size = result.Msg.Len()
+		}
+		observeQuery(server, result)
+	}()
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = !opts.NoRecursion
+	if opts.DNSSEC {
+		// Ask for RRSIGs, and set CD so a validating upstream hands back
+		// bogus data for us to classify instead of a bare SERVFAIL.
+		msg.SetEdns0(dns.DefaultMsgSize, true)
+		msg.CheckingDisabled = true
+	}
+
+	result, err = exchange(ctx, msg, server, opts.Transport, opts)
+	if err != nil {
+		return result, err
+	}
+
+	if result.Transport == "udp" && result.Msg.Truncated {
+		result.Truncated = true
+		if !opts.NoTCPFallback {
+			attempts := result.Attempts
+			tcpResult, err := exchange(ctx, msg, server, "tcp", opts)
+			if err != nil {
+				return result, fmt.Errorf("TCP fallback after truncated UDP reply: %w", err)
+			}
+			tcpResult.Truncated = true
+			tcpResult.Attempts += attempts
+			result = tcpResult
+		}
+	}
+
+	if result.Msg.Rcode != dns.RcodeSuccess {
+		return result, fmt.Errorf("lookup %s: %s", name, dns.RcodeToString[result.Msg.Rcode])
+	}
+	return result, nil
+}
+
+func exchange(ctx context.Context, msg *dns.Msg, server, transport string, opts queryOptions) (*queryResult, error) {
+	result := &queryResult{Transport: transport}
+
+	var send func() (*dns.Msg, time.Duration, error)
+	switch transport {
+	case "doh":
+		send = func() (*dns.Msg, time.Duration, error) { return exchangeDoH(ctx, msg, server, opts.Timeout) }
+	case "dot":
+		serverName := opts.TLSServerName
+		if serverName == "" && server == defaultDoTEndpoint {
+			serverName = defaultDoTName
+		} else if serverName == "" {
+			serverName, _, _ = net.SplitHostPort(server)
+		}
+		client := &dns.Client{
+			Net:       "tcp-tls",
+			Timeout:   opts.Timeout,
+			TLSConfig: &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12, RootCAs: toolserver.RootCAs()},
+		}
+		send = func() (*dns.Msg, time.Duration, error) { return client.ExchangeContext(ctx, msg, server) }
+	default:
+		client := &dns.Client{Net: transport, Timeout: opts.Timeout}
+		send = func() (*dns.Msg, time.Duration, error) { return client.ExchangeContext(ctx, msg, server) }
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		result.Attempts++
+		reply, rtt, err := send()
+		if err == nil {
+			result.Msg = reply
+			result.RTT = rtt
+			return result, nil
+		}
+		lastErr = err
+		if !retryable(err) {
+			break
+		}
+	}
+	return result, fmt.Errorf("%s query to %s failed after %d attempt(s): %w", transport, server, result.Attempts, lastErr)
+}
+
+// exchangeDoH sends msg as an RFC 8484 POST with an application/dns-message
+// body.
+func exchangeDoH(ctx context.Context, msg *dns.Msg, endpoint string, timeout time.Duration) (*dns.Msg, time.Duration, error) {
+	// The RFC recommends ID 0 so responses are HTTP-cacheable.
+	m := msg.Copy()
+	m.Id = 0
+	packed, err := m.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(packed))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", dohMediaType)
+	req.Header.Set("Accept", dohMediaType)
+
+	client := &http.Client{Timeout: timeout, Transport: dohTransport}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("DoH server returned HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, 0, err
+	}
+	rtt := time.Since(start)
+
+	reply := new(dns.Msg)
+	if err := reply.Unpack(body); err != nil {
+		return nil, rtt, fmt.Errorf("decoding DoH response: %w", err)
+	}
+	reply.Id = msg.Id
+	return reply, rtt, nil
+}
+
+func retryable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,42 @@
+package dnstool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewQueryOptions(t *testing.T) {
+	zero, two, negative := 0, 2, -1
+	tests := []struct {
+		name        string
+		timeoutMs   int
+		retries     *int
+		transport   string
+		wantTimeout time.Duration
+		wantRetries int
+		wantErr     bool
+	}{
+		{name: "defaults", wantTimeout: defaultTimeout, wantRetries: defaultRetries},
+		{name: "no retries", retries: &zero, wantTimeout: defaultTimeout, wantRetries: 0},
+		{name: "two retries", timeoutMs: 250, retries: &two, wantTimeout: 250 * time.Millisecond, wantRetries: 2},
+		{name: "negative retries", retries: &negative, wantErr: true},
+		{name: "unsupported transport", transport: "quic", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := newQueryOptions(tt.timeoutMs, tt.retries, tt.transport, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("newQueryOptions = %+v, want an error", opts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opts.Timeout != tt.wantTimeout || opts.Retries != tt.wantRetries || opts.Transport != "udp" {
+				t.Errorf("newQueryOptions = %+v, want timeout %v, %d retries over udp", opts, tt.wantTimeout, tt.wantRetries)
+			}
+		})
+	}
+}
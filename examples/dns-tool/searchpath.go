@@ -0,0 +1,170 @@
+package dnstool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/miekg/dns"
+)
+
+// kubeletNdots is the ndots value the kubelet writes for ClusterFirst pods.
+const kubeletNdots = 5
+
+type SearchPathRequest struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// Search and Ndots describe the resolver config to emulate. When Search
+	// is empty, Namespace builds a ClusterFirst pod's search list; otherwise
+	// this pod's own /etc/resolv.conf is used.
+	Search        []string `json:"search"`
+	Ndots         *int     `json:"ndots"`
+	Namespace     string   `json:"namespace"`
+	ClusterDomain string   `json:"clusterDomain"`
+
+	// Execute sends each candidate query in order and stops at the first
+	// one that returns records, like the libc resolver.
+	Execute    bool   `json:"execute"`
+	Nameserver string `json:"nameserver"`
+}
+
+type SearchQuery struct {
+	Name      string   `json:"name"`
+	Rcode     string   `json:"rcode,omitempty"`
+	Records   []string `json:"records,omitempty"`
+	LatencyMs float64  `json:"latencyMs,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type SearchPathResponse struct {
+	Name       string        `json:"name"`
+	Type       string        `json:"type"`
+	Search     []string      `json:"search"`
+	Ndots      int           `json:"ndots"`
+	Source     string        `json:"source"` // request, namespace, or resolv.conf
+	Explain    string        `json:"explain"`
+	Queries    []SearchQuery `json:"queries"`
+	ResolvedAs string        `json:"resolvedAs,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+func searchPath(ctx context.Context, req SearchPathRequest) (SearchPathResponse, error) {
+	if req.Name == "" {
+		return SearchPathResponse{}, toolserver.BadRequest("name is required")
+	}
+	if req.Type == "" {
+		req.Type = "A"
+	}
+	qtype, ok := supportedTypes[req.Type]
+	if !ok {
+		return SearchPathResponse{}, toolserver.BadRequest("unsupported record type: %s", req.Type)
+	}
+
+	name, _, err := normalizeHostname(req.Name)
+	if err != nil {
+		return SearchPathResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	resp := SearchPathResponse{Name: req.Name, Type: req.Type}
+	switch {
+	case len(req.Search) > 0:
+		resp.Source = "request"
+		resp.Search = req.Search
+		resp.Ndots = 1
+	case req.Namespace != "":
+		domain := req.ClusterDomain
+		if domain == "" {
+			domain = defaultClusterDomain
+		}
+		domain = strings.TrimSuffix(domain, ".")
+		resp.Source = "namespace"
+		resp.Search = []string{req.Namespace + ".svc." + domain, "svc." + domain, domain}
+		resp.Ndots = kubeletNdots
+	default:
+		conf, err := dns.ClientConfigFromFile(resolvConfPath)
+		if err != nil {
+			return SearchPathResponse{}, fmt.Errorf("reading %s: %v", resolvConfPath, err)
+		}
+		resp.Source = "resolv.conf"
+		resp.Search = conf.Search
+		resp.Ndots = conf.Ndots
+		if resp.Search == nil {
+			resp.Search = []string{}
+		}
+	}
+	if req.Ndots != nil {
+		resp.Ndots = *req.Ndots
+	}
+
+	candidates, explain := searchCandidates(name, resp.Search, resp.Ndots)
+	resp.Explain = explain
+	resp.Queries = make([]SearchQuery, 0, len(candidates))
+	for _, c := range candidates {
+		resp.Queries = append(resp.Queries, SearchQuery{Name: c})
+	}
+
+	if req.Execute {
+		server, err := resolveNameserver(req.Nameserver, "udp")
+		if err != nil {
+			resp.Error = err.Error()
+			return resp, nil
+		}
+		for i := range resp.Queries {
+			q := &resp.Queries[i]
+			if runSearchQuery(ctx, q, qtype, server) {
+				resp.ResolvedAs = q.Name
+				resp.Queries = resp.Queries[:i+1]
+				break
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// searchCandidates returns the fully-qualified names the stub resolver tries,
+// in order. A trailing dot disables the search list; a name with at least
+// ndots dots is tried as-is first; otherwise every search domain is tried
+// before the bare name.
+func searchCandidates(name string, search []string, ndots int) ([]string, string) {
+	if dns.IsFqdn(name) {
+		return []string{name}, "name ends with a dot, so it is absolute and the search list is skipped"
+	}
+
+	expanded := make([]string, 0, len(search))
+	for _, domain := range search {
+		expanded = append(expanded, dns.Fqdn(name+"."+strings.TrimSuffix(domain, ".")))
+	}
+
+	dots := strings.Count(name, ".")
+	if dots >= ndots {
+		explain := fmt.Sprintf("name has %d dot(s), at least ndots=%d, so it is tried as-is before the search list", dots, ndots)
+		return append([]string{dns.Fqdn(name)}, expanded...), explain
+	}
+	explain := fmt.Sprintf("name has %d dot(s), fewer than ndots=%d, so each search domain is tried before the name as-is", dots, ndots)
+	return append(expanded, dns.Fqdn(name)), explain
+}
+
+// runSearchQuery resolves q.Name and reports whether the resolver would stop
+// here. NXDOMAIN and empty answers move on to the next candidate.
+func runSearchQuery(ctx context.Context, q *SearchQuery, qtype uint16, server string) bool {
+	result, err := query(ctx, q.Name, qtype, server, defaultQueryOptions())
+	q.LatencyMs = float64(result.Duration.Microseconds()) / 1000
+	if result.Msg != nil {
+		q.Rcode = dns.RcodeToString[result.Msg.Rcode]
+	}
+	if err != nil {
+		if q.Rcode == "" {
+			q.Error = err.Error()
+		}
+		return false
+	}
+	for _, rr := range result.Msg.Answer {
+		if rr.Header().Rrtype == qtype {
+			q.Records = append(q.Records, strings.TrimPrefix(rr.String(), rr.Header().String()))
+		}
+	}
+	return len(q.Records) > 0
+}
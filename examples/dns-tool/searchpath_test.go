@@ -0,0 +1,45 @@
+package dnstool
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSearchCandidates(t *testing.T) {
+	search := []string{"web.svc.cluster.local", "svc.cluster.local", "cluster.local"}
+	tests := []struct {
+		name  string
+		ndots int
+		want  []string
+	}{
+		{name: "api", ndots: 5, want: []string{"api.web.svc.cluster.local.", "api.svc.cluster.local.", "api.cluster.local.", "api."}},
+		{name: "api.other", ndots: 5, want: []string{"api.other.web.svc.cluster.local.", "api.other.svc.cluster.local.", "api.other.cluster.local.", "api.other."}},
+		{name: "example.com", ndots: 1, want: []string{"example.com.", "example.com.web.svc.cluster.local.", "example.com.svc.cluster.local.", "example.com.cluster.local."}},
+		{name: "a.b.c.d.e", ndots: 5, want: []string{"a.b.c.d.e.web.svc.cluster.local.", "a.b.c.d.e.svc.cluster.local.", "a.b.c.d.e.cluster.local.", "a.b.c.d.e."}},
+		{name: "a.b.c.d.e.f", ndots: 5, want: []string{"a.b.c.d.e.f.", "a.b.c.d.e.f.web.svc.cluster.local.", "a.b.c.d.e.f.svc.cluster.local.", "a.b.c.d.e.f.cluster.local."}},
+		{name: "example.com.", ndots: 5, want: []string{"example.com."}},
+		{name: "api", ndots: 0, want: []string{"api.", "api.web.svc.cluster.local.", "api.svc.cluster.local.", "api.cluster.local."}},
+	}
+	for _, tt := range tests {
+		got, _ := searchCandidates(tt.name, search, tt.ndots)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("searchCandidates(%q, ndots=%d) = %v, want %v", tt.name, tt.ndots, got, tt.want)
+		}
+	}
+}
+
+func TestSearchPathExecute(t *testing.T) {
+	server, _ := zoneNameserver(t, testSOA("cluster.local."), testA("api.svc.cluster.local.", "10.96.0.10"))
+	resp, err := searchPath(context.Background(), SearchPathRequest{Name: "api", Namespace: "web", Execute: true, Nameserver: server})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Ndots != kubeletNdots || resp.ResolvedAs != "api.svc.cluster.local." {
+		t.Errorf("ndots %d, resolved as %q; want %d, api.svc.cluster.local.", resp.Ndots, resp.ResolvedAs, kubeletNdots)
+	}
+	// The resolver stops at the first candidate with records.
+	if len(resp.Queries) != 2 || resp.Queries[0].Rcode != "NXDOMAIN" || !slices.Equal(resp.Queries[1].Records, []string{"10.96.0.10"}) {
+		t.Errorf("queries = %+v", resp.Queries)
+	}
+}
@@ -0,0 +1,186 @@
+package hashtool
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// ChecksumsRequest represents the incoming request body for /verify-checksums
+type ChecksumsRequest struct {
+	// Checksums is the content of a sha256sums-style file: "<digest>  <name>"
+	// (a "*" before the name marks binary mode) or BSD-style
+	// "SHA256 (<name>) = <digest>" lines.
+	Checksums string `json:"checksums"`
+	// Algorithm defaults to the one implied by the digest length (md5, sha1,
+	// sha256 or sha512) or named in BSD-style lines.
+	Algorithm string `json:"algorithm,omitempty"`
+
+	Files         map[string]string `json:"files,omitempty"`          // name -> content
+	InputEncoding string            `json:"input_encoding,omitempty"` // encoding of files values: utf8 or base64
+	URLs          map[string]string `json:"urls,omitempty"`           // name -> URL
+	// BaseURL resolves names found in neither files nor urls, e.g. a
+	// release download directory.
+	BaseURL  string `json:"base_url,omitempty"`
+	MaxBytes int64  `json:"max_bytes,omitempty"` // per URL, default 1 GiB
+}
+
+// ChecksumResult is the outcome for one line of the checksum file.
+type ChecksumResult struct {
+	Name      string `json:"name"`
+	Algorithm string `json:"algorithm"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual,omitempty"`
+	Status    string `json:"status"` // pass, fail, missing, or error
+	Error     string `json:"error,omitempty"`
+}
+
+// ChecksumsResponse represents the outgoing response body for /verify-checksums
+type ChecksumsResponse struct {
+	AllPassed bool             `json:"all_passed"`
+	Passed    int              `json:"passed"`
+	Failed    int              `json:"failed"`
+	Missing   int              `json:"missing"`
+	Errors    int              `json:"errors"`
+	Results   []ChecksumResult `json:"results"`
+}
+
+type checksumEntry struct {
+	name      string
+	algorithm string
+	digest    string
+}
+
+var bsdChecksumLine = regexp.MustCompile(`^([A-Za-z0-9-]+) \((.+)\) = ([0-9a-fA-F]+)$`)
+
+func verifyChecksums(ctx context.Context, req ChecksumsRequest) (ChecksumsResponse, error) {
+	if req.Checksums == "" {
+		return ChecksumsResponse{}, toolserver.BadRequest("checksums is required")
+	}
+
+	entries, err := parseChecksums(req.Checksums, req.Algorithm)
+	if err != nil {
+		return ChecksumsResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	resp := ChecksumsResponse{Results: []ChecksumResult{}}
+	for _, e := range entries {
+		res := ChecksumResult{Name: e.name, Algorithm: e.algorithm, Expected: e.digest}
+
+		hreq, ok := checksumSource(req, e.name)
+		if !ok {
+			res.Status = "missing"
+			resp.Missing++
+			resp.Results = append(resp.Results, res)
+			continue
+		}
+
+		sums, _, err := computeDigests(ctx, hreq, []string{e.algorithm})
+		switch {
+		case err != nil:
+			res.Status = "error"
+			res.Error = err.Error()
+			resp.Errors++
+		default:
+			res.Actual = hex.EncodeToString(sums[e.algorithm])
+			if res.Actual == e.digest {
+				res.Status = "pass"
+				resp.Passed++
+			} else {
+				res.Status = "fail"
+				resp.Failed++
+			}
+		}
+		resp.Results = append(resp.Results, res)
+	}
+	resp.AllPassed = len(entries) > 0 && resp.Passed == len(entries)
+
+	return resp, nil
+}
+
+// checksumSource finds the content for name: inline files first, then urls,
+// then base_url.
+func checksumSource(req ChecksumsRequest, name string) (HashRequest, bool) {
+	if content, ok := req.Files[name]; ok {
+		return HashRequest{Input: content, InputEncoding: req.InputEncoding}, true
+	}
+	if u, ok := req.URLs[name]; ok {
+		return HashRequest{URL: u, MaxBytes: req.MaxBytes}, true
+	}
+	if req.BaseURL != "" {
+		base, err := url.Parse(strings.TrimSuffix(req.BaseURL, "/") + "/")
+		if err == nil {
+			if ref, err := url.Parse(name); err == nil {
+				return HashRequest{URL: base.ResolveReference(ref).String(), MaxBytes: req.MaxBytes}, true
+			}
+		}
+	}
+	return HashRequest{}, false
+}
+
+// parseChecksums reads GNU coreutils and BSD checksum lines, skipping blanks
+// and comments.
+func parseChecksums(content, algorithm string) ([]checksumEntry, error) {
+	var entries []checksumEntry
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var e checksumEntry
+		if m := bsdChecksumLine.FindStringSubmatch(line); m != nil {
+			e = checksumEntry{name: m[2], algorithm: strings.ToLower(m[1]), digest: m[3]}
+		} else {
+			digest, name, ok := strings.Cut(line, " ")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected \"<digest>  <name>\"", lineNo)
+			}
+			name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+			e = checksumEntry{name: name, digest: digest}
+		}
+		e.digest = strings.ToLower(e.digest)
+		if _, err := hex.DecodeString(e.digest); err != nil {
+			return nil, fmt.Errorf("line %d: digest is not hex", lineNo)
+		}
+
+		if algorithm != "" {
+			e.algorithm = algorithm
+		} else if e.algorithm == "" {
+			e.algorithm = algorithmForLength(len(e.digest))
+		}
+		if _, err := newHash(e.algorithm); err != nil {
+			return nil, fmt.Errorf("line %d: cannot determine algorithm: %v", lineNo, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no checksum lines found")
+	}
+	return entries, nil
+}
+
+func algorithmForLength(hexLen int) string {
+	switch hexLen {
+	case 32:
+		return "md5"
+	case 40:
+		return "sha1"
+	case 64:
+		return "sha256"
+	case 128:
+		return "sha512"
+	default:
+		return ""
+	}
+}
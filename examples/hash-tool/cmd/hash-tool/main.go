@@ -0,0 +1,22 @@
+// Command hash-tool serves hash-tool on its own. kube-mcp-tools serves it together
+// with the other example tools.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	hashtool "github.com/atippey/kube-mcp/examples/hash-tool"
+)
+
+func main() {
+	s, err := hashtool.New()
+	if err != nil {
+		slog.Error("starting hash-tool", "err", err)
+		os.Exit(1)
+	}
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,123 @@
+package hashtool
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// EncodeRequest represents the incoming request body for /encode
+type EncodeRequest struct {
+	Input     string `json:"input"`
+	Encoding  string `json:"encoding"`  // base64, base64url, hex, url
+	Direction string `json:"direction"` // encode (default) or decode
+}
+
+// EncodeResponse represents the outgoing response body for /encode
+type EncodeResponse struct {
+	Output    string `json:"output"`
+	Encoding  string `json:"encoding"`
+	Direction string `json:"direction"`
+	// Binary is set when decoded bytes are not valid UTF-8; Output then holds
+	// them as standard base64 so nothing is lost in the JSON response.
+	Binary bool `json:"binary,omitempty"`
+}
+
+func encode(ctx context.Context, req EncodeRequest) (EncodeResponse, error) {
+	if req.Input == "" {
+		return EncodeResponse{}, toolserver.BadRequest("input is required")
+	}
+	if req.Encoding == "" {
+		return EncodeResponse{}, toolserver.BadRequest("encoding is required")
+	}
+	if req.Direction == "" {
+		req.Direction = "encode"
+	}
+
+	resp := EncodeResponse{Encoding: req.Encoding, Direction: req.Direction}
+	switch req.Direction {
+	case "encode":
+		out, err := encodeString(req.Input, req.Encoding)
+		if err != nil {
+			return EncodeResponse{}, toolserver.BadRequest("%v", err)
+		}
+		resp.Output = out
+	case "decode":
+		out, err := decodeString(req.Input, req.Encoding)
+		if err != nil {
+			return EncodeResponse{}, toolserver.BadRequest("%v", err)
+		} else if utf8.Valid(out) {
+			resp.Output = string(out)
+		} else {
+			resp.Output = base64.StdEncoding.EncodeToString(out)
+			resp.Binary = true
+		}
+	default:
+		return EncodeResponse{}, toolserver.BadRequest("unsupported direction: %s", req.Direction)
+	}
+
+	return resp, nil
+}
+
+func encodeString(input, encoding string) (string, error) {
+	data := []byte(input)
+	switch encoding {
+	case "base64":
+		return base64.StdEncoding.EncodeToString(data), nil
+	case "base64url":
+		return base64.RawURLEncoding.EncodeToString(data), nil
+	case "hex":
+		return hex.EncodeToString(data), nil
+	case "url":
+		return url.QueryEscape(input), nil
+	default:
+		return "", fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+}
+
+// decodeString accepts base64 with or without padding, since Secret values
+// copied from tooling come both ways, and surrounding whitespace.
+func decodeString(input, encoding string) ([]byte, error) {
+	input = strings.TrimSpace(input)
+	switch encoding {
+	case "base64":
+		return decodeBase64(input, base64.StdEncoding, base64.RawStdEncoding)
+	case "base64url":
+		return decodeBase64(input, base64.URLEncoding, base64.RawURLEncoding)
+	case "hex":
+		out, err := hex.DecodeString(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex: %v", err)
+		}
+		return out, nil
+	case "url":
+		out, err := url.QueryUnescape(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL encoding: %v", err)
+		}
+		return []byte(out), nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+}
+
+func decodeBase64(input string, padded, raw *base64.Encoding) ([]byte, error) {
+	if strings.HasSuffix(input, "=") {
+		out, err := padded.DecodeString(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64: %v", err)
+		}
+		return out, nil
+	}
+	out, err := raw.DecodeString(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %v", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+package hashtool
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+const (
+	defaultMaxFetchBytes = 1 << 30 // 1 GiB
+	defaultFetchTimeout  = 60 * time.Second
+
+	// fetchOperationTimeout bounds operations that may hash a url, in place
+	// of the server's default, so that timeout_seconds governs the fetch.
+	fetchOperationTimeout = 10 * time.Minute
+)
+
+// fetchClient fetches url inputs; its requests are counted in /metrics.
+var fetchClient = &http.Client{Transport: toolserver.InstrumentTransport("fetch", nil)}
+
+// hashURL streams the body at rawURL into w and returns the number of bytes
+// read. Bodies larger than maxBytes are rejected rather than truncated, so a
+// digest is never reported for partial content. It fails with
+// INVALID_ARGUMENT for a bad url or an oversized body, UPSTREAM_ERROR if
+// the fetch fails or is not answered with 200 OK, and UPSTREAM_TIMEOUT if
+// it runs past the timeout.
+func hashURL(ctx context.Context, rawURL string, w io.Writer, maxBytes int64, timeoutSeconds int) (int64, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return 0, toolserver.BadRequest("url must be an http or https URL")
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxFetchBytes
+	}
+	timeout := defaultFetchTimeout
+	if timeoutSeconds > 0 {
+		timeout = time.Duration(timeoutSeconds) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, toolserver.BadRequest("%v", err)
+	}
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		return 0, toolserver.UpstreamError(err, "fetching url")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, toolserver.NewError(toolserver.CodeUpstreamError, "fetching url: HTTP %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxBytes {
+		return 0, toolserver.BadRequest("content length %d exceeds max_bytes %d", resp.ContentLength, maxBytes)
+	}
+
+	n, err := io.Copy(w, io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return n, toolserver.UpstreamError(err, "reading url")
+	}
+	if n > maxBytes {
+		return n, toolserver.BadRequest("body exceeds max_bytes %d", maxBytes)
+	}
+	return n, nil
+}
@@ -0,0 +1,195 @@
+package hashtool
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// ImageMatchRequest represents the incoming request body for /verify-image.
+// The content is given with the usual /hash input options; algorithm, mode
+// and the output options are ignored since OCI digests fix them.
+type ImageMatchRequest struct {
+	HashRequest
+	Image    string `json:"image"`              // e.g. ghcr.io/atippey/hash-tool:latest
+	Platform string `json:"platform,omitempty"` // os/arch[/variant] for multi-arch images, default linux/amd64
+}
+
+// ImageDigest is one digest referenced by an image: its index or manifest,
+// its config blob, a compressed layer blob or an uncompressed layer (diff_id).
+type ImageDigest struct {
+	Kind      string `json:"kind"` // index, manifest, config, layer or diff_id
+	Index     int    `json:"index"`
+	Digest    string `json:"digest"`
+	MediaType string `json:"media_type,omitempty"`
+}
+
+// ImageMatchResponse represents the outgoing response body for /verify-image
+type ImageMatchResponse struct {
+	Image       string        `json:"image"`
+	Digest      string        `json:"digest"` // the content's sha256 digest
+	Match       bool          `json:"match"`
+	Matches     []ImageDigest `json:"matches"`
+	Checked     int           `json:"checked"` // number of image digests compared
+	InputLength int           `json:"input_length"`
+}
+
+func verifyImage(ctx context.Context, req ImageMatchRequest) (ImageMatchResponse, error) {
+	if req.Input == "" && req.URL == "" {
+		return ImageMatchResponse{}, toolserver.BadRequest("input or url is required")
+	}
+	if req.Image == "" {
+		return ImageMatchResponse{}, toolserver.BadRequest("image is required")
+	}
+	release, err := registryPool.Acquire(ctx)
+	if err != nil {
+		return ImageMatchResponse{}, err
+	}
+	defer release()
+
+	resp := ImageMatchResponse{Image: req.Image, Matches: []ImageDigest{}}
+
+	opts := []crane.Option{crane.WithContext(ctx), crane.WithTransport(registryTransport)}
+	if req.Platform != "" {
+		platform, err := v1.ParsePlatform(req.Platform)
+		if err != nil {
+			return ImageMatchResponse{}, toolserver.BadRequest("invalid platform: %v", err)
+		}
+		opts = append(opts, crane.WithPlatform(platform))
+	}
+
+	digests, err := imageDigests(req.Image, opts...)
+	if err != nil {
+		return ImageMatchResponse{}, err
+	}
+	resp.Checked = len(digests)
+
+	// Hash with every algorithm the image uses; sha256 is always computed
+	// so the content's digest can be reported.
+	algorithms := []string{"sha256"}
+	for _, d := range digests {
+		if h, err := v1.NewHash(d.Digest); err == nil && !slices.Contains(algorithms, h.Algorithm) {
+			if _, err := newHash(h.Algorithm); err == nil {
+				algorithms = append(algorithms, h.Algorithm)
+			}
+		}
+	}
+
+	req.Mode = ""
+	sums, n, err := computeDigests(ctx, req.HashRequest, algorithms)
+	if err != nil {
+		return ImageMatchResponse{}, err
+	}
+
+	resp.InputLength = int(n)
+	resp.Digest, _ = formatDigest(sums["sha256"], "sha256", "hex", true)
+	resp.Matches = matchImageDigests(sums, digests)
+	resp.Match = len(resp.Matches) > 0
+
+	return resp, nil
+}
+
+// registryTransport is used for registry requests so they are counted in
+// /metrics and go through the egress proxy, trusting the CA bundle.
+var registryTransport = toolserver.InstrumentTransport("registry", toolserver.Outbound(remote.DefaultTransport))
+
+// registryPool bounds the image inspections running at once, each of which
+// holds manifests and config blobs in memory.
+var registryPool = toolserver.NewPool("registry", 4, 32)
+
+// imageDigests lists the digests referenced by ref without pulling any layer
+// blobs: only the manifest (and index) and the config are fetched. For an
+// index, the manifest is the one selected for the requested platform.
+func imageDigests(ref string, opts ...crane.Option) ([]ImageDigest, error) {
+	desc, err := crane.Get(ref, opts...)
+	if err != nil {
+		return nil, registryError(err, "failed to fetch image")
+	}
+
+	var digests []ImageDigest
+	if desc.MediaType.IsIndex() {
+		digests = append(digests, ImageDigest{Kind: "index", Digest: desc.Digest.String(), MediaType: string(desc.MediaType)})
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return nil, registryError(err, "failed to resolve image")
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, registryError(err, "failed to read manifest")
+	}
+	manifestDigest, err := img.Digest()
+	if err != nil {
+		return nil, registryError(err, "failed to digest manifest")
+	}
+	digests = append(digests,
+		ImageDigest{Kind: "manifest", Digest: manifestDigest.String(), MediaType: string(manifest.MediaType)},
+		ImageDigest{Kind: "config", Digest: manifest.Config.Digest.String(), MediaType: string(manifest.Config.MediaType)},
+	)
+	for i, layer := range manifest.Layers {
+		digests = append(digests, ImageDigest{Kind: "layer", Index: i, Digest: layer.Digest.String(), MediaType: string(layer.MediaType)})
+	}
+
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, registryError(err, "failed to read config")
+	}
+	for i, diffID := range config.RootFS.DiffIDs {
+		digests = append(digests, ImageDigest{Kind: "diff_id", Index: i, Digest: diffID.String()})
+	}
+	return digests, nil
+}
+
+// matchImageDigests returns the image digests equal to one of sums, which
+// maps algorithm to raw digest.
+func matchImageDigests(sums map[string][]byte, digests []ImageDigest) []ImageDigest {
+	matches := []ImageDigest{}
+	for _, d := range digests {
+		h, err := v1.NewHash(d.Digest)
+		if err != nil {
+			continue
+		}
+		if sum, ok := sums[h.Algorithm]; ok && hex.EncodeToString(sum) == h.Hex {
+			matches = append(matches, d)
+		}
+	}
+	return matches
+}
+
+// registryError reports a failed registry request, with a formatted message
+// followed by err: INVALID_ARGUMENT for a malformed image reference,
+// NOT_FOUND for a missing repository, tag or digest, FORBIDDEN when the
+// registry refuses the tool's credentials, and otherwise an upstream error,
+// retryable if the registry is throttling.
+func registryError(err error, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	var badName *name.ErrBadName
+	if errors.As(err, &badName) {
+		return toolserver.NewError(toolserver.CodeInvalidArgument, "%s: %v", msg, err)
+	}
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		switch terr.StatusCode {
+		case http.StatusNotFound:
+			return toolserver.NewError(toolserver.CodeNotFound, "%s: %v", msg, err)
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return toolserver.NewError(toolserver.CodeForbidden, "%s: %v", msg, err)
+		case http.StatusTooManyRequests:
+			e := toolserver.UpstreamError(err, format, args...)
+			e.Retryable = true
+			return e
+		}
+	}
+	return toolserver.UpstreamError(err, format, args...)
+}
@@ -0,0 +1,294 @@
+package hashtool
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// defaultJWTKeyDir is where Secrets holding signing keys are mounted.
+// key_file paths are resolved inside it (override with JWT_KEY_DIR).
+const defaultJWTKeyDir = "/etc/hash-tool/keys"
+
+// JWTSignRequest represents the incoming request body for /jwt/sign
+type JWTSignRequest struct {
+	Algorithm string         `json:"algorithm"` // HS256 or RS256
+	Claims    map[string]any `json:"claims"`
+	// ExpiresInSeconds sets iat to now and exp to now plus this many seconds.
+	ExpiresInSeconds int64  `json:"expires_in_seconds,omitempty"`
+	KeyID            string `json:"kid,omitempty"`
+
+	Key         string `json:"key,omitempty"`          // HS256 secret
+	KeyEncoding string `json:"key_encoding,omitempty"` // raw (default) or base64
+	PrivateKey  string `json:"private_key,omitempty"`  // RS256 PEM (PKCS#1 or PKCS#8)
+	KeyFile     string `json:"key_file,omitempty"`     // file under JWT_KEY_DIR holding the key instead
+}
+
+// JWTSignResponse represents the outgoing response body for /jwt/sign
+type JWTSignResponse struct {
+	Token  string         `json:"token"`
+	Header map[string]any `json:"header,omitempty"`
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+// JWTVerifyRequest represents the incoming request body for /jwt/verify
+type JWTVerifyRequest struct {
+	Token string `json:"token"`
+	// Algorithm pins the expected algorithm; the token's own alg header is
+	// never trusted to choose how it is verified.
+	Algorithm     string `json:"algorithm"`
+	LeewaySeconds int64  `json:"leeway_seconds,omitempty"`
+
+	Key         string `json:"key,omitempty"`          // HS256 secret
+	KeyEncoding string `json:"key_encoding,omitempty"` // raw (default) or base64
+	PublicKey   string `json:"public_key,omitempty"`   // RS256 PEM public key or certificate
+	KeyFile     string `json:"key_file,omitempty"`     // file under JWT_KEY_DIR holding the key instead
+}
+
+// JWTVerifyResponse represents the outgoing response body for /jwt/verify.
+// Header and claims are decoded even when verification fails, for debugging.
+type JWTVerifyResponse struct {
+	Valid          bool           `json:"valid"`
+	SignatureValid bool           `json:"signature_valid"`
+	Expired        bool           `json:"expired,omitempty"`
+	NotYetValid    bool           `json:"not_yet_valid,omitempty"`
+	Header         map[string]any `json:"header,omitempty"`
+	Claims         map[string]any `json:"claims,omitempty"`
+	Error          string         `json:"error,omitempty"`
+}
+
+var b64url = base64.RawURLEncoding
+
+func jwtSign(ctx context.Context, req JWTSignRequest) (JWTSignResponse, error) {
+	if req.Claims == nil {
+		req.Claims = map[string]any{}
+	}
+	if req.ExpiresInSeconds > 0 {
+		now := time.Now().Unix()
+		req.Claims["iat"] = now
+		req.Claims["exp"] = now + req.ExpiresInSeconds
+	}
+	header := map[string]any{"alg": req.Algorithm, "typ": "JWT"}
+	if req.KeyID != "" {
+		header["kid"] = req.KeyID
+	}
+
+	keyMaterial, err := jwtKeyMaterial(req.Algorithm, req.Key, req.KeyEncoding, req.PrivateKey, req.KeyFile)
+	if err != nil {
+		return JWTSignResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	token, err := signJWT(req.Algorithm, header, req.Claims, keyMaterial)
+	if err != nil {
+		return JWTSignResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	return JWTSignResponse{Token: token, Header: header, Claims: req.Claims}, nil
+}
+
+func jwtVerify(ctx context.Context, req JWTVerifyRequest) (JWTVerifyResponse, error) {
+	if req.Token == "" {
+		return JWTVerifyResponse{}, toolserver.BadRequest("token is required")
+	}
+
+	keyMaterial, err := jwtKeyMaterial(req.Algorithm, req.Key, req.KeyEncoding, req.PublicKey, req.KeyFile)
+	if err != nil {
+		return JWTVerifyResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	return verifyJWT(req.Token, req.Algorithm, keyMaterial, time.Duration(req.LeewaySeconds)*time.Second, time.Now()), nil
+}
+
+// jwtKeyMaterial returns the raw HMAC secret for HS256 or the PEM bytes for
+// RS256, from the request or from a file in the key directory.
+func jwtKeyMaterial(algorithm, key, keyEncoding, pemKey, keyFile string) ([]byte, error) {
+	if algorithm != "HS256" && algorithm != "RS256" {
+		return nil, fmt.Errorf("unsupported JWT algorithm: %q (use HS256 or RS256)", algorithm)
+	}
+
+	if keyFile != "" {
+		dir := config.String("JWT_KEY_DIR", defaultJWTKeyDir)
+		path := filepath.Join(dir, filepath.Clean("/"+keyFile))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading key_file: %v", err)
+		}
+		if algorithm == "HS256" {
+			return decodeKey(strings.TrimRight(string(data), "\r\n"), keyEncoding)
+		}
+		return data, nil
+	}
+
+	if algorithm == "HS256" {
+		return decodeKey(key, keyEncoding)
+	}
+	if pemKey == "" {
+		return nil, errors.New("a PEM key or key_file is required for RS256")
+	}
+	return []byte(pemKey), nil
+}
+
+func signJWT(algorithm string, header, claims map[string]any, keyMaterial []byte) (string, error) {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := b64url.EncodeToString(headerJSON) + "." + b64url.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var sig []byte
+	switch algorithm {
+	case "HS256":
+		mac := hmac.New(sha256.New, keyMaterial)
+		mac.Write([]byte(signingInput))
+		sig = mac.Sum(nil)
+	case "RS256":
+		key, err := parseRSAPrivateKey(keyMaterial)
+		if err != nil {
+			return "", err
+		}
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			return "", err
+		}
+	}
+	return signingInput + "." + b64url.EncodeToString(sig), nil
+}
+
+func verifyJWT(token, algorithm string, keyMaterial []byte, leeway time.Duration, now time.Time) JWTVerifyResponse {
+	var resp JWTVerifyResponse
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		resp.Error = "token must have three dot-separated parts"
+		return resp
+	}
+	if err := decodeJWTPart(parts[0], &resp.Header); err != nil {
+		resp.Error = "invalid header: " + err.Error()
+		return resp
+	}
+	if err := decodeJWTPart(parts[1], &resp.Claims); err != nil {
+		resp.Error = "invalid claims: " + err.Error()
+		return resp
+	}
+	if alg, _ := resp.Header["alg"].(string); alg != algorithm {
+		resp.Error = fmt.Sprintf("token alg %q does not match expected %s", alg, algorithm)
+		return resp
+	}
+	sig, err := b64url.DecodeString(parts[2])
+	if err != nil {
+		resp.Error = "invalid signature encoding"
+		return resp
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+	switch algorithm {
+	case "HS256":
+		mac := hmac.New(sha256.New, keyMaterial)
+		mac.Write([]byte(signingInput))
+		resp.SignatureValid = hmac.Equal(sig, mac.Sum(nil))
+	case "RS256":
+		key, err := parseRSAPublicKey(keyMaterial)
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		resp.SignatureValid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	}
+	if !resp.SignatureValid {
+		resp.Error = "signature does not match"
+		return resp
+	}
+
+	if exp, ok := resp.Claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		resp.Expired = true
+	}
+	if nbf, ok := resp.Claims["nbf"].(float64); ok && now.Add(leeway).Before(time.Unix(int64(nbf), 0)) {
+		resp.NotYetValid = true
+	}
+	resp.Valid = !resp.Expired && !resp.NotYetValid
+	return resp
+}
+
+func decodeJWTPart(part string, v *map[string]any) error {
+	data, err := b64url.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func parseRSAPrivateKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// parseRSAPublicKey accepts a PKIX or PKCS#1 public key, a certificate, or
+// a private key (whose public half is used).
+func parseRSAPublicKey(pemBytes []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate: %v", err)
+		}
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+		return nil, errors.New("certificate does not hold an RSA key")
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "RSA PRIVATE KEY", "PRIVATE KEY":
+		key, err := parseRSAPrivateKey(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %v", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return key, nil
+}
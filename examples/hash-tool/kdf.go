@@ -0,0 +1,156 @@
+package hashtool
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDF defaults match OWASP's PBKDF2-HMAC-SHA256 guidance and scrypt's
+// interactive-login parameters. As with password hashing, upper bounds keep a
+// single request from tying up the pod or exceeding its memory limit.
+const (
+	defaultPBKDF2Iterations = 600000
+	defaultScryptN          = 1 << 15
+	defaultScryptR          = 8
+	defaultScryptP          = 1
+	defaultKDFKeyLength     = 32
+
+	maxPBKDF2Iterations = 10000000
+	maxScryptMemory     = 128 << 20 // bytes (128 * N * r); keep below the container memory limit
+	maxScryptP          = 16
+	maxKDFKeyLength     = 1024
+)
+
+// KDFRequest represents the incoming request body for /kdf
+type KDFRequest struct {
+	Algorithm    string `json:"algorithm"` // pbkdf2 or scrypt
+	Password     string `json:"password"`
+	Salt         string `json:"salt"`
+	SaltEncoding string `json:"salt_encoding,omitempty"` // raw (default), hex or base64
+	KeyLength    int    `json:"key_length,omitempty"`    // bytes, default 32
+
+	Hash       string `json:"hash,omitempty"`       // pbkdf2 PRF: sha1, sha256 (default) or sha512
+	Iterations int    `json:"iterations,omitempty"` // pbkdf2, default 600000
+
+	N int `json:"n,omitempty"` // scrypt CPU/memory cost, a power of two; default 32768
+	R int `json:"r,omitempty"` // scrypt block size, default 8
+	P int `json:"p,omitempty"` // scrypt parallelism, default 1
+
+	OutputEncoding string `json:"output_encoding,omitempty"` // hex (default), base64 or base64url
+}
+
+// KDFResponse represents the outgoing response body for /kdf. The effective
+// parameters are echoed so defaults can be copied into application config.
+type KDFResponse struct {
+	Key        string `json:"key"`
+	Algorithm  string `json:"algorithm"`
+	KeyLength  int    `json:"key_length"`
+	Hash       string `json:"hash,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	N          int    `json:"n,omitempty"`
+	R          int    `json:"r,omitempty"`
+	P          int    `json:"p,omitempty"`
+}
+
+func kdf(ctx context.Context, req KDFRequest) (KDFResponse, error) {
+	if req.Password == "" {
+		return KDFResponse{}, toolserver.BadRequest("password is required")
+	}
+	if _, err := formatDigest(nil, "", req.OutputEncoding, false); err != nil {
+		return KDFResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	resp, key, err := deriveKey(req)
+	if err != nil {
+		return KDFResponse{}, toolserver.BadRequest("%v", err)
+	}
+	resp.Key, _ = formatDigest(key, "", req.OutputEncoding, false)
+
+	return resp, nil
+}
+
+// deriveKey applies defaults and limits to req, then derives the key. The
+// returned response carries the effective parameters.
+func deriveKey(req KDFRequest) (KDFResponse, []byte, error) {
+	resp := KDFResponse{Algorithm: req.Algorithm, KeyLength: req.KeyLength}
+	if resp.KeyLength == 0 {
+		resp.KeyLength = defaultKDFKeyLength
+	}
+	if resp.KeyLength < 1 || resp.KeyLength > maxKDFKeyLength {
+		return resp, nil, fmt.Errorf("key_length must be between 1 and %d", maxKDFKeyLength)
+	}
+
+	salt := []byte(req.Salt)
+	switch req.SaltEncoding {
+	case "", "raw":
+	case "hex", "base64":
+		decoded, err := decodeString(req.Salt, req.SaltEncoding)
+		if err != nil {
+			return resp, nil, fmt.Errorf("salt: %v", err)
+		}
+		salt = decoded
+	default:
+		return resp, nil, fmt.Errorf("unsupported salt encoding: %s", req.SaltEncoding)
+	}
+
+	switch req.Algorithm {
+	case "pbkdf2":
+		resp.Hash, resp.Iterations = req.Hash, req.Iterations
+		if resp.Hash == "" {
+			resp.Hash = "sha256"
+		}
+		if resp.Iterations == 0 {
+			resp.Iterations = defaultPBKDF2Iterations
+		}
+		if resp.Iterations < 1 || resp.Iterations > maxPBKDF2Iterations {
+			return resp, nil, fmt.Errorf("iterations must be between 1 and %d", maxPBKDF2Iterations)
+		}
+		var prf func() hash.Hash
+		switch resp.Hash {
+		case "sha1":
+			prf = sha1.New
+		case "sha256":
+			prf = sha256.New
+		case "sha512":
+			prf = sha512.New
+		default:
+			return resp, nil, fmt.Errorf("unsupported PBKDF2 hash: %s (use sha1, sha256 or sha512)", resp.Hash)
+		}
+		key, err := pbkdf2.Key(prf, req.Password, salt, resp.Iterations, resp.KeyLength)
+		return resp, key, err
+
+	case "scrypt":
+		resp.N, resp.R, resp.P = req.N, req.R, req.P
+		if resp.N == 0 {
+			resp.N = defaultScryptN
+		}
+		if resp.R == 0 {
+			resp.R = defaultScryptR
+		}
+		if resp.P == 0 {
+			resp.P = defaultScryptP
+		}
+		if resp.N < 2 || resp.N&(resp.N-1) != 0 {
+			return resp, nil, fmt.Errorf("n must be a power of two greater than 1")
+		}
+		if resp.R < 1 || int64(128*resp.R)*int64(resp.N) > maxScryptMemory {
+			return resp, nil, fmt.Errorf("n and r need more than %d MiB (128 * n * r bytes)", maxScryptMemory>>20)
+		}
+		if resp.P < 1 || resp.P > maxScryptP {
+			return resp, nil, fmt.Errorf("p must be between 1 and %d", maxScryptP)
+		}
+		key, err := scrypt.Key([]byte(req.Password), salt, resp.N, resp.R, resp.P, resp.KeyLength)
+		return resp, key, err
+
+	default:
+		return resp, nil, fmt.Errorf("unsupported KDF algorithm: %q (use pbkdf2 or scrypt)", req.Algorithm)
+	}
+}
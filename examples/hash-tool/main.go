@@ -1,107 +1,308 @@
-package main
+// Package hashtool implements hash-tool, which hashes, verifies and encodes
+// strings, downloads, Secrets and image contents.
+package hashtool
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
-	"os"
+	"hash"
+	"hash/crc32"
+	"io"
+	"slices"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/cespare/xxhash/v2"
+	"golang.org/x/crypto/blake2b"
+	"lukechampine.com/blake3"
 )
 
 // HashRequest represents the incoming request body
 type HashRequest struct {
 	Input     string `json:"input"`
-	Algorithm string `json:"algorithm"` // md5, sha1, sha256, sha512
+	Algorithm string `json:"algorithm"` // md5, sha1, sha256, sha512, blake2b-256, blake2b-512, blake3, crc32, xxhash64
+
+	// InputEncoding is "utf8" (default) or "base64" for binary input.
+	InputEncoding string `json:"input_encoding,omitempty"`
+	// Algorithms computes several digests in one pass; results are returned
+	// in Hashes.
+	Algorithms []string `json:"algorithms,omitempty"`
+
+	// Mode is "hash" (default) or "hmac". HMAC mode requires Key and only
+	// accepts sha256 and sha512.
+	Mode        string `json:"mode,omitempty"`
+	Key         string `json:"key,omitempty"`
+	KeyEncoding string `json:"key_encoding,omitempty"` // raw (default) or base64
+
+	// URL, when set instead of Input, is fetched and streamed through the
+	// hash without buffering the body.
+	URL            string `json:"url,omitempty"`
+	MaxBytes       int64  `json:"max_bytes,omitempty"`       // default 1 GiB
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"` // default 60
+
+	// OutputEncoding is "hex" (default), "base64" or "base64url". Prefixed
+	// adds "<algorithm>:" as in OCI digests ("sha256:...").
+	OutputEncoding string `json:"output_encoding,omitempty"`
+	Prefixed       bool   `json:"prefixed,omitempty"`
 }
 
 // HashResponse represents the outgoing response body
 type HashResponse struct {
-	Hash        string `json:"hash"`
-	Algorithm   string `json:"algorithm"`
-	InputLength int    `json:"input_length"`
-	Error       string `json:"error,omitempty"`
+	Hash        string            `json:"hash"`
+	Hashes      map[string]string `json:"hashes,omitempty"` // algorithm -> digest, when algorithms is set
+	Algorithm   string            `json:"algorithm"`
+	Mode        string            `json:"mode,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	InputLength int               `json:"input_length"`
 }
 
-func main() {
-	http.HandleFunc("/health", handleHealth)
-	http.HandleFunc("/hash", handleHash)
+// New returns the hash-tool server. The Kubernetes client, used by
+// /hash-object, is optional.
+func New() (*toolserver.Server, error) {
+	s := toolserver.New("hash-tool")
+	s.AddDiagnostics("kubernetes", kubeClient.Diagnostics)
+	toolserver.Register(s, "/hash", hashInput,
+		toolserver.Name("hash-tool"), toolserver.Describe("Generate cryptographic hashes for strings."),
+		toolserver.Timeout(fetchOperationTimeout), toolserver.Sensitive("input"))
+	s.HandleFunc("/hash-stream", handleHashStream)
+	toolserver.Register(s, "/verify", verify,
+		toolserver.Name("hash-verify"), toolserver.Describe("Check input (or the body at a url) against an expected digest."),
+		toolserver.Timeout(fetchOperationTimeout), toolserver.Sensitive("input"))
+	toolserver.Register(s, "/verify-checksums", verifyChecksums,
+		toolserver.Describe("Verify every entry of a sha256sums-style checksum file."),
+		toolserver.Timeout(fetchOperationTimeout))
+	toolserver.Register(s, "/verify-image", verifyImage,
+		toolserver.Describe("Check whether content matches a digest shipped in a container image."),
+		toolserver.Timeout(fetchOperationTimeout))
+	toolserver.Register(s, "/password-hash", passwordHash,
+		toolserver.Describe("Hash a password with bcrypt or argon2id."))
+	toolserver.Register(s, "/password-verify", passwordVerify,
+		toolserver.Describe("Check a password against a bcrypt or argon2id hash."))
+	toolserver.Register(s, "/kdf", kdf,
+		toolserver.Describe("Derive a key from a password with PBKDF2 or scrypt."))
+	toolserver.Register(s, "/encode", encode,
+		toolserver.Describe("Encode or decode strings as base64, base64url, hex, or URL (query) encoding."),
+		toolserver.Sensitive("input"))
+	toolserver.Register(s, "/hash-object", hashObject,
+		toolserver.Describe("Digest the contents of a Kubernetes Secret or ConfigMap without exposing any values."),
+		toolserver.RBAC("get secrets", "get configmaps"))
+	toolserver.Register(s, "/jwt/sign", jwtSign,
+		toolserver.Describe("Sign a JWT with HS256 or RS256 for debugging service-to-service auth."))
+	toolserver.Register(s, "/jwt/verify", jwtVerify,
+		toolserver.Describe("Verify a JWT's HS256 or RS256 signature and its exp/nbf claims."))
+
+	return s, nil
+}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+func hashInput(ctx context.Context, req HashRequest) (HashResponse, error) {
+	if req.Input == "" && req.URL == "" {
+		return HashResponse{}, toolserver.BadRequest("input or url is required")
 	}
 
-	log.Printf("Starting hash-tool server on :%s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	if req.Algorithm == "" && len(req.Algorithms) == 0 {
+		return HashResponse{}, toolserver.BadRequest("algorithm is required")
 	}
-}
 
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
-}
+	algorithms := req.Algorithms
+	if req.Algorithm != "" && !slices.Contains(algorithms, req.Algorithm) {
+		algorithms = append([]string{req.Algorithm}, algorithms...)
+	}
 
-func handleHash(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	if _, err := formatDigest(nil, "", req.OutputEncoding, false); err != nil {
+		return HashResponse{}, toolserver.BadRequest("%v", err)
+	}
 
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
-		return
+	sums, n, err := computeDigests(ctx, req, algorithms)
+	if err != nil {
+		return HashResponse{}, err
 	}
 
-	var req HashRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(HashResponse{Error: "invalid request body"})
-		return
+	resp := HashResponse{
+		Algorithm:   req.Algorithm,
+		Mode:        req.Mode,
+		URL:         req.URL,
+		InputLength: int(n),
+	}
+	if req.Algorithm != "" {
+		resp.Hash, _ = formatDigest(sums[req.Algorithm], req.Algorithm, req.OutputEncoding, req.Prefixed)
+	}
+	if len(req.Algorithms) > 0 {
+		resp.Hashes = make(map[string]string, len(sums))
+		for algorithm, sum := range sums {
+			resp.Hashes[algorithm], _ = formatDigest(sum, algorithm, req.OutputEncoding, req.Prefixed)
+		}
 	}
 
-	if req.Input == "" {
-		json.NewEncoder(w).Encode(HashResponse{Error: "input is required"})
-		return
+	return resp, nil
+}
+
+// computeDigests feeds the request's input (decoded per input_encoding) or
+// the body at its URL through every algorithm in one pass, honoring the
+// hmac mode and key. It returns the raw sums and the number of input bytes,
+// or INVALID_ARGUMENT for bad input and hashURL's error if the fetch fails.
+func computeDigests(ctx context.Context, req HashRequest, algorithms []string) (map[string][]byte, int64, error) {
+	input := []byte(req.Input)
+	switch req.InputEncoding {
+	case "", "utf8":
+	case "base64":
+		decoded, err := decodeString(req.Input, "base64")
+		if err != nil {
+			return nil, 0, toolserver.BadRequest("%v", err)
+		}
+		input = decoded
+	default:
+		return nil, 0, toolserver.BadRequest("unsupported input encoding: %s", req.InputEncoding)
 	}
 
-	if req.Algorithm == "" {
-		json.NewEncoder(w).Encode(HashResponse{Error: "algorithm is required"})
-		return
+	var key []byte
+	if req.Mode == "hmac" {
+		var err error
+		if key, err = decodeKey(req.Key, req.KeyEncoding); err != nil {
+			return nil, 0, toolserver.BadRequest("%v", err)
+		}
 	}
 
-	hash, err := computeHash(req.Input, req.Algorithm)
-	if err != nil {
-		json.NewEncoder(w).Encode(HashResponse{Error: err.Error()})
-		return
+	digests := make(map[string]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		if _, ok := digests[algorithm]; ok {
+			continue
+		}
+		h, err := newDigest(algorithm, req.Mode, key)
+		if err != nil {
+			return nil, 0, toolserver.BadRequest("%v", err)
+		}
+		digests[algorithm] = h
+		writers = append(writers, h)
 	}
+	sink := io.MultiWriter(writers...)
 
-	resp := HashResponse{
-		Hash:        hash,
-		Algorithm:   req.Algorithm,
-		InputLength: len(req.Input),
+	var n int64
+	if req.URL != "" {
+		var err error
+		if n, err = hashURL(ctx, req.URL, sink, req.MaxBytes, req.TimeoutSeconds); err != nil {
+			return nil, n, err
+		}
+	} else {
+		sink.Write(input)
+		n = int64(len(input))
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	sums := make(map[string][]byte, len(digests))
+	for algorithm, h := range digests {
+		sums[algorithm] = h.Sum(nil)
+	}
+	return sums, n, nil
 }
 
-func computeHash(input, algorithm string) (string, error) {
-	data := []byte(input)
+// formatDigest renders sum in the requested output encoding, optionally as
+// "<algorithm>:<digest>". Base64 output is padded standard base64, the form
+// subresource integrity attributes use after their "sha256-" prefix.
+func formatDigest(sum []byte, algorithm, encoding string, prefixed bool) (string, error) {
+	var out string
+	switch encoding {
+	case "", "hex":
+		out = hex.EncodeToString(sum)
+	case "base64":
+		out = base64.StdEncoding.EncodeToString(sum)
+	case "base64url":
+		out = base64.RawURLEncoding.EncodeToString(sum)
+	default:
+		return "", fmt.Errorf("unsupported output encoding: %s", encoding)
+	}
+	if prefixed {
+		out = algorithm + ":" + out
+	}
+	return out, nil
+}
+
+// newHash returns a constructor for the named algorithm.
+func newHash(algorithm string) (func() hash.Hash, error) {
 	switch algorithm {
 	case "md5":
-		sum := md5.Sum(data)
-		return hex.EncodeToString(sum[:]), nil
+		return md5.New, nil
 	case "sha1":
-		sum := sha1.Sum(data)
-		return hex.EncodeToString(sum[:]), nil
+		return sha1.New, nil
 	case "sha256":
-		sum := sha256.Sum256(data)
-		return hex.EncodeToString(sum[:]), nil
+		return sha256.New, nil
 	case "sha512":
-		sum := sha512.Sum512(data)
-		return hex.EncodeToString(sum[:]), nil
+		return sha512.New, nil
+	case "blake2b-256":
+		return func() hash.Hash { h, _ := blake2b.New256(nil); return h }, nil
+	case "blake2b-512":
+		return func() hash.Hash { h, _ := blake2b.New512(nil); return h }, nil
+	case "blake3":
+		return func() hash.Hash { return blake3.New(32, nil) }, nil
+	case "crc32":
+		// IEEE polynomial, as used by gzip, zip and PNG.
+		return func() hash.Hash { return crc32.NewIEEE() }, nil
+	case "xxhash64":
+		return func() hash.Hash { return xxhash.New() }, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+}
+
+// newDigest returns a hash for mode "hash" (or empty) or a keyed HMAC for
+// mode "hmac", which only accepts sha256 and sha512.
+func newDigest(algorithm, mode string, key []byte) (hash.Hash, error) {
+	newFn, err := newHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case "", "hash":
+		return newFn(), nil
+	case "hmac":
+		if algorithm != "sha256" && algorithm != "sha512" {
+			return nil, fmt.Errorf("unsupported HMAC algorithm: %s (use sha256 or sha512)", algorithm)
+		}
+		return hmac.New(newFn, key), nil
+	default:
+		return nil, fmt.Errorf("unsupported mode: %s", mode)
+	}
+}
+
+func computeHash(input, algorithm string) (string, error) {
+	h, err := newDigest(algorithm, "hash", nil)
+	if err != nil {
+		return "", err
+	}
+	h.Write([]byte(input))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// computeHMAC returns the hex HMAC of input, as used for webhook signatures
+// (e.g. GitHub's X-Hub-Signature-256 is "sha256=" plus this value).
+func computeHMAC(input string, key []byte, algorithm string) (string, error) {
+	h, err := newDigest(algorithm, "hmac", key)
+	if err != nil {
+		return "", err
+	}
+	h.Write([]byte(input))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func decodeKey(key, encoding string) ([]byte, error) {
+	if key == "" {
+		return nil, fmt.Errorf("key is required for hmac mode")
+	}
+	switch encoding {
+	case "", "raw":
+		return []byte(key), nil
+	case "base64":
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 key: %v", err)
+		}
+		return decoded, nil
 	default:
-		return "", fmt.Errorf("unsupported algorithm: %s", algorithm)
+		return nil, fmt.Errorf("unsupported key encoding: %s", encoding)
 	}
 }
@@ -1,7 +1,17 @@
-package main
+package hashtool
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/atippey/kube-mcp/pkg/tooltest"
 )
 
 func TestComputeHash(t *testing.T) {
@@ -25,6 +35,26 @@ func TestComputeHash(t *testing.T) {
 			want:      "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
 			wantErr:   false,
 		},
+		{
+			name:      "blake2b-256",
+			algorithm: "blake2b-256",
+			want:      "256c83b297114d201b30179f3f0ef0cace9783622da5974326b436178aeef610",
+		},
+		{
+			name:      "blake3",
+			algorithm: "blake3",
+			want:      "d74981efa70a0c880b8d8c1985d075dbcbf679b99a5f9914e5aaf96b831a9e24",
+		},
+		{
+			name:      "crc32",
+			algorithm: "crc32",
+			want:      "0d4a1185",
+		},
+		{
+			name:      "xxhash64",
+			algorithm: "xxhash64",
+			want:      "45ab6734b21e6968",
+		},
 		{
 			name:      "unsupported",
 			algorithm: "foo",
@@ -46,3 +76,293 @@ func TestComputeHash(t *testing.T) {
 		})
 	}
 }
+
+func TestComputeHMAC(t *testing.T) {
+	// RFC 4231 test case 2.
+	key := []byte("Jefe")
+	input := "what do ya want for nothing?"
+
+	tests := []struct {
+		name      string
+		algorithm string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "sha256",
+			algorithm: "sha256",
+			want:      "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		},
+		{
+			name:      "sha512",
+			algorithm: "sha512",
+			want:      "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737",
+		},
+		{
+			name:      "md5 rejected",
+			algorithm: "md5",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := computeHMAC(input, key, tt.algorithm)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("computeHMAC() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("computeHMAC() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordRoundTrip(t *testing.T) {
+	bcryptHash, err := hashBcrypt("s3cret", 4)
+	if err != nil {
+		t.Fatalf("hashBcrypt() error = %v", err)
+	}
+	argonHash, err := hashArgon2id("s3cret", 1, 1024, 1)
+	if err != nil {
+		t.Fatalf("hashArgon2id() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		name          string
+		hash          string
+		wantAlgorithm string
+	}{
+		{name: "bcrypt", hash: bcryptHash, wantAlgorithm: "bcrypt"},
+		{name: "argon2id", hash: argonHash, wantAlgorithm: "argon2id"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			algorithm, valid, err := verifyPassword("s3cret", tt.hash)
+			if err != nil || !valid || algorithm != tt.wantAlgorithm {
+				t.Errorf("verifyPassword(correct) = %v, %v, %v", algorithm, valid, err)
+			}
+			if _, valid, err := verifyPassword("wrong", tt.hash); err != nil || valid {
+				t.Errorf("verifyPassword(wrong) = %v, %v", valid, err)
+			}
+		})
+	}
+}
+
+func TestParseExpectedDigest(t *testing.T) {
+	// sha256("hello world")
+	const hexDigest = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+
+	tests := []struct {
+		name     string
+		expected string
+		wantErr  bool
+	}{
+		{name: "hex", expected: hexDigest},
+		{name: "uppercase hex", expected: "B94D27B9934D3E08A52E52D7DA7DABFAC484EFE37A5380EE9088F7ACE2EFCDE9"},
+		{name: "oci prefix", expected: "sha256:" + hexDigest},
+		{name: "signature prefix", expected: "sha256=" + hexDigest},
+		{name: "base64", expected: "uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek="},
+		{name: "wrong length", expected: hexDigest[:62], wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExpectedDigest(tt.expected, "sha256", 32)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseExpectedDigest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && hex.EncodeToString(got) != hexDigest {
+				t.Errorf("parseExpectedDigest() = %x, want %s", got, hexDigest)
+			}
+		})
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	content := `# release v1.2.3
+5eb63bbbe01eeed093cb22bb8f5acdc3  notes.txt
+B94D27B9934D3E08A52E52D7DA7DABFAC484EFE37A5380EE9088F7ACE2EFCDE9 *app.tar.gz
+
+SHA512 (app.zip) = 309ecc489c12d6eb4cc40f50c902f2b4d0ed77ee511a7c7a9bcd3ca86d4cd86f989dd35bc5ff499670da34255b45b0cfd830e81f605dcf7dc5542e93ae9cd76f
+`
+	entries, err := parseChecksums(content, "")
+	if err != nil {
+		t.Fatalf("parseChecksums() error = %v", err)
+	}
+
+	want := []checksumEntry{
+		{name: "notes.txt", algorithm: "md5", digest: "5eb63bbbe01eeed093cb22bb8f5acdc3"},
+		{name: "app.tar.gz", algorithm: "sha256", digest: "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"},
+		{name: "app.zip", algorithm: "sha512", digest: "309ecc489c12d6eb4cc40f50c902f2b4d0ed77ee511a7c7a9bcd3ca86d4cd86f989dd35bc5ff499670da34255b45b0cfd830e81f605dcf7dc5542e93ae9cd76f"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("parseChecksums() returned %d entries, want %d", len(entries), len(want))
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+
+	if _, err := parseChecksums("not-hex  file", ""); err == nil {
+		t.Error("parseChecksums() accepted a non-hex digest")
+	}
+}
+
+func TestJWTRoundTrip(t *testing.T) {
+	key := []byte("your-256-bit-secret")
+	claims := map[string]any{"sub": "1234567890", "exp": float64(2000000000)}
+	header := map[string]any{"alg": "HS256", "typ": "JWT"}
+
+	token, err := signJWT("HS256", header, claims, key)
+	if err != nil {
+		t.Fatalf("signJWT() error = %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	if got := verifyJWT(token, "HS256", key, 0, now); !got.Valid || got.Claims["sub"] != "1234567890" {
+		t.Errorf("verifyJWT() = %+v, want valid", got)
+	}
+	if got := verifyJWT(token, "HS256", []byte("wrong"), 0, now); got.SignatureValid {
+		t.Error("verifyJWT() accepted a token signed with another key")
+	}
+	if got := verifyJWT(token, "RS256", key, 0, now); got.Valid {
+		t.Error("verifyJWT() accepted a token whose alg does not match")
+	}
+	if got := verifyJWT(token, "HS256", key, 0, time.Unix(2000000001, 0)); got.Valid || !got.Expired {
+		t.Errorf("verifyJWT() after exp = %+v, want expired", got)
+	}
+}
+
+func TestFormatDigest(t *testing.T) {
+	sum, _ := hex.DecodeString("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	tests := []struct {
+		encoding string
+		prefixed bool
+		want     string
+	}{
+		{"", false, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"hex", true, "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"base64", false, "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+		{"base64url", false, "47DEQpj8HBSa-_TImW-5JCeuQeRkm5NMpJWZG3hSuFU"},
+	}
+	for _, tt := range tests {
+		got, err := formatDigest(sum, "sha256", tt.encoding, tt.prefixed)
+		if err != nil || got != tt.want {
+			t.Errorf("formatDigest(%q, %v) = %q, %v, want %q", tt.encoding, tt.prefixed, got, err, tt.want)
+		}
+	}
+	if _, err := formatDigest(sum, "sha256", "base32", false); err == nil {
+		t.Error("formatDigest() accepted an unsupported encoding")
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	tests := []struct {
+		name string
+		req  KDFRequest
+		want string
+	}{
+		{
+			// RFC 6070 test vector 2
+			name: "pbkdf2-sha1",
+			req:  KDFRequest{Algorithm: "pbkdf2", Hash: "sha1", Password: "password", Salt: "salt", Iterations: 2, KeyLength: 20},
+			want: "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957",
+		},
+		{
+			// RFC 7914 section 12, second vector
+			name: "scrypt",
+			req:  KDFRequest{Algorithm: "scrypt", Password: "password", Salt: "NaCl", N: 1024, R: 8, P: 16, KeyLength: 64},
+			want: "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640",
+		},
+		{
+			name: "hex salt",
+			req:  KDFRequest{Algorithm: "pbkdf2", Hash: "sha1", Password: "password", Salt: "73616c74", SaltEncoding: "hex", Iterations: 2, KeyLength: 20},
+			want: "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, key, err := deriveKey(tt.req)
+			if err != nil {
+				t.Fatalf("deriveKey() error = %v", err)
+			}
+			if got := hex.EncodeToString(key); got != tt.want {
+				t.Errorf("deriveKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := deriveKey(KDFRequest{Algorithm: "scrypt", Password: "p", N: 1000}); err == nil {
+		t.Error("deriveKey() accepted an N that is not a power of two")
+	}
+	if _, _, err := deriveKey(KDFRequest{Algorithm: "scrypt", Password: "p", N: 1 << 20, R: 8}); err == nil {
+		t.Error("deriveKey() accepted scrypt parameters over the memory limit")
+	}
+}
+
+func TestMatchImageDigests(t *testing.T) {
+	sum := sha256.Sum256([]byte("layer"))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	digests := []ImageDigest{
+		{Kind: "manifest", Digest: "sha256:" + strings.Repeat("0", 64)},
+		{Kind: "layer", Index: 1, Digest: digest},
+		{Kind: "diff_id", Index: 1, Digest: "sha512:" + strings.Repeat("0", 128)},
+		{Kind: "config", Digest: "not-a-digest"},
+	}
+
+	matches := matchImageDigests(map[string][]byte{"sha256": sum[:]}, digests)
+	if len(matches) != 1 || matches[0] != digests[1] {
+		t.Errorf("matchImageDigests() = %+v, want only the layer", matches)
+	}
+	if matches := matchImageDigests(map[string][]byte{"sha256": make([]byte, 32)}, digests[1:]); len(matches) != 0 {
+		t.Errorf("matchImageDigests() = %+v, want no matches", matches)
+	}
+}
+
+func TestHashURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			io.WriteString(w, "hello world")
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	tooltest.Run(t, tooltest.NewServer(t, s), []tooltest.Case{
+		{Name: "hash", Path: "/hash", Body: HashRequest{URL: srv.URL + "/ok", Algorithm: "sha256"}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out HashResponse
+			resp.Decode(&out)
+			if out.Hash != expected || out.InputLength != 11 {
+				t.Errorf("response = %+v", out)
+			}
+		}},
+		{Name: "verify", Path: "/verify", Body: VerifyRequest{HashRequest: HashRequest{URL: srv.URL + "/ok", Algorithm: "sha256"}, Expected: expected}},
+		{Name: "not http", Path: "/hash", Body: HashRequest{URL: "ftp://example.com/file", Algorithm: "sha256"}, Code: toolserver.CodeInvalidArgument},
+		{Name: "unsupported algorithm", Path: "/hash", Body: HashRequest{URL: srv.URL + "/ok", Algorithm: "foo"}, Code: toolserver.CodeInvalidArgument},
+		{Name: "over max_bytes", Path: "/hash", Body: HashRequest{URL: srv.URL + "/ok", Algorithm: "sha256", MaxBytes: 4}, Code: toolserver.CodeInvalidArgument},
+		{Name: "not found", Path: "/hash", Body: HashRequest{URL: srv.URL + "/missing", Algorithm: "sha256"}, Code: toolserver.CodeUpstreamError},
+		{Name: "verify not found", Path: "/verify", Body: VerifyRequest{HashRequest: HashRequest{URL: srv.URL + "/missing", Algorithm: "sha256"}, Expected: expected}, Code: toolserver.CodeUpstreamError},
+		{Name: "connection refused", Path: "/hash", Body: HashRequest{URL: closed.URL, Algorithm: "sha256"}, Code: toolserver.CodeUpstreamError, Check: func(t *testing.T, resp *tooltest.Response) {
+			if !resp.Error().Retryable {
+				t.Errorf("error %s is not retryable", resp.Body)
+			}
+		}},
+		{Name: "timeout", Path: "/hash", Body: HashRequest{URL: srv.URL + "/slow", Algorithm: "sha256", TimeoutSeconds: 1}, Code: toolserver.CodeUpstreamTimeout},
+	})
+}
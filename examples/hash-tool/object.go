@@ -0,0 +1,144 @@
+package hashtool
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"slices"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ObjectHashRequest represents the incoming request body for /hash-object
+type ObjectHashRequest struct {
+	Kind      string `json:"kind"` // secret or configmap
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key,omitempty"`       // hash one key; all keys when empty
+	Algorithm string `json:"algorithm,omitempty"` // default sha256
+}
+
+// ObjectHashResponse represents the outgoing response body for /hash-object.
+// Values are never returned, only their digests and sizes.
+type ObjectHashResponse struct {
+	Kind            string            `json:"kind"`
+	Namespace       string            `json:"namespace"`
+	Name            string            `json:"name"`
+	Algorithm       string            `json:"algorithm"`
+	ResourceVersion string            `json:"resource_version,omitempty"`
+	Hash            string            `json:"hash"`                    // the key's digest, or the combined digest of all keys
+	Keys            map[string]string `json:"keys,omitempty"`          // key -> digest, when no key was requested
+	Sizes           map[string]int    `json:"sizes,omitempty"`         // key -> value length in bytes
+	RetriedTimes    int               `json:"retried_times,omitempty"` // API calls retried after transient errors
+}
+
+func hashObject(ctx context.Context, req ObjectHashRequest) (ObjectHashResponse, error) {
+	if req.Name == "" {
+		return ObjectHashResponse{}, toolserver.BadRequest("name is required")
+	}
+	if req.Namespace == "" {
+		req.Namespace = "default"
+	}
+	clientset, err := kubeClient.ForNamespace(req.Namespace)
+	if err != nil {
+		return ObjectHashResponse{}, err
+	}
+	if req.Algorithm == "" {
+		req.Algorithm = "sha256"
+	}
+	newFn, err := newHash(req.Algorithm)
+	if err != nil {
+		return ObjectHashResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	resp := ObjectHashResponse{Kind: req.Kind, Namespace: req.Namespace, Name: req.Name, Algorithm: req.Algorithm}
+
+	ctx = kube.CountRetries(ctx)
+	var data map[string][]byte
+	switch req.Kind {
+	case "secret":
+		secret, err := kube.Retry(ctx, func(ctx context.Context) (*corev1.Secret, error) {
+			return clientset.CoreV1().Secrets(req.Namespace).Get(ctx, req.Name, metav1.GetOptions{})
+		})
+		if err != nil {
+			return ObjectHashResponse{}, objectError("secret", err)
+		}
+		resp.ResourceVersion = secret.ResourceVersion
+		data = secret.Data
+	case "configmap":
+		cm, err := kube.Retry(ctx, func(ctx context.Context) (*corev1.ConfigMap, error) {
+			return clientset.CoreV1().ConfigMaps(req.Namespace).Get(ctx, req.Name, metav1.GetOptions{})
+		})
+		if err != nil {
+			return ObjectHashResponse{}, objectError("configmap", err)
+		}
+		resp.ResourceVersion = cm.ResourceVersion
+		data = make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+		for k, v := range cm.Data {
+			data[k] = []byte(v)
+		}
+		for k, v := range cm.BinaryData {
+			data[k] = v
+		}
+	default:
+		return ObjectHashResponse{}, toolserver.BadRequest("kind must be secret or configmap")
+	}
+	resp.RetriedTimes = kube.Retried(ctx)
+
+	if req.Key != "" {
+		value, ok := data[req.Key]
+		if !ok {
+			return ObjectHashResponse{}, toolserver.Errorf(http.StatusNotFound, "key %q not found", req.Key)
+		}
+		h := newFn()
+		h.Write(value)
+		resp.Hash = hex.EncodeToString(h.Sum(nil))
+		resp.Sizes = map[string]int{req.Key: len(value)}
+		return resp, nil
+	}
+
+	resp.Keys = make(map[string]string, len(data))
+	resp.Sizes = make(map[string]int, len(data))
+	for k, v := range data {
+		h := newFn()
+		h.Write(v)
+		resp.Keys[k] = hex.EncodeToString(h.Sum(nil))
+		resp.Sizes[k] = len(v)
+	}
+	resp.Hash = combinedDigest(newFn(), data)
+
+	return resp, nil
+}
+
+// objectError reports a failed Get of the named kind.
+func objectError(kind string, err error) error {
+	return kube.Errorf(err, "failed to get %s", kind)
+}
+
+// combinedDigest hashes every key and value in key order, each prefixed with
+// its length so that no two different maps produce the same byte stream.
+// The result only depends on content, so it can be compared across clusters
+// whatever the object's metadata.
+func combinedDigest(h hash.Hash, data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var lenBuf [8]byte
+	for _, k := range keys {
+		binary.BigEndian.PutUint64(lenBuf[:], uint64(len(k)))
+		h.Write(lenBuf[:])
+		h.Write([]byte(k))
+		binary.BigEndian.PutUint64(lenBuf[:], uint64(len(data[k])))
+		h.Write(lenBuf[:])
+		h.Write(data[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
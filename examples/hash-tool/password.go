@@ -0,0 +1,191 @@
+package hashtool
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing defaults follow the RFC 9106 second recommended argon2id
+// profile and bcrypt's own default cost. Upper bounds keep a single request
+// from tying up the pod.
+const (
+	defaultArgon2Time    = 3
+	defaultArgon2Memory  = 64 * 1024 // KiB
+	defaultArgon2Threads = 4
+	argon2SaltLen        = 16
+	argon2KeyLen         = 32
+
+	maxArgon2Time    = 10
+	maxArgon2Memory  = 128 * 1024 // KiB; keep below the container memory limit
+	maxArgon2Threads = 16
+	maxBcryptCost    = 15
+)
+
+// PasswordHashRequest represents the incoming request body for /password-hash
+type PasswordHashRequest struct {
+	Password  string `json:"password"`
+	Algorithm string `json:"algorithm"` // bcrypt (default) or argon2id
+
+	Cost      int    `json:"cost,omitempty"`       // bcrypt cost, default 10
+	Time      uint32 `json:"time,omitempty"`       // argon2id iterations
+	MemoryKiB uint32 `json:"memory_kib,omitempty"` // argon2id memory
+	Threads   uint8  `json:"threads,omitempty"`    // argon2id parallelism
+}
+
+// PasswordHashResponse represents the outgoing response body for /password-hash
+type PasswordHashResponse struct {
+	Hash      string `json:"hash"`
+	Algorithm string `json:"algorithm"`
+}
+
+// PasswordVerifyRequest represents the incoming request body for /password-verify
+type PasswordVerifyRequest struct {
+	Password string `json:"password"`
+	Hash     string `json:"hash"` // bcrypt ($2a$/$2b$/$2y$) or argon2id PHC string
+}
+
+// PasswordVerifyResponse represents the outgoing response body for /password-verify
+type PasswordVerifyResponse struct {
+	Valid     bool   `json:"valid"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func passwordHash(ctx context.Context, req PasswordHashRequest) (PasswordHashResponse, error) {
+	if req.Password == "" {
+		return PasswordHashResponse{}, toolserver.BadRequest("password is required")
+	}
+	if req.Algorithm == "" {
+		req.Algorithm = "bcrypt"
+	}
+
+	var hash string
+	var err error
+	switch req.Algorithm {
+	case "bcrypt":
+		hash, err = hashBcrypt(req.Password, req.Cost)
+	case "argon2id":
+		hash, err = hashArgon2id(req.Password, req.Time, req.MemoryKiB, req.Threads)
+	default:
+		err = fmt.Errorf("unsupported password algorithm: %s", req.Algorithm)
+	}
+	if err != nil {
+		return PasswordHashResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	return PasswordHashResponse{Hash: hash, Algorithm: req.Algorithm}, nil
+}
+
+func passwordVerify(ctx context.Context, req PasswordVerifyRequest) (PasswordVerifyResponse, error) {
+	if req.Password == "" || req.Hash == "" {
+		return PasswordVerifyResponse{}, toolserver.BadRequest("password and hash are required")
+	}
+
+	algorithm, valid, err := verifyPassword(req.Password, req.Hash)
+	resp := PasswordVerifyResponse{Valid: valid, Algorithm: algorithm}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp, nil
+}
+
+func hashBcrypt(password string, cost int) (string, error) {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	if cost < bcrypt.MinCost || cost > maxBcryptCost {
+		return "", fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, maxBcryptCost)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// hashArgon2id returns a PHC-format string:
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>
+func hashArgon2id(password string, time, memory uint32, threads uint8) (string, error) {
+	if time == 0 {
+		time = defaultArgon2Time
+	}
+	if memory == 0 {
+		memory = defaultArgon2Memory
+	}
+	if threads == 0 {
+		threads = defaultArgon2Threads
+	}
+	if time > maxArgon2Time || memory > maxArgon2Memory || threads > maxArgon2Threads {
+		return "", fmt.Errorf("argon2id parameters exceed limits (time <= %d, memory_kib <= %d, threads <= %d)", maxArgon2Time, maxArgon2Memory, maxArgon2Threads)
+	}
+
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, argon2KeyLen)
+
+	b64 := base64.RawStdEncoding
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, memory, time, threads, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+// verifyPassword detects the algorithm from the hash prefix and checks the
+// password against it.
+func verifyPassword(password, encoded string) (string, bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return "bcrypt", false, nil
+		}
+		return "bcrypt", err == nil, err
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		valid, err := verifyArgon2id(password, encoded)
+		return "argon2id", valid, err
+	default:
+		return "", false, fmt.Errorf("unrecognized hash format")
+	}
+}
+
+func verifyArgon2id(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, fmt.Errorf("unsupported argon2id version: %s", parts[2])
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, fmt.Errorf("invalid argon2id parameters: %s", parts[3])
+	}
+	if time > maxArgon2Time || memory > maxArgon2Memory || threads > maxArgon2Threads {
+		return false, fmt.Errorf("argon2id parameters exceed limits")
+	}
+
+	b64 := base64.RawStdEncoding
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return false, fmt.Errorf("invalid argon2id salt: %v", err)
+	}
+	want, err := b64.DecodeString(parts[5])
+	if err != nil {
+		return false, fmt.Errorf("invalid argon2id hash: %v", err)
+	}
+
+	got := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1, nil
+}
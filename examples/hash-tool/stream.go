@@ -0,0 +1,98 @@
+package hashtool
+
+import (
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// handleHashStream hashes the raw request body as it arrives, so chunked
+// uploads of hundreds of megabytes never sit in memory or in a JSON string.
+// Options come from the query string: algorithm (comma-separated or
+// repeated), max_bytes (default 1 GiB), output_encoding and prefixed as in
+// /hash, e.g.
+//
+//	curl -T image.tar 'http://hash-tool-svc:8080/hash-stream?algorithm=sha256,md5'
+//
+// It is meant for direct HTTP clients; MCP tool calls carry JSON and should
+// use /hash with a url instead.
+func handleHashStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		toolserver.WriteError(w, toolserver.Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+
+	query := r.URL.Query()
+	var algorithms []string
+	for _, v := range query["algorithm"] {
+		for _, algorithm := range strings.Split(v, ",") {
+			if algorithm = strings.TrimSpace(algorithm); algorithm != "" {
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+	}
+	if len(algorithms) == 0 {
+		toolserver.WriteError(w, toolserver.BadRequest("algorithm is required"))
+		return
+	}
+
+	maxBytes := int64(defaultMaxFetchBytes)
+	if v := query.Get("max_bytes"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			toolserver.WriteError(w, toolserver.BadRequest("max_bytes must be a positive integer"))
+			return
+		}
+		maxBytes = parsed
+	}
+	outputEncoding := query.Get("output_encoding")
+	prefixed, _ := strconv.ParseBool(query.Get("prefixed"))
+	if _, err := formatDigest(nil, "", outputEncoding, false); err != nil {
+		toolserver.WriteError(w, toolserver.BadRequest("%v", err))
+		return
+	}
+	if r.ContentLength > maxBytes {
+		toolserver.WriteError(w, toolserver.Errorf(http.StatusRequestEntityTooLarge, "content length %d exceeds max_bytes %d", r.ContentLength, maxBytes))
+		return
+	}
+
+	digests := make(map[string]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		if _, ok := digests[algorithm]; ok {
+			continue
+		}
+		h, err := newDigest(algorithm, "hash", nil)
+		if err != nil {
+			toolserver.WriteError(w, toolserver.BadRequest("%v", err))
+			return
+		}
+		digests[algorithm] = h
+		writers = append(writers, h)
+	}
+
+	n, err := io.Copy(io.MultiWriter(writers...), io.LimitReader(r.Body, maxBytes+1))
+	if err != nil {
+		toolserver.WriteError(w, toolserver.BadRequest("reading body: %v", err))
+		return
+	}
+	if n > maxBytes {
+		toolserver.WriteError(w, toolserver.Errorf(http.StatusRequestEntityTooLarge, "body exceeds max_bytes %d", maxBytes))
+		return
+	}
+
+	resp := HashResponse{Algorithm: algorithms[0], InputLength: int(n)}
+	resp.Hash, _ = formatDigest(digests[algorithms[0]].Sum(nil), algorithms[0], outputEncoding, prefixed)
+	if len(digests) > 1 {
+		resp.Hashes = make(map[string]string, len(digests))
+		for algorithm, h := range digests {
+			resp.Hashes[algorithm], _ = formatDigest(h.Sum(nil), algorithm, outputEncoding, prefixed)
+		}
+	}
+
+	toolserver.WriteJSON(w, http.StatusOK, resp)
+}
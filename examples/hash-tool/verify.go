@@ -0,0 +1,80 @@
+package hashtool
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// VerifyRequest represents the incoming request body for /verify. It takes
+// every /hash input option plus the expected digest.
+type VerifyRequest struct {
+	HashRequest
+	// Expected is hex or base64, optionally prefixed with "<algorithm>:" or
+	// "<algorithm>=" as in OCI digests and webhook signature headers.
+	Expected string `json:"expected"`
+}
+
+// VerifyResponse represents the outgoing response body for /verify
+type VerifyResponse struct {
+	Match       bool   `json:"match"`
+	Algorithm   string `json:"algorithm"`
+	InputLength int    `json:"input_length"`
+}
+
+func verify(ctx context.Context, req VerifyRequest) (VerifyResponse, error) {
+	if req.Input == "" && req.URL == "" {
+		return VerifyResponse{}, toolserver.BadRequest("input or url is required")
+	}
+	if req.Algorithm == "" {
+		return VerifyResponse{}, toolserver.BadRequest("algorithm is required")
+	}
+	if req.Expected == "" {
+		return VerifyResponse{}, toolserver.BadRequest("expected is required")
+	}
+
+	sums, n, err := computeDigests(ctx, req.HashRequest, []string{req.Algorithm})
+	if err != nil {
+		return VerifyResponse{}, err
+	}
+
+	sum := sums[req.Algorithm]
+	expected, err := parseExpectedDigest(req.Expected, req.Algorithm, len(sum))
+	if err != nil {
+		return VerifyResponse{}, toolserver.BadRequest("%v", err)
+	}
+	return VerifyResponse{
+		Algorithm:   req.Algorithm,
+		InputLength: int(n),
+		Match:       subtle.ConstantTimeCompare(sum, expected) == 1,
+	}, nil
+}
+
+// parseExpectedDigest decodes an expected digest of size bytes. It is read as
+// hex when its length matches a hex digest and as base64 otherwise.
+func parseExpectedDigest(expected, algorithm string, size int) ([]byte, error) {
+	expected = strings.TrimSpace(expected)
+	for _, sep := range []string{":", "="} {
+		if prefix := algorithm + sep; len(expected) > len(prefix) && strings.EqualFold(expected[:len(prefix)], prefix) {
+			expected = expected[len(prefix):]
+			break
+		}
+	}
+
+	if len(expected) == hex.EncodedLen(size) {
+		if decoded, err := hex.DecodeString(expected); err == nil {
+			return decoded, nil
+		}
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(expected); err == nil && len(decoded) == size {
+			return decoded, nil
+		}
+	}
+	return nil, fmt.Errorf("expected is not a %d-byte %s digest in hex or base64", size, algorithm)
+}
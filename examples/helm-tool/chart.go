@@ -0,0 +1,100 @@
+package helmtool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// chartLayerMediaType is the media type of the layer holding the chart
+// archive in a chart pushed to an OCI registry with helm push.
+const chartLayerMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+
+// maxChartSize bounds a chart archive as pulled; Helm's loader bounds it
+// uncompressed.
+const maxChartSize = 20 << 20
+
+// registryTransport is used for registry requests so they are counted in
+// /metrics and go through the egress proxy, trusting the CA bundle.
+var registryTransport = toolserver.InstrumentTransport("registry", toolserver.Outbound(remote.DefaultTransport))
+
+// pullChart fetches the chart at ref, an OCI reference such as
+// oci://ghcr.io/stefanprodan/charts/podinfo:6.7.1.
+func pullChart(ctx context.Context, ref string) (*chart.Chart, error) {
+	r, err := name.ParseReference(strings.TrimPrefix(ref, "oci://"))
+	if err != nil {
+		return nil, toolserver.BadRequest("chart %q is not an OCI reference such as oci://ghcr.io/org/charts/app:1.0.0: %v", ref, err)
+	}
+	img, err := remote.Image(r, remote.WithContext(ctx), remote.WithTransport(registryTransport))
+	if err != nil {
+		return nil, registryError(err, "failed to fetch chart %s", ref)
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, registryError(err, "failed to fetch chart %s", ref)
+	}
+	for _, layer := range layers {
+		if mt, err := layer.MediaType(); err != nil || mt != chartLayerMediaType {
+			continue
+		}
+		rc, err := layer.Compressed()
+		if err != nil {
+			return nil, registryError(err, "failed to fetch chart %s", ref)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, maxChartSize+1))
+		if err != nil {
+			return nil, registryError(err, "failed to fetch chart %s", ref)
+		}
+		if len(data) > maxChartSize {
+			return nil, toolserver.NewError(toolserver.CodeUnprocessable, "chart %s is over %d MiB", ref, maxChartSize>>20)
+		}
+		c, err := loader.LoadArchive(bytes.NewReader(data))
+		if err != nil {
+			return nil, toolserver.NewError(toolserver.CodeUnprocessable, "chart %s: %v", ref, err)
+		}
+		return c, checkDependencies(c)
+	}
+	return nil, toolserver.NewError(toolserver.CodeUnprocessable, "%s is not a Helm chart: it has no %s layer", ref, chartLayerMediaType)
+}
+
+// registryError reports a failed registry request, with a formatted message
+// followed by err: NOT_FOUND for a missing repository or tag, FORBIDDEN
+// when the registry refuses the tool's credentials, and otherwise an
+// upstream error.
+func registryError(err error, format string, args ...any) error {
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		switch terr.StatusCode {
+		case http.StatusNotFound:
+			return toolserver.NewError(toolserver.CodeNotFound, "%s: %v", fmt.Sprintf(format, args...), err)
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return toolserver.NewError(toolserver.CodeForbidden, "%s: %v", fmt.Sprintf(format, args...), err)
+		}
+	}
+	return toolserver.UpstreamError(err, format, args...)
+}
+
+// checkDependencies fails with UNPROCESSABLE if a dependency listed in
+// Chart.yaml is missing from the chart's charts/ directory, as helm install
+// does.
+func checkDependencies(c *chart.Chart) error {
+	for _, dep := range c.Metadata.Dependencies {
+		if !slices.ContainsFunc(c.Dependencies(), func(d *chart.Chart) bool { return d.Name() == dep.Name }) {
+			return toolserver.NewError(toolserver.CodeUnprocessable, "chart %s lists dependency %s, which is missing from its charts/ directory", c.Name(), dep.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+// Command helm-tool serves helm-tool on its own. kube-mcp-tools serves it together
+// with the other example tools.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	helmtool "helm-tool"
+)
+
+func main() {
+	s, err := helmtool.New()
+	if err != nil {
+		slog.Error("starting helm-tool", "err", err)
+		os.Exit(1)
+	}
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,341 @@
+// Package helmtool implements helm-tool, which reports the Helm releases in
+// a cluster: their charts, status, values, manifests, notes and history. It
+// also renders charts and diffs an upgrade against a release's manifest.
+package helmtool
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// kubeClient is loaded on first use. Until it can be, every endpoint fails
+// with 503 and /readyz reports why.
+var kubeClient = kube.New("helm-tool")
+
+// staleMaxAge is how long listings are kept to answer with, marked stale,
+// while the API server cannot be reached.
+const staleMaxAge = 15 * time.Minute
+
+// defaultHistoryMax is how many revisions /history returns by default, the
+// latest ones.
+const defaultHistoryMax = 10
+
+// --- /releases types ---
+
+type ReleasesRequest struct {
+	Namespace string `json:"namespace"` // empty lists every namespace
+}
+
+type ReleaseInfo struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	Revision     int    `json:"revision"`
+	Status       string `json:"status"`
+	Chart        string `json:"chart"`
+	ChartVersion string `json:"chartVersion"`
+	AppVersion   string `json:"appVersion,omitempty"`
+	Updated      string `json:"updated,omitempty"`
+	Error        string `json:"error,omitempty"` // why the release could not be read; the rest is from its labels
+}
+
+type ReleasesResponse struct {
+	Releases     []ReleaseInfo `json:"releases"`
+	Count        int           `json:"count"`
+	RetriedTimes int           `json:"retriedTimes,omitempty"` // API calls retried after transient errors
+}
+
+// --- /history types ---
+
+type HistoryRequest struct {
+	Namespace string `json:"namespace"` // defaults to "default"
+	Name      string `json:"name"`
+	Max       int    `json:"max"` // the latest revisions to return; defaults to 10
+}
+
+type Revision struct {
+	Revision     int    `json:"revision"`
+	Status       string `json:"status"`
+	Chart        string `json:"chart"`
+	ChartVersion string `json:"chartVersion"`
+	AppVersion   string `json:"appVersion,omitempty"`
+	Updated      string `json:"updated,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type HistoryResponse struct {
+	Name         string     `json:"name"`
+	Namespace    string     `json:"namespace"`
+	Revisions    []Revision `json:"revisions"` // oldest first
+	Total        int        `json:"total"`     // revisions stored, including those not returned
+	RetriedTimes int        `json:"retriedTimes,omitempty"`
+}
+
+// --- /values, /manifest and /notes types ---
+
+type ReleaseRequest struct {
+	Namespace string `json:"namespace"` // defaults to "default"
+	Name      string `json:"name"`
+	Revision  int    `json:"revision"` // defaults to the latest
+}
+
+type ValuesRequest struct {
+	Namespace string `json:"namespace"` // defaults to "default"
+	Name      string `json:"name"`
+	Revision  int    `json:"revision"` // defaults to the latest
+	All       bool   `json:"all"`      // the computed values: the chart's defaults with the user's applied
+}
+
+type ValuesResponse struct {
+	Name         string         `json:"name"`
+	Namespace    string         `json:"namespace"`
+	Revision     int            `json:"revision"`
+	Chart        string         `json:"chart"`
+	ChartVersion string         `json:"chartVersion"`
+	All          bool           `json:"all"`
+	Values       map[string]any `json:"values"`
+	RetriedTimes int            `json:"retriedTimes,omitempty"`
+}
+
+type ManifestResponse struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	Revision     int    `json:"revision"`
+	Manifest     string `json:"manifest"`
+	RetriedTimes int    `json:"retriedTimes,omitempty"`
+}
+
+// ContentBlocks gives MCP clients the manifest as a YAML resource instead
+// of a JSON string with escaped newlines.
+func (r ManifestResponse) ContentBlocks() []toolserver.Content {
+	uri := fmt.Sprintf("helm://namespaces/%s/releases/%s/manifest?revision=%d", r.Namespace, r.Name, r.Revision)
+	return []toolserver.Content{toolserver.ResourceContent(uri, "application/yaml", r.Manifest)}
+}
+
+type NotesResponse struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	Revision     int    `json:"revision"`
+	Notes        string `json:"notes"`
+	RetriedTimes int    `json:"retriedTimes,omitempty"`
+}
+
+// New returns the helm-tool server, which connects to the cluster from
+// inside it, or else with the kubeconfig, on first use.
+func New() (*toolserver.Server, error) {
+	configureAPICalls()
+
+	s := toolserver.New("helm-tool")
+	s.AddReadinessCheck("kubernetes", kubeClient.Ready)
+	s.AddDiagnostics("kubernetes", kubeClient.Diagnostics)
+	s.AddWarmup("kubernetes", kubeClient.Warm)
+	s.CheckRBAC(kubeClient.Allowed)
+	toolserver.Register(s, "/releases", listReleases,
+		toolserver.Name("helm-releases"), toolserver.Describe("List the Helm releases in a namespace, or in every namespace, with their chart and status."),
+		toolserver.ServeStale(staleMaxAge), toolserver.RBAC("list secrets"))
+	toolserver.Register(s, "/history", history,
+		toolserver.Name("helm-history"), toolserver.Describe("List the revisions of a Helm release with their chart, status and description."),
+		toolserver.ServeStale(staleMaxAge), toolserver.RBAC("list secrets"))
+	toolserver.Register(s, "/values", values,
+		toolserver.Name("helm-values"), toolserver.Describe("Show the values a Helm release was installed with, or all its computed values."),
+		toolserver.RBAC("get secrets", "list secrets"))
+	toolserver.Register(s, "/manifest", manifest,
+		toolserver.Name("helm-manifest"), toolserver.Describe("Show the manifest a Helm release rendered."),
+		toolserver.RBAC("get secrets", "list secrets"))
+	toolserver.Register(s, "/notes", notes,
+		toolserver.Name("helm-notes"), toolserver.Describe("Show the notes a Helm release's chart printed on install or upgrade."),
+		toolserver.RBAC("get secrets", "list secrets"))
+	toolserver.Register(s, "/template", templateChart,
+		toolserver.Name("helm-template"), toolserver.Describe("Render a Helm chart, from an OCI registry or an installed release, with the given values, as helm template does."),
+		toolserver.Timeout(renderTimeout), toolserver.RBAC("get secrets", "list secrets"))
+	toolserver.Register(s, "/diff", diffUpgrade,
+		toolserver.Name("helm-diff"), toolserver.Describe("Show what upgrading a Helm release with the given chart or values would change in its manifest, resource by resource."),
+		toolserver.Timeout(renderTimeout), toolserver.RBAC("get secrets", "list secrets"))
+
+	return s, nil
+}
+
+// chartInfo returns the chart name, version and app version of rel.
+func chartInfo(rel *release) (chart, version, appVersion string) {
+	md := rel.Chart.Metadata
+	return md.Name, md.Version, md.AppVersion
+}
+
+func listReleases(ctx context.Context, req ReleasesRequest) (ReleasesResponse, error) {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	secrets, err := listReleaseSecrets(ctx, req.Namespace, "")
+	if err != nil {
+		return ReleasesResponse{}, err
+	}
+
+	// Only the latest revision of each release is decoded.
+	latest := make(map[[2]string]*corev1.Secret)
+	for i := range secrets {
+		secret := &secrets[i]
+		key := [2]string{secret.Namespace, secret.Labels["name"]}
+		if prev, ok := latest[key]; !ok || secretRevision(secret) > secretRevision(prev) {
+			latest[key] = secret
+		}
+	}
+	releases := make([]ReleaseInfo, 0, len(latest))
+	for _, secret := range latest {
+		rel, err := releaseFromSecret(secret)
+		if err != nil {
+			releases = append(releases, ReleaseInfo{
+				Name:      secret.Labels["name"],
+				Namespace: secret.Namespace,
+				Revision:  secretRevision(secret),
+				Status:    secret.Labels["status"],
+				Error:     err.Error(),
+			})
+			continue
+		}
+		chart, version, appVersion := chartInfo(rel)
+		releases = append(releases, ReleaseInfo{
+			Name:         rel.Name,
+			Namespace:    rel.Namespace,
+			Revision:     rel.Version,
+			Status:       rel.Info.Status,
+			Chart:        chart,
+			ChartVersion: version,
+			AppVersion:   appVersion,
+			Updated:      rel.Info.LastDeployed,
+		})
+	}
+	slices.SortFunc(releases, func(a, b ReleaseInfo) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name))
+	})
+
+	return ReleasesResponse{Releases: releases, Count: len(releases), RetriedTimes: kube.Retried(ctx)}, nil
+}
+
+func history(ctx context.Context, req HistoryRequest) (HistoryResponse, error) {
+	if err := checkName(req.Name); err != nil {
+		return HistoryResponse{}, err
+	}
+	namespace := cmp.Or(req.Namespace, "default")
+	limit := req.Max
+	if limit <= 0 {
+		limit = defaultHistoryMax
+	}
+
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	secrets, err := listReleaseSecrets(ctx, namespace, req.Name)
+	if err != nil {
+		return HistoryResponse{}, err
+	}
+	if len(secrets) == 0 {
+		return HistoryResponse{}, toolserver.NewError(toolserver.CodeNotFound, "release %s not found in namespace %s", req.Name, namespace)
+	}
+	slices.SortFunc(secrets, func(a, b corev1.Secret) int {
+		return cmp.Compare(secretRevision(&a), secretRevision(&b))
+	})
+	total := len(secrets)
+	secrets = secrets[max(0, total-limit):]
+
+	revisions := make([]Revision, 0, len(secrets))
+	for i := range secrets {
+		rel, err := releaseFromSecret(&secrets[i])
+		if err != nil {
+			return HistoryResponse{}, err
+		}
+		chart, version, appVersion := chartInfo(rel)
+		revisions = append(revisions, Revision{
+			Revision:     rel.Version,
+			Status:       rel.Info.Status,
+			Chart:        chart,
+			ChartVersion: version,
+			AppVersion:   appVersion,
+			Updated:      rel.Info.LastDeployed,
+			Description:  rel.Info.Description,
+		})
+	}
+
+	return HistoryResponse{Name: req.Name, Namespace: namespace, Revisions: revisions, Total: total, RetriedTimes: kube.Retried(ctx)}, nil
+}
+
+// checkName checks the release name of a request. Helm's release names are
+// label values, as the Secrets storing them are labeled with them.
+func checkName(name string) error {
+	if name == "" {
+		return toolserver.BadRequest("name is required")
+	}
+	if errs := validation.IsValidLabelValue(name); len(errs) > 0 {
+		return toolserver.BadRequest("name %q is not a release name: %s", name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// fetchRelease returns the release a request names, its latest revision
+// unless it names one.
+func fetchRelease(ctx context.Context, namespace, name string, revision int) (*release, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	if revision < 0 {
+		return nil, toolserver.BadRequest("revision must be positive")
+	}
+	return getRelease(ctx, cmp.Or(namespace, "default"), name, revision)
+}
+
+func values(ctx context.Context, req ValuesRequest) (ValuesResponse, error) {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	rel, err := fetchRelease(ctx, req.Namespace, req.Name, req.Revision)
+	if err != nil {
+		return ValuesResponse{}, err
+	}
+	vals := rel.Config
+	if req.All {
+		vals = coalesceValues(rel.Chart.Values, rel.Config)
+	}
+	if vals == nil {
+		vals = map[string]any{}
+	}
+	chart, version, _ := chartInfo(rel)
+	return ValuesResponse{
+		Name:         rel.Name,
+		Namespace:    rel.Namespace,
+		Revision:     rel.Version,
+		Chart:        chart,
+		ChartVersion: version,
+		All:          req.All,
+		Values:       vals,
+		RetriedTimes: kube.Retried(ctx),
+	}, nil
+}
+
+func manifest(ctx context.Context, req ReleaseRequest) (ManifestResponse, error) {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	rel, err := fetchRelease(ctx, req.Namespace, req.Name, req.Revision)
+	if err != nil {
+		return ManifestResponse{}, err
+	}
+	return ManifestResponse{Name: rel.Name, Namespace: rel.Namespace, Revision: rel.Version, Manifest: rel.Manifest, RetriedTimes: kube.Retried(ctx)}, nil
+}
+
+func notes(ctx context.Context, req ReleaseRequest) (NotesResponse, error) {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	rel, err := fetchRelease(ctx, req.Namespace, req.Name, req.Revision)
+	if err != nil {
+		return NotesResponse{}, err
+	}
+	return NotesResponse{Name: rel.Name, Namespace: rel.Namespace, Revision: rel.Version, Notes: rel.Info.Notes, RetriedTimes: kube.Retried(ctx)}, nil
+}
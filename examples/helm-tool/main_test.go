@@ -0,0 +1,489 @@
+package helmtool
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"sigs.k8s.io/yaml"
+)
+
+// podinfoTemplates are the templates of the test's podinfo chart, a cut
+// down https://github.com/stefanprodan/podinfo chart.
+var podinfoTemplates = map[string]string{
+	"templates/_helpers.tpl": `{{- define "podinfo.fullname" -}}
+{{ .Release.Name | trunc 63 | trimSuffix "-" }}
+{{- end }}
+
+{{- define "podinfo.labels" -}}
+app.kubernetes.io/name: {{ .Chart.Name }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- end }}
+`,
+	"templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "podinfo.fullname" . }}
+  labels:
+    {{- include "podinfo.labels" . | nindent 4 }}
+spec:
+  replicas: {{ .Values.replicaCount }}
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: {{ .Chart.Name }}
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: {{ .Chart.Name }}
+    spec:
+      containers:
+        - name: podinfo
+          image: "{{ .Values.image.repository }}:{{ .Values.image.tag | default .Chart.AppVersion }}"
+          imagePullPolicy: {{ .Values.image.pullPolicy }}
+          {{- with .Values.resources }}
+          resources:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+`,
+	"templates/service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ include "podinfo.fullname" . }}
+  labels:
+    {{- include "podinfo.labels" . | nindent 4 }}
+spec:
+  ports:
+    - port: 9898
+  selector:
+    app.kubernetes.io/name: {{ .Chart.Name }}
+`,
+	"templates/ingress.yaml": `{{- if .Values.ingress.enabled -}}
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: {{ include "podinfo.fullname" . }}
+spec:
+  rules:
+    - host: {{ required "ingress.host is required with the ingress enabled" .Values.ingress.host | quote }}
+      http:
+        paths:
+          - path: /
+            pathType: Prefix
+            backend:
+              service:
+                name: {{ include "podinfo.fullname" . }}
+                port:
+                  number: 9898
+{{- end }}
+`,
+	"templates/tests/test-connection.yaml": `apiVersion: v1
+kind: Pod
+metadata:
+  name: {{ include "podinfo.fullname" . }}-test
+  annotations:
+    helm.sh/hook: test
+spec:
+  containers:
+    - name: curl
+      image: curlimages/curl:8.10.1
+      args: ["{{ include "podinfo.fullname" . }}:9898"]
+  restartPolicy: Never
+`,
+	"templates/NOTES.txt": `Visit http://{{ include "podinfo.fullname" . }}.{{ .Release.Namespace }}.svc to use {{ .Release.Name }}.
+`,
+}
+
+// podinfoValues are the default values of the test's podinfo chart.
+const podinfoValues = `replicaCount: 1
+image:
+  repository: ghcr.io/stefanprodan/podinfo
+  tag: ""
+  pullPolicy: IfNotPresent
+ingress:
+  enabled: false
+resources: {}
+`
+
+// storedManifest returns the manifest Helm stores for a release of the
+// podinfo chart, as its templates render it.
+func storedManifest(name string, replicas any, tag string) string {
+	return fmt.Sprintf(`---
+# Source: podinfo/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+  labels:
+    app.kubernetes.io/name: podinfo
+    app.kubernetes.io/instance: %[1]s
+spec:
+  ports:
+    - port: 9898
+  selector:
+    app.kubernetes.io/name: podinfo
+---
+# Source: podinfo/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: %[1]s
+  labels:
+    app.kubernetes.io/name: podinfo
+    app.kubernetes.io/instance: %[1]s
+spec:
+  replicas: %[2]v
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: podinfo
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: podinfo
+    spec:
+      containers:
+        - name: podinfo
+          image: "ghcr.io/stefanprodan/podinfo:%[3]s"
+          imagePullPolicy: IfNotPresent
+`, name, replicas, tag)
+}
+
+// releaseSecret returns the Secret Helm stores a revision of a release of
+// the podinfo chart in.
+func releaseSecret(t *testing.T, namespace, name string, revision int, status, chartVersion string, config map[string]any) *corev1.Secret {
+	var defaults map[string]any
+	if err := yaml.Unmarshal([]byte(podinfoValues), &defaults); err != nil {
+		t.Fatal(err)
+	}
+	var templates []map[string]any
+	for _, name := range slices.Sorted(maps.Keys(podinfoTemplates)) {
+		templates = append(templates, map[string]any{"name": name, "data": []byte(podinfoTemplates[name])})
+	}
+	computed := coalesceValues(defaults, config)
+	tag := cmp.Or(computed["image"].(map[string]any)["tag"].(string), "6.7.0")
+	rel := map[string]any{
+		"name":      name,
+		"namespace": namespace,
+		"version":   revision,
+		"info": map[string]any{
+			"first_deployed": "2026-09-01T10:00:00Z",
+			"last_deployed":  fmt.Sprintf("2026-09-%02dT10:00:00Z", revision),
+			"deleted":        "",
+			"description":    fmt.Sprintf("Upgrade to %s complete", chartVersion),
+			"status":         status,
+			"notes":          fmt.Sprintf("Visit http://%s.%s.svc to use %s.", name, namespace, name),
+		},
+		"chart": map[string]any{
+			"metadata":  map[string]any{"name": "podinfo", "version": chartVersion, "appVersion": "6.7.0"},
+			"values":    defaults,
+			"templates": templates,
+		},
+		"config":   config,
+		"manifest": storedManifest(name, computed["replicaCount"], tag),
+	}
+	data, err := json.Marshal(rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      fmt.Sprintf("sh.helm.release.v1.%s.v%d", name, revision),
+			Labels:    map[string]string{"owner": "helm", "name": name, "version": fmt.Sprint(revision), "status": status},
+		},
+		Type: "helm.sh/release.v1",
+		Data: map[string][]byte{"release": []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))},
+	}
+}
+
+// chartImage returns a chart as helm push pushes it: an image whose layer
+// is the chart's archive, with files keyed by their path in the chart.
+func chartImage(t *testing.T, files map[string]string) v1.Image {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		tw.WriteHeader(&tar.Header{Name: "podinfo/" + name, Mode: 0o644, Size: int64(len(files[name])), Typeflag: tar.TypeReg})
+		tw.Write([]byte(files[name]))
+	}
+	tw.Close()
+	zw.Close()
+	img, err := mutate.Append(empty.Image, mutate.Addendum{Layer: static.NewLayer(buf.Bytes(), chartLayerMediaType)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// podinfoChart returns the files of the podinfo chart at version, with a
+// redis subchart its redis.enabled value enables, and extra files.
+func podinfoChart(version string, extra map[string]string) map[string]string {
+	files := map[string]string{
+		"Chart.yaml": fmt.Sprintf(`apiVersion: v2
+name: podinfo
+version: %s
+appVersion: 6.7.0
+kubeVersion: ">=1.25.0-0"
+dependencies:
+  - name: redis
+    version: 1.0.0
+    condition: redis.enabled
+`, version),
+		"values.yaml":              podinfoValues + "redis:\n  enabled: false\n",
+		"charts/redis/Chart.yaml":  "apiVersion: v2\nname: redis\nversion: 1.0.0\n",
+		"charts/redis/values.yaml": "port: 6379\n",
+		"charts/redis/templates/service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Release.Name }}-redis
+spec:
+  ports:
+    - port: {{ .Values.port }}
+`,
+		"charts/redis/templates/pdb.yaml": `{{- if .Capabilities.APIVersions.Has "policy/v1/PodDisruptionBudget" -}}
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: {{ .Release.Name }}-redis
+spec:
+  minAvailable: 1
+{{- end }}
+`,
+	}
+	for name, data := range podinfoTemplates {
+		files[name] = data
+	}
+	for name, data := range extra {
+		files[name] = data
+	}
+	return files
+}
+
+func testCluster(t *testing.T) []runtime.Object {
+	corrupt := releaseSecret(t, "web", "broken", 1, "deployed", "1.0.0", nil)
+	corrupt.Data["release"] = []byte("not a release")
+	return []runtime.Object{
+		releaseSecret(t, "web", "frontend", 1, "superseded", "6.6.0", map[string]any{"replicaCount": 2}),
+		releaseSecret(t, "web", "frontend", 2, "superseded", "6.7.0", map[string]any{"replicaCount": 3}),
+		releaseSecret(t, "web", "frontend", 3, "deployed", "6.7.1", map[string]any{
+			"replicaCount": 3,
+			"image":        map[string]any{"tag": "6.7.1"},
+			"resources":    nil,
+		}),
+		releaseSecret(t, "monitoring", "grafana", 1, "failed", "8.5.0", nil),
+		corrupt,
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "db-password"}, Data: map[string][]byte{"password": []byte("hunter2")}},
+	}
+}
+
+func newTestServer(t *testing.T) *tooltest.Server {
+	k := tooltest.FakeKube(t, kubeClient, testCluster(t)...)
+	disco := k.Clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	disco.FakedServerVersion = &version.Info{Major: "1", Minor: "35", GitVersion: "v1.35.1"}
+	disco.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "services", Kind: "Service"}, {Name: "secrets", Kind: "Secret", Namespaced: true}, {Name: "pods/log", Kind: "Pod"}}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment"}}},
+		{GroupVersion: "policy/v1", APIResources: []metav1.APIResource{{Name: "poddisruptionbudgets", Kind: "PodDisruptionBudget"}}},
+	}
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tooltest.NewServer(t, s)
+}
+
+func TestHandlers(t *testing.T) {
+	tooltest.Run(t, newTestServer(t), []tooltest.Case{
+		{Name: "releases", Path: "/releases", Body: `{}`, Golden: "releases"},
+		{Name: "releases in namespace", Path: "/releases", Body: `{"namespace":"monitoring"}`, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out ReleasesResponse
+			resp.Decode(&out)
+			if out.Count != 1 || out.Releases[0].Name != "grafana" || out.Releases[0].Status != "failed" {
+				t.Errorf("releases = %+v", out)
+			}
+		}},
+		{Name: "history", Path: "/history", Body: `{"namespace":"web","name":"frontend"}`, Golden: "history"},
+		{Name: "latest history", Path: "/history", Body: `{"namespace":"web","name":"frontend","max":1}`, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out HistoryResponse
+			resp.Decode(&out)
+			if out.Total != 3 || len(out.Revisions) != 1 || out.Revisions[0].Revision != 3 {
+				t.Errorf("history = %+v", out)
+			}
+		}},
+		{Name: "history of missing release", Path: "/history", Body: `{"namespace":"web","name":"nope"}`, Code: toolserver.CodeNotFound},
+		{Name: "values", Path: "/values", Body: `{"namespace":"web","name":"frontend"}`, Golden: "values"},
+		{Name: "computed values", Path: "/values", Body: `{"namespace":"web","name":"frontend","all":true}`, Golden: "values-all"},
+		{Name: "values of revision", Path: "/values", Body: `{"namespace":"web","name":"frontend","revision":1}`, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out ValuesResponse
+			resp.Decode(&out)
+			if out.Revision != 1 || out.ChartVersion != "6.6.0" || out.Values["replicaCount"] != 2.0 {
+				t.Errorf("values = %+v", out)
+			}
+		}},
+		{Name: "values without config", Path: "/values", Body: `{"namespace":"monitoring","name":"grafana"}`, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out ValuesResponse
+			resp.Decode(&out)
+			if out.Values == nil || len(out.Values) != 0 {
+				t.Errorf("values = %+v, want {}", out.Values)
+			}
+		}},
+		{Name: "manifest", Path: "/manifest", Body: `{"namespace":"web","name":"frontend"}`, Golden: "manifest"},
+		{Name: "notes", Path: "/notes", Body: `{"namespace":"web","name":"frontend","revision":2}`, Golden: "notes"},
+		{Name: "missing revision", Path: "/manifest", Body: `{"namespace":"web","name":"frontend","revision":9}`, Code: toolserver.CodeNotFound},
+		{Name: "missing release", Path: "/notes", Body: `{"name":"frontend"}`, Code: toolserver.CodeNotFound},
+		{Name: "corrupt release", Path: "/manifest", Body: `{"namespace":"web","name":"broken"}`, Code: toolserver.CodeInternal},
+		{Name: "without name", Path: "/values", Body: `{"namespace":"web"}`, Code: toolserver.CodeInvalidArgument},
+		{Name: "selector in name", Path: "/history", Body: `{"namespace":"web","name":"frontend,owner!=helm"}`, Code: toolserver.CodeInvalidArgument},
+	})
+}
+
+func TestTemplateAndDiff(t *testing.T) {
+	registry := tooltest.Registry(t)
+	tooltest.PushImage(t, registry+"/charts/podinfo:6.8.0", chartImage(t, podinfoChart("6.8.0", nil)))
+	tooltest.PushImage(t, registry+"/charts/podinfo:7.0.0", chartImage(t, podinfoChart("7.0.0", map[string]string{
+		"Chart.yaml": "apiVersion: v2\nname: podinfo\nversion: 7.0.0\nkubeVersion: \">=1.40.0\"\n",
+	})))
+	tooltest.PushImage(t, registry+"/charts/podinfo:6.9.0", chartImage(t, podinfoChart("6.9.0", map[string]string{
+		"templates/configmap.yaml": `{{- $secret := lookup "v1" "Secret" .Release.Namespace "db-password" -}}
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-config
+data:
+  passwordSet: {{ hasKey ($secret.data | default dict) "password" | quote }}
+`,
+	})))
+	tooltest.PushImage(t, registry+"/charts/podinfo:6.9.1", chartImage(t, podinfoChart("6.9.1", map[string]string{
+		"templates/configmap.yaml": "{{ toIni .Values }}",
+	})))
+	tooltest.PushImage(t, registry+"/charts/nginx:1.27", nil)
+	chart := func(tag string) string { return "oci://" + registry + "/charts/" + tag }
+	changes := func(t *testing.T, resp *tooltest.Response) []Change {
+		var out DiffResponse
+		resp.Decode(&out)
+		return out.Changes
+	}
+
+	tooltest.Run(t, newTestServer(t), []tooltest.Case{
+		{Name: "template chart", Path: "/template", Body: UpgradeRequest{Chart: chart("podinfo:6.8.0"), Values: map[string]any{"redis": map[string]any{"enabled": true}}}, Golden: "template"},
+		{Name: "template release", Path: "/template", Body: `{"namespace":"web","name":"frontend"}`, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out TemplateResponse
+			resp.Decode(&out)
+			var kinds []string
+			for _, r := range out.Resources {
+				kinds = append(kinds, r.Kind+"/"+r.Hook)
+			}
+			if got := strings.Join(kinds, " "); got != "Service/ Deployment/ Pod/test" {
+				t.Errorf("resources = %s, want the Service, the Deployment, then the test hook", got)
+			}
+			if out.ChartVersion != "6.7.1" || out.Notes != "Visit http://frontend.web.svc to use frontend.\n" {
+				t.Errorf("response = %+v", out)
+			}
+		}},
+		{Name: "template without release", Path: "/template", Body: `{"namespace":"web","name":"backend"}`, Code: toolserver.CodeNotFound},
+		{Name: "template failing required", Path: "/template", Body: `{"namespace":"web","name":"frontend","values":{"ingress":{"enabled":true}},"reuseValues":true}`,
+			Code: toolserver.CodeUnprocessable},
+		{Name: "template with lookup", Path: "/template", Body: UpgradeRequest{Namespace: "web", Chart: chart("podinfo:6.9.0")}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out TemplateResponse
+			resp.Decode(&out)
+			if !strings.Contains(out.Manifest, `passwordSet: "false"`) {
+				t.Errorf("manifest = %s, want lookup to find nothing, as with helm template", out.Manifest)
+			}
+		}},
+		{Name: "template with unknown function", Path: "/template", Body: UpgradeRequest{Chart: chart("podinfo:6.9.1")}, Code: toolserver.CodeUnprocessable},
+		{Name: "template for newer kubernetes", Path: "/template", Body: UpgradeRequest{Chart: chart("podinfo:7.0.0")}, Code: toolserver.CodeUnprocessable},
+		{Name: "template of image", Path: "/template", Body: UpgradeRequest{Chart: chart("nginx:1.27")}, Code: toolserver.CodeUnprocessable},
+		{Name: "template of missing chart", Path: "/template", Body: UpgradeRequest{Chart: chart("podinfo:0.1.0")}, Code: toolserver.CodeNotFound},
+		{Name: "diff unchanged", Path: "/diff", Body: `{"namespace":"web","name":"frontend"}`, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out DiffResponse
+			resp.Decode(&out)
+			if len(out.Changes) != 0 || out.Unchanged != 2 || out.Revision != 3 {
+				t.Errorf("diff = %+v, want 2 unchanged resources", out)
+			}
+		}},
+		{Name: "diff values", Path: "/diff", Body: `{"namespace":"web","name":"frontend","values":{"replicaCount":5},"reuseValues":true}`, Golden: "diff-values"},
+		{Name: "diff replaced values", Path: "/diff", Body: `{"namespace":"web","name":"frontend","values":{"replicaCount":3}}`, Check: func(t *testing.T, resp *tooltest.Response) {
+			// Without reuseValues, the image tag reverts to the chart's appVersion.
+			if c := changes(t, resp); len(c) != 1 || c[0].Kind != "Deployment" || !strings.Contains(c[0].Diff, "+      - image: ghcr.io/stefanprodan/podinfo:6.7.0") {
+				t.Errorf("changes = %+v", c)
+			}
+		}},
+		{Name: "diff adding ingress", Path: "/diff", Body: `{"namespace":"web","name":"frontend","values":{"ingress":{"enabled":true,"host":"podinfo.example.com"}},"reuseValues":true}`, Check: func(t *testing.T, resp *tooltest.Response) {
+			if c := changes(t, resp); len(c) != 1 || c[0].Kind != "Ingress" || c[0].Change != "added" {
+				t.Errorf("changes = %+v", c)
+			}
+		}},
+		{Name: "diff chart", Path: "/diff", Body: UpgradeRequest{Namespace: "web", Name: "frontend", Chart: chart("podinfo:6.8.0"), Values: map[string]any{"redis": map[string]any{"enabled": true}}, ReuseValues: true}, Golden: "diff-chart"},
+		{Name: "diff without release", Path: "/diff", Body: UpgradeRequest{Namespace: "web", Name: "backend", Chart: chart("podinfo:6.8.0")}, Code: toolserver.CodeNotFound},
+		{Name: "diff without name", Path: "/diff", Body: UpgradeRequest{Chart: chart("podinfo:6.8.0")}, Code: toolserver.CodeInvalidArgument},
+	})
+}
+
+func TestCoalesceValues(t *testing.T) {
+	defaults := map[string]any{
+		"replicaCount": 1,
+		"image":        map[string]any{"repository": "nginx", "tag": "1.27"},
+		"ingress":      map[string]any{"enabled": false},
+	}
+	user := map[string]any{
+		"image":   map[string]any{"tag": "1.28"},
+		"ingress": nil,
+		"extra":   "yes",
+	}
+	got, _ := json.Marshal(coalesceValues(defaults, user))
+	want := `{"extra":"yes","image":{"repository":"nginx","tag":"1.28"},"replicaCount":1}`
+	if string(got) != want {
+		t.Errorf("coalesceValues = %s, want %s", got, want)
+	}
+	if defaults["image"].(map[string]any)["tag"] != "1.27" {
+		t.Error("coalesceValues changed the chart's defaults")
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	want := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`
+	if got := unifiedDiff("old", "new", a, b); got != want {
+		t.Errorf("unifiedDiff =\n%s\nwant:\n%s", got, want)
+	}
+	if got := unifiedDiff("old", "new", a, a); got != "" {
+		t.Errorf("unifiedDiff of equal documents = %q, want none", got)
+	}
+}
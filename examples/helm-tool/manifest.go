@@ -0,0 +1,280 @@
+package helmtool
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"sigs.k8s.io/yaml"
+)
+
+// hookAnnotation marks a chart's hooks, such as tests and migration Jobs,
+// which Helm runs around an install or upgrade and keeps out of the
+// release's manifest.
+const hookAnnotation = "helm.sh/hook"
+
+// installOrder is the order Helm installs resources in, and writes them in
+// a release's manifest, by kind. Other kinds follow, by kind.
+var installOrder = []string{
+	"PriorityClass", "Namespace", "NetworkPolicy", "ResourceQuota", "LimitRange",
+	"PodSecurityPolicy", "PodDisruptionBudget", "ServiceAccount", "Secret", "SecretList",
+	"ConfigMap", "StorageClass", "PersistentVolume", "PersistentVolumeClaim",
+	"CustomResourceDefinition", "ClusterRole", "ClusterRoleList", "ClusterRoleBinding",
+	"ClusterRoleBindingList", "Role", "RoleList", "RoleBinding", "RoleBindingList", "Service",
+	"DaemonSet", "Pod", "ReplicationController", "ReplicaSet", "Deployment",
+	"HorizontalPodAutoscaler", "StatefulSet", "Job", "CronJob", "IngressClass", "Ingress",
+	"APIService",
+}
+
+// docSeparator separates the YAML documents of a manifest.
+var docSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// sourcePrefix starts the comment naming the template a document of a
+// release's manifest was rendered from.
+const sourcePrefix = "# Source: "
+
+// resource is a document of a rendered chart or of a release's manifest.
+type resource struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Source    string `json:"source"`         // the template, e.g. podinfo/templates/service.yaml
+	Hook      string `json:"hook,omitempty"` // the hook events, for a hook
+
+	doc    string         // as rendered
+	object map[string]any // as parsed
+}
+
+// key identifies the resource across revisions, whatever its API version.
+func (r *resource) key() string {
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+// String names the resource as kubectl does, e.g. Deployment web/frontend.
+func (r *resource) String() string {
+	if r.Namespace == "" {
+		return r.Kind + " " + r.Name
+	}
+	return r.Kind + " " + r.Namespace + "/" + r.Name
+}
+
+// splitManifest returns the resources of a template's output, or, with
+// source "", of a release's manifest, whose documents each start with a
+// "# Source:" comment. Documents without a kind, e.g. empty ones, are left
+// out.
+func splitManifest(source, manifest string) ([]*resource, error) {
+	var resources []*resource
+	for _, doc := range docSeparator.Split(manifest, -1) {
+		doc = strings.TrimSpace(doc)
+		if doc == "" {
+			continue
+		}
+		r := &resource{Source: source, doc: doc}
+		if first, rest, _ := strings.Cut(doc, "\n"); strings.HasPrefix(first, sourcePrefix) {
+			r.Source, r.doc = strings.TrimPrefix(first, sourcePrefix), strings.TrimSpace(rest)
+		}
+		obj, err := parseYAML(r.doc)
+		if err != nil {
+			return nil, toolserver.NewError(toolserver.CodeUnprocessable, "%s is not valid YAML: %v", r.Source, err)
+		}
+		kind, _ := obj["kind"].(string)
+		if kind == "" {
+			continue
+		}
+		meta, _ := obj["metadata"].(map[string]any)
+		name, _ := meta["name"].(string)
+		namespace, _ := meta["namespace"].(string)
+		annotations, _ := meta["annotations"].(map[string]any)
+		hook, _ := annotations[hookAnnotation].(string)
+		r.Kind, r.Name, r.Namespace, r.Hook, r.object = kind, name, namespace, hook, obj
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+// renderedResources returns the resources of a rendering, in the order
+// Helm installs them.
+func renderedResources(out *rendering) ([]*resource, error) {
+	var resources []*resource
+	for _, name := range slices.Sorted(maps.Keys(out.outputs)) {
+		rs, err := splitManifest(name, out.outputs[name])
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, rs...)
+	}
+	slices.SortStableFunc(resources, func(a, b *resource) int {
+		ai, bi := slices.Index(installOrder, a.Kind), slices.Index(installOrder, b.Kind)
+		switch {
+		case ai >= 0 && bi >= 0:
+			return ai - bi
+		case ai >= 0:
+			return -1
+		case bi >= 0:
+			return 1
+		}
+		return cmp.Compare(a.Kind, b.Kind)
+	})
+	return resources, nil
+}
+
+// joinManifest writes resources as Helm writes a release's manifest.
+func joinManifest(resources []*resource) string {
+	var b strings.Builder
+	for _, r := range resources {
+		fmt.Fprintf(&b, "---\n%s%s\n%s\n", sourcePrefix, r.Source, r.doc)
+	}
+	return b.String()
+}
+
+// normalized returns the resource as YAML with sorted keys, so that only
+// changes of content, not of formatting, tell two revisions apart.
+func (r *resource) normalized() string {
+	data, err := yaml.Marshal(r.object)
+	if err != nil {
+		return r.doc
+	}
+	return string(data)
+}
+
+// maxDiffCells bounds the table lineDiff fills, the product of the line
+// counts of the parts of two documents that differ, to bound its memory.
+const maxDiffCells = 4 << 20
+
+// unifiedDiff returns the differences between a and b in unified format,
+// with three lines of context, or "" if they are equal.
+func unifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := lineDiff(splitLines(a), splitLines(b))
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	const context = 3
+	for i := 0; i < len(ops); {
+		if ops[i].op == ' ' {
+			i++
+			continue
+		}
+		// A hunk runs from context lines before the change to context lines
+		// after the last change less than 2*context lines after another.
+		start := max(i-context, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].op != ' ' {
+				end = j
+			} else if j-end > 2*context {
+				break
+			}
+		}
+		end = min(end+context+1, len(ops))
+		var aStart, aLen, bStart, bLen int
+		aStart, bStart = ops[start].a+1, ops[start].b+1
+		for _, op := range ops[start:end] {
+			if op.op != '+' {
+				aLen++
+			}
+			if op.op != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&out, "%c%s\n", op.op, op.line)
+		}
+		i = end
+	}
+	return out.String()
+}
+
+func hunkRange(start, n int) string {
+	if n == 0 {
+		start-- // an empty range is written as the line before it
+	}
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffOp is a line of a diff: kept (' '), removed ('-') or added ('+'),
+// with its index in the old and new lines.
+type diffOp struct {
+	op   byte
+	line string
+	a, b int
+}
+
+// lineDiff returns the edits turning a into b, from the longest common
+// subsequence of the lines between their common prefix and suffix. If
+// that part is too long to compare, it is all replaced.
+func lineDiff(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	var ops []diffOp
+	for i := range prefix {
+		ops = append(ops, diffOp{' ', a[i], i, i})
+	}
+	if (len(am)+1)*(len(bm)+1) > maxDiffCells {
+		for i, line := range am {
+			ops = append(ops, diffOp{'-', line, prefix + i, prefix})
+		}
+		for j, line := range bm {
+			ops = append(ops, diffOp{'+', line, prefix + len(am), prefix + j})
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of
+		// am[i:] and bm[j:].
+		lcs := make([][]int32, len(am)+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, len(bm)+1)
+		}
+		for i := len(am) - 1; i >= 0; i-- {
+			for j := len(bm) - 1; j >= 0; j-- {
+				if am[i] == bm[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(am) || j < len(bm) {
+			switch {
+			case i < len(am) && j < len(bm) && am[i] == bm[j]:
+				ops = append(ops, diffOp{' ', am[i], prefix + i, prefix + j})
+				i, j = i+1, j+1
+			case j == len(bm) || i < len(am) && lcs[i+1][j] >= lcs[i][j+1]:
+				ops = append(ops, diffOp{'-', am[i], prefix + i, prefix + j})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', bm[j], prefix + i, prefix + j})
+				j++
+			}
+		}
+	}
+	for k := range suffix {
+		i, j := len(a)-suffix+k, len(b)-suffix+k
+		ops = append(ops, diffOp{' ', a[i], i, j})
+	}
+	return ops
+}
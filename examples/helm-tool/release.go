@@ -0,0 +1,171 @@
+package helmtool
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"helm.sh/helm/v3/pkg/chart"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Helm 3 stores each revision of a release in a Secret of type
+// helm.sh/release.v1 named sh.helm.release.v1.NAME.vREVISION, labeled
+// owner=helm, name=NAME, version=REVISION and status. Its "release" key
+// holds the release as gzipped JSON, base64-encoded once more on top of
+// the Secret's own encoding.
+const (
+	releaseSecretPrefix = "sh.helm.release.v1."
+	releaseOwnerLabel   = "owner=helm"
+)
+
+// gzipMagic starts a gzipped release; Helm 3 releases always are, but
+// older Helm 3 betas stored plain JSON.
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// release is the part of a stored Helm release the tool reports. Times are
+// kept as Helm writes them, RFC 3339, or "" when unset.
+type release struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Info      struct {
+		FirstDeployed string `json:"first_deployed"`
+		LastDeployed  string `json:"last_deployed"`
+		Description   string `json:"description"`
+		Status        string `json:"status"`
+		Notes         string `json:"notes"`
+	} `json:"info"`
+	Chart    *chart.Chart   `json:"chart"` // without its subcharts, which Helm does not store
+	Config   map[string]any `json:"config"`
+	Manifest string         `json:"manifest"`
+}
+
+// decodeRelease decodes the "release" key of a release Secret.
+func decodeRelease(data []byte) (*release, error) {
+	raw, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding release: %w", err)
+	}
+	if bytes.HasPrefix(raw, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing release: %w", err)
+		}
+		if raw, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("decompressing release: %w", err)
+		}
+	}
+	var rel release
+	if err := json.Unmarshal(raw, &rel); err != nil {
+		return nil, fmt.Errorf("parsing release: %w", err)
+	}
+	return &rel, nil
+}
+
+// releaseFromSecret decodes the release stored in secret, failing with
+// INTERNAL if it cannot be read.
+func releaseFromSecret(secret *corev1.Secret) (*release, error) {
+	rel, err := decodeRelease(secret.Data["release"])
+	if err != nil {
+		return nil, toolserver.NewError(toolserver.CodeInternal, "secret %s/%s: %v", secret.Namespace, secret.Name, err)
+	}
+	if rel.Chart == nil || rel.Chart.Metadata == nil {
+		return nil, toolserver.NewError(toolserver.CodeInternal, "secret %s/%s: the release has no chart", secret.Namespace, secret.Name)
+	}
+	if rel.Namespace == "" {
+		rel.Namespace = secret.Namespace
+	}
+	return rel, nil
+}
+
+// secretRevision returns the revision in the version label of a release
+// Secret, or 0 if it has none.
+func secretRevision(secret *corev1.Secret) int {
+	revision, _ := strconv.Atoi(secret.Labels["version"])
+	return revision
+}
+
+// listReleaseSecrets lists the release Secrets in namespace, "" for every
+// namespace, of the release name, "" for every release.
+func listReleaseSecrets(ctx context.Context, namespace, name string) ([]corev1.Secret, error) {
+	selector := releaseOwnerLabel
+	if name != "" {
+		selector += ",name=" + name
+	}
+	list, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.SecretList, error) {
+		return clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	})
+	if err != nil {
+		return nil, kube.Error(err)
+	}
+	return list.Items, nil
+}
+
+// getRelease returns the revision of the release name in namespace, or its
+// latest revision if revision is 0, failing with NOT_FOUND if there is no
+// such release or revision.
+func getRelease(ctx context.Context, namespace, name string, revision int) (*release, error) {
+	if revision > 0 {
+		secret, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.Secret, error) {
+			return clientset.CoreV1().Secrets(namespace).Get(ctx, fmt.Sprintf("%s%s.v%d", releaseSecretPrefix, name, revision), metav1.GetOptions{})
+		})
+		if apierrors.IsNotFound(err) {
+			return nil, toolserver.NewError(toolserver.CodeNotFound, "release %s has no revision %d in namespace %s", name, revision, namespace)
+		}
+		if err != nil {
+			return nil, kube.Error(err)
+		}
+		return releaseFromSecret(secret)
+	}
+
+	secrets, err := listReleaseSecrets(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	var latest *corev1.Secret
+	for i := range secrets {
+		if latest == nil || secretRevision(&secrets[i]) > secretRevision(latest) {
+			latest = &secrets[i]
+		}
+	}
+	if latest == nil {
+		return nil, toolserver.NewError(toolserver.CodeNotFound, "release %s not found in namespace %s", name, namespace)
+	}
+	return releaseFromSecret(latest)
+}
+
+// coalesceValues returns the values a release was rendered with, as helm
+// get values --all does: the chart's defaults overridden by the user's
+// values, merging maps key by key, where a null user value removes the
+// default.
+func coalesceValues(defaults, user map[string]any) map[string]any {
+	out := make(map[string]any, len(defaults)+len(user))
+	for k, v := range defaults {
+		out[k] = v
+	}
+	for k, v := range user {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		userMap, userIsMap := v.(map[string]any)
+		defaultMap, defaultIsMap := out[k].(map[string]any)
+		if userIsMap && defaultIsMap {
+			out[k] = coalesceValues(defaultMap, userMap)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
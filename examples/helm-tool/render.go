@@ -0,0 +1,65 @@
+package helmtool
+
+import (
+	"path"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"sigs.k8s.io/yaml"
+)
+
+// notesFile is the template whose output Helm prints after an install or
+// upgrade rather than applies.
+const notesFile = "NOTES.txt"
+
+// rendering is a rendered chart: its templates' output, by template name,
+// e.g. podinfo/templates/deployment.yaml, and the notes of its top chart.
+type rendering struct {
+	outputs map[string]string
+	notes   string
+}
+
+// render renders c with the user's values for the release, as helm install
+// and upgrade do with Helm's engine: the values are coalesced with the
+// chart's defaults, and the subcharts they disable are dropped. As with helm
+// template, lookup finds nothing: the tool does not read the cluster on a
+// caller's behalf. It fails with UNPROCESSABLE if the values fail the chart's
+// schema or a template does not parse or fails, e.g. with required.
+func render(c *chart.Chart, values map[string]any, rel chartutil.ReleaseOptions, caps *chartutil.Capabilities) (*rendering, error) {
+	if values == nil {
+		values = map[string]any{}
+	}
+	if err := chartutil.ProcessDependenciesWithMerge(c, values); err != nil {
+		return nil, toolserver.NewError(toolserver.CodeUnprocessable, "chart %s: %v", c.Name(), err)
+	}
+	top, err := chartutil.ToRenderValues(c, values, rel, caps)
+	if err != nil {
+		return nil, toolserver.NewError(toolserver.CodeUnprocessable, "chart %s: %v", c.Name(), err)
+	}
+	files, err := engine.Render(c, top)
+	if err != nil {
+		return nil, toolserver.NewError(toolserver.CodeUnprocessable, "%v", err)
+	}
+
+	out := &rendering{outputs: make(map[string]string, len(files))}
+	for name, text := range files {
+		if path.Base(name) != notesFile {
+			out.outputs[name] = text
+		} else if name == path.Join(c.Name(), "templates", notesFile) {
+			out.notes = text // a subchart's are not printed
+		}
+	}
+	return out, nil
+}
+
+// parseYAML parses a rendered document, for the identity and comparison of
+// the resource it holds.
+func parseYAML(doc string) (map[string]any, error) {
+	var obj map[string]any
+	if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
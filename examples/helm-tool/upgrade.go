@@ -0,0 +1,343 @@
+package helmtool
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// renderTimeout bounds /template and /diff, in place of the server's
+	// default, as they may pull a chart before rendering it.
+	renderTimeout = 2 * time.Minute
+
+	// chartPullTimeout bounds pulling a chart from its registry.
+	chartPullTimeout = time.Minute
+
+	// defaultTemplateName names the release /template renders a chart for
+	// when the request names none, as helm template does.
+	defaultTemplateName = "release-name"
+
+	// defaultKubeVersion is the version charts see when the API server
+	// reports none, Helm's default for helm template.
+	defaultKubeVersion = "v1.20.0"
+)
+
+// --- /template and /diff types ---
+
+// UpgradeRequest describes a helm upgrade, or for /template of a release
+// that is not installed, a helm install. Values are applied as helm upgrade
+// applies them: without values, the release's values are kept; with
+// reuseValues, the values given are merged over them; otherwise they
+// replace them.
+type UpgradeRequest struct {
+	Namespace   string         `json:"namespace"`   // defaults to "default"
+	Name        string         `json:"name"`        // the release; /template of a chart defaults to "release-name"
+	Chart       string         `json:"chart"`       // an OCI reference such as oci://ghcr.io/org/charts/app:1.0.0; defaults to the release's chart
+	Values      map[string]any `json:"values"`      // as in a values file
+	ReuseValues bool           `json:"reuseValues"` // merge values over the release's instead of replacing them
+}
+
+type TemplateResponse struct {
+	Name         string      `json:"name"`
+	Namespace    string      `json:"namespace"`
+	Chart        string      `json:"chart"`
+	ChartVersion string      `json:"chartVersion"`
+	Manifest     string      `json:"manifest"` // the resources, then the hooks, as helm template prints them
+	Resources    []*resource `json:"resources"`
+	Notes        string      `json:"notes,omitempty"`
+	Warnings     []string    `json:"warnings,omitempty"`
+	RetriedTimes int         `json:"retriedTimes,omitempty"`
+}
+
+// ContentBlocks gives MCP clients the manifest as a YAML resource instead
+// of a JSON string with escaped newlines.
+func (r TemplateResponse) ContentBlocks() []toolserver.Content {
+	blocks := []toolserver.Content{
+		toolserver.ResourceContent(fmt.Sprintf("helm://namespaces/%s/releases/%s/template", r.Namespace, r.Name), "application/yaml", r.Manifest),
+	}
+	if len(r.Warnings) > 0 {
+		blocks = append(blocks, toolserver.TextContent("Warnings:\n"+strings.Join(r.Warnings, "\n")))
+	}
+	return blocks
+}
+
+// Change is a resource an upgrade would add, remove or change.
+type Change struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Change    string `json:"change"` // added, removed or changed
+	Diff      string `json:"diff"`   // unified, of the resource's YAML with sorted keys
+}
+
+type DiffResponse struct {
+	Name         string   `json:"name"`
+	Namespace    string   `json:"namespace"`
+	Revision     int      `json:"revision"` // the revision compared with
+	Chart        string   `json:"chart"`
+	FromVersion  string   `json:"fromVersion"` // the chart version of the revision
+	ToVersion    string   `json:"toVersion"`   // the chart version of the upgrade
+	Changes      []Change `json:"changes"`
+	Unchanged    int      `json:"unchanged"`
+	Skipped      int      `json:"skipped,omitempty"` // resources of subcharts that could not be rendered
+	Warnings     []string `json:"warnings,omitempty"`
+	RetriedTimes int      `json:"retriedTimes,omitempty"`
+}
+
+// ContentBlocks gives MCP clients a summary of the changes and their diff
+// as one text/x-diff resource.
+func (r DiffResponse) ContentBlocks() []toolserver.Content {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "Upgrading %s/%s from revision %d (%s %s) to %s %s: %d changed, %d unchanged.",
+		r.Namespace, r.Name, r.Revision, r.Chart, r.FromVersion, r.Chart, r.ToVersion, len(r.Changes), r.Unchanged)
+	for _, w := range r.Warnings {
+		fmt.Fprintf(&summary, "\nWarning: %s", w)
+	}
+	blocks := []toolserver.Content{toolserver.TextContent(summary.String())}
+	if len(r.Changes) > 0 {
+		var diff strings.Builder
+		for _, c := range r.Changes {
+			diff.WriteString(c.Diff)
+		}
+		uri := fmt.Sprintf("helm://namespaces/%s/releases/%s/diff?revision=%d", r.Namespace, r.Name, r.Revision)
+		blocks = append(blocks, toolserver.ResourceContent(uri, "text/x-diff", diff.String()))
+	}
+	return blocks
+}
+
+// upgrade is an upgrade rendered for /template or /diff.
+type upgrade struct {
+	name, namespace string
+	current         *release // nil for an install
+	chart           *chart.Chart
+	resources       []*resource // hooks included
+	notes           string
+	warnings        []string
+	retried         int // API calls retried after transient errors
+}
+
+// planUpgrade renders the upgrade req describes. If install, req may name
+// a chart and a release that is not installed, or none.
+func planUpgrade(ctx context.Context, req UpgradeRequest, install bool) (*upgrade, error) {
+	namespace := cmp.Or(req.Namespace, "default")
+	name := req.Name
+	if name == "" && install && req.Chart != "" {
+		name = defaultTemplateName
+	}
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	up := &upgrade{name: name, namespace: namespace}
+
+	apiCtx, cancel := apiContext(ctx)
+	defer cancel()
+	current, err := getRelease(apiCtx, namespace, name, 0)
+	var e *toolserver.Error
+	if err != nil && !(install && req.Chart != "" && errors.As(err, &e) && e.Code == toolserver.CodeNotFound) {
+		return nil, err
+	}
+	up.current = current
+	caps, err := discoverCapabilities(apiCtx)
+	if err != nil {
+		return nil, err
+	}
+	up.retried = kube.Retried(apiCtx)
+
+	if req.Chart != "" {
+		pullCtx, cancel := context.WithTimeout(ctx, chartPullTimeout)
+		defer cancel()
+		if up.chart, err = pullChart(pullCtx, req.Chart); err != nil {
+			return nil, err
+		}
+	} else {
+		up.chart = current.Chart
+		if len(up.chart.Metadata.Dependencies) > 0 {
+			up.warnings = append(up.warnings, fmt.Sprintf("the subcharts of %s are not stored in the release, so their resources are not rendered; pass chart to render them", up.chart.Metadata.Name))
+		}
+	}
+	if err := checkKubeVersion(up.chart, caps.KubeVersion); err != nil {
+		return nil, err
+	}
+
+	values := req.Values
+	switch {
+	case current != nil && req.ReuseValues:
+		values = coalesceValues(current.Config, req.Values)
+	case current != nil && len(req.Values) == 0:
+		values = current.Config
+	}
+	rel := chartutil.ReleaseOptions{Name: name, Namespace: namespace, Revision: 1, IsInstall: true}
+	if current != nil {
+		rel.Revision, rel.IsInstall, rel.IsUpgrade = current.Version+1, false, true
+	}
+	out, err := render(up.chart, values, rel, caps)
+	if err != nil {
+		return nil, err
+	}
+	if up.resources, err = renderedResources(out); err != nil {
+		return nil, err
+	}
+	up.notes = out.notes
+	return up, nil
+}
+
+// discoverCapabilities returns the .Capabilities of the cluster, from
+// discovery. Groups that fail discovery, e.g. an unavailable aggregated API,
+// are left out.
+func discoverCapabilities(ctx context.Context) (*chartutil.Capabilities, error) {
+	caps, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*chartutil.Capabilities, error) {
+		info, err := clientset.Discovery().ServerVersion()
+		if err != nil {
+			return nil, err
+		}
+		_, lists, err := clientset.Discovery().ServerGroupsAndResources()
+		if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, err
+		}
+		caps := &chartutil.Capabilities{
+			KubeVersion: chartutil.KubeVersion{
+				Version: cmp.Or(info.GitVersion, defaultKubeVersion),
+				Major:   info.Major,
+				Minor:   info.Minor,
+			},
+			HelmVersion: chartutil.DefaultCapabilities.HelmVersion,
+		}
+		for _, list := range lists {
+			caps.APIVersions = append(caps.APIVersions, list.GroupVersion)
+			for _, r := range list.APIResources {
+				if !strings.Contains(r.Name, "/") { // not a subresource
+					caps.APIVersions = append(caps.APIVersions, list.GroupVersion+"/"+r.Kind)
+				}
+			}
+		}
+		return caps, nil
+	})
+	if err != nil {
+		return nil, kube.Error(err)
+	}
+	return caps, nil
+}
+
+// checkKubeVersion fails with UNPROCESSABLE if the chart's kubeVersion
+// constraint excludes the cluster's version, as helm install does. Like
+// Helm, it ignores the pre-release of a provider's build, e.g. -gke.100.
+func checkKubeVersion(c *chart.Chart, kv chartutil.KubeVersion) error {
+	if c.Metadata.KubeVersion == "" {
+		return nil
+	}
+	constraint, err := semver.NewConstraint(c.Metadata.KubeVersion)
+	if err != nil {
+		return toolserver.NewError(toolserver.CodeUnprocessable, "chart %s has an invalid kubeVersion %q: %v", c.Metadata.Name, c.Metadata.KubeVersion, err)
+	}
+	v, err := semver.NewVersion(kv.Version)
+	if err != nil {
+		return nil // not a version to check against
+	}
+	if !constraint.Check(semver.New(v.Major(), v.Minor(), v.Patch(), "", "")) {
+		return toolserver.NewError(toolserver.CodeUnprocessable, "chart %s requires Kubernetes %s, but the cluster runs %s", c.Metadata.Name, c.Metadata.KubeVersion, kv.Version)
+	}
+	return nil
+}
+
+func templateChart(ctx context.Context, req UpgradeRequest) (TemplateResponse, error) {
+	up, err := planUpgrade(ctx, req, true)
+	if err != nil {
+		return TemplateResponse{}, err
+	}
+	var resources, hooks []*resource
+	for _, r := range up.resources {
+		if r.Hook != "" {
+			hooks = append(hooks, r)
+		} else {
+			resources = append(resources, r)
+		}
+	}
+	return TemplateResponse{
+		Name:         up.name,
+		Namespace:    up.namespace,
+		Chart:        up.chart.Metadata.Name,
+		ChartVersion: up.chart.Metadata.Version,
+		Manifest:     joinManifest(resources) + joinManifest(hooks),
+		Resources:    append(resources, hooks...),
+		Notes:        up.notes,
+		Warnings:     up.warnings,
+		RetriedTimes: up.retried,
+	}, nil
+}
+
+// diffUpgrade compares the resources an upgrade would apply with the
+// release's manifest. Hooks are not compared, as Helm does not keep them
+// in the manifest.
+func diffUpgrade(ctx context.Context, req UpgradeRequest) (DiffResponse, error) {
+	up, err := planUpgrade(ctx, req, false)
+	if err != nil {
+		return DiffResponse{}, err
+	}
+	old, err := splitManifest("", up.current.Manifest)
+	if err != nil {
+		return DiffResponse{}, err
+	}
+
+	resp := DiffResponse{
+		Name:         up.name,
+		Namespace:    up.namespace,
+		Revision:     up.current.Version,
+		Chart:        up.chart.Metadata.Name,
+		FromVersion:  up.current.Chart.Metadata.Version,
+		ToVersion:    up.chart.Metadata.Version,
+		Changes:      []Change{},
+		Warnings:     up.warnings,
+		RetriedTimes: up.retried,
+	}
+	from := fmt.Sprintf("revision %d", up.current.Version)
+	to := fmt.Sprintf("revision %d", up.current.Version+1)
+	change := func(r *resource, kind, a, b string) {
+		resp.Changes = append(resp.Changes, Change{
+			Kind: r.Kind, Namespace: r.Namespace, Name: r.Name, Change: kind,
+			Diff: unifiedDiff(from+": "+r.String(), to+": "+r.String(), a, b),
+		})
+	}
+
+	oldByKey := make(map[string]*resource, len(old))
+	for _, r := range old {
+		oldByKey[r.key()] = r
+	}
+	seen := make(map[string]bool)
+	for _, r := range up.resources {
+		if r.Hook != "" {
+			continue
+		}
+		seen[r.key()] = true
+		prev, ok := oldByKey[r.key()]
+		switch {
+		case !ok:
+			change(r, "added", "", r.normalized())
+		case prev.normalized() != r.normalized():
+			change(r, "changed", prev.normalized(), r.normalized())
+		default:
+			resp.Unchanged++
+		}
+	}
+	for _, r := range old {
+		switch {
+		case seen[r.key()]:
+		case req.Chart == "" && strings.Contains(r.Source, "/charts/"):
+			resp.Skipped++ // a subchart's, which the stored chart cannot render
+		default:
+			change(r, "removed", r.normalized(), "")
+		}
+	}
+	return resp, nil
+}
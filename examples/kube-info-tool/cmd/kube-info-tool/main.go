@@ -0,0 +1,22 @@
+// Command kube-info-tool serves kube-info-tool on its own. kube-mcp-tools serves it together
+// with the other example tools.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	kubeinfotool "kube-info-tool"
+)
+
+func main() {
+	s, err := kubeinfotool.New()
+	if err != nil {
+		slog.Error("starting kube-info-tool", "err", err)
+		os.Exit(1)
+	}
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,170 @@
+package kubeinfotool
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+type DrainPreviewRequest struct {
+	Node string `json:"node"`
+}
+
+type DrainPod struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Controller string `json:"controller,omitempty"` // Kind/Name of the owning controller
+	Reason     string `json:"reason,omitempty"`
+	LocalData  bool   `json:"localData,omitempty"` // uses emptyDir volumes whose data is lost on eviction
+}
+
+// DrainPreviewResponse groups the node's pods by what `kubectl drain` would do
+// with them. Nothing is evicted or cordoned.
+type DrainPreviewResponse struct {
+	Node          string     `json:"node,omitempty"`
+	Unschedulable bool       `json:"unschedulable"`
+	Evicted       []DrainPod `json:"evicted"`
+	BlockedByPDB  []DrainPod `json:"blockedByPDB"`
+	Unmanaged     []DrainPod `json:"unmanaged"`              // no controller; lost unless --force recreates them elsewhere
+	Ignored       []DrainPod `json:"ignored"`                // DaemonSet and mirror pods drain skips
+	RetriedTimes  int        `json:"retriedTimes,omitempty"` // API calls retried after transient errors
+}
+
+func drainPreview(ctx context.Context, req DrainPreviewRequest) (DrainPreviewResponse, error) {
+	if req.Node == "" {
+		return DrainPreviewResponse{}, toolserver.BadRequest("node is required")
+	}
+
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	node, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.Node, error) {
+		return clientset.CoreV1().Nodes().Get(ctx, req.Node, metav1.GetOptions{})
+	})
+	if err != nil {
+		return DrainPreviewResponse{}, kube.Error(err)
+	}
+
+	podList, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.PodList, error) {
+		return clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+			FieldSelector: "spec.nodeName=" + req.Node,
+		})
+	})
+	if err != nil {
+		return DrainPreviewResponse{}, kube.Error(err)
+	}
+
+	pdbList, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*policyv1.PodDisruptionBudgetList, error) {
+		return clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return DrainPreviewResponse{}, kube.Error(err)
+	}
+
+	resp := previewDrain(podList.Items, pdbList.Items)
+	resp.Node = node.Name
+	resp.Unschedulable = node.Spec.Unschedulable
+	resp.RetriedTimes = kube.Retried(ctx)
+
+	return resp, nil
+}
+
+// previewDrain classifies pods the way drain would. Each PDB's remaining
+// disruption allowance is consumed as pods are evicted, so a budget that
+// covers several pods on the node blocks the ones past the allowance.
+func previewDrain(pods []corev1.Pod, pdbs []policyv1.PodDisruptionBudget) DrainPreviewResponse {
+	resp := DrainPreviewResponse{
+		Evicted:      []DrainPod{},
+		BlockedByPDB: []DrainPod{},
+		Unmanaged:    []DrainPod{},
+		Ignored:      []DrainPod{},
+	}
+
+	budgets := make(map[string]int32, len(pdbs))
+	for _, pdb := range pdbs {
+		budgets[pdb.Namespace+"/"+pdb.Name] = pdb.Status.DisruptionsAllowed
+	}
+
+	for _, pod := range pods {
+		dp := DrainPod{Name: pod.Name, Namespace: pod.Namespace, LocalData: hasLocalData(&pod)}
+		owner := metav1.GetControllerOf(&pod)
+		if owner != nil {
+			dp.Controller = owner.Kind + "/" + owner.Name
+		}
+
+		switch {
+		case pod.Annotations[mirrorPodAnnotation] != "":
+			dp.Reason = "mirror pod managed by the kubelet"
+			resp.Ignored = append(resp.Ignored, dp)
+			continue
+		case owner != nil && owner.Kind == "DaemonSet":
+			dp.Reason = "DaemonSet pod"
+			resp.Ignored = append(resp.Ignored, dp)
+			continue
+		case pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed:
+			dp.Reason = fmt.Sprintf("pod is %s", pod.Status.Phase)
+			resp.Evicted = append(resp.Evicted, dp)
+			continue
+		}
+
+		if blocking := blockingPDB(&pod, pdbs, budgets); blocking != "" {
+			dp.Reason = "PodDisruptionBudget " + blocking + " allows no further disruptions"
+			resp.BlockedByPDB = append(resp.BlockedByPDB, dp)
+			continue
+		}
+
+		if owner == nil {
+			dp.Reason = "no controller; pod will not be recreated"
+			resp.Unmanaged = append(resp.Unmanaged, dp)
+			continue
+		}
+
+		resp.Evicted = append(resp.Evicted, dp)
+	}
+
+	return resp
+}
+
+// blockingPDB returns the name of the first matching PDB with no allowance
+// left, or "" after charging one disruption to every matching PDB. As in
+// policy/v1, a PDB without a selector matches no pods and one with an empty
+// selector matches every pod in its namespace.
+func blockingPDB(pod *corev1.Pod, pdbs []policyv1.PodDisruptionBudget, budgets map[string]int32) string {
+	var matched []string
+	for _, pdb := range pdbs {
+		if pdb.Namespace != pod.Namespace || pdb.Spec.Selector == nil {
+			continue
+		}
+		sel, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || !sel.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		key := pdb.Namespace + "/" + pdb.Name
+		if budgets[key] <= 0 {
+			return pdb.Name
+		}
+		matched = append(matched, key)
+	}
+	for _, key := range matched {
+		budgets[key]--
+	}
+	return ""
+}
+
+func hasLocalData(pod *corev1.Pod) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package kubeinfotool
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBlockingPDB(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "web", Labels: map[string]string{"app": "web"}}}
+	pdb := func(ns, name string, selector *metav1.LabelSelector) policyv1.PodDisruptionBudget {
+		return policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns}, Spec: policyv1.PodDisruptionBudgetSpec{Selector: selector}}
+	}
+	matchWeb := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	tests := []struct {
+		name        string
+		pdbs        []policyv1.PodDisruptionBudget
+		budgets     map[string]int32
+		want        string
+		wantBudgets map[string]int32
+	}{
+		{
+			name:        "allowed",
+			pdbs:        []policyv1.PodDisruptionBudget{pdb("web", "web", matchWeb)},
+			budgets:     map[string]int32{"web/web": 1},
+			wantBudgets: map[string]int32{"web/web": 0},
+		},
+		{
+			name:        "exhausted",
+			pdbs:        []policyv1.PodDisruptionBudget{pdb("web", "web", matchWeb)},
+			budgets:     map[string]int32{"web/web": 0},
+			want:        "web",
+			wantBudgets: map[string]int32{"web/web": 0},
+		},
+		{
+			name:        "empty selector matches every pod",
+			pdbs:        []policyv1.PodDisruptionBudget{pdb("web", "all", &metav1.LabelSelector{})},
+			budgets:     map[string]int32{"web/all": 0},
+			want:        "all",
+			wantBudgets: map[string]int32{"web/all": 0},
+		},
+		{
+			name:        "empty selector is charged",
+			pdbs:        []policyv1.PodDisruptionBudget{pdb("web", "all", &metav1.LabelSelector{}), pdb("web", "web", matchWeb)},
+			budgets:     map[string]int32{"web/all": 2, "web/web": 1},
+			wantBudgets: map[string]int32{"web/all": 1, "web/web": 0},
+		},
+		{
+			name:        "no selector matches no pods",
+			pdbs:        []policyv1.PodDisruptionBudget{pdb("web", "none", nil)},
+			budgets:     map[string]int32{"web/none": 0},
+			wantBudgets: map[string]int32{"web/none": 0},
+		},
+		{
+			name:        "other namespace",
+			pdbs:        []policyv1.PodDisruptionBudget{pdb("other", "all", &metav1.LabelSelector{})},
+			budgets:     map[string]int32{"other/all": 0},
+			wantBudgets: map[string]int32{"other/all": 0},
+		},
+		{
+			name:        "other labels",
+			pdbs:        []policyv1.PodDisruptionBudget{pdb("web", "db", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}})},
+			budgets:     map[string]int32{"web/db": 0},
+			wantBudgets: map[string]int32{"web/db": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := blockingPDB(pod, tt.pdbs, tt.budgets); got != tt.want {
+				t.Errorf("blockingPDB = %q, want %q", got, tt.want)
+			}
+			for key, want := range tt.wantBudgets {
+				if tt.budgets[key] != want {
+					t.Errorf("budget of %s = %d, want %d", key, tt.budgets[key], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package kubeinfotool
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/atippey/kube-mcp/pkg/kube"
+)
+
+// apiTimeout is the deadline of a handler's API calls, retries included,
+// overridable with $KUBE_TIMEOUT (see package config; package kube has the
+// rate limit and retry settings).
+var apiTimeout = 10 * time.Second
+
+// configureAPICalls applies the timeout setting to the package-level
+// default.
+func configureAPICalls() {
+	apiTimeout = config.Duration("KUBE_TIMEOUT", apiTimeout)
+	slog.Info("kubernetes API calls", "timeout", apiTimeout.String())
+}
+
+// apiContext derives the context for a handler's API calls from the incoming
+// request's, so a disconnected client or the deadline cancels outstanding
+// work. It counts the calls' retries for the response's retriedTimes.
+func apiContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(kube.CountRetries(ctx), apiTimeout)
+}
@@ -0,0 +1,211 @@
+package kubeinfotool
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultTailLines bounds log output when the caller doesn't ask for a specific amount.
+const defaultTailLines int64 = 200
+
+type LogsRequest struct {
+	Namespace  string `json:"namespace"`
+	Pod        string `json:"pod"`
+	Container  string `json:"container"`  // empty means all containers in the pod
+	Previous   bool   `json:"previous"`   // logs from the prior (crashed) container instance
+	Timestamps bool   `json:"timestamps"` // prefix each line with an RFC3339 timestamp
+	TailLines  int64  `json:"tailLines"`  // defaults to 200
+	Follow     bool   `json:"follow"`     // send new lines as notifications until cancelled; WebSocket only
+}
+
+type ContainerLogs struct {
+	Container    string `json:"container"`
+	Init         bool   `json:"init,omitempty"`
+	Previous     bool   `json:"previous"`
+	RestartCount int32  `json:"restartCount"`
+	Logs         string `json:"logs"`
+	Error        string `json:"error,omitempty"`
+}
+
+type LogsResponse struct {
+	Pod          string          `json:"pod,omitempty"`
+	Namespace    string          `json:"namespace,omitempty"`
+	Containers   []ContainerLogs `json:"containers,omitempty"`
+	Followed     int             `json:"followed,omitempty"`     // lines sent as notifications while following
+	RetriedTimes int             `json:"retriedTimes,omitempty"` // API calls retried after transient errors
+}
+
+// ContentBlocks gives MCP clients each container's logs as a plain text
+// resource instead of a JSON string with escaped newlines.
+func (r LogsResponse) ContentBlocks() []toolserver.Content {
+	var blocks []toolserver.Content
+	for _, c := range r.Containers {
+		if c.Error != "" {
+			blocks = append(blocks, toolserver.TextContent(fmt.Sprintf("container %s: %s", c.Container, c.Error)))
+			continue
+		}
+		uri := fmt.Sprintf("k8s://namespaces/%s/pods/%s/log?container=%s", r.Namespace, r.Pod, url.QueryEscape(c.Container))
+		if c.Previous {
+			uri += "&previous=true"
+		}
+		blocks = append(blocks, toolserver.ResourceContent(uri, "text/plain", c.Logs))
+	}
+	return blocks
+}
+
+func podLogs(ctx context.Context, req LogsRequest) (LogsResponse, error) {
+	if req.Pod == "" {
+		return LogsResponse{}, toolserver.BadRequest("pod is required")
+	}
+
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace(ctx)
+	}
+
+	tailLines := req.TailLines
+	if tailLines <= 0 {
+		tailLines = defaultTailLines
+	}
+
+	if req.Follow && !toolserver.CanNotify(ctx) {
+		return LogsResponse{}, toolserver.BadRequest("follow needs the WebSocket transport, /v1/ws")
+	}
+	if req.Follow && req.Previous {
+		return LogsResponse{}, toolserver.BadRequest("previous logs cannot be followed")
+	}
+	// Following runs until the caller cancels the call, not for the
+	// duration of an API call.
+	follow := ctx
+
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	pod, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.Pod, error) {
+		return clientset.CoreV1().Pods(namespace).Get(ctx, req.Pod, metav1.GetOptions{})
+	})
+	if err != nil {
+		return LogsResponse{}, kube.Error(err)
+	}
+
+	targets := logTargets(pod, req.Container)
+	if len(targets) == 0 {
+		return LogsResponse{}, toolserver.BadRequest("container %q not found in pod %s", req.Container, req.Pod)
+	}
+	if req.Follow {
+		if len(targets) > 1 {
+			return LogsResponse{}, toolserver.BadRequest("pod %s has several containers: name the one to follow", req.Pod)
+		}
+		return followLogs(follow, pod, targets[0], &corev1.PodLogOptions{
+			Container:  targets[0].Container,
+			Timestamps: req.Timestamps,
+			TailLines:  &tailLines,
+			Follow:     true,
+		})
+	}
+
+	containers := make([]ContainerLogs, 0, len(targets))
+	for _, target := range targets {
+		target.Previous = req.Previous
+		opts := &corev1.PodLogOptions{
+			Container:  target.Container,
+			Previous:   req.Previous,
+			Timestamps: req.Timestamps,
+			TailLines:  &tailLines,
+		}
+		logs, err := fetchLogs(ctx, namespace, pod.Name, opts)
+		if err != nil {
+			target.Error = err.Error()
+		}
+		target.Logs = logs
+		containers = append(containers, target)
+	}
+
+	return LogsResponse{
+		Pod:          pod.Name,
+		Namespace:    pod.Namespace,
+		Containers:   containers,
+		RetriedTimes: kube.Retried(ctx),
+	}, nil
+}
+
+// followLogs sends each new line of the container's logs to the caller as
+// a notifications/message until the call is cancelled or times out, or the
+// container exits.
+func followLogs(ctx context.Context, pod *corev1.Pod, target ContainerLogs, opts *corev1.PodLogOptions) (LogsResponse, error) {
+	clientset, err := kubeClient.Clientset()
+	if err != nil {
+		return LogsResponse{}, err
+	}
+	stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts).Stream(ctx)
+	if err != nil {
+		return LogsResponse{}, kube.Error(err)
+	}
+	defer stream.Close()
+
+	resp := LogsResponse{Pod: pod.Name, Namespace: pod.Namespace, Containers: []ContainerLogs{target}}
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		err := toolserver.Notify(ctx, "notifications/message", map[string]any{
+			"level":  "info",
+			"logger": pod.Name + "/" + target.Container,
+			"data":   scanner.Text(),
+		})
+		if err != nil {
+			break
+		}
+		resp.Followed++
+	}
+	return resp, nil
+}
+
+// logTargets returns the containers whose logs should be fetched. An empty
+// name selects every init and regular container so a single call covers the
+// whole pod.
+func logTargets(pod *corev1.Pod, name string) []ContainerLogs {
+	restarts := make(map[string]int32)
+	for _, st := range pod.Status.InitContainerStatuses {
+		restarts[st.Name] = st.RestartCount
+	}
+	for _, st := range pod.Status.ContainerStatuses {
+		restarts[st.Name] = st.RestartCount
+	}
+
+	var targets []ContainerLogs
+	for _, c := range pod.Spec.InitContainers {
+		if name == "" || name == c.Name {
+			targets = append(targets, ContainerLogs{Container: c.Name, Init: true, RestartCount: restarts[c.Name]})
+		}
+	}
+	for _, c := range pod.Spec.Containers {
+		if name == "" || name == c.Name {
+			targets = append(targets, ContainerLogs{Container: c.Name, RestartCount: restarts[c.Name]})
+		}
+	}
+	return targets
+}
+
+func fetchLogs(ctx context.Context, namespace, pod string, opts *corev1.PodLogOptions) (string, error) {
+	stream, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (io.ReadCloser, error) {
+		return clientset.CoreV1().Pods(namespace).GetLogs(pod, opts).Stream(ctx)
+	})
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return string(data), err
+	}
+	return string(data), nil
+}
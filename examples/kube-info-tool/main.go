@@ -1,18 +1,28 @@
-package main
+// Package kubeinfotool implements kube-info-tool, which reports namespaces,
+// pods, logs, quotas and network policies in a cluster.
+package kubeinfotool
 
 import (
 	"context"
-	"encoding/json"
-	"log"
-	"net/http"
-	"os"
+	"time"
 
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 )
 
-var clientset *kubernetes.Clientset
+// kubeClient is loaded on first use. Until it can be, every endpoint fails
+// with 503 and /readyz reports why.
+var kubeClient = kube.New("kube-info-tool")
+
+// staleMaxAge is how long listings are kept to answer with, marked stale,
+// while the API server cannot be reached.
+const staleMaxAge = 15 * time.Minute
+
+// maxFollow bounds how long pod-logs follows a container's logs.
+const maxFollow = 30 * time.Minute
 
 type NamespaceInfo struct {
 	Name   string `json:"name"`
@@ -20,8 +30,8 @@ type NamespaceInfo struct {
 }
 
 type NamespacesResponse struct {
-	Namespaces []NamespaceInfo `json:"namespaces,omitempty"`
-	Error      string          `json:"error,omitempty"`
+	Namespaces   []NamespaceInfo `json:"namespaces,omitempty"`
+	RetriedTimes int             `json:"retriedTimes,omitempty"` // API calls retried after transient errors
 }
 
 type PodInfo struct {
@@ -36,54 +46,56 @@ type PodsRequest struct {
 }
 
 type PodsResponse struct {
-	Pods  []PodInfo `json:"pods,omitempty"`
-	Error string    `json:"error,omitempty"`
-}
-
-func main() {
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		log.Fatalf("Failed to get in-cluster config: %v", err)
-	}
-
-	clientset, err = kubernetes.NewForConfig(config)
-	if err != nil {
-		log.Fatalf("Failed to create Kubernetes client: %v", err)
-	}
-
-	http.HandleFunc("/health", handleHealth)
-	http.HandleFunc("/namespaces", handleNamespaces)
-	http.HandleFunc("/pods", handlePods)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	log.Printf("Starting kube-info-tool server on :%s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
-	}
+	Pods         []PodInfo `json:"pods,omitempty"`
+	RetriedTimes int       `json:"retriedTimes,omitempty"` // API calls retried after transient errors
 }
 
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+// New returns the kube-info-tool server, which connects to the cluster from
+// inside it, or else with the kubeconfig, on first use.
+func New() (*toolserver.Server, error) {
+	configureAPICalls()
+
+	s := toolserver.New("kube-info-tool")
+	s.AddReadinessCheck("kubernetes", kubeClient.Ready)
+	s.AddDiagnostics("kubernetes", kubeClient.Diagnostics)
+	s.AddWarmup("kubernetes", kubeClient.Warm)
+	s.CheckRBAC(kubeClient.Allowed)
+	s.AddResource(summaryResource, clusterSummary)
+	s.AddPrompt(troubleshootPodPrompt)
+	toolserver.Register(s, "/namespaces", listNamespaces,
+		toolserver.Name("list-namespaces"), toolserver.Describe("List all Kubernetes namespaces in the cluster with their current status."),
+		toolserver.ServeStale(staleMaxAge), toolserver.RBAC("list namespaces"))
+	toolserver.Register(s, "/pods", listPods,
+		toolserver.Name("list-pods"), toolserver.Describe("List pods in a Kubernetes namespace with name, status, and node placement."),
+		toolserver.ServeStale(staleMaxAge), toolserver.RBAC("list pods"))
+	toolserver.Register(s, "/logs", podLogs,
+		toolserver.Name("pod-logs"), toolserver.Describe("Fetch logs for a pod, or follow them over the WebSocket transport."),
+		toolserver.Timeout(maxFollow), toolserver.RBAC("get pods", "get pods/log"))
+	toolserver.Register(s, "/quotas", quotas,
+		toolserver.Name("namespace-quotas"), toolserver.Describe("Report ResourceQuota usage and LimitRange defaults per namespace."),
+		toolserver.ServeStale(staleMaxAge), toolserver.RBAC("list resourcequotas", "list limitranges", "list events"))
+	toolserver.Register(s, "/netpol", netpol,
+		toolserver.Name("network-policies"), toolserver.Describe("List NetworkPolicies in a namespace, or check whether they allow a connection."),
+		toolserver.ServeStale(staleMaxAge), toolserver.RBAC("list networkpolicies.networking.k8s.io", "get pods", "get namespaces"))
+	toolserver.Register(s, "/use-namespace", useNamespace,
+		toolserver.Name("use-namespace"), toolserver.Describe("Select the namespace that later calls in this session default to."),
+		toolserver.RBAC("get namespaces"))
+	toolserver.Register(s, "/drain-preview", drainPreview,
+		toolserver.Describe("Simulate draining a node without touching it."),
+		toolserver.RBAC("get nodes", "list pods", "list poddisruptionbudgets.policy"))
+
+	return s, nil
 }
 
-func handleNamespaces(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+func listNamespaces(ctx context.Context, _ struct{}) (NamespacesResponse, error) {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
 
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
-		return
-	}
-
-	nsList, err := clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+	nsList, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.NamespaceList, error) {
+		return clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(NamespacesResponse{Error: err.Error()})
-		return
+		return NamespacesResponse{}, kube.Error(err)
 	}
 
 	namespaces := make([]NamespaceInfo, 0, len(nsList.Items))
@@ -94,34 +106,23 @@ func handleNamespaces(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	json.NewEncoder(w).Encode(NamespacesResponse{Namespaces: namespaces})
+	return NamespacesResponse{Namespaces: namespaces, RetriedTimes: kube.Retried(ctx)}, nil
 }
 
-func handlePods(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req PodsRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(PodsResponse{Error: "invalid request body"})
-		return
-	}
-
+func listPods(ctx context.Context, req PodsRequest) (PodsResponse, error) {
 	namespace := req.Namespace
 	if namespace == "" {
-		namespace = "default"
+		namespace = defaultNamespace(ctx)
 	}
 
-	podList, err := clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	podList, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.PodList, error) {
+		return clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(PodsResponse{Error: err.Error()})
-		return
+		return PodsResponse{}, kube.Error(err)
 	}
 
 	pods := make([]PodInfo, 0, len(podList.Items))
@@ -134,5 +135,5 @@ func handlePods(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	json.NewEncoder(w).Encode(PodsResponse{Pods: pods})
+	return PodsResponse{Pods: pods, RetriedTimes: kube.Retried(ctx)}, nil
 }
@@ -0,0 +1,72 @@
+package kubeinfotool
+
+import (
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func testCluster() []runtime.Object {
+	namespace := func(name string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}, Status: corev1.NamespaceStatus{Phase: corev1.NamespaceActive}}
+	}
+	pod := func(ns, name, node string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+			Spec:       corev1.PodSpec{NodeName: node, Containers: []corev1.Container{{Name: "app"}}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	return []runtime.Object{
+		namespace("default"), namespace("web"),
+		pod("default", "debug", "node-1", corev1.PodRunning),
+		pod("web", "frontend", "node-1", corev1.PodRunning),
+		pod("web", "backend", "node-2", corev1.PodPending),
+	}
+}
+
+func newTestServer(t *testing.T) *tooltest.Server {
+	tooltest.FakeKube(t, kubeClient, testCluster()...)
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tooltest.NewServer(t, s)
+}
+
+func TestHandlers(t *testing.T) {
+	tooltest.Run(t, newTestServer(t), []tooltest.Case{
+		{Name: "namespaces", Path: "/namespaces", Body: `{}`, Golden: "namespaces"},
+		{Name: "pods", Path: "/pods", Body: `{"namespace":"web"}`, Golden: "pods"},
+		{Name: "pods in default", Path: "/pods", Body: `{}`, Golden: "pods-default"},
+		{Name: "logs", Path: "/logs", Body: `{"namespace":"web","pod":"frontend"}`, Golden: "logs"},
+		{Name: "logs without pod", Path: "/logs", Body: `{}`, Code: toolserver.CodeInvalidArgument},
+		{Name: "logs of missing pod", Path: "/logs", Body: `{"namespace":"web","pod":"nope"}`, Code: toolserver.CodeNotFound},
+		{Name: "logs of missing container", Path: "/logs", Body: `{"namespace":"web","pod":"frontend","container":"nope"}`, Code: toolserver.CodeInvalidArgument},
+		{Name: "follow over REST", Path: "/logs", Body: `{"namespace":"web","pod":"frontend","follow":true}`, Code: toolserver.CodeInvalidArgument},
+		{Name: "use-namespace without session", Path: "/use-namespace", Body: `{"namespace":"web"}`, Code: toolserver.CodeInvalidArgument},
+	})
+}
+
+func TestUseNamespace(t *testing.T) {
+	srv := newTestServer(t)
+	srv.Header.Set("Mcp-Session-Id", "conversation-1")
+	tooltest.Run(t, srv, []tooltest.Case{
+		{Name: "missing namespace", Path: "/use-namespace", Body: `{"namespace":"nope"}`, Code: toolserver.CodeNotFound},
+		{Name: "select", Path: "/use-namespace", Body: `{"namespace":"web"}`, Golden: "use-namespace"},
+		{Name: "pods in the selected namespace", Path: "/pods", Body: `{}`, Golden: "pods"},
+		{Name: "named namespace wins", Path: "/pods", Body: `{"namespace":"default"}`, Golden: "pods-default"},
+		{Name: "clear", Path: "/use-namespace", Body: `{}`, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out UseNamespaceResponse
+			resp.Decode(&out)
+			if out.Namespace != "default" || out.Previous != "web" {
+				t.Errorf("response = %+v", out)
+			}
+		}},
+		{Name: "pods in default again", Path: "/pods", Body: `{}`, Golden: "pods-default"},
+	})
+}
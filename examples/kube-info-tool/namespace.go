@@ -0,0 +1,57 @@
+package kubeinfotool
+
+import (
+	"context"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// namespaceKey is the session state holding the namespace selected with
+// use-namespace.
+const namespaceKey = "namespace"
+
+type UseNamespaceRequest struct {
+	Namespace string `json:"namespace"` // empty clears the selection
+}
+
+type UseNamespaceResponse struct {
+	Namespace string `json:"namespace"`
+	Previous  string `json:"previous,omitempty"`
+}
+
+// useNamespace selects the namespace later calls in the session default
+// to, after checking that it exists.
+func useNamespace(ctx context.Context, req UseNamespaceRequest) (UseNamespaceResponse, error) {
+	state := toolserver.SessionState(ctx)
+	if state == nil {
+		return UseNamespaceResponse{}, toolserver.BadRequest("use-namespace needs a session: call it over MCP or with an Mcp-Session-Id header")
+	}
+	previous, _ := state.Get(namespaceKey).(string)
+	if req.Namespace == "" {
+		state.Delete(namespaceKey)
+		return UseNamespaceResponse{Namespace: "default", Previous: previous}, nil
+	}
+
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+	if _, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.Namespace, error) {
+		return clientset.CoreV1().Namespaces().Get(ctx, req.Namespace, metav1.GetOptions{})
+	}); err != nil {
+		return UseNamespaceResponse{}, kube.Error(err)
+	}
+	state.Set(namespaceKey, req.Namespace)
+	return UseNamespaceResponse{Namespace: req.Namespace, Previous: previous}, nil
+}
+
+// defaultNamespace is the namespace of calls that name none: the one
+// selected in the session, or "default".
+func defaultNamespace(ctx context.Context) string {
+	if ns, ok := toolserver.SessionState(ctx).Get(namespaceKey).(string); ok {
+		return ns
+	}
+	return "default"
+}
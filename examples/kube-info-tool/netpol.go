@@ -0,0 +1,376 @@
+package kubeinfotool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+type PodRef struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+}
+
+type NetpolRequest struct {
+	Namespace   string  `json:"namespace"`
+	Source      *PodRef `json:"source,omitempty"`
+	Destination *PodRef `json:"destination,omitempty"`
+	Port        int32   `json:"port"`
+	Protocol    string  `json:"protocol"` // TCP (default), UDP, SCTP
+}
+
+type NetpolInfo struct {
+	Name        string   `json:"name"`
+	Namespace   string   `json:"namespace"`
+	PodSelector string   `json:"podSelector"`
+	PolicyTypes []string `json:"policyTypes"`
+	Ingress     int      `json:"ingressRules"`
+	Egress      int      `json:"egressRules"`
+}
+
+// DirectionVerdict describes how one side of a connection is treated. A pod
+// that no policy selects for the direction is not isolated and allows all
+// traffic; otherwise at least one selecting policy must allow it.
+type DirectionVerdict struct {
+	Isolated bool     `json:"isolated"`
+	Allowed  bool     `json:"allowed"`
+	Matching []string `json:"matching,omitempty"` // selecting policies that allow the traffic
+	Denying  []string `json:"denying,omitempty"`  // selecting policies that do not allow it
+}
+
+type Reachability struct {
+	Allowed bool             `json:"allowed"`
+	Egress  DirectionVerdict `json:"egress"`  // evaluated against the source pod
+	Ingress DirectionVerdict `json:"ingress"` // evaluated against the destination pod
+}
+
+type NetpolResponse struct {
+	Policies     []NetpolInfo  `json:"policies,omitempty"`
+	Reachability *Reachability `json:"reachability,omitempty"`
+	RetriedTimes int           `json:"retriedTimes,omitempty"` // API calls retried after transient errors
+}
+
+func netpol(ctx context.Context, req NetpolRequest) (NetpolResponse, error) {
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace(ctx)
+	}
+
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	policyList, err := listNetworkPolicies(ctx, namespace)
+	if err != nil {
+		return NetpolResponse{}, kube.Error(err)
+	}
+
+	policies := make([]NetpolInfo, 0, len(policyList.Items))
+	for _, p := range policyList.Items {
+		policies = append(policies, netpolInfo(&p))
+	}
+
+	resp := NetpolResponse{Policies: policies}
+	if req.Source == nil && req.Destination == nil {
+		resp.RetriedTimes = kube.Retried(ctx)
+		return resp, nil
+	}
+
+	if req.Source == nil || req.Destination == nil || req.Port == 0 {
+		return NetpolResponse{}, toolserver.BadRequest("source, destination and port are required for reachability evaluation")
+	}
+
+	protocol := corev1.Protocol(strings.ToUpper(req.Protocol))
+	if protocol == "" {
+		protocol = corev1.ProtocolTCP
+	}
+
+	reach, err := evaluateReachability(ctx, *req.Source, *req.Destination, req.Port, protocol, namespace)
+	if err != nil {
+		return NetpolResponse{}, kube.Error(err)
+	}
+	resp.Reachability = reach
+	resp.RetriedTimes = kube.Retried(ctx)
+
+	return resp, nil
+}
+
+func netpolInfo(p *networkingv1.NetworkPolicy) NetpolInfo {
+	types := policyTypes(p)
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, string(t))
+	}
+	return NetpolInfo{
+		Name:        p.Name,
+		Namespace:   p.Namespace,
+		PodSelector: metav1.FormatLabelSelector(&p.Spec.PodSelector),
+		PolicyTypes: names,
+		Ingress:     len(p.Spec.Ingress),
+		Egress:      len(p.Spec.Egress),
+	}
+}
+
+// netpolEndpoint is a resolved pod together with its namespace labels, which
+// namespaceSelector peers match against.
+type netpolEndpoint struct {
+	pod      *corev1.Pod
+	nsLabels labels.Set
+}
+
+func resolveEndpoint(ctx context.Context, ref PodRef, defaultNamespace string) (*netpolEndpoint, error) {
+	if ref.Namespace == "" {
+		ref.Namespace = defaultNamespace
+	}
+	if ref.Pod == "" {
+		return nil, fmt.Errorf("pod name is required")
+	}
+	pod, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.Pod, error) {
+		return clientset.CoreV1().Pods(ref.Namespace).Get(ctx, ref.Pod, metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	ns, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.Namespace, error) {
+		return clientset.CoreV1().Namespaces().Get(ctx, ref.Namespace, metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &netpolEndpoint{pod: pod, nsLabels: labels.Set(ns.Labels)}, nil
+}
+
+func evaluateReachability(ctx context.Context, srcRef, dstRef PodRef, port int32, protocol corev1.Protocol, defaultNamespace string) (*Reachability, error) {
+	src, err := resolveEndpoint(ctx, srcRef, defaultNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	dst, err := resolveEndpoint(ctx, dstRef, defaultNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("destination: %w", err)
+	}
+
+	srcPolicies, err := listNetworkPolicies(ctx, src.pod.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	dstPolicies := srcPolicies
+	if dst.pod.Namespace != src.pod.Namespace {
+		dstPolicies, err = listNetworkPolicies(ctx, dst.pod.Namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return evaluatePolicies(srcPolicies.Items, dstPolicies.Items, src, dst, port, protocol), nil
+}
+
+func listNetworkPolicies(ctx context.Context, namespace string) (*networkingv1.NetworkPolicyList, error) {
+	return kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*networkingv1.NetworkPolicyList, error) {
+		return clientset.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{})
+	})
+}
+
+// evaluatePolicies applies NetworkPolicy semantics: traffic flows only if the
+// source's egress and the destination's ingress both allow it.
+func evaluatePolicies(srcPolicies, dstPolicies []networkingv1.NetworkPolicy, src, dst *netpolEndpoint, port int32, protocol corev1.Protocol) *Reachability {
+	reach := &Reachability{
+		Egress:  DirectionVerdict{Allowed: true},
+		Ingress: DirectionVerdict{Allowed: true},
+	}
+
+	for i := range srcPolicies {
+		p := &srcPolicies[i]
+		if !hasPolicyType(p, networkingv1.PolicyTypeEgress) || !selectsPod(p, src.pod) {
+			continue
+		}
+		reach.Egress.Isolated = true
+		allowed := false
+		for _, rule := range p.Spec.Egress {
+			if peersMatch(rule.To, p.Namespace, dst) && portsMatch(rule.Ports, dst.pod, port, protocol) {
+				allowed = true
+				break
+			}
+		}
+		if allowed {
+			reach.Egress.Matching = append(reach.Egress.Matching, p.Name)
+		} else {
+			reach.Egress.Denying = append(reach.Egress.Denying, p.Name)
+		}
+	}
+	if reach.Egress.Isolated {
+		reach.Egress.Allowed = len(reach.Egress.Matching) > 0
+	}
+
+	for i := range dstPolicies {
+		p := &dstPolicies[i]
+		if !hasPolicyType(p, networkingv1.PolicyTypeIngress) || !selectsPod(p, dst.pod) {
+			continue
+		}
+		reach.Ingress.Isolated = true
+		allowed := false
+		for _, rule := range p.Spec.Ingress {
+			if peersMatch(rule.From, p.Namespace, src) && portsMatch(rule.Ports, dst.pod, port, protocol) {
+				allowed = true
+				break
+			}
+		}
+		if allowed {
+			reach.Ingress.Matching = append(reach.Ingress.Matching, p.Name)
+		} else {
+			reach.Ingress.Denying = append(reach.Ingress.Denying, p.Name)
+		}
+	}
+	if reach.Ingress.Isolated {
+		reach.Ingress.Allowed = len(reach.Ingress.Matching) > 0
+	}
+
+	reach.Allowed = reach.Egress.Allowed && reach.Ingress.Allowed
+	return reach
+}
+
+// policyTypes returns the effective policy types, applying the API defaulting
+// rules when spec.policyTypes is empty.
+func policyTypes(p *networkingv1.NetworkPolicy) []networkingv1.PolicyType {
+	if len(p.Spec.PolicyTypes) > 0 {
+		return p.Spec.PolicyTypes
+	}
+	types := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	if len(p.Spec.Egress) > 0 {
+		types = append(types, networkingv1.PolicyTypeEgress)
+	}
+	return types
+}
+
+func hasPolicyType(p *networkingv1.NetworkPolicy, t networkingv1.PolicyType) bool {
+	for _, pt := range policyTypes(p) {
+		if pt == t {
+			return true
+		}
+	}
+	return false
+}
+
+func selectsPod(p *networkingv1.NetworkPolicy, pod *corev1.Pod) bool {
+	if p.Namespace != pod.Namespace {
+		return false
+	}
+	return selectorMatches(&p.Spec.PodSelector, pod.Labels)
+}
+
+func selectorMatches(sel *metav1.LabelSelector, set map[string]string) bool {
+	s, err := metav1.LabelSelectorAsSelector(sel)
+	if err != nil {
+		return false
+	}
+	return s.Matches(labels.Set(set))
+}
+
+// peersMatch reports whether ep is covered by any peer. An empty peer list
+// matches everything.
+func peersMatch(peers []networkingv1.NetworkPolicyPeer, policyNamespace string, ep *netpolEndpoint) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if peerMatches(peer, policyNamespace, ep) {
+			return true
+		}
+	}
+	return false
+}
+
+func peerMatches(peer networkingv1.NetworkPolicyPeer, policyNamespace string, ep *netpolEndpoint) bool {
+	if peer.IPBlock != nil {
+		return ipBlockMatches(peer.IPBlock, ep.pod.Status.PodIP)
+	}
+
+	if peer.NamespaceSelector != nil {
+		if !selectorMatches(peer.NamespaceSelector, ep.nsLabels) {
+			return false
+		}
+	} else if ep.pod.Namespace != policyNamespace {
+		// A bare podSelector only matches pods in the policy's namespace.
+		return false
+	}
+
+	if peer.PodSelector != nil {
+		return selectorMatches(peer.PodSelector, ep.pod.Labels)
+	}
+	return true
+}
+
+func ipBlockMatches(block *networkingv1.IPBlock, podIP string) bool {
+	ip := net.ParseIP(podIP)
+	if ip == nil {
+		return false
+	}
+	_, cidr, err := net.ParseCIDR(block.CIDR)
+	if err != nil || !cidr.Contains(ip) {
+		return false
+	}
+	for _, except := range block.Except {
+		if _, ex, err := net.ParseCIDR(except); err == nil && ex.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// portsMatch reports whether port/protocol is covered by the rule's ports.
+// Named ports are resolved against the destination pod's container ports.
+func portsMatch(ports []networkingv1.NetworkPolicyPort, dstPod *corev1.Pod, port int32, protocol corev1.Protocol) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, p := range ports {
+		proto := corev1.ProtocolTCP
+		if p.Protocol != nil {
+			proto = *p.Protocol
+		}
+		if proto != protocol {
+			continue
+		}
+		if p.Port == nil {
+			return true
+		}
+		if p.Port.StrVal != "" {
+			if namedPort(dstPod, p.Port.StrVal, protocol) == port {
+				return true
+			}
+			continue
+		}
+		start := p.Port.IntVal
+		end := start
+		if p.EndPort != nil {
+			end = *p.EndPort
+		}
+		if port >= start && port <= end {
+			return true
+		}
+	}
+	return false
+}
+
+func namedPort(pod *corev1.Pod, name string, protocol corev1.Protocol) int32 {
+	for _, c := range pod.Spec.Containers {
+		for _, cp := range c.Ports {
+			proto := cp.Protocol
+			if proto == "" {
+				proto = corev1.ProtocolTCP
+			}
+			if cp.Name == name && proto == protocol {
+				return cp.ContainerPort
+			}
+		}
+	}
+	return 0
+}
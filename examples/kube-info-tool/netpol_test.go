@@ -0,0 +1,147 @@
+package kubeinfotool
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func testEndpoint(ns, name string, podLabels map[string]string) *netpolEndpoint {
+	return &netpolEndpoint{
+		pod: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, Labels: podLabels},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name:  "app",
+				Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+			}}},
+			Status: corev1.PodStatus{PodIP: "10.0.0.5"},
+		},
+		nsLabels: labels.Set{"kubernetes.io/metadata.name": ns},
+	}
+}
+
+func TestEvaluatePolicies(t *testing.T) {
+	frontend := testEndpoint("web", "frontend", map[string]string{"app": "frontend"})
+	backend := testEndpoint("web", "backend", map[string]string{"app": "backend"})
+	other := testEndpoint("other", "client", map[string]string{"app": "frontend"})
+
+	tcp := corev1.ProtocolTCP
+	httpPort := intstr.FromString("http")
+	port8080 := intstr.FromInt32(8080)
+
+	denyAll := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "deny-all", Namespace: "web"},
+		Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}},
+	}
+	allowFrontend := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-frontend", Namespace: "web"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "backend"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From:  []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}}}},
+				Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &httpPort}},
+			}},
+		},
+	}
+	denyEgress := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "deny-egress", Namespace: "web"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		},
+	}
+	allowEgress8080 := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "allow-egress", Namespace: "web"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}},
+			Egress: []networkingv1.NetworkPolicyEgressRule{{
+				Ports: []networkingv1.NetworkPolicyPort{{Port: &port8080}},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		policies    []networkingv1.NetworkPolicy
+		src         *netpolEndpoint
+		port        int32
+		wantAllowed bool
+		wantDenying []string
+	}{
+		{
+			name:        "no policies",
+			src:         frontend,
+			port:        8080,
+			wantAllowed: true,
+		},
+		{
+			name:        "default deny",
+			policies:    []networkingv1.NetworkPolicy{denyAll},
+			src:         frontend,
+			port:        8080,
+			wantAllowed: false,
+			wantDenying: []string{"deny-all"},
+		},
+		{
+			name:        "named port allowed from frontend",
+			policies:    []networkingv1.NetworkPolicy{denyAll, allowFrontend},
+			src:         frontend,
+			port:        8080,
+			wantAllowed: true,
+			wantDenying: []string{"deny-all"},
+		},
+		{
+			name:        "wrong port",
+			policies:    []networkingv1.NetworkPolicy{allowFrontend},
+			src:         frontend,
+			port:        9090,
+			wantAllowed: false,
+			wantDenying: []string{"allow-frontend"},
+		},
+		{
+			name:        "pod selector does not cross namespaces",
+			policies:    []networkingv1.NetworkPolicy{allowFrontend},
+			src:         other,
+			port:        8080,
+			wantAllowed: false,
+			wantDenying: []string{"allow-frontend"},
+		},
+		{
+			name:        "egress isolated",
+			policies:    []networkingv1.NetworkPolicy{denyEgress},
+			src:         frontend,
+			port:        8080,
+			wantAllowed: false,
+		},
+		{
+			name:        "egress allowed by port",
+			policies:    []networkingv1.NetworkPolicy{denyEgress, allowEgress8080},
+			src:         frontend,
+			port:        8080,
+			wantAllowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var srcPolicies []networkingv1.NetworkPolicy
+			for _, p := range tt.policies {
+				if p.Namespace == tt.src.pod.Namespace {
+					srcPolicies = append(srcPolicies, p)
+				}
+			}
+			got := evaluatePolicies(srcPolicies, tt.policies, tt.src, backend, tt.port, corev1.ProtocolTCP)
+			if got.Allowed != tt.wantAllowed {
+				t.Errorf("evaluatePolicies() allowed = %v, want %v (%+v)", got.Allowed, tt.wantAllowed, got)
+			}
+			if len(tt.wantDenying) > 0 && !slices.Equal(got.Ingress.Denying, tt.wantDenying) {
+				t.Errorf("evaluatePolicies() ingress denying = %v, want %v", got.Ingress.Denying, tt.wantDenying)
+			}
+		})
+	}
+}
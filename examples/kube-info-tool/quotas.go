@@ -0,0 +1,164 @@
+package kubeinfotool
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type QuotasRequest struct {
+	Namespace string `json:"namespace"` // empty means all namespaces
+}
+
+type QuotaResource struct {
+	Resource string `json:"resource"`
+	Used     string `json:"used"`
+	Hard     string `json:"hard"`
+	AtLimit  bool   `json:"atLimit,omitempty"`  // used equals hard, as always with a hard of 0; further requests are rejected
+	Exceeded bool   `json:"exceeded,omitempty"` // used is over hard, e.g. after hard was lowered
+}
+
+type QuotaInfo struct {
+	Name      string          `json:"name"`
+	Resources []QuotaResource `json:"resources"`
+}
+
+type LimitInfo struct {
+	Type           string            `json:"type"`
+	Default        map[string]string `json:"default,omitempty"`
+	DefaultRequest map[string]string `json:"defaultRequest,omitempty"`
+	Min            map[string]string `json:"min,omitempty"`
+	Max            map[string]string `json:"max,omitempty"`
+}
+
+type LimitRangeInfo struct {
+	Name   string      `json:"name"`
+	Limits []LimitInfo `json:"limits"`
+}
+
+type NamespaceQuotas struct {
+	Namespace   string           `json:"namespace"`
+	Quotas      []QuotaInfo      `json:"quotas,omitempty"`
+	LimitRanges []LimitRangeInfo `json:"limitRanges,omitempty"`
+	// Blocked is set when quota admission is rejecting pod creation, as seen
+	// from FailedCreate events emitted by workload controllers.
+	Blocked        bool     `json:"blocked"`
+	BlockedReasons []string `json:"blockedReasons,omitempty"`
+}
+
+type QuotasResponse struct {
+	Namespaces   []NamespaceQuotas `json:"namespaces,omitempty"`
+	RetriedTimes int               `json:"retriedTimes,omitempty"` // API calls retried after transient errors
+}
+
+func quotas(ctx context.Context, req QuotasRequest) (QuotasResponse, error) {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	quotaList, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.ResourceQuotaList, error) {
+		return clientset.CoreV1().ResourceQuotas(req.Namespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return QuotasResponse{}, kube.Error(err)
+	}
+
+	limitList, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.LimitRangeList, error) {
+		return clientset.CoreV1().LimitRanges(req.Namespace).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return QuotasResponse{}, kube.Error(err)
+	}
+
+	eventList, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.EventList, error) {
+		return clientset.CoreV1().Events(req.Namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: "reason=FailedCreate",
+		})
+	})
+	if err != nil {
+		return QuotasResponse{}, kube.Error(err)
+	}
+
+	byNamespace := make(map[string]*NamespaceQuotas)
+	entry := func(ns string) *NamespaceQuotas {
+		if e, ok := byNamespace[ns]; ok {
+			return e
+		}
+		e := &NamespaceQuotas{Namespace: ns}
+		byNamespace[ns] = e
+		return e
+	}
+
+	for _, q := range quotaList.Items {
+		e := entry(q.Namespace)
+		e.Quotas = append(e.Quotas, quotaInfo(q))
+	}
+
+	for _, lr := range limitList.Items {
+		e := entry(lr.Namespace)
+		e.LimitRanges = append(e.LimitRanges, limitRangeInfo(lr))
+	}
+
+	for _, ev := range eventList.Items {
+		if !strings.Contains(ev.Message, "exceeded quota") {
+			continue
+		}
+		e := entry(ev.Namespace)
+		e.Blocked = true
+		e.BlockedReasons = append(e.BlockedReasons, ev.InvolvedObject.Kind+"/"+ev.InvolvedObject.Name+": "+ev.Message)
+	}
+
+	namespaces := make([]NamespaceQuotas, 0, len(byNamespace))
+	for _, e := range byNamespace {
+		namespaces = append(namespaces, *e)
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Namespace < namespaces[j].Namespace })
+
+	return QuotasResponse{Namespaces: namespaces, RetriedTimes: kube.Retried(ctx)}, nil
+}
+
+func quotaInfo(q corev1.ResourceQuota) QuotaInfo {
+	info := QuotaInfo{Name: q.Name, Resources: []QuotaResource{}}
+	for name, hard := range q.Status.Hard {
+		used := q.Status.Used[name]
+		cmp := used.Cmp(hard)
+		info.Resources = append(info.Resources, QuotaResource{
+			Resource: string(name),
+			Used:     used.String(),
+			Hard:     hard.String(),
+			AtLimit:  cmp == 0,
+			Exceeded: cmp > 0,
+		})
+	}
+	sort.Slice(info.Resources, func(i, j int) bool { return info.Resources[i].Resource < info.Resources[j].Resource })
+	return info
+}
+
+func limitRangeInfo(lr corev1.LimitRange) LimitRangeInfo {
+	info := LimitRangeInfo{Name: lr.Name, Limits: []LimitInfo{}}
+	for _, item := range lr.Spec.Limits {
+		info.Limits = append(info.Limits, LimitInfo{
+			Type:           string(item.Type),
+			Default:        resourceStrings(item.Default),
+			DefaultRequest: resourceStrings(item.DefaultRequest),
+			Min:            resourceStrings(item.Min),
+			Max:            resourceStrings(item.Max),
+		})
+	}
+	return info
+}
+
+func resourceStrings(list corev1.ResourceList) map[string]string {
+	if len(list) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(list))
+	for name, qty := range list {
+		out[string(name)] = qty.String()
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package kubeinfotool
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestQuotaInfo(t *testing.T) {
+	q := corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "web"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("2"),
+				corev1.ResourceRequestsMemory: resource.MustParse("1Gi"),
+				corev1.ResourcePods:           resource.MustParse("10"),
+				corev1.ResourceServices:       resource.MustParse("0"),
+				corev1.ResourceSecrets:        resource.MustParse("5"),
+			},
+			Used: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:    resource.MustParse("500m"),
+				corev1.ResourceRequestsMemory: resource.MustParse("1024Mi"),
+				corev1.ResourcePods:           resource.MustParse("12"),
+				corev1.ResourceServices:       resource.MustParse("0"),
+			},
+		},
+	}
+
+	want := map[string]QuotaResource{
+		"requests.cpu":    {Resource: "requests.cpu", Used: "500m", Hard: "2"},
+		"requests.memory": {Resource: "requests.memory", Used: "1Gi", Hard: "1Gi", AtLimit: true},
+		"pods":            {Resource: "pods", Used: "12", Hard: "10", Exceeded: true},
+		"services":        {Resource: "services", Used: "0", Hard: "0", AtLimit: true},
+		"secrets":         {Resource: "secrets", Used: "0", Hard: "5"},
+	}
+	info := quotaInfo(q)
+	if info.Name != "compute" || len(info.Resources) != len(want) {
+		t.Fatalf("quotaInfo = %+v", info)
+	}
+	for _, got := range info.Resources {
+		if got != want[got.Resource] {
+			t.Errorf("%s = %+v, want %+v", got.Resource, got, want[got.Resource])
+		}
+	}
+}
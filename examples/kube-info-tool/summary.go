@@ -0,0 +1,85 @@
+package kubeinfotool
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// summaryResource is the cluster summary MCP clients can attach as context.
+var summaryResource = toolserver.Resource{
+	URI:         "k8s://cluster/summary",
+	Name:        "cluster-summary",
+	Description: "Node readiness, namespaces and pods by phase across the cluster.",
+	MIMEType:    "application/json",
+}
+
+// troubleshootPodPrompt walks a model through diagnosing a failing pod with
+// the tool's own operations.
+var troubleshootPodPrompt = toolserver.Prompt{
+	Name:        "troubleshoot-pod",
+	Description: "Diagnose why a pod is not running or keeps restarting.",
+	Template: `Pod {{pod}} in namespace {{namespace}} is not healthy. Find out why:
+1. Use list-pods for namespace {{namespace}} to see its status and node.
+2. Use pod-logs for {{pod}}, with previous set if it has restarted, and look for the last error.
+3. Use namespace-quotas for {{namespace}} to rule out exhausted quotas.
+4. Use network-policies to check whether its traffic is allowed.
+Summarize the most likely cause and the fix.`,
+	Variables: []toolserver.PromptVariable{
+		{Name: "pod", Description: "Name of the pod", Required: true},
+		{Name: "namespace", Description: "Namespace of the pod", Default: "default"},
+	},
+}
+
+type ClusterSummary struct {
+	Nodes        int            `json:"nodes"`
+	ReadyNodes   int            `json:"readyNodes"`
+	Namespaces   int            `json:"namespaces"`
+	Pods         map[string]int `json:"pods"` // by phase
+	RetriedTimes int            `json:"retriedTimes,omitempty"`
+}
+
+// clusterSummary reads the summary resource.
+func clusterSummary(ctx context.Context) (string, error) {
+	ctx, cancel := apiContext(ctx)
+	defer cancel()
+
+	nodes, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.NodeList, error) {
+		return clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return "", kube.Error(err)
+	}
+	namespaces, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.NamespaceList, error) {
+		return clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return "", kube.Error(err)
+	}
+	pods, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.PodList, error) {
+		return clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	})
+	if err != nil {
+		return "", kube.Error(err)
+	}
+
+	summary := ClusterSummary{Nodes: len(nodes.Items), Namespaces: len(namespaces.Items), Pods: map[string]int{}}
+	for _, node := range nodes.Items {
+		for _, c := range node.Status.Conditions {
+			if c.Type == corev1.NodeReady && c.Status == corev1.ConditionTrue {
+				summary.ReadyNodes++
+			}
+		}
+	}
+	for _, pod := range pods.Items {
+		summary.Pods[string(pod.Status.Phase)]++
+	}
+	summary.RetriedTimes = kube.Retried(ctx)
+	b, err := json.Marshal(summary)
+	return string(b), err
+}
@@ -0,0 +1,22 @@
+// Command kubectl-explain serves kubectl-explain on its own. kube-mcp-tools serves it together
+// with the other example tools.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	kubectlexplain "github.com/atippey/kube-mcp/examples/kubectl-explain"
+)
+
+func main() {
+	s, err := kubectlexplain.New()
+	if err != nil {
+		slog.Error("starting kubectl-explain", "err", err)
+		os.Exit(1)
+	}
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
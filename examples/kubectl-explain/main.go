@@ -1,17 +1,23 @@
-package main
+// Package kubectlexplain implements kubectl-explain, which documents the
+// fields of Kubernetes resources from the cluster's OpenAPI schema.
+package kubectlexplain
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
-	"os"
+	"log/slog"
+	"maps"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
+	protobuf "google.golang.org/protobuf/proto"
 	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/kube-openapi/pkg/util/proto"
 )
 
@@ -24,12 +30,11 @@ type ExplainRequest struct {
 
 // ExplainResponse represents the response
 type ExplainResponse struct {
-	Resource    string   `json:"resource"`
-	Kind        string   `json:"kind,omitempty"`
-	Description string   `json:"description,omitempty"`
-	Type        string   `json:"type,omitempty"`
-	Fields      []Field  `json:"fields,omitempty"`
-	Error       string   `json:"error,omitempty"`
+	Resource    string  `json:"resource"`
+	Kind        string  `json:"kind,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Type        string  `json:"type,omitempty"`
+	Fields      []Field `json:"fields,omitempty"`
 }
 
 // Field represents a field in the schema
@@ -41,77 +46,69 @@ type Field struct {
 	Fields      []Field `json:"fields,omitempty"` // nested fields when recursive
 }
 
-var discoveryClient *discovery.DiscoveryClient
-
-func main() {
-	// Initialize Kubernetes client
-	if err := initKubeClient(); err != nil {
-		log.Fatalf("Failed to initialize Kubernetes client: %v", err)
-	}
-
-	// HTTP routes
-	http.HandleFunc("/health", handleHealth)
-	http.HandleFunc("/explain", handleExplain)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	log.Printf("Starting kubectl-explain server on :%s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
-	}
+// kubeClient is loaded on first use. Until it can be, /explain fails with
+// 503 and /readyz reports why.
+var kubeClient = kube.New("kubectl-explain")
+
+// openAPIPool bounds the OpenAPI documents fetched and parsed at once: each
+// takes tens of megabytes for a typical cluster.
+var openAPIPool = toolserver.NewPool("openapi", 2, 16)
+
+// explainCacheTTL is how long explanations are reused. The schema only
+// changes when the cluster is upgraded or a CRD is installed.
+const explainCacheTTL = 10 * time.Minute
+
+// staleMaxAge is how long explanations are kept to give, marked stale,
+// while the API server cannot be reached. The schema rarely changes.
+const staleMaxAge = time.Hour
+
+// New returns the kubectl-explain server, which connects to the cluster from
+// inside it, or else with the kubeconfig, on first use.
+func New() (*toolserver.Server, error) {
+	s := toolserver.New("kubectl-explain")
+	s.AddReadinessCheck("kubernetes", kubeClient.Ready)
+	s.AddDiagnostics("kubernetes", schemaDiagnostics)
+	s.AddWarmup("openapi", warmModels)
+	s.AddResource(toolserver.Resource{
+		URI:         "k8s://explain/kinds",
+		Name:        "explain-kinds",
+		Description: "The resource kinds kubectl-explain can document in this cluster, with their descriptions.",
+		MIMEType:    "application/json",
+	}, explainKinds)
+	toolserver.Register(s, "/explain", explain,
+		toolserver.Name("kubectl-explain"), toolserver.Describe("Get documentation for Kubernetes resource fields."),
+		toolserver.Cache(explainCacheTTL), toolserver.ServeStale(staleMaxAge))
+
+	return s, nil
 }
 
-func initKubeClient() error {
-	var config *rest.Config
-	var err error
+// ExplainKind is an entry of the explain-kinds resource.
+type ExplainKind struct {
+	Kind        string `json:"kind"`
+	Model       string `json:"model"`
+	Description string `json:"description,omitempty"`
+}
 
-	// Try in-cluster config first
-	config, err = rest.InClusterConfig()
+// explainKinds reads the explain-kinds resource: the kinds explain knows
+// whose models the cluster's schema has.
+func explainKinds(ctx context.Context) (string, error) {
+	models, err := loadModels(ctx)
 	if err != nil {
-		// Fall back to kubeconfig
-		kubeconfig := os.Getenv("KUBECONFIG")
-		if kubeconfig == "" {
-			kubeconfig = os.Getenv("HOME") + "/.kube/config"
-		}
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
-		if err != nil {
-			return fmt.Errorf("failed to build config: %w", err)
-		}
+		return "", err
 	}
-
-	discoveryClient, err = discovery.NewDiscoveryClientForConfig(config)
-	if err != nil {
-		return fmt.Errorf("failed to create discovery client: %w", err)
+	kinds := []ExplainKind{}
+	for _, kind := range slices.Sorted(maps.Keys(kindMappings)) {
+		if schema := findSchemaForKind(models, kind); schema != nil {
+			kinds = append(kinds, ExplainKind{Kind: kind, Model: schema.GetPath().String(), Description: schema.GetDescription()})
+		}
 	}
-
-	return nil
+	b, err := json.Marshal(kinds)
+	return string(b), err
 }
 
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
-}
-
-func handleExplain(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req ExplainRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(ExplainResponse{Error: "invalid request body"})
-		return
-	}
-
+func explain(ctx context.Context, req ExplainRequest) (ExplainResponse, error) {
 	if req.Resource == "" {
-		json.NewEncoder(w).Encode(ExplainResponse{Error: "resource is required"})
-		return
+		return ExplainResponse{}, toolserver.BadRequest("resource is required")
 	}
 
 	// Default max depth
@@ -120,32 +117,24 @@ func handleExplain(w http.ResponseWriter, r *http.Request) {
 		maxDepth = 5
 	}
 
-	response := explainResource(req.Resource, req.Recursive, maxDepth)
-	json.NewEncoder(w).Encode(response)
+	return explainResource(ctx, req.Resource, req.Recursive, maxDepth)
 }
 
-func explainResource(resource string, recursive bool, maxDepth int) ExplainResponse {
+func explainResource(ctx context.Context, resource string, recursive bool, maxDepth int) (ExplainResponse, error) {
 	// Parse resource path (e.g., "pod.spec.containers" -> kind="pod", path=["spec", "containers"])
 	parts := strings.Split(strings.ToLower(resource), ".")
 	kind := parts[0]
 	fieldPath := parts[1:]
 
-	// Fetch OpenAPI schema
-	doc, err := discoveryClient.OpenAPISchema()
+	models, err := loadModels(ctx)
 	if err != nil {
-		return ExplainResponse{Resource: resource, Error: fmt.Sprintf("failed to fetch OpenAPI schema: %v", err)}
-	}
-
-	// Parse the OpenAPI document
-	models, err := proto.NewOpenAPIData(doc)
-	if err != nil {
-		return ExplainResponse{Resource: resource, Error: fmt.Sprintf("failed to parse OpenAPI schema: %v", err)}
+		return ExplainResponse{}, err
 	}
 
 	// Find the schema for the requested kind
 	schema := findSchemaForKind(models, kind)
 	if schema == nil {
-		return ExplainResponse{Resource: resource, Error: fmt.Sprintf("unknown resource: %s", kind)}
+		return ExplainResponse{}, toolserver.NewError(toolserver.CodeNotFound, "unknown resource: %s", kind)
 	}
 
 	// Navigate to the requested field path
@@ -153,31 +142,138 @@ func explainResource(resource string, recursive bool, maxDepth int) ExplainRespo
 	for _, field := range fieldPath {
 		currentSchema = navigateToField(currentSchema, field, models)
 		if currentSchema == nil {
-			return ExplainResponse{Resource: resource, Error: fmt.Sprintf("unknown field: %s", strings.Join(fieldPath, "."))}
+			return ExplainResponse{}, toolserver.NewError(toolserver.CodeNotFound, "unknown field: %s", strings.Join(fieldPath, "."))
 		}
 	}
 
 	// Build response from schema
-	return buildResponse(resource, currentSchema, models, recursive, maxDepth)
+	return buildResponse(resource, currentSchema, models, recursive, maxDepth), nil
 }
 
-func findSchemaForKind(models proto.Models, kind string) proto.Schema {
-	// Common API group mappings
-	kindMappings := map[string][]string{
-		"pod":         {"io.k8s.api.core.v1.Pod"},
-		"deployment":  {"io.k8s.api.apps.v1.Deployment"},
-		"service":     {"io.k8s.api.core.v1.Service"},
-		"configmap":   {"io.k8s.api.core.v1.ConfigMap"},
-		"secret":      {"io.k8s.api.core.v1.Secret"},
-		"namespace":   {"io.k8s.api.core.v1.Namespace"},
-		"node":        {"io.k8s.api.core.v1.Node"},
-		"ingress":     {"io.k8s.api.networking.v1.Ingress"},
-		"statefulset": {"io.k8s.api.apps.v1.StatefulSet"},
-		"daemonset":   {"io.k8s.api.apps.v1.DaemonSet"},
-		"job":         {"io.k8s.api.batch.v1.Job"},
-		"cronjob":     {"io.k8s.api.batch.v1.CronJob"},
+// schemaCache holds the cluster's OpenAPI models, which loadModels fetches
+// and parses on first use, or with $WARMUP at startup, and again once they
+// are explainCacheTTL old.
+var schemaCache struct {
+	mu      sync.Mutex
+	models  proto.Models
+	fetched time.Time
+	took    time.Duration // to fetch and parse them
+}
+
+// loadModels returns the cluster's OpenAPI models, fetching them unless
+// they are cached. Calls wait for a fetch in progress rather than start
+// their own. If a refresh fails, the models fetched before are used while
+// they are less than staleMaxAge old.
+func loadModels(ctx context.Context) (proto.Models, error) {
+	schemaCache.mu.Lock()
+	defer schemaCache.mu.Unlock()
+	age := time.Since(schemaCache.fetched)
+	if schemaCache.models != nil && age < explainCacheTTL {
+		return schemaCache.models, nil
 	}
+	start := time.Now()
+	models, err := fetchModels(ctx)
+	if err != nil {
+		if schemaCache.models != nil && age < staleMaxAge {
+			slog.Warn("refreshing the OpenAPI schema failed; using the one fetched before", "err", err, "age", age.String())
+			return schemaCache.models, nil
+		}
+		return nil, err
+	}
+	schemaCache.models, schemaCache.fetched, schemaCache.took = models, time.Now(), time.Since(start)
+	slog.Info("loaded the OpenAPI schema", "took", schemaCache.took.String())
+	return models, nil
+}
+
+// warmModels is the warm-up that loads the models at startup.
+func warmModels(ctx context.Context) error {
+	_, err := loadModels(ctx)
+	return err
+}
+
+// fetchModels fetches and parses the cluster's OpenAPI schema.
+func fetchModels(ctx context.Context) (proto.Models, error) {
+	clientset, err := kubeClient.Clientset()
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := openAPIPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	doc, err := kube.Retry(ctx, func(ctx context.Context) (*openapi_v2.Document, error) {
+		return openAPISchema(ctx, clientset.Discovery())
+	})
+	if err != nil {
+		return nil, toolserver.UpstreamError(err, "failed to fetch OpenAPI schema")
+	}
+	models, err := proto.NewOpenAPIData(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI schema: %v", err)
+	}
+	return models, nil
+}
+
+// openAPIV2Protobuf is the media type of the protobuf-encoded OpenAPI v2
+// document.
+const openAPIV2Protobuf = "application/com.github.proto-openapi.spec.v2@v1.0+protobuf"
+
+// openAPISchema fetches the cluster's OpenAPI v2 document like
+// discovery.OpenAPISchema, but under ctx, so the fetch is cancelled with the
+// request.
+func openAPISchema(ctx context.Context, client discovery.DiscoveryInterface) (*openapi_v2.Document, error) {
+	data, err := client.RESTClient().Get().
+		AbsPath("/openapi/v2").
+		SetHeader("Accept", openAPIV2Protobuf).
+		Do(ctx).
+		Raw()
+	if err != nil {
+		return nil, err
+	}
+	doc := &openapi_v2.Document{}
+	if err := protobuf.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
 
+// schemaDiagnostics reports in /healthz/verbose the client's state, and
+// when the OpenAPI models were last loaded, how long ago and how long
+// fetching and parsing them took.
+func schemaDiagnostics() map[string]any {
+	details := kubeClient.Diagnostics()
+	schemaCache.mu.Lock()
+	t, took := schemaCache.fetched, schemaCache.took
+	schemaCache.mu.Unlock()
+	if t.IsZero() {
+		details["schema_fetched"] = false
+		return details
+	}
+	details["schema_fetched_at"], details["schema_age_seconds"] = t, int(time.Since(t).Seconds())
+	details["schema_load_ms"] = took.Milliseconds()
+	return details
+}
+
+// kindMappings are the models of the kinds explain knows, by kind.
+var kindMappings = map[string][]string{
+	"pod":         {"io.k8s.api.core.v1.Pod"},
+	"deployment":  {"io.k8s.api.apps.v1.Deployment"},
+	"service":     {"io.k8s.api.core.v1.Service"},
+	"configmap":   {"io.k8s.api.core.v1.ConfigMap"},
+	"secret":      {"io.k8s.api.core.v1.Secret"},
+	"namespace":   {"io.k8s.api.core.v1.Namespace"},
+	"node":        {"io.k8s.api.core.v1.Node"},
+	"ingress":     {"io.k8s.api.networking.v1.Ingress"},
+	"statefulset": {"io.k8s.api.apps.v1.StatefulSet"},
+	"daemonset":   {"io.k8s.api.apps.v1.DaemonSet"},
+	"job":         {"io.k8s.api.batch.v1.Job"},
+	"cronjob":     {"io.k8s.api.batch.v1.CronJob"},
+}
+
+func findSchemaForKind(models proto.Models, kind string) proto.Schema {
 	if refs, ok := kindMappings[kind]; ok {
 		for _, ref := range refs {
 			if schema := models.LookupModel(ref); schema != nil {
@@ -322,4 +418,3 @@ func getTypeName(schema proto.Schema) string {
 		return "unknown"
 	}
 }
-
@@ -0,0 +1,101 @@
+package kubectlexplain
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
+	protobuf "google.golang.org/protobuf/proto"
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+// testSchema returns the OpenAPI v2 document of a Kubernetes 1.27 API
+// server, protobuf-encoded as fetchModels fetches it.
+func testSchema(tb testing.TB) []byte {
+	tb.Helper()
+	doc, err := openapi_v2.ParseDocument(tooltest.OpenAPIv2(tb))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	pb, err := protobuf.Marshal(doc)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return pb
+}
+
+// parseSchema does what fetchModels does with the fetched document.
+func parseSchema(tb testing.TB, pb []byte) proto.Models {
+	doc := &openapi_v2.Document{}
+	if err := protobuf.Unmarshal(pb, doc); err != nil {
+		tb.Fatal(err)
+	}
+	models, err := proto.NewOpenAPIData(doc)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return models
+}
+
+func TestBuildResponse(t *testing.T) {
+	models := parseSchema(t, testSchema(t))
+
+	tests := []struct {
+		resource string
+		path     []string
+		wantType string
+	}{
+		{resource: "deployment", wantType: "object"},
+		{resource: "deployment", path: []string{"spec", "replicas"}, wantType: "integer"},
+		{resource: "pod", path: []string{"spec", "containers"}, wantType: "[]io.k8s.api.core.v1.Container"},
+		{resource: "cronjob", path: []string{"spec", "schedule"}, wantType: "string"},
+	}
+	for _, tt := range tests {
+		schema := findSchemaForKind(models, tt.resource)
+		for _, field := range tt.path {
+			schema = navigateToField(schema, field, models)
+		}
+		if schema == nil {
+			t.Errorf("%s %v: not found", tt.resource, tt.path)
+			continue
+		}
+		if got := buildResponse(tt.resource, schema, models, false, 5).Type; got != tt.wantType {
+			t.Errorf("%s %v: type %q, want %q", tt.resource, tt.path, got, tt.wantType)
+		}
+	}
+}
+
+// TestBuildFieldsAllocs guards against regressions that BenchmarkBuildFields
+// would show, with headroom over the baseline in docs/BENCHMARKS.md.
+func TestBuildFieldsAllocs(t *testing.T) {
+	models := parseSchema(t, testSchema(t))
+	schema := findSchemaForKind(models, "deployment")
+	if allocs := testing.AllocsPerRun(10, func() { buildResponse("deployment", schema, models, true, 5) }); allocs > 720 {
+		t.Errorf("recursive deployment to depth 5: %.0f allocations, budget 720", allocs)
+	}
+}
+
+func BenchmarkParseOpenAPI(b *testing.B) {
+	pb := testSchema(b)
+	b.SetBytes(int64(len(pb)))
+	b.ReportAllocs()
+	for b.Loop() {
+		parseSchema(b, pb)
+	}
+}
+
+func BenchmarkBuildFields(b *testing.B) {
+	models := parseSchema(b, testSchema(b))
+	for _, kind := range []string{"pod", "deployment"} {
+		schema := findSchemaForKind(models, kind)
+		for _, depth := range []int{1, 3, 5} {
+			b.Run(fmt.Sprintf("%s/depth=%d", kind, depth), func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					buildResponse(kind, schema, models, true, depth)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,22 @@
+// Command kustomize-tool serves kustomize-tool on its own. kube-mcp-tools serves it
+// together with the other example tools.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	kustomizetool "kustomize-tool"
+)
+
+func main() {
+	s, err := kustomizetool.New()
+	if err != nil {
+		slog.Error("starting kustomize-tool", "err", err)
+		os.Exit(1)
+	}
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,144 @@
+package kustomizetool
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the table lineDiff fills, the product of the line
+// counts of the parts of two documents that differ, to bound its memory.
+const maxDiffCells = 4 << 20
+
+// unifiedDiff returns the differences between a and b in unified format,
+// with three lines of context, or "" if they are equal.
+func unifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := lineDiff(splitLines(a), splitLines(b))
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	const context = 3
+	for i := 0; i < len(ops); {
+		if ops[i].op == ' ' {
+			i++
+			continue
+		}
+		// A hunk runs from context lines before the change to context lines
+		// after the last change less than 2*context lines after another.
+		start := max(i-context, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].op != ' ' {
+				end = j
+			} else if j-end > 2*context {
+				break
+			}
+		}
+		end = min(end+context+1, len(ops))
+		var aStart, aLen, bStart, bLen int
+		aStart, bStart = ops[start].a+1, ops[start].b+1
+		for _, op := range ops[start:end] {
+			if op.op != '+' {
+				aLen++
+			}
+			if op.op != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&out, "%c%s\n", op.op, op.line)
+		}
+		i = end
+	}
+	return out.String()
+}
+
+func hunkRange(start, n int) string {
+	if n == 0 {
+		start-- // an empty range is written as the line before it
+	}
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffOp is a line of a diff: kept (' '), removed ('-') or added ('+'),
+// with its index in the old and new lines.
+type diffOp struct {
+	op   byte
+	line string
+	a, b int
+}
+
+// lineDiff returns the edits turning a into b, from the longest common
+// subsequence of the lines between their common prefix and suffix. If
+// that part is too long to compare, it is all replaced.
+func lineDiff(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	var ops []diffOp
+	for i := range prefix {
+		ops = append(ops, diffOp{' ', a[i], i, i})
+	}
+	if (len(am)+1)*(len(bm)+1) > maxDiffCells {
+		for i, line := range am {
+			ops = append(ops, diffOp{'-', line, prefix + i, prefix})
+		}
+		for j, line := range bm {
+			ops = append(ops, diffOp{'+', line, prefix + len(am), prefix + j})
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of
+		// am[i:] and bm[j:].
+		lcs := make([][]int32, len(am)+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, len(bm)+1)
+		}
+		for i := len(am) - 1; i >= 0; i-- {
+			for j := len(bm) - 1; j >= 0; j-- {
+				if am[i] == bm[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(am) || j < len(bm) {
+			switch {
+			case i < len(am) && j < len(bm) && am[i] == bm[j]:
+				ops = append(ops, diffOp{' ', am[i], prefix + i, prefix + j})
+				i, j = i+1, j+1
+			case j == len(bm) || i < len(am) && lcs[i+1][j] >= lcs[i][j+1]:
+				ops = append(ops, diffOp{'-', am[i], prefix + i, prefix + j})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', bm[j], prefix + i, prefix + j})
+				j++
+			}
+		}
+	}
+	for k := range suffix {
+		i, j := len(a)-suffix+k, len(b)-suffix+k
+		ops = append(ops, diffOp{' ', a[i], i, j})
+	}
+	return ops
+}
@@ -0,0 +1,193 @@
+package kustomizetool
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// gitTimeout bounds fetching a git source.
+const gitTimeout = time.Minute
+
+// systemCABundles are where distributions keep the system's CAs, which
+// git stops trusting when given a CA file, so they are added to it.
+var systemCABundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt", // Alpine, Debian
+	"/etc/pki/tls/certs/ca-bundle.crt",   // Fedora, RHEL
+}
+
+// gitSource is a repository directory as kustomize names it:
+// https://HOST/REPO//DIR?ref=REF, or https://HOST/REPO.git/DIR?ref=REF.
+type gitSource struct {
+	repo   *url.URL
+	dir    string // "." for the repository's root
+	ref    string // a branch, tag or commit; "" for the default branch
+	source string // the URL without its credentials, for messages
+}
+
+// parseGitSource parses a git source. As with kustomize, the scheme
+// defaults to https, and the ref may also be given as version.
+func parseGitSource(raw string) (*gitSource, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, toolserver.BadRequest("git must be an https URL such as https://github.com/org/repo//deploy?ref=v1.0.0")
+	}
+	src := &gitSource{dir: ".", ref: cmp.Or(u.Query().Get("ref"), u.Query().Get("version"))}
+	repoPath := u.Path
+	if before, after, ok := strings.Cut(u.Path, "//"); ok {
+		repoPath, src.dir = before, after
+	} else if before, after, ok := strings.Cut(u.Path, ".git/"); ok {
+		repoPath, src.dir = before+".git", after
+	}
+	if src.dir, err = cleanPath(cmp.Or(src.dir, ".")); err != nil {
+		return nil, toolserver.BadRequest("git: %v", err)
+	}
+	if strings.HasPrefix(src.ref, "-") {
+		return nil, toolserver.BadRequest("git: ref %q is not a branch, tag or commit", src.ref)
+	}
+	src.repo = &url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host, Path: repoPath}
+	src.source = u.Redacted()
+	return src, nil
+}
+
+// fetchGit checks out a git source's ref, with history of depth 1, in a
+// temporary directory, and reads its files.
+func fetchGit(ctx context.Context, raw string) (*openedSource, error) {
+	src, err := parseGitSource(raw)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp("", "kustomize-tool-git-")
+	if err != nil {
+		return nil, toolserver.NewError(toolserver.CodeInternal, "creating a directory for %s: %v", src.source, err)
+	}
+	defer os.RemoveAll(tmp)
+
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+	env, err := gitEnv(tmp)
+	if err != nil {
+		return nil, toolserver.NewError(toolserver.CodeInternal, "%v", err)
+	}
+	checkout := filepath.Join(tmp, "checkout")
+	git := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Env = env
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", gitError(ctx, src, err, stderr.String())
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	if _, err := git("init", "-q", checkout); err != nil {
+		return nil, err
+	}
+	if _, err := git("-C", checkout, "fetch", "-q", "--depth", "1", "--no-tags", "--", src.repo.String(), cmp.Or(src.ref, "HEAD")); err != nil {
+		return nil, err
+	}
+	if _, err := git("-C", checkout, "checkout", "-q", "FETCH_HEAD"); err != nil {
+		return nil, err
+	}
+	revision, err := git("-C", checkout, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := os.OpenRoot(checkout)
+	if err != nil {
+		return nil, toolserver.NewError(toolserver.CodeInternal, "opening the checkout of %s: %v", src.source, err)
+	}
+	defer root.Close()
+	files, err := readTree(root.FS())
+	var e *toolserver.Error
+	switch {
+	case errors.As(err, &e):
+		return nil, e
+	case err != nil:
+		return nil, toolserver.NewError(toolserver.CodeInternal, "reading the checkout of %s: %v", src.source, err)
+	}
+	return &openedSource{tree: files, root: src.dir, revision: revision}, nil
+}
+
+// gitEnv returns the environment git runs with: no prompts or system and
+// user config, only http and https, and the egress proxy and CA bundle
+// settings of package toolserver.
+func gitEnv(home string) ([]string, error) {
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + home,
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_ALLOW_PROTOCOL=http:https",
+	}
+	for _, name := range []string{"HTTPS_PROXY", "HTTP_PROXY", "NO_PROXY"} {
+		if v := config.String(name, os.Getenv(strings.ToLower(name))); v != "" {
+			env = append(env, name+"="+v)
+		}
+	}
+	bundle := config.String("CA_BUNDLE", "")
+	if bundle == "" {
+		return env, nil
+	}
+	var pem []byte
+	for _, file := range append(systemCABundles, strings.Split(bundle, ",")...) {
+		if file = strings.TrimSpace(file); file == "" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) && !strings.Contains(bundle, file) {
+			continue // not this distribution's
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading CA_BUNDLE: %v", err)
+		}
+		pem = append(append(pem, data...), '\n')
+	}
+	caFile := filepath.Join(home, "ca.pem")
+	if err := os.WriteFile(caFile, pem, 0o600); err != nil {
+		return nil, err
+	}
+	return append(env, "GIT_SSL_CAINFO="+caFile), nil
+}
+
+// gitError reports a failed git command: NOT_FOUND for a missing
+// repository or ref, FORBIDDEN when the server wants credentials, and
+// otherwise an upstream error, a timeout if the deadline passed.
+func gitError(ctx context.Context, src *gitSource, err error, stderr string) error {
+	if ctx.Err() != nil {
+		return toolserver.UpstreamError(ctx.Err(), "fetching %s", src.source)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return toolserver.NewError(toolserver.CodeUnavailable, "running git: %v", err) // e.g. not installed
+	}
+	msg := strings.TrimSpace(stderr)
+	if src.repo.User != nil {
+		msg = strings.ReplaceAll(msg, src.repo.String(), src.source)
+	}
+	switch {
+	case strings.Contains(msg, "couldn't find remote ref"), strings.Contains(msg, "not found"),
+		strings.Contains(msg, "returned error: 404"):
+		return toolserver.NewError(toolserver.CodeNotFound, "fetching %s: %s", src.source, msg)
+	case strings.Contains(msg, "could not read Username"), strings.Contains(msg, "Authentication failed"),
+		strings.Contains(msg, "returned error: 401"), strings.Contains(msg, "returned error: 403"):
+		return toolserver.NewError(toolserver.CodeForbidden, "fetching %s: %s", src.source, msg)
+	}
+	return toolserver.UpstreamError(errors.New(msg), "fetching %s", src.source)
+}
@@ -0,0 +1,25 @@
+package kustomizetool
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/atippey/kube-mcp/pkg/kube"
+)
+
+// kubeClient is loaded on first use. Without a reachable cluster, only
+// ConfigMap sources fail, with 503.
+var kubeClient = kube.New("kustomize-tool")
+
+// apiTimeout is the deadline of reading a ConfigMap source, retries included,
+// overridable with $KUBE_TIMEOUT (see package config; package kube has the
+// rate limit and retry settings).
+var apiTimeout = 10 * time.Second
+
+// configureAPICalls applies the timeout setting to the package-level
+// default.
+func configureAPICalls() {
+	apiTimeout = config.Duration("KUBE_TIMEOUT", apiTimeout)
+	slog.Info("kubernetes API calls", "timeout", apiTimeout.String())
+}
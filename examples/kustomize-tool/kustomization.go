@@ -0,0 +1,130 @@
+package kustomizetool
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// kustomizationFiles are the names kustomize reads a directory's
+// kustomization from, in order.
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// unprocessable reports a kustomization that cannot be built.
+func unprocessable(format string, args ...any) error {
+	return toolserver.NewError(toolserver.CodeUnprocessable, format, args...)
+}
+
+// kustomizationFile returns the path of dir's kustomization, or "" if it
+// has none.
+func (t memTree) kustomizationFile(dir string) string {
+	for _, name := range kustomizationFiles {
+		if p := path.Join(dir, name); t[p] != nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// loadKustomization reads dir's kustomization, as kustomize does.
+func (t memTree) loadKustomization(dir string) (*types.Kustomization, string, error) {
+	file := t.kustomizationFile(dir)
+	if file == "" {
+		return nil, "", toolserver.NewError(toolserver.CodeNotFound, "%s has no kustomization.yaml", dir)
+	}
+	var k types.Kustomization
+	if err := k.Unmarshal(t[file]); err != nil {
+		return nil, "", unprocessable("%s: %v", file, err)
+	}
+	k.FixKustomization()
+	return &k, file, nil
+}
+
+// remote reports whether a resources, bases or components entry is a
+// remote file or repository, which kustomize would fetch itself.
+func remote(entry string) bool {
+	return strings.Contains(entry, "://") || strings.HasPrefix(entry, "github.com/") || strings.HasPrefix(entry, "git@")
+}
+
+// checkRemote fails with UNPROCESSABLE if a kustomization of the source
+// lists a remote resource, so a build never fetches one past the tool's
+// egress settings. Kustomizations that do not parse are left for the
+// build to report.
+func (t memTree) checkRemote() error {
+	for _, name := range slices.Sorted(maps.Keys(t)) {
+		dir, file := path.Split(name)
+		if !slices.Contains(kustomizationFiles, file) {
+			continue
+		}
+		k, _, err := t.loadKustomization(path.Clean(dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range slices.Concat(k.Resources, k.Bases, k.Components) {
+			if remote(entry) {
+				return unprocessable("%s: remote resource %s is not supported; build it as a git source", name, entry)
+			}
+		}
+	}
+	return nil
+}
+
+// kustomize builds the kustomization in dir of t with kustomize's krusty
+// over an in-memory copy of t, as kustomize build does: resources are
+// sorted in its legacy order unless the kustomization's sortOptions say
+// otherwise, and plugins, including helmCharts, are disabled.
+func kustomize(t memTree, dir string) (resmap.ResMap, error) {
+	if t.kustomizationFile(dir) == "" {
+		return nil, toolserver.NewError(toolserver.CodeNotFound, "%s has no kustomization.yaml", dir)
+	}
+	if err := t.checkRemote(); err != nil {
+		return nil, err
+	}
+	fsys := filesys.MakeFsInMemory()
+	for name, data := range t {
+		if err := fsys.WriteFile("/"+name, data); err != nil {
+			return nil, toolserver.NewError(toolserver.CodeInternal, "copying %s: %v", name, err)
+		}
+	}
+	opts := krusty.MakeDefaultOptions()
+	opts.Reorder = krusty.ReorderOptionUnspecified
+	m, err := krusty.MakeKustomizer(opts).Run(fsys, "/"+dir)
+	if err != nil {
+		return nil, unprocessable("%v", err)
+	}
+	return m, nil
+}
+
+// manifest writes a build as kustomize build prints it: YAML documents
+// separated by ---, each with its keys sorted.
+func manifest(m resmap.ResMap) (string, error) {
+	data, err := m.AsYaml()
+	if err != nil {
+		return "", toolserver.NewError(toolserver.CodeInternal, "encoding the build: %v", err)
+	}
+	return string(data), nil
+}
+
+// describe names a resource as kubectl does, e.g. Deployment web/frontend.
+func describe(r *resource.Resource) string {
+	if ns := r.GetNamespace(); ns != "" {
+		return fmt.Sprintf("%s %s/%s", r.GetKind(), ns, r.GetName())
+	}
+	return r.GetKind() + " " + r.GetName()
+}
+
+// resourceYAML returns a resource as manifest writes it, or "" if it cannot
+// be encoded, which resources decoded from YAML always can.
+func resourceYAML(r *resource.Resource) string {
+	data, _ := r.AsYAML()
+	return string(data)
+}
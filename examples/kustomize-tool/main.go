@@ -0,0 +1,316 @@
+// Package kustomizetool implements kustomize-tool, which builds
+// kustomizations read inline, from a ConfigMap or from git, as kustomize
+// build does. It also lists a source's overlays and previews what a patch
+// would change in a build.
+package kustomizetool
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
+)
+
+// buildTimeout bounds a request, which may fetch a git source and build its
+// overlay twice.
+const buildTimeout = 2 * time.Minute
+
+// --- /build types ---
+
+type BuildRequest struct {
+	Source
+	Overlay string `json:"overlay"` // the directory to build, relative to the source; defaults to its root
+}
+
+type Resource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+type BuildResponse struct {
+	Overlay      string     `json:"overlay"`
+	Revision     string     `json:"revision,omitempty"` // the commit built, for a git source
+	Manifest     string     `json:"manifest"`           // as kustomize build prints it
+	Resources    []Resource `json:"resources"`
+	Count        int        `json:"count"`
+	RetriedTimes int        `json:"retriedTimes,omitempty"` // API calls retried after transient errors
+}
+
+// ContentBlocks gives MCP clients the manifest as a YAML resource instead
+// of a JSON string with escaped newlines.
+func (r BuildResponse) ContentBlocks() []toolserver.Content {
+	return []toolserver.Content{toolserver.ResourceContent("kustomize://"+r.Overlay+"/build", "application/yaml", r.Manifest)}
+}
+
+// --- /overlays types ---
+
+type OverlaysRequest struct {
+	Source
+}
+
+type Overlay struct {
+	Path       string   `json:"path"`
+	Kind       string   `json:"kind"`                 // Kustomization or Component
+	Bases      []string `json:"bases,omitempty"`      // the directories it builds on, from its resources and bases
+	Components []string `json:"components,omitempty"` // the components it applies
+	Error      string   `json:"error,omitempty"`      // why its kustomization could not be read
+}
+
+type OverlaysResponse struct {
+	Overlays     []Overlay `json:"overlays"`
+	Count        int       `json:"count"`
+	Revision     string    `json:"revision,omitempty"`
+	RetriedTimes int       `json:"retriedTimes,omitempty"`
+}
+
+// --- /patch types ---
+
+type PatchRequest struct {
+	Source
+	Overlay string          `json:"overlay"`
+	Patch   string          `json:"patch"`  // strategic merge patches, or a JSON 6902 patch, in YAML or JSON
+	Target  *types.Selector `json:"target"` // required for a JSON 6902 patch; otherwise the patch names its resource
+}
+
+type Change struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Change    string `json:"change"` // added, removed or changed
+	Diff      string `json:"diff"`   // unified, of the resource's YAML
+}
+
+type PatchResponse struct {
+	Overlay      string   `json:"overlay"`
+	Revision     string   `json:"revision,omitempty"`
+	Patched      int      `json:"patched"` // resources the patch changed
+	Changes      []Change `json:"changes"`
+	Unchanged    int      `json:"unchanged"`
+	RetriedTimes int      `json:"retriedTimes,omitempty"`
+}
+
+// ContentBlocks gives MCP clients a summary of the changes and their diff
+// as one text/x-diff resource.
+func (r PatchResponse) ContentBlocks() []toolserver.Content {
+	blocks := []toolserver.Content{toolserver.TextContent(fmt.Sprintf(
+		"Patching %s: %d resources patched, %d changed, %d unchanged.", r.Overlay, r.Patched, len(r.Changes), r.Unchanged))}
+	if len(r.Changes) > 0 {
+		var diff strings.Builder
+		for _, c := range r.Changes {
+			diff.WriteString(c.Diff)
+		}
+		blocks = append(blocks, toolserver.ResourceContent("kustomize://"+r.Overlay+"/patch", "text/x-diff", diff.String()))
+	}
+	return blocks
+}
+
+// New returns the kustomize-tool server. The Kubernetes client, used only
+// for ConfigMap sources, is optional, so unlike the tools that need the
+// cluster it neither warms it up nor checks its RBAC at startup.
+func New() (*toolserver.Server, error) {
+	configureAPICalls()
+
+	s := toolserver.New("kustomize-tool")
+	s.AddDiagnostics("kubernetes", kubeClient.Diagnostics)
+	toolserver.Register(s, "/build", build,
+		toolserver.Name("kustomize-build"), toolserver.Describe("Build a kustomization, inline, from a ConfigMap or from a git repository, and return its manifests as kustomize build does."),
+		toolserver.Timeout(buildTimeout), toolserver.RBAC("get configmaps"))
+	toolserver.Register(s, "/overlays", overlays,
+		toolserver.Name("kustomize-overlays"), toolserver.Describe("List the kustomizations of a source, inline, from a ConfigMap or from a git repository, with the bases and components each builds on."),
+		toolserver.Timeout(buildTimeout), toolserver.RBAC("get configmaps"))
+	toolserver.Register(s, "/patch", previewPatch,
+		toolserver.Name("kustomize-patch-preview"), toolserver.Describe("Show what adding a strategic merge or JSON 6902 patch to a kustomization would change in its build, resource by resource."),
+		toolserver.Timeout(buildTimeout), toolserver.RBAC("get configmaps"))
+
+	return s, nil
+}
+
+// openOverlay opens a request's source and returns the overlay directory
+// in it.
+func openOverlay(ctx context.Context, src Source, overlay string) (*openedSource, string, error) {
+	s, err := src.open(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	dir, err := cleanPath(path.Join(s.root, cmp.Or(overlay, ".")))
+	if err != nil {
+		return nil, "", toolserver.BadRequest("overlay: %v", err)
+	}
+	if !s.tree.isDir(dir) {
+		return nil, "", toolserver.NewError(toolserver.CodeNotFound, "overlay %s is not a directory of the source", cmp.Or(overlay, "."))
+	}
+	return s, dir, nil
+}
+
+func build(ctx context.Context, req BuildRequest) (BuildResponse, error) {
+	ctx = kube.CountRetries(ctx)
+	src, dir, err := openOverlay(ctx, req.Source, req.Overlay)
+	if err != nil {
+		return BuildResponse{}, err
+	}
+
+	m, err := kustomize(src.tree, dir)
+	if err != nil {
+		return BuildResponse{}, err
+	}
+	out, err := manifest(m)
+	if err != nil {
+		return BuildResponse{}, err
+	}
+	resources := make([]Resource, 0, m.Size())
+	for _, r := range m.Resources() {
+		resources = append(resources, Resource{APIVersion: r.GetApiVersion(), Kind: r.GetKind(), Namespace: r.GetNamespace(), Name: r.GetName()})
+	}
+	return BuildResponse{
+		Overlay:      cmp.Or(req.Overlay, "."),
+		Revision:     src.revision,
+		Manifest:     out,
+		Resources:    resources,
+		Count:        len(resources),
+		RetriedTimes: kube.Retried(ctx),
+	}, nil
+}
+
+func overlays(ctx context.Context, req OverlaysRequest) (OverlaysResponse, error) {
+	ctx = kube.CountRetries(ctx)
+	src, root, err := openOverlay(ctx, req.Source, "")
+	if err != nil {
+		return OverlaysResponse{}, err
+	}
+
+	var dirs []string
+	for name := range src.tree {
+		if dir, file := path.Split(name); slices.Contains(kustomizationFiles, file) {
+			if dir = path.Clean(dir); root == "." || dir == root || strings.HasPrefix(dir, root+"/") {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	found := []Overlay{}
+	slices.Sort(dirs)
+	for _, dir := range slices.Compact(dirs) {
+		rel := relPath(root, dir)
+		k, _, err := src.tree.loadKustomization(dir)
+		if err != nil {
+			found = append(found, Overlay{Path: rel, Kind: "Kustomization", Error: err.Error()})
+			continue
+		}
+		o := Overlay{Path: rel, Kind: k.Kind}
+		for _, entry := range slices.Concat(k.Resources, k.Bases) {
+			if p, err := cleanPath(path.Join(dir, entry)); err == nil && !remote(entry) && src.tree.isDir(p) {
+				o.Bases = append(o.Bases, relPath(root, p))
+			}
+		}
+		for _, entry := range k.Components {
+			if p, err := cleanPath(path.Join(dir, entry)); err == nil && !remote(entry) {
+				o.Components = append(o.Components, relPath(root, p))
+			}
+		}
+		found = append(found, o)
+	}
+	return OverlaysResponse{Overlays: found, Count: len(found), Revision: src.revision, RetriedTimes: kube.Retried(ctx)}, nil
+}
+
+// relPath returns the path of a directory of the source relative to its
+// root, as requests name overlays.
+func relPath(root, dir string) string {
+	if root == "." {
+		return dir
+	}
+	if dir == root {
+		return "."
+	}
+	if rel, ok := strings.CutPrefix(dir, root+"/"); ok {
+		return rel
+	}
+	return "/" + dir // outside the root, e.g. a base elsewhere in the repository
+}
+
+func previewPatch(ctx context.Context, req PatchRequest) (PatchResponse, error) {
+	if strings.TrimSpace(req.Patch) == "" {
+		return PatchResponse{}, toolserver.BadRequest("patch is required")
+	}
+	ctx = kube.CountRetries(ctx)
+	src, dir, err := openOverlay(ctx, req.Source, req.Overlay)
+	if err != nil {
+		return PatchResponse{}, err
+	}
+
+	before, err := kustomize(src.tree, dir)
+	if err != nil {
+		return PatchResponse{}, err
+	}
+	patched, err := withPatch(src.tree, dir, types.Patch{Patch: req.Patch, Target: req.Target})
+	if err != nil {
+		return PatchResponse{}, err
+	}
+	after, err := kustomize(patched, dir)
+	if err != nil {
+		return PatchResponse{}, err
+	}
+
+	resp := PatchResponse{Overlay: cmp.Or(req.Overlay, "."), Revision: src.revision, Changes: []Change{}}
+	change := func(r *resource.Resource, kind, a, b string) {
+		resp.Changes = append(resp.Changes, Change{
+			Kind: r.GetKind(), Namespace: r.GetNamespace(), Name: r.GetName(), Change: kind,
+			Diff: unifiedDiff("build: "+describe(r), "patched: "+describe(r), a, b),
+		})
+	}
+	old := make(map[string]*resource.Resource, before.Size())
+	for _, r := range before.Resources() {
+		old[describe(r)] = r
+	}
+	for _, r := range after.Resources() {
+		prev, ok := old[describe(r)]
+		delete(old, describe(r))
+		switch {
+		case !ok:
+			change(r, "added", "", resourceYAML(r))
+		case resourceYAML(prev) != resourceYAML(r):
+			change(r, "changed", resourceYAML(prev), resourceYAML(r))
+		default:
+			resp.Unchanged++
+		}
+	}
+	// Resources the patch deleted, or renamed, in build order.
+	for _, r := range before.Resources() {
+		if _, ok := old[describe(r)]; ok {
+			change(r, "removed", resourceYAML(r), "")
+		}
+	}
+	if resp.Patched = len(resp.Changes); resp.Patched == 0 {
+		return PatchResponse{}, unprocessable("the patch changes no resource of %s", cmp.Or(req.Overlay, "."))
+	}
+	resp.RetriedTimes = kube.Retried(ctx)
+	return resp, nil
+}
+
+// withPatch returns t with p added to the patches of the kustomization in
+// dir, as the last of them.
+func withPatch(t memTree, dir string, p types.Patch) (memTree, error) {
+	k, file, err := t.loadKustomization(dir)
+	if err != nil {
+		return nil, err
+	}
+	k.Patches = append(k.Patches, p)
+	data, err := yaml.Marshal(k)
+	if err != nil {
+		return nil, toolserver.NewError(toolserver.CodeInternal, "encoding %s: %v", file, err)
+	}
+	out := maps.Clone(t)
+	out[file] = data
+	return out, nil
+}
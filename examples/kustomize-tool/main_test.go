@@ -0,0 +1,309 @@
+package kustomizetool
+
+import (
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+)
+
+// webFiles are a base, a component and a prod overlay of a web server.
+var webFiles = map[string]string{
+	"base/kustomization.yaml": `resources:
+- deployment.yaml
+- service.yaml
+configMapGenerator:
+- name: web-config
+  literals:
+  - LOG_LEVEL=info
+`,
+	"base/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.27
+        envFrom:
+        - configMapRef:
+            name: web-config
+`,
+	"base/service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  selector:
+    app: web
+  ports:
+  - port: 80
+`,
+	"components/monitoring/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1alpha1
+kind: Component
+patches:
+- target:
+    kind: Deployment
+  patch: |
+    apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      name: any
+      annotations:
+        prometheus.io/scrape: "true"
+`,
+	"overlays/prod/kustomization.yaml": `resources:
+- ../../base
+components:
+- ../../components/monitoring
+namespace: prod
+namePrefix: prod-
+commonLabels:
+  env: prod
+images:
+- name: nginx
+  newTag: 1.27.3
+replicas:
+- name: web
+  count: 3
+patches:
+- path: resources.yaml
+configMapGenerator:
+- name: web-config
+  behavior: merge
+  literals:
+  - LOG_LEVEL=warn
+`,
+	"overlays/prod/resources.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        resources:
+          limits:
+            memory: 256Mi
+`,
+}
+
+// withFiles returns webFiles with files added or, if empty, removed.
+func withFiles(files map[string]string) map[string]string {
+	out := make(map[string]string, len(webFiles)+len(files))
+	for k, v := range webFiles {
+		out[k] = v
+	}
+	for k, v := range files {
+		if v == "" {
+			delete(out, k)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func newTestServer(t *testing.T) *tooltest.Server {
+	data := make(map[string]string)
+	for name, content := range webFiles {
+		if rest, ok := strings.CutPrefix(name, "base/"); ok {
+			data["web__"+rest] = content
+		}
+	}
+	tooltest.FakeKube(t, kubeClient, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "manifests", Namespace: "deploy"},
+		Data:       data,
+	})
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tooltest.NewServer(t, s)
+}
+
+func TestHandlers(t *testing.T) {
+	patchCheck := func(want string, patched, changed int) func(*testing.T, *tooltest.Response) {
+		return func(t *testing.T, resp *tooltest.Response) {
+			var out PatchResponse
+			resp.Decode(&out)
+			if out.Patched != patched || len(out.Changes) != changed || changed > 0 && !strings.Contains(out.Changes[0].Diff, want) {
+				t.Errorf("patch = %+v, want %d patched, %d changed with %q", out, patched, changed, want)
+			}
+		}
+	}
+
+	tooltest.Run(t, newTestServer(t), []tooltest.Case{
+		{Name: "build overlay", Path: "/build", Body: BuildRequest{Source: Source{Files: webFiles}, Overlay: "overlays/prod"}, Golden: "build-prod"},
+		{Name: "build base", Path: "/build", Body: BuildRequest{Source: Source{Files: webFiles}, Overlay: "base"}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out BuildResponse
+			resp.Decode(&out)
+			if out.Count != 3 || out.Resources[0].Kind != "ConfigMap" || !strings.Contains(out.Manifest, "name: "+out.Resources[0].Name+"\n") {
+				t.Errorf("build = %+v, want the hashed ConfigMap referenced by the Deployment", out)
+			}
+		}},
+		{Name: "build configmap source", Path: "/build", Body: `{"configMap":{"namespace":"deploy","name":"manifests"},"overlay":"web"}`, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out BuildResponse
+			resp.Decode(&out)
+			if out.Count != 3 || out.Resources[2].Kind != "Deployment" {
+				t.Errorf("build = %+v", out)
+			}
+		}},
+		{Name: "missing configmap", Path: "/build", Body: `{"configMap":{"name":"manifests"}}`, Code: toolserver.CodeNotFound},
+		{Name: "overlays", Path: "/overlays", Body: OverlaysRequest{Source: Source{Files: withFiles(map[string]string{
+			"broken/kustomization.yaml": "resources:\n- deployment.yaml\nfrobnicate: true\n",
+		})}}, Golden: "overlays"},
+		{Name: "patch preview", Path: "/patch", Body: PatchRequest{Source: Source{Files: webFiles}, Overlay: "overlays/prod", Patch: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        env:
+        - name: FEATURE_X
+          value: "on"
+`}, Golden: "patch"},
+		{Name: "json patch preview", Path: "/patch", Body: PatchRequest{Source: Source{Files: webFiles}, Overlay: "overlays/prod",
+			Patch: `[{"op": "replace", "path": "/spec/ports/0/port", "value": 8080}]`, Target: &types.Selector{ResId: resid.ResId{Gvk: resid.Gvk{Kind: "Service"}}}},
+			Check: patchCheck("+  - port: 8080", 1, 1)},
+		{Name: "delete patch preview", Path: "/patch", Body: PatchRequest{Source: Source{Files: webFiles}, Overlay: "base",
+			Patch: "$patch: delete\napiVersion: v1\nkind: Service\nmetadata:\n  name: web\n"},
+			Check: patchCheck("-kind: Service", 1, 1)},
+		{Name: "patch matching nothing", Path: "/patch", Body: PatchRequest{Source: Source{Files: webFiles}, Patch: `{"spec":{"replicas":2}}`, Target: &types.Selector{ResId: resid.ResId{Gvk: resid.Gvk{Kind: "StatefulSet"}}}, Overlay: "base"},
+			Code: toolserver.CodeUnprocessable},
+		{Name: "patch of missing resource", Path: "/patch", Body: PatchRequest{Source: Source{Files: webFiles}, Patch: "kind: Deployment\nmetadata:\n  name: api\n", Overlay: "base"},
+			Code: toolserver.CodeUnprocessable},
+		{Name: "without patch", Path: "/patch", Body: PatchRequest{Source: Source{Files: webFiles}}, Code: toolserver.CodeInvalidArgument},
+		{Name: "missing overlay", Path: "/build", Body: BuildRequest{Source: Source{Files: webFiles}, Overlay: "overlays/staging"}, Code: toolserver.CodeNotFound},
+		{Name: "overlay outside source", Path: "/build", Body: BuildRequest{Source: Source{Files: webFiles}, Overlay: "../prod"}, Code: toolserver.CodeInvalidArgument},
+		{Name: "directory without kustomization", Path: "/build", Body: BuildRequest{Source: Source{Files: webFiles}, Overlay: "overlays"}, Code: toolserver.CodeNotFound},
+		{Name: "without source", Path: "/build", Body: `{"overlay":"base"}`, Code: toolserver.CodeInvalidArgument},
+		{Name: "two sources", Path: "/build", Body: BuildRequest{Source: Source{Files: webFiles, Git: "https://github.com/org/repo"}}, Code: toolserver.CodeInvalidArgument},
+		{Name: "helm chart", Path: "/build", Body: BuildRequest{Source: Source{Files: withFiles(map[string]string{
+			"base/kustomization.yaml": "helmCharts:\n- name: redis\n  repo: https://charts.example.com\n",
+		})}, Overlay: "base"}, Code: toolserver.CodeUnprocessable},
+		{Name: "cycle", Path: "/build", Body: BuildRequest{Source: Source{Files: withFiles(map[string]string{
+			"base/kustomization.yaml": "resources:\n- ../overlays/prod\n",
+		})}, Overlay: "overlays/prod"}, Code: toolserver.CodeUnprocessable},
+		{Name: "remote resource", Path: "/build", Body: BuildRequest{Source: Source{Files: withFiles(map[string]string{
+			"base/kustomization.yaml": "resources:\n- https://github.com/org/repo//deploy?ref=v1\n",
+		})}, Overlay: "base"}, Code: toolserver.CodeUnprocessable},
+		{Name: "resource outside source", Path: "/build", Body: BuildRequest{Source: Source{Files: withFiles(map[string]string{
+			"base/kustomization.yaml": "resources:\n- ../../etc/passwd\n",
+		})}, Overlay: "base"}, Code: toolserver.CodeUnprocessable},
+		{Name: "missing resource", Path: "/build", Body: BuildRequest{Source: Source{Files: withFiles(map[string]string{
+			"base/service.yaml": "",
+		})}, Overlay: "base"}, Code: toolserver.CodeUnprocessable},
+		{Name: "component as resource", Path: "/build", Body: BuildRequest{Source: Source{Files: withFiles(map[string]string{
+			"base/kustomization.yaml": "resources:\n- ../components/monitoring\n",
+		})}, Overlay: "base"}, Code: toolserver.CodeUnprocessable},
+	})
+}
+
+// TestGitSource builds an overlay of a repository served by git
+// http-backend.
+func TestGitSource(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	repo := filepath.Join(root, "web.git")
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "HOME="+root)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	if err := os.MkdirAll(repo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "-q", "-b", "main")
+	for name, content := range webFiles {
+		p := filepath.Join(repo, "deploy", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "web")
+	git("tag", "v1.0.0")
+	commit := git("rev-parse", "HEAD")
+
+	server := httptest.NewServer(&cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	t.Cleanup(server.Close)
+
+	tooltest.Run(t, newTestServer(t), []tooltest.Case{
+		{Name: "build tag", Path: "/build", Body: BuildRequest{Source: Source{Git: server.URL + "/web.git//deploy?ref=v1.0.0"}, Overlay: "overlays/prod"}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out BuildResponse
+			resp.Decode(&out)
+			if out.Revision != commit || out.Count != 3 || out.Resources[2].Name != "prod-web" {
+				t.Errorf("build = %+v, want prod-web at %s", out, commit)
+			}
+		}},
+		{Name: "overlays of default branch", Path: "/overlays", Body: OverlaysRequest{Source: Source{Git: server.URL + "/web.git/deploy"}}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out OverlaysResponse
+			resp.Decode(&out)
+			if out.Count != 3 || out.Overlays[2].Path != "overlays/prod" || out.Overlays[2].Bases[0] != "base" {
+				t.Errorf("overlays = %+v", out)
+			}
+		}},
+		{Name: "missing ref", Path: "/build", Body: BuildRequest{Source: Source{Git: server.URL + "/web.git//deploy?ref=v9"}}, Code: toolserver.CodeNotFound},
+		{Name: "missing repository", Path: "/build", Body: BuildRequest{Source: Source{Git: server.URL + "/api.git"}}, Code: toolserver.CodeNotFound},
+		{Name: "ssh", Path: "/build", Body: BuildRequest{Source: Source{Git: "ssh://git@github.com/org/repo"}}, Code: toolserver.CodeInvalidArgument},
+		{Name: "option as ref", Path: "/build", Body: BuildRequest{Source: Source{Git: server.URL + "/web.git?ref=--upload-pack=touch"}}, Code: toolserver.CodeInvalidArgument},
+	})
+}
+
+func TestParseGitSource(t *testing.T) {
+	for _, tc := range []struct{ in, repo, dir, ref string }{
+		{"github.com/org/repo//deploy/overlays?ref=v1.2.0", "https://github.com/org/repo", "deploy/overlays", "v1.2.0"},
+		{"https://github.com/org/repo.git/deploy?version=main", "https://github.com/org/repo.git", "deploy", "main"},
+		{"https://git.example.com/org/repo", "https://git.example.com/org/repo", ".", ""},
+	} {
+		src, err := parseGitSource(tc.in)
+		if err != nil {
+			t.Errorf("parseGitSource(%s): %v", tc.in, err)
+			continue
+		}
+		if src.repo.String() != tc.repo || src.dir != tc.dir || src.ref != tc.ref {
+			t.Errorf("parseGitSource(%s) = %s %s %s, want %s %s %s", tc.in, src.repo, src.dir, src.ref, tc.repo, tc.dir, tc.ref)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package kustomizetool
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// maxFileSize bounds a file read from a git source; larger ones are
+	// left out.
+	maxFileSize = 4 << 20
+
+	// maxSourceBytes bounds the files read from a git source in all.
+	maxSourceBytes = 32 << 20
+
+	// configMapPathSeparator stands for "/" in the keys of a ConfigMap
+	// source, which cannot hold slashes, e.g. overlays__prod__kustomization.yaml.
+	configMapPathSeparator = "__"
+)
+
+// Source is where a kustomization is read from: exactly one of files,
+// configMap and git.
+type Source struct {
+	Files     map[string]string `json:"files"`     // inline, by path, e.g. {"kustomization.yaml": "...", "base/deployment.yaml": "..."}
+	ConfigMap *ConfigMapSource  `json:"configMap"` // a ConfigMap whose keys are the files, with "__" for "/" in their paths
+	Git       string            `json:"git"`       // a repository as kustomize takes it, e.g. https://github.com/org/repo//deploy?ref=v1.2.0
+}
+
+type ConfigMapSource struct {
+	Namespace string `json:"namespace"` // defaults to "default"
+	Name      string `json:"name"`
+}
+
+// openedSource is a source's files, with the directory its kustomizations
+// are relative to and, for git, the commit read.
+type openedSource struct {
+	tree     memTree
+	root     string // e.g. deploy for https://github.com/org/repo//deploy
+	revision string
+}
+
+// open reads the source.
+func (s Source) open(ctx context.Context) (*openedSource, error) {
+	set := 0
+	for _, ok := range []bool{s.Files != nil, s.ConfigMap != nil, s.Git != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, toolserver.BadRequest("exactly one of files, configMap and git is required")
+	}
+	switch {
+	case s.Files != nil:
+		files := make(memTree, len(s.Files))
+		for name, data := range s.Files {
+			clean, err := cleanPath(name)
+			if err != nil {
+				return nil, toolserver.BadRequest("files: %v", err)
+			}
+			files[clean] = []byte(data)
+		}
+		return &openedSource{tree: files, root: "."}, nil
+	case s.ConfigMap != nil:
+		files, err := configMapTree(ctx, s.ConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		return &openedSource{tree: files, root: "."}, nil
+	}
+	return fetchGit(ctx, s.Git)
+}
+
+// configMapTree reads the files of a ConfigMap source.
+func configMapTree(ctx context.Context, src *ConfigMapSource) (memTree, error) {
+	if src.Name == "" {
+		return nil, toolserver.BadRequest("configMap.name is required")
+	}
+	namespace := src.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	// The handler's context counts the retries; the git fetch has its own,
+	// longer, deadline.
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	cm, err := kube.Call(ctx, kubeClient, func(ctx context.Context, clientset kubernetes.Interface) (*corev1.ConfigMap, error) {
+		return clientset.CoreV1().ConfigMaps(namespace).Get(ctx, src.Name, metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, kube.Error(err)
+	}
+	files := make(memTree, len(cm.Data)+len(cm.BinaryData))
+	add := func(key string, data []byte) error {
+		name, err := cleanPath(strings.ReplaceAll(key, configMapPathSeparator, "/"))
+		if err != nil {
+			return toolserver.NewError(toolserver.CodeUnprocessable, "configmap %s/%s: key %s: %v", namespace, src.Name, key, err)
+		}
+		files[name] = data
+		return nil
+	}
+	for key, data := range cm.Data {
+		if err := add(key, []byte(data)); err != nil {
+			return nil, err
+		}
+	}
+	for key, data := range cm.BinaryData {
+		if err := add(key, data); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// cleanPath returns the path of a file or directory in a source, relative
+// to its root, failing if it leaves it.
+func cleanPath(name string) (string, error) {
+	clean := path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%s is outside the source", name)
+	}
+	return clean, nil
+}
+
+// memTree is the files of a source, by slash-separated path from its root.
+type memTree map[string][]byte
+
+// isDir reports whether name is a directory of the tree, one holding a
+// file.
+func (t memTree) isDir(name string) bool {
+	if name == "." {
+		return true
+	}
+	for file := range t {
+		if strings.HasPrefix(file, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// readTree reads the files of fsys, such as a git checkout, leaving out
+// .git and files over maxFileSize, which no kustomization reads. It fails
+// with PAYLOAD_TOO_LARGE past maxSourceBytes.
+func readTree(fsys fs.FS) (memTree, error) {
+	t := make(memTree)
+	var size int64
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case d.IsDir() && name == ".git":
+			return fs.SkipDir
+		case !d.Type().IsRegular():
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > maxFileSize {
+			return nil
+		}
+		if size += info.Size(); size > maxSourceBytes {
+			return toolserver.NewError(toolserver.CodeTooLarge, "the source is over %d MiB", maxSourceBytes>>20)
+		}
+		t[name], err = fs.ReadFile(fsys, name)
+		return err
+	})
+	return t, err
+}
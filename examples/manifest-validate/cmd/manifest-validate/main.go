@@ -0,0 +1,22 @@
+// Command manifest-validate serves manifest-validate on its own. kube-mcp-tools serves it
+// together with the other example tools.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	manifestvalidate "manifest-validate"
+)
+
+func main() {
+	s, err := manifestvalidate.New()
+	if err != nil {
+		slog.Error("starting manifest-validate", "err", err)
+		os.Exit(1)
+	}
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,73 @@
+package manifestvalidate
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// deprecation is a deprecated API version of a kind: the Kubernetes minor
+// version it was deprecated in, the one it was, or will be, removed in,
+// and the apiVersion to use instead.
+type deprecation struct {
+	deprecated, removed string
+	replacement         string
+}
+
+// deprecations are the built-in kinds whose API versions were deprecated
+// since Kubernetes 1.16, from the deprecated API migration guide.
+var deprecations = map[schema.GroupVersionKind]deprecation{
+	// Removed in 1.16.
+	{Group: "extensions", Version: "v1beta1", Kind: "Deployment"}:        {"1.9", "1.16", "apps/v1"},
+	{Group: "extensions", Version: "v1beta1", Kind: "DaemonSet"}:         {"1.9", "1.16", "apps/v1"},
+	{Group: "extensions", Version: "v1beta1", Kind: "ReplicaSet"}:        {"1.9", "1.16", "apps/v1"},
+	{Group: "extensions", Version: "v1beta1", Kind: "NetworkPolicy"}:     {"1.9", "1.16", "networking.k8s.io/v1"},
+	{Group: "extensions", Version: "v1beta1", Kind: "PodSecurityPolicy"}: {"1.10", "1.16", "policy/v1beta1"},
+	{Group: "apps", Version: "v1beta1", Kind: "Deployment"}:              {"1.9", "1.16", "apps/v1"},
+	{Group: "apps", Version: "v1beta1", Kind: "StatefulSet"}:             {"1.9", "1.16", "apps/v1"},
+	{Group: "apps", Version: "v1beta2", Kind: "Deployment"}:              {"1.9", "1.16", "apps/v1"},
+	{Group: "apps", Version: "v1beta2", Kind: "DaemonSet"}:               {"1.9", "1.16", "apps/v1"},
+	{Group: "apps", Version: "v1beta2", Kind: "ReplicaSet"}:              {"1.9", "1.16", "apps/v1"},
+	{Group: "apps", Version: "v1beta2", Kind: "StatefulSet"}:             {"1.9", "1.16", "apps/v1"},
+
+	// Removed in 1.22.
+	{Group: "extensions", Version: "v1beta1", Kind: "Ingress"}:                                          {"1.14", "1.22", "networking.k8s.io/v1"},
+	{Group: "networking.k8s.io", Version: "v1beta1", Kind: "Ingress"}:                                   {"1.19", "1.22", "networking.k8s.io/v1"},
+	{Group: "networking.k8s.io", Version: "v1beta1", Kind: "IngressClass"}:                              {"1.19", "1.22", "networking.k8s.io/v1"},
+	{Group: "admissionregistration.k8s.io", Version: "v1beta1", Kind: "MutatingWebhookConfiguration"}:   {"1.16", "1.22", "admissionregistration.k8s.io/v1"},
+	{Group: "admissionregistration.k8s.io", Version: "v1beta1", Kind: "ValidatingWebhookConfiguration"}: {"1.16", "1.22", "admissionregistration.k8s.io/v1"},
+	{Group: "apiextensions.k8s.io", Version: "v1beta1", Kind: "CustomResourceDefinition"}:               {"1.16", "1.22", "apiextensions.k8s.io/v1"},
+	{Group: "apiregistration.k8s.io", Version: "v1beta1", Kind: "APIService"}:                           {"1.19", "1.22", "apiregistration.k8s.io/v1"},
+	{Group: "authentication.k8s.io", Version: "v1beta1", Kind: "TokenReview"}:                           {"1.19", "1.22", "authentication.k8s.io/v1"},
+	{Group: "authorization.k8s.io", Version: "v1beta1", Kind: "SubjectAccessReview"}:                    {"1.19", "1.22", "authorization.k8s.io/v1"},
+	{Group: "certificates.k8s.io", Version: "v1beta1", Kind: "CertificateSigningRequest"}:               {"1.19", "1.22", "certificates.k8s.io/v1"},
+	{Group: "coordination.k8s.io", Version: "v1beta1", Kind: "Lease"}:                                   {"1.19", "1.22", "coordination.k8s.io/v1"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "ClusterRole"}:                       {"1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "ClusterRoleBinding"}:                {"1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "Role"}:                              {"1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "RoleBinding"}:                       {"1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{Group: "scheduling.k8s.io", Version: "v1beta1", Kind: "PriorityClass"}:                             {"1.14", "1.22", "scheduling.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "CSIDriver"}:                                    {"1.19", "1.22", "storage.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "CSINode"}:                                      {"1.17", "1.22", "storage.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "StorageClass"}:                                 {"1.19", "1.22", "storage.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "VolumeAttachment"}:                             {"1.19", "1.22", "storage.k8s.io/v1"},
+
+	// Removed in 1.25.
+	{Group: "batch", Version: "v1beta1", Kind: "CronJob"}:                       {"1.21", "1.25", "batch/v1"},
+	{Group: "discovery.k8s.io", Version: "v1beta1", Kind: "EndpointSlice"}:      {"1.21", "1.25", "discovery.k8s.io/v1"},
+	{Group: "events.k8s.io", Version: "v1beta1", Kind: "Event"}:                 {"1.19", "1.25", "events.k8s.io/v1"},
+	{Group: "autoscaling", Version: "v2beta1", Kind: "HorizontalPodAutoscaler"}: {"1.22", "1.25", "autoscaling/v2"},
+	{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"}:          {"1.21", "1.25", "policy/v1"},
+	{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"}:            {"1.21", "1.25", ""},
+	{Group: "node.k8s.io", Version: "v1beta1", Kind: "RuntimeClass"}:            {"1.20", "1.25", "node.k8s.io/v1"},
+
+	// Removed in 1.26, 1.27 and 1.29.
+	{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler"}:                     {"1.23", "1.26", "autoscaling/v2"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta1", Kind: "FlowSchema"}:                 {"1.23", "1.26", "flowcontrol.apiserver.k8s.io/v1"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta1", Kind: "PriorityLevelConfiguration"}: {"1.23", "1.26", "flowcontrol.apiserver.k8s.io/v1"},
+	{Group: "storage.k8s.io", Version: "v1beta1", Kind: "CSIStorageCapacity"}:                       {"1.24", "1.27", "storage.k8s.io/v1"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta2", Kind: "FlowSchema"}:                 {"1.26", "1.29", "flowcontrol.apiserver.k8s.io/v1"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta2", Kind: "PriorityLevelConfiguration"}: {"1.26", "1.29", "flowcontrol.apiserver.k8s.io/v1"},
+
+	// Removed in 1.32.
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta3", Kind: "FlowSchema"}:                 {"1.29", "1.32", "flowcontrol.apiserver.k8s.io/v1"},
+	{Group: "flowcontrol.apiserver.k8s.io", Version: "v1beta3", Kind: "PriorityLevelConfiguration"}: {"1.29", "1.32", "flowcontrol.apiserver.k8s.io/v1"},
+}
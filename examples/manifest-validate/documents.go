@@ -0,0 +1,160 @@
+package manifestvalidate
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// document is a YAML or JSON document of a manifest, or an item of a List
+// in one.
+type document struct {
+	index int            // in the manifest, from 0, counting List items
+	line  int            // where it starts, from 1
+	obj   map[string]any // as the API server decodes it: timestamps are strings and numbers float64
+	lines map[string]int // the line of each field, by its path, e.g. .spec.containers[0].image
+	err   error          // why it could not be parsed
+}
+
+// lineOf returns the line of the field at path or, if the document does
+// not have it, of its nearest parent that it has.
+func (d *document) lineOf(path string) int {
+	for {
+		if line, ok := d.lines[path]; ok {
+			return line
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			return d.line
+		}
+		path = path[:i]
+	}
+}
+
+// splitDocuments splits a manifest into its documents, at lines starting
+// with ---, so that one that cannot be parsed does not hide the others.
+// Empty documents, such as one after a trailing separator, are dropped.
+func splitDocuments(manifest string) []*document {
+	var docs []*document
+	var chunk strings.Builder
+	start, n := 1, 0
+	flush := func() {
+		if text := chunk.String(); strings.TrimSpace(stripComments(text)) != "" {
+			docs = append(docs, parseDocument(text, start)...)
+		}
+		chunk.Reset()
+	}
+	scanner := bufio.NewScanner(strings.NewReader(manifest))
+	scanner.Buffer(nil, len(manifest)+1)
+	for scanner.Scan() {
+		n++
+		line := scanner.Text()
+		if line == "---" || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "---\t") {
+			flush()
+			start = n + 1
+			continue
+		}
+		chunk.WriteString(line)
+		chunk.WriteByte('\n')
+	}
+	flush()
+	for i, d := range docs {
+		d.index = i
+	}
+	return docs
+}
+
+// stripComments drops the comment lines of a document, to tell one with
+// nothing but comments.
+func stripComments(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// yamlLine matches the line numbers of YAML parse errors, which count from
+// the document's start.
+var yamlLine = regexp.MustCompile(`line (\d+)`)
+
+// parseDocument parses the text of a document starting at line start. A
+// List is returned as its items.
+func parseDocument(text string, start int) []*document {
+	d := &document{line: start, lines: make(map[string]int)}
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &root); err != nil {
+		msg := yamlLine.ReplaceAllStringFunc(err.Error(), func(s string) string {
+			n, _ := strconv.Atoi(strings.TrimPrefix(s, "line "))
+			return fmt.Sprintf("line %d", n+start-1)
+		})
+		d.err = fmt.Errorf("%s", strings.TrimPrefix(msg, "yaml: "))
+		return []*document{d}
+	}
+	if err := sigsyaml.Unmarshal([]byte(text), &d.obj); err != nil {
+		d.err = err
+		return []*document{d}
+	}
+	if d.obj == nil {
+		d.err = fmt.Errorf("the document is not an object")
+		return []*document{d}
+	}
+	if len(root.Content) > 0 {
+		recordLines(root.Content[0], "", start-1, d.lines)
+	}
+
+	// Lists, as kubectl get -o yaml prints, are validated item by item.
+	items, ok := d.obj["items"].([]any)
+	if kind, _ := d.obj["kind"].(string); !ok || !strings.HasSuffix(kind, "List") {
+		return []*document{d}
+	}
+	var docs []*document
+	for i, item := range items {
+		prefix := fmt.Sprintf(".items[%d]", i)
+		obj, _ := item.(map[string]any)
+		item := &document{line: d.lineOf(prefix), obj: obj, lines: make(map[string]int)}
+		for path, line := range d.lines {
+			if rest, ok := strings.CutPrefix(path, prefix); ok && (rest == "" || rest[0] == '.' || rest[0] == '[') {
+				item.lines[rest] = line
+			}
+		}
+		if obj == nil {
+			item.err = fmt.Errorf("item %d of the %s is not an object", i, d.obj["kind"])
+		}
+		docs = append(docs, item)
+	}
+	return docs
+}
+
+// recordLines records in lines the line of node and of each field under
+// it, by path, adding offset to the lines of the node's document.
+func recordLines(node *yaml.Node, path string, offset int, lines map[string]int) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	lines[path] = node.Line + offset
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				continue // a merge key's fields are where its anchor is
+			}
+			field := path + "." + key.Value
+			recordLines(value, field, offset, lines)
+			lines[field] = key.Line + offset
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			recordLines(item, fmt.Sprintf("%s[%d]", path, i), offset, lines)
+		}
+	}
+}
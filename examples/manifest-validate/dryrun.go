@@ -0,0 +1,102 @@
+package manifestvalidate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// fieldManager is the field manager of the dry runs' server-side applies.
+const fieldManager = "manifest-validate"
+
+// dryRunner dry-runs documents with the server-side apply of kubectl apply
+// --server-side --dry-run=server, so the API server's own validation and
+// its admission webhooks see them, but nothing is changed.
+type dryRunner struct {
+	dynamic   dynamic.Interface
+	mapper    meta.RESTMapper
+	namespace string // of documents without one
+}
+
+// newDryRunner maps kinds to resources with the cluster's discovery
+// information.
+func newDryRunner(ctx context.Context, namespace string) (*dryRunner, error) {
+	clientset, err := kubeClient.Clientset()
+	if err != nil {
+		return nil, err
+	}
+	dyn, err := kubeClient.Dynamic()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	groups, err := kube.Retry(ctx, func(ctx context.Context) ([]*restmapper.APIGroupResources, error) {
+		return restmapper.GetAPIGroupResources(clientset.Discovery())
+	})
+	if err != nil {
+		return nil, kube.Error(err)
+	}
+	return &dryRunner{dynamic: dyn, mapper: restmapper.NewDiscoveryRESTMapper(groups), namespace: cmp.Or(namespace, "default")}, nil
+}
+
+// run dry-runs d, returning the issues the API server found with it. It
+// fails only if the API server could not be asked.
+func (r *dryRunner) run(ctx context.Context, d *document, gvk schema.GroupVersionKind) ([]Issue, error) {
+	mapping, err := r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return []Issue{{Severity: "error", Check: "dry-run", Line: d.line, Message: fmt.Sprintf("the cluster has no resource for %s: %v", gvk, err)}}, nil
+	}
+	obj := &unstructured.Unstructured{Object: d.obj}
+	var resource dynamic.ResourceInterface = r.dynamic.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		resource = r.dynamic.Resource(mapping.Resource).Namespace(cmp.Or(obj.GetNamespace(), r.namespace))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	_, err = kube.Retry(ctx, func(ctx context.Context) (*unstructured.Unstructured, error) {
+		if obj.GetName() == "" {
+			// Only created objects may have their name generated.
+			return resource.Create(ctx, obj, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}, FieldManager: fieldManager})
+		}
+		return resource.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{DryRun: []string{metav1.DryRunAll}, FieldManager: fieldManager, Force: true})
+	})
+	if err == nil {
+		return nil, nil
+	}
+
+	var status apierrors.APIStatus
+	switch {
+	case apierrors.IsForbidden(err):
+		return []Issue{{Severity: "warning", Check: "dry-run", Line: d.line, Message: fmt.Sprintf("not dry-run: the tool may not apply it: %v", err)}}, nil
+	case !errors.As(err, &status) || status.Status().Code >= 500 || apierrors.IsTooManyRequests(err) || apierrors.IsTimeout(err):
+		return nil, kube.Error(err)
+	}
+	var causes []metav1.StatusCause
+	if details := status.Status().Details; details != nil {
+		causes = details.Causes
+	}
+	if len(causes) == 0 {
+		return []Issue{{Severity: "error", Check: "dry-run", Line: d.line, Message: status.Status().Message}}, nil
+	}
+	issues := make([]Issue, 0, len(causes))
+	for _, c := range causes {
+		issue := Issue{Severity: "error", Check: "dry-run", Field: c.Field, Line: d.line, Message: c.Message}
+		if c.Field != "" {
+			issue.Line = d.lineOf("." + c.Field)
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
@@ -0,0 +1,25 @@
+package manifestvalidate
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/atippey/kube-mcp/pkg/kube"
+)
+
+// kubeClient is loaded on first use. Until it can be, /validate fails with
+// 503 and /readyz reports why.
+var kubeClient = kube.New("manifest-validate")
+
+// apiTimeout is the deadline of each document's dry run, retries included,
+// overridable with $KUBE_TIMEOUT (see package config; package kube has the
+// rate limit and retry settings).
+var apiTimeout = 10 * time.Second
+
+// configureAPICalls applies the timeout setting to the package-level
+// default.
+func configureAPICalls() {
+	apiTimeout = config.Duration("KUBE_TIMEOUT", apiTimeout)
+	slog.Info("kubernetes API calls", "timeout", apiTimeout.String())
+}
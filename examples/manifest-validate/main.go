@@ -0,0 +1,268 @@
+// Package manifestvalidate implements manifest-validate, which checks
+// manifests against the cluster's OpenAPI schema and, optionally, with a
+// server-side dry run, before they are applied.
+package manifestvalidate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/util/proto/validation"
+)
+
+// validateTimeout bounds a request, which may dry-run every document of a
+// manifest in turn.
+const validateTimeout = 2 * time.Minute
+
+type ValidateRequest struct {
+	Manifest  string `json:"manifest"`  // YAML documents separated by ---, or JSON
+	DryRun    bool   `json:"dryRun"`    // also dry-run the documents on the API server
+	Namespace string `json:"namespace"` // of namespaced documents without one, for the dry run; defaults to "default"
+}
+
+// Issue is a problem found with a document. Errors make it invalid;
+// warnings, such as a deprecated apiVersion, do not.
+type Issue struct {
+	Severity string `json:"severity"`        // error or warning
+	Check    string `json:"check"`           // parse, schema, unknown-field, required, type, deprecated or dry-run
+	Field    string `json:"field,omitempty"` // e.g. spec.template.spec.containers[0].image
+	Line     int    `json:"line,omitempty"`  // in the manifest, of the field or, if it is missing, of its parent
+	Message  string `json:"message"`
+}
+
+type Document struct {
+	Index      int     `json:"index"` // from 0, counting the items of Lists
+	Line       int     `json:"line"`  // where it starts in the manifest
+	APIVersion string  `json:"apiVersion,omitempty"`
+	Kind       string  `json:"kind,omitempty"`
+	Namespace  string  `json:"namespace,omitempty"`
+	Name       string  `json:"name,omitempty"`
+	Valid      bool    `json:"valid"`
+	DryRun     string  `json:"dryRun,omitempty"` // passed, failed, or skipped for an invalid document or one the tool may not apply
+	Issues     []Issue `json:"issues"`
+}
+
+type ValidateResponse struct {
+	Valid        bool       `json:"valid"` // no document has errors
+	Documents    []Document `json:"documents"`
+	Errors       int        `json:"errors"`
+	Warnings     int        `json:"warnings"`
+	KubeVersion  string     `json:"kubeVersion,omitempty"` // of the schema validated against
+	RetriedTimes int        `json:"retriedTimes,omitempty"`
+}
+
+// ContentBlocks gives MCP clients a summary and each issue on a line of
+// its own, with the document and line it is in.
+func (r ValidateResponse) ContentBlocks() []toolserver.Content {
+	var b strings.Builder
+	valid := 0
+	for _, d := range r.Documents {
+		if d.Valid {
+			valid++
+		}
+	}
+	fmt.Fprintf(&b, "%d of %d documents valid against the Kubernetes %s schema: %d errors, %d warnings.",
+		valid, len(r.Documents), cmp.Or(r.KubeVersion, "cluster's"), r.Errors, r.Warnings)
+	for _, d := range r.Documents {
+		for _, issue := range d.Issues {
+			fmt.Fprintf(&b, "\n%s: document %d", issue.Severity, d.Index)
+			if d.Kind != "" {
+				fmt.Fprintf(&b, " (%s %s)", d.Kind, cmp.Or(d.Name, "without a name"))
+			}
+			if issue.Line > 0 {
+				fmt.Fprintf(&b, ", line %d", issue.Line)
+			}
+			if issue.Field != "" {
+				fmt.Fprintf(&b, ", %s", issue.Field)
+			}
+			fmt.Fprintf(&b, ": %s", issue.Message)
+		}
+	}
+	return []toolserver.Content{toolserver.TextContent(b.String())}
+}
+
+// New returns the manifest-validate server. It needs the cluster for its
+// schema; the dry run also needs permission to apply each kind validated,
+// which it cannot declare up front, so a document it may not apply gets a
+// warning rather than the tool being marked unavailable.
+func New() (*toolserver.Server, error) {
+	configureAPICalls()
+
+	s := toolserver.New("manifest-validate")
+	s.AddReadinessCheck("kubernetes", kubeClient.Ready)
+	s.AddDiagnostics("kubernetes", schemaDiagnostics)
+	s.AddWarmup("openapi", warmSchema)
+	toolserver.Register(s, "/validate", validate,
+		toolserver.Name("manifest-validate"), toolserver.Describe("Validate Kubernetes manifests against the cluster's OpenAPI schema, flag unknown fields and deprecated apiVersions, and optionally dry-run them on the API server, with the line of each problem."),
+		toolserver.Timeout(validateTimeout), toolserver.Sensitive("manifest"))
+
+	return s, nil
+}
+
+func validate(ctx context.Context, req ValidateRequest) (ValidateResponse, error) {
+	if strings.TrimSpace(req.Manifest) == "" {
+		return ValidateResponse{}, toolserver.BadRequest("manifest is required")
+	}
+	docs := splitDocuments(req.Manifest)
+	if len(docs) == 0 {
+		return ValidateResponse{}, toolserver.BadRequest("manifest has no documents")
+	}
+	ctx = kube.CountRetries(ctx)
+	s, err := loadSchema(ctx)
+	if err != nil {
+		return ValidateResponse{}, err
+	}
+
+	resp := ValidateResponse{Valid: true, Documents: make([]Document, 0, len(docs)), KubeVersion: s.version}
+	var runner *dryRunner
+	for _, d := range docs {
+		doc, gvk := check(s, d)
+		if req.DryRun {
+			switch {
+			case !doc.Valid:
+				doc.DryRun = "skipped"
+			default:
+				if runner == nil {
+					if runner, err = newDryRunner(ctx, req.Namespace); err != nil {
+						return ValidateResponse{}, err
+					}
+				}
+				issues, err := runner.run(ctx, d, gvk)
+				if err != nil {
+					return ValidateResponse{}, err
+				}
+				doc.DryRun = "passed"
+				for _, issue := range issues {
+					if issue.Severity == "error" {
+						doc.Valid, doc.DryRun = false, "failed"
+					} else if doc.DryRun == "passed" {
+						doc.DryRun = "skipped"
+					}
+				}
+				doc.Issues = append(doc.Issues, issues...)
+			}
+		}
+		slices.SortStableFunc(doc.Issues, func(a, b Issue) int { return cmp.Compare(a.Line, b.Line) })
+		for _, issue := range doc.Issues {
+			if issue.Severity == "error" {
+				resp.Errors++
+			} else {
+				resp.Warnings++
+			}
+		}
+		resp.Valid = resp.Valid && doc.Valid
+		resp.Documents = append(resp.Documents, doc)
+	}
+	resp.RetriedTimes = kube.Retried(ctx)
+	return resp, nil
+}
+
+// check validates a document against the schema, returning the result and
+// its kind.
+func check(s *clusterSchema, d *document) (Document, schema.GroupVersionKind) {
+	doc := Document{Index: d.index, Line: d.line, Issues: []Issue{}}
+	fail := func(check, field, format string, args ...any) {
+		line := d.line
+		if field != "" {
+			line = d.lineOf("." + field)
+		}
+		doc.Issues = append(doc.Issues, Issue{Severity: "error", Check: check, Field: field, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+	if d.err != nil {
+		fail("parse", "", "%v", d.err)
+		return doc, schema.GroupVersionKind{}
+	}
+
+	doc.APIVersion, _ = d.obj["apiVersion"].(string)
+	doc.Kind, _ = d.obj["kind"].(string)
+	meta, _ := d.obj["metadata"].(map[string]any)
+	doc.Namespace, _ = meta["namespace"].(string)
+	doc.Name, _ = meta["name"].(string)
+	if doc.APIVersion == "" {
+		fail("required", "apiVersion", "apiVersion is required")
+	}
+	if doc.Kind == "" {
+		fail("required", "kind", "kind is required")
+	}
+	if generateName, _ := meta["generateName"].(string); doc.Name == "" && generateName == "" {
+		fail("required", "metadata.name", "metadata.name or metadata.generateName is required")
+	}
+	if doc.APIVersion == "" || doc.Kind == "" {
+		return doc, schema.GroupVersionKind{}
+	}
+
+	gvk := schema.FromAPIVersionAndKind(doc.APIVersion, doc.Kind)
+	dep, deprecated := deprecations[gvk]
+	model := s.lookup(gvk)
+	switch {
+	case model == nil && deprecated:
+		fail("deprecated", "apiVersion", "%s %s was removed in Kubernetes %s%s", doc.APIVersion, doc.Kind, dep.removed, dep.use())
+	case model == nil:
+		if versions := s.servedVersions(gvk.Group, gvk.Kind); len(versions) > 0 {
+			slices.Sort(versions)
+			fail("schema", "apiVersion", "the cluster does not serve %s %s; it serves %s in %s", doc.APIVersion, doc.Kind, doc.Kind, strings.Join(groupVersions(gvk.Group, versions), ", "))
+		} else {
+			fail("schema", "kind", "the cluster does not serve %s %s; if it is a custom resource, its CustomResourceDefinition is not installed", doc.APIVersion, doc.Kind)
+		}
+	default:
+		if deprecated {
+			doc.Issues = append(doc.Issues, Issue{Severity: "warning", Check: "deprecated", Field: "apiVersion", Line: d.lineOf(".apiVersion"),
+				Message: fmt.Sprintf("%s %s is deprecated since Kubernetes %s and removed in %s%s", doc.APIVersion, doc.Kind, dep.deprecated, dep.removed, dep.use())})
+		}
+		for _, err := range validation.ValidateModel(d.obj, model, "") {
+			doc.Issues = append(doc.Issues, schemaIssue(d, err))
+		}
+	}
+
+	doc.Valid = !slices.ContainsFunc(doc.Issues, func(i Issue) bool { return i.Severity == "error" })
+	return doc, gvk
+}
+
+// use says what to use instead of a deprecated apiVersion.
+func (d deprecation) use() string {
+	if d.replacement == "" {
+		return ", with no replacement"
+	}
+	return "; use " + d.replacement
+}
+
+// groupVersions returns the apiVersions of versions of group.
+func groupVersions(group string, versions []string) []string {
+	apiVersions := make([]string, len(versions))
+	for i, v := range versions {
+		apiVersions[i] = schema.GroupVersion{Group: group, Version: v}.String()
+	}
+	return apiVersions
+}
+
+// schemaIssue turns an error of the OpenAPI validation into an issue with
+// the path and line of the field it is about.
+func schemaIssue(d *document, err error) Issue {
+	issue := Issue{Severity: "error", Check: "schema", Line: d.line, Message: err.Error()}
+	var ve validation.ValidationError
+	if !errors.As(err, &ve) {
+		return issue
+	}
+	path := ve.Path
+	issue.Message = ve.Err.Error()
+	switch e := ve.Err.(type) {
+	case validation.UnknownFieldError:
+		issue.Check, path = "unknown-field", path+"."+e.Field
+	case validation.MissingRequiredFieldError:
+		issue.Check, path = "required", path+"."+e.Field
+	case validation.InvalidTypeError:
+		issue.Check = "type"
+	case validation.InvalidObjectTypeError:
+		issue.Check, path = "type", e.Path
+	}
+	issue.Field, issue.Line = strings.TrimPrefix(path, "."), d.lineOf(path)
+	return issue
+}
@@ -0,0 +1,253 @@
+package manifestvalidate
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// useTestSchema caches the schema of a Kubernetes 1.27 API server, as
+// loadSchema would fetch it, until the test ends.
+func useTestSchema(tb testing.TB) {
+	tb.Helper()
+	doc, err := openapi_v2.ParseDocument(tooltest.OpenAPIv2(tb))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	s, err := parseSchema(doc)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	schemaCache.schema, schemaCache.fetched = s, time.Now()
+	tb.Cleanup(func() { schemaCache.schema, schemaCache.fetched = nil, time.Time{} })
+}
+
+// webManifest is a Deployment with an unknown field, a container without
+// a name and a port of the wrong type, and a valid Service.
+const webManifest = `# The web server.
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replica: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - image: nginx:1.27
+        ports:
+        - containerPort: [80]
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  selector:
+    app: web
+  ports:
+  - port: 80
+    targetPort: http
+`
+
+// olderManifest has a deprecated, a removed and an unknown apiVersion, and
+// a document that is not YAML.
+const olderManifest = `apiVersion: flowcontrol.apiserver.k8s.io/v1beta2
+kind: FlowSchema
+metadata:
+  name: batch
+spec:
+  priorityLevelConfiguration:
+    name: workload-low
+---
+apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+spec:
+  minAvailable: 1
+---
+metadata: [unclosed
+---
+apiVersion: apps/v1beta1
+kind: StatefulSet
+metadata:
+  name: db
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  generateName: widget-
+`
+
+func newTestServer(t *testing.T) (*tooltest.Server, *tooltest.Kube) {
+	useTestSchema(t)
+	k := tooltest.FakeKube(t, kubeClient)
+	k.Clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}, {Name: "namespaces", Kind: "Namespace"}}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment", Namespaced: true}}},
+	}
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tooltest.NewServer(t, s), k
+}
+
+func TestHandlers(t *testing.T) {
+	s, _ := newTestServer(t)
+	tooltest.Run(t, s, []tooltest.Case{
+		{Name: "invalid", Path: "/validate", Body: ValidateRequest{Manifest: webManifest}, Golden: "invalid"},
+		{Name: "older", Path: "/validate", Body: ValidateRequest{Manifest: olderManifest}, Golden: "older"},
+		{Name: "list", Path: "/validate", Body: ValidateRequest{Manifest: `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: a
+  data:
+    key: value
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: b
+  data: value
+`}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out ValidateResponse
+			resp.Decode(&out)
+			if len(out.Documents) != 2 || !out.Documents[0].Valid || out.Documents[1].Valid {
+				t.Fatalf("documents %+v, want the items, the second invalid", out.Documents)
+			}
+			if issue := out.Documents[1].Issues[0]; issue.Field != "data" || issue.Line != 14 || issue.Check != "type" {
+				t.Errorf("issue %+v, want a type error of data on line 14", issue)
+			}
+		}},
+		{Name: "json", Path: "/validate", Body: ValidateRequest{Manifest: `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "web"}}`},
+			Check: func(t *testing.T, resp *tooltest.Response) {
+				var out ValidateResponse
+				resp.Decode(&out)
+				if !out.Valid || out.KubeVersion != "v1.27.0" {
+					t.Errorf("got %+v, want a valid document checked against v1.27.0", out)
+				}
+			}},
+		{Name: "empty", Path: "/validate", Body: ValidateRequest{Manifest: " "}, Code: toolserver.CodeInvalidArgument},
+		{Name: "only comments", Path: "/validate", Body: ValidateRequest{Manifest: "# nothing\n---\n"}, Code: toolserver.CodeInvalidArgument},
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	s, k := newTestServer(t)
+	k.Dynamic.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "web", field.ErrorList{
+			field.Invalid(field.NewPath("spec", "replicas"), -1, "must be greater than or equal to 0"),
+		})
+	})
+	var applied []string
+	k.Dynamic.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchActionImpl)
+		if patch.GetPatchType() != types.ApplyPatchType || !slices.Contains(patch.GetPatchOptions().DryRun, metav1.DryRunAll) {
+			t.Errorf("patch %s with options %+v, want a server-side apply dry run", patch.GetPatchType(), patch.GetPatchOptions())
+		}
+		applied = append(applied, patch.GetNamespace()+"/"+patch.GetName())
+		return true, &unstructured.Unstructured{}, nil
+	})
+	k.Dynamic.PrependReactor("patch", "namespaces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, "web", errors.New("no RBAC policy matched"))
+	})
+
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: -1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.27
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other
+  namespace: tools
+data: [value]
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: web
+`
+	var out ValidateResponse
+	s.Post("/validate", ValidateRequest{Manifest: manifest, DryRun: true, Namespace: "apps"}).Decode(&out)
+	var got []string
+	for _, d := range out.Documents {
+		got = append(got, d.Kind+" "+d.DryRun)
+	}
+	if want := []string{"Deployment failed", "ConfigMap passed", "ConfigMap skipped", "Namespace skipped"}; !slices.Equal(got, want) {
+		t.Errorf("dry runs %q, want %q", got, want)
+	}
+	if issues := out.Documents[0].Issues; len(issues) != 1 || issues[0].Field != "spec.replicas" || issues[0].Line != 6 {
+		t.Errorf("deployment issues %+v, want spec.replicas on line 6", issues)
+	}
+	if issues := out.Documents[3].Issues; len(issues) != 1 || issues[0].Severity != "warning" || !out.Documents[3].Valid {
+		t.Errorf("namespace %+v, want it valid with a warning that it was not dry-run", out.Documents[3])
+	}
+	if want := []string{"apps/web"}; !slices.Equal(applied, want) {
+		t.Errorf("applied %q, want %q", applied, want)
+	}
+	if out.Valid || out.Errors != 2 || out.Warnings != 1 {
+		t.Errorf("valid %t with %d errors and %d warnings, want invalid with 2 and 1", out.Valid, out.Errors, out.Warnings)
+	}
+}
+
+func TestSplitDocuments(t *testing.T) {
+	docs := splitDocuments("---\na: 1\n--- # second\n\n# comment\nb:\n  c: 2\n---\n# only a comment\n")
+	if len(docs) != 2 {
+		t.Fatalf("got %d documents, want 2", len(docs))
+	}
+	if docs[0].line != 2 || docs[1].line != 4 || docs[1].index != 1 {
+		t.Errorf("documents start on lines %d and %d, want 2 and 4", docs[0].line, docs[1].line)
+	}
+	if line := docs[1].lineOf(".b.c"); line != 7 {
+		t.Errorf("b.c on line %d, want 7", line)
+	}
+	if line := docs[1].lineOf(".b.d.e"); line != 6 {
+		t.Errorf("b.d.e, which is missing, on line %d, want 6, its parent's", line)
+	}
+}
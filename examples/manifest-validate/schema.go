@@ -0,0 +1,201 @@
+package manifestvalidate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
+	protobuf "google.golang.org/protobuf/proto"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+// openAPIPool bounds the OpenAPI documents fetched and parsed at once: each
+// takes tens of megabytes for a typical cluster.
+var openAPIPool = toolserver.NewPool("openapi", 2, 16)
+
+// schemaCacheTTL is how long the schema is reused. It only changes when the
+// cluster is upgraded or a CRD is installed or changed.
+const schemaCacheTTL = 5 * time.Minute
+
+// staleMaxAge is how long the schema is kept to validate with while the API
+// server cannot be reached.
+const staleMaxAge = time.Hour
+
+// clusterSchema is the cluster's OpenAPI models, with the model of each
+// kind it serves.
+type clusterSchema struct {
+	models  proto.Models
+	kinds   map[schema.GroupVersionKind]string // model names
+	version string                             // the Kubernetes version, e.g. v1.27.0
+}
+
+// newClusterSchema indexes models by the kinds their
+// x-kubernetes-group-version-kind extension names, as kubectl does.
+func newClusterSchema(models proto.Models, version string) *clusterSchema {
+	s := &clusterSchema{models: models, kinds: make(map[schema.GroupVersionKind]string), version: version}
+	for _, name := range models.ListModels() {
+		model := models.LookupModel(name)
+		if model == nil {
+			continue
+		}
+		gvks, _ := model.GetExtensions()["x-kubernetes-group-version-kind"].([]any)
+		for _, v := range gvks {
+			var gvk schema.GroupVersionKind
+			switch v := v.(type) {
+			case map[any]any:
+				gvk.Group, _ = v["group"].(string)
+				gvk.Version, _ = v["version"].(string)
+				gvk.Kind, _ = v["kind"].(string)
+			case map[string]any:
+				gvk.Group, _ = v["group"].(string)
+				gvk.Version, _ = v["version"].(string)
+				gvk.Kind, _ = v["kind"].(string)
+			}
+			if gvk.Version != "" && gvk.Kind != "" {
+				s.kinds[gvk] = name
+			}
+		}
+	}
+	return s
+}
+
+// lookup returns the model of a kind, or nil if the cluster does not serve
+// it.
+func (s *clusterSchema) lookup(gvk schema.GroupVersionKind) proto.Schema {
+	name, ok := s.kinds[gvk]
+	if !ok {
+		return nil
+	}
+	return s.models.LookupModel(name)
+}
+
+// servedVersions returns the versions of group the cluster serves kind in.
+func (s *clusterSchema) servedVersions(group, kind string) []string {
+	var versions []string
+	for gvk := range s.kinds {
+		if gvk.Group == group && gvk.Kind == kind {
+			versions = append(versions, gvk.Version)
+		}
+	}
+	return versions
+}
+
+// schemaCache holds the cluster's schema, which loadSchema fetches and
+// parses on first use, or with $WARMUP at startup, and again once it is
+// schemaCacheTTL old.
+var schemaCache struct {
+	mu      sync.Mutex
+	schema  *clusterSchema
+	fetched time.Time
+	took    time.Duration // to fetch and parse it
+}
+
+// loadSchema returns the cluster's schema, fetching it unless it is
+// cached. Calls wait for a fetch in progress rather than start their own.
+// If a refresh fails, the schema fetched before is used while it is less
+// than staleMaxAge old.
+func loadSchema(ctx context.Context) (*clusterSchema, error) {
+	schemaCache.mu.Lock()
+	defer schemaCache.mu.Unlock()
+	age := time.Since(schemaCache.fetched)
+	if schemaCache.schema != nil && age < schemaCacheTTL {
+		return schemaCache.schema, nil
+	}
+	start := time.Now()
+	s, err := fetchSchema(ctx)
+	if err != nil {
+		if schemaCache.schema != nil && age < staleMaxAge {
+			slog.Warn("refreshing the OpenAPI schema failed; using the one fetched before", "err", err, "age", age.String())
+			return schemaCache.schema, nil
+		}
+		return nil, err
+	}
+	schemaCache.schema, schemaCache.fetched, schemaCache.took = s, time.Now(), time.Since(start)
+	slog.Info("loaded the OpenAPI schema", "took", schemaCache.took.String(), "kinds", len(s.kinds))
+	return s, nil
+}
+
+// warmSchema is the warm-up that loads the schema at startup.
+func warmSchema(ctx context.Context) error {
+	_, err := loadSchema(ctx)
+	return err
+}
+
+// fetchSchema fetches and parses the cluster's OpenAPI v2 schema.
+func fetchSchema(ctx context.Context) (*clusterSchema, error) {
+	clientset, err := kubeClient.Clientset()
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := openAPIPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	doc, err := kube.Retry(ctx, func(ctx context.Context) (*openapi_v2.Document, error) {
+		return openAPISchema(ctx, clientset.Discovery())
+	})
+	if err != nil {
+		return nil, toolserver.UpstreamError(err, "failed to fetch OpenAPI schema")
+	}
+	return parseSchema(doc)
+}
+
+// parseSchema parses an OpenAPI v2 document into the schema validate uses.
+func parseSchema(doc *openapi_v2.Document) (*clusterSchema, error) {
+	models, err := proto.NewOpenAPIData(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI schema: %v", err)
+	}
+	return newClusterSchema(models, doc.GetInfo().GetVersion()), nil
+}
+
+// openAPIV2Protobuf is the media type of the protobuf-encoded OpenAPI v2
+// document.
+const openAPIV2Protobuf = "application/com.github.proto-openapi.spec.v2@v1.0+protobuf"
+
+// openAPISchema fetches the cluster's OpenAPI v2 document like
+// discovery.OpenAPISchema, but under ctx, so the fetch is cancelled with the
+// request.
+func openAPISchema(ctx context.Context, client discovery.DiscoveryInterface) (*openapi_v2.Document, error) {
+	data, err := client.RESTClient().Get().
+		AbsPath("/openapi/v2").
+		SetHeader("Accept", openAPIV2Protobuf).
+		Do(ctx).
+		Raw()
+	if err != nil {
+		return nil, err
+	}
+	doc := &openapi_v2.Document{}
+	if err := protobuf.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// schemaDiagnostics reports in /healthz/verbose the client's state, and
+// when the schema was last loaded, how long ago, how long fetching and
+// parsing it took and how many kinds it has.
+func schemaDiagnostics() map[string]any {
+	details := kubeClient.Diagnostics()
+	schemaCache.mu.Lock()
+	s, t, took := schemaCache.schema, schemaCache.fetched, schemaCache.took
+	schemaCache.mu.Unlock()
+	if s == nil {
+		details["schema_fetched"] = false
+		return details
+	}
+	details["schema_fetched_at"], details["schema_age_seconds"] = t, int(time.Since(t).Seconds())
+	details["schema_load_ms"] = took.Milliseconds()
+	details["schema_kinds"], details["schema_version"] = len(s.kinds), s.version
+	return details
+}
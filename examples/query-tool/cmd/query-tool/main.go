@@ -0,0 +1,22 @@
+// Command query-tool serves query-tool on its own. kube-mcp-tools serves it
+// together with the other example tools.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	querytool "query-tool"
+)
+
+func main() {
+	s, err := querytool.New()
+	if err != nil {
+		slog.Error("starting query-tool", "err", err)
+		os.Exit(1)
+	}
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
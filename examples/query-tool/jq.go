@@ -0,0 +1,69 @@
+package querytool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/itchyny/gojq"
+)
+
+// Programs are evaluated with gojq, which implements the whole jq language
+// and its builtins. They read only the document or object queried: input,
+// inputs, import and include fail to compile, and $ENV and env are empty
+// rather than the tool's environment.
+
+// jqTimeout bounds the evaluation of a program, so that one such as
+// [range(1e9)] or [..] of a large list fails quickly rather than run, and
+// allocate, for the whole request timeout.
+const jqTimeout = time.Second
+
+// errJQTimeout ends a program that runs for more than jqTimeout.
+var errJQTimeout = fmt.Errorf("the program ran for more than %v; narrow it, e.g. with select or limit", jqTimeout)
+
+// compileJQ parses and compiles a jq program.
+func compileJQ(program string) (*gojq.Code, error) {
+	q, err := gojq.Parse(program)
+	if err != nil {
+		var perr *gojq.ParseError
+		if errors.As(err, &perr) {
+			return nil, fmt.Errorf("syntax error at position %d: %v", perr.Offset, err)
+		}
+		return nil, err
+	}
+	return gojq.Compile(q)
+}
+
+// runJQ runs a program on an input, calling yield with each output and its
+// JSON as jq -c prints it. It fails with errJQTimeout if the program runs
+// for more than jqTimeout, and with the program's error if it raises one;
+// halt ends the outputs without one.
+func runJQ(ctx context.Context, code *gojq.Code, in any, yield func(out any, text []byte)) error {
+	jqCtx, cancel := context.WithTimeout(ctx, jqTimeout)
+	defer cancel()
+	iter := code.RunWithContext(jqCtx, in)
+	for {
+		out, ok := iter.Next()
+		if !ok {
+			return nil
+		}
+		if err, ok := out.(error); ok {
+			var halt *gojq.HaltError
+			switch {
+			case errors.As(err, &halt) && halt.Value() == nil:
+				return nil
+			case ctx.Err() != nil:
+				return ctx.Err()
+			case errors.Is(err, context.DeadlineExceeded):
+				return errJQTimeout
+			}
+			return err
+		}
+		text, err := gojq.Marshal(out)
+		if err != nil {
+			return err
+		}
+		yield(out, text)
+	}
+}
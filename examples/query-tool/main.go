@@ -0,0 +1,185 @@
+// Package querytool implements query-tool, which evaluates JSONPath and jq
+// expressions against a JSON or YAML document or an object read from the
+// cluster, so that only the fields asked for are returned.
+package querytool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	// queryTimeout bounds a request, which reads discovery information and
+	// then the object or list queried.
+	queryTimeout = 30 * time.Second
+
+	// maxResults and maxTextBytes cap what a query returns; the results
+	// beyond them are dropped and the response marked truncated.
+	maxResults   = 1000
+	maxTextBytes = 256 << 10
+)
+
+type JSONPathRequest struct {
+	Source
+	Expression       string `json:"expression"`       // as kubectl -o jsonpath takes it, e.g. {.spec.replicas} or {range .items[*]}{.metadata.name}{"\n"}{end}; the braces are optional for a single path
+	AllowMissingKeys bool   `json:"allowMissingKeys"` // print nothing for a missing key instead of failing
+}
+
+type JQRequest struct {
+	Source
+	Program string `json:"program"` // e.g. .spec.template.spec.containers[] | {name, image}
+	Raw     bool   `json:"raw"`     // print strings without quotes, as jq -r does
+}
+
+type QueryResponse struct {
+	Source       string `json:"source"`    // what was queried, e.g. document or apps/v1 Deployment default/web
+	Results      []any  `json:"results"`   // the values the expression selected, with the literal text of a JSONPath template
+	Text         string `json:"text"`      // the results as kubectl -o jsonpath, or jq -c, prints them
+	Count        int    `json:"count"`     // of results, including any truncated
+	Truncated    bool   `json:"truncated"` // results or text were dropped past the limits
+	RetriedTimes int    `json:"retriedTimes,omitempty"`
+}
+
+// ContentBlocks gives MCP clients the printed results alone.
+func (r QueryResponse) ContentBlocks() []toolserver.Content {
+	text := r.Text
+	if r.Truncated {
+		text += fmt.Sprintf("\n(truncated: %d results in all)", r.Count)
+	}
+	return []toolserver.Content{toolserver.TextContent(text)}
+}
+
+// New returns the query-tool server. Documents are queried without a
+// cluster; objects are read with whatever get and list permissions the
+// tool's service account has, so a kind it may not read fails with
+// FORBIDDEN rather than the tool being marked unavailable.
+func New() (*toolserver.Server, error) {
+	configureAPICalls()
+
+	s := toolserver.New("query-tool")
+	s.AddDiagnostics("kubernetes", kubeClient.Diagnostics)
+	toolserver.Register(s, "/jsonpath", queryJSONPath,
+		toolserver.Name("query-jsonpath"), toolserver.Describe("Evaluate a kubectl-style JSONPath expression against a JSON or YAML document, or a cluster object or list, and return only what it selects."),
+		toolserver.Timeout(queryTimeout), toolserver.Sensitive("document"))
+	toolserver.Register(s, "/jq", queryJQ,
+		toolserver.Name("query-jq"), toolserver.Describe("Evaluate a jq program against a JSON or YAML document, or a cluster object or list, and return its outputs."),
+		toolserver.Timeout(queryTimeout), toolserver.Sensitive("document"))
+
+	return s, nil
+}
+
+func queryJSONPath(ctx context.Context, req JSONPathRequest) (QueryResponse, error) {
+	expr := strings.TrimSpace(req.Expression)
+	if expr == "" {
+		return QueryResponse{}, toolserver.BadRequest("expression is required")
+	}
+	jp := jsonpath.New("query").AllowMissingKeys(req.AllowMissingKeys)
+	if err := jp.Parse(relaxJSONPath(expr)); err != nil {
+		return QueryResponse{}, toolserver.BadRequest("expression: %v", err)
+	}
+	ctx = kube.CountRetries(ctx)
+	v, source, err := req.load(ctx)
+	if err != nil {
+		return QueryResponse{}, err
+	}
+
+	found, err := jp.FindResults(v)
+	if err != nil {
+		return QueryResponse{}, toolserver.NewError(toolserver.CodeUnprocessable, "%v", err)
+	}
+	resp := QueryResponse{Source: source, Results: []any{}}
+	var text bytes.Buffer
+	for _, values := range found {
+		if err := jp.PrintResults(&text, values); err != nil {
+			return QueryResponse{}, toolserver.NewError(toolserver.CodeUnprocessable, "%v", err)
+		}
+		for _, r := range values {
+			resp.add(r.Interface())
+		}
+	}
+	resp.Text, resp.Truncated = truncateText(text.String(), resp.Truncated)
+	resp.RetriedTimes = kube.Retried(ctx)
+	return resp, nil
+}
+
+func queryJQ(ctx context.Context, req JQRequest) (QueryResponse, error) {
+	if strings.TrimSpace(req.Program) == "" {
+		return QueryResponse{}, toolserver.BadRequest("program is required")
+	}
+	code, err := compileJQ(req.Program)
+	if err != nil {
+		return QueryResponse{}, toolserver.BadRequest("program: %v", err)
+	}
+	ctx = kube.CountRetries(ctx)
+	v, source, err := req.load(ctx)
+	if err != nil {
+		return QueryResponse{}, err
+	}
+
+	resp := QueryResponse{Source: source, Results: []any{}}
+	var text strings.Builder
+	err = runJQ(ctx, code, v, func(out any, data []byte) {
+		// The results are sent as jq prints them, which JSON can carry
+		// whatever the value, e.g. infinite.
+		if !resp.add(json.RawMessage(data)) {
+			return
+		}
+		if s, ok := out.(string); ok && req.Raw {
+			text.WriteString(s)
+		} else {
+			text.Write(data)
+		}
+		text.WriteByte('\n')
+	})
+	switch {
+	case err == errJQTimeout:
+		return QueryResponse{}, toolserver.NewError(toolserver.CodeUnprocessable, "%v", err)
+	case ctx.Err() != nil:
+		return QueryResponse{}, ctx.Err()
+	case err != nil:
+		return QueryResponse{}, toolserver.NewError(toolserver.CodeUnprocessable, "jq: error: %v", err)
+	}
+	resp.Text, resp.Truncated = truncateText(text.String(), resp.Truncated)
+	resp.RetriedTimes = kube.Retried(ctx)
+	return resp, nil
+}
+
+// add counts a result and keeps it, unless there are maxResults already.
+func (r *QueryResponse) add(v any) bool {
+	r.Count++
+	if len(r.Results) == maxResults {
+		r.Truncated = true
+		return false
+	}
+	r.Results = append(r.Results, v)
+	return true
+}
+
+// relaxJSONPath accepts an expression without braces, as kubectl does:
+// .spec.replicas, spec.replicas and $.spec.replicas all mean
+// {.spec.replicas}.
+func relaxJSONPath(expr string) string {
+	if strings.Contains(expr, "{") {
+		return expr
+	}
+	expr = strings.TrimPrefix(expr, "$")
+	if !strings.HasPrefix(expr, ".") && !strings.HasPrefix(expr, "[") {
+		expr = "." + expr
+	}
+	return "{" + expr + "}"
+}
+
+func truncateText(text string, truncated bool) (string, bool) {
+	if len(text) <= maxTextBytes {
+		return text, truncated
+	}
+	return strings.ToValidUTF8(text[:maxTextBytes], ""), true
+}
@@ -0,0 +1,256 @@
+package querytool
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+)
+
+// podDocument is a pod with two containers, in YAML.
+const podDocument = `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: apps
+  labels:
+    app: web
+spec:
+  containers:
+  - name: web
+    image: nginx:1.27
+    ports:
+    - containerPort: 80
+  - name: metrics
+    image: prom/statsd-exporter:v0.26.0
+status:
+  phase: Running
+`
+
+func TestJQ(t *testing.T) {
+	for _, tc := range []struct {
+		program, input string
+		want           string // the outputs, as jq -c prints them
+		err            string // part of the error, for programs that fail
+	}{
+		{program: ".", input: `{"a":1}`, want: `{"a":1}`},
+		{program: ".a.b", input: `{"a":{"b":[1,2]}}`, want: `[1,2]`},
+		{program: `.a."b-c"`, input: `{"a":{"b-c":1}}`, want: `1`},
+		{program: `.["a"]`, input: `{"a":1}`, want: `1`},
+		{program: ".missing", input: `{}`, want: `null`},
+		{program: ".[1], .[-1], .[5]", input: `[1,2,3]`, want: "2\n3\nnull"},
+		{program: ".[1:], .[:-1], .[:1]", input: `[1,2,3]`, want: "[2,3]\n[1,2]\n[1]"},
+		{program: ".[2:4]", input: `"abcdef"`, want: `"cd"`},
+		{program: ".[]", input: `{"b":2,"a":1}`, want: "1\n2"},
+		{program: ".a[]?", input: `{"a":1}`, want: ""},
+		{program: ".a[]", input: `{"a":1}`, err: "cannot iterate over: number (1)"},
+		{program: ".a.b", input: `{"a":"x"}`, err: `expected an object but got: string ("x")`},
+		{program: "[.[] | . * 2]", input: `[1,2]`, want: `[2,4]`},
+		{program: "[.[] | select(. > 1)]", input: `[1,2,3]`, want: `[2,3]`},
+		{program: "(1,2) + (10,20)", input: `null`, want: "11\n12\n21\n22"},
+		{program: "{a, b: .c, (.k): 1, \"d\": (1,2)}", input: `{"a":1,"c":2,"k":"x"}`, want: "{\"a\":1,\"b\":2,\"d\":1,\"x\":1}\n{\"a\":1,\"b\":2,\"d\":2,\"x\":1}"},
+		{program: `"\(.name):\(.n + 1)"`, input: `{"name":"web","n":1}`, want: `"web:2"`},
+		{program: `@base64 "x\(.)y"`, input: `"hi"`, want: `"xaGk=y"`},
+		{program: "@csv, @tsv", input: `["a\"b",1,null,"c\td"]`, want: "\"\\\"a\\\"\\\"b\\\",1,,\\\"c\\td\\\"\"\n\"a\\\"b\\t1\\t\\tc\\\\td\""},
+		{program: "@base64d", input: `"aGk="`, want: `"hi"`},
+		{program: "@uri", input: `"a b&c"`, want: `"a%20b%26c"`},
+		{program: ". as $x | [$x, $x + 1]", input: `1`, want: `[1,2]`},
+		{program: "reduce .[] as $x (0; . + $x)", input: `[1,2,3]`, want: `6`},
+		{program: `if . > 1 then "big" elif . == 1 then "one" else "small" end`, input: `1`, want: `"one"`},
+		{program: "if . then 1 end", input: `false`, want: `false`},
+		{program: `try error("boom") catch .`, input: `null`, want: `"boom"`},
+		{program: `try error({a: 1})`, input: `null`, want: ""},
+		{program: `error("boom")`, input: `null`, err: "boom"},
+		{program: ".a // .b // 3", input: `{"a":false,"b":null}`, want: `3`},
+		{program: "(.a and .b), (.a or .b), (.c | not)", input: `{"a":true,"b":false}`, want: "false\ntrue\ntrue"},
+		{program: "1 / 0", input: `null`, err: "cannot divide number (1) by: number (0)"},
+		{program: `{} + {"a":1} * {"a":{"b":2}}, [1,2,1] - [1], "ab" * 2, "a,b" / ","`, input: `null`, want: "{\"a\":{\"b\":2}}\n[2]\n\"abab\"\n[\"a\",\"b\"]"},
+		{program: "[null, true, false, 0, -1, \"a\", [], {}] | sort", input: `null`, want: `[null,false,true,-1,0,"a",[],{}]`},
+		{program: "length, keys, has(\"a\"), (to_entries | map(.key)), add", input: `{"b":[1],"a":[2,3]}`, want: "2\n[\"a\",\"b\"]\ntrue\n[\"a\",\"b\"]\n[2,3,1]"},
+		{program: "[.[].n] | (add, min, max, unique, reverse, first, last)", input: `[{"n":3},{"n":1},{"n":3}]`, want: "7\n1\n3\n[1,3]\n[3,1,3]\n3\n3"},
+		{program: "sort_by(.n) | map(.k)", input: `[{"n":2,"k":"a"},{"n":1,"k":"b"},{"n":2,"k":"c"}]`, want: `["b","a","c"]`},
+		{program: "group_by(.n) | map(length)", input: `[{"n":2},{"n":1},{"n":2}]`, want: `[1,2]`},
+		{program: "unique_by(.n) | length, (min_by(.n) | .n), (max_by(.n) | .n)", input: `[{"n":2},{"n":1},{"n":2}]`, want: "2\n1\n2"},
+		{program: "with_entries(.value += 1)", input: `{"a":1}`, want: `{"a":2}`},
+		{program: "with_entries({key: (.key | ascii_upcase), value})", input: `{"a":1}`, want: `{"A":1}`},
+		{program: "from_entries", input: `[{"name":"a","value":1},{"key":"b","value":2}]`, want: `{"a":1,"b":2}`},
+		{program: "map_values(. + 1), map_values(empty)", input: `{"a":1,"b":2}`, want: "{\"a\":2,\"b\":3}\n{}"},
+		{program: `split(",") | join("-")`, input: `"a,b,c"`, want: `"a-b-c"`},
+		{program: `ltrimstr("v") | tonumber`, input: `"v12"`, want: `12`},
+		{program: `startswith("nginx"), endswith(":1.27"), contains("z"), test("^NGINX"; "i")`, input: `"nginx:1.27"`, want: "true\ntrue\nfalse\ntrue"},
+		{program: `capture("(?<repo>[^:]+):(?<tag>.+)")`, input: `"nginx:1.27"`, want: `{"repo":"nginx","tag":"1.27"}`},
+		{program: `sub("(?<x>[0-9]+)"; "<\(.x)>"), gsub("[0-9]"; "#")`, input: `"a1b22"`, want: "\"a<1>b22\"\n\"a#b##\""},
+		{program: `contains({a: [1]}), contains([2])`, input: `{"a":[1,2]}`, err: "cannot be applied to: object"},
+		{program: `contains({a: [1]})`, input: `{"a":[1,2],"b":1}`, want: `true`},
+		{program: "[range(3)], [range(1; 3)], [limit(2; range(10))], first(range(5; 10)), [.[] | numbers]", input: `[1,"a",null]`, want: "[0,1,2]\n[1,2]\n[0,1]\n5\n[1]"},
+		{program: "[paths], [..] | length", input: `{"a":[1,{"b":2}]}`, want: "4\n5"},
+		{program: "[paths]", input: `{"a":[1]}`, want: `[["a"],["a",0]]`},
+		{program: `getpath(["a", 0, "b"]), getpath(["x", "y"])`, input: `{"a":[{"b":1}]}`, want: "1\nnull"},
+		{program: "walk(if type == \"number\" then . + 1 else . end)", input: `{"a":[1,{"b":2}]}`, want: `{"a":[2,{"b":3}]}`},
+		{program: "tojson, (tojson | fromjson), tostring", input: `{"a":"<b>"}`, want: "\"{\\\"a\\\":\\\"<b>\\\"}\"\n{\"a\":\"<b>\"}\n\"{\\\"a\\\":\\\"<b>\\\"}\""},
+		{program: "floor, ceil, round, -., (. | abs)", input: `-1.5`, want: "-2\n-1\n-2\n1.5\n1.5"},
+		{program: "flatten, flatten(1)", input: `[[1,[false]],[]]`, want: "[1,false]\n[1,[false]]"},
+		{program: "any, all, (map(. == 1) | any), all(. > 0)", input: `[1,2]`, want: "true\ntrue\ntrue\ntrue"},
+		{program: ".. | numbers", input: `{"a":[1,{"b":2}]}`, want: "1\n2"},
+		{program: "[recurse(if . < 3 then . + 1 else empty end)]", input: `0`, want: `[0,1,2,3]`},
+		{program: "type, length", input: `"héllo"`, want: "\"string\"\n5"},
+		{program: "def f: 1; f", input: `null`, want: `1`},
+		{program: ".a = 1, del(.b), (.c |= . + 1)", input: `{"b":2,"c":3}`, want: "{\"a\":1,\"b\":2,\"c\":3}\n{\"c\":3}\n{\"b\":2,\"c\":4}"},
+		{program: "[foreach .[] as $x (0; . + $x)], [path(..)]", input: `[1,2]`, want: "[1,3]\n[[],[0],[1]]"},
+		{program: "1, halt, 2", input: `null`, want: `1`},
+		{program: `"boom" | halt_error`, input: `null`, err: "boom"},
+		{program: "infinite, nan", input: `null`, want: "1.7976931348623157e+308\nnull"},
+		{program: "$ENV, env", input: `null`, want: "{}\n{}"},
+		{program: "input", input: `null`, err: "input(s)/0 is not allowed"},
+		{program: `import "a" as a; .`, input: `null`, err: `cannot load module: "a"`},
+		{program: "[range(1e9)] | length", input: `null`, err: "the program ran for more than 1s"},
+		{program: "nosuch(1)", input: `null`, err: "function not defined: nosuch/1"},
+		{program: ".a |", input: `null`, err: "syntax error at position 4"},
+		{program: `"unterminated`, input: `null`, err: "unterminated string"},
+	} {
+		t.Run(tc.program, func(t *testing.T) {
+			got, err := evalJQ(tc.program, tc.input)
+			switch {
+			case tc.err != "":
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got %q, error %v, want an error with %q", got, err, tc.err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case got != tc.want:
+				t.Errorf("got\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+// evalJQ runs a program on a JSON input, returning its outputs as jq -c
+// prints them.
+func evalJQ(program, input string) (string, error) {
+	code, err := compileJQ(program)
+	if err != nil {
+		return "", err
+	}
+	var in any
+	if err := json.Unmarshal([]byte(input), &in); err != nil {
+		return "", err
+	}
+	var lines []string
+	err = runJQ(context.Background(), code, in, func(_ any, text []byte) {
+		lines = append(lines, string(text))
+	})
+	return strings.Join(lines, "\n"), err
+}
+
+func newTestServer(t *testing.T) *tooltest.Server {
+	replicas := int32(2)
+	deployment := func(namespace, name, app string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"app": app},
+				ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply}}},
+			Spec: appsv1.DeploymentSpec{Replicas: &replicas, Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: name, Image: "nginx:1.27"}},
+			}}},
+		}
+	}
+	k := tooltest.FakeKube(t, kubeClient,
+		deployment("default", "web", "web"), deployment("apps", "api", "api"), deployment("apps", "worker", "worker"),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}})
+	k.Clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "namespaces", SingularName: "namespace", Kind: "Namespace", ShortNames: []string{"ns"}}}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", SingularName: "deployment", Kind: "Deployment", Namespaced: true, ShortNames: []string{"deploy"}}}},
+	}
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tooltest.NewServer(t, s)
+}
+
+func TestHandlers(t *testing.T) {
+	s := newTestServer(t)
+	text := func(want string) func(t *testing.T, resp *tooltest.Response) {
+		return func(t *testing.T, resp *tooltest.Response) {
+			var out QueryResponse
+			resp.Decode(&out)
+			if out.Text != want {
+				t.Errorf("text %q, want %q", out.Text, want)
+			}
+		}
+	}
+	tooltest.Run(t, s, []tooltest.Case{
+		{Name: "jsonpath document", Path: "/jsonpath", Body: JSONPathRequest{Source: Source{Document: podDocument},
+			Expression: `{range .spec.containers[*]}{.name}={.image}{"\n"}{end}`}, Golden: "jsonpath-document"},
+		{Name: "jsonpath relaxed", Path: "/jsonpath", Body: JSONPathRequest{Source: Source{Document: podDocument}, Expression: "spec.containers[0].ports[0].containerPort"},
+			Check: text("80")},
+		{Name: "jsonpath missing", Path: "/jsonpath", Body: JSONPathRequest{Source: Source{Document: podDocument}, Expression: ".spec.nodeName"},
+			Code: toolserver.CodeUnprocessable},
+		{Name: "jsonpath allow missing", Path: "/jsonpath", Body: JSONPathRequest{Source: Source{Document: podDocument}, Expression: ".spec.nodeName", AllowMissingKeys: true},
+			Check: text("")},
+		{Name: "jsonpath invalid", Path: "/jsonpath", Body: JSONPathRequest{Source: Source{Document: podDocument}, Expression: "{.spec"},
+			Code: toolserver.CodeInvalidArgument},
+		{Name: "jsonpath object", Path: "/jsonpath", Body: JSONPathRequest{Source: Source{Object: &ObjectRef{Kind: "deploy", Name: "web"}}, Expression: "{.spec.replicas}"},
+			Check: func(t *testing.T, resp *tooltest.Response) {
+				var out QueryResponse
+				resp.Decode(&out)
+				if out.Text != "2" || out.Source != "apps/v1 Deployment default/web" {
+					t.Errorf("got %q from %q, want 2 from apps/v1 Deployment default/web", out.Text, out.Source)
+				}
+			}},
+		{Name: "jq document", Path: "/jq", Body: JQRequest{Source: Source{Document: podDocument},
+			Program: ".spec.containers[] | {name, image, ports: [.ports[]?.containerPort]}"}, Golden: "jq-document"},
+		{Name: "jq raw", Path: "/jq", Body: JQRequest{Source: Source{Document: podDocument}, Program: `.spec.containers[] | "\(.name)\t\(.image)"`, Raw: true},
+			Check: text("web\tnginx:1.27\nmetrics\tprom/statsd-exporter:v0.26.0\n")},
+		{Name: "jq documents", Path: "/jq", Body: JQRequest{Source: Source{Document: "a: 1\n---\na: 2\n"}, Program: "[.items[].a] | add"},
+			Check: text("3\n")},
+		{Name: "jq json", Path: "/jq", Body: JQRequest{Source: Source{Document: `{"a": [1, 2]}`}, Program: ".a | length"},
+			Check: text("2\n")},
+		{Name: "jq list", Path: "/jq", Body: JQRequest{Source: Source{Object: &ObjectRef{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "apps"}},
+			Program: `.kind, [.items[].metadata.name], [.items[].metadata.managedFields]`},
+			Check: text("\"DeploymentList\"\n[\"api\",\"worker\"]\n[null,null]\n")},
+		{Name: "jq all namespaces", Path: "/jq", Body: JQRequest{Source: Source{Object: &ObjectRef{Kind: "deployments", LabelSelector: "app!=worker"}}, Program: `[.items[].metadata.name] | sort`},
+			Check: func(t *testing.T, resp *tooltest.Response) {
+				var out QueryResponse
+				resp.Decode(&out)
+				if out.Text != "[\"api\",\"web\"]\n" || out.Source != "apps/v1 DeploymentList in all namespaces" {
+					t.Errorf("got %q from %q, want api and web from all namespaces", out.Text, out.Source)
+				}
+			}},
+		{Name: "jq cluster-scoped", Path: "/jq", Body: JQRequest{Source: Source{Object: &ObjectRef{Kind: "ns", Name: "apps"}}, Program: ".metadata.name"},
+			Check: text("\"apps\"\n")},
+		{Name: "jq runtime error", Path: "/jq", Body: JQRequest{Source: Source{Document: podDocument}, Program: ".metadata.name + 1"},
+			Code: toolserver.CodeUnprocessable},
+		{Name: "jq syntax error", Path: "/jq", Body: JQRequest{Source: Source{Document: podDocument}, Program: ".spec |"},
+			Code: toolserver.CodeInvalidArgument},
+		{Name: "jq too expensive", Path: "/jq", Body: JQRequest{Source: Source{Document: "{}"}, Program: "[range(1e9)]"},
+			Code: toolserver.CodeUnprocessable, Check: func(t *testing.T, resp *tooltest.Response) {
+				if msg := resp.Error().Message; !strings.Contains(msg, "ran for more than") {
+					t.Errorf("message %q, want the program's time limit", msg)
+				}
+			}},
+		{Name: "no source", Path: "/jq", Body: JQRequest{Program: "."}, Code: toolserver.CodeInvalidArgument},
+		{Name: "both sources", Path: "/jq", Body: JQRequest{Source: Source{Document: "{}", Object: &ObjectRef{Kind: "ns", Name: "apps"}}, Program: "."},
+			Code: toolserver.CodeInvalidArgument},
+		{Name: "invalid document", Path: "/jq", Body: JQRequest{Source: Source{Document: "a: [1"}, Program: "."}, Code: toolserver.CodeInvalidArgument},
+		{Name: "unknown kind", Path: "/jq", Body: JQRequest{Source: Source{Object: &ObjectRef{Kind: "Widget", Name: "a"}}, Program: "."},
+			Code: toolserver.CodeNotFound},
+		{Name: "missing object", Path: "/jq", Body: JQRequest{Source: Source{Object: &ObjectRef{Kind: "Deployment", Name: "nope"}}, Program: "."},
+			Code: toolserver.CodeNotFound},
+	})
+}
+
+func TestTruncated(t *testing.T) {
+	s := newTestServer(t)
+	var out QueryResponse
+	s.Post("/jq", JQRequest{Source: Source{Document: "{}"}, Program: "range(1500)"}).Decode(&out)
+	if !out.Truncated || out.Count != 1500 || len(out.Results) != maxResults {
+		t.Errorf("truncated %t with %d of %d results, want %d of 1500", out.Truncated, len(out.Results), out.Count, maxResults)
+	}
+}
@@ -0,0 +1,197 @@
+package querytool
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// maxListItems caps the objects a query of a list reads.
+const maxListItems = 500
+
+// Source is what an expression is evaluated against: a document, or an
+// object read from the cluster. Exactly one is required.
+type Source struct {
+	Document string     `json:"document"` // JSON or YAML; several YAML documents are queried as a List of them
+	Object   *ObjectRef `json:"object"`
+}
+
+// ObjectRef names a cluster object, or, without a name, the list of the
+// objects of a kind.
+type ObjectRef struct {
+	APIVersion    string `json:"apiVersion"`    // e.g. apps/v1; defaults to the version the cluster prefers
+	Kind          string `json:"kind"`          // a kind, resource or short name, e.g. Deployment, deployments or deploy
+	Namespace     string `json:"namespace"`     // of a namespaced object; defaults to "default", or to all namespaces for a list
+	Name          string `json:"name"`          // empty to query the list of objects, at most 500, as a List
+	LabelSelector string `json:"labelSelector"` // filters a list, e.g. app=web
+}
+
+// load returns the value to query and a description of where it came
+// from, e.g. "apps/v1 Deployment default/web".
+func (s Source) load(ctx context.Context) (any, string, error) {
+	switch {
+	case s.Document != "" && s.Object != nil:
+		return nil, "", toolserver.BadRequest("document and object are exclusive")
+	case s.Object != nil:
+		return s.Object.fetch(ctx)
+	case strings.TrimSpace(s.Document) == "":
+		return nil, "", toolserver.BadRequest("document or object is required")
+	}
+	v, err := parseDocument(s.Document)
+	return v, "document", err
+}
+
+// parseDocument parses a JSON or YAML document, or several YAML documents
+// as a List of them.
+func parseDocument(doc string) (any, error) {
+	dec := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(doc), 4096)
+	var docs []any
+	for {
+		var v any
+		if err := dec.Decode(&v); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, toolserver.BadRequest("document %d is not valid JSON or YAML: %v", len(docs)+1, err)
+		}
+		if v != nil {
+			docs = append(docs, v)
+		}
+	}
+	switch len(docs) {
+	case 0:
+		return nil, toolserver.BadRequest("document is empty")
+	case 1:
+		return docs[0], nil
+	}
+	return map[string]any{"apiVersion": "v1", "kind": "List", "items": docs}, nil
+}
+
+// fetch reads the object, or the list of objects, from the cluster. Their
+// managed fields are dropped, as they are rarely what is queried and often
+// most of an object.
+func (ref *ObjectRef) fetch(ctx context.Context) (any, string, error) {
+	if ref.Kind == "" {
+		return nil, "", toolserver.BadRequest("object.kind is required")
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, "", toolserver.BadRequest("object.apiVersion: %v", err)
+	}
+	clientset, err := kubeClient.Clientset()
+	if err != nil {
+		return nil, "", err
+	}
+	dyn, err := kubeClient.Dynamic()
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiCtx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	groups, err := kube.Retry(apiCtx, func(ctx context.Context) ([]*restmapper.APIGroupResources, error) {
+		return restmapper.GetAPIGroupResources(clientset.Discovery())
+	})
+	if err != nil {
+		return nil, "", kube.Error(err)
+	}
+	mapper := restmapper.NewShortcutExpander(restmapper.NewDiscoveryRESTMapper(groups), clientset.Discovery(), func(msg string) {
+		slog.Debug("resolving a short name", "kind", ref.Kind, "warning", msg)
+	})
+	gvk, err := mapper.KindFor(gv.WithResource(strings.ToLower(ref.Kind)))
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, "", toolserver.NewError(toolserver.CodeNotFound, "the cluster serves no kind or resource %q%s", ref.Kind, inVersion(ref.APIVersion))
+		}
+		return nil, "", kube.Error(err)
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, "", kube.Error(err)
+	}
+
+	var resource dynamic.ResourceInterface = dyn.Resource(mapping.Resource)
+	namespace, where := "", ""
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace = ref.Namespace
+		if ref.Name != "" {
+			namespace = cmp.Or(namespace, "default")
+		}
+		resource = dyn.Resource(mapping.Resource).Namespace(namespace)
+		where = " in all namespaces"
+		if namespace != "" {
+			where = " in " + namespace
+		}
+	}
+	apiVersion := gvk.GroupVersion().String()
+
+	apiCtx, cancel = context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+	if ref.Name != "" {
+		obj, err := kube.Retry(apiCtx, func(ctx context.Context) (*unstructured.Unstructured, error) {
+			return resource.Get(ctx, ref.Name, metav1.GetOptions{})
+		})
+		if err != nil {
+			return nil, "", kube.Error(err)
+		}
+		obj.SetManagedFields(nil)
+		v, err := normalize(obj.Object)
+		return v, fmt.Sprintf("%s %s %s", apiVersion, gvk.Kind, objectName{namespace, ref.Name}), err
+	}
+	list, err := kube.Retry(apiCtx, func(ctx context.Context) (*unstructured.UnstructuredList, error) {
+		return resource.List(ctx, metav1.ListOptions{LabelSelector: ref.LabelSelector, Limit: maxListItems})
+	})
+	if err != nil {
+		return nil, "", kube.Error(err)
+	}
+	for i := range list.Items {
+		list.Items[i].SetManagedFields(nil)
+	}
+	v, err := normalize(list.UnstructuredContent())
+	return v, fmt.Sprintf("%s %sList%s", apiVersion, gvk.Kind, where), err
+}
+
+// objectName is a namespace and name, printed as kubectl does.
+type objectName struct{ namespace, name string }
+
+func (c objectName) String() string {
+	if c.namespace == "" {
+		return c.name
+	}
+	return c.namespace + "/" + c.name
+}
+
+func inVersion(apiVersion string) string {
+	if apiVersion == "" {
+		return ""
+	}
+	return " in " + apiVersion
+}
+
+// normalize turns an object's content into the values JSON decodes to, so
+// its integers are float64, as they are in a parsed document.
+func normalize(obj any) (any, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, toolserver.NewError(toolserver.CodeInternal, "encoding the object: %v", err)
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, toolserver.NewError(toolserver.CodeInternal, "decoding the object: %v", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,60 @@
+package timetool
+
+import "time"
+
+// CalendarDetails is the calendar metadata returned with details: true.
+type CalendarDetails struct {
+	Weekday        string          `json:"weekday"`
+	DayOfYear      int             `json:"day_of_year"`
+	ISOYear        int             `json:"iso_year"` // differs from the year around January 1st
+	ISOWeek        int             `json:"iso_week"`
+	Quarter        int             `json:"quarter"`
+	LeapYear       bool            `json:"leap_year"`
+	DaysInMonth    int             `json:"days_in_month"`
+	DSTTransitions []DSTTransition `json:"dst_transitions"` // in the zone, for the calendar year
+}
+
+// DSTTransition is a change of UTC offset, usually into or out of DST.
+type DSTTransition struct {
+	Time         string `json:"time"` // RFC 3339, in the new offset
+	OffsetBefore string `json:"offset_before"`
+	OffsetAfter  string `json:"offset_after"`
+	DST          bool   `json:"dst"` // whether DST is in effect after the transition
+}
+
+// calendarDetails describes t in its own location.
+func calendarDetails(t time.Time) CalendarDetails {
+	isoYear, isoWeek := t.ISOWeek()
+	year := t.Year()
+	return CalendarDetails{
+		Weekday:        t.Weekday().String(),
+		DayOfYear:      t.YearDay(),
+		ISOYear:        isoYear,
+		ISOWeek:        isoWeek,
+		Quarter:        (int(t.Month())-1)/3 + 1,
+		LeapYear:       year%4 == 0 && (year%100 != 0 || year%400 == 0),
+		DaysInMonth:    time.Date(year, t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day(),
+		DSTTransitions: dstTransitions(year, t.Location()),
+	}
+}
+
+// dstTransitions lists the offset changes in loc during year, walking the
+// zone periods with ZoneBounds.
+func dstTransitions(year int, loc *time.Location) []DSTTransition {
+	transitions := []DSTTransition{}
+	t := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	end := time.Date(year+1, time.January, 1, 0, 0, 0, 0, loc)
+	for {
+		_, next := t.ZoneBounds()
+		if next.IsZero() || !next.Before(end) {
+			return transitions
+		}
+		transitions = append(transitions, DSTTransition{
+			Time:         next.Format(time.RFC3339),
+			OffsetBefore: t.Format("-07:00"),
+			OffsetAfter:  next.Format("-07:00"),
+			DST:          next.IsDST(),
+		})
+		t = next
+	}
+}
@@ -0,0 +1,22 @@
+// Command time-tool serves time-tool on its own. kube-mcp-tools serves it together
+// with the other example tools.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	timetool "time-tool"
+)
+
+func main() {
+	s, err := timetool.New()
+	if err != nil {
+		slog.Error("starting time-tool", "err", err)
+		os.Exit(1)
+	}
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,93 @@
+package timetool
+
+import (
+	"context"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// ConvertRequest represents the incoming request body for /convert
+type ConvertRequest struct {
+	Timestamp string `json:"timestamp"`
+	// InputFormat is a named format, "unix", "unix_ms" or a Go layout. When
+	// empty the format is detected.
+	InputFormat string `json:"input_format,omitempty"`
+	// InputTimezone is the zone for timestamps that carry none, default UTC.
+	InputTimezone string `json:"input_timezone,omitempty"`
+	Timezone      string `json:"timezone,omitempty"` // output zone, default UTC
+	Format        string `json:"format,omitempty"`   // output format, default rfc3339
+	// Timezones additionally renders the converted time in each zone.
+	Timezones []string `json:"timezones,omitempty"`
+	// Reference is the time "relative" output is measured from, in any
+	// supported format; default now.
+	Reference string `json:"reference,omitempty"`
+	Details   bool   `json:"details,omitempty"` // include calendar metadata in the output zone
+}
+
+// ConvertResponse represents the outgoing response body for /convert
+type ConvertResponse struct {
+	Time        string           `json:"time"`
+	Timezone    string           `json:"timezone"`
+	Unix        int64            `json:"unix"`
+	InputFormat string           `json:"input_format"`    // the format the timestamp was parsed as
+	Times       []ZoneTime       `json:"times,omitempty"` // one entry per requested timezones element
+	Details     *CalendarDetails `json:"details,omitempty"`
+}
+
+func convert(ctx context.Context, req ConvertRequest) (ConvertResponse, error) {
+	if req.Timestamp == "" {
+		return ConvertResponse{}, toolserver.BadRequest("timestamp is required")
+	}
+
+	inputLoc, err := loadLocation(req.InputTimezone)
+	if err != nil {
+		return ConvertResponse{}, toolserver.BadRequest("invalid input_timezone: %v", err)
+	}
+	loc, err := loadLocation(req.Timezone)
+	if err != nil {
+		return ConvertResponse{}, toolserver.BadRequest("invalid timezone: %v", err)
+	}
+
+	t, inputFormat, err := parseTimestamp(req.Timestamp, req.InputFormat, inputLoc)
+	if err != nil {
+		return ConvertResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	ref := time.Now()
+	if req.Reference != "" {
+		if ref, _, err = parseTimestamp(req.Reference, "", inputLoc); err != nil {
+			return ConvertResponse{}, toolserver.BadRequest("reference: %v", err)
+		}
+	}
+
+	t = t.In(loc)
+	formatted, err := formatTime(t, req.Format, ref)
+	if err != nil {
+		return ConvertResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	resp := ConvertResponse{
+		Time:        formatted,
+		Timezone:    loc.String(),
+		Unix:        t.Unix(),
+		InputFormat: inputFormat,
+	}
+	if len(req.Timezones) > 0 {
+		resp.Times = zoneTimes(t, req.Timezones, req.Format, ref)
+	}
+	if req.Details {
+		details := calendarDetails(t)
+		resp.Details = &details
+	}
+
+	return resp, nil
+}
+
+// loadLocation loads an IANA zone name, defaulting to UTC.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		name = "UTC"
+	}
+	return time.LoadLocation(name)
+}
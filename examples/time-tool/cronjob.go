@@ -0,0 +1,209 @@
+package timetool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultPreviewCount = 5
+	maxPreviewCount     = 100
+
+	// maxMissedRuns mirrors the CronJob controller, which gives up on a
+	// CronJob with more than 100 missed start times.
+	maxMissedRuns = 100
+
+	// scheduleSlack is how late a run may be created before it is counted as
+	// missed; the controller normally creates Jobs within a few seconds.
+	scheduleSlack = time.Minute
+)
+
+// CronJobPreviewRequest represents the incoming request body for /cronjob-preview
+type CronJobPreviewRequest struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Count     int    `json:"count,omitempty"` // previous and next runs to list, default 5
+}
+
+// CronJobPreviewResponse represents the outgoing response body for
+// /cronjob-preview. Run times are RFC 3339 in the CronJob's timezone.
+type CronJobPreviewResponse struct {
+	Namespace               string   `json:"namespace"`
+	Name                    string   `json:"name"`
+	Schedule                string   `json:"schedule"`
+	TimeZone                string   `json:"time_zone"` // spec.timeZone, or the controller's default (UTC)
+	StartingDeadlineSeconds *int64   `json:"starting_deadline_seconds,omitempty"`
+	Suspended               bool     `json:"suspended"`
+	Active                  int      `json:"active"`
+	LastScheduleTime        string   `json:"last_schedule_time,omitempty"`
+	LastSuccessfulTime      string   `json:"last_successful_time,omitempty"`
+	PreviousRuns            []string `json:"previous_runs"`
+	NextRuns                []string `json:"next_runs"`
+	MissedRuns              int      `json:"missed_runs"` // scheduled since last_schedule_time but not started
+	LikelyMissed            bool     `json:"likely_missed"`
+	Message                 string   `json:"message,omitempty"`
+	RetriedTimes            int      `json:"retried_times,omitempty"` // API calls retried after transient errors
+}
+
+func cronJobPreview(ctx context.Context, req CronJobPreviewRequest) (CronJobPreviewResponse, error) {
+	if req.Name == "" {
+		return CronJobPreviewResponse{}, toolserver.BadRequest("name is required")
+	}
+	if req.Namespace == "" {
+		req.Namespace = "default"
+	}
+	clientset, err := kubeClient.ForNamespace(req.Namespace)
+	if err != nil {
+		return CronJobPreviewResponse{}, err
+	}
+	if req.Count == 0 {
+		req.Count = defaultPreviewCount
+	}
+	if req.Count < 1 || req.Count > maxPreviewCount {
+		return CronJobPreviewResponse{}, toolserver.BadRequest("count must be between 1 and %d", maxPreviewCount)
+	}
+
+	resp := CronJobPreviewResponse{Namespace: req.Namespace, Name: req.Name}
+
+	ctx = kube.CountRetries(ctx)
+	cj, err := kube.Retry(ctx, func(ctx context.Context) (*batchv1.CronJob, error) {
+		return clientset.BatchV1().CronJobs(req.Namespace).Get(ctx, req.Name, metav1.GetOptions{})
+	})
+	if err != nil {
+		return CronJobPreviewResponse{}, kube.Errorf(err, "failed to get cronjob")
+	}
+	resp.RetriedTimes = kube.Retried(ctx)
+
+	resp.Schedule = cj.Spec.Schedule
+	resp.StartingDeadlineSeconds = cj.Spec.StartingDeadlineSeconds
+	resp.Suspended = cj.Spec.Suspend != nil && *cj.Spec.Suspend
+	resp.Active = len(cj.Status.Active)
+
+	// Like the controller, interpret the schedule in spec.timeZone when set
+	// and in UTC otherwise. A TZ= or CRON_TZ= prefix in the schedule itself
+	// is handled by the parser.
+	loc := time.UTC
+	if cj.Spec.TimeZone != nil {
+		if loc, err = time.LoadLocation(*cj.Spec.TimeZone); err != nil {
+			return CronJobPreviewResponse{}, toolserver.Errorf(http.StatusUnprocessableEntity, "invalid timeZone: %v", err)
+		}
+	}
+	resp.TimeZone = loc.String()
+
+	sched, err := cron.ParseStandard(cj.Spec.Schedule)
+	if err != nil {
+		return CronJobPreviewResponse{}, toolserver.Errorf(http.StatusUnprocessableEntity, "invalid schedule: %v", err)
+	}
+
+	now := time.Now().In(loc)
+	created := cj.CreationTimestamp.In(loc)
+	if cj.Status.LastScheduleTime != nil {
+		resp.LastScheduleTime = cj.Status.LastScheduleTime.In(loc).Format(time.RFC3339)
+	}
+	if cj.Status.LastSuccessfulTime != nil {
+		resp.LastSuccessfulTime = cj.Status.LastSuccessfulTime.In(loc).Format(time.RFC3339)
+	}
+
+	resp.PreviousRuns = formatRuns(previousRuns(sched, created, now, req.Count), loc)
+	resp.NextRuns = formatRuns(nextRuns(sched, now, req.Count), loc)
+
+	// Missed runs are the schedule times after the last one the controller
+	// acted on (or creation) that are older than scheduleSlack.
+	since := created
+	if cj.Status.LastScheduleTime != nil {
+		since = cj.Status.LastScheduleTime.In(loc)
+	}
+	missed := scheduledBetween(sched, since, now.Add(-scheduleSlack), maxMissedRuns+1)
+	resp.MissedRuns = len(missed)
+	resp.LikelyMissed = resp.MissedRuns > 0 && !resp.Suspended
+	resp.Message = missedRunsMessage(resp, missed, now)
+
+	return resp, nil
+}
+
+// missedRunsMessage explains what the controller will do about missed runs.
+func missedRunsMessage(resp CronJobPreviewResponse, missed []time.Time, now time.Time) string {
+	switch {
+	case len(missed) == 0:
+		return ""
+	case resp.Suspended:
+		return "cronjob is suspended; scheduled runs are skipped until it is resumed"
+	case len(missed) > maxMissedRuns:
+		if resp.StartingDeadlineSeconds == nil {
+			return fmt.Sprintf("more than %d missed start times; the controller will not start this job until startingDeadlineSeconds is set", maxMissedRuns)
+		}
+		return fmt.Sprintf("more than %d missed start times", maxMissedRuns)
+	case resp.StartingDeadlineSeconds != nil:
+		deadline := time.Duration(*resp.StartingDeadlineSeconds) * time.Second
+		if latest := missed[len(missed)-1]; now.Sub(latest) > deadline {
+			return fmt.Sprintf("missed runs are older than startingDeadlineSeconds (%ds) and will not be started", *resp.StartingDeadlineSeconds)
+		}
+		return "the most recent missed run is within startingDeadlineSeconds and may still be started"
+	default:
+		return "runs were scheduled but not started; check the controller and Job events"
+	}
+}
+
+// nextRuns returns the next count schedule times after from.
+func nextRuns(sched cron.Schedule, from time.Time, count int) []time.Time {
+	runs := make([]time.Time, 0, count)
+	for t := from; len(runs) < count; {
+		if t = sched.Next(t); t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
+
+// previousRuns returns up to count schedule times in (notBefore, now],
+// newest first. cron schedules can only be walked forwards, so the window
+// grows until it holds enough runs or reaches notBefore or a year back.
+func previousRuns(sched cron.Schedule, notBefore, now time.Time, count int) []time.Time {
+	var runs []time.Time
+	for window := time.Hour; ; window *= 4 {
+		start := now.Add(-window)
+		if start.Before(notBefore) {
+			start = notBefore
+		}
+		runs = scheduledBetween(sched, start, now, 0)
+		if len(runs) >= count || !start.After(notBefore) || window > 366*24*time.Hour {
+			break
+		}
+	}
+	if len(runs) > count {
+		runs = runs[len(runs)-count:]
+	}
+	slices.Reverse(runs)
+	return runs
+}
+
+// scheduledBetween returns schedule times in (after, until], oldest first,
+// stopping at limit when limit is positive.
+func scheduledBetween(sched cron.Schedule, after, until time.Time, limit int) []time.Time {
+	var runs []time.Time
+	for t := sched.Next(after); !t.IsZero() && !t.After(until); t = sched.Next(t) {
+		runs = append(runs, t)
+		if limit > 0 && len(runs) == limit {
+			break
+		}
+	}
+	return runs
+}
+
+func formatRuns(runs []time.Time, loc *time.Location) []string {
+	out := make([]string, len(runs))
+	for i, t := range runs {
+		out[i] = t.In(loc).Format(time.RFC3339)
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package timetool
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// DiffRequest represents the incoming request body for /diff. Both
+// timestamps accept any format /convert does.
+type DiffRequest struct {
+	From          string `json:"from"`
+	To            string `json:"to"`
+	InputFormat   string `json:"input_format,omitempty"`
+	InputTimezone string `json:"input_timezone,omitempty"` // zone for timestamps that carry none, default UTC
+}
+
+// DiffResponse represents the outgoing response body for /diff. The
+// difference is to minus from, so it is negative when to is earlier.
+type DiffResponse struct {
+	From     string  `json:"from"` // RFC 3339, in UTC
+	To       string  `json:"to"`
+	Seconds  float64 `json:"seconds"`
+	Duration string  `json:"duration"` // Go duration string, e.g. "76h0m0s"
+	Human    string  `json:"human"`    // e.g. "3 days 4 hours"
+}
+
+func diff(ctx context.Context, req DiffRequest) (DiffResponse, error) {
+	if req.From == "" || req.To == "" {
+		return DiffResponse{}, toolserver.BadRequest("from and to are required")
+	}
+
+	loc, err := loadLocation(req.InputTimezone)
+	if err != nil {
+		return DiffResponse{}, toolserver.BadRequest("invalid input_timezone: %v", err)
+	}
+
+	from, _, err := parseTimestamp(req.From, req.InputFormat, loc)
+	if err != nil {
+		return DiffResponse{}, toolserver.BadRequest("from: %v", err)
+	}
+	to, _, err := parseTimestamp(req.To, req.InputFormat, loc)
+	if err != nil {
+		return DiffResponse{}, toolserver.BadRequest("to: %v", err)
+	}
+
+	d := to.Sub(from)
+	return DiffResponse{
+		From:     from.UTC().Format(time.RFC3339Nano),
+		To:       to.UTC().Format(time.RFC3339Nano),
+		Seconds:  d.Seconds(),
+		Duration: d.String(),
+		Human:    humanizeDuration(d),
+	}, nil
+}
+
+// units are the steps humanized durations are expressed in, largest first.
+var units = []struct {
+	name string
+	size time.Duration
+}{
+	{"day", 24 * time.Hour},
+	{"hour", time.Hour},
+	{"minute", time.Minute},
+	{"second", time.Second},
+}
+
+// humanizeDuration renders d in its largest unit and the next one down,
+// e.g. "3 days 4 hours" or "-2 minutes 5 seconds". Days are 24 hours, ignoring
+// DST, which matches how the durations are computed.
+func humanizeDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+
+	for i, u := range units {
+		n := d / u.size
+		if n == 0 {
+			continue
+		}
+		out := sign + plural(int64(n), u.name)
+		if i+1 < len(units) {
+			if m := (d % u.size) / units[i+1].size; m > 0 {
+				out += " " + plural(int64(m), units[i+1].name)
+			}
+		}
+		return out
+	}
+	return "0 seconds"
+}
+
+// relativeTime renders t relative to ref in its largest unit, e.g.
+// "42 minutes ago" or "in 3 days", as people describe pod ages and
+// certificate expirations.
+func relativeTime(t, ref time.Time) string {
+	d := t.Sub(ref)
+	abs := max(d, -d)
+	for _, u := range units {
+		if n := abs / u.size; n > 0 {
+			if d < 0 {
+				return plural(int64(n), u.name) + " ago"
+			}
+			return "in " + plural(int64(n), u.name)
+		}
+	}
+	return "now"
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,176 @@
+package timetool
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// namedLayouts maps the format names accepted by /time and /convert to Go
+// layouts. The log layouts cover Apache/nginx access logs (clf), syslog and
+// Go's standard logger.
+var namedLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc822":      time.RFC822,
+	"rfc822z":     time.RFC822Z,
+	"rfc850":      time.RFC850,
+	"ansic":       time.ANSIC,
+	"unixdate":    time.UnixDate,
+	"rubydate":    time.RubyDate,
+	"kitchen":     time.Kitchen,
+	"human":       "2006-01-02 15:04:05 MST",
+	"datetime":    time.DateTime,
+	"date":        time.DateOnly,
+	"clf":         "02/Jan/2006:15:04:05 -0700",
+	"syslog":      time.Stamp,
+	"golog":       "2006/01/02 15:04:05",
+}
+
+// parseOrder is the order layouts are tried in when no input format is
+// given. Unambiguous, zone-qualified layouts come first.
+var parseOrder = []string{
+	"rfc3339nano", "rfc1123z", "rfc1123", "rfc850", "rfc822z", "rfc822",
+	"rubydate", "unixdate", "ansic", "clf", "human", "datetime", "golog",
+	"date", "syslog",
+}
+
+// parseTimestamp parses input as format: a named format, "unix", "unix_ms",
+// a strftime pattern or a Go layout. With no format it detects unix timestamps by magnitude
+// (seconds, milliseconds, microseconds or nanoseconds) and otherwise tries
+// each named layout. Inputs without a zone are read in loc. The detected
+// format name is returned alongside the time.
+func parseTimestamp(input, format string, loc *time.Location) (time.Time, string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return time.Time{}, "", fmt.Errorf("timestamp is empty")
+	}
+
+	switch name := strings.ToLower(format); name {
+	case "":
+	case "unix", "unix_ms":
+		t, err := parseUnix(input, name == "unix_ms")
+		return t, name, err
+	default:
+		layout, ok := namedLayouts[name]
+		switch {
+		case ok:
+		case isStrftime(format):
+			var err error
+			if layout, err = strftimeToLayout(format); err != nil {
+				return time.Time{}, "", err
+			}
+			name = "strftime"
+		default:
+			layout, name = format, "layout"
+		}
+		t, err := parseInLayout(input, layout, loc)
+		if err != nil {
+			return time.Time{}, "", fmt.Errorf("cannot parse %q as %s: %v", input, format, err)
+		}
+		return t, name, nil
+	}
+
+	if _, err := strconv.ParseFloat(input, 64); err == nil {
+		t, err := parseUnix(input, false)
+		return t, "unix", err
+	}
+	for _, name := range parseOrder {
+		if t, err := parseInLayout(input, namedLayouts[name], loc); err == nil {
+			return t, name, nil
+		}
+	}
+	return time.Time{}, "", fmt.Errorf("unrecognized timestamp %q; set input_format to a named format or Go layout", input)
+}
+
+// parseInLayout parses input in loc. Layouts without a year, such as syslog
+// timestamps, get the current year.
+func parseInLayout(input, layout string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation(layout, input, loc)
+	if err != nil {
+		return t, err
+	}
+	if t.Year() == 0 {
+		t = t.AddDate(time.Now().In(loc).Year(), 0, 0)
+	}
+	return t, nil
+}
+
+// parseUnix parses a unix timestamp with an optional fraction. Unless millis
+// is set, integers are scaled by magnitude so that millisecond, microsecond
+// and nanosecond timestamps from logs and tracing tools parse as well.
+func parseUnix(input string, millis bool) (time.Time, error) {
+	if millis {
+		ms, err := strconv.ParseInt(input, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix milliseconds: %q", input)
+		}
+		return time.UnixMilli(ms).UTC(), nil
+	}
+
+	if strings.ContainsAny(input, ".eE") {
+		sec, err := strconv.ParseFloat(input, 64)
+		if err != nil || math.IsInf(sec, 0) || math.IsNaN(sec) {
+			return time.Time{}, fmt.Errorf("invalid unix timestamp: %q", input)
+		}
+		whole, frac := math.Modf(sec)
+		return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+	}
+
+	n, err := strconv.ParseInt(input, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid unix timestamp: %q", input)
+	}
+	switch abs := max(n, -n); {
+	case abs < 1e11:
+		return time.Unix(n, 0).UTC(), nil
+	case abs < 1e14:
+		return time.UnixMilli(n).UTC(), nil
+	case abs < 1e17:
+		return time.UnixMicro(n).UTC(), nil
+	default:
+		return time.Unix(0, n).UTC(), nil
+	}
+}
+
+// formatTime renders t as format: a named format, "unix", "unix_ms",
+// "relative" (to ref), a strftime pattern or a Go layout. An empty format is RFC 3339.
+func formatTime(t time.Time, format string, ref time.Time) (string, error) {
+	switch name := strings.ToLower(format); name {
+	case "":
+		return t.Format(time.RFC3339), nil
+	case "relative":
+		return relativeTime(t, ref), nil
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixMilli(), 10), nil
+	default:
+		if layout, ok := namedLayouts[name]; ok {
+			return t.Format(layout), nil
+		}
+		if isStrftime(format) {
+			return formatStrftime(t, format)
+		}
+		if !isGoLayout(format) {
+			return "", fmt.Errorf("unsupported format: %s", format)
+		}
+		return t.Format(format), nil
+	}
+}
+
+// isGoLayout reports whether format contains at least one element of Go's
+// reference time, so that typos in format names are reported rather than
+// echoed back verbatim.
+func isGoLayout(format string) bool {
+	for _, elem := range []string{"2006", "06", "Jan", "01", "02", "_2", "15", "03", "04", "05", "PM", "MST", "-07", "Z07"} {
+		if strings.Contains(format, elem) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,7 @@
+package timetool
+
+import "github.com/atippey/kube-mcp/pkg/kube"
+
+// kubeClient is loaded on first use. Without a reachable cluster, the
+// Kubernetes-aware endpoints fail with 503 rather than the whole tool.
+var kubeClient = kube.New("time-tool")
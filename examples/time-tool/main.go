@@ -1,59 +1,52 @@
-package main
+// Package timetool implements time-tool, which formats, converts and
+// compares times and previews CronJob schedules.
+package timetool
 
 import (
-	"encoding/json"
-	"fmt"
-	"log"
-	"net/http"
-	"os"
+	"context"
 	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
 )
 
 type TimeRequest struct {
-	Timezone string `json:"timezone"`
-	Format   string `json:"format"`
+	Timezone  string   `json:"timezone"`
+	Timezones []string `json:"timezones,omitempty"` // also render the time in each of these
+	Format    string   `json:"format"`
+	Details   bool     `json:"details,omitempty"` // include calendar metadata
 }
 
 type TimeResponse struct {
-	Time     string `json:"time"`
-	Timezone string `json:"timezone"`
-	Error    string `json:"error,omitempty"`
-}
-
-func main() {
-	http.HandleFunc("/health", handleHealth)
-	http.HandleFunc("/time", handleTime)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	log.Printf("Starting time-tool server on :%s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
-	}
+	Time     string           `json:"time"`
+	Timezone string           `json:"timezone"`
+	Times    []ZoneTime       `json:"times,omitempty"` // one entry per requested timezones element
+	Details  *CalendarDetails `json:"details,omitempty"`
 }
 
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+// New returns the time-tool server. The Kubernetes client, used by
+// /cronjob-preview, is optional.
+func New() (*toolserver.Server, error) {
+	s := toolserver.New("time-tool")
+	s.AddDiagnostics("kubernetes", kubeClient.Diagnostics)
+	s.AddWarmup("zoneinfo", warmZoneNames)
+	toolserver.Register(s, "/time", currentTime,
+		toolserver.Name("time-tool"), toolserver.Describe("Return the current time in a specified timezone and format."))
+	toolserver.Register(s, "/convert", convert,
+		toolserver.Name("time-convert"), toolserver.Describe("Parse a timestamp and re-emit it in another format and timezone."))
+	toolserver.Register(s, "/diff", diff,
+		toolserver.Name("time-diff"), toolserver.Describe("Compute the difference between two timestamps."))
+	toolserver.Register(s, "/timezones", timezones,
+		toolserver.Describe("List valid IANA timezone names with their current UTC offset."), toolserver.AllowGet())
+	toolserver.Register(s, "/range", timeRange,
+		toolserver.Name("time-range"), toolserver.Describe("Generate timestamps from start to end at a fixed step."))
+	toolserver.Register(s, "/cronjob-preview", cronJobPreview,
+		toolserver.Describe("Preview a Kubernetes CronJob's previous and next run times."),
+		toolserver.RBAC("get cronjobs.batch"))
+
+	return s, nil
 }
 
-func handleTime(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req TimeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(TimeResponse{Error: "invalid request body"})
-		return
-	}
-
+func currentTime(ctx context.Context, req TimeRequest) (TimeResponse, error) {
 	targetTimezone := req.Timezone
 	if targetTimezone == "" {
 		targetTimezone = "UTC"
@@ -61,29 +54,26 @@ func handleTime(w http.ResponseWriter, r *http.Request) {
 
 	loc, err := time.LoadLocation(targetTimezone)
 	if err != nil {
-		json.NewEncoder(w).Encode(TimeResponse{Error: fmt.Sprintf("invalid timezone: %v", err)})
-		return
+		return TimeResponse{}, toolserver.BadRequest("invalid timezone: %v", err)
 	}
 
 	now := time.Now().In(loc)
-	var formattedTime string
-
-	switch req.Format {
-	case "rfc3339", "RFC3339", "":
-		formattedTime = now.Format(time.RFC3339)
-	case "unix", "Unix":
-		formattedTime = fmt.Sprintf("%d", now.Unix())
-	case "human", "Human":
-		formattedTime = now.Format("2006-01-02 15:04:05 MST")
-	default:
-		json.NewEncoder(w).Encode(TimeResponse{Error: fmt.Sprintf("unsupported format: %s", req.Format)})
-		return
+	formattedTime, err := formatTime(now, req.Format, now)
+	if err != nil {
+		return TimeResponse{}, toolserver.BadRequest("%v", err)
 	}
 
 	resp := TimeResponse{
 		Time:     formattedTime,
 		Timezone: targetTimezone,
 	}
+	if len(req.Timezones) > 0 {
+		resp.Times = zoneTimes(now, req.Timezones, req.Format, now)
+	}
+	if req.Details {
+		details := calendarDetails(now)
+		resp.Details = &details
+	}
 
-	json.NewEncoder(w).Encode(resp)
+	return resp, nil
 }
@@ -0,0 +1,205 @@
+package timetool
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2024, 1, 30, 20, 4, 5, 0, time.UTC)
+	tests := []struct {
+		input  string
+		format string
+		name   string
+	}{
+		{"1706645045", "", "unix"},
+		{"1706645045000", "", "unix"},
+		{"1706645045000000000", "", "unix"},
+		{"1706645045000", "unix_ms", "unix_ms"},
+		{"2024-01-30T15:04:05-05:00", "", "rfc3339nano"},
+		{"Tue, 30 Jan 2024 20:04:05 GMT", "", "rfc1123"},
+		{"30/Jan/2024:15:04:05 -0500", "", "clf"},
+		{"2024-01-30 20:04:05", "", "datetime"},
+		{"30.01.2024 20:04", "02.01.2006 15:04", "layout"},
+		{"30.01.2024 20:04", "%d.%m.%Y %H:%M", "strftime"},
+	}
+	for _, tt := range tests {
+		got, name, err := parseTimestamp(tt.input, tt.format, time.UTC)
+		if err != nil {
+			t.Errorf("parseTimestamp(%q, %q) error = %v", tt.input, tt.format, err)
+			continue
+		}
+		wantTime := want
+		if tt.name == "layout" || tt.name == "strftime" {
+			wantTime = want.Truncate(time.Minute)
+		}
+		if !got.Equal(wantTime) || name != tt.name {
+			t.Errorf("parseTimestamp(%q, %q) = %v, %q, want %v, %q", tt.input, tt.format, got, name, wantTime, tt.name)
+		}
+	}
+
+	if _, _, err := parseTimestamp("yesterday-ish", "", time.UTC); err == nil {
+		t.Error("parseTimestamp() accepted an unrecognized timestamp")
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	ts := time.Date(2024, 1, 30, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "2024-01-30T15:04:05Z"},
+		{"Unix", "1706627045"},
+		{"unix_ms", "1706627045000"},
+		{"clf", "30/Jan/2024:15:04:05 +0000"},
+		{"Mon 2 Jan", "Tue 30 Jan"},
+		{"%Y-%m-%d %H:%M", "2024-01-30 15:04"},
+		{"%a %-d %b, day %j, %s", "Tue 30 Jan, day 030, 1706627045"},
+		{"%:z %u %%", "+00:00 2 %"},
+	}
+	for _, tt := range tests {
+		if got, err := formatTime(ts, tt.format, ts); err != nil || got != tt.want {
+			t.Errorf("formatTime(%q) = %q, %v, want %q", tt.format, got, err, tt.want)
+		}
+	}
+	if _, err := formatTime(ts, "iso", ts); err == nil {
+		t.Error("formatTime() accepted an unknown format name")
+	}
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{76 * time.Hour, "3 days 4 hours"},
+		{24*time.Hour + 5*time.Minute, "1 day"},
+		{-(2*time.Minute + 5*time.Second), "-2 minutes 5 seconds"},
+		{time.Hour + time.Minute, "1 hour 1 minute"},
+		{500 * time.Millisecond, "0 seconds"},
+	}
+	for _, tt := range tests {
+		if got := humanizeDuration(tt.d); got != tt.want {
+			t.Errorf("humanizeDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestCronRuns(t *testing.T) {
+	sched, err := cron.ParseStandard("*/15 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 30, 10, 7, 0, 0, time.UTC)
+
+	next := nextRuns(sched, now, 2)
+	if len(next) != 2 || !next[0].Equal(now.Add(8*time.Minute)) || !next[1].Equal(now.Add(23*time.Minute)) {
+		t.Errorf("nextRuns() = %v", next)
+	}
+
+	prev := previousRuns(sched, now.Add(-24*time.Hour), now, 3)
+	want := []time.Time{now.Add(-7 * time.Minute), now.Add(-22 * time.Minute), now.Add(-37 * time.Minute)}
+	if !slices.EqualFunc(prev, want, time.Time.Equal) {
+		t.Errorf("previousRuns() = %v, want %v", prev, want)
+	}
+
+	// Runs before creation are not reported.
+	if prev := previousRuns(sched, now.Add(-10*time.Minute), now, 3); len(prev) != 1 {
+		t.Errorf("previousRuns() after creation = %v, want one run", prev)
+	}
+
+	if missed := scheduledBetween(sched, now.Add(-time.Hour), now, 2); len(missed) != 2 {
+		t.Errorf("scheduledBetween() with limit = %v, want 2 runs", missed)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	ref := time.Date(2024, 1, 30, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{ref.Add(-42 * time.Minute), "42 minutes ago"},
+		{ref.Add(76 * time.Hour), "in 3 days"},
+		{ref.Add(-time.Second), "1 second ago"},
+		{ref, "now"},
+	}
+	for _, tt := range tests {
+		if got := relativeTime(tt.t, ref); got != tt.want {
+			t.Errorf("relativeTime(%v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestCalendarDetails(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("zoneinfo not available: %v", err)
+	}
+
+	d := calendarDetails(time.Date(2024, 12, 30, 12, 0, 0, 0, berlin))
+	if d.ISOYear != 2025 || d.ISOWeek != 1 || d.DayOfYear != 365 || d.Quarter != 4 || !d.LeapYear || d.DaysInMonth != 31 {
+		t.Errorf("calendarDetails() = %+v", d)
+	}
+
+	want := []DSTTransition{
+		{Time: "2024-03-31T03:00:00+02:00", OffsetBefore: "+01:00", OffsetAfter: "+02:00", DST: true},
+		{Time: "2024-10-27T02:00:00+01:00", OffsetBefore: "+02:00", OffsetAfter: "+01:00", DST: false},
+	}
+	if !slices.Equal(d.DSTTransitions, want) {
+		t.Errorf("DSTTransitions = %+v, want %+v", d.DSTTransitions, want)
+	}
+	if got := dstTransitions(2024, time.UTC); len(got) != 0 {
+		t.Errorf("dstTransitions(UTC) = %+v, want none", got)
+	}
+}
+
+func TestRangeTicks(t *testing.T) {
+	start := time.Date(2024, 1, 30, 10, 7, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	step, err := parseStep("15m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticks, truncated := rangeTicks(start, end, step, true, 10)
+	want := []time.Time{
+		start.Add(8 * time.Minute), start.Add(23 * time.Minute),
+		start.Add(38 * time.Minute), start.Add(53 * time.Minute),
+	}
+	if !slices.EqualFunc(ticks, want, time.Time.Equal) || truncated {
+		t.Errorf("rangeTicks() aligned = %v, %v, want %v", ticks, truncated, want)
+	}
+
+	// Steps that do not divide a day align to the epoch too.
+	step7h, _ := parseStep("7h")
+	ticks, _ = rangeTicks(start, start.Add(12*time.Hour), step7h, true, 10)
+	want = []time.Time{time.Date(2024, 1, 30, 13, 0, 0, 0, time.UTC), time.Date(2024, 1, 30, 20, 0, 0, 0, time.UTC)}
+	if !slices.EqualFunc(ticks, want, time.Time.Equal) {
+		t.Errorf("rangeTicks() aligned to 7h = %v, want %v", ticks, want)
+	}
+
+	if ticks, truncated := rangeTicks(start, end, step, false, 2); len(ticks) != 2 || !ticks[0].Equal(start) || !truncated {
+		t.Errorf("rangeTicks() limited = %v, %v", ticks, truncated)
+	}
+
+	// Day steps follow the calendar across a DST change.
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("zoneinfo not available: %v", err)
+	}
+	day, _ := parseStep("1d")
+	from := time.Date(2024, 3, 30, 9, 0, 0, 0, berlin)
+	ticks, _ = rangeTicks(from, from.Add(48*time.Hour), day, false, 10)
+	if len(ticks) != 3 || ticks[1].Hour() != 9 || ticks[2].Hour() != 9 {
+		t.Errorf("rangeTicks() daily = %v, want 09:00 on three days", ticks)
+	}
+
+	if _, err := parseStep("-5m"); err == nil {
+		t.Error("parseStep() accepted a negative step")
+	}
+}
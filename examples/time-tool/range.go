@@ -0,0 +1,149 @@
+package timetool
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+const (
+	defaultRangeLimit = 1000
+	maxRangeLimit     = 10000
+)
+
+// RangeRequest represents the incoming request body for /range
+type RangeRequest struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Step is a Go duration ("15m", "1h30m") or a number of calendar days or
+	// weeks ("1d", "2w"), which follow DST in the timezone.
+	Step string `json:"step"`
+	// Align moves the first tick to the next multiple of step: a multiple of
+	// the duration since the unix epoch, as Prometheus does, or local
+	// midnight for day and week steps.
+	Align         bool   `json:"align,omitempty"`
+	Limit         int    `json:"limit,omitempty"` // maximum ticks, default 1000
+	InputFormat   string `json:"input_format,omitempty"`
+	InputTimezone string `json:"input_timezone,omitempty"` // zone for start/end that carry none, default UTC
+	Timezone      string `json:"timezone,omitempty"`       // output zone, and the zone calendar steps follow
+	Format        string `json:"format,omitempty"`
+}
+
+// RangeResponse represents the outgoing response body for /range. Ticks run
+// from the (aligned) start up to and including end.
+type RangeResponse struct {
+	Ticks     []string `json:"ticks"`
+	Count     int      `json:"count"`
+	Truncated bool     `json:"truncated"` // more ticks remain before end
+}
+
+func timeRange(ctx context.Context, req RangeRequest) (RangeResponse, error) {
+	if req.Start == "" || req.End == "" || req.Step == "" {
+		return RangeResponse{}, toolserver.BadRequest("start, end and step are required")
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultRangeLimit
+	}
+	if req.Limit < 1 || req.Limit > maxRangeLimit {
+		return RangeResponse{}, toolserver.BadRequest("limit must be between 1 and %d", maxRangeLimit)
+	}
+
+	inputLoc, err := loadLocation(req.InputTimezone)
+	if err != nil {
+		return RangeResponse{}, toolserver.BadRequest("invalid input_timezone: %v", err)
+	}
+	loc, err := loadLocation(req.Timezone)
+	if err != nil {
+		return RangeResponse{}, toolserver.BadRequest("invalid timezone: %v", err)
+	}
+
+	start, _, err := parseTimestamp(req.Start, req.InputFormat, inputLoc)
+	if err != nil {
+		return RangeResponse{}, toolserver.BadRequest("start: %v", err)
+	}
+	end, _, err := parseTimestamp(req.End, req.InputFormat, inputLoc)
+	if err != nil {
+		return RangeResponse{}, toolserver.BadRequest("end: %v", err)
+	}
+	if end.Before(start) {
+		return RangeResponse{}, toolserver.BadRequest("end is before start")
+	}
+
+	step, err := parseStep(req.Step)
+	if err != nil {
+		return RangeResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	ticks, truncated := rangeTicks(start.In(loc), end, step, req.Align, req.Limit)
+	resp := RangeResponse{Ticks: make([]string, 0, len(ticks)), Truncated: truncated}
+	for _, t := range ticks {
+		formatted, err := formatTime(t, req.Format, start)
+		if err != nil {
+			return RangeResponse{}, toolserver.BadRequest("%v", err)
+		}
+		resp.Ticks = append(resp.Ticks, formatted)
+	}
+	resp.Count = len(resp.Ticks)
+
+	return resp, nil
+}
+
+// rangeStep is either a fixed duration or a number of calendar days.
+type rangeStep struct {
+	duration time.Duration
+	days     int
+}
+
+func (s rangeStep) next(t time.Time) time.Time {
+	if s.days > 0 {
+		return t.AddDate(0, 0, s.days)
+	}
+	return t.Add(s.duration)
+}
+
+// parseStep parses a Go duration or a positive "<n>d" / "<n>w".
+func parseStep(step string) (rangeStep, error) {
+	for suffix, days := range map[string]int{"d": 1, "w": 7} {
+		if n, ok := strings.CutSuffix(step, suffix); ok {
+			if count, err := strconv.Atoi(n); err == nil && count > 0 {
+				return rangeStep{days: count * days}, nil
+			}
+		}
+	}
+	d, err := time.ParseDuration(step)
+	if err != nil || d <= 0 {
+		return rangeStep{}, fmt.Errorf("invalid step %q: use a positive Go duration (e.g. 15m) or days/weeks (e.g. 1d, 2w)", step)
+	}
+	return rangeStep{duration: d}, nil
+}
+
+// rangeTicks returns up to limit ticks from start (aligned if requested)
+// through end, and whether ticks were cut off by the limit.
+func rangeTicks(start, end time.Time, step rangeStep, align bool, limit int) ([]time.Time, bool) {
+	t := start
+	if align {
+		if step.days > 0 {
+			t = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+		} else {
+			// time.Truncate counts from the zero time, not the epoch.
+			epoch := time.Unix(0, 0).In(start.Location())
+			t = epoch.Add(start.Sub(epoch).Truncate(step.duration))
+		}
+		if t.Before(start) {
+			t = step.next(t)
+		}
+	}
+
+	var ticks []time.Time
+	for ; !t.After(end); t = step.next(t) {
+		if len(ticks) == limit {
+			return ticks, true
+		}
+		ticks = append(ticks, t)
+	}
+	return ticks, false
+}
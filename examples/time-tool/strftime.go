@@ -0,0 +1,139 @@
+package timetool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// strftimeLayouts maps strftime conversion characters to Go layouts.
+// Conversions Go layouts cannot express (%s, %u, %w) are handled by
+// formatStrftime and are not available for parsing.
+var strftimeLayouts = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'e': "_2",
+	'j': "002",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'f': "000000", // microseconds, as in Python; must follow %S and a "."
+	'p': "PM",
+	'b': "Jan",
+	'h': "Jan",
+	'B': "January",
+	'a': "Mon",
+	'A': "Monday",
+	'Z': "MST",
+	'z': "-0700",
+	'F': "2006-01-02",
+	'T': "15:04:05",
+	'R': "15:04",
+	'D': "01/02/06",
+	'x': "01/02/06",
+	'X': "15:04:05",
+	'c': "Mon Jan _2 15:04:05 2006",
+	'%': "%",
+}
+
+// unpaddedLayouts are the Go layouts for the "-" flag (%-d, %-m, %-I).
+var unpaddedLayouts = map[byte]string{
+	'd': "2",
+	'm': "1",
+	'I': "3",
+}
+
+// isStrftime reports whether format is a strftime pattern rather than a
+// named format or Go layout.
+func isStrftime(format string) bool {
+	return strings.Contains(format, "%")
+}
+
+// formatStrftime renders t with a strftime pattern. Each conversion is
+// formatted on its own, so literal text is never mistaken for part of a Go
+// layout.
+func formatStrftime(t time.Time, format string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		if i+1 == len(format) {
+			return "", fmt.Errorf("strftime pattern ends with %%")
+		}
+		i++
+		unpadded := format[i] == '-' && i+1 < len(format)
+		if unpadded {
+			i++
+		}
+
+		var out string
+		switch c := format[i]; c {
+		case 's':
+			out = strconv.FormatInt(t.Unix(), 10)
+		case 'u':
+			out = strconv.Itoa((int(t.Weekday())+6)%7 + 1)
+		case 'w':
+			out = strconv.Itoa(int(t.Weekday()))
+		case ':':
+			if i+1 == len(format) || format[i+1] != 'z' {
+				return "", fmt.Errorf("unsupported strftime conversion %%:")
+			}
+			i++
+			out = t.Format("-07:00")
+		case 'f':
+			out = t.Format(".000000")[1:]
+		default:
+			layout, ok := strftimeLayouts[c]
+			if !ok {
+				return "", fmt.Errorf("unsupported strftime conversion %%%c", c)
+			}
+			out = t.Format(layout)
+		}
+		if unpadded {
+			if trimmed := strings.TrimLeft(out, "0 "); trimmed != "" {
+				out = trimmed
+			} else {
+				out = "0"
+			}
+		}
+		b.WriteString(out)
+	}
+	return b.String(), nil
+}
+
+// strftimeToLayout translates a strftime pattern to a Go layout for parsing.
+func strftimeToLayout(format string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		if i+1 == len(format) {
+			return "", fmt.Errorf("strftime pattern ends with %%")
+		}
+		i++
+		layouts := strftimeLayouts
+		if format[i] == '-' && i+1 < len(format) {
+			i++
+			layouts = unpaddedLayouts
+		}
+		if format[i] == ':' && i+1 < len(format) && format[i+1] == 'z' {
+			i++
+			b.WriteString("-07:00")
+			continue
+		}
+		layout, ok := layouts[format[i]]
+		if !ok {
+			return "", fmt.Errorf("strftime conversion %%%c cannot be used for parsing", format[i])
+		}
+		b.WriteString(layout)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,94 @@
+package timetool
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// TimezonesRequest represents the incoming request body for /timezones
+type TimezonesRequest struct {
+	Filter string `json:"filter,omitempty"` // case-insensitive substring of the zone name
+}
+
+// TimezonesResponse represents the outgoing response body for /timezones
+type TimezonesResponse struct {
+	Timezones []ZoneTime `json:"timezones"`
+	Count     int        `json:"count"`
+}
+
+// zoneNames lists the zones on first use, or with $WARMUP at startup.
+var zoneNames = sync.OnceValues(listZoneNames)
+
+// warmZoneNames is the warm-up that lists the zones.
+func warmZoneNames(context.Context) error {
+	_, err := zoneNames()
+	return err
+}
+
+func timezones(ctx context.Context, req TimezonesRequest) (TimezonesResponse, error) {
+	names, err := zoneNames()
+	if err != nil {
+		return TimezonesResponse{}, err
+	}
+
+	now := time.Now()
+	filter := strings.ToLower(req.Filter)
+	resp := TimezonesResponse{Timezones: []ZoneTime{}}
+	for _, name := range names {
+		if filter != "" && !strings.Contains(strings.ToLower(name), filter) {
+			continue
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			continue
+		}
+		resp.Timezones = append(resp.Timezones, zoneTime(now.In(loc), "", now))
+	}
+	resp.Count = len(resp.Timezones)
+
+	return resp, nil
+}
+
+// listZoneNames walks the system zoneinfo database ($ZONEINFO or
+// /usr/share/zoneinfo, installed by the image's tzdata package). Go has no
+// API for listing zones, so this is the same set time.LoadLocation reads.
+func listZoneNames() ([]string, error) {
+	root := os.Getenv("ZONEINFO")
+	if root == "" {
+		root = "/usr/share/zoneinfo"
+	}
+
+	var names []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, _ := filepath.Rel(root, path)
+		if d.IsDir() {
+			// posix/ and right/ duplicate the whole tree.
+			if name == "posix" || name == "right" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Data files such as zone.tab, tzdata.zi and posixrules are lowercase
+		// or carry an extension; zone names start with an uppercase letter.
+		if !unicode.IsUpper(rune(filepath.Base(name)[0])) || strings.Contains(name, ".") {
+			return nil
+		}
+		names = append(names, filepath.ToSlash(name))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	return names, nil
+}
@@ -0,0 +1,49 @@
+package timetool
+
+import (
+	"fmt"
+	"time"
+)
+
+// ZoneTime is one instant rendered in one timezone.
+type ZoneTime struct {
+	Timezone      string `json:"timezone"`
+	Time          string `json:"time"`
+	Abbreviation  string `json:"abbreviation"` // e.g. "EST", "CEST"
+	UTCOffset     string `json:"utc_offset"`   // e.g. "-05:00"
+	OffsetSeconds int    `json:"offset_seconds"`
+	DST           bool   `json:"dst"`
+	Error         string `json:"error,omitempty"`
+}
+
+// zoneTimes renders t in each of zones. An invalid zone only fails its own
+// entry, so one typo doesn't hide the others.
+func zoneTimes(t time.Time, zones []string, format string, ref time.Time) []ZoneTime {
+	times := make([]ZoneTime, 0, len(zones))
+	for _, zone := range zones {
+		loc, err := loadLocation(zone)
+		if err != nil {
+			times = append(times, ZoneTime{Timezone: zone, Error: fmt.Sprintf("invalid timezone: %v", err)})
+			continue
+		}
+		times = append(times, zoneTime(t.In(loc), format, ref))
+	}
+	return times
+}
+
+func zoneTime(t time.Time, format string, ref time.Time) ZoneTime {
+	abbrev, offset := t.Zone()
+	zt := ZoneTime{
+		Timezone:      t.Location().String(),
+		Abbreviation:  abbrev,
+		UTCOffset:     t.Format("-07:00"),
+		OffsetSeconds: offset,
+		DST:           t.IsDST(),
+	}
+	formatted, err := formatTime(t, format, ref)
+	if err != nil {
+		zt.Error = err.Error()
+	}
+	zt.Time = formatted
+	return zt
+}
@@ -0,0 +1,69 @@
+package weathertool
+
+import (
+	"context"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+const maxBatchLocations = 50
+
+// batchPool bounds the provider requests in flight for batches, across
+// calls. Its queue holds two full batches; $WEATHER_BATCH_CONCURRENCY and
+// $WEATHER_BATCH_QUEUE override the defaults.
+var batchPool = toolserver.NewPool("weather-batch", 4, 2*maxBatchLocations)
+
+// LocationResult is one location's outcome in a batch. Exactly one of
+// Current and History is set, depending on whether dates were requested;
+// for a location that failed, it may carry the resolved location.
+type LocationResult struct {
+	Index   int              `json:"index"`
+	Label   string           `json:"label,omitempty"`
+	Current *WeatherResponse `json:"current,omitempty"`
+	History *HistoryResponse `json:"history,omitempty"`
+}
+
+// BatchResponse is the /weather response when locations is set: a result
+// per location, with the locations that failed listed in errors.
+type BatchResponse = toolserver.Batch[LocationResult]
+
+// weatherBatch serves /weather requests with several locations, looking
+// them up concurrently as far as batchPool allows. Locations it has no room
+// for fail; if every location fails, so does the request.
+func weatherBatch(ctx context.Context, req WeatherRequest, units unitSystem) (BatchResponse, error) {
+	if len(req.Locations) > maxBatchLocations {
+		return BatchResponse{}, toolserver.BadRequest("at most %d locations per request", maxBatchLocations)
+	}
+	if req.City != "" || req.Latitude != nil || req.Longitude != nil {
+		return BatchResponse{}, toolserver.BadRequest("use either locations or a single city/latitude/longitude")
+	}
+
+	history := req.Date != "" || req.DateRange != nil
+	if history {
+		if _, _, err := historyDates(req, time.Now().UTC()); err != nil {
+			return BatchResponse{}, toolserver.BadRequest("%v", err)
+		}
+	}
+
+	resp, err := toolserver.RunBatch(ctx, len(req.Locations), batchPool, func(ctx context.Context, i int) (LocationResult, error) {
+		single := req
+		single.LocationQuery, single.Locations = req.Locations[i], nil
+		var result LocationResult
+		var err error
+		if history {
+			var h HistoryResponse
+			h, err = weatherHistory(ctx, single, units)
+			result.History = &h
+		} else {
+			var c WeatherResponse
+			c, err = currentWeather(ctx, single, units)
+			result.Current = &c
+		}
+		return result, err
+	})
+	for i := range resp.Results {
+		resp.Results[i].Index, resp.Results[i].Label = i, req.Locations[i].Label
+	}
+	return resp, err
+}
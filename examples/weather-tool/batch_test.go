@@ -0,0 +1,67 @@
+package weathertool
+
+import (
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+)
+
+func TestWeatherBatch(t *testing.T) {
+	fakeOpenMeteo(t)
+	lat, lon := 40.0, -105.0
+	tooltest.Run(t, newTestServer(t), []tooltest.Case{
+		{Name: "partial failure", Path: "/weather", Body: WeatherRequest{Units: "metric", Locations: []LocationQuery{
+			{City: "Paris", Label: "eu-west"},
+			{City: "Nowhere", Label: "lost"},
+			{City: "Atlantis", Label: "ocean"},
+			{Latitude: &lat, Longitude: &lon},
+		}}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out BatchResponse
+			resp.Decode(&out)
+			if !out.Partial || len(out.Results) != 4 {
+				t.Fatalf("response = %+v", out)
+			}
+			for i, label := range []string{"eu-west", "lost", "ocean", ""} {
+				if r := out.Results[i]; r.Index != i || r.Label != label {
+					t.Errorf("results[%d] is %d %q, want label %q", i, r.Index, r.Label, label)
+				}
+			}
+			if c := out.Results[0].Current; c == nil || c.Temperature != 20 || c.Location.CountryCode != "FR" {
+				t.Errorf("results[0] = %+v", c)
+			}
+			if c := out.Results[3].Current; c == nil || c.Temperature != 20 {
+				t.Errorf("results[3] = %+v", c)
+			}
+			// A location that fails after geocoding still reports where it is.
+			if c := out.Results[2].Current; c == nil || c.Location == nil || c.Location.Name != "Atlantis" {
+				t.Errorf("results[2] = %+v, want its location", c)
+			}
+			want := []toolserver.Code{toolserver.CodeNotFound, toolserver.CodeUpstreamError}
+			if len(out.Errors) != len(want) {
+				t.Fatalf("errors = %+v", out.Errors)
+			}
+			for i, e := range out.Errors {
+				if e.Index != i+1 || e.Code != want[i] {
+					t.Errorf("errors[%d] = %+v, want index %d, %s", i, e, i+1, want[i])
+				}
+			}
+		}},
+		{Name: "history", Path: "/weather", Body: WeatherRequest{Date: "2000-01-01", Locations: []LocationQuery{{City: "Paris"}, {City: "Paris", Country: "US"}}}, Check: func(t *testing.T, resp *tooltest.Response) {
+			var out BatchResponse
+			resp.Decode(&out)
+			if out.Partial || len(out.Errors) != 0 || len(out.Results) != 2 {
+				t.Fatalf("response = %+v", out)
+			}
+			for i, r := range out.Results {
+				if r.Current != nil || r.History == nil || len(r.History.Days) != 1 {
+					t.Errorf("results[%d] = %+v", i, r)
+				}
+			}
+		}},
+		{Name: "all failing", Path: "/weather", Body: WeatherRequest{Locations: []LocationQuery{{City: "Nowhere"}, {City: "Broken"}}}, Code: toolserver.CodeNotFound},
+		{Name: "bad dates", Path: "/weather", Body: WeatherRequest{Date: "tomorrow", Locations: []LocationQuery{{City: "Paris"}}}, Code: toolserver.CodeInvalidArgument},
+		{Name: "locations and city", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Paris"}, Locations: []LocationQuery{{City: "Paris"}}}, Code: toolserver.CodeInvalidArgument},
+		{Name: "too many", Path: "/weather", Body: WeatherRequest{Locations: make([]LocationQuery, maxBatchLocations+1)}, Code: toolserver.CodeInvalidArgument},
+	})
+}
@@ -0,0 +1,22 @@
+// Command weather-tool serves weather-tool on its own. kube-mcp-tools serves it together
+// with the other example tools.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	weathertool "weather-tool"
+)
+
+func main() {
+	s, err := weathertool.New()
+	if err != nil {
+		slog.Error("starting weather-tool", "err", err)
+		os.Exit(1)
+	}
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
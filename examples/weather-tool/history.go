@@ -0,0 +1,175 @@
+package weathertool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// Recent days come from the forecast API, which keeps about three months of
+// past data; older ones come from the ERA5 archive, which lags about five
+// days behind.
+var archiveURL = config.String("WEATHER_ARCHIVE_API_URL", "https://archive-api.open-meteo.com/v1/archive")
+
+const (
+	recentHistoryDays = 60
+	maxHistoryDays    = 366
+)
+
+// DateRange is an inclusive range of dates in YYYY-MM-DD form.
+type DateRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// DailyWeather is the observed weather for one day, in the location's
+// local time.
+type DailyWeather struct {
+	Date           string  `json:"date"`
+	Conditions     string  `json:"conditions"` // the day's most severe weather
+	TemperatureMax float64 `json:"temperature_max"`
+	TemperatureMin float64 `json:"temperature_min"`
+	FeelsLikeMax   float64 `json:"feels_like_max"`
+	FeelsLikeMin   float64 `json:"feels_like_min"`
+	Precipitation  float64 `json:"precipitation"`
+	WindSpeedMax   float64 `json:"wind_speed_max"`
+	WindGustsMax   float64 `json:"wind_gusts_max"`
+	WindDirection  int     `json:"wind_direction"` // dominant, degrees the wind blows from
+	WindCompass    string  `json:"wind_compass"`
+}
+
+// HistoryResponse is the /weather response when date or date_range is set.
+type HistoryResponse struct {
+	Days     []DailyWeather `json:"days"`
+	Units    *Units         `json:"units,omitempty"`
+	Location *Location      `json:"location,omitempty"`
+}
+
+// weatherHistory looks up observed daily weather for req's location and
+// dates. When the lookup fails after geocoding, the response still carries
+// the location.
+func weatherHistory(ctx context.Context, req WeatherRequest, units unitSystem) (HistoryResponse, error) {
+	start, end, err := historyDates(req, time.Now().UTC())
+	if err != nil {
+		return HistoryResponse{}, toolserver.BadRequest("%v", err)
+	}
+
+	loc, err := resolveLocation(ctx, req.LocationQuery)
+	if err != nil {
+		return HistoryResponse{}, err
+	}
+
+	days, err := fetchDaily(ctx, loc.Latitude, loc.Longitude, start, end, units)
+	if err != nil {
+		return HistoryResponse{Location: &loc}, toolserver.UpstreamError(err, "weather history lookup failed")
+	}
+
+	slog.DebugContext(ctx, "weather history", "location", loc.Name, "latitude", loc.Latitude, "longitude", loc.Longitude,
+		"start", start.Format(time.DateOnly), "end", end.Format(time.DateOnly))
+
+	return HistoryResponse{Days: days, Units: &units.Units, Location: &loc}, nil
+}
+
+// historyDates validates the request's date or date_range against today.
+func historyDates(req WeatherRequest, now time.Time) (time.Time, time.Time, error) {
+	startStr, endStr := req.Date, req.Date
+	if req.DateRange != nil {
+		if req.Date != "" {
+			return time.Time{}, time.Time{}, fmt.Errorf("date and date_range cannot both be set")
+		}
+		startStr, endStr = req.DateRange.Start, req.DateRange.End
+	}
+
+	start, err := time.Parse(time.DateOnly, startStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date %q: use YYYY-MM-DD", startStr)
+	}
+	end, err := time.Parse(time.DateOnly, endStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date %q: use YYYY-MM-DD", endStr)
+	}
+
+	today := now.Truncate(24 * time.Hour)
+	switch {
+	case end.Before(start):
+		return time.Time{}, time.Time{}, fmt.Errorf("date_range end is before start")
+	case !end.Before(today):
+		return time.Time{}, time.Time{}, fmt.Errorf("dates must be before today; use /weather without a date for current conditions")
+	case end.Sub(start) >= maxHistoryDays*24*time.Hour:
+		return time.Time{}, time.Time{}, fmt.Errorf("date_range may cover at most %d days", maxHistoryDays)
+	}
+	return start, end, nil
+}
+
+// fetchDaily returns daily observations for start through end.
+func fetchDaily(ctx context.Context, lat, lon float64, start, end time.Time, units unitSystem) ([]DailyWeather, error) {
+	q := url.Values{}
+	q.Set("latitude", strconv.FormatFloat(lat, 'f', -1, 64))
+	q.Set("longitude", strconv.FormatFloat(lon, 'f', -1, 64))
+	q.Set("start_date", start.Format(time.DateOnly))
+	q.Set("end_date", end.Format(time.DateOnly))
+	q.Set("daily", "weather_code,temperature_2m_max,temperature_2m_min,apparent_temperature_max,apparent_temperature_min,precipitation_sum,wind_speed_10m_max,wind_gusts_10m_max,wind_direction_10m_dominant")
+	q.Set("timezone", "auto")
+	units.setUnits(q)
+
+	base := archiveURL
+	if time.Since(start) < recentHistoryDays*24*time.Hour {
+		base = forecastURL
+	}
+
+	var result struct {
+		Daily struct {
+			Time           []string   `json:"time"`
+			WeatherCode    []*int     `json:"weather_code"`
+			TemperatureMax []*float64 `json:"temperature_2m_max"`
+			TemperatureMin []*float64 `json:"temperature_2m_min"`
+			FeelsLikeMax   []*float64 `json:"apparent_temperature_max"`
+			FeelsLikeMin   []*float64 `json:"apparent_temperature_min"`
+			Precipitation  []*float64 `json:"precipitation_sum"`
+			WindSpeedMax   []*float64 `json:"wind_speed_10m_max"`
+			WindGustsMax   []*float64 `json:"wind_gusts_10m_max"`
+			WindDirection  []*int     `json:"wind_direction_10m_dominant"`
+		} `json:"daily"`
+	}
+	if err := getJSON(ctx, base+"?"+q.Encode(), &result); err != nil {
+		return nil, fmt.Errorf("weather history lookup failed: %v", err)
+	}
+
+	// Missing values (null, or short arrays) are left as zero.
+	d := result.Daily
+	days := make([]DailyWeather, len(d.Time))
+	for i, date := range d.Time {
+		day := DailyWeather{
+			Date:           date,
+			Conditions:     "unknown",
+			TemperatureMax: at(d.TemperatureMax, i),
+			TemperatureMin: at(d.TemperatureMin, i),
+			FeelsLikeMax:   at(d.FeelsLikeMax, i),
+			FeelsLikeMin:   at(d.FeelsLikeMin, i),
+			Precipitation:  at(d.Precipitation, i),
+			WindSpeedMax:   at(d.WindSpeedMax, i),
+			WindGustsMax:   at(d.WindGustsMax, i),
+			WindDirection:  at(d.WindDirection, i),
+		}
+		if i < len(d.WeatherCode) && d.WeatherCode[i] != nil {
+			day.Conditions = weatherConditions(*d.WeatherCode[i])
+		}
+		day.WindCompass = compassDirection(day.WindDirection)
+		days[i] = day
+	}
+	return days, nil
+}
+
+func at[T any](values []*T, i int) T {
+	var zero T
+	if i >= len(values) || values[i] == nil {
+		return zero
+	}
+	return *values[i]
+}
@@ -0,0 +1,88 @@
+package weathertool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+)
+
+func TestHistoryDates(t *testing.T) {
+	now := time.Date(2026, 10, 15, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		req       WeatherRequest
+		wantStart string
+		wantEnd   string
+		wantErr   bool
+	}{
+		{name: "date", req: WeatherRequest{Date: "2026-10-14"}, wantStart: "2026-10-14", wantEnd: "2026-10-14"},
+		{name: "range", req: WeatherRequest{DateRange: &DateRange{Start: "2026-10-01", End: "2026-10-07"}}, wantStart: "2026-10-01", wantEnd: "2026-10-07"},
+		{name: "full year", req: WeatherRequest{DateRange: &DateRange{Start: "2025-10-14", End: "2026-10-14"}}, wantStart: "2025-10-14", wantEnd: "2026-10-14"},
+		{name: "over a year", req: WeatherRequest{DateRange: &DateRange{Start: "2025-10-13", End: "2026-10-14"}}, wantErr: true},
+		{name: "today", req: WeatherRequest{Date: "2026-10-15"}, wantErr: true},
+		{name: "future", req: WeatherRequest{DateRange: &DateRange{Start: "2026-10-10", End: "2026-10-20"}}, wantErr: true},
+		{name: "end before start", req: WeatherRequest{DateRange: &DateRange{Start: "2026-10-07", End: "2026-10-01"}}, wantErr: true},
+		{name: "both", req: WeatherRequest{Date: "2026-10-14", DateRange: &DateRange{Start: "2026-10-01", End: "2026-10-07"}}, wantErr: true},
+		{name: "bad date", req: WeatherRequest{Date: "14/10/2026"}, wantErr: true},
+		{name: "missing end", req: WeatherRequest{DateRange: &DateRange{Start: "2026-10-01"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := historyDates(tt.req, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("historyDates = %v, %v, want an error", start, end)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := start.Format(time.DateOnly); got != tt.wantStart {
+				t.Errorf("start = %s, want %s", got, tt.wantStart)
+			}
+			if got := end.Format(time.DateOnly); got != tt.wantEnd {
+				t.Errorf("end = %s, want %s", got, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestWeatherHistory(t *testing.T) {
+	requests := fakeOpenMeteo(t)
+	day := func(daysAgo int) string {
+		return time.Now().UTC().AddDate(0, 0, -daysAgo).Format(time.DateOnly)
+	}
+	history := func(wantDays int, wantConditions string) func(*testing.T, *tooltest.Response) {
+		return func(t *testing.T, resp *tooltest.Response) {
+			var out HistoryResponse
+			resp.Decode(&out)
+			if len(out.Days) != wantDays || out.Location == nil || out.Location.Name != "Paris" {
+				t.Fatalf("response = %+v", out)
+			}
+			for _, d := range out.Days {
+				if d.Conditions != wantConditions || d.TemperatureMax != 20 || d.WindGustsMax != 0 {
+					t.Errorf("day = %+v, want %s", d, wantConditions)
+				}
+			}
+		}
+	}
+	tooltest.Run(t, newTestServer(t), []tooltest.Case{
+		{Name: "yesterday", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Paris"}, Date: day(1)}, Check: history(1, "rainy")},
+		{Name: "last week", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Paris"}, DateRange: &DateRange{Start: day(7), End: day(1)}}, Check: history(7, "rainy")},
+		{Name: "last year", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Paris"}, DateRange: &DateRange{Start: day(365), End: day(360)}}, Check: history(6, "cloudy")},
+		{Name: "today", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Paris"}, Date: day(0)}, Code: toolserver.CodeInvalidArgument},
+		{Name: "over a year", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Paris"}, DateRange: &DateRange{Start: day(400), End: day(1)}}, Code: toolserver.CodeInvalidArgument},
+		{Name: "lookup failing", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Atlantis"}, Date: day(1)}, Code: toolserver.CodeUpstreamError},
+	})
+
+	// Recent days come from the forecast API and older ones from the archive.
+	if n := len(requests["/v1/forecast"]); n != 3 {
+		t.Errorf("%d forecast requests, want 3", n)
+	}
+	if n := len(requests["/v1/archive"]); n != 1 {
+		t.Errorf("%d archive requests, want 1", n)
+	}
+}
@@ -1,80 +1,141 @@
-package main
+// Package weathertool implements weather-tool, which reports current and
+// historical weather from Open-Meteo.
+package weathertool
 
 import (
-	"encoding/json"
-	"fmt"
-	"log"
-	"math/rand"
-	"net/http"
-	"os"
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
 	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
 )
 
+// LocationQuery identifies a location by city (optionally narrowed by
+// country) or by explicit coordinates.
+type LocationQuery struct {
+	City      string   `json:"city,omitempty"`
+	Country   string   `json:"country,omitempty"` // ISO 3166-1 alpha-2 code, e.g. "US"
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Label     string   `json:"label,omitempty"` // echoed back in batch results, e.g. "dc-east"
+}
+
+// WeatherRequest asks about one location, or about each of Locations with
+// the same units and dates.
 type WeatherRequest struct {
-	City string `json:"city"`
+	LocationQuery
+	Locations []LocationQuery `json:"locations,omitempty"`
+	Units     string          `json:"units,omitempty"` // imperial (default) or metric
+
+	// Date or DateRange (YYYY-MM-DD, inclusive) returns observed daily
+	// weather for past days instead of current conditions.
+	Date      string     `json:"date,omitempty"`
+	DateRange *DateRange `json:"date_range,omitempty"`
 }
 
 type WeatherResponse struct {
-	Temperature int    `json:"temperature"`
-	Conditions  string `json:"conditions"`
-	Humidity    int    `json:"humidity"`
-	Error       string `json:"error,omitempty"`
+	Temperature   float64   `json:"temperature"`
+	FeelsLike     float64   `json:"feels_like"`
+	Conditions    string    `json:"conditions"`
+	Humidity      int       `json:"humidity"`
+	WindSpeed     float64   `json:"wind_speed"`
+	WindDirection int       `json:"wind_direction"` // degrees the wind blows from
+	WindCompass   string    `json:"wind_compass"`   // e.g. "SW"
+	Precipitation float64   `json:"precipitation"`
+	Units         *Units    `json:"units,omitempty"`
+	Location      *Location `json:"location,omitempty"` // the resolved place and coordinates
+	ObservedAt    string    `json:"observed_at,omitempty"`
 }
 
-func main() {
-	http.HandleFunc("/health", handleHealth)
-	http.HandleFunc("/weather", handleWeather)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+// New returns the weather-tool server.
+func New() (*toolserver.Server, error) {
+	s := toolserver.New("weather-tool")
+	if u, err := url.Parse(forecastURL); err == nil {
+		s.AddReadinessCheck("open-meteo", toolserver.ResolveCheck(u.Hostname()))
 	}
+	toolserver.Register(s, "/weather", weather,
+		toolserver.Name("weather-tool"), toolserver.Describe("Return current or historical weather for a city or coordinates, from Open-Meteo."))
 
-	log.Printf("Starting weather-tool server on :%s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
-	}
+	return s, nil
 }
 
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
-}
-
-func handleWeather(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	if r.Method != http.MethodPost {
-		http.Error(w, `{"error": "method not allowed"}`, http.StatusMethodNotAllowed)
-		return
+// weather answers a /weather request with a WeatherResponse, a
+// HistoryResponse or a BatchResponse depending on its fields.
+func weather(ctx context.Context, req WeatherRequest) (any, error) {
+	if req.Units == "" {
+		req.Units = "imperial"
+	}
+	units, ok := unitSystems[req.Units]
+	if !ok {
+		return nil, toolserver.BadRequest("units must be metric or imperial")
 	}
 
-	var req WeatherRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(WeatherResponse{Error: "invalid JSON body"})
-		return
+	if len(req.Locations) > 0 {
+		return weatherBatch(ctx, req, units)
+	}
+	if req.Date != "" || req.DateRange != nil {
+		return weatherHistory(ctx, req, units)
 	}
+	return currentWeather(ctx, req, units)
+}
 
-	if strings.TrimSpace(req.City) == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(WeatherResponse{Error: "city is required"})
-		return
+// currentWeather looks up the current conditions for req's location. When
+// the lookup fails after geocoding, the response still carries the location.
+func currentWeather(ctx context.Context, req WeatherRequest, units unitSystem) (WeatherResponse, error) {
+	loc, err := resolveLocation(ctx, req.LocationQuery)
+	if err != nil {
+		return WeatherResponse{}, err
 	}
 
-	// Mock logic
-	temp := 40 + rand.Intn(61) // 40 to 100
-	conditions := []string{"sunny", "cloudy", "rainy", "snowy"}
-	condition := conditions[rand.Intn(len(conditions))]
-	humidity := rand.Intn(101) // 0 to 100
+	current, err := fetchCurrent(ctx, loc.Latitude, loc.Longitude, units)
+	if err != nil {
+		return WeatherResponse{Location: &loc}, toolserver.UpstreamError(err, "weather lookup failed")
+	}
 
 	resp := WeatherResponse{
-		Temperature: temp,
-		Conditions:  condition,
-		Humidity:    humidity,
+		Temperature:   current.Temperature,
+		FeelsLike:     current.FeelsLike,
+		Conditions:    weatherConditions(current.WeatherCode),
+		Humidity:      current.Humidity,
+		WindSpeed:     current.WindSpeed,
+		WindDirection: current.WindDirection,
+		WindCompass:   compassDirection(current.WindDirection),
+		Precipitation: current.Precipitation,
+		Units:         &units.Units,
+		Location:      &loc,
+		ObservedAt:    current.Time,
 	}
 
-	fmt.Printf("Weather request for %s: %d°F, %s, %d%%\n", req.City, temp, condition, humidity)
+	// The location comes from the caller, so it is only logged at debug level.
+	slog.DebugContext(ctx, "weather", "location", loc.Name, "latitude", loc.Latitude, "longitude", loc.Longitude,
+		"temperature", resp.Temperature, "conditions", resp.Conditions)
+
+	return resp, nil
+}
+
+// resolveLocation returns the request's coordinates, geocoding the city when
+// none were given.
+func resolveLocation(ctx context.Context, req LocationQuery) (Location, error) {
+	if req.Latitude != nil || req.Longitude != nil {
+		if req.Latitude == nil || req.Longitude == nil {
+			return Location{}, toolserver.BadRequest("latitude and longitude must be given together")
+		}
+		if *req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180 {
+			return Location{}, toolserver.BadRequest("latitude must be within ±90 and longitude within ±180")
+		}
+		return Location{Latitude: *req.Latitude, Longitude: *req.Longitude}, nil
+	}
 
-	json.NewEncoder(w).Encode(resp)
+	if strings.TrimSpace(req.City) == "" {
+		return Location{}, toolserver.BadRequest("city or latitude/longitude is required")
+	}
+	loc, err := geocode(ctx, strings.TrimSpace(req.City), req.Country)
+	if errors.Is(err, errLocationNotFound) {
+		return Location{}, toolserver.NewError(toolserver.CodeNotFound, "%v", err)
+	} else if err != nil {
+		return Location{}, toolserver.UpstreamError(err, "geocoding failed")
+	}
+	return loc, nil
 }
@@ -0,0 +1,164 @@
+package weathertool
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/atippey/kube-mcp/pkg/tooltest"
+)
+
+// places are the fake geocoder's results, by name. "Broken" makes it fail.
+var places = map[string][]Location{
+	"Paris": {
+		{Name: "Paris", Country: "France", CountryCode: "FR", Latitude: 48.85, Longitude: 2.35, Timezone: "Europe/Paris"},
+		{Name: "Paris", Admin1: "Texas", Country: "United States", CountryCode: "US", Latitude: 33.66, Longitude: -95.56, Timezone: "America/Chicago"},
+	},
+	"Atlantis": {{Name: "Atlantis", Latitude: 0, Longitude: 0}},
+}
+
+// fakeOpenMeteo serves the geocoding, forecast and archive APIs and points
+// GEOCODING_API_URL, WEATHER_API_URL and WEATHER_ARCHIVE_API_URL at them.
+// Forecasts fail for 0,0. The temperature is 20 in Celsius and 68 in
+// Fahrenheit; daily history is rainy from the forecast API and cloudy from
+// the archive. It returns the requests made, by path, which may only be
+// read once they are done.
+func fakeOpenMeteo(t *testing.T) map[string][]*http.Request {
+	t.Helper()
+	var mu sync.Mutex
+	requests := make(map[string][]*http.Request)
+	record := func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests[r.URL.Path] = append(requests[r.URL.Path], r)
+	}
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	mux.HandleFunc("/v1/search", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		name := r.URL.Query().Get("name")
+		if name == "Broken" {
+			reply(w, http.StatusInternalServerError, map[string]any{"error": true, "reason": "geocoder is down"})
+			return
+		}
+		reply(w, http.StatusOK, map[string]any{"results": places[name]})
+	})
+	daily := func(w http.ResponseWriter, r *http.Request, code int) {
+		q := r.URL.Query()
+		if q.Get("latitude") == "0" && q.Get("longitude") == "0" {
+			reply(w, http.StatusBadRequest, map[string]any{"error": true, "reason": "no data over the ocean"})
+			return
+		}
+		if q.Get("current") != "" {
+			temperature := 20.0
+			if q.Get("temperature_unit") == "fahrenheit" {
+				temperature = 68
+			}
+			reply(w, http.StatusOK, map[string]any{"current": map[string]any{
+				"time": "2026-10-15T12:00", "temperature_2m": temperature, "apparent_temperature": temperature,
+				"relative_humidity_2m": 50, "wind_speed_10m": 10, "wind_direction_10m": 225, "precipitation": 0, "weather_code": 2,
+			}})
+			return
+		}
+		start, _ := time.Parse(time.DateOnly, q.Get("start_date"))
+		end, _ := time.Parse(time.DateOnly, q.Get("end_date"))
+		var dates []string
+		var codes, maxima []any
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			dates = append(dates, d.Format(time.DateOnly))
+			codes = append(codes, code)
+			maxima = append(maxima, 20)
+		}
+		// The archive has no gusts, which come back as nulls.
+		reply(w, http.StatusOK, map[string]any{"daily": map[string]any{
+			"time": dates, "weather_code": codes, "temperature_2m_max": maxima, "wind_gusts_10m_max": make([]any, len(dates)),
+		}})
+	}
+	mux.HandleFunc("/v1/forecast", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		daily(w, r, 61)
+	})
+	mux.HandleFunc("/v1/archive", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		daily(w, r, 3)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	urls := []*string{&geocodingURL, &forecastURL, &archiveURL}
+	saved := []string{geocodingURL, forecastURL, archiveURL}
+	geocodingURL, forecastURL, archiveURL = srv.URL+"/v1/search", srv.URL+"/v1/forecast", srv.URL+"/v1/archive"
+	t.Cleanup(func() {
+		for i, u := range urls {
+			*u = saved[i]
+		}
+	})
+	return requests
+}
+
+func newTestServer(t *testing.T) *tooltest.Server {
+	t.Helper()
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tooltest.NewServer(t, s)
+}
+
+func TestCurrentWeather(t *testing.T) {
+	requests := fakeOpenMeteo(t)
+	lat, lon := 40.0, -105.0
+	current := func(want WeatherResponse) func(*testing.T, *tooltest.Response) {
+		return func(t *testing.T, resp *tooltest.Response) {
+			var out WeatherResponse
+			resp.Decode(&out)
+			if out.Temperature != want.Temperature || *out.Units != *want.Units || out.Location.Name != want.Location.Name ||
+				out.Location.CountryCode != want.Location.CountryCode || out.WindCompass != "SW" || out.Conditions != "partly cloudy" {
+				t.Errorf("response = %+v, units %+v, location %+v", out, out.Units, out.Location)
+			}
+		}
+	}
+	imperial, metric := unitSystems["imperial"].Units, unitSystems["metric"].Units
+	tooltest.Run(t, newTestServer(t), []tooltest.Case{
+		{Name: "city", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Paris"}},
+			Check: current(WeatherResponse{Temperature: 68, Units: &imperial, Location: &Location{Name: "Paris", CountryCode: "FR"}})},
+		{Name: "city in country", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: " Paris ", Country: "us"}},
+			Check: current(WeatherResponse{Temperature: 68, Units: &imperial, Location: &Location{Name: "Paris", CountryCode: "US"}})},
+		{Name: "metric", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Paris"}, Units: "metric"},
+			Check: current(WeatherResponse{Temperature: 20, Units: &metric, Location: &Location{Name: "Paris", CountryCode: "FR"}})},
+		{Name: "coordinates", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{Latitude: &lat, Longitude: &lon}},
+			Check: current(WeatherResponse{Temperature: 68, Units: &imperial, Location: &Location{}})},
+		{Name: "unknown units", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Paris"}, Units: "kelvin"}, Code: toolserver.CodeInvalidArgument},
+		{Name: "no location", Path: "/weather", Body: WeatherRequest{}, Code: toolserver.CodeInvalidArgument},
+		{Name: "latitude only", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{Latitude: &lat}}, Code: toolserver.CodeInvalidArgument},
+		{Name: "unknown city", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Nowhere"}}, Code: toolserver.CodeNotFound},
+		{Name: "not in country", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Paris", Country: "DE"}}, Code: toolserver.CodeNotFound},
+		{Name: "geocoder failing", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Broken"}}, Code: toolserver.CodeUpstreamError},
+		{Name: "forecast failing", Path: "/weather", Body: WeatherRequest{LocationQuery: LocationQuery{City: "Atlantis"}}, Code: toolserver.CodeUpstreamError},
+	})
+
+	// The units are converted by the provider, so each request must ask
+	// for them.
+	for _, r := range requests["/v1/forecast"] {
+		q := r.URL.Query()
+		want := unitSystems["imperial"]
+		if q.Get("temperature_unit") == "celsius" {
+			want = unitSystems["metric"]
+		}
+		if q.Get("temperature_unit") != want.temperatureParam || q.Get("wind_speed_unit") != want.windSpeedParam || q.Get("precipitation_unit") != want.precipitationParam {
+			t.Errorf("forecast request %s does not set its units", r.URL.RawQuery)
+		}
+	}
+	if lat := requests["/v1/forecast"][0].URL.Query().Get("latitude"); lat != strconv.FormatFloat(48.85, 'f', -1, 64) {
+		t.Errorf("first forecast at latitude %s, want Paris, France", lat)
+	}
+}
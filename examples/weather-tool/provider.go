@@ -0,0 +1,181 @@
+package weathertool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// Weather data comes from Open-Meteo, which needs no API key. The base URLs
+// can be pointed at a self-hosted instance or a proxy.
+var (
+	geocodingURL = config.String("GEOCODING_API_URL", "https://geocoding-api.open-meteo.com/v1/search")
+	forecastURL  = config.String("WEATHER_API_URL", "https://api.open-meteo.com/v1/forecast")
+
+	providerClient = &http.Client{Timeout: 10 * time.Second, Transport: toolserver.InstrumentTransport("open-meteo", nil)}
+
+	errLocationNotFound = errors.New("location not found")
+)
+
+// Location is a resolved place. Name and country are empty when the request
+// gave coordinates instead of a city.
+type Location struct {
+	Name        string  `json:"name,omitempty"`
+	Admin1      string  `json:"admin1,omitempty"` // state or region
+	Country     string  `json:"country,omitempty"`
+	CountryCode string  `json:"country_code,omitempty"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Timezone    string  `json:"timezone,omitempty"`
+}
+
+// geocode resolves a city name to its most relevant match, optionally
+// restricted to an ISO 3166-1 alpha-2 country code.
+func geocode(ctx context.Context, city, countryCode string) (Location, error) {
+	q := url.Values{}
+	q.Set("name", city)
+	q.Set("count", "10")
+	q.Set("language", "en")
+	q.Set("format", "json")
+
+	var result struct {
+		Results []Location `json:"results"`
+	}
+	if err := getJSON(ctx, geocodingURL+"?"+q.Encode(), &result); err != nil {
+		return Location{}, fmt.Errorf("geocoding failed: %v", err)
+	}
+	for _, loc := range result.Results {
+		if countryCode == "" || strings.EqualFold(loc.CountryCode, countryCode) {
+			return loc, nil
+		}
+	}
+	if countryCode != "" {
+		return Location{}, fmt.Errorf("%w: no match for city %q in country %q", errLocationNotFound, city, countryCode)
+	}
+	return Location{}, fmt.Errorf("%w: no match for city %q", errLocationNotFound, city)
+}
+
+// Units labels the units values are reported in.
+type Units struct {
+	Temperature   string `json:"temperature"`
+	WindSpeed     string `json:"wind_speed"`
+	Precipitation string `json:"precipitation"`
+}
+
+// unitSystem is a units choice with the matching Open-Meteo parameters.
+type unitSystem struct {
+	Units
+	temperatureParam, windSpeedParam, precipitationParam string
+}
+
+var unitSystems = map[string]unitSystem{
+	"imperial": {Units{"°F", "mph", "in"}, "fahrenheit", "mph", "inch"},
+	"metric":   {Units{"°C", "km/h", "mm"}, "celsius", "kmh", "mm"},
+}
+
+// setUnits adds the provider's unit parameters for units to q.
+func (u unitSystem) setUnits(q url.Values) {
+	q.Set("temperature_unit", u.temperatureParam)
+	q.Set("wind_speed_unit", u.windSpeedParam)
+	q.Set("precipitation_unit", u.precipitationParam)
+}
+
+// currentConditions is Open-Meteo's "current" block.
+type currentConditions struct {
+	Time          string  `json:"time"`
+	Temperature   float64 `json:"temperature_2m"`
+	FeelsLike     float64 `json:"apparent_temperature"`
+	Humidity      int     `json:"relative_humidity_2m"`
+	WindSpeed     float64 `json:"wind_speed_10m"`
+	WindDirection int     `json:"wind_direction_10m"` // degrees the wind blows from
+	Precipitation float64 `json:"precipitation"`
+	WeatherCode   int     `json:"weather_code"`
+}
+
+// fetchCurrent returns the current conditions at the given coordinates.
+func fetchCurrent(ctx context.Context, lat, lon float64, units unitSystem) (currentConditions, error) {
+	q := url.Values{}
+	q.Set("latitude", strconv.FormatFloat(lat, 'f', -1, 64))
+	q.Set("longitude", strconv.FormatFloat(lon, 'f', -1, 64))
+	q.Set("current", "temperature_2m,apparent_temperature,relative_humidity_2m,wind_speed_10m,wind_direction_10m,precipitation,weather_code")
+	units.setUnits(q)
+
+	var result struct {
+		Current currentConditions `json:"current"`
+	}
+	if err := getJSON(ctx, forecastURL+"?"+q.Encode(), &result); err != nil {
+		return currentConditions{}, fmt.Errorf("weather lookup failed: %v", err)
+	}
+	return result.Current, nil
+}
+
+// getJSON fetches u and decodes the JSON body into v. Open-Meteo reports
+// bad parameters as {"error": true, "reason": "..."}.
+func getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := providerClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Reason string `json:"reason"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Reason != "" {
+			return fmt.Errorf("%s: %s", resp.Status, apiErr.Reason)
+		}
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// compassDirection names the 16-point compass direction for degrees.
+func compassDirection(degrees int) string {
+	points := []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+	return points[((degrees%360+360)%360*2+22)/45%16]
+}
+
+// weatherConditions maps a WMO weather interpretation code to a short
+// description.
+func weatherConditions(code int) string {
+	switch {
+	case code == 0:
+		return "clear"
+	case code == 1:
+		return "mainly clear"
+	case code == 2:
+		return "partly cloudy"
+	case code == 3:
+		return "cloudy"
+	case code == 45 || code == 48:
+		return "fog"
+	case code >= 51 && code <= 57:
+		return "drizzle"
+	case code >= 61 && code <= 67:
+		return "rainy"
+	case code >= 71 && code <= 77:
+		return "snowy"
+	case code >= 80 && code <= 82:
+		return "rain showers"
+	case code == 85 || code == 86:
+		return "snow showers"
+	case code >= 95:
+		return "thunderstorm"
+	default:
+		return "unknown"
+	}
+}
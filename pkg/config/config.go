@@ -0,0 +1,332 @@
+// Package config reads the settings of a tool. Each setting has an
+// environment-style name, such as TLS_CERT_FILE, and is taken from the
+// first of:
+//
+//   - a command-line flag: --tls-cert-file=/certs/tls.crt or
+//     --tls-cert-file /certs/tls.crt
+//   - the environment variable TLS_CERT_FILE
+//   - the YAML file named by --config or $CONFIG_FILE
+//
+// In the file, nested keys are joined with underscores and lists with
+// commas, so this sets TLS_CERT_FILE and OIDC_AUDIENCE:
+//
+//	tls:
+//	  cert_file: /certs/tls.crt
+//	oidc:
+//	  audience: [tools, gateway]
+//
+// A bare flag such as --debug sets "true". Flags and the file are read on
+// first use; a config file that cannot be read is fatal. Watch rereads the
+// file when it changes.
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// settings are the values from flags and the config file, by name.
+type settings struct {
+	flags map[string]string
+	args  []string // the arguments that are not flags
+	file  map[string]string
+	path  string // the config file, if any
+	data  []byte // its content
+}
+
+var initial = sync.OnceValue(func() *settings {
+	s, err := load(os.Args[1:], os.Getenv)
+	if err != nil {
+		slog.Error("reading config file", "err", err)
+		os.Exit(1)
+	}
+	return s
+})
+
+// reloaded holds the settings once Watch has reread the config file.
+var reloaded atomic.Pointer[settings]
+
+var loaded = func() *settings {
+	if s := reloaded.Load(); s != nil {
+		return s
+	}
+	return initial()
+}
+
+// load parses args as flags and reads the config file they or getenv name.
+func load(args []string, getenv func(string) string) (*settings, error) {
+	s := &settings{file: make(map[string]string)}
+	s.flags, s.args = parseFlags(args)
+	path := s.flags["CONFIG"]
+	if path == "" {
+		path = getenv("CONFIG_FILE")
+	}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if s.file, err = parseFile(path, data); err != nil {
+		return nil, err
+	}
+	s.path, s.data = path, data
+	return s, nil
+}
+
+// parseFile returns the settings in data, the content of the config file at
+// path.
+func parseFile(path string, data []byte) (map[string]string, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	file := make(map[string]string)
+	flatten("", doc, file)
+	return file, nil
+}
+
+// reread returns the settings with the config file read again, or s itself
+// if its content has not changed.
+func (s *settings) reread() (*settings, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return s, err
+	}
+	if bytes.Equal(data, s.data) {
+		return s, nil
+	}
+	file, err := parseFile(s.path, data)
+	if err != nil {
+		return s, err
+	}
+	return &settings{flags: s.flags, args: s.args, file: file, path: s.path, data: data}, nil
+}
+
+// Watch rereads the config file every interval until ctx is done, and when
+// settings in it change, takes them up and calls fn with their names. If the
+// file can no longer be read or parsed, the settings are kept and fn is
+// called with the error, once until the error changes. The file's content
+// is compared rather than its modification time, so the symlink swap of an
+// updated ConfigMap volume is seen too. Flags and environment variables
+// still take precedence. Watch returns at once if there is no config file.
+func Watch(ctx context.Context, interval time.Duration, fn func(changed []string, err error)) {
+	if loaded().path == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var failed string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s := loaded()
+		next, err := s.reread()
+		if err != nil {
+			if err.Error() != failed {
+				failed = err.Error()
+				fn(nil, err)
+			}
+			continue
+		}
+		failed = ""
+		if next == s {
+			continue
+		}
+		reloaded.Store(next)
+		if changed := changedSettings(s.file, next.file); len(changed) > 0 {
+			fn(changed, nil)
+		}
+	}
+}
+
+// changedSettings returns the sorted names of the settings that differ
+// between old and new.
+func changedSettings(old, new map[string]string) []string {
+	var changed []string
+	for name, v := range old {
+		if w, ok := new[name]; !ok || w != v {
+			changed = append(changed, name)
+		}
+	}
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// settingName turns a flag or file key into a setting name:
+// "tls-cert-file" and "tls.cert_file" are both TLS_CERT_FILE.
+func settingName(key string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(key))
+}
+
+// parseFlags returns the values of --name=value, --name value and bare
+// --name flags in args, which may also start with a single dash, and the
+// other arguments. Parsing stops at "--"; the arguments after it are all
+// returned as other arguments.
+func parseFlags(args []string) (flags map[string]string, rest []string) {
+	flags = make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			rest = append(rest, arg)
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !ok {
+			value = "true"
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				value = args[i+1]
+				i++
+			}
+		}
+		flags[settingName(key)] = value
+	}
+	return flags, rest
+}
+
+// flatten adds the scalars in v to out under their joined, upper-cased keys.
+func flatten(prefix string, v any, out map[string]string) {
+	switch v := v.(type) {
+	case map[any]any:
+		for k, child := range v {
+			flatten(joinKey(prefix, fmt.Sprint(k)), child, out)
+		}
+	case map[string]any:
+		for k, child := range v {
+			flatten(joinKey(prefix, k), child, out)
+		}
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		out[prefix] = strings.Join(items, ",")
+	case nil:
+	default:
+		out[prefix] = fmt.Sprint(v)
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return settingName(key)
+	}
+	return prefix + "_" + settingName(key)
+}
+
+// Lookup returns the value of the named setting and whether it is set.
+func Lookup(name string) (string, bool) {
+	s := loaded()
+	if v, ok := s.flags[name]; ok {
+		return v, true
+	}
+	if v, ok := os.LookupEnv(name); ok {
+		return v, true
+	}
+	v, ok := s.file[name]
+	return v, ok
+}
+
+// String returns the named setting, or def if it is unset or empty.
+func String(name, def string) string {
+	if v, _ := Lookup(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// Int returns the named setting as an integer, or def if it is unset or
+// invalid.
+func Int(name string, def int) int {
+	return parse(name, def, strconv.Atoi)
+}
+
+// Float returns the named setting as a number, or def if it is unset or
+// invalid.
+func Float(name string, def float64) float64 {
+	return parse(name, def, func(v string) (float64, error) { return strconv.ParseFloat(v, 64) })
+}
+
+// Duration returns the named setting, e.g. "30s", or def if it is unset or
+// invalid.
+func Duration(name string, def time.Duration) time.Duration {
+	return parse(name, def, time.ParseDuration)
+}
+
+// Bool returns the named setting as true/false (or 1/0), or def if it is
+// unset or invalid.
+func Bool(name string, def bool) bool {
+	return parse(name, def, strconv.ParseBool)
+}
+
+// List returns the named setting split at commas, without empty items.
+func List(name string) []string {
+	var items []string
+	for _, item := range strings.Split(String(name, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Feature reports whether the named feature is listed in FEATURES, e.g.
+// FEATURES=write-ops,dry-run.
+func Feature(name string) bool {
+	return slices.Contains(List("FEATURES"), name)
+}
+
+// Args returns the command-line arguments that are not flags or their
+// values, such as a subcommand and its operands. Since "--name value" sets
+// name, a bare flag followed by an operand must be written --name=true.
+func Args() []string {
+	return loaded().args
+}
+
+// Kubeconfig returns the kubeconfig file for use outside a cluster: the
+// KUBECONFIG setting, or ~/.kube/config.
+func Kubeconfig() string {
+	if path := String("KUBECONFIG", ""); path != "" {
+		return path
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".kube", "config")
+}
+
+func parse[T any](name string, def T, parse func(string) (T, error)) T {
+	v, _ := Lookup(name)
+	if v == "" {
+		return def
+	}
+	x, err := parse(v)
+	if err != nil {
+		slog.Warn("invalid "+name, "value", v, "using", def)
+		return def
+	}
+	return x
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseFlags(t *testing.T) {
+	got, args := parseFlags([]string{"serve", "--port=9090", "-transport", "stdio", "--debug", "--tls.cert-file", "/certs/tls.crt", "extra", "--", "--not-a-flag"})
+	want := map[string]string{"PORT": "9090", "TRANSPORT": "stdio", "DEBUG": "true", "TLS_CERT_FILE": "/certs/tls.crt"}
+	if !maps.Equal(got, want) {
+		t.Errorf("parseFlags = %v, want %v", got, want)
+	}
+	if want := []string{"serve", "extra", "--not-a-flag"}; !slices.Equal(args, want) {
+		t.Errorf("parseFlags arguments = %q, want %q", args, want)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`
+port: 9090
+log_level: debug
+tls:
+  cert_file: /certs/tls.crt
+  client-ca-file: /certs/ca.crt
+oidc:
+  audience: [tools, gateway]
+features:
+  - dry-run
+`), 0o600)
+
+	for _, args := range [][]string{{"--config", path}, nil} {
+		s, err := load(args, func(name string) string {
+			if name == "CONFIG_FILE" {
+				return path
+			}
+			return ""
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{
+			"PORT":               "9090",
+			"LOG_LEVEL":          "debug",
+			"TLS_CERT_FILE":      "/certs/tls.crt",
+			"TLS_CLIENT_CA_FILE": "/certs/ca.crt",
+			"OIDC_AUDIENCE":      "tools,gateway",
+			"FEATURES":           "dry-run",
+		}
+		if !maps.Equal(s.file, want) {
+			t.Errorf("file settings = %v, want %v", s.file, want)
+		}
+	}
+
+	if _, err := load([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")}, os.Getenv); err == nil {
+		t.Error("missing config file was not an error")
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	saved := loaded
+	t.Cleanup(func() { loaded = saved })
+	loaded = func() *settings {
+		return &settings{
+			flags: map[string]string{"PORT": "1111"},
+			file: map[string]string{
+				"PORT":            "3333",
+				"LOG_LEVEL":       "warn",
+				"REQUEST_TIMEOUT": "45s",
+				"RATE_LIMIT":      "fast",
+				"FEATURES":        "dry-run, write-ops",
+			},
+		}
+	}
+	t.Setenv("PORT", "2222")
+	t.Setenv("LOG_LEVEL", "debug")
+
+	if got := String("PORT", "8080"); got != "1111" {
+		t.Errorf("PORT = %s, want the flag", got)
+	}
+	if got := String("LOG_LEVEL", "info"); got != "debug" {
+		t.Errorf("LOG_LEVEL = %s, want the environment", got)
+	}
+	if got := Duration("REQUEST_TIMEOUT", time.Second); got != 45*time.Second {
+		t.Errorf("REQUEST_TIMEOUT = %v, want the file", got)
+	}
+	if got := Float("RATE_LIMIT", 5); got != 5 {
+		t.Errorf("invalid RATE_LIMIT = %v, want the default", got)
+	}
+	if got := Int("CACHE_SIZE", 100); got != 100 {
+		t.Errorf("unset CACHE_SIZE = %d, want the default", got)
+	}
+	if !Feature("write-ops") || Feature("write") {
+		t.Errorf("FEATURES = %v", List("FEATURES"))
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("log_level: info\nport: 9090\n"), 0o600)
+	s, err := load([]string{"--config", path}, os.Getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := loaded
+	t.Cleanup(func() { loaded = saved; reloaded.Store(nil) })
+	reloaded.Store(s)
+	loaded = func() *settings { return reloaded.Load() }
+
+	type event struct {
+		changed []string
+		err     error
+	}
+	events := make(chan event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Watch(ctx, 10*time.Millisecond, func(changed []string, err error) { events <- event{changed, err} })
+	next := func() event {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reload")
+			return event{}
+		}
+	}
+
+	// Replace the file in one step, as the kubelet does, so the watcher
+	// never sees it half-written.
+	write := func(content string) {
+		tmp := path + ".tmp"
+		os.WriteFile(tmp, []byte(content), 0o600)
+		os.Rename(tmp, path)
+	}
+	write("log_level: debug\nport: 9090\nrate_limit: 5\n")
+	if e := next(); e.err != nil || !slices.Equal(e.changed, []string{"LOG_LEVEL", "RATE_LIMIT"}) {
+		t.Errorf("reload = %v, %v; want LOG_LEVEL and RATE_LIMIT changed", e.changed, e.err)
+	}
+	if got := String("LOG_LEVEL", ""); got != "debug" {
+		t.Errorf("LOG_LEVEL after reload = %s, want debug", got)
+	}
+
+	write("log_level: [unclosed\n")
+	if e := next(); e.err == nil {
+		t.Errorf("invalid file reloaded with %v", e.changed)
+	}
+	if got := String("LOG_LEVEL", ""); got != "debug" {
+		t.Errorf("LOG_LEVEL after a failed reload = %s, want the previous debug", got)
+	}
+
+	write("log_level: warn\n")
+	if e := next(); e.err != nil || !slices.Equal(e.changed, []string{"LOG_LEVEL", "PORT", "RATE_LIMIT"}) {
+		t.Errorf("reload = %v, %v; want LOG_LEVEL, PORT and RATE_LIMIT changed", e.changed, e.err)
+	}
+}
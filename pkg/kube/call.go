@@ -0,0 +1,57 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Call runs fn with c's clientset, retrying it with Retry while it fails
+// with a transient error. Without a clientset, it returns the 503 error
+// Clientset does.
+func Call[T any](ctx context.Context, c *Client, fn func(context.Context, kubernetes.Interface) (T, error)) (T, error) {
+	clientset, err := c.Clientset()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return Retry(ctx, func(ctx context.Context) (T, error) {
+		return fn(ctx, clientset)
+	})
+}
+
+// Error maps a failed API call to the error a handler returns: NOT_FOUND
+// for a missing object, FORBIDDEN when the tool's credentials may not make
+// the call, and otherwise an upstream error, retryable if it was transient.
+// An error already reported, such as Clientset's 503, is returned as is.
+func Error(err error) error {
+	return callError(err, "", "kubernetes API request failed")
+}
+
+// Errorf is Error with a formatted message before err, such as "failed to
+// get cronjob".
+func Errorf(err error, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	return callError(err, msg+": ", msg)
+}
+
+// callError is Error, with prefix before the message of a NOT_FOUND or
+// FORBIDDEN error and upstream as the message of an upstream one.
+func callError(err error, prefix, upstream string) error {
+	var e *toolserver.Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case apierrors.IsNotFound(err):
+		return toolserver.NewError(toolserver.CodeNotFound, "%s%v", prefix, err)
+	case apierrors.IsForbidden(err):
+		return toolserver.NewError(toolserver.CodeForbidden, "%s%v", prefix, err)
+	}
+	e = toolserver.UpstreamError(err, "%s", upstream)
+	e.Retryable = e.Retryable || IsTransient(err)
+	return e
+}
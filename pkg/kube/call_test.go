@@ -0,0 +1,84 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCall(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "") // not in a cluster
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+	getPod := func(ctx context.Context, clientset kubernetes.Interface) (*corev1.Pod, error) {
+		return clientset.CoreV1().Pods("web").Get(ctx, "web-1", metav1.GetOptions{})
+	}
+
+	c := New("kube-info-tool")
+	var e *toolserver.Error
+	if _, err := Call(t.Context(), c, getPod); !errors.As(err, &e) || e.Code != toolserver.CodeUnavailable {
+		t.Errorf("Call without a cluster = %v, want UNAVAILABLE", err)
+	}
+
+	clientset := fake.NewClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "web-1"}})
+	calls := 0
+	clientset.PrependReactor("get", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if calls++; calls == 1 {
+			return true, nil, apierrors.NewServiceUnavailable("restarting")
+		}
+		return false, nil, nil
+	})
+	c.SetClients(clientset, nil)
+	pod, err := Call(t.Context(), c, getPod)
+	if err != nil || pod.Name != "web-1" || calls != 2 {
+		t.Errorf("Call = %v, %v after %d calls; want the pod after a retry", pod, err, calls)
+	}
+}
+
+func TestError(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	unavailable := toolserver.NewError(toolserver.CodeUnavailable, "kubernetes client not available")
+
+	tests := []struct {
+		name      string
+		err       error
+		code      toolserver.Code
+		message   string
+		retryable bool
+	}{
+		{"already reported", unavailable, toolserver.CodeUnavailable, "kubernetes client not available", false},
+		{"not found", apierrors.NewNotFound(pods, "web-1"), toolserver.CodeNotFound, `failed to get pod: pods "web-1" not found`, false},
+		{"forbidden", apierrors.NewForbidden(pods, "web-1", errors.New("no")), toolserver.CodeForbidden, `failed to get pod: pods "web-1" is forbidden: no`, false},
+		{"transient", apierrors.NewTooManyRequests("slow down", 1), toolserver.CodeUpstreamError, "failed to get pod: slow down", true},
+		{"other", apierrors.NewBadRequest("bad"), toolserver.CodeUpstreamError, "failed to get pod: bad", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e *toolserver.Error
+			if !errors.As(Errorf(tt.err, "failed to get %s", "pod"), &e) {
+				t.Fatal("Errorf did not return a *toolserver.Error")
+			}
+			if e.Code != tt.code || e.Message != tt.message || e.Retryable != tt.retryable {
+				t.Errorf("Errorf = %s %q retryable=%v, want %s %q retryable=%v", e.Code, e.Message, e.Retryable, tt.code, tt.message, tt.retryable)
+			}
+		})
+	}
+
+	var e *toolserver.Error
+	if !errors.As(Error(apierrors.NewNotFound(pods, "web-1")), &e) || e.Code != toolserver.CodeNotFound || e.Message != `pods "web-1" not found` {
+		t.Errorf("Error(not found) = %v", e)
+	}
+	if !errors.As(Error(apierrors.NewBadRequest("bad")), &e) || e.Message != "kubernetes API request failed: bad" {
+		t.Errorf("Error(bad request) = %v", e)
+	}
+}
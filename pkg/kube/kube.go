@@ -0,0 +1,329 @@
+// Package kube connects tools to the Kubernetes API. A Client loads its
+// configuration on first use, from inside the cluster or else from a
+// kubeconfig, with the same rate limits, user agent and request metrics for
+// every tool. A tool that cannot reach a cluster keeps serving: its
+// Kubernetes operations fail with 503 UNAVAILABLE, and its readiness check
+// and /healthz/verbose say why, instead of the tool exiting.
+//
+// Settings (see package config):
+//
+//	KUBECONFIG            the kubeconfig outside a cluster (default ~/.kube/config)
+//	KUBE_CONTEXT          its context to use (default its current context)
+//	KUBE_QPS, KUBE_BURST  client-side rate limit (default 20 and 40; client-go's own are 5 and 10)
+//	KUBE_RETRIES          attempts Retry makes at a call, including the first (default 4)
+//
+// With the TokenRequest API, a tool can use its own credentials only to
+// request short-lived, audience-bound tokens for narrower service accounts,
+// and make its calls with those:
+//
+//	KUBE_TOKEN_SERVICE_ACCOUNT      "namespace/name" of the service account to call as
+//	KUBE_NAMESPACE_SERVICE_ACCOUNT  the service account to call as in each namespace, for ForNamespace
+//	KUBE_TOKEN_AUDIENCES            the tokens' audiences (default the API server's)
+//	KUBE_TOKEN_TTL                  the tokens' lifetime (default 10m, the shortest allowed)
+//
+// The tool's own service account then needs only create on
+// serviceaccounts/token for those service accounts.
+package kube
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	defaultQPS   = 20
+	defaultBurst = 40
+
+	// retryInterval is how long a configuration that failed to load is
+	// reported before it is loaded again, e.g. once a kubeconfig Secret is
+	// mounted.
+	retryInterval = 30 * time.Second
+)
+
+// A Client is a tool's connection to the Kubernetes API. It is safe for
+// concurrent use.
+type Client struct {
+	tool string
+
+	mu        sync.Mutex
+	cfg       *rest.Config
+	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
+	base      kubernetes.Interface // with the tool's own credentials, for token requests
+	tokens    *tokenSource         // nil unless calls use requested tokens
+	scoped    map[string]kubernetes.Interface
+	source    string    // "in-cluster", the kubeconfig context or "fake"
+	err       error     // why the client is not available
+	tried     time.Time // when loading last failed
+	loaded    time.Time // when loading succeeded
+	took      time.Duration
+}
+
+// New returns the named tool's client. It does not load the configuration
+// until it is first used.
+func New(tool string) *Client {
+	return &Client{tool: tool}
+}
+
+// Config returns the client configuration, for clients other than the
+// clientset, or the 503 error Clientset returns.
+func (c *Client) Config() (*rest.Config, error) {
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return rest.CopyConfig(c.cfg), nil
+}
+
+// Clientset returns the clientset, or, if there is no usable configuration,
+// a 503 UNAVAILABLE error saying why, for handlers to return as is.
+func (c *Client) Clientset() (kubernetes.Interface, error) {
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c.clientset, nil
+}
+
+// Dynamic returns a dynamic client, for custom resources and objects of
+// any kind, or the 503 error Clientset returns.
+func (c *Client) Dynamic() (dynamic.Interface, error) {
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c.dynamic, nil
+}
+
+// SetClients makes c use clientset and dyn, such as the fakes of
+// k8s.io/client-go/kubernetes/fake and k8s.io/client-go/dynamic/fake, in
+// place of loading a configuration, so tests can run handlers against
+// objects of their own; package tooltest wires them up. ForNamespace then
+// returns clientset too. Passing nil clients unloads c again.
+func (c *Client) SetClients(clientset kubernetes.Interface, dyn dynamic.Interface) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clientset, c.dynamic = clientset, dyn
+	c.cfg, c.base, c.tokens, c.scoped, c.source, c.err = nil, nil, nil, nil, "", nil
+	c.loaded, c.took = time.Time{}, 0
+	if clientset != nil {
+		c.cfg, c.source = &rest.Config{Host: "fake"}, "fake"
+	}
+}
+
+// load loads the configuration and creates the clientset, unless that is
+// done or failed less than retryInterval ago.
+func (c *Client) load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clientset != nil {
+		return nil
+	}
+	if c.err != nil && time.Since(c.tried) < retryInterval {
+		return c.unavailable()
+	}
+	start := time.Now()
+	cfg, source, err := restConfig()
+	if err == nil {
+		cfg, err = c.connect(cfg)
+	}
+	if err != nil {
+		if c.err == nil {
+			slog.Warn("kubernetes client not available; operations that need it will fail", "err", err)
+		}
+		c.err, c.tried = err, time.Now()
+		return c.unavailable()
+	}
+	c.cfg, c.source, c.err = cfg, source, nil
+	c.loaded, c.took = time.Now(), time.Since(start)
+	slog.Info("kubernetes client", "source", source, "host", cfg.Host, "qps", cfg.QPS, "burst", cfg.Burst, "took", c.took.String())
+	return nil
+}
+
+// connect creates the clientsets for cfg and returns the configuration
+// calls are made with: cfg, or, with $KUBE_TOKEN_SERVICE_ACCOUNT, a copy
+// using that service account's tokens. c.mu must be held.
+func (c *Client) connect(cfg *rest.Config) (*rest.Config, error) {
+	c.configure(cfg)
+	base, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.base, c.clientset, c.scoped = base, base, make(map[string]kubernetes.Interface)
+	sa := config.String("KUBE_TOKEN_SERVICE_ACCOUNT", "")
+	if sa == "" {
+		if c.dynamic, err = dynamic.NewForConfig(cfg); err != nil {
+			c.clientset = nil
+			return nil, err
+		}
+		return cfg, nil
+	}
+	namespace, name, ok := strings.Cut(sa, "/")
+	if !ok || namespace == "" || name == "" {
+		c.clientset = nil
+		return nil, fmt.Errorf("KUBE_TOKEN_SERVICE_ACCOUNT must be namespace/name, not %q", sa)
+	}
+	c.tokens = newTokenSource(base, namespace, name)
+	cfg = withToken(cfg, c.tokens)
+	if c.clientset, err = kubernetes.NewForConfig(cfg); err != nil {
+		return nil, err
+	}
+	if c.dynamic, err = dynamic.NewForConfig(cfg); err != nil {
+		c.clientset = nil
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ForNamespace returns a clientset for calls in namespace. With
+// $KUBE_NAMESPACE_SERVICE_ACCOUNT, it calls as that service account of
+// namespace, with requested tokens, so a tool whose Roles are granted per
+// namespace cannot reach beyond it; otherwise, it is Clientset.
+func (c *Client) ForNamespace(namespace string) (kubernetes.Interface, error) {
+	clientset, err := c.Clientset()
+	name := config.String("KUBE_NAMESPACE_SERVICE_ACCOUNT", "")
+	if err != nil || name == "" {
+		return clientset, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.base == nil { // set with SetClients
+		return clientset, nil
+	}
+	if scoped, ok := c.scoped[namespace]; ok {
+		return scoped, nil
+	}
+	scoped, err := kubernetes.NewForConfig(withToken(c.cfg, newTokenSource(c.base, namespace, name)))
+	if err != nil {
+		return nil, toolserver.NewError(toolserver.CodeUnavailable, "kubernetes client for namespace %s not available: %v", namespace, err)
+	}
+	c.scoped[namespace] = scoped
+	return scoped, nil
+}
+
+func (c *Client) unavailable() error {
+	return toolserver.NewError(toolserver.CodeUnavailable, "kubernetes client not available: %v", c.err)
+}
+
+// restConfig returns the in-cluster configuration, or else the one of the
+// kubeconfig context, and where it came from.
+func restConfig() (*rest.Config, string, error) {
+	if cfg, err := rest.InClusterConfig(); err == nil {
+		return cfg, "in-cluster", nil
+	}
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: config.Kubeconfig()},
+		&clientcmd.ConfigOverrides{CurrentContext: config.String("KUBE_CONTEXT", "")})
+	raw, err := loader.RawConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	cfg, err := loader.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	return cfg, "kubeconfig context " + config.String("KUBE_CONTEXT", raw.CurrentContext), nil
+}
+
+// configure applies the rate limit, tags requests with the tool's name in
+// the user agent, so API server audit logs and flow control can tell the
+// tools apart, and counts requests in /metrics.
+func (c *Client) configure(cfg *rest.Config) {
+	cfg.QPS = float32(config.Float("KUBE_QPS", defaultQPS))
+	cfg.Burst = config.Int("KUBE_BURST", defaultBurst)
+	cfg.UserAgent = c.tool + " (kube-mcp) " + rest.DefaultKubernetesUserAgent()
+	instrument(cfg)
+}
+
+func instrument(cfg *rest.Config) {
+	cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return toolserver.InstrumentTransport("kubernetes", rt)
+	})
+}
+
+// Ready is a readiness check for tools that cannot work without the
+// cluster: the kube-apiserver must answer /version with the tool's
+// credentials.
+func (c *Client) Ready(ctx context.Context) error {
+	clientset, err := c.Clientset()
+	if err != nil {
+		return err
+	}
+	return clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+}
+
+// Warm is a warm-up (see Server.AddWarmup) that loads the client and
+// makes its first request, the Ready check, so the first call finds the
+// client built and connected.
+func (c *Client) Warm(ctx context.Context) error {
+	return c.Ready(ctx)
+}
+
+// Allowed is the access check for Server.CheckRBAC: it asks the API server
+// with a SelfSubjectAccessReview, which every authenticated user may create,
+// whether the client may use p in all namespaces. With
+// $KUBE_NAMESPACE_SERVICE_ACCOUNT, calls are made as each namespace's
+// service account, which the tool cannot review as, so p is reported
+// allowed.
+func (c *Client) Allowed(ctx context.Context, p toolserver.Permission) (bool, error) {
+	if config.String("KUBE_NAMESPACE_SERVICE_ACCOUNT", "") != "" {
+		return true, nil
+	}
+	clientset, err := c.Clientset()
+	if err != nil {
+		return false, err
+	}
+	review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:        p.Verb,
+				Group:       p.Group,
+				Resource:    p.Resource,
+				Subresource: p.Subresource,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// Diagnostics returns the client's state for Server.AddDiagnostics:
+// "connected", with where its configuration came from, its rate limit and
+// when and how quickly it loaded, "degraded", with the error, or "not
+// loaded" before first use.
+func (c *Client) Diagnostics() map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.clientset != nil:
+		details := map[string]any{"state": "connected", "source": c.source, "host": c.cfg.Host, "qps": c.cfg.QPS, "burst": c.cfg.Burst}
+		if !c.loaded.IsZero() {
+			details["loaded_at"], details["init_ms"] = c.loaded, float64(c.took.Microseconds())/1000
+		}
+		if c.tokens != nil {
+			maps.Copy(details, c.tokens.diagnostics())
+		}
+		if len(c.scoped) > 0 {
+			details["scoped_namespaces"] = len(c.scoped)
+		}
+		return details
+	case c.err != nil:
+		return map[string]any{"state": "degraded", "error": c.err.Error()}
+	default:
+		return map[string]any{"state": "not loaded"}
+	}
+}
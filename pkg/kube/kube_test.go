@@ -0,0 +1,118 @@
+package kube
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster: {server: "https://dev.example:6443"}
+- name: staging
+  cluster: {server: "https://staging.example:6443"}
+users:
+- name: tools
+  user: {token: secret}
+contexts:
+- name: dev
+  context: {cluster: dev, user: tools}
+- name: staging
+  context: {cluster: staging, user: tools}
+`
+
+func TestClient(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "") // not in a cluster
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(kubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBECONFIG", path)
+	t.Setenv("KUBE_CONTEXT", "staging")
+	t.Setenv("KUBE_QPS", "7")
+
+	c := New("time-tool")
+	if got := c.Diagnostics()["state"]; got != "not loaded" {
+		t.Errorf("state before first use = %v", got)
+	}
+	cfg, err := c.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "https://staging.example:6443" || cfg.QPS != 7 || cfg.Burst != defaultBurst {
+		t.Errorf("config = host %s, QPS %v, burst %d; want the staging cluster, 7 and %d", cfg.Host, cfg.QPS, cfg.Burst, defaultBurst)
+	}
+	if !strings.HasPrefix(cfg.UserAgent, "time-tool (kube-mcp) ") {
+		t.Errorf("user agent %q does not name the tool", cfg.UserAgent)
+	}
+	if d := c.Diagnostics(); d["state"] != "connected" || d["source"] != "kubeconfig context staging" || d["loaded_at"] == nil || d["init_ms"] == nil {
+		t.Errorf("diagnostics = %v", d)
+	}
+}
+
+func TestClientDegraded(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+
+	c := New("kube-info-tool")
+	_, err := c.Clientset()
+	var e *toolserver.Error
+	if !errors.As(err, &e) || e.Code != toolserver.CodeUnavailable || !strings.HasPrefix(e.Message, "kubernetes client not available: ") {
+		t.Fatalf("Clientset without a cluster = %v, want an UNAVAILABLE error", err)
+	}
+	if err := c.Ready(t.Context()); err == nil {
+		t.Error("Ready passed without a cluster")
+	}
+	if d := c.Diagnostics(); d["state"] != "degraded" || d["error"] == "" {
+		t.Errorf("diagnostics = %v, want degraded with the error", d)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	clientset := fake.NewClientset()
+	var reviewed []authorizationv1.ResourceAttributes
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := *review.Spec.ResourceAttributes
+		reviewed = append(reviewed, attrs)
+		review.Status.Allowed = attrs.Resource == "pods" && attrs.Namespace == ""
+		return true, review, nil
+	})
+	c := New("kube-info-tool")
+	c.SetClients(clientset, nil)
+
+	tests := []struct {
+		perm    toolserver.Permission
+		allowed bool
+	}{
+		{toolserver.Permission{Verb: "get", Resource: "pods", Subresource: "log"}, true},
+		{toolserver.Permission{Verb: "list", Group: "policy", Resource: "poddisruptionbudgets"}, false},
+	}
+	for _, tt := range tests {
+		allowed, err := c.Allowed(t.Context(), tt.perm)
+		if err != nil || allowed != tt.allowed {
+			t.Errorf("Allowed(%s) = %v, %v; want %v", tt.perm, allowed, err, tt.allowed)
+		}
+	}
+	want := authorizationv1.ResourceAttributes{Verb: "get", Resource: "pods", Subresource: "log"}
+	if len(reviewed) != 2 || reviewed[0] != want || reviewed[1].Group != "policy" {
+		t.Errorf("reviewed %+v", reviewed)
+	}
+
+	// Calls as per-namespace service accounts cannot be reviewed.
+	t.Setenv("KUBE_NAMESPACE_SERVICE_ACCOUNT", "tools")
+	if allowed, err := c.Allowed(t.Context(), tests[1].perm); err != nil || !allowed {
+		t.Errorf("Allowed with per-namespace service accounts = %v, %v; want allowed", allowed, err)
+	}
+}
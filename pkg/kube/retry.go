@@ -0,0 +1,107 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// defaultAttempts is the number of attempts Retry makes, including the
+	// first, unless $KUBE_RETRIES says otherwise.
+	defaultAttempts = 4
+	// retryBase is the delay before the first retry, doubled before each
+	// one after it, up to retryMax.
+	retryBase = 200 * time.Millisecond
+	retryMax  = 5 * time.Second
+	// maxRetryAfter caps the delay the API server may ask for.
+	maxRetryAfter = 10 * time.Second
+)
+
+var retriesTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "toolserver_kubernetes_retries_total",
+	Help: "Kubernetes API calls retried after a transient error.",
+})
+
+// retriesKey is the context key of the retry counter of CountRetries.
+type retriesKey struct{}
+
+// CountRetries returns a context in which Retry counts its retries, for a
+// handler to report them with Retried.
+func CountRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retriesKey{}, new(atomic.Int32))
+}
+
+// Retried returns how many times the calls made with ctx, a context from
+// CountRetries or derived from one, were retried, or 0 for another context.
+func Retried(ctx context.Context) int {
+	if n, ok := ctx.Value(retriesKey{}).(*atomic.Int32); ok {
+		return int(n.Load())
+	}
+	return 0
+}
+
+// Retry calls fn, and calls it again while it fails with a transient error,
+// so a restarting or overloaded kube-apiserver does not fail the caller's
+// request outright. It waits between attempts with jittered exponential
+// backoff, or as long as the API server asks with Retry-After, and stops
+// after $KUBE_RETRIES attempts in all (default 4), or once ctx is done or
+// its deadline would pass before the next attempt. fn should make a single
+// read, such as a get or list.
+func Retry[T any](ctx context.Context, fn func(context.Context) (T, error)) (T, error) {
+	attempts := config.Int("KUBE_RETRIES", defaultAttempts)
+	delay := retryBase
+	for attempt := 1; ; attempt++ {
+		v, err := fn(ctx)
+		if err == nil || attempt >= attempts || !IsTransient(err) || ctx.Err() != nil {
+			return v, err
+		}
+		wait := delay/2 + rand.N(delay/2+1)
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok && seconds > 0 {
+			wait = min(time.Duration(seconds)*time.Second, maxRetryAfter)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return v, err
+		}
+		slog.DebugContext(ctx, "retrying kubernetes API call", "attempt", attempt, "wait", wait.String(), "err", err)
+		retriesTotal.Inc()
+		if n, ok := ctx.Value(retriesKey{}).(*atomic.Int32); ok {
+			n.Add(1)
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return v, err
+		case <-t.C:
+		}
+		delay = min(2*delay, retryMax)
+	}
+}
+
+// IsTransient reports whether err is worth retrying: throttling, server-side
+// timeouts and unavailability, or a dropped connection.
+func IsTransient(err error) bool {
+	if apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,69 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRetry(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	throttled := apierrors.NewTooManyRequests("slow down", 1)
+	ctx := CountRetries(context.Background())
+
+	calls := 0
+	start := time.Now()
+	v, err := Retry(ctx, func(context.Context) (string, error) {
+		calls++
+		if calls < 3 {
+			if calls == 1 {
+				return "", throttled
+			}
+			return "", apierrors.NewServiceUnavailable("restarting")
+		}
+		return "pods", nil
+	})
+	if err != nil || v != "pods" {
+		t.Fatalf("Retry = %q, %v; want the third attempt's result", v, err)
+	}
+	if calls != 3 || Retried(ctx) != 2 {
+		t.Errorf("%d calls and %d retries, want 3 and 2", calls, Retried(ctx))
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, before the 1s Retry-After", elapsed)
+	}
+
+	calls = 0
+	_, err = Retry(ctx, func(context.Context) (string, error) {
+		calls++
+		return "", apierrors.NewNotFound(pods, "web")
+	})
+	if !apierrors.IsNotFound(err) || calls != 1 {
+		t.Errorf("NotFound: %d calls, err %v; want 1 call and the error", calls, err)
+	}
+
+	t.Setenv("KUBE_RETRIES", "2")
+	calls = 0
+	_, err = Retry(context.Background(), func(context.Context) (string, error) {
+		calls++
+		return "", apierrors.NewInternalError(errors.New("etcd timeout"))
+	})
+	if !apierrors.IsInternalError(err) || calls != 2 {
+		t.Errorf("with KUBE_RETRIES=2: %d calls, err %v; want 2 calls and the error", calls, err)
+	}
+
+	deadline, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	calls = 0
+	_, err = Retry(deadline, func(context.Context) (string, error) {
+		calls++
+		return "", throttled
+	})
+	if err != throttled || calls != 1 {
+		t.Errorf("Retry-After past the deadline: %d calls, err %v; want 1 call and the error", calls, err)
+	}
+}
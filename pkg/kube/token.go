@@ -0,0 +1,106 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// defaultTokenTTL is the default of $KUBE_TOKEN_TTL, the shortest lifetime
+// the API server grants.
+const defaultTokenTTL = 10 * time.Minute
+
+// A tokenSource keeps a short-lived token of a service account current,
+// requesting it with the TokenRequest API with the tool's own credentials.
+type tokenSource struct {
+	client          kubernetes.Interface // with the tool's own credentials
+	namespace, name string               // of the service account
+	audiences       []string             // empty for the API server's own
+	ttl             time.Duration
+
+	mu      sync.Mutex
+	token   string
+	refresh time.Time // when to request the next token
+	expires time.Time
+}
+
+func newTokenSource(client kubernetes.Interface, namespace, name string) *tokenSource {
+	return &tokenSource{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		audiences: config.List("KUBE_TOKEN_AUDIENCES"),
+		ttl:       config.Duration("KUBE_TOKEN_TTL", defaultTokenTTL),
+	}
+}
+
+// get returns the current token, requesting a new one once four fifths of
+// its lifetime have passed. If that fails, the token is used until it
+// expires.
+func (ts *tokenSource) get(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	now := time.Now()
+	if ts.token != "" && now.Before(ts.refresh) {
+		return ts.token, nil
+	}
+	seconds := int64(ts.ttl.Seconds())
+	tr, err := ts.client.CoreV1().ServiceAccounts(ts.namespace).CreateToken(ctx, ts.name, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{Audiences: ts.audiences, ExpirationSeconds: &seconds},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		if ts.token != "" && now.Before(ts.expires) {
+			return ts.token, nil
+		}
+		return "", fmt.Errorf("requesting a token for service account %s/%s: %w", ts.namespace, ts.name, err)
+	}
+	ts.token, ts.expires = tr.Status.Token, tr.Status.ExpirationTimestamp.Time
+	ts.refresh = ts.expires.Add(-ts.expires.Sub(now) / 5)
+	return ts.token, nil
+}
+
+// diagnostics returns the service account and when its token expires.
+func (ts *tokenSource) diagnostics() map[string]any {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	details := map[string]any{"service_account": ts.namespace + "/" + ts.name}
+	if ts.token != "" {
+		details["token_expires_at"] = ts.expires
+	}
+	return details
+}
+
+// tokenTransport authenticates requests with the token of src.
+type tokenTransport struct {
+	rt  http.RoundTripper
+	src *tokenSource
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.src.get(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.rt.RoundTrip(req)
+}
+
+// withToken returns a copy of cfg that authenticates with the tokens of src
+// instead of cfg's own credentials.
+func withToken(cfg *rest.Config, src *tokenSource) *rest.Config {
+	scoped := rest.AnonymousClientConfig(cfg)
+	instrument(scoped)
+	scoped.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &tokenTransport{rt: rt, src: src}
+	})
+	return scoped
+}
@@ -0,0 +1,103 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTokenRequest(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string            // service accounts tokens were requested for
+	authorized := map[string]string{} // path -> bearer token
+	apiserver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if strings.HasSuffix(r.URL.Path, "/token") {
+			if token != "tool" {
+				http.Error(w, "token requests need the tool's own credentials", http.StatusForbidden)
+				return
+			}
+			// /api/v1/namespaces/NS/serviceaccounts/NAME/token
+			parts := strings.Split(r.URL.Path, "/")
+			sa := parts[4] + "/" + parts[6]
+			requested = append(requested, sa)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(authenticationv1.TokenRequest{
+				TypeMeta: metav1.TypeMeta{Kind: "TokenRequest", APIVersion: "authentication.k8s.io/v1"},
+				Status: authenticationv1.TokenRequestStatus{
+					Token:               fmt.Sprintf("%s#%d", sa, len(requested)),
+					ExpirationTimestamp: metav1.NewTime(time.Now().Add(time.Hour)),
+				},
+			})
+			return
+		}
+		authorized[r.URL.Path] = token
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"kind":"ConfigMap","apiVersion":"v1","metadata":{"name":"settings"}}`)
+	}))
+	defer apiserver.Close()
+
+	path := filepath.Join(t.TempDir(), "config")
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- {name: test, cluster: {server: %q, insecure-skip-tls-verify: true}}
+users:
+- {name: tool, user: {token: tool}}
+contexts:
+- {name: test, context: {cluster: test, user: tool}}
+`, apiserver.URL)
+	if err := os.WriteFile(path, []byte(kubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBECONFIG", path)
+	t.Setenv("KUBE_TOKEN_SERVICE_ACCOUNT", "tools/reader")
+	t.Setenv("KUBE_NAMESPACE_SERVICE_ACCOUNT", "tool-scoped")
+
+	c := New("hash-tool")
+	clientset, err := c.Clientset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, err := clientset.CoreV1().ConfigMaps("default").Get(t.Context(), "settings", metav1.GetOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scoped, err := c.ForNamespace("team-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scoped.CoreV1().ConfigMaps("team-a").Get(t.Context(), "settings", metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := authorized["/api/v1/namespaces/default/configmaps/settings"]; got != "tools/reader#1" {
+		t.Errorf("calls were made with token %q, want the reader's first token", got)
+	}
+	if got := authorized["/api/v1/namespaces/team-a/configmaps/settings"]; !strings.HasPrefix(got, "team-a/tool-scoped#") {
+		t.Errorf("namespaced calls were made with token %q, want team-a's service account's", got)
+	}
+	if len(requested) != 2 {
+		t.Errorf("tokens were requested for %v, want one each for the reader and team-a", requested)
+	}
+	if d := c.Diagnostics(); d["service_account"] != "tools/reader" || d["scoped_namespaces"] != 1 {
+		t.Errorf("diagnostics = %v", d)
+	}
+}
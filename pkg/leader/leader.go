@@ -0,0 +1,180 @@
+// Package leader runs work that should not be repeated by every replica of
+// a tool, such as a watch keeping a cache current, on one replica at a
+// time, elected with a coordination.k8s.io Lease. The leader publishes what
+// it builds as a Snapshot, which the other replicas read instead of
+// watching the kube-apiserver themselves, so scaling a Deployment out does
+// not multiply its API load.
+//
+// Election is optional: unless $LEADER_ELECTION is true, every replica
+// leads, as a single replica would.
+package leader
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// The defaults of the lease timings, those of Kubernetes' own controllers.
+const (
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewDeadline = 10 * time.Second
+	defaultRetryPeriod   = 2 * time.Second
+)
+
+// namespaceFile holds the namespace of the pod's service account.
+const namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+var isLeader = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "toolserver_leader",
+	Help: "Whether this replica holds the lease: 1 if it does, 0 if not.",
+}, []string{"lease"})
+
+// An Elector elects the replica of a tool that runs its shared work.
+type Elector struct {
+	client    kubernetes.Interface // nil when election is off
+	namespace string
+	lease     string
+	identity  string
+
+	leaseDuration, renewDeadline, retryPeriod time.Duration
+
+	leading atomic.Bool
+	leader  atomic.Value // string: the identity of the last observed leader
+}
+
+// New returns the elector of the named tool's replicas, which compete for
+// the Lease "<name>-leader" when $LEADER_ELECTION is true. The Lease is in
+// $LEADER_ELECTION_NAMESPACE, else $POD_NAMESPACE, else the namespace of
+// the pod's service account, and each replica is identified by $POD_NAME or
+// else its hostname. $LEADER_LEASE_DURATION, $LEADER_RENEW_DEADLINE and
+// $LEADER_RETRY_PERIOD override the lease timings (15s, 10s and 2s).
+//
+// The service account needs get, create and update on leases in the
+// coordination.k8s.io group, and on configmaps for snapshots.
+func New(client kubernetes.Interface, name string) *Elector {
+	e := &Elector{
+		namespace:     namespace(),
+		lease:         name + "-leader",
+		identity:      identity(),
+		leaseDuration: config.Duration("LEADER_LEASE_DURATION", defaultLeaseDuration),
+		renewDeadline: config.Duration("LEADER_RENEW_DEADLINE", defaultRenewDeadline),
+		retryPeriod:   config.Duration("LEADER_RETRY_PERIOD", defaultRetryPeriod),
+	}
+	if config.Bool("LEADER_ELECTION", false) {
+		if client == nil {
+			slog.Warn("leader election needs a Kubernetes client; every replica will lead", "lease", e.lease)
+		}
+		e.client = client
+	}
+	e.leader.Store("")
+	return e
+}
+
+func namespace() string {
+	if ns := config.String("LEADER_ELECTION_NAMESPACE", config.String("POD_NAMESPACE", "")); ns != "" {
+		return ns
+	}
+	if data, err := os.ReadFile(namespaceFile); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return "default"
+}
+
+func identity() string {
+	if name := config.String("POD_NAME", ""); name != "" {
+		return name
+	}
+	host, _ := os.Hostname()
+	return host
+}
+
+// Enabled reports whether the replicas elect a leader.
+func (e *Elector) Enabled() bool { return e.client != nil }
+
+// IsLeader reports whether this replica currently leads. With election off,
+// it always does.
+func (e *Elector) IsLeader() bool { return !e.Enabled() || e.leading.Load() }
+
+// Run runs fn while this replica leads, until ctx is done. fn's context is
+// cancelled when the replica loses the lease, and fn runs again if it wins
+// the lease back, so fn should start its watches afresh each time. With
+// election off, fn runs once, until ctx is done. Run returns when ctx is
+// done; a leader then releases the lease, so another replica takes over at
+// once rather than after the lease expires.
+func (e *Elector) Run(ctx context.Context, fn func(ctx context.Context)) {
+	if !e.Enabled() {
+		fn(ctx)
+		return
+	}
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Namespace: e.namespace, Name: e.lease},
+		Client:     e.client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: e.identity},
+	}
+	gauge := isLeader.WithLabelValues(e.lease)
+	// running is held while fn runs, so a replica that loses the lease
+	// and wins it back does not run fn again before it has stopped.
+	var running sync.Mutex
+	for ctx.Err() == nil {
+		le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			Name:            e.lease,
+			LeaseDuration:   e.leaseDuration,
+			RenewDeadline:   e.renewDeadline,
+			RetryPeriod:     e.retryPeriod,
+			ReleaseOnCancel: true,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					running.Lock()
+					defer running.Unlock()
+					e.leading.Store(true)
+					gauge.Set(1)
+					slog.Info("leading", "lease", e.lease, "identity", e.identity)
+					fn(ctx)
+				},
+				OnStoppedLeading: func() {
+					if e.leading.Swap(false) {
+						slog.Warn("lost the lease", "lease", e.lease, "identity", e.identity)
+					}
+					gauge.Set(0)
+				},
+				OnNewLeader: func(identity string) {
+					e.leader.Store(identity)
+					if identity != e.identity {
+						slog.Info("following", "lease", e.lease, "leader", identity)
+					}
+				},
+			},
+		})
+		if err != nil {
+			slog.Error("invalid leader election settings; every replica will lead", "lease", e.lease, "err", err)
+			fn(ctx)
+			return
+		}
+		le.Run(ctx)
+	}
+}
+
+// Diagnostics returns the state of the election, for
+// Server.AddDiagnostics.
+func (e *Elector) Diagnostics() map[string]any {
+	details := map[string]any{"enabled": e.Enabled(), "is_leader": e.IsLeader(), "identity": e.identity}
+	if e.Enabled() {
+		details["lease"] = e.namespace + "/" + e.lease
+		details["leader"] = e.leader.Load()
+	}
+	return details
+}
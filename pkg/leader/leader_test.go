@@ -0,0 +1,93 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestElection(t *testing.T) {
+	t.Setenv("LEADER_ELECTION", "true")
+	t.Setenv("POD_NAMESPACE", "tools")
+	client := fake.NewClientset()
+	elector := func(name string) *Elector {
+		t.Setenv("POD_NAME", name)
+		e := New(client, "dns-tool")
+		e.leaseDuration, e.renewDeadline, e.retryPeriod = time.Second, 500*time.Millisecond, 100*time.Millisecond
+		return e
+	}
+	first, second := elector("dns-tool-0"), elector("dns-tool-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	published := NewSnapshot[map[string]int](first, "endpoints")
+	led := make(chan string, 2)
+	for _, e := range []*Elector{first, second} {
+		go e.Run(ctx, func(ctx context.Context) {
+			led <- e.identity
+			if err := published.Publish(ctx, map[string]int{"web": 3}); err != nil {
+				t.Error(err)
+			}
+			<-ctx.Done()
+		})
+	}
+	var leader string
+	select {
+	case leader = <-led:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no replica was elected")
+	}
+	follower := second
+	if leader == second.identity {
+		follower = first
+	}
+	if follower.IsLeader() {
+		t.Errorf("%s leads, but %s reports that it leads too", leader, follower.identity)
+	}
+
+	followed := NewSnapshot[map[string]int](follower, "endpoints")
+	go followed.Follow(ctx, 50*time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if v, _, ok := followed.Get(); ok {
+			if v["web"] != 3 {
+				t.Errorf("follower read %v, want the leader's snapshot", v)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the follower never read the snapshot")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	select {
+	case id := <-led:
+		t.Errorf("%s led as well", id)
+	case <-time.After(300 * time.Millisecond):
+	}
+	if d := follower.Diagnostics(); d["leader"] != leader || d["is_leader"] != false {
+		t.Errorf("follower diagnostics = %v", d)
+	}
+}
+
+func TestElectionDisabled(t *testing.T) {
+	e := New(fake.NewClientset(), "time-tool")
+	if e.Enabled() || !e.IsLeader() {
+		t.Fatalf("with LEADER_ELECTION unset, Enabled = %t and IsLeader = %t, want false and true", e.Enabled(), e.IsLeader())
+	}
+	ran := false
+	e.Run(context.Background(), func(context.Context) { ran = true })
+	if !ran {
+		t.Error("fn did not run")
+	}
+	s := NewSnapshot[string](e, "zones")
+	if err := s.Publish(context.Background(), "UTC"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _, ok := s.Get(); !ok || v != "UTC" {
+		t.Errorf("Get = %q, %t, want the published value", v, ok)
+	}
+}
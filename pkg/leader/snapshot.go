@@ -0,0 +1,142 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// snapshotKey is the ConfigMap key holding a snapshot's JSON.
+	snapshotKey = "snapshot.json"
+	// publishedAnnotation records when the snapshot was published.
+	publishedAnnotation = "kube-mcp.atippey.github.io/published-at"
+	// maxSnapshotSize keeps a snapshot within the 1 MiB limit of a
+	// ConfigMap, leaving room for its metadata.
+	maxSnapshotSize = 1000 << 10
+)
+
+// A Snapshot is state the leader builds, such as a cache kept current by a
+// watch, shared with the other replicas through a ConfigMap so they can
+// serve reads from it. With election off, it is kept in memory only.
+type Snapshot[T any] struct {
+	elector *Elector
+	name    string // of the ConfigMap
+
+	mu        sync.RWMutex
+	value     T
+	published time.Time // zero until there is a snapshot
+	version   string    // the ConfigMap's resourceVersion last read
+}
+
+// NewSnapshot returns the snapshot kept in the ConfigMap "<lease>-<name>",
+// next to the elector's Lease, e.g. "dns-tool-leader-endpoints".
+func NewSnapshot[T any](e *Elector, name string) *Snapshot[T] {
+	return &Snapshot[T]{elector: e, name: e.lease + "-" + name}
+}
+
+// Get returns the latest snapshot and when it was published, or false if
+// none has been yet.
+func (s *Snapshot[T]) Get() (v T, published time.Time, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value, s.published, !s.published.IsZero()
+}
+
+// Publish replaces the snapshot with v, on this replica and, with election
+// on, in the ConfigMap for the others. Only the leader publishes, from the
+// fn it passes to Elector.Run. v must marshal to JSON within about 1 MiB.
+func (s *Snapshot[T]) Publish(ctx context.Context, v T) error {
+	now := time.Now()
+	s.mu.Lock()
+	s.value, s.published = v, now
+	s.mu.Unlock()
+	if !s.elector.Enabled() {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxSnapshotSize {
+		return fmt.Errorf("snapshot %s is %d bytes, over the limit of %d", s.name, len(data), maxSnapshotSize)
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        s.name,
+			Namespace:   s.elector.namespace,
+			Annotations: map[string]string{publishedAnnotation: now.UTC().Format(time.RFC3339Nano)},
+		},
+		Data: map[string]string{snapshotKey: string(data)},
+	}
+	configMaps := s.elector.client.CoreV1().ConfigMaps(s.elector.namespace)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("publishing snapshot %s: %w", s.name, err)
+	}
+	return nil
+}
+
+// Follow reads the snapshot the leader publishes every interval while this
+// replica does not lead, until ctx is done. Each read is a single GET of the
+// ConfigMap, however much the snapshot cost the leader to build. With
+// election off, Follow returns at once.
+func (s *Snapshot[T]) Follow(ctx context.Context, interval time.Duration) {
+	if !s.elector.Enabled() {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if !s.elector.IsLeader() {
+			if err := s.refresh(ctx); err != nil && ctx.Err() == nil {
+				slog.Warn("reading snapshot", "snapshot", s.name, "err", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// refresh reads the snapshot from its ConfigMap, if it has changed.
+func (s *Snapshot[T]) refresh(ctx context.Context) error {
+	cm, err := s.elector.client.CoreV1().ConfigMaps(s.elector.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil // not published yet
+	}
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	unchanged := cm.ResourceVersion != "" && cm.ResourceVersion == s.version
+	s.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal([]byte(cm.Data[snapshotKey]), &v); err != nil {
+		return err
+	}
+	published, err := time.Parse(time.RFC3339Nano, cm.Annotations[publishedAnnotation])
+	if err != nil {
+		published = cm.CreationTimestamp.Time
+	}
+	s.mu.Lock()
+	s.value, s.published, s.version = v, published, cm.ResourceVersion
+	s.mu.Unlock()
+	return nil
+}
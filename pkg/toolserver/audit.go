@@ -0,0 +1,256 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+const (
+	// defaultAuditRecent is how many entries /audit/recent keeps by default.
+	defaultAuditRecent = 100
+	// maxAuditString is the length past which string parameters are cut
+	// short in audit entries, so large inputs do not bloat the log.
+	maxAuditString = 256
+	// redacted replaces the values of sensitive parameters.
+	redacted = "[REDACTED]"
+)
+
+// sensitiveParams are the parameter names whose values are never recorded,
+// in addition to those ending in _password, _secret or _token and those an
+// operation marks with Sensitive.
+var sensitiveParams = []string{
+	"password", "passphrase", "secret", "token", "key", "private_key",
+	"api_key", "client_secret", "credentials", "authorization", "cookie",
+}
+
+// AuditEntry records one call of an operation, over any transport.
+type AuditEntry struct {
+	Time       time.Time       `json:"time"`
+	RequestID  string          `json:"request_id,omitempty"`
+	User       string          `json:"user,omitempty"`
+	Issuer     string          `json:"issuer,omitempty"`
+	Tool       string          `json:"tool"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	Outcome    string          `json:"outcome"` // "ok" or "error"
+	Status     int             `json:"status"`
+	Error      string          `json:"error,omitempty"`
+	DurationMS float64         `json:"duration_ms"`
+}
+
+// AuditResponse is the body of /audit/recent, newest entry first.
+type AuditResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+// auditLog appends an entry for every call to its output, if any, and keeps
+// the most recent in memory for /audit/recent.
+type auditLog struct {
+	mu      sync.Mutex
+	readers []string  // users, groups or roles who may read every entry
+	out     io.Writer // nil when entries are only kept in memory
+	path    string    // "stdout" or the file out writes to
+	recent  []AuditEntry
+	next    int // index in recent of the next entry once it is full
+	size    int
+}
+
+// auditFromConfig configures the audit log from the settings:
+//
+//	AUDIT_LOG      "stdout" for JSON lines on stdout, or a file to append
+//	               them to; unset keeps entries in memory only
+//	AUDIT_RECENT   entries kept for /audit/recent, default 100
+//	AUDIT_READERS  comma-separated users, groups or roles who may read every
+//	               entry from /audit/recent; when authentication is on,
+//	               other callers see only their own
+//
+// A log file that cannot be opened is fatal, since calls would otherwise go
+// unrecorded.
+func auditFromConfig() (*auditLog, error) {
+	a := &auditLog{
+		readers: config.List("AUDIT_READERS"),
+		size:    max(config.Int("AUDIT_RECENT", defaultAuditRecent), 0),
+	}
+	switch a.path = config.String("AUDIT_LOG", ""); a.path {
+	case "":
+	case "stdout":
+		a.out = os.Stdout
+	default:
+		f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		a.out = f
+	}
+	return a, nil
+}
+
+// record adds the entry for a call of op with the request body.
+func (a *auditLog) record(ctx context.Context, op *operation, body []byte, start time.Time, err error) {
+	e := AuditEntry{
+		Time:       start.UTC(),
+		RequestID:  RequestID(ctx),
+		Tool:       op.name,
+		Params:     sanitizeParams(body, op.sensitive),
+		Outcome:    "ok",
+		Status:     http.StatusOK,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if id := IdentityFrom(ctx); id != nil {
+		e.User, e.Issuer = id.Username, id.Issuer
+	}
+	if err != nil {
+		e.Outcome, e.Status, e.Error = "error", errorStatus(err), err.Error()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.out != nil {
+		line, _ := json.Marshal(e)
+		if _, err := a.out.Write(append(line, '\n')); err != nil {
+			slog.ErrorContext(ctx, "writing audit log", "path", a.path, "err", err)
+		}
+	}
+	if a.size == 0 {
+		return
+	}
+	if len(a.recent) < a.size {
+		a.recent = append(a.recent, e)
+		return
+	}
+	a.recent[a.next] = e
+	a.next = (a.next + 1) % a.size
+}
+
+// useStderr moves an audit log on stdout to stderr, for the stdio transport,
+// where stdout carries the MCP messages.
+func (a *auditLog) useStderr() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.out == os.Stdout {
+		slog.Warn("writing the audit log to stderr: stdout carries MCP over stdio")
+		a.out = os.Stderr
+	}
+}
+
+// entries returns up to limit of the recorded entries, newest first, that
+// keep returns true for.
+func (a *auditLog) entries(limit int, keep func(AuditEntry) bool) []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := []AuditEntry{}
+	for i := range a.recent {
+		e := a.recent[(a.next+len(a.recent)-1-i)%len(a.recent)]
+		if len(out) == limit {
+			break
+		}
+		if keep(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// setReaders replaces the users, groups and roles who may read every entry.
+func (a *auditLog) setReaders(readers []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.readers = readers
+}
+
+// canReadAll reports whether id may read every caller's entries.
+func (a *auditLog) canReadAll(id *Identity) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, r := range a.readers {
+		if id.Username == r || slices.Contains(id.Groups, r) || id.HasRole(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAuditRecent serves /audit/recent: the most recent calls, newest
+// first, optionally filtered by ?tool= and ?user= and capped by ?limit=.
+func (s *Server) handleAuditRecent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+	q := r.URL.Query()
+	limit := -1
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			WriteError(w, BadRequest("limit must be a non-negative integer"))
+			return
+		}
+		limit = n
+	}
+	tool, user := q.Get("tool"), q.Get("user")
+	if id := IdentityFrom(r.Context()); id != nil && !s.audit.canReadAll(id) {
+		if user != "" && user != id.Username {
+			WriteError(w, Errorf(http.StatusForbidden, "only your own calls can be read"))
+			return
+		}
+		user = id.Username
+	}
+
+	entries := s.audit.entries(limit, func(e AuditEntry) bool {
+		return (tool == "" || e.Tool == tool) && (user == "" || e.User == user)
+	})
+	WriteJSON(w, http.StatusOK, AuditResponse{Entries: entries})
+}
+
+// sanitizeParams returns the request body for the audit log with the values
+// of sensitive parameters redacted and long strings truncated.
+func sanitizeParams(body []byte, sensitive []string) json.RawMessage {
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		data, _ := json.Marshal("invalid JSON, " + strconv.Itoa(len(body)) + " bytes")
+		return data
+	}
+	data, _ := json.Marshal(redact(v, sensitive))
+	return data
+}
+
+func redact(v any, sensitive []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if isSensitive(k, sensitive) {
+				v[k] = redacted
+			} else {
+				v[k] = redact(child, sensitive)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = redact(child, sensitive)
+		}
+	case string:
+		if len(v) > maxAuditString {
+			return v[:maxAuditString] + "... (" + strconv.Itoa(len(v)) + " bytes)"
+		}
+	}
+	return v
+}
+
+func isSensitive(name string, extra []string) bool {
+	name = strings.ToLower(name)
+	return slices.Contains(sensitiveParams, name) || slices.Contains(extra, name) ||
+		strings.HasSuffix(name, "_password") || strings.HasSuffix(name, "_secret") || strings.HasSuffix(name, "_token")
+}
@@ -0,0 +1,122 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	t.Setenv("AUDIT_LOG", path)
+	t.Setenv("AUDIT_RECENT", "2")
+	t.Setenv("AUDIT_READERS", "auditors")
+	s := New("vault")
+	Register(s, "/login", func(ctx context.Context, req struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+		Note     string `json:"note"`
+	}) (echoResponse, error) {
+		if req.User == "" {
+			return echoResponse{}, BadRequest("user is required")
+		}
+		return echoResponse{Echo: req.User}, nil
+	}, Sensitive("Note"))
+
+	for _, body := range []string{
+		`{"user":"alice","password":"hunter2","note":"x"}`,
+		`{"password":"` + strings.Repeat("p", 300) + `"}`,
+	} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+	}
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/rpc",
+		strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"login","params":{"user":"`+strings.Repeat("b", 300)+`"}}`)))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("audit log has %d lines, want 3:\n%s", len(lines), data)
+	}
+	var first AuditEntry
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Tool != "login" || first.Outcome != "ok" || first.RequestID == "" ||
+		string(first.Params) != `{"note":"[REDACTED]","password":"[REDACTED]","user":"alice"}` {
+		t.Errorf("first entry = %+v, params %s", first, first.Params)
+	}
+
+	// Only the two most recent calls are kept, newest first.
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/audit/recent", nil))
+	var resp AuditResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Entries) != 2 {
+		t.Fatalf("/audit/recent = %s, want 2 entries", rec.Body)
+	}
+	if e := resp.Entries[0]; !strings.Contains(string(e.Params), "... (300 bytes)") || e.Outcome != "ok" {
+		t.Errorf("newest entry = %+v, params %s, want the truncated user", e, e.Params)
+	}
+	if e := resp.Entries[1]; e.Outcome != "error" || e.Status != http.StatusBadRequest || e.Error != "user is required" {
+		t.Errorf("older entry = %+v, want the failed call", e)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/audit/recent?limit=x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit = %d, want 400", rec.Code)
+	}
+}
+
+func TestAuditRecentAccess(t *testing.T) {
+	t.Setenv("AUDIT_READERS", "auditors")
+	s := newEchoServer()
+	for _, user := range []string{"alice", "bob", "alice"} {
+		ctx := context.WithValue(context.Background(), identityKey{}, &Identity{Username: user})
+		req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/echo", strings.NewReader(`{"message":"hi"}`))
+		s.lookup("echo").ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	tests := []struct {
+		name   string
+		id     *Identity
+		query  string
+		status int
+		want   int
+	}{
+		{"auditor", &Identity{Username: "carol", Groups: []string{"auditors"}}, "", http.StatusOK, 3},
+		{"auditor filtering", &Identity{Username: "carol", Groups: []string{"auditors"}}, "?user=bob", http.StatusOK, 1},
+		{"own calls", &Identity{Username: "alice"}, "?limit=1", http.StatusOK, 1},
+		{"other caller", &Identity{Username: "bob"}, "?user=alice", http.StatusForbidden, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), identityKey{}, tt.id)
+			rec := httptest.NewRecorder()
+			s.handleAuditRecent(rec, httptest.NewRequestWithContext(ctx, http.MethodGet, "/audit/recent"+tt.query, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			var resp AuditResponse
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			if len(resp.Entries) != tt.want {
+				t.Errorf("got %d entries, want %d", len(resp.Entries), tt.want)
+			}
+			for _, e := range resp.Entries {
+				if !tt.id.HasRole("auditors") && len(tt.id.Groups) == 0 && e.User != tt.id.Username {
+					t.Errorf("%s read %s's call", tt.id.Username, e.User)
+				}
+			}
+		})
+	}
+}
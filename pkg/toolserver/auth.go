@@ -0,0 +1,354 @@
+package toolserver
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+const (
+	// jwksRefreshInterval is how long fetched signing keys are used before
+	// they are fetched again.
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefresh limits refetches for tokens signed with an unknown key
+	// ID, which is how key rotation shows, so bogus tokens cannot flood
+	// the issuer.
+	jwksMinRefresh = time.Minute
+	// tokenLeeway allows for clock skew in exp and nbf.
+	tokenLeeway = time.Minute
+)
+
+// Identity is the caller authenticated from a bearer token or a client
+// certificate.
+type Identity struct {
+	Issuer   string
+	Subject  string
+	Username string   // from $OIDC_USERNAME_CLAIM, default "sub"
+	Groups   []string // from $OIDC_GROUPS_CLAIM, default "groups"
+	Claims   map[string]any
+	Roles    []string // mapped from the client certificate by $TLS_CLIENT_ROLES
+}
+
+// HasRole reports whether the identity was granted role.
+func (id *Identity) HasRole(role string) bool {
+	return id != nil && slices.Contains(id.Roles, role)
+}
+
+type identityKey struct{}
+
+// IdentityFrom returns the authenticated caller of the request being served
+// in ctx, or nil if authentication is off or the call came over stdio. Tools
+// can record it or impersonate it in their Kubernetes requests.
+func IdentityFrom(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// authenticator verifies bearer tokens issued by one OIDC provider.
+type authenticator struct {
+	issuer        string // required iss, if set
+	jwksURL       string // if empty, discovered from the issuer
+	audiences     []string
+	claims        map[string]string // required claim values
+	usernameClaim string
+	groupsClaim   string
+	client        *http.Client
+
+	mu         sync.Mutex
+	keys       []signingKey
+	fetched    time.Time
+	refreshing chan struct{} // closed when the fetch in progress ends
+}
+
+// authFromConfig configures authentication from the settings. It returns nil,
+// leaving every endpoint open, unless $OIDC_ISSUER or $OIDC_JWKS_URL is set:
+//
+//	OIDC_ISSUER           issuer URL; tokens' iss must match, and the JWKS is
+//	                      discovered from it unless OIDC_JWKS_URL is set
+//	OIDC_JWKS_URL         URL of the signing keys
+//	OIDC_AUDIENCE         comma-separated; a token's aud must include one
+//	OIDC_REQUIRED_CLAIMS  comma-separated claim=value pairs; array claims
+//	                      such as groups must contain the value
+//	OIDC_USERNAME_CLAIM   claim naming the caller, default "sub"
+//	OIDC_GROUPS_CLAIM     claim listing the caller's groups, default "groups"
+func authFromConfig() *authenticator {
+	issuer := config.String("OIDC_ISSUER", "")
+	jwksURL := config.String("OIDC_JWKS_URL", "")
+	if issuer == "" && jwksURL == "" {
+		return nil
+	}
+
+	a := &authenticator{
+		issuer:        issuer,
+		jwksURL:       jwksURL,
+		audiences:     strings.FieldsFunc(config.String("OIDC_AUDIENCE", ""), func(r rune) bool { return r == ',' || r == ' ' }),
+		claims:        parseKeyValues(config.String("OIDC_REQUIRED_CLAIMS", "")),
+		usernameClaim: config.String("OIDC_USERNAME_CLAIM", "sub"),
+		groupsClaim:   config.String("OIDC_GROUPS_CLAIM", "groups"),
+		client:        &http.Client{Timeout: 10 * time.Second, Transport: InstrumentTransport("oidc", nil)},
+	}
+	if len(a.audiences) == 0 {
+		slog.Warn("OIDC_AUDIENCE is not set; accepting tokens issued for any audience")
+	}
+	slog.Info("authenticating requests", "issuer", issuer, "jwks", jwksURL, "audience", a.audiences)
+	return a
+}
+
+// authenticate verifies the bearer token in an Authorization header value.
+func (a *authenticator) authenticate(ctx context.Context, authorization string) (*Identity, error) {
+	scheme, token, _ := strings.Cut(authorization, " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, errUnauthenticated
+	}
+	t, err := parseJWT(strings.TrimSpace(token))
+	if err != nil {
+		return nil, invalidToken(err.Error())
+	}
+	key, err := a.key(ctx, t.header.Kid, t.header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.verify(key); err != nil {
+		return nil, invalidToken(err.Error())
+	}
+	return a.identity(t.claims, time.Now())
+}
+
+// identity checks verified claims and extracts the caller from them.
+func (a *authenticator) identity(claims map[string]any, now time.Time) (*Identity, error) {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, invalidToken("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(tokenLeeway)) {
+		return nil, invalidToken("token has expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(tokenLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, invalidToken("token is not valid yet")
+	}
+	iss, _ := claims["iss"].(string)
+	if a.issuer != "" && iss != a.issuer {
+		return nil, invalidToken("token issuer does not match")
+	}
+	if len(a.audiences) > 0 && !slices.ContainsFunc(a.audiences, func(aud string) bool { return claimHas(claims["aud"], aud) }) {
+		return nil, invalidToken("token audience does not match")
+	}
+	for claim, want := range a.claims {
+		if !claimHas(claims[claim], want) {
+			return nil, Errorf(http.StatusForbidden, "token claim %s does not allow access", claim)
+		}
+	}
+
+	id := &Identity{Issuer: iss, Claims: claims}
+	id.Subject, _ = claims["sub"].(string)
+	id.Username = claimString(claims[a.usernameClaim])
+	if groups, ok := claims[a.groupsClaim].([]any); ok {
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				id.Groups = append(id.Groups, s)
+			}
+		}
+	}
+	if id.Username == "" {
+		return nil, invalidToken(fmt.Sprintf("token has no %s claim", a.usernameClaim))
+	}
+	return id, nil
+}
+
+// claimHas reports whether a claim equals want or, for an array claim,
+// contains it. Non-string values are compared in their JSON form, so
+// email_verified=true matches a boolean.
+func claimHas(claim any, want string) bool {
+	if values, ok := claim.([]any); ok {
+		return slices.ContainsFunc(values, func(v any) bool { return claimString(v) == want })
+	}
+	return claim != nil && claimString(claim) == want
+}
+
+func claimString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	if v == nil {
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// key returns the issuer's key for a token's kid and alg, fetching the key
+// set when it is stale or does not have the key. The fetch runs without
+// a.mu held, and calls that need keys while it runs wait for it rather than
+// fetch again.
+func (a *authenticator) key(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	a.mu.Lock()
+	key, age, done := a.find(kid, alg), time.Since(a.fetched), a.refreshing
+	switch {
+	case key != nil && age < jwksRefreshInterval:
+		a.mu.Unlock()
+		return key, nil
+	case done != nil:
+		a.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	case a.keys == nil || age >= jwksMinRefresh:
+		done = make(chan struct{})
+		a.refreshing, a.fetched = done, time.Now()
+		a.mu.Unlock()
+		// Detached from the caller, so that its going away does not fail
+		// the calls waiting for the keys.
+		keys, err := a.fetchKeys(context.WithoutCancel(ctx))
+		if err != nil {
+			slog.WarnContext(ctx, "fetching OIDC signing keys failed", "err", err)
+		}
+		a.mu.Lock()
+		if err == nil {
+			a.keys = keys
+		}
+		a.refreshing = nil
+		a.mu.Unlock()
+		close(done)
+	default:
+		a.mu.Unlock()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if key := a.find(kid, alg); key != nil {
+		return key, nil
+	}
+	if a.keys == nil {
+		return nil, Errorf(http.StatusServiceUnavailable, "cannot fetch token signing keys")
+	}
+	return nil, invalidToken("token signing key is unknown")
+}
+
+// find returns the key of the current set for kid and alg, or nil. The
+// caller holds a.mu.
+func (a *authenticator) find(kid, alg string) crypto.PublicKey {
+	for _, k := range a.keys {
+		if (kid == "" || k.kid == kid) && k.fits(alg) {
+			return k.key
+		}
+	}
+	return nil
+}
+
+// fetchKeys fetches the key set, discovering its URL from the issuer if need
+// be. Only the call that started a refresh runs it, so it can set a.jwksURL
+// without a.mu.
+func (a *authenticator) fetchKeys(ctx context.Context) ([]signingKey, error) {
+	if a.jwksURL == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := a.getJSON(ctx, strings.TrimSuffix(a.issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, err
+		}
+		if discovery.Issuer != a.issuer || discovery.JWKSURI == "" {
+			return nil, fmt.Errorf("discovery document of %s names issuer %q and jwks_uri %q", a.issuer, discovery.Issuer, discovery.JWKSURI)
+		}
+		a.jwksURL = discovery.JWKSURI
+	}
+
+	var raw json.RawMessage
+	if err := a.getJSON(ctx, a.jwksURL, &raw); err != nil {
+		return nil, err
+	}
+	return parseJWKS(raw)
+}
+
+func (a *authenticator) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+var errUnauthenticated = Errorf(http.StatusUnauthorized, "bearer token required")
+
+func invalidToken(reason string) *Error {
+	return Errorf(http.StatusUnauthorized, "invalid token: %s", reason)
+}
+
+// requireAuth wraps the handler registered for pattern to reject requests
+// without a valid client certificate or bearer token, whichever are
+// configured. Probe and scrape endpoints stay open.
+func (s *Server) requireAuth(pattern string, h http.Handler) http.Handler {
+	if probePatterns[pattern] {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil && s.certAuth == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		id, err := s.authenticate(r)
+		if err != nil {
+			var e *Error
+			if s.auth != nil && err != errClientCertRequired && errors.As(err, &e) && e.Status == http.StatusUnauthorized {
+				challenge := "Bearer"
+				if e != errUnauthenticated {
+					challenge = `Bearer error="invalid_token"`
+				}
+				w.Header().Set("WWW-Authenticate", challenge)
+			}
+			slog.InfoContext(r.Context(), "authentication failed", "err", err)
+			WriteError(w, err)
+			return
+		}
+		if user, ok := r.Context().Value(requestUserKey{}).(**Identity); ok {
+			*user = id
+		}
+		spanFromContext(r.Context()).SetAttr("enduser.id", id.Username)
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+	})
+}
+
+// authenticate checks the request's client certificate and bearer token. If
+// both are required, the identity is the token's, with the certificate's
+// roles.
+func (s *Server) authenticate(r *http.Request) (*Identity, error) {
+	var id *Identity
+	if s.certAuth != nil {
+		var err error
+		if id, err = s.certAuth.authenticate(r.TLS); err != nil {
+			return nil, err
+		}
+	}
+	if s.auth != nil {
+		tokenID, err := s.auth.authenticate(r.Context(), r.Header.Get("Authorization"))
+		if err != nil {
+			return nil, err
+		}
+		if id != nil {
+			tokenID.Roles = id.Roles
+		}
+		id = tokenID
+	}
+	return id, nil
+}
@@ -0,0 +1,255 @@
+package toolserver
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testIssuer is an OIDC provider serving discovery and a JWKS.
+type testIssuer struct {
+	*httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	edKey  ed25519.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	iss := &testIssuer{}
+	iss.rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	iss.ecKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, iss.edKey, _ = ed25519.GenerateKey(rand.Reader)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"issuer": iss.URL, "jwks_uri": iss.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		ec := iss.ecKey.PublicKey
+		WriteJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64url.EncodeToString(iss.rsaKey.N.Bytes()), "e": "AQAB"},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64url.EncodeToString(ec.X.FillBytes(make([]byte, 32))), "y": b64url.EncodeToString(ec.Y.FillBytes(make([]byte, 32)))},
+			{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64url.EncodeToString(iss.edKey.Public().(ed25519.PublicKey))},
+			{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		}})
+	})
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+// sign returns a token with the given header alg and kid, signed with the
+// matching key.
+func (iss *testIssuer) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64url.EncodeToString(header) + "." + b64url.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	var err error
+	switch alg {
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, iss.rsaKey, crypto.SHA256, digest[:])
+	case "PS256":
+		sig, err = rsa.SignPSS(rand.Reader, iss.rsaKey, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, iss.ecKey, digest[:])
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case "EdDSA":
+		sig = ed25519.Sign(iss.edKey, []byte(signed))
+	case "HS256":
+		// Keyed with public material, as in the alg-confusion attack.
+		mac := hmac.New(sha256.New, iss.rsaKey.N.Bytes())
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + b64url.EncodeToString(sig)
+}
+
+func TestAuthentication(t *testing.T) {
+	iss := newTestIssuer(t)
+	t.Setenv("OIDC_ISSUER", iss.URL)
+	t.Setenv("OIDC_AUDIENCE", "tools")
+	t.Setenv("OIDC_REQUIRED_CLAIMS", "groups=mcp-users")
+	t.Setenv("OIDC_USERNAME_CLAIM", "email")
+
+	var seen *Identity
+	s := New("echo")
+	Register(s, "/whoami", func(ctx context.Context, _ struct{}) (echoResponse, error) {
+		seen = IdentityFrom(ctx)
+		return echoResponse{Echo: seen.Username}, nil
+	})
+
+	now := time.Now().Unix()
+	claims := func(changes map[string]any) map[string]any {
+		c := map[string]any{
+			"iss":    iss.URL,
+			"sub":    "1234",
+			"aud":    []string{"other", "tools"},
+			"exp":    now + 300,
+			"email":  "ada@example.com",
+			"groups": []string{"devs", "mcp-users"},
+		}
+		for k, v := range changes {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+	valid := claims(nil)
+
+	tests := []struct {
+		name      string
+		header    string
+		status    int
+		challenge string
+	}{
+		{"no token", "", http.StatusUnauthorized, "Bearer"},
+		{"not bearer", "Basic YTpi", http.StatusUnauthorized, "Bearer"},
+		{"RS256", "Bearer " + iss.sign(t, "RS256", "rsa", valid), http.StatusOK, ""},
+		{"PS256", "Bearer " + iss.sign(t, "PS256", "rsa", valid), http.StatusOK, ""},
+		{"ES256", "Bearer " + iss.sign(t, "ES256", "ec", valid), http.StatusOK, ""},
+		{"EdDSA", "Bearer " + iss.sign(t, "EdDSA", "ed", valid), http.StatusOK, ""},
+		{"no kid", "Bearer " + iss.sign(t, "ES256", "", valid), http.StatusOK, ""},
+		{"HMAC", "Bearer " + iss.sign(t, "HS256", "rsa", valid), http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"wrong key", "Bearer " + iss.sign(t, "RS256", "ec", valid), http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"unknown kid", "Bearer " + iss.sign(t, "RS256", "gone", valid), http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"tampered", "Bearer " + iss.sign(t, "RS256", "rsa", valid) + "x", http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"expired", "Bearer " + iss.sign(t, "RS256", "rsa", claims(map[string]any{"exp": now - 600})), http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"no expiry", "Bearer " + iss.sign(t, "RS256", "rsa", claims(map[string]any{"exp": nil})), http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"not yet valid", "Bearer " + iss.sign(t, "RS256", "rsa", claims(map[string]any{"nbf": now + 600})), http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"wrong issuer", "Bearer " + iss.sign(t, "RS256", "rsa", claims(map[string]any{"iss": "https://evil"})), http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"wrong audience", "Bearer " + iss.sign(t, "RS256", "rsa", claims(map[string]any{"aud": "other"})), http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"missing group", "Bearer " + iss.sign(t, "RS256", "rsa", claims(map[string]any{"groups": []string{"devs"}})), http.StatusForbidden, ""},
+		{"no username", "Bearer " + iss.sign(t, "RS256", "rsa", claims(map[string]any{"email": nil})), http.StatusUnauthorized, `Bearer error="invalid_token"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/whoami", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.challenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.challenge)
+			}
+			if tt.status == http.StatusOK && !strings.Contains(rec.Body.String(), `"ada@example.com"`) {
+				t.Errorf("body = %s", rec.Body)
+			}
+		})
+	}
+
+	if seen == nil || seen.Subject != "1234" || seen.Issuer != iss.URL || strings.Join(seen.Groups, ",") != "devs,mcp-users" {
+		t.Errorf("identity = %+v", seen)
+	}
+
+	// Probes and scrapes need no token.
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/health = %d without a token", rec.Code)
+	}
+}
+
+func TestAuthenticationIssuerDown(t *testing.T) {
+	iss := newTestIssuer(t)
+	token := iss.sign(t, "RS256", "rsa", map[string]any{"iss": iss.URL, "sub": "x", "exp": time.Now().Unix() + 300})
+	iss.Close()
+
+	t.Setenv("OIDC_ISSUER", iss.URL)
+	s := newEchoServer()
+	req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"message":"hi"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503 while the issuer is unreachable", rec.Code)
+	}
+}
+
+func TestAuthenticationConcurrentRefresh(t *testing.T) {
+	iss := newTestIssuer(t)
+	release := make(chan struct{})
+	var fetches atomic.Int64
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		resp, err := http.Get(iss.URL + "/keys")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(jwks.Close)
+	a := &authenticator{jwksURL: jwks.URL, client: jwks.Client()}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Go(func() {
+			_, err := a.key(context.Background(), "rsa", "RS256")
+			errs <- err
+		})
+	}
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// The fetch is under way, and does not hold the lock.
+	locked := make(chan struct{})
+	go func() {
+		a.mu.Lock()
+		a.mu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a.mu is held while the keys are fetched")
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("key: %v", err)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetched the keys %d times, want 1", n)
+	}
+}
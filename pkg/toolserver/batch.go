@@ -0,0 +1,82 @@
+package toolserver
+
+import (
+	"context"
+	"sync"
+)
+
+// Batch is the response of an operation that fans out over several items,
+// such as locations, images or lookups, so that callers can tell one bad
+// item from a failed call. Results has an entry per item, in request order:
+// the item's result, or, for an item that failed, whatever the tool learned
+// before it did (e.g. null). Errors lists the failed items, and Partial is
+// set when some, but not all, failed. When every item fails, the call
+// itself fails instead (see RunBatch).
+type Batch[T any] struct {
+	Results []T          `json:"results"`
+	Errors  []BatchError `json:"errors"`
+	Partial bool         `json:"partial"`
+}
+
+// BatchError is a failed item of a batch: its index in the request, and the
+// code and message it would have failed with on its own.
+type BatchError struct {
+	Index     int    `json:"index"`
+	Code      Code   `json:"code"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable,omitempty"`
+}
+
+// RunBatch calls fn for each of n items concurrently, each once it gets a
+// slot of pool, if not nil, and collects the outcomes in a Batch. An item
+// that gets no slot fails with the pool's error, and one whose fn panics
+// with INTERNAL. If every item fails, RunBatch returns an error with the
+// first item's code and the item errors in its details.
+func RunBatch[T any](ctx context.Context, n int, pool *Pool, fn func(ctx context.Context, i int) (T, error)) (Batch[T], error) {
+	batch := Batch[T]{Results: make([]T, n), Errors: []BatchError{}}
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A panic would take the process down from this goroutine, past
+			// the operation's own recovery, so it fails just the item.
+			defer func() {
+				if v := recover(); v != nil {
+					errs[i] = recovered(ctx, "batch item", v)
+				}
+			}()
+			if pool != nil {
+				release, err := pool.Acquire(ctx)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				defer release()
+			}
+			batch.Results[i], errs[i] = fn(ctx, i)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		resp, _ := errorResponse(err)
+		batch.Errors = append(batch.Errors, BatchError{Index: i, Code: resp.Code, Message: resp.Message, Retryable: resp.Retryable})
+	}
+	if n > 0 && len(batch.Errors) == n {
+		return batch, batchFailed(batch.Errors)
+	}
+	batch.Partial = len(batch.Errors) > 0
+	return batch, nil
+}
+
+// batchFailed is the error of a batch whose items all failed.
+func batchFailed(errs []BatchError) *Error {
+	e := NewError(errs[0].Code, "all %d items failed; the first: %s", len(errs), errs[0].Message)
+	e.Details = map[string]any{"errors": errs}
+	return e
+}
@@ -0,0 +1,58 @@
+package toolserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	items := []string{"a", "missing", "b"}
+	lookup := func(ctx context.Context, i int) (*string, error) {
+		if items[i] == "missing" {
+			return nil, NewError(CodeNotFound, "%s not found", items[i])
+		}
+		return &items[i], nil
+	}
+
+	batch, err := RunBatch(t.Context(), len(items), nil, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Results) != 3 || *batch.Results[0] != "a" || batch.Results[1] != nil || *batch.Results[2] != "b" {
+		t.Errorf("results = %v, want one per item in order", batch.Results)
+	}
+	want := BatchError{Index: 1, Code: CodeNotFound, Message: "missing not found"}
+	if !batch.Partial || len(batch.Errors) != 1 || batch.Errors[0] != want {
+		t.Errorf("batch = %+v, want partial with %+v", batch, want)
+	}
+
+	items = []string{"a", "b"}
+	batch, err = RunBatch(t.Context(), len(items), NewPool("batch-test", 1, 4), lookup)
+	if err != nil || batch.Partial || len(batch.Errors) != 0 {
+		t.Errorf("batch without failures = %+v, %v", batch, err)
+	}
+
+	items = []string{"missing", "missing"}
+	_, err = RunBatch(t.Context(), len(items), nil, lookup)
+	var e *Error
+	if !errors.As(err, &e) || e.Code != CodeNotFound || len(e.Details["errors"].([]BatchError)) != 2 {
+		t.Errorf("batch with every item failing = %v, want NOT_FOUND with the item errors", err)
+	}
+}
+
+func TestRunBatchPanic(t *testing.T) {
+	batch, err := RunBatch(t.Context(), 3, nil, func(ctx context.Context, i int) (int, error) {
+		if i == 1 {
+			panic("boom")
+		}
+		return i, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := BatchError{Index: 1, Code: CodeInternal, Message: "internal error"}
+	if !batch.Partial || len(batch.Errors) != 1 || batch.Errors[0] != want || batch.Results[2] != 2 {
+		t.Errorf("batch = %+v, want item 1 failing with %+v", batch, want)
+	}
+}
@@ -0,0 +1,84 @@
+package toolserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// defaultMaxBodySize is the default of $MAX_BODY_SIZE: well above any
+// Kubernetes object, which etcd caps at 1.5 MiB, and small enough that a
+// few concurrent requests cannot exhaust a tool pod's memory.
+const defaultMaxBodySize = 4 << 20
+
+// maxBodySize returns $MAX_BODY_SIZE, the largest request body or MCP
+// message in bytes a tool reads.
+func maxBodySize() int64 {
+	n := config.Int("MAX_BODY_SIZE", defaultMaxBodySize)
+	if n <= 0 {
+		return defaultMaxBodySize
+	}
+	return int64(n)
+}
+
+// strictJSON returns $STRICT_JSON, default true: whether request fields
+// the tool does not know are rejected rather than ignored.
+func strictJSON() bool {
+	return config.Bool("STRICT_JSON", true)
+}
+
+// errBodyTooLarge reports a request body over limit bytes.
+func errBodyTooLarge(limit int64) *Error {
+	return Errorf(http.StatusRequestEntityTooLarge, "request body exceeds %d bytes", limit)
+}
+
+// readBody reads r's body, failing with 413 Request Entity Too Large once
+// it passes limit bytes rather than reading on.
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, errBodyTooLarge(limit)
+		}
+		return nil, errInvalidBody
+	}
+	return data, nil
+}
+
+// DecodeJSON decodes the JSON body of a request to a plain handler into v
+// with the limits operations get: a body over $MAX_BODY_SIZE fails with
+// 413, and unless $STRICT_JSON is false, fields v does not have fail with
+// 400. Write the error it returns with WriteError.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	data, err := readBody(w, r, maxBodySize())
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strictJSON() {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return BadRequest("invalid request body: %v", err)
+	}
+	if dec.More() {
+		return BadRequest("invalid request body: data after the JSON value")
+	}
+	return nil
+}
+
+// writeReadError answers a message body readMessages rejected: 413 with an
+// invalid request error for one too large, else status with a parse error.
+func writeReadError(w http.ResponseWriter, err error, status int) {
+	resp := &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+		Error: &rpcError{Code: codeParseError, Message: "parse error"}}
+	if errorStatus(err) == http.StatusRequestEntityTooLarge {
+		status, resp.Error = http.StatusRequestEntityTooLarge, &rpcError{Code: codeInvalidRequest, Message: err.Error()}
+	}
+	WriteJSON(w, status, resp)
+}
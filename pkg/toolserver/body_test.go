@@ -0,0 +1,66 @@
+package toolserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimits(t *testing.T) {
+	t.Setenv("MAX_BODY_SIZE", "64")
+	s := newEchoServer()
+	Register(s, "/manifest", func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		return echoResponse{Echo: req.Message}, nil
+	}, MaxBodySize(1024))
+
+	long := `{"message":"` + strings.Repeat("x", 100) + `"}`
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"rest", "/echo", long, http.StatusRequestEntityTooLarge, `{"code":"PAYLOAD_TOO_LARGE","message":"request body exceeds 64 bytes","retryable":false,"error":"request body exceeds 64 bytes"}`},
+		{"operation limit", "/manifest", long, http.StatusOK, ""},
+		{"rpc", "/rpc", `{"jsonrpc":"2.0","id":1,"method":"echo","params":` + long + `}`, http.StatusRequestEntityTooLarge, `"code":-32600`},
+		{"mcp", "/mcp", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":` + long + `}}`, http.StatusRequestEntityTooLarge, `request body exceeds 64 bytes`},
+		{"trailing data", "/echo", `{"message":"hi"} {}`, http.StatusBadRequest, `{"code":"INVALID_ARGUMENT","message":"invalid request body: data after the JSON value","retryable":false,"error":"invalid request body: data after the JSON value"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Accept", "application/json, text/event-stream")
+			s.ServeHTTP(rec, req)
+			if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("%s = %d %s, want %d with %s", tt.path, rec.Code, rec.Body, tt.status, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrictJSON(t *testing.T) {
+	body := `{"message":"hi","mesage":"typo"}`
+	for _, tt := range []struct {
+		strict string
+		status int
+	}{{"", http.StatusBadRequest}, {"false", http.StatusOK}} {
+		t.Setenv("STRICT_JSON", tt.strict)
+		s := newEchoServer()
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(body)))
+		if rec.Code != tt.status {
+			t.Errorf("STRICT_JSON=%q: status = %d %s, want %d", tt.strict, rec.Code, rec.Body, tt.status)
+		}
+
+		rec = httptest.NewRecorder()
+		var req echoRequest
+		err := DecodeJSON(rec, httptest.NewRequest(http.MethodPost, "/plain", strings.NewReader(body)), &req)
+		if (err != nil) != (tt.status != http.StatusOK) {
+			t.Errorf("STRICT_JSON=%q: DecodeJSON = %v", tt.strict, err)
+		}
+	}
+}
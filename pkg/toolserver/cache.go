@@ -0,0 +1,164 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// defaultCacheEntries is the default of $CACHE_MAX_ENTRIES.
+const defaultCacheEntries = 1000
+
+// Cache caches the operation's successful responses for ttl, keyed by the
+// caller and the request with its fields in canonical order, so repeated
+// calls from chatty agents are answered without redoing the work. Use it
+// for idempotent operations whose results change slowly, such as schema
+// lookups. Callers skip the cache with a Cache-Control: no-cache (or
+// Pragma: no-cache) request header, which also stores the fresh response.
+// Each operation keeps at most $CACHE_MAX_ENTRIES responses (default
+// 1000); 0 turns caching off. $NAME_CACHE_TTL overrides ttl, where NAME is
+// the operation's name upper-cased with dashes as underscores, and is
+// reloaded with the config file.
+func Cache(ttl time.Duration) Option {
+	return func(op *operation) {
+		if n := config.Int("CACHE_MAX_ENTRIES", defaultCacheEntries); n > 0 && ttl > 0 {
+			op.cache = &responseCache{defaultTTL: ttl, max: n, entries: make(map[string]cachedResponse)}
+		}
+	}
+}
+
+type cachedResponse struct {
+	resp   any
+	stored time.Time
+}
+
+// responseCache holds an operation's responses until they expire.
+type responseCache struct {
+	defaultTTL time.Duration
+	ttl        atomic.Int64 // a time.Duration; 0 until configured
+	max        int
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+// configure sets the cache's TTL from $NAME_CACHE_TTL for the operation op,
+// or else to the one it was created with. Responses already cached expire
+// by the new TTL.
+func (c *responseCache) configure(op string) {
+	c.ttl.Store(int64(config.Duration(settingName(op)+"_CACHE_TTL", c.defaultTTL)))
+}
+
+// expired reports whether a response stored at stored is too old at now.
+func (c *responseCache) expired(stored, now time.Time) bool {
+	return now.Sub(stored) >= time.Duration(c.ttl.Load())
+}
+
+// cacheKey returns the key of a call with body, or false if the body is not
+// JSON and so cannot be normalized.
+func cacheKey(ctx context.Context, body []byte) (string, bool) {
+	var v any
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &v); err != nil {
+			return "", false
+		}
+	}
+	// Marshalling sorts object keys, so field order and spacing do not
+	// matter.
+	canonical, _ := json.Marshal(v)
+	// Handlers may answer from session state, such as a selected namespace,
+	// so calls in a session are kept apart from other calls.
+	return callerKey(ctx) + "\x00" + SessionID(ctx) + "\x00" + string(canonical), true
+}
+
+// callerKey identifies the caller of ctx, or is empty for anonymous
+// callers.
+func callerKey(ctx context.Context) string {
+	if id := IdentityFrom(ctx); id != nil {
+		return id.Issuer + "\x00" + id.Username
+	}
+	return ""
+}
+
+func (c *responseCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if ok && c.expired(e.stored, time.Now()) {
+		delete(c.entries, key)
+		ok = false
+	}
+	return e.resp, ok
+}
+
+func (c *responseCache) put(key string, resp any) {
+	if c.ttl.Load() <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= c.max {
+		for k, e := range c.entries {
+			if c.expired(e.stored, now) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) >= c.max {
+		// Still full: drop an arbitrary entry rather than track recency.
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = cachedResponse{resp: resp, stored: now}
+}
+
+// call answers a call of op from the cache if it can, and otherwise runs
+// fn and caches its response.
+func (c *responseCache) call(ctx context.Context, op string, body []byte, fn func() (any, error)) (any, error) {
+	key, ok := cacheKey(ctx, body)
+	if !ok {
+		return fn()
+	}
+	if noCache(ctx) {
+		cacheRequests.WithLabelValues(op, "bypass").Inc()
+	} else if resp, ok := c.get(key); ok {
+		cacheRequests.WithLabelValues(op, "hit").Inc()
+		return resp, nil
+	} else {
+		cacheRequests.WithLabelValues(op, "miss").Inc()
+	}
+	resp, err := fn()
+	if _, stale := resp.(staleResponse); err == nil && !stale {
+		c.put(key, resp)
+	}
+	return resp, err
+}
+
+type noCacheKey struct{}
+
+// noCache reports whether the caller asked to skip cached responses.
+func noCache(ctx context.Context) bool {
+	return ctx.Value(noCacheKey{}) != nil
+}
+
+// cacheControl wraps a handler to honour Cache-Control: no-cache and
+// Pragma: no-cache on requests, passing them on to cached operations
+// whichever transport calls them.
+func cacheControl(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache") ||
+			strings.EqualFold(r.Header.Get("Pragma"), "no-cache") {
+			r = r.WithContext(context.WithValue(r.Context(), noCacheKey{}, true))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,74 @@
+package toolserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	s := New("cached")
+	calls := 0
+	Register(s, "/explain", func(ctx context.Context, req struct {
+		Resource  string `json:"resource"`
+		Recursive bool   `json:"recursive"`
+	}) (echoResponse, error) {
+		calls++
+		if req.Resource == "" {
+			return echoResponse{}, BadRequest("resource is required")
+		}
+		return echoResponse{Echo: req.Resource}, nil
+	}, Cache(time.Minute))
+
+	post := func(body string, header ...string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/explain", strings.NewReader(body))
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		header []string
+		calls  int
+	}{
+		{"miss", `{"resource":"pod","recursive":true}`, nil, 1},
+		{"hit in another field order", `{ "recursive": true, "resource": "pod" }`, nil, 1},
+		{"other request", `{"resource":"pod"}`, nil, 2},
+		{"bypass", `{"resource":"pod","recursive":true}`, []string{"Cache-Control", "no-cache"}, 3},
+		{"hit after bypass", `{"resource":"pod","recursive":true}`, nil, 3},
+		{"errors are not cached", `{}`, nil, 4},
+		{"errors are not cached again", `{}`, nil, 5},
+	}
+	for _, tt := range tests {
+		post(tt.body, tt.header...)
+		if calls != tt.calls {
+			t.Errorf("%s: handler ran %d times, want %d", tt.name, calls, tt.calls)
+		}
+	}
+
+	// Callers do not share responses.
+	op := s.lookup("explain")
+	ctx := context.WithValue(context.Background(), identityKey{}, &Identity{Username: "alice"})
+	if _, err := op.call(ctx, []byte(`{"resource":"pod"}`)); err != nil || calls != 6 {
+		t.Errorf("another caller's call = %v, handler ran %d times, want 6", err, calls)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`toolserver_cache_requests_total{operation="explain",result="hit"} 2`,
+		`toolserver_cache_requests_total{operation="explain",result="bypass"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("/metrics is missing %s", want)
+		}
+	}
+}
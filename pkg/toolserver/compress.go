@@ -0,0 +1,186 @@
+package toolserver
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// defaultCompressMinSize is the default of $COMPRESS_MIN_SIZE: below about
+// a kilobyte, compression saves less than it costs.
+const defaultCompressMinSize = 1024
+
+var (
+	gzipWriters = sync.Pool{New: func() any { w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression); return w }}
+	zlibWriters = sync.Pool{New: func() any { return zlib.NewWriter(nil) }}
+)
+
+// compressMinSize returns the smallest response body compressed, or 0 if
+// $COMPRESSION is false.
+func compressMinSize() int {
+	if !config.Bool("COMPRESSION", true) {
+		return 0
+	}
+	return max(config.Int("COMPRESS_MIN_SIZE", defaultCompressMinSize), 1)
+}
+
+// compress wraps a handler to compress responses of at least minSize bytes
+// with gzip or deflate, whichever the request's Accept-Encoding prefers.
+// Event streams, and responses flushed or encoded by the handler itself,
+// are sent as they are.
+func compress(minSize int, h http.Handler) http.Handler {
+	if minSize == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			h.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+		h.ServeHTTP(cw, r)
+		// Not deferred: after a panic, recoverPanics writes the response.
+		cw.close()
+	})
+}
+
+// negotiateEncoding returns "gzip" or "deflate", whichever accept allows
+// with the higher quality (gzip on a tie), or "" for neither.
+func negotiateEncoding(accept string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if (coding == "gzip" || coding == "deflate") && (q > bestQ || q == bestQ && coding == "gzip") && q > 0 {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it reaches minSize,
+// then compresses it; shorter responses are written as they are on close.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status  int
+	buf     []byte
+	decided bool           // whether the response is being compressed or passed through
+	enc     io.WriteCloser // nil unless compressing
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.passThrough()
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.decided {
+		header := cw.Header()
+		if header.Get("Content-Encoding") != "" || strings.HasPrefix(header.Get("Content-Type"), "text/event-stream") {
+			cw.passThrough()
+		} else {
+			cw.buf = append(cw.buf, p...)
+			if len(cw.buf) >= cw.minSize {
+				cw.startCompressing()
+			}
+			return len(p), nil
+		}
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends what has been written so far. A response flushed before it
+// reaches minSize is streaming, so it is sent uncompressed.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.passThrough()
+	}
+	if f, ok := cw.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter { return cw.ResponseWriter }
+
+func (cw *compressWriter) writeHeader() {
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+}
+
+func (cw *compressWriter) passThrough() {
+	cw.decided = true
+	cw.writeHeader()
+	if len(cw.buf) > 0 {
+		cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+}
+
+func (cw *compressWriter) startCompressing() {
+	cw.decided = true
+	header := cw.Header()
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+	cw.writeHeader()
+	switch cw.encoding {
+	case "gzip":
+		gw := gzipWriters.Get().(*gzip.Writer)
+		gw.Reset(cw.ResponseWriter)
+		cw.enc = gw
+	default:
+		// The deflate content coding is the zlib format (RFC 9110).
+		zw := zlibWriters.Get().(*zlib.Writer)
+		zw.Reset(cw.ResponseWriter)
+		cw.enc = zw
+	}
+	cw.enc.Write(cw.buf)
+	cw.buf = nil
+}
+
+// close finishes the response once the handler returns.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		cw.passThrough()
+		return
+	}
+	if cw.enc == nil {
+		return
+	}
+	cw.enc.Close()
+	switch enc := cw.enc.(type) {
+	case *gzip.Writer:
+		gzipWriters.Put(enc)
+	case *zlib.Writer:
+		zlibWriters.Put(enc)
+	}
+}
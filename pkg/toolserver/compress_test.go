@@ -0,0 +1,80 @@
+package toolserver
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	s := newEchoServer()
+	Register(s, "/tree", func(ctx context.Context, _ struct{}) (echoResponse, error) {
+		return echoResponse{Echo: strings.Repeat("spec.containers.", 200)}, nil
+	})
+	s.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: "+strings.Repeat("x", 2000)+"\n\n")
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		encoding string
+	}{
+		{"gzip", "/v1/tree", "gzip, deflate, br", "gzip"},
+		{"deflate preferred", "/v1/tree", "gzip;q=0.5, deflate", "deflate"},
+		{"refused", "/v1/tree", "gzip;q=0", ""},
+		{"not accepted", "/v1/tree", "", ""},
+		{"small", "/v1/echo", "gzip", ""},
+		{"event stream", "/v1/events", "gzip", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			body := "{}"
+			if tt.path == "/v1/echo" {
+				body = `{"message":"hi"}`
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(body))
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			s.ServeHTTP(rec, req)
+			if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+			if !strings.Contains(rec.Header().Get("Vary"), "Accept-Encoding") {
+				t.Errorf("Vary = %q", rec.Header().Get("Vary"))
+			}
+
+			var r io.Reader = rec.Body
+			var err error
+			switch tt.encoding {
+			case "gzip":
+				r, err = gzip.NewReader(rec.Body)
+			case "deflate":
+				r, err = zlib.NewReader(rec.Body)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := `"echo":`
+			if tt.path == "/v1/events" {
+				want = "data: "
+			}
+			if rec.Code != http.StatusOK || !strings.HasPrefix(string(data), want) && !strings.HasPrefix(string(data), "{"+want) {
+				t.Errorf("response = %d %.80s", rec.Code, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package toolserver
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// mcpContentType is the media type of REST responses shaped as MCP tool
+// results. A request asks for it with its Accept header.
+const mcpContentType = "application/vnd.mcp.content+json"
+
+// Content is a content block of an MCP tool result: text, or a resource
+// embedded with its contents.
+type Content struct {
+	Type     string            `json:"type"` // "text" or "resource"
+	Text     string            `json:"text,omitempty"`
+	Resource *EmbeddedResource `json:"resource,omitempty"`
+}
+
+// EmbeddedResource is the resource of a "resource" content block.
+type EmbeddedResource struct {
+	URI      string `json:"uri"`
+	MIMEType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// TextContent returns a text content block.
+func TextContent(text string) Content {
+	return Content{Type: "text", Text: text}
+}
+
+// ResourceContent returns a content block embedding the resource at uri
+// with its text, e.g. a pod's logs or a rendered manifest.
+func ResourceContent(uri, mimeType, text string) Content {
+	return Content{Type: "resource", Resource: &EmbeddedResource{URI: uri, MIMEType: mimeType, Text: text}}
+}
+
+// A ContentBlocker is a response that chooses its own content blocks for
+// MCP clients, such as logs as a resource rather than as a JSON string.
+// Without it, a tool result has a single text block with the response as
+// JSON. Either way the response is also the result's structuredContent.
+type ContentBlocker interface {
+	ContentBlocks() []Content
+}
+
+// wantsContent reports whether r asks for the response as an MCP tool
+// result, with Accept: application/vnd.mcp.content+json.
+func wantsContent(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if t, _, err := mime.ParseMediaType(accept); err == nil && t == mcpContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// writeContent writes the result of a REST call as an MCP tool result, so
+// an aggregating MCP server can pass it through as the result of its own
+// tools/call: the content blocks, the response as structuredContent with a
+// reference to its schema in the OpenAPI document under
+// _meta.outputSchema, and errors as results with isError set, sent with
+// the error's status.
+func writeContent(w http.ResponseWriter, op *operation, resp any, err error) {
+	w.Header().Set("Content-Type", mcpContentType)
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(e.RetryAfter)))
+		}
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(toolError(err))
+		return
+	}
+	result := toolResult(resp)
+	if result.StructuredContent != nil {
+		result.Meta = map[string]any{"outputSchema": op.schemaRef()}
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// schemaRef is the URL of op's response schema in the OpenAPI document.
+func (op *operation) schemaRef() string {
+	return op.server.prefix + openAPIPath + "#/components/schemas/" + op.name
+}
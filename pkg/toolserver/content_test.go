@@ -0,0 +1,67 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type logsResponse struct {
+	Logs string `json:"logs"`
+}
+
+func (r logsResponse) ContentBlocks() []Content {
+	return []Content{ResourceContent("k8s://namespaces/default/pods/web/log", "text/plain", r.Logs)}
+}
+
+func TestContentMode(t *testing.T) {
+	root := New("root")
+	s := newEchoServer()
+	Register(s, "/logs", func(ctx context.Context, req struct{}) (logsResponse, error) {
+		return logsResponse{Logs: "line 1\nline 2\n"}, nil
+	})
+	root.Mount("/echo", s)
+	post := func(path, body string) (*httptest.ResponseRecorder, callResult) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Accept", "application/json;q=0.5, "+mcpContentType)
+		root.ServeHTTP(rec, req)
+		var result callResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("%s: %v in %s", path, err, rec.Body)
+		}
+		return rec, result
+	}
+
+	rec, result := post("/echo/v1/echo", `{"message":"hi"}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != mcpContentType {
+		t.Errorf("echo = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != `{"echo":"hi"}` || result.StructuredContent == nil ||
+		result.Meta["outputSchema"] != "/echo/openapi.json#/components/schemas/echo" {
+		t.Errorf("echo = %s, want the response as text and structured content with its schema", rec.Body)
+	}
+
+	rec, result = post("/echo/v1/logs", `{}`)
+	if len(result.Content) != 1 || result.Content[0].Type != "resource" || result.Content[0].Resource.Text != "line 1\nline 2\n" {
+		t.Errorf("logs = %s, want the resource block the response chose", rec.Body)
+	}
+
+	rec, result = post("/echo/v1/echo", `{}`)
+	if rec.Code != http.StatusBadRequest || !result.IsError || !strings.Contains(result.Content[0].Text, `"INVALID_ARGUMENT"`) {
+		t.Errorf("failed call = %d %s, want the error as a tool result", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	root.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/echo/openapi.json", nil))
+	var doc struct {
+		Components struct{ Schemas map[string]map[string]any }
+	}
+	json.Unmarshal(rec.Body.Bytes(), &doc)
+	if doc.Components.Schemas["echo"]["type"] != "object" || doc.Components.Schemas["ToolResult"] == nil {
+		t.Errorf("the OpenAPI document's schemas are %v, want echo's response and ToolResult", doc.Components.Schemas)
+	}
+}
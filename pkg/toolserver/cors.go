@@ -0,0 +1,87 @@
+package toolserver
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// Defaults for CORS_ALLOWED_METHODS and CORS_ALLOWED_HEADERS: what the REST
+// endpoints and the MCP transports use.
+const (
+	defaultCORSMethods = "GET, POST, DELETE"
+	defaultCORSHeaders = "Authorization, Content-Type, Accept, Last-Event-ID, Mcp-Session-Id, Mcp-Protocol-Version, X-Request-Id, API-Version, Idempotency-Key"
+)
+
+// corsExposedHeaders are the response headers browser scripts may read.
+var corsExposedHeaders = strings.Join([]string{sessionHeader, requestIDHeader, apiVersionHeader, "Deprecation", replayedHeader, "Link", "Retry-After", "WWW-Authenticate"}, ", ")
+
+// corsPolicy lets browser pages on other origins, such as an MCP inspector
+// or a dashboard, call the tool.
+type corsPolicy struct {
+	origins []string // "*" allows any origin
+	methods string
+	headers string
+}
+
+// corsFromConfig returns the CORS policy configured by the settings, or nil
+// if cross-origin requests are not allowed:
+//
+//	CORS_ALLOWED_ORIGINS  comma-separated origins, e.g.
+//	                      http://localhost:6274, or * for any
+//	CORS_ALLOWED_METHODS  default "GET, POST, DELETE"
+//	CORS_ALLOWED_HEADERS  default: the headers the tool reads
+func corsFromConfig() *corsPolicy {
+	var origins []string
+	for _, o := range config.List("CORS_ALLOWED_ORIGINS") {
+		origins = append(origins, strings.TrimSuffix(o, "/"))
+	}
+	if len(origins) == 0 {
+		return nil
+	}
+	c := &corsPolicy{
+		origins: origins,
+		methods: config.String("CORS_ALLOWED_METHODS", defaultCORSMethods),
+		headers: config.String("CORS_ALLOWED_HEADERS", defaultCORSHeaders),
+	}
+	if slices.Contains(origins, "*") {
+		slog.Warn("allowing cross-origin requests from any origin")
+	}
+	return c
+}
+
+// allows reports whether the policy admits origin.
+func (c *corsPolicy) allows(origin string) bool {
+	return c != nil && origin != "" && (slices.Contains(c.origins, "*") || slices.Contains(c.origins, origin))
+}
+
+// handleCORS wraps a handler to add CORS headers to responses for allowed
+// origins and to answer their preflight requests itself, since browsers
+// send those without credentials.
+func (s *Server) handleCORS(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		cors := s.cors.Load()
+		if !cors.allows(origin) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		header := w.Header()
+		header.Add("Vary", "Origin")
+		header.Set("Access-Control-Allow-Origin", origin)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", cors.methods)
+			header.Set("Access-Control-Allow-Headers", cors.headers)
+			header.Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		header.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		h.ServeHTTP(w, r)
+	})
+}
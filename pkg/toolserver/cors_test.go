@@ -0,0 +1,56 @@
+package toolserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	const inspector = "http://localhost:6274"
+	t.Setenv("CORS_ALLOWED_ORIGINS", inspector+"/, http://dashboard.example")
+	s := newEchoServer()
+
+	// Preflights are answered before authentication and routing to methods.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/mcp", nil)
+	req.Header.Set("Origin", inspector)
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type, mcp-session-id")
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != inspector ||
+		rec.Header().Get("Access-Control-Allow-Methods") != defaultCORSMethods ||
+		!strings.Contains(rec.Header().Get("Access-Control-Allow-Headers"), "Mcp-Session-Id") {
+		t.Errorf("preflight = %d %v", rec.Code, rec.Header())
+	}
+
+	tests := []struct {
+		name    string
+		origin  string
+		status  int
+		allowed bool
+	}{
+		{"allowed", inspector, http.StatusOK, true},
+		{"other origin", "http://evil.example", http.StatusForbidden, false},
+		{"same origin", "http://example.com", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`))
+			req.Header.Set("Origin", tt.origin)
+			s.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			got := rec.Header().Get("Access-Control-Allow-Origin")
+			if tt.allowed != (got == tt.origin) {
+				t.Errorf("Access-Control-Allow-Origin = %q", got)
+			}
+			if tt.allowed && !strings.Contains(rec.Header().Get("Access-Control-Expose-Headers"), sessionHeader) {
+				t.Errorf("Access-Control-Expose-Headers = %q", rec.Header().Get("Access-Control-Expose-Headers"))
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package toolserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Requests to backends outside the cluster, such as registries, weather
+// APIs, DNS-over-HTTPS resolvers and OIDC issuers, go through the egress
+// proxy and trust the private CAs of these settings (see package config):
+//
+//	HTTPS_PROXY, HTTP_PROXY  the proxy for https and http URLs; the lower-case names are read too
+//	NO_PROXY                 hosts, domains (".corp.example"), IPs and CIDRs reached directly
+//	CA_BUNDLE                PEM files, comma-separated, of CAs trusted besides the system's
+//
+// The proxy settings are read on every request, so a config reload applies
+// them at once; the CA bundle is read at first use.
+
+// OutboundTransport returns the transport for requests outside the cluster,
+// shared so connections are reused. InstrumentTransport wraps it when given
+// no transport.
+func OutboundTransport() http.RoundTripper {
+	return outbound
+}
+
+var outbound = Outbound(nil)
+
+// Outbound returns a transport like base, an *http.Transport such as
+// go-containerregistry's remote.DefaultTransport, or http.DefaultTransport
+// if nil, that uses the proxy settings and trusts the CA bundle. It copies
+// base on first use, so it can be created before the settings are loaded,
+// e.g. in a package variable.
+func Outbound(base http.RoundTripper) http.RoundTripper {
+	return &outboundTransport{transport: sync.OnceValue(func() *http.Transport { return outboundCopy(base, RootCAs()) })}
+}
+
+type outboundTransport struct {
+	transport func() *http.Transport
+}
+
+func (t *outboundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.transport().RoundTrip(req)
+}
+
+func (t *outboundTransport) CloseIdleConnections() {
+	t.transport().CloseIdleConnections()
+}
+
+// outboundCopy returns a copy of base that uses the proxy settings and, if
+// pool is not nil, trusts its CAs.
+func outboundCopy(base http.RoundTripper, pool *x509.CertPool) *http.Transport {
+	t, ok := base.(*http.Transport)
+	if !ok {
+		t = http.DefaultTransport.(*http.Transport)
+	}
+	t = t.Clone()
+	t.Proxy = proxyFromConfig
+	if pool != nil {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+	return t
+}
+
+// RootCAs returns the system's CAs and those of $CA_BUNDLE, for TLS
+// clients other than HTTP ones such as DNS-over-TLS, or nil, meaning the
+// system's, without a bundle. A bundle that cannot be read is logged and
+// left out.
+func RootCAs() *x509.CertPool {
+	return rootCAs()
+}
+
+var rootCAs = sync.OnceValue(func() *x509.CertPool {
+	bundle := config.String("CA_BUNDLE", "")
+	if bundle == "" {
+		return nil
+	}
+	pool, err := loadCABundle(bundle)
+	if err != nil {
+		slog.Error("CA_BUNDLE not loaded; trusting only the system CAs", "err", err)
+		return nil
+	}
+	slog.Info("trusting CA bundle", "files", bundle)
+	return pool
+})
+
+// loadCABundle returns the system's CAs with those of the comma-separated
+// PEM files added.
+func loadCABundle(files string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, path := range strings.Split(files, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s has no PEM certificates", path)
+		}
+	}
+	return pool, nil
+}
+
+// proxies caches the proxy function of the latest proxy settings.
+var proxies struct {
+	mu  sync.Mutex
+	cfg httpproxy.Config
+	fn  func(*url.URL) (*url.URL, error)
+}
+
+// proxyFromConfig returns the proxy for req like http.ProxyFromEnvironment,
+// but from the current settings, which a config file or flags may set.
+func proxyFromConfig(req *http.Request) (*url.URL, error) {
+	cfg := httpproxy.Config{
+		HTTPProxy:  proxySetting("HTTP_PROXY"),
+		HTTPSProxy: proxySetting("HTTPS_PROXY"),
+		NoProxy:    proxySetting("NO_PROXY"),
+	}
+	proxies.mu.Lock()
+	if proxies.fn == nil || proxies.cfg != cfg {
+		proxies.cfg, proxies.fn = cfg, cfg.ProxyFunc()
+	}
+	fn := proxies.fn
+	proxies.mu.Unlock()
+	return fn(req.URL)
+}
+
+func proxySetting(name string) string {
+	return config.String(name, os.Getenv(strings.ToLower(name)))
+}
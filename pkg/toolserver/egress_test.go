@@ -0,0 +1,81 @@
+package toolserver
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProxyFromConfig(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://proxy.corp.example:3128")
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("http_proxy", "http://plain-proxy.corp.example:3128")
+	t.Setenv("NO_PROXY", ".internal.example,10.0.0.0/8")
+
+	tests := []struct {
+		url, want string
+	}{
+		{"https://registry-1.docker.io/v2/", "http://proxy.corp.example:3128"},
+		{"http://api.open-meteo.com/v1/forecast", "http://plain-proxy.corp.example:3128"},
+		{"https://registry.internal.example/v2/", ""},
+		{"https://10.1.2.3/dns-query", ""},
+		{"http://127.0.0.1:8080/", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		proxy, err := proxyFromConfig(req)
+		got := ""
+		if proxy != nil {
+			got = proxy.String()
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("proxy for %s = %q, %v; want %q", tt.url, got, err, tt.want)
+		}
+	}
+
+	// Changed settings apply to the next request.
+	t.Setenv("HTTPS_PROXY", "http://other-proxy.corp.example:3128")
+	if proxy, _ := proxyFromConfig(httptest.NewRequest(http.MethodGet, tests[0].url, nil)); proxy == nil || proxy.Host != "other-proxy.corp.example:3128" {
+		t.Errorf("proxy after the setting changed = %v", proxy)
+	}
+}
+
+func TestCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// Without the server's CA, its certificate is not trusted.
+	client := &http.Client{Transport: outboundCopy(nil, nil)}
+	if _, err := client.Get(srv.URL); err == nil {
+		t.Fatal("request to a server with a private CA succeeded without the CA bundle")
+	}
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, cert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := loadCABundle(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: outboundCopy(nil, pool)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with the CA bundle: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := loadCABundle(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("loadCABundle of a missing file succeeded")
+	}
+	if err := os.WriteFile(path, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadCABundle(path); err == nil {
+		t.Error("loadCABundle of a file without certificates succeeded")
+	}
+}
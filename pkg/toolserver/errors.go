@@ -0,0 +1,213 @@
+package toolserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// A Code is the machine-readable kind of an error, which agents can branch
+// on instead of parsing the message. Codes are part of the API: new ones
+// may be added, but existing ones keep their meaning.
+type Code string
+
+// The error codes. Each is sent with the HTTP status in its comment, and
+// those marked retryable may succeed if the call is simply repeated later.
+const (
+	CodeInvalidArgument  Code = "INVALID_ARGUMENT"   // 400: the request is malformed or a value is not allowed
+	CodeValidationFailed Code = "VALIDATION_FAILED"  // 400: the request does not match the input schema; fields names each field
+	CodeUnauthenticated  Code = "UNAUTHENTICATED"    // 401: no valid token or client certificate
+	CodeForbidden        Code = "FORBIDDEN"          // 403: the caller, or the tool's service account, may not do this
+	CodeNotFound         Code = "NOT_FOUND"          // 404: the resource, image, record or location does not exist
+	CodeMethodNotAllowed Code = "METHOD_NOT_ALLOWED" // 405
+	CodeNotAcceptable    Code = "NOT_ACCEPTABLE"     // 406: e.g. an API version the tool does not serve
+	CodeConflict         Code = "CONFLICT"           // 409: the resource changed or already exists
+	CodeTooLarge         Code = "PAYLOAD_TOO_LARGE"  // 413
+	CodeUnprocessable    Code = "UNPROCESSABLE"      // 422: the request is well-formed but its content cannot be used, e.g. a bad cron schedule
+	CodeRateLimited      Code = "RATE_LIMITED"       // 429, retryable
+	CodeInternal         Code = "INTERNAL"           // 500: a bug or unexpected failure in the tool
+	CodeUpstreamError    Code = "UPSTREAM_ERROR"     // 502: the kube-apiserver, a registry, resolver or API failed
+	CodeUnavailable      Code = "UNAVAILABLE"        // 503, retryable: the tool is busy or a dependency is not ready
+	CodeUpstreamTimeout  Code = "UPSTREAM_TIMEOUT"   // 504, retryable: a backend did not answer in time
+	CodeDeadlineExceeded Code = "DEADLINE_EXCEEDED"  // 504, retryable: the call ran past its timeout
+)
+
+// codeInfo is how a code is sent.
+type codeInfo struct {
+	status    int
+	retryable bool
+}
+
+// codes is the registry of error codes.
+var codes = map[Code]codeInfo{
+	CodeInvalidArgument:  {http.StatusBadRequest, false},
+	CodeValidationFailed: {http.StatusBadRequest, false},
+	CodeUnauthenticated:  {http.StatusUnauthorized, false},
+	CodeForbidden:        {http.StatusForbidden, false},
+	CodeNotFound:         {http.StatusNotFound, false},
+	CodeMethodNotAllowed: {http.StatusMethodNotAllowed, false},
+	CodeNotAcceptable:    {http.StatusNotAcceptable, false},
+	CodeConflict:         {http.StatusConflict, false},
+	CodeTooLarge:         {http.StatusRequestEntityTooLarge, false},
+	CodeUnprocessable:    {http.StatusUnprocessableEntity, false},
+	CodeRateLimited:      {http.StatusTooManyRequests, true},
+	CodeInternal:         {http.StatusInternalServerError, false},
+	CodeUpstreamError:    {http.StatusBadGateway, false},
+	CodeUnavailable:      {http.StatusServiceUnavailable, true},
+	CodeUpstreamTimeout:  {http.StatusGatewayTimeout, true},
+	CodeDeadlineExceeded: {http.StatusGatewayTimeout, true},
+}
+
+// statusCodes are the codes of errors created with only a status.
+var statusCodes = map[int]Code{
+	http.StatusBadRequest:            CodeInvalidArgument,
+	http.StatusUnauthorized:          CodeUnauthenticated,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusNotAcceptable:         CodeNotAcceptable,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodeTooLarge,
+	http.StatusUnprocessableEntity:   CodeUnprocessable,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusBadGateway:            CodeUpstreamError,
+	http.StatusServiceUnavailable:    CodeUnavailable,
+	http.StatusGatewayTimeout:        CodeUpstreamTimeout,
+}
+
+// Error is an error with the HTTP status and code it should be reported
+// with.
+type Error struct {
+	Status  int
+	Code    Code // if empty, the code of Status
+	Message string
+	Fields  []FieldError   // set for requests that fail schema validation
+	Details map[string]any // more machine-readable context, e.g. the resource not found
+
+	// RetryAfter, if set, is sent as the Retry-After header, telling the
+	// client when a 429 or 503 is worth retrying.
+	RetryAfter time.Duration
+	// Retryable marks a failure as transient even if its code is not, such
+	// as a kube-apiserver answering 500 during an upgrade.
+	Retryable bool
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Errorf returns an Error with a formatted message and the code of status.
+func Errorf(status int, format string, args ...any) *Error {
+	return &Error{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewError returns an Error with the code and a formatted message, sent
+// with the code's status.
+func NewError(code Code, format string, args ...any) *Error {
+	return &Error{Status: codes[code].status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// BadRequest reports a problem with the request itself.
+func BadRequest(format string, args ...any) *Error {
+	return Errorf(http.StatusBadRequest, format, args...)
+}
+
+// UpstreamError reports a failed call to a backend such as the
+// kube-apiserver, a registry, resolver or web API, with a formatted message
+// followed by err. Timeouts are UPSTREAM_TIMEOUT, refused and reset
+// connections are retryable, and anything else is UPSTREAM_ERROR. Callers
+// map errors the backend reports about the request itself, such as a
+// missing object, to their own codes first.
+func UpstreamError(err error, format string, args ...any) *Error {
+	e := NewError(CodeUpstreamError, "%s: %v", fmt.Sprintf(format, args...), err)
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout():
+		e.Status, e.Code = codes[CodeUpstreamTimeout].status, CodeUpstreamTimeout
+	case errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET):
+		e.Retryable = true
+	}
+	return e
+}
+
+// code returns the error's code: its own, or else the one of its status.
+func (e *Error) code() Code {
+	if e.Code != "" {
+		return e.Code
+	}
+	if c, ok := statusCodes[e.Status]; ok {
+		return c
+	}
+	if e.Status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeInvalidArgument
+}
+
+// ErrorResponse is the envelope of every error response. The failed items
+// of a Batch carry the same code and message, so clients see one shape
+// either way.
+type ErrorResponse struct {
+	Code      Code           `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	Retryable bool           `json:"retryable"`
+
+	// Fields are the fields that fail validation.
+	Fields []FieldError `json:"fields,omitempty"`
+	// Error repeats the message for clients written before codes.
+	Error string `json:"error,omitempty"`
+}
+
+// errorResponse returns the envelope and HTTP status of err. An *Error
+// (possibly wrapped) sets them; any other error is a 500.
+func errorResponse(err error) (ErrorResponse, int) {
+	resp := ErrorResponse{Code: CodeInternal, Message: err.Error(), Error: err.Error()}
+	var e *Error
+	if !errors.As(err, &e) {
+		return resp, http.StatusInternalServerError
+	}
+	resp.Code = e.code()
+	resp.Retryable = e.Retryable || e.RetryAfter > 0 || codes[resp.Code].retryable
+	resp.Fields = e.Fields
+	if len(e.Details) > 0 || e.RetryAfter > 0 {
+		resp.Details = make(map[string]any, len(e.Details)+1)
+		maps.Copy(resp.Details, e.Details)
+		if e.RetryAfter > 0 {
+			resp.Details["retryAfterSeconds"] = retryAfterSeconds(e.RetryAfter)
+		}
+	}
+	return resp, e.Status
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// WriteError writes err in the error envelope. An *Error (possibly wrapped)
+// sets the status, code and Retry-After; any other error is a 500 with code
+// INTERNAL.
+func WriteError(w http.ResponseWriter, err error) {
+	resp, status := errorResponse(err)
+	var e *Error
+	if errors.As(err, &e) && e.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(e.RetryAfter)))
+	}
+	WriteJSON(w, status, resp)
+}
+
+// errorStatus is the HTTP status err is reported with: an *Error's own
+// status, or 500 for anything else.
+func errorStatus(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,74 @@
+package toolserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		want   string
+	}{
+		{"code from status", Errorf(http.StatusNotFound, "pod web-0 not found"), http.StatusNotFound,
+			`{"code":"NOT_FOUND","message":"pod web-0 not found","retryable":false,"error":"pod web-0 not found"}`},
+		{"explicit code", NewError(CodeUpstreamTimeout, "registry did not answer"), http.StatusGatewayTimeout,
+			`{"code":"UPSTREAM_TIMEOUT","message":"registry did not answer","retryable":true,"error":"registry did not answer"}`},
+		{"wrapped with details", fmt.Errorf("inspect: %w", &Error{Status: http.StatusBadGateway, Message: "registry failed", Details: map[string]any{"image": "nginx"}, Retryable: true}), http.StatusBadGateway,
+			`{"code":"UPSTREAM_ERROR","message":"inspect: registry failed","details":{"image":"nginx"},"retryable":true,"error":"inspect: registry failed"}`},
+		{"retry after", &Error{Status: http.StatusServiceUnavailable, Message: "busy", RetryAfter: 1500 * time.Millisecond}, http.StatusServiceUnavailable,
+			`{"code":"UNAVAILABLE","message":"busy","details":{"retryAfterSeconds":2},"retryable":true,"error":"busy"}`},
+		{"unregistered status", Errorf(http.StatusTeapot, "short and stout"), http.StatusTeapot,
+			`{"code":"INVALID_ARGUMENT","message":"short and stout","retryable":false,"error":"short and stout"}`},
+		{"plain error", errors.New("boom"), http.StatusInternalServerError,
+			`{"code":"INTERNAL","message":"boom","retryable":false,"error":"boom"}`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		WriteError(rec, tt.err)
+		if rec.Code != tt.status || strings.TrimSpace(rec.Body.String()) != tt.want {
+			t.Errorf("%s: %d %s, want %d %s", tt.name, rec.Code, rec.Body, tt.status, tt.want)
+		}
+	}
+}
+
+func TestCodeRegistry(t *testing.T) {
+	for status, code := range statusCodes {
+		if codes[code].status != status {
+			t.Errorf("status %d maps to %s, which is sent as %d", status, code, codes[code].status)
+		}
+	}
+	for code, info := range codes {
+		if got, _ := errorResponse(NewError(code, "x")); got.Code != code || got.Retryable != info.retryable {
+			t.Errorf("NewError(%s) = %+v", code, got)
+		}
+	}
+}
+
+func TestUpstreamError(t *testing.T) {
+	tests := []struct {
+		err       error
+		code      Code
+		retryable bool
+	}{
+		{fmt.Errorf("get pods: %w", context.DeadlineExceeded), CodeUpstreamTimeout, true},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, CodeUpstreamError, true},
+		{errors.New("unexpected status 500"), CodeUpstreamError, false},
+	}
+	for _, tt := range tests {
+		resp, _ := errorResponse(UpstreamError(tt.err, "fetching %s", "schema"))
+		if resp.Code != tt.code || resp.Retryable != tt.retryable || resp.Message != "fetching schema: "+tt.err.Error() {
+			t.Errorf("UpstreamError(%v) = %+v, want %s, retryable %t", tt.err, resp, tt.code, tt.retryable)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package toolserver
+
+import (
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// The write features: the kinds of change an operation registered with
+// Writes makes.
+const (
+	FeatureExec   = "exec"   // running commands in containers
+	FeatureApply  = "apply"  // creating or updating objects
+	FeatureScale  = "scale"  // changing replica counts
+	FeatureCopy   = "copy"   // copying files into or out of containers
+	FeatureDelete = "delete" // deleting objects
+)
+
+// Writes marks the operation as one that changes the cluster or its
+// workloads, as part of the named write feature, such as FeatureScale. It
+// is refused with 403 Forbidden unless the feature is listed in $FEATURES,
+// e.g. FEATURES=scale,apply, and always when the tool runs with --read-only
+// or $READ_ONLY, so the same binary can be deployed in locked-down
+// clusters. Both settings are read on every call, so a config reload
+// applies them at once.
+//
+// Callers that may retry a write send an Idempotency-Key header (over MCP,
+// _meta.idempotencyKey) with it: a repeated call with the same key gets the
+// first call's result instead of making the change twice.
+func Writes(feature string) Option {
+	return func(op *operation) { op.feature = feature }
+}
+
+// readOnly returns --read-only or $READ_ONLY, default false: whether every
+// write operation is refused.
+func readOnly() bool {
+	return config.Bool("READ_ONLY", false)
+}
+
+// writeDisabled returns the error a call of op fails with if it is a write
+// operation whose feature is disabled, or nil.
+func (op *operation) writeDisabled() *Error {
+	if op.feature == "" {
+		return nil
+	}
+	var e *Error
+	switch {
+	case readOnly():
+		e = NewError(CodeForbidden, "%s is disabled: the tool is running in read-only mode", op.name)
+	case !config.Feature(op.feature):
+		e = NewError(CodeForbidden, "%s is disabled: the %s feature is not enabled (see FEATURES)", op.name, op.feature)
+	default:
+		return nil
+	}
+	e.Details = map[string]any{"feature": op.feature, "readOnly": readOnly()}
+	return e
+}
@@ -0,0 +1,64 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteGates(t *testing.T) {
+	s := New("gated")
+	Register(s, "/scale", func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		return echoResponse{Echo: req.Message}, nil
+	}, Writes(FeatureScale))
+	Register(s, "/echo", func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		return echoResponse{Echo: req.Message}, nil
+	})
+	post := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1"+path, strings.NewReader(`{"message":"hi"}`)))
+		return rec
+	}
+
+	tests := []struct {
+		name     string
+		features string
+		readOnly string
+		status   int
+		want     string
+	}{
+		{"feature off", "", "", http.StatusForbidden, "the scale feature is not enabled"},
+		{"other feature on", "apply,delete", "", http.StatusForbidden, "the scale feature is not enabled"},
+		{"feature on", "apply,scale", "", http.StatusOK, `"echo":"hi"`},
+		{"read-only", "apply,scale", "true", http.StatusForbidden, "read-only mode"},
+	}
+	for _, tt := range tests {
+		t.Setenv("FEATURES", tt.features)
+		t.Setenv("READ_ONLY", tt.readOnly)
+		rec := post("/scale")
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: %d %s, want %d containing %q", tt.name, rec.Code, rec.Body, tt.status, tt.want)
+		}
+		if tt.status == http.StatusForbidden && !strings.Contains(rec.Body.String(), `"code":"FORBIDDEN","message":"scale is disabled`) {
+			t.Errorf("%s: %s, want a FORBIDDEN error naming the operation", tt.name, rec.Body)
+		}
+		if rec := post("/echo"); rec.Code != http.StatusOK {
+			t.Errorf("%s: read operation = %d, want 200", tt.name, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tools", nil))
+	var resp ToolsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range resp.Tools {
+		if tool.Name == "scale" && (tool.Feature != FeatureScale || !tool.Disabled) {
+			t.Errorf("/v1/tools lists scale as %+v, want the scale feature, disabled", tool)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package toolserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// readinessTimeout bounds the readiness checks, within the 5s timeout of
+// the example manifests' readiness probes.
+const readinessTimeout = 3 * time.Second
+
+// verboseHealthPath serves the dependency diagnostics.
+const verboseHealthPath = "/healthz/verbose"
+
+// readinessCheck is a dependency checked by /readyz, with the outcome of
+// its checks so far for /healthz/verbose.
+type readinessCheck struct {
+	name    string
+	check   func(context.Context) error // nil for a dependency with only diagnostics
+	details func() map[string]any       // nil unless added with AddDiagnostics
+
+	mu          sync.Mutex
+	latency     time.Duration
+	failing     bool
+	lastErr     string
+	lastErrAt   time.Time
+	lastSuccess time.Time
+}
+
+// AddReadinessCheck adds a dependency, such as the kube-apiserver or a
+// registry, that must be usable for the tool to serve. /readyz runs every
+// check and fails while any of them does, so Kubernetes only routes traffic
+// to replicas that can do their work. Add checks before Run.
+func (s *Server) AddReadinessCheck(name string, check func(context.Context) error) {
+	s.dependency(name).check = check
+}
+
+// AddDiagnostics adds details, such as a cache's size and age, to the named
+// dependency's entry in /healthz/verbose. details is called on each request
+// and must be safe for concurrent use. Add diagnostics before Run.
+func (s *Server) AddDiagnostics(name string, details func() map[string]any) {
+	s.dependency(name).details = details
+}
+
+// dependency returns the named dependency, adding it if need be.
+func (s *Server) dependency(name string) *readinessCheck {
+	for _, c := range s.checks {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &readinessCheck{name: name}
+	s.checks = append(s.checks, c)
+	return c
+}
+
+// run runs the check and records its outcome.
+func (c *readinessCheck) run(ctx context.Context) error {
+	start := time.Now()
+	err := c.check(ctx)
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.latency = now.Sub(start)
+	c.failing = err != nil
+	if err != nil {
+		c.lastErr, c.lastErrAt = err.Error(), now
+	} else {
+		c.lastSuccess = now
+	}
+	return err
+}
+
+// runChecks runs the readiness checks concurrently and returns "ok" or the
+// error of each.
+func (s *Server) runChecks(ctx context.Context) map[string]string {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	results := make(map[string]string, len(s.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range s.checks {
+		if c.check == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := "ok"
+			if err := c.run(ctx); err != nil {
+				result = err.Error()
+			}
+			mu.Lock()
+			results[c.name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// ResolveCheck returns a readiness check that host resolves, for tools that
+// depend on an external service such as a registry.
+func ResolveCheck(host string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := net.DefaultResolver.LookupHost(ctx, host)
+		return err
+	}
+}
+
+// ReadinessResponse is the body of /readyz: the overall status, "ready" or
+// "not ready", and "ok" or the error of each check; how long the process
+// took to start serving; and the state of each warm-up (see AddWarmup).
+type ReadinessResponse struct {
+	Status    string                `json:"status"`
+	Checks    map[string]string     `json:"checks,omitempty"`
+	StartupMS float64               `json:"startup_ms,omitempty"`
+	Init      map[string]InitStatus `json:"init,omitempty"`
+}
+
+// handleLive serves /livez, and /health for existing probes: the process is
+// up and serving, whatever the state of its dependencies.
+func handleLive(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+}
+
+// handleReady serves /readyz, running the readiness checks concurrently.
+// It answers 503 Service Unavailable unless all of them pass.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	resp := ReadinessResponse{Status: "ready", Checks: s.runChecks(r.Context()), Init: s.initStatus()}
+	resp.StartupMS = float64(time.Duration(s.startup.Load()).Microseconds()) / 1000
+	status := http.StatusOK
+	for _, result := range resp.Checks {
+		if result != "ok" {
+			resp.Status = "not ready"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	WriteJSON(w, status, resp)
+}
+
+// HealthResponse is the body of /healthz/verbose: "healthy" or "degraded",
+// and the state of each dependency.
+type HealthResponse struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyHealth `json:"dependencies"`
+}
+
+// DependencyHealth is the state of one dependency: "ok", "failing", or
+// "unchecked" if it has diagnostics but no check; how long its latest check
+// took; when it last failed, and how, and last passed; and the tool's
+// diagnostics for it.
+type DependencyHealth struct {
+	Status        string         `json:"status"`
+	LatencyMS     float64        `json:"latency_ms"`
+	LastError     string         `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time     `json:"last_error_at,omitempty"`
+	LastSuccessAt *time.Time     `json:"last_success_at,omitempty"`
+	Details       map[string]any `json:"details,omitempty"`
+}
+
+// handleVerboseHealth serves /healthz/verbose, running the readiness checks
+// and reporting every dependency with the history of its checks, so
+// operators can tell which leg of a tool is broken. The last error is kept
+// after the dependency recovers. It answers 503 while any check fails.
+func (s *Server) handleVerboseHealth(w http.ResponseWriter, r *http.Request) {
+	s.runChecks(r.Context())
+
+	resp := HealthResponse{Status: "healthy", Dependencies: make(map[string]DependencyHealth, len(s.checks))}
+	status := http.StatusOK
+	for _, c := range s.checks {
+		d := c.health()
+		if d.Status == "failing" {
+			resp.Status = "degraded"
+			status = http.StatusServiceUnavailable
+		}
+		resp.Dependencies[c.name] = d
+	}
+	WriteJSON(w, status, resp)
+}
+
+// health returns the dependency's state.
+func (c *readinessCheck) health() DependencyHealth {
+	c.mu.Lock()
+	d := DependencyHealth{Status: "ok", LatencyMS: float64(c.latency.Microseconds()) / 1000, LastError: c.lastErr}
+	switch {
+	case c.check == nil:
+		d.Status = "unchecked"
+	case c.failing:
+		d.Status = "failing"
+	}
+	if t := c.lastErrAt; !t.IsZero() {
+		d.LastErrorAt = &t
+	}
+	if t := c.lastSuccess; !t.IsZero() {
+		d.LastSuccessAt = &t
+	}
+	c.mu.Unlock()
+	if c.details != nil {
+		d.Details = c.details()
+	}
+	return d
+}
@@ -0,0 +1,82 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbes(t *testing.T) {
+	s := newEchoServer()
+	var kubeErr error
+	s.AddReadinessCheck("kubernetes", func(ctx context.Context) error { return kubeErr })
+	s.AddReadinessCheck("registry", func(ctx context.Context) error { return nil })
+
+	get := func(path string) (int, ReadinessResponse) {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp ReadinessResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	if code, resp := get("/readyz"); code != http.StatusOK || resp.Status != "ready" || resp.Checks["kubernetes"] != "ok" {
+		t.Errorf("/readyz = %d %+v", code, resp)
+	}
+
+	kubeErr = errors.New("connection refused")
+	code, resp := get("/readyz")
+	if code != http.StatusServiceUnavailable || resp.Status != "not ready" ||
+		resp.Checks["kubernetes"] != "connection refused" || resp.Checks["registry"] != "ok" {
+		t.Errorf("/readyz = %d %+v while a check fails", code, resp)
+	}
+	// Liveness does not depend on the checks.
+	for _, path := range []string{"/livez", "/health"} {
+		if code, _ := get(path); code != http.StatusOK {
+			t.Errorf("%s = %d while a check fails", path, code)
+		}
+	}
+}
+
+func TestVerboseHealth(t *testing.T) {
+	s := newEchoServer()
+	var kubeErr error
+	s.AddReadinessCheck("kubernetes", func(ctx context.Context) error { return kubeErr })
+	s.AddDiagnostics("kubernetes", func() map[string]any { return map[string]any{"schema_age_seconds": 42} })
+	s.AddDiagnostics("cache", func() map[string]any { return map[string]any{"entries": 3} })
+
+	get := func() (int, HealthResponse) {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz/verbose", nil))
+		var resp HealthResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	code, resp := get()
+	kube := resp.Dependencies["kubernetes"]
+	if code != http.StatusOK || resp.Status != "healthy" || kube.Status != "ok" || kube.LastSuccessAt == nil ||
+		kube.LastErrorAt != nil || kube.Details["schema_age_seconds"] != 42.0 {
+		t.Errorf("/healthz/verbose = %d %+v", code, resp)
+	}
+	if cache := resp.Dependencies["cache"]; cache.Status != "unchecked" || cache.Details["entries"] != 3.0 {
+		t.Errorf("diagnostics-only dependency = %+v", cache)
+	}
+
+	kubeErr = errors.New("connection refused")
+	code, resp = get()
+	kube = resp.Dependencies["kubernetes"]
+	if code != http.StatusServiceUnavailable || resp.Status != "degraded" || kube.Status != "failing" ||
+		kube.LastError != "connection refused" || kube.LastErrorAt == nil || kube.LastSuccessAt == nil {
+		t.Errorf("/healthz/verbose = %d %+v while a check fails", code, resp)
+	}
+
+	// The last error is kept once the dependency recovers.
+	kubeErr = nil
+	if _, resp = get(); resp.Dependencies["kubernetes"].Status != "ok" || resp.Dependencies["kubernetes"].LastError != "connection refused" {
+		t.Errorf("/healthz/verbose = %+v after recovering", resp)
+	}
+}
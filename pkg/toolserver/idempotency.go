@@ -0,0 +1,175 @@
+package toolserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+const (
+	// idempotencyHeader carries the key of a REST call; MCP tool calls send
+	// it as _meta.idempotencyKey.
+	idempotencyHeader = "Idempotency-Key"
+	// replayedHeader marks a REST response replayed for a repeated key.
+	replayedHeader = "Idempotent-Replayed"
+
+	defaultIdempotencyTTL  = 10 * time.Minute // default of $IDEMPOTENCY_TTL
+	defaultIdempotencyKeys = 10000            // default of $IDEMPOTENCY_MAX_KEYS
+	maxIdempotencyKeyLen   = 255
+)
+
+// idempotentCall is the idempotency key of a call, carried in its context,
+// and whether its result was replayed.
+type idempotentCall struct {
+	key      string
+	replayed bool
+}
+
+type idempotencyKey struct{}
+
+// withIdempotencyKey returns ctx carrying the call's idempotency key, if it
+// has one.
+func withIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyKey{}, &idempotentCall{key: key})
+}
+
+func idempotentCallFrom(ctx context.Context) *idempotentCall {
+	c, _ := ctx.Value(idempotencyKey{}).(*idempotentCall)
+	return c
+}
+
+// idempotentResult is the result of the first call with a key.
+type idempotentResult struct {
+	request [sha256.Size]byte // digest of the request, to tell a reused key
+	done    chan struct{}     // closed when the call returns
+	resp    any
+	err     error
+	expires time.Time // zero while the call runs
+}
+
+// idempotencyStore holds the results of a write operation's calls by
+// idempotency key, so a retried call returns the first one's result instead
+// of making the change again. Every operation registered with Writes has
+// one; a call opts in by sending a key.
+//
+// A call with a key seen in the last $IDEMPOTENCY_TTL (default 10m) from the
+// same caller is not run: it waits for the first call if that is still
+// running, then returns its response or error, marked with an
+// Idempotent-Replayed: true header over REST. Retryable errors, such as a
+// timeout before the change was made, are not kept, so the retry runs. A
+// key reused with a different request fails with 422 UNPROCESSABLE. At most
+// $IDEMPOTENCY_MAX_KEYS (default 10000) keys are kept per operation.
+type idempotencyStore struct {
+	mu      sync.Mutex
+	results map[string]*idempotentResult
+}
+
+func newIdempotencyStore() *idempotencyStore {
+	return &idempotencyStore{results: make(map[string]*idempotentResult)}
+}
+
+// call runs fn for a call of op with the key in ctx, unless a call with the
+// same key has run already, and returns its result.
+func (st *idempotencyStore) call(ctx context.Context, op string, body []byte, fn func() (any, error)) (any, error) {
+	c := idempotentCallFrom(ctx)
+	if c == nil {
+		return fn()
+	}
+	if len(c.key) > maxIdempotencyKeyLen {
+		return nil, BadRequest("%s must be at most %d characters", idempotencyHeader, maxIdempotencyKeyLen)
+	}
+	canonical, ok := cacheKey(ctx, body)
+	if !ok {
+		return fn()
+	}
+	var user string
+	if id := IdentityFrom(ctx); id != nil {
+		user = id.Issuer + "\x00" + id.Username
+	}
+	key := user + "\x00" + c.key
+	request := sha256.Sum256([]byte(canonical))
+
+	st.mu.Lock()
+	st.evict(time.Now())
+	r, ok := st.results[key]
+	if !ok {
+		r = &idempotentResult{request: request, done: make(chan struct{})}
+		st.results[key] = r
+		st.mu.Unlock()
+		idempotencyRequests.WithLabelValues(op, "first").Inc()
+		return st.run(key, r, fn)
+	}
+	st.mu.Unlock()
+
+	if r.request != request {
+		idempotencyRequests.WithLabelValues(op, "mismatch").Inc()
+		return nil, NewError(CodeUnprocessable, "%s %q was already used with a different request", idempotencyHeader, c.key)
+	}
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return nil, NewError(CodeConflict, "the first call with %s %q is still running", idempotencyHeader, c.key)
+	}
+	idempotencyRequests.WithLabelValues(op, "replay").Inc()
+	c.replayed = true
+	return r.resp, r.err
+}
+
+// run runs fn as the first call with key and keeps its result, unless it is
+// a retryable error or fn panics.
+func (st *idempotencyStore) run(key string, r *idempotentResult, fn func() (any, error)) (any, error) {
+	keep := false
+	defer func() {
+		st.mu.Lock()
+		if keep {
+			r.expires = time.Now().Add(config.Duration("IDEMPOTENCY_TTL", defaultIdempotencyTTL))
+		} else {
+			delete(st.results, key)
+		}
+		st.mu.Unlock()
+		close(r.done)
+	}()
+	r.resp, r.err = fn()
+	if r.err != nil {
+		envelope, _ := errorResponse(r.err)
+		keep = !envelope.Retryable
+	} else {
+		keep = true
+	}
+	return r.resp, r.err
+}
+
+// evict drops expired results and, while the store is full, finished ones,
+// so a new key always fits.
+// st.mu must be held.
+func (st *idempotencyStore) evict(now time.Time) {
+	limit := config.Int("IDEMPOTENCY_MAX_KEYS", defaultIdempotencyKeys)
+	for k, r := range st.results {
+		if !r.expires.IsZero() && now.After(r.expires) {
+			delete(st.results, k)
+		}
+	}
+	for k, r := range st.results {
+		if len(st.results) < limit {
+			break
+		}
+		if !r.expires.IsZero() {
+			delete(st.results, k)
+		}
+	}
+}
+
+// markReplayed sets the Idempotent-Replayed header on a REST response whose
+// result was replayed.
+func markReplayed(ctx context.Context, w http.ResponseWriter) {
+	if c := idempotentCallFrom(ctx); c != nil && c.replayed {
+		w.Header().Set(replayedHeader, "true")
+	}
+}
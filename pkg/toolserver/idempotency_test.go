@@ -0,0 +1,69 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	t.Setenv("FEATURES", "scale")
+	var calls atomic.Int32
+	s := New("idempotent")
+	Register(s, "/scale", func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		n := calls.Add(1)
+		if req.Message == "busy" && n == 1 {
+			return echoResponse{}, NewError(CodeUnavailable, "try again")
+		}
+		return echoResponse{Echo: req.Message + " " + strconv.Itoa(int(n))}, nil
+	}, Writes(FeatureScale))
+	post := func(key, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/scale", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(idempotencyHeader, key)
+		}
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := post("k1", `{"message":"web"}`)
+	again := post("k1", `{ "message": "web" }`)
+	if again.Body.String() != first.Body.String() || again.Header().Get(replayedHeader) != "true" {
+		t.Errorf("repeated key: %s (replayed %q), want %s replayed", again.Body, again.Header().Get(replayedHeader), first.Body)
+	}
+	if first.Header().Get(replayedHeader) != "" {
+		t.Error("the first call is marked as replayed")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("the handler ran %d times, want once", calls.Load())
+	}
+	if rec := post("k1", `{"message":"api"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key: %d %s, want 422", rec.Code, rec.Body)
+	}
+	if rec := post("", `{"message":"web"}`); rec.Header().Get(replayedHeader) != "" || calls.Load() != 2 {
+		t.Error("a call without a key was replayed")
+	}
+
+	calls.Store(0)
+	if rec := post("k2", `{"message":"busy"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("first call = %d, want the 503", rec.Code)
+	}
+	if rec := post("k2", `{"message":"busy"}`); rec.Code != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("retry after a retryable error = %d with %d calls, want it to run again", rec.Code, calls.Load())
+	}
+
+	call := func(key string) string {
+		params, _ := json.Marshal(map[string]any{"name": "scale", "arguments": map[string]string{"message": "mcp"}, "_meta": map[string]string{"idempotencyKey": key}})
+		result, _ := s.dispatch(context.Background(), "tools/call", params)
+		return result.(callResult).Content[0].Text
+	}
+	if a, b := call("k3"), call("k3"); a != b {
+		t.Errorf("MCP calls with the same key returned %s and %s", a, b)
+	}
+}
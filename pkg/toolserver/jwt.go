@@ -0,0 +1,232 @@
+package toolserver
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // hashes for RS256/PS256/ES256
+	_ "crypto/sha512" // and the 384/512 variants
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var b64url = base64.RawURLEncoding
+
+// jwt is a decoded JWT whose signature has not been checked yet.
+type jwt struct {
+	header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	claims    map[string]any
+	signed    []byte // the signing input: header and payload as sent
+	signature []byte
+}
+
+func parseJWT(token string) (*jwt, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token must have three dot-separated parts")
+	}
+	t := &jwt{signed: []byte(parts[0] + "." + parts[1])}
+	header, err := b64url.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(header, &t.header) != nil {
+		return nil, errors.New("invalid token header")
+	}
+	payload, err := b64url.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(payload, &t.claims) != nil {
+		return nil, errors.New("invalid token claims")
+	}
+	if t.signature, err = b64url.DecodeString(parts[2]); err != nil {
+		return nil, errors.New("invalid token signature")
+	}
+	return t, nil
+}
+
+// jwsAlgorithm is how a JWS alg signs (RFC 7518): with which type of key,
+// hash and, for ECDSA, curve.
+type jwsAlgorithm struct {
+	kty   string // JWK key type: RSA, EC or OKP
+	hash  crypto.Hash
+	pss   bool           // RSASSA-PSS rather than PKCS #1 v1.5
+	curve elliptic.Curve // of EC keys
+}
+
+// jwsAlgorithms are the algorithms tokens can be signed with. Only
+// asymmetric ones are listed: an issuer's keys are public, so HMAC with them
+// proves nothing.
+var jwsAlgorithms = map[string]jwsAlgorithm{
+	"RS256": {kty: "RSA", hash: crypto.SHA256},
+	"RS384": {kty: "RSA", hash: crypto.SHA384},
+	"RS512": {kty: "RSA", hash: crypto.SHA512},
+	"PS256": {kty: "RSA", hash: crypto.SHA256, pss: true},
+	"PS384": {kty: "RSA", hash: crypto.SHA384, pss: true},
+	"PS512": {kty: "RSA", hash: crypto.SHA512, pss: true},
+	"ES256": {kty: "EC", hash: crypto.SHA256, curve: elliptic.P256()},
+	"ES384": {kty: "EC", hash: crypto.SHA384, curve: elliptic.P384()},
+	"ES512": {kty: "EC", hash: crypto.SHA512, curve: elliptic.P521()},
+	"EdDSA": {kty: "OKP"},
+}
+
+// fits reports whether key is of the algorithm's type and, for ECDSA, on
+// its curve.
+func (a jwsAlgorithm) fits(key crypto.PublicKey) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return a.kty == "RSA"
+	case *ecdsa.PublicKey:
+		return a.kty == "EC" && k.Curve == a.curve
+	case ed25519.PublicKey:
+		return a.kty == "OKP"
+	}
+	return false
+}
+
+// verify checks the token's signature with key. It fails if the header's
+// alg is not one of jwsAlgorithms or key does not fit it.
+func (t *jwt) verify(key crypto.PublicKey) error {
+	alg, ok := jwsAlgorithms[t.header.Alg]
+	if !ok {
+		return fmt.Errorf("unsupported token algorithm %q", t.header.Alg)
+	}
+	if !alg.fits(key) {
+		return fmt.Errorf("token signing key does not fit algorithm %s", t.header.Alg)
+	}
+	if k, ok := key.(ed25519.PublicKey); ok {
+		if !ed25519.Verify(k, t.signed, t.signature) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	}
+
+	h := alg.hash.New()
+	h.Write(t.signed)
+	digest := h.Sum(nil)
+	var err error
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if alg.pss {
+			err = rsa.VerifyPSS(k, alg.hash, digest, t.signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			err = rsa.VerifyPKCS1v15(k, alg.hash, digest, t.signature)
+		}
+	case *ecdsa.PublicKey:
+		// ES signatures are r and s concatenated, each the size of the curve.
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(t.signature) != 2*size {
+			err = errors.New("wrong signature size")
+			break
+		}
+		r := new(big.Int).SetBytes(t.signature[:size])
+		s := new(big.Int).SetBytes(t.signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			err = errors.New("verification failed")
+		}
+	}
+	if err != nil {
+		return errors.New("invalid token signature")
+	}
+	return nil
+}
+
+// jwk is a public key from a JWKS document (RFC 7517).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// signingKey is a parsed JWK.
+type signingKey struct {
+	kid string
+	alg string // empty if the JWK does not restrict it
+	key crypto.PublicKey
+}
+
+// fits reports whether the key can verify signatures made with alg: alg is
+// one of jwsAlgorithms, and the key is of its type and curve.
+func (k signingKey) fits(alg string) bool {
+	if k.alg != "" && k.alg != alg {
+		return false
+	}
+	a, ok := jwsAlgorithms[alg]
+	return ok && a.fits(k.key)
+}
+
+// parseJWKS returns the signature keys of a JWKS document, skipping keys of
+// unsupported types and encryption keys.
+func parseJWKS(data []byte) ([]signingKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %v", err)
+	}
+	var keys []signingKey
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys = append(keys, signingKey{kid: k.Kid, alg: k.Alg, key: key})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err1 := b64url.DecodeString(k.N)
+		e, err2 := b64url.DecodeString(k.E)
+		if err1 != nil || err2 != nil || len(e) > 4 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err1 := b64url.DecodeString(k.X)
+		y, err2 := b64url.DecodeString(k.Y)
+		if err1 != nil || err2 != nil {
+			return nil, errors.New("invalid EC key")
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid EC key")
+		}
+		return key, nil
+	case "OKP":
+		x, err := b64url.DecodeString(k.X)
+		if k.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid OKP key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
@@ -0,0 +1,118 @@
+package toolserver
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+	"testing"
+)
+
+// signJWT returns a token with alg in its header, signed with key using
+// hash, whatever alg says.
+func signJWT(t *testing.T, alg string, key crypto.Signer, hash crypto.Hash) *jwt {
+	t.Helper()
+	tok := &jwt{signed: []byte(b64url.EncodeToString([]byte(`{"alg":"`+alg+`"}`)) + ".e30")}
+	tok.header.Alg = alg
+	digest := tok.signed
+	if hash != 0 {
+		h := hash.New()
+		h.Write(tok.signed)
+		digest = h.Sum(nil)
+	}
+
+	var err error
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if alg[:2] == "PS" {
+			tok.signature, err = rsa.SignPSS(rand.Reader, k, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			tok.signature, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+		}
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest)
+		size := (k.Curve.Params().BitSize + 7) / 8
+		tok.signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	case ed25519.PrivateKey:
+		tok.signature = ed25519.Sign(k, tok.signed)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestJWTVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	p521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name  string
+		alg   string
+		key   crypto.Signer
+		hash  crypto.Hash
+		valid bool
+	}{
+		{"RS256", "RS256", rsaKey, crypto.SHA256, true},
+		{"RS384", "RS384", rsaKey, crypto.SHA384, true},
+		{"RS512", "RS512", rsaKey, crypto.SHA512, true},
+		{"PS256", "PS256", rsaKey, crypto.SHA256, true},
+		{"PS512", "PS512", rsaKey, crypto.SHA512, true},
+		{"ES256", "ES256", p256, crypto.SHA256, true},
+		{"ES384", "ES384", p384, crypto.SHA384, true},
+		{"ES512", "ES512", p521, crypto.SHA512, true},
+		{"EdDSA", "EdDSA", edKey, 0, true},
+		{"RS256 hashed as RS512", "RS256", rsaKey, crypto.SHA512, false},
+		{"unknown RSA alg", "RSP256", rsaKey, crypto.SHA256, false},
+		{"ES384 with a P-256 key", "ES384", p256, crypto.SHA384, false},
+		{"ES256 with a P-384 key", "ES256", p384, crypto.SHA256, false},
+		{"ES512 with a P-384 key", "ES512", p384, crypto.SHA512, false},
+		{"RS256 with an EC key", "RS256", p256, crypto.SHA256, false},
+		{"ES256 with an RSA key", "ES256", rsaKey, crypto.SHA256, false},
+		{"EdDSA with an EC key", "EdDSA", p256, crypto.SHA256, false},
+		{"HS256", "HS256", rsaKey, crypto.SHA256, false},
+		{"none", "none", rsaKey, crypto.SHA256, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := signJWT(t, tt.alg, tt.key, tt.hash)
+			err := tok.verify(tt.key.Public())
+			if tt.valid && err != nil {
+				t.Errorf("verify: %v", err)
+			} else if !tt.valid && err == nil {
+				t.Error("verify accepted the token")
+			}
+		})
+	}
+}
+
+func TestSigningKeyFits(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		key  signingKey
+		alg  string
+		fits bool
+	}{
+		{signingKey{key: &p256.PublicKey}, "ES256", true},
+		{signingKey{key: &p256.PublicKey}, "ES384", false},
+		{signingKey{key: &p256.PublicKey}, "RS256", false},
+		{signingKey{key: &rsaKey.PublicKey}, "PS384", true},
+		{signingKey{key: &rsaKey.PublicKey}, "RSP256", false},
+		{signingKey{key: &rsaKey.PublicKey, alg: "RS256"}, "PS256", false},
+		{signingKey{key: &rsaKey.PublicKey}, "HS256", false},
+	}
+	for _, tt := range tests {
+		if got := tt.key.fits(tt.alg); got != tt.fits {
+			t.Errorf("%T key with alg %q: fits(%s) = %v, want %v", tt.key.key, tt.key.alg, tt.alg, got, tt.fits)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package toolserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// requestIDHeader carries a request's ID. A valid ID sent by the caller
+// (e.g. the MCP gateway) is kept, otherwise one is generated. Either way it
+// is returned in the response and passed on to backends.
+const requestIDHeader = "X-Request-Id"
+
+// maxRequestIDLen bounds caller-supplied request IDs.
+const maxRequestIDLen = 128
+
+// logLevel is the level records are logged at.
+var logLevel slog.LevelVar
+
+// Tools log JSON to stderr through log/slog, at the level set by
+// $LOG_LEVEL: debug, info (the default), warn or error. The default logger
+// is replaced when the package loads so that a tool's own start-up messages,
+// logged before New, come out the same way. Records logged with a context
+// carry its request and trace IDs and the authenticated user.
+func init() {
+	slog.SetDefault(slog.New(contextHandler{slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: &logLevel})}))
+	setLogLevel()
+}
+
+// setLogLevel sets the log level from $LOG_LEVEL.
+func setLogLevel() {
+	level := slog.LevelInfo
+	v := config.String("LOG_LEVEL", "")
+	invalid := v != "" && level.UnmarshalText([]byte(v)) != nil
+	if invalid {
+		level = slog.LevelInfo
+	}
+	logLevel.Set(level)
+	if invalid {
+		slog.Warn("invalid LOG_LEVEL; using info", "value", v)
+	}
+}
+
+// contextHandler adds the request ID, trace context and user from the
+// record's context to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if span := spanFromContext(ctx); span != nil {
+		r.AddAttrs(
+			slog.String("trace_id", hex.EncodeToString(span.traceID[:])),
+			slog.String("span_id", hex.EncodeToString(span.spanID[:])),
+		)
+	}
+	if id := IdentityFrom(ctx); id != nil {
+		r.AddAttrs(slog.String("user", id.Username))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type requestIDKey struct{}
+
+// RequestID returns the ID of the request being served in ctx, or "" if
+// there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withRequestID returns ctx with the given request ID, or with a new one if
+// id is not usable.
+func withRequestID(ctx context.Context, id string) context.Context {
+	if !validRequestID(id) {
+		b := make([]byte, 16)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// validRequestID accepts short IDs of printable ASCII without spaces, so a
+// caller's ID can be logged and echoed in a header safely.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestUserKey holds where requireAuth records the caller for the request
+// log, which is written from a context that predates authentication.
+type requestUserKey struct{}
+
+// logRequests wraps the handler registered for pattern to assign each
+// request an ID and log it, with the authenticated user, once it has been
+// served. Health checks and metric scrapes are logged at debug level only.
+func logRequests(pattern string, h http.Handler) http.Handler {
+	level := slog.LevelInfo
+	if probePatterns[pattern] {
+		level = slog.LevelDebug
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := withRequestID(r.Context(), r.Header.Get(requestIDHeader))
+		w.Header().Set(requestIDHeader, RequestID(ctx))
+		var user *Identity
+		ctx = context.WithValue(ctx, requestUserKey{}, &user)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r.WithContext(ctx))
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if user != nil {
+			attrs = append(attrs, "user", user.Username)
+		}
+		slog.Log(ctx, level, "request", attrs...)
+	})
+}
+
+// logCall logs one call of the named operation. REST calls are already in
+// the request log, so successful calls are logged at debug level; failures
+// are logged at info, or at error for internal errors.
+func logCall(ctx context.Context, operation string, start time.Time, err error) {
+	level := slog.LevelDebug
+	attrs := []any{
+		"operation", operation,
+		"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status := errorStatus(err)
+		level = slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		attrs = append(attrs, "status", status, "error", err.Error())
+	}
+	slog.Log(ctx, level, "call", attrs...)
+}
@@ -0,0 +1,96 @@
+package toolserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var downstream string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Get("X-Request-Id")
+	}))
+	defer backend.Close()
+
+	var seen string
+	s := New("echo")
+	Register(s, "/fetch", func(ctx context.Context, _ struct{}) (echoResponse, error) {
+		seen = RequestID(ctx)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL, nil)
+		resp, err := (&http.Client{Transport: InstrumentTransport("backend", nil)}).Do(req)
+		if err != nil {
+			return echoResponse{}, err
+		}
+		resp.Body.Close()
+		return echoResponse{Echo: "ok"}, nil
+	})
+
+	tests := []struct {
+		name, header string
+		keep         bool
+	}{
+		{"none", "", false},
+		{"caller", "gw-1234", true},
+		{"spaces", "not an id", false},
+		{"too long", strings.Repeat("x", maxRequestIDLen+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/fetch", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Request-Id", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			id := rec.Header().Get("X-Request-Id")
+			if !validRequestID(id) || (id == tt.header) != tt.keep {
+				t.Errorf("response X-Request-Id = %q for %q", id, tt.header)
+			}
+			if seen != id || downstream != id {
+				t.Errorf("handler saw %q and backend %q, want %q", seen, downstream, id)
+			}
+		})
+	}
+
+	// Calls that do not arrive over HTTP get an ID too.
+	seen = ""
+	if _, err := s.lookup("fetch").call(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if !validRequestID(seen) {
+		t.Errorf("stdio call request ID = %q", seen)
+	}
+}
+
+func TestContextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(contextHandler{slog.NewJSONHandler(&buf, nil)}).With("tool", "echo")
+
+	ctx := withRequestID(context.Background(), "req-1")
+	ctx = extractTrace(ctx, http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}})
+	logger.InfoContext(ctx, "hello")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"msg":        "hello",
+		"tool":       "echo",
+		"request_id": "req-1",
+		"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":    "00f067aa0ba902b7",
+	}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("%s = %v, want %q", k, record[k], v)
+		}
+	}
+}
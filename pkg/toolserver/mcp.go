@@ -0,0 +1,295 @@
+package toolserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+)
+
+// protocolVersion is the MCP revision the server implements.
+const protocolVersion = "2025-06-18"
+
+// JSON-RPC 2.0 error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// rpcMessage is a JSON-RPC request, a notification (a request without an
+// ID) or a response from the client.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// isCall reports whether m is a request, which is answered, rather than a
+// notification or a response.
+func (m *rpcMessage) isCall() bool {
+	return m.ID != nil && m.Result == nil && m.Error == nil
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// tool is an operation as listed by tools/list.
+type tool struct {
+	Name         string         `json:"name"`
+	Description  string         `json:"description,omitempty"`
+	InputSchema  map[string]any `json:"inputSchema"`
+	OutputSchema map[string]any `json:"outputSchema,omitempty"`
+}
+
+type callParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Meta      struct {
+		IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	} `json:"_meta"`
+}
+
+type callResult struct {
+	Content           []Content      `json:"content"`
+	StructuredContent any            `json:"structuredContent,omitempty"`
+	IsError           bool           `json:"isError,omitempty"`
+	Meta              map[string]any `json:"_meta,omitempty"`
+}
+
+// ServeStdio speaks MCP over newline-delimited JSON-RPC, as used by local
+// clients that launch the tool as a subprocess. Requests are handled
+// concurrently. It returns when in is exhausted, or when ctx is done, which
+// also cancels the calls in flight.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	enc := json.NewEncoder(out)
+	write := func(resp *rpcResponse) {
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(resp)
+	}
+
+	// Reads block, so they run apart from the loop that watches ctx.
+	lines := make(chan []byte)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), int(s.maxBody))
+		for scanner.Scan() {
+			select {
+			case lines <- bytes.Clone(scanner.Bytes()):
+			case <-ctx.Done():
+				return
+			}
+		}
+		errc <- scanner.Err()
+	}()
+
+	defer wg.Wait()
+	for {
+		var line []byte
+		select {
+		case <-ctx.Done():
+			return nil
+		case l, ok := <-lines:
+			if !ok {
+				return <-errc
+			}
+			line = l
+		}
+
+		if len(line) == 0 {
+			continue
+		}
+		msg, errResp := decodeMessage(line)
+		if errResp != nil {
+			write(errResp)
+			continue
+		}
+		if !msg.isCall() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			write(s.handleMessage(ctx, msg))
+		}()
+	}
+}
+
+// decodeMessage decodes one JSON-RPC message. For malformed messages it
+// returns the error response to send instead.
+func decodeMessage(raw []byte) (rpcMessage, *rpcResponse) {
+	var msg rpcMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		code, message := codeInvalidRequest, "invalid request"
+		if !json.Valid(raw) {
+			code, message = codeParseError, "parse error"
+		}
+		return msg, &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+			Error: &rpcError{Code: code, Message: message}}
+	}
+	if msg.JSONRPC != "2.0" || (msg.Method == "" && msg.isCall()) {
+		id := msg.ID
+		if id == nil {
+			id = json.RawMessage("null")
+		}
+		return msg, &rpcResponse{JSONRPC: "2.0", ID: id,
+			Error: &rpcError{Code: codeInvalidRequest, Message: "invalid request"}}
+	}
+	return msg, nil
+}
+
+// handleMessage answers a request decoded by decodeMessage.
+func (s *Server) handleMessage(ctx context.Context, msg rpcMessage) *rpcResponse {
+	resp := &rpcResponse{JSONRPC: "2.0", ID: msg.ID}
+	result, err := s.dispatch(ctx, msg.Method, msg.Params)
+	if err != nil {
+		resp.Error = err
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+func (s *Server) dispatch(ctx context.Context, method string, params json.RawMessage) (any, *rpcError) {
+	switch method {
+	case "initialize":
+		capabilities := map[string]any{"tools": map[string]any{}}
+		if len(s.resources) > 0 {
+			capabilities["resources"] = map[string]any{}
+		}
+		if len(s.prompts) > 0 {
+			capabilities["prompts"] = map[string]any{}
+		}
+		return map[string]any{
+			"protocolVersion": protocolVersion,
+			"capabilities":    capabilities,
+			"serverInfo":      map[string]string{"name": s.name, "version": version()},
+		}, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		tools := make([]tool, 0, len(s.ops))
+		for _, op := range s.ops {
+			tools = append(tools, op.tool())
+		}
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		var p callParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params"}
+		}
+		op := s.lookup(p.Name)
+		if op == nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", p.Name)}
+		}
+		return op.callTool(withIdempotencyKey(ctx, p.Meta.IdempotencyKey), p.Arguments), nil
+	// Resources and prompts are only served by tools that have some, as
+	// only those declare the capability in initialize.
+	case "resources/list":
+		if len(s.resources) > 0 {
+			return s.listResources(), nil
+		}
+	case "resources/read":
+		if len(s.resources) > 0 {
+			return s.readResource(ctx, params)
+		}
+	case "prompts/list":
+		if len(s.prompts) > 0 {
+			return s.listPrompts(), nil
+		}
+	case "prompts/get":
+		if len(s.prompts) > 0 {
+			return s.getPrompt(params)
+		}
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", method)}
+}
+
+// tool describes op for tools/list. MCP output schemas must be objects, so
+// operations with other results, sent only as text, have none.
+func (op *operation) tool() tool {
+	t := tool{Name: op.name, Description: op.description, InputSchema: op.input}
+	if op.output["type"] == "object" {
+		t.OutputSchema = op.output
+	}
+	return t
+}
+
+// callTool runs the operation with MCP tool arguments. Handler errors are
+// tool results with isError set whose text is the error envelope, so the
+// model sees the message and agents can branch on the code.
+func (op *operation) callTool(ctx context.Context, args json.RawMessage) callResult {
+	resp, err := op.call(ctx, args)
+	if err != nil {
+		return toolError(err)
+	}
+	return toolResult(resp)
+}
+
+// toolResult is the result of a tools/call that returned resp: the content
+// blocks resp chooses if it is a ContentBlocker, or else resp as JSON text,
+// and resp as structuredContent.
+func toolResult(resp any) callResult {
+	text, err := json.Marshal(resp)
+	if err != nil {
+		return toolError(err)
+	}
+	result := callResult{Content: []Content{{Type: "text", Text: string(text)}}}
+	if b, ok := resp.(ContentBlocker); ok {
+		if blocks := b.ContentBlocks(); len(blocks) > 0 {
+			result.Content = blocks
+		}
+	}
+	// structuredContent must be an object; slices and scalars are left as text.
+	if len(text) > 0 && text[0] == '{' {
+		result.StructuredContent = json.RawMessage(text)
+	}
+	return result
+}
+
+// toolError is the result of a tools/call that failed with err: the error
+// envelope, without the message repeated for older REST clients.
+func toolError(err error) callResult {
+	resp, _ := errorResponse(err)
+	resp.Error = ""
+	text, _ := json.Marshal(resp)
+	return callResult{Content: []Content{{Type: "text", Text: string(text)}}, IsError: true}
+}
+
+// version reports the main module version for serverInfo.
+func version() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
@@ -0,0 +1,167 @@
+package toolserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveStdio runs the echo server over stdio with the given input lines and
+// returns the responses keyed by request ID.
+func serveStdio(t *testing.T, lines ...string) map[string]rpcResponse {
+	t.Helper()
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(lines, "\n") + "\n")
+	if err := newEchoServer().ServeStdio(context.Background(), in, &out); err != nil {
+		t.Fatalf("ServeStdio: %v", err)
+	}
+
+	responses := make(map[string]rpcResponse)
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp struct {
+			rpcResponse
+			Result json.RawMessage `json:"result"`
+		}
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		resp.rpcResponse.Result = resp.Result
+		responses[string(resp.ID)] = resp.rpcResponse
+	}
+	return responses
+}
+
+func TestServeStdio(t *testing.T) {
+	got := serveStdio(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"nope"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":7,"method":"ping"}`,
+		`{`,
+	)
+
+	if len(got) != 8 {
+		t.Fatalf("got %d responses, want 8 (no response to the notification): %v", len(got), got)
+	}
+
+	tests := []struct {
+		id     string
+		result string
+		code   int
+	}{
+		{"1", `{"capabilities":{"tools":{}},"protocolVersion":"2025-06-18","serverInfo":{"name":"echo","version":"(devel)"}}`, 0},
+		{"2", `{"tools":[{"name":"echo","inputSchema":{"properties":{"message":{"type":"string"}},"type":"object","additionalProperties":false},"outputSchema":{"properties":{"echo":{"type":"string"}},"type":"object"}}]}`, 0},
+		{"3", `{"content":[{"type":"text","text":"{\"echo\":\"hi\"}"}],"structuredContent":{"echo":"hi"}}`, 0},
+		{"4", `{"content":[{"type":"text","text":"{\"code\":\"INVALID_ARGUMENT\",\"message\":\"message is required\",\"retryable\":false}"}],"isError":true}`, 0},
+		{"5", ``, codeInvalidParams},
+		{"6", ``, codeMethodNotFound},
+		{"7", `{}`, 0},
+		{"null", ``, codeParseError},
+	}
+	for _, tt := range tests {
+		resp, ok := got[tt.id]
+		if !ok {
+			t.Errorf("no response for id %s", tt.id)
+			continue
+		}
+		if tt.code != 0 {
+			if resp.Error == nil || resp.Error.Code != tt.code {
+				t.Errorf("id %s: error = %+v, want code %d", tt.id, resp.Error, tt.code)
+			}
+			continue
+		}
+		if resp.Error != nil {
+			t.Errorf("id %s: unexpected error %+v", tt.id, resp.Error)
+			continue
+		}
+		if result := string(resp.Result.(json.RawMessage)); !jsonEqual(t, result, tt.result) {
+			t.Errorf("id %s: result = %s, want %s", tt.id, result, tt.result)
+		}
+	}
+}
+
+func TestServeStdioCancel(t *testing.T) {
+	in, _ := io.Pipe() // never closed, like the stdin of a client that hangs
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- newEchoServer().ServeStdio(ctx, in, io.Discard) }()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeStdio returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ServeStdio did not return after ctx was cancelled")
+	}
+}
+
+func TestInputSchema(t *testing.T) {
+	type inner struct {
+		Depth int `json:"depth,omitempty"`
+	}
+	type request struct {
+		inner
+		Names   []string          `json:"names"`
+		Labels  map[string]string `json:"labels"`
+		Ratio   *float64          `json:"ratio"`
+		Verbose bool              `json:"verbose"`
+		Skipped string            `json:"-"`
+	}
+
+	raw, _ := json.Marshal(inputSchema(reflect.TypeFor[request]()))
+	want := `{"type":"object","properties":{
+		"depth":{"type":"integer"},
+		"names":{"type":"array","items":{"type":"string"}},
+		"labels":{"type":"object","additionalProperties":{"type":"string"}},
+		"ratio":{"type":"number"},
+		"verbose":{"type":"boolean"}},
+		"additionalProperties":false}`
+	if !jsonEqual(t, string(raw), want) {
+		t.Errorf("schema = %s", raw)
+	}
+
+	raw, _ = json.Marshal(inputSchema(reflect.TypeFor[struct{}]()))
+	if !jsonEqual(t, string(raw), `{"type":"object","properties":{},"additionalProperties":false}`) {
+		t.Errorf("empty schema = %s", raw)
+	}
+}
+
+func TestOutputSchema(t *testing.T) {
+	type response struct {
+		Names []string `json:"names"`
+		Count int      `json:"count"`
+	}
+	raw, _ := json.Marshal(outputSchema(reflect.TypeFor[response]()))
+	want := `{"type":"object","properties":{
+		"names":{"type":["array","null"],"items":{"type":"string"}},
+		"count":{"type":"integer"}}}`
+	if !jsonEqual(t, string(raw), want) {
+		t.Errorf("schema = %s", raw)
+	}
+}
+
+func jsonEqual(t *testing.T, a, b string) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal([]byte(a), &va); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal([]byte(b), &vb); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return bytes.Equal(ja, jb)
+}
@@ -0,0 +1,154 @@
+package toolserver
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Request metrics are recorded for every endpoint registered on a Server,
+// operation metrics for every call of a registered operation whichever
+// transport it arrives on (REST, /rpc or MCP), pool metrics for every Pool,
+// and client metrics for outgoing requests made through
+// InstrumentTransport.
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "toolserver_requests_total",
+		Help: "HTTP requests served, by endpoint, method and status code.",
+	}, []string{"handler", "method", "code"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "toolserver_request_duration_seconds",
+		Help:    "Time to serve an HTTP request, by endpoint and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"handler", "method"})
+
+	requestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "toolserver_requests_in_flight",
+		Help: "HTTP requests being served, including open event streams.",
+	}, []string{"handler"})
+
+	operationCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "toolserver_operation_calls_total",
+		Help: "Operation calls over any transport, by operation and resulting HTTP status.",
+	}, []string{"operation", "status"})
+
+	operationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "toolserver_operation_duration_seconds",
+		Help:    "Time to run an operation, by operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	deprecatedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "toolserver_deprecated_requests_total",
+		Help: "Requests to the unversioned paths of endpoints, by endpoint.",
+	}, []string{"handler"})
+
+	panicsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "toolserver_panics_total",
+		Help: "Panics recovered, by endpoint or operation.",
+	}, []string{"handler"})
+
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "toolserver_cache_requests_total",
+		Help: "Calls of cached operations, by operation and result (hit, miss or bypass).",
+	}, []string{"operation", "result"})
+
+	staleResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "toolserver_stale_responses_total",
+		Help: "Calls answered with an earlier response because a dependency was unreachable, by operation.",
+	}, []string{"operation"})
+
+	websocketSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "toolserver_websocket_sessions",
+		Help: "Open MCP sessions over WebSocket.",
+	})
+
+	idempotencyRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "toolserver_idempotency_requests_total",
+		Help: "Calls of write operations with an idempotency key, by operation and result (first, replay or mismatch).",
+	}, []string{"operation", "result"})
+
+	poolSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "toolserver_pool_size",
+		Help: "Slots of a worker pool, by pool.",
+	}, []string{"pool"})
+
+	poolInUse = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "toolserver_pool_in_use",
+		Help: "Slots of a worker pool in use, by pool.",
+	}, []string{"pool"})
+
+	poolQueued = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "toolserver_pool_queue_depth",
+		Help: "Tasks waiting for a slot of a worker pool, by pool.",
+	}, []string{"pool"})
+
+	poolWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "toolserver_pool_wait_seconds",
+		Help:    "Time queued tasks waited for a slot of a worker pool, by pool.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"pool"})
+
+	poolRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "toolserver_pool_rejected_total",
+		Help: "Tasks turned away because a worker pool and its queue were full, by pool.",
+	}, []string{"pool"})
+
+	configReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "toolserver_config_reloads_total",
+		Help: "Changes to the config file taken up (success) or rejected (error), by result.",
+	}, []string{"result"})
+
+	clientRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "toolserver_client_requests_total",
+		Help: "Outgoing HTTP requests, by client (e.g. kubernetes, registry), method and status code.",
+	}, []string{"client", "method", "code"})
+
+	clientDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "toolserver_client_request_duration_seconds",
+		Help:    "Time until the response headers of an outgoing HTTP request, by client and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"client", "method"})
+)
+
+// instrument wraps the handler registered for pattern with request metrics.
+func instrument(pattern string, h http.Handler) http.Handler {
+	labels := prometheus.Labels{"handler": pattern}
+	return promhttp.InstrumentHandlerInFlight(requestsInFlight.With(labels),
+		promhttp.InstrumentHandlerDuration(requestDuration.MustCurryWith(labels),
+			promhttp.InstrumentHandlerCounter(requestsTotal.MustCurryWith(labels), h)))
+}
+
+// observeCall records one call of the named operation.
+func observeCall(operation string, start time.Time, err error) {
+	status := http.StatusOK
+	if err != nil {
+		status = errorStatus(err)
+	}
+	operationCalls.WithLabelValues(operation, strconv.Itoa(status)).Inc()
+	operationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// InstrumentTransport wraps rt, or OutboundTransport if rt is nil, to
+// record the requests a tool makes to its backends under the given client
+// label, and to trace them, passing the trace on in a traceparent header
+// and the request ID in X-Request-Id.
+// For a Kubernetes clientset, wrap the rest.Config:
+//
+//	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+//		return toolserver.InstrumentTransport("kubernetes", rt)
+//	})
+func InstrumentTransport(client string, rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = OutboundTransport()
+	}
+	labels := prometheus.Labels{"client": client}
+	return promhttp.InstrumentRoundTripperCounter(clientRequests.MustCurryWith(labels),
+		promhttp.InstrumentRoundTripperDuration(clientDuration.MustCurryWith(labels),
+			&tracingTransport{client: client, rt: rt}))
+}
@@ -0,0 +1,42 @@
+package toolserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	s := newEchoServer()
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"message":"hi"}`)))
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/rpc",
+		strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"echo","params":{}}`)))
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer backend.Close()
+	client := &http.Client{Transport: InstrumentTransport("backend", nil)}
+	resp, err := client.Get(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`toolserver_requests_total{code="200",handler="/echo",method="post"}`,
+		`toolserver_request_duration_seconds_count{handler="/rpc",method="post"}`,
+		`toolserver_requests_in_flight{handler="/metrics"} 1`,
+		`toolserver_operation_calls_total{operation="echo",status="200"}`,
+		`toolserver_operation_calls_total{operation="echo",status="400"}`,
+		`toolserver_client_requests_total{client="backend",code="418",method="get"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics is missing %s", want)
+		}
+	}
+}
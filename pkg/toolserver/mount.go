@@ -0,0 +1,33 @@
+package toolserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Mount serves t, another tool's server, under prefix, so one process and
+// port can serve several tools: after s.Mount("/explain", t), t's
+// /v1/explain is served as /explain/v1/explain and its MCP endpoint as
+// /explain/v1/mcp. t's endpoints keep their own middleware, so s only
+// routes to them, but t's /tools and /openapi.json give the paths with the
+// prefix. s's /readyz and /healthz/verbose also report t's dependencies,
+// named with the prefix, e.g. "explain/kubernetes"; config reloads apply to
+// t; and shutting s down ends t's event streams and cancels its calls. Mount
+// t once its checks are added, and before Run.
+func (s *Server) Mount(prefix string, t *Server) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	s.mux.Handle(prefix+"/", http.StripPrefix(prefix, t))
+	t.prefix = prefix
+	for _, c := range t.checks {
+		d := s.dependency(strings.TrimPrefix(prefix, "/") + "/" + c.name)
+		d.check, d.details = c.check, c.details
+	}
+	context.AfterFunc(s.halted, t.halt)
+	s.mounted = append(s.mounted, t)
+}
+
+// servers returns s and the servers mounted on it.
+func (s *Server) servers() []*Server {
+	return append([]*Server{s}, s.mounted...)
+}
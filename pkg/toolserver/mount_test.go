@@ -0,0 +1,49 @@
+package toolserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMount(t *testing.T) {
+	echo := New("echo-tool")
+	Register(echo, "/echo", func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		return echoResponse{Echo: req.Message}, nil
+	})
+	echo.AddReadinessCheck("upstream", func(context.Context) error { return errors.New("connection refused") })
+
+	s := New("multi-tool")
+	s.Mount("/echo/", echo)
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		want               string
+	}{
+		{http.MethodPost, "/echo/v1/echo", `{"message":"hi"}`, http.StatusOK, `"echo":"hi"`},
+		{http.MethodGet, "/echo/v1/tools", "", http.StatusOK, `"path":"/echo/v1/echo"`},
+		{http.MethodGet, "/echo/openapi.json", "", http.StatusOK, `"servers":[{"url":"/echo"}]`},
+		{http.MethodPost, "/v1/echo", `{"message":"hi"}`, http.StatusNotFound, ""},
+		{http.MethodGet, "/readyz", "", http.StatusServiceUnavailable, `"echo/upstream":"connection refused"`},
+		{http.MethodGet, "/echo/readyz", "", http.StatusServiceUnavailable, `"upstream":"connection refused"`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s %s = %d %s, want %d containing %s", tt.method, tt.path, rec.Code, rec.Body, tt.status, tt.want)
+		}
+	}
+
+	s.halt()
+	select {
+	case <-echo.halted.Done():
+	case <-time.After(time.Second):
+		t.Error("halting the server did not halt the mounted one")
+	}
+}
@@ -0,0 +1,172 @@
+package toolserver
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// openAPIPath serves the OpenAPI document. It is not versioned: the
+// document lists the versioned paths itself.
+const openAPIPath = "/openapi.json"
+
+// errorSchema is the JSON Schema of ErrorResponse.
+var errorSchema = map[string]any{
+	"type":     "object",
+	"required": []string{"code", "message", "retryable"},
+	"properties": map[string]any{
+		"code":      map[string]any{"type": "string", "enum": slices.Sorted(maps.Keys(codes))},
+		"message":   map[string]any{"type": "string"},
+		"details":   map[string]any{"type": "object"},
+		"retryable": map[string]any{"type": "boolean"},
+		"error":     map[string]any{"type": "string", "deprecated": true},
+		"fields": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"field":   map[string]any{"type": "string"},
+					"message": map[string]any{"type": "string"},
+				},
+			},
+		},
+	},
+}
+
+// toolResultSchema is the JSON Schema of the MCP tool results REST calls
+// answer with when they accept application/vnd.mcp.content+json.
+var toolResultSchema = map[string]any{
+	"type":     "object",
+	"required": []string{"content"},
+	"properties": map[string]any{
+		"content": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type":     "object",
+				"required": []string{"type"},
+				"properties": map[string]any{
+					"type": map[string]any{"type": "string", "enum": []string{"text", "resource"}},
+					"text": map[string]any{"type": "string"},
+					"resource": map[string]any{
+						"type":     "object",
+						"required": []string{"uri", "text"},
+						"properties": map[string]any{
+							"uri":      map[string]any{"type": "string"},
+							"mimeType": map[string]any{"type": "string"},
+							"text":     map[string]any{"type": "string"},
+						},
+					},
+				},
+			},
+		},
+		"structuredContent": map[string]any{"type": "object"},
+		"isError":           map[string]any{"type": "boolean"},
+		"_meta": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"outputSchema": map[string]any{"type": "string", "format": "uri-reference"}},
+		},
+	},
+}
+
+// openAPI returns an OpenAPI 3.1 document describing the REST endpoints of
+// the registered operations, for SDK generators and OpenAPI-based agent
+// frameworks. Request and response schemas are the ones MCP clients get.
+func (s *Server) openAPI() map[string]any {
+	paths := make(map[string]any, len(s.ops))
+	schemas := map[string]any{"Error": errorSchema, "ToolResult": toolResultSchema}
+	for _, op := range s.ops {
+		schemas[op.name] = op.output
+		item := map[string]any{"post": openAPIOperation(op, op.name, map[string]any{
+			"requestBody": map[string]any{
+				"content": map[string]any{"application/json": map[string]any{"schema": op.input}},
+			},
+		})}
+		if op.allowGet {
+			item["get"] = openAPIOperation(op, op.name+"-get", map[string]any{"parameters": queryParameters(op.input)})
+		}
+		paths[versionedPath(op.path)] = item
+	}
+
+	doc := map[string]any{
+		"openapi": "3.1.0",
+		"info":    map[string]any{"title": s.name, "version": APIVersion},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+	if s.prefix != "" {
+		doc["servers"] = []any{map[string]any{"url": s.prefix}}
+	}
+	var security []any
+	schemes := map[string]any{}
+	if s.auth != nil {
+		schemes["bearer"] = map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"}
+		security = append(security, map[string]any{"bearer": []string{}})
+	}
+	if s.certAuth != nil {
+		schemes["clientCertificate"] = map[string]any{"type": "mutualTLS"}
+		security = append(security, map[string]any{"clientCertificate": []string{}})
+	}
+	if len(schemes) > 0 {
+		doc["components"].(map[string]any)["securitySchemes"] = schemes
+		if len(security) == 2 {
+			// Both are required, so they form a single requirement.
+			security = []any{map[string]any{"bearer": []string{}, "clientCertificate": []string{}}}
+		}
+		doc["security"] = security
+	}
+	return doc
+}
+
+// openAPIOperation describes op under the operation ID id, with the fields
+// in extra added.
+func openAPIOperation(op *operation, id string, extra map[string]any) map[string]any {
+	o := map[string]any{
+		"operationId": id,
+		"responses": map[string]any{
+			"200": map[string]any{
+				"description": "OK",
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/" + op.name}},
+					mcpContentType:     map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/ToolResult"}},
+				},
+			},
+			"default": map[string]any{
+				"description": "Error",
+				"content": map[string]any{"application/json": map[string]any{
+					"schema": map[string]any{"$ref": "#/components/schemas/Error"},
+				}},
+			},
+		},
+	}
+	if op.description != "" {
+		o["description"] = op.description
+	}
+	maps.Copy(o, extra)
+	return o
+}
+
+// queryParameters lists the string properties of an input schema, which
+// are what a GET request can set from its query.
+func queryParameters(input map[string]any) []any {
+	properties, _ := input["properties"].(map[string]any)
+	params := []any{}
+	for _, name := range slices.Sorted(maps.Keys(properties)) {
+		prop, _ := properties[name].(map[string]any)
+		if prop["type"] != "string" {
+			continue
+		}
+		params = append(params, map[string]any{"name": name, "in": "query", "schema": prop})
+	}
+	return params
+}
+
+// handleOpenAPI serves the OpenAPI document.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+	WriteJSON(w, http.StatusOK, s.openAPI())
+}
@@ -0,0 +1,63 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	s := newEchoServer()
+	Register(s, "/jwt/verify", func(ctx context.Context, req struct {
+		Token   string `json:"token"`
+		Leeway  int    `json:"leeway_seconds"`
+		Subject string `json:"subject"`
+	}) (echoResponse, error) {
+		return echoResponse{}, nil
+	}, Describe("Verify a JWT."), AllowGet())
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Deprecation") != "" {
+		t.Fatalf("/openapi.json = %d %v", rec.Code, rec.Header())
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct{ Title, Version string }
+		Paths   map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Description string
+			Parameters  []struct{ Name, In string }
+			RequestBody struct {
+				Content map[string]struct{ Schema map[string]any }
+			} `json:"requestBody"`
+			Responses map[string]any
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title != "echo" || doc.Info.Version != "v1" {
+		t.Errorf("header = %s %+v", doc.OpenAPI, doc.Info)
+	}
+	if len(doc.Paths) != 2 {
+		t.Errorf("paths = %v, want /v1/echo and /v1/jwt/verify", doc.Paths)
+	}
+
+	echo := doc.Paths["/v1/echo"]["post"]
+	if echo.OperationID != "echo" || echo.RequestBody.Content["application/json"].Schema["type"] != "object" ||
+		echo.Responses["200"] == nil || echo.Responses["default"] == nil {
+		t.Errorf("/v1/echo post = %+v", echo)
+	}
+	if _, ok := doc.Paths["/v1/echo"]["get"]; ok {
+		t.Error("/v1/echo has a get operation without AllowGet")
+	}
+
+	get := doc.Paths["/v1/jwt/verify"]["get"]
+	if get.OperationID != "jwt-verify-get" || get.Description != "Verify a JWT." || len(get.Parameters) != 2 ||
+		get.Parameters[0].Name != "subject" || get.Parameters[1].Name != "token" || get.Parameters[0].In != "query" {
+		t.Errorf("/v1/jwt/verify get = %+v, want the string fields as query parameters", get)
+	}
+}
@@ -0,0 +1,309 @@
+package toolserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// Export batching. Spans beyond maxQueuedSpans are dropped rather than
+// blocking requests while the collector is slow or down.
+const (
+	exportInterval  = 5 * time.Second
+	maxExportBatch  = 512
+	maxQueuedSpans  = 2048
+	exportTimeout   = 10 * time.Second
+	instrumentation = "github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// tracer is the process-wide tracing configuration; nil until setupTracing
+// finds an OTLP endpoint.
+var (
+	tracer      atomic.Pointer[tracerConfig]
+	tracingOnce sync.Once
+)
+
+type tracerConfig struct {
+	sampler  sampler
+	exporter *exporter
+}
+
+func activeTracer() *tracerConfig { return tracer.Load() }
+
+// setupTracing enables tracing from the standard OpenTelemetry environment
+// variables when OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set. Spans are exported with
+// OTLP/HTTP in its JSON encoding, which every OpenTelemetry Collector
+// accepts on port 4318. serviceName is used unless OTEL_SERVICE_NAME is set.
+func setupTracing(serviceName string) {
+	tracingOnce.Do(func() {
+		if config.Bool("OTEL_SDK_DISABLED", false) || config.String("OTEL_TRACES_EXPORTER", "") == "none" {
+			return
+		}
+		endpoint := config.String("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+		if endpoint == "" {
+			base := config.String("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+			if base == "" {
+				return
+			}
+			endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+		}
+		if protocol := otelEnv("PROTOCOL"); protocol != "" && protocol != "http/json" {
+			slog.Warn("unsupported OTLP protocol; exporting traces as http/json", "protocol", protocol)
+		}
+		if name := config.String("OTEL_SERVICE_NAME", ""); name != "" {
+			serviceName = name
+		}
+
+		resource := map[string]any{"service.name": serviceName}
+		for k, v := range parseKeyValues(config.String("OTEL_RESOURCE_ATTRIBUTES", "")) {
+			if k != "service.name" || config.String("OTEL_SERVICE_NAME", "") == "" {
+				resource[k] = v
+			}
+		}
+
+		exp := &exporter{
+			endpoint: endpoint,
+			headers:  parseKeyValues(otelEnv("HEADERS")),
+			resource: resource,
+			client:   &http.Client{Timeout: exportTimeout, Transport: OutboundTransport()},
+			queue:    make(chan *Span, maxQueuedSpans),
+		}
+		go exp.run()
+		tracer.Store(&tracerConfig{sampler: samplerFromConfig(), exporter: exp})
+		slog.Info("exporting traces", "endpoint", endpoint, "service", resource["service.name"])
+	})
+}
+
+// otelEnv reads OTEL_EXPORTER_OTLP_TRACES_<name>, falling back to
+// OTEL_EXPORTER_OTLP_<name>.
+func otelEnv(name string) string {
+	if v := config.String("OTEL_EXPORTER_OTLP_TRACES_"+name, ""); v != "" {
+		return v
+	}
+	return config.String("OTEL_EXPORTER_OTLP_"+name, "")
+}
+
+// parseKeyValues parses the "k1=v1,k2=v2" lists used by OTEL_* variables,
+// with URL-encoded values.
+func parseKeyValues(s string) map[string]string {
+	kv := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		if decoded, err := url.PathUnescape(strings.TrimSpace(v)); err == nil {
+			v = decoded
+		}
+		kv[strings.TrimSpace(k)] = v
+	}
+	return kv
+}
+
+// samplerFromConfig follows OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG.
+// The default, as in the OpenTelemetry SDKs, is parentbased_always_on.
+func samplerFromConfig() sampler {
+	ratio := 1.0
+	if arg, err := strconv.ParseFloat(config.String("OTEL_TRACES_SAMPLER_ARG", ""), 64); err == nil {
+		ratio = arg
+	}
+	switch name := config.String("OTEL_TRACES_SAMPLER", ""); name {
+	case "always_on":
+		return sampler{ratio: 1}
+	case "always_off":
+		return sampler{ratio: 0}
+	case "traceidratio":
+		return sampler{ratio: ratio}
+	case "parentbased_always_off":
+		return sampler{ratio: 0, parentBased: true}
+	case "parentbased_traceidratio":
+		return sampler{ratio: ratio, parentBased: true}
+	case "", "parentbased_always_on":
+		return sampler{ratio: 1, parentBased: true}
+	default:
+		slog.Warn("unsupported OTEL_TRACES_SAMPLER; using parentbased_always_on", "sampler", name)
+		return sampler{ratio: 1, parentBased: true}
+	}
+}
+
+// exporter batches ended spans and posts them to an OTLP/HTTP endpoint.
+type exporter struct {
+	endpoint string
+	headers  map[string]string
+	resource map[string]any
+	client   *http.Client
+	queue    chan *Span
+}
+
+func (e *exporter) enqueue(span *Span) {
+	select {
+	case e.queue <- span:
+	default: // queue full: drop the span
+	}
+}
+
+func (e *exporter) run() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := e.flush(context.Background()); err != nil {
+			slog.Warn("exporting traces failed", "err", err)
+		}
+	}
+}
+
+// flush exports every queued span.
+func (e *exporter) flush(ctx context.Context) error {
+	for {
+		var batch []*Span
+	collect:
+		for len(batch) < maxExportBatch {
+			select {
+			case span := <-e.queue:
+				batch = append(batch, span)
+			default:
+				break collect
+			}
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := e.export(ctx, batch); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *exporter) export(ctx context.Context, batch []*Span) error {
+	spans := make([]otlpSpan, len(batch))
+	for i, s := range batch {
+		spans[i] = s.otlp()
+	}
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes(e.resource)},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: instrumentation}, Spans: spans}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", e.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// The OTLP/JSON encoding of ExportTraceServiceRequest. IDs are hex and
+// 64-bit integers are decimal strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"` // 2 is error
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+func (s *Span) otlp() otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	for _, a := range s.attrs {
+		span.Attributes = append(span.Attributes, otlpKeyValue{Key: a.key, Value: otlpValue(a.value)})
+	}
+	if s.err != "" {
+		span.Status = otlpStatus{Code: 2, Message: s.err}
+	}
+	return span
+}
+
+func otlpAttributes[V any](m map[string]V) []otlpKeyValue {
+	attrs := make([]otlpKeyValue, 0, len(m))
+	for k, v := range m {
+		attrs = append(attrs, otlpKeyValue{Key: k, Value: otlpValue(v)})
+	}
+	return attrs
+}
+
+func otlpValue(v any) map[string]any {
+	switch v := v.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
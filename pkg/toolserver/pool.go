@@ -0,0 +1,91 @@
+package toolserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// poolRetryAfter is the Retry-After sent when a pool is saturated. Work in
+// pools takes seconds at most, so slots free up quickly.
+const poolRetryAfter = time.Second
+
+// A Pool bounds how many expensive tasks, such as registry inspections or
+// OpenAPI parsing, run at once across all calls, so that agents fanning out
+// requests cannot exhaust a tool's memory. Tasks beyond its size wait in a
+// bounded queue; once that is full too, Acquire fails with 503 Service
+// Unavailable and a Retry-After, which clients back off on.
+//
+// Its slots in use, queue depth, waits and rejections are exported as
+// toolserver_pool_* metrics labelled with its name.
+type Pool struct {
+	name    string
+	slots   chan struct{}
+	queue   int64
+	waiting atomic.Int64
+}
+
+// NewPool returns a pool of size slots with room for queue waiting tasks.
+// The settings NAME_CONCURRENCY and NAME_QUEUE override them, where NAME is
+// the upper-cased name with dashes as underscores: "weather-batch" is set
+// by $WEATHER_BATCH_CONCURRENCY and $WEATHER_BATCH_QUEUE.
+func NewPool(name string, size, queue int) *Pool {
+	prefix := settingName(name)
+	size = max(config.Int(prefix+"_CONCURRENCY", size), 1)
+	queue = max(config.Int(prefix+"_QUEUE", queue), 0)
+	poolSize.WithLabelValues(name).Set(float64(size))
+	return &Pool{name: name, slots: make(chan struct{}, size), queue: int64(queue)}
+}
+
+// settingName returns the prefix of the settings of the pool or operation
+// called name: name upper-cased, with dashes as underscores.
+func settingName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Acquire waits for a slot and returns the function that releases it. It
+// fails straight away if the queue is full, and with ctx's error if ctx is
+// done first.
+func (p *Pool) Acquire(ctx context.Context) (release func(), err error) {
+	select {
+	case p.slots <- struct{}{}:
+		return p.acquired(), nil
+	default:
+	}
+
+	if p.waiting.Add(1) > p.queue {
+		p.waiting.Add(-1)
+		poolRejected.WithLabelValues(p.name).Inc()
+		return nil, &Error{Status: http.StatusServiceUnavailable, Message: p.name + " is busy, try again later", RetryAfter: poolRetryAfter}
+	}
+	queued := poolQueued.WithLabelValues(p.name)
+	queued.Inc()
+	defer func(start time.Time) {
+		p.waiting.Add(-1)
+		queued.Dec()
+		poolWait.WithLabelValues(p.name).Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	select {
+	case p.slots <- struct{}{}:
+		return p.acquired(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *Pool) acquired() func() {
+	inUse := poolInUse.WithLabelValues(p.name)
+	inUse.Inc()
+	var once atomic.Bool
+	return func() {
+		if once.CompareAndSwap(false, true) {
+			inUse.Dec()
+			<-p.slots
+		}
+	}
+}
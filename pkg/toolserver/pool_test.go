@@ -0,0 +1,54 @@
+package toolserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	t.Setenv("TEST_POOL_QUEUE", "1")
+	p := NewPool("test-pool", 1, 5)
+
+	release, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queued := make(chan error)
+	go func() {
+		release, err := p.Acquire(context.Background())
+		if err == nil {
+			release()
+		}
+		queued <- err
+	}()
+	for p.waiting.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The queue holds one task, so the next is turned away.
+	_, err = p.Acquire(context.Background())
+	rec := httptest.NewRecorder()
+	WriteError(rec, err)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("saturated pool = %d %v, want 503 with Retry-After", rec.Code, rec.Header())
+	}
+
+	release()
+	release() // releasing twice frees one slot only
+	if err := <-queued; err != nil {
+		t.Errorf("queued task = %v, want a slot once released", err)
+	}
+
+	release, _ = p.Acquire(context.Background())
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire past its deadline = %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package toolserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Prompt is a parameterized prompt, such as steps for troubleshooting a
+// pod, that MCP clients offer their users. It has the fields of an
+// MCPPrompt's spec, so the operator can aggregate it as one.
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Template    string           `json:"template"` // with {{variable}} placeholders
+	Variables   []PromptVariable `json:"variables,omitempty"`
+}
+
+// PromptVariable is a variable of a Prompt's template.
+type PromptVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+// promptArgument describes a variable for prompts/list.
+type promptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// promptMessage is a message of a prompts/get result.
+type promptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+// placeholder matches a {{variable}} of a template.
+var placeholder = regexp.MustCompile(`\{\{([a-zA-Z0-9_]+)\}\}`)
+
+// AddPrompt serves p as an MCP prompt: listed by prompts/list and rendered
+// with the caller's arguments by prompts/get, as a single user message.
+// Over REST, GET /v1/prompts lists the prompts with their templates and
+// POST /v1/prompts/NAME renders one from a JSON object of arguments. Add
+// prompts before Run.
+func (s *Server) AddPrompt(p Prompt) {
+	s.prompts = append(s.prompts, p)
+}
+
+func (s *Server) prompt(name string) (Prompt, bool) {
+	for _, p := range s.prompts {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Prompt{}, false
+}
+
+// render returns p's template with its placeholders replaced by args, or
+// by the variables' defaults. A missing required variable is an error;
+// placeholders that are not variables are left as they are.
+func (p Prompt) render(args map[string]string) (string, error) {
+	values := make(map[string]string, len(p.Variables))
+	var missing []string
+	for _, v := range p.Variables {
+		value, ok := args[v.Name]
+		switch {
+		case ok:
+		case v.Required:
+			missing = append(missing, v.Name)
+		default:
+			value = v.Default
+		}
+		values[v.Name] = value
+	}
+	if len(missing) > 0 {
+		return "", BadRequest("prompt %s needs %s", p.Name, strings.Join(missing, ", "))
+	}
+	return placeholder.ReplaceAllStringFunc(p.Template, func(m string) string {
+		if value, ok := values[m[2:len(m)-2]]; ok {
+			return value
+		}
+		return m
+	}), nil
+}
+
+// get renders p as a prompts/get result.
+func (p Prompt) get(args map[string]string) (any, error) {
+	text, err := p.render(args)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"description": p.Description,
+		"messages":    []promptMessage{{Role: "user", Content: TextContent(text)}},
+	}, nil
+}
+
+// listPrompts answers prompts/list.
+func (s *Server) listPrompts() any {
+	list := make([]map[string]any, 0, len(s.prompts))
+	for _, p := range s.prompts {
+		args := make([]promptArgument, 0, len(p.Variables))
+		for _, v := range p.Variables {
+			args = append(args, promptArgument{Name: v.Name, Description: v.Description, Required: v.Required})
+		}
+		list = append(list, map[string]any{"name": p.Name, "description": p.Description, "arguments": args})
+	}
+	return map[string]any{"prompts": list}
+}
+
+// getPrompt answers prompts/get.
+func (s *Server) getPrompt(params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params"}
+	}
+	prompt, ok := s.prompt(p.Name)
+	if !ok {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown prompt: %s", p.Name)}
+	}
+	result, err := prompt.get(p.Arguments)
+	if err != nil {
+		return nil, rpcErrorFor(err)
+	}
+	return result, nil
+}
+
+// handlePrompts lists the prompts over REST, with their templates.
+func (s *Server) handlePrompts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"prompts": append([]Prompt{}, s.prompts...)})
+}
+
+// handlePrompt renders the prompt named in the path with the arguments in
+// the request body.
+func (s *Server) handlePrompt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+	prompt, ok := s.prompt(r.PathValue("name"))
+	if !ok {
+		WriteError(w, NewError(CodeNotFound, "prompt %s not found", r.PathValue("name")))
+		return
+	}
+	body, err := readBody(w, r, s.maxBody)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	var args map[string]string
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &args); err != nil {
+			WriteError(w, BadRequest("arguments must be a JSON object of strings"))
+			return
+		}
+	}
+	result, err := prompt.get(args)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,45 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrompts(t *testing.T) {
+	s := newEchoServer()
+	s.AddPrompt(Prompt{
+		Name:     "troubleshoot-pod",
+		Template: "Why is {{pod}} in {{namespace}} failing? Keep {{braces}}.",
+		Variables: []PromptVariable{
+			{Name: "pod", Required: true},
+			{Name: "namespace", Default: "default"},
+		},
+	})
+
+	list, _ := s.dispatch(context.Background(), "prompts/list", nil)
+	if got, _ := json.Marshal(list); string(got) != `{"prompts":[{"arguments":[{"name":"pod","required":true},{"name":"namespace"}],"description":"","name":"troubleshoot-pod"}]}` {
+		t.Errorf("prompts/list = %s", got)
+	}
+	get, rpcErr := s.dispatch(context.Background(), "prompts/get", json.RawMessage(`{"name":"troubleshoot-pod","arguments":{"pod":"web-0"}}`))
+	if got, _ := json.Marshal(get); rpcErr != nil || !strings.Contains(string(got), `"text":"Why is web-0 in default failing? Keep {{braces}}."`) {
+		t.Errorf("prompts/get = %s, %v", got, rpcErr)
+	}
+	if _, rpcErr := s.dispatch(context.Background(), "prompts/get", json.RawMessage(`{"name":"troubleshoot-pod"}`)); rpcErr == nil || rpcErr.Code != codeInvalidParams {
+		t.Errorf("prompts/get without a required argument: %v, want invalid params", rpcErr)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/prompts/troubleshoot-pod", strings.NewReader(`{"pod":"api-1","namespace":"prod"}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Why is api-1 in prod failing?") {
+		t.Errorf("POST /v1/prompts/troubleshoot-pod = %d %s", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/prompts", nil))
+	if !strings.Contains(rec.Body.String(), `"template":"Why is {{pod}}`) {
+		t.Errorf("GET /v1/prompts = %s, want the templates for the operator", rec.Body)
+	}
+}
@@ -0,0 +1,168 @@
+package toolserver
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"golang.org/x/time/rate"
+)
+
+// clientSweepInterval is how often idle per-client buckets are dropped.
+const clientSweepInterval = time.Minute
+
+// rateLimiter throttles requests with token buckets, one shared by all
+// clients and one per client, so that a runaway agent loop cannot flood the
+// kube-apiserver, registries or resolvers behind a tool.
+type rateLimiter struct {
+	global *rate.Limiter // nil when there is no global limit
+
+	perClient   rate.Limit // 0 when there is no per-client limit
+	clientBurst int
+
+	mu      sync.Mutex
+	clients map[string]*clientBucket
+	swept   time.Time
+}
+
+type clientBucket struct {
+	*rate.Limiter
+	seen time.Time
+}
+
+// rateLimitFromConfig returns the rate limiter configured by the settings,
+// or nil if requests are not limited:
+//
+//	RATE_LIMIT                   requests per second across all clients
+//	RATE_LIMIT_BURST             requests allowed at once (default: the rate,
+//	                             rounded up)
+//	RATE_LIMIT_PER_CLIENT        requests per second from each client
+//	RATE_LIMIT_PER_CLIENT_BURST  the same for each client
+//
+// A client is the authenticated user, or else the remote IP address.
+func rateLimitFromConfig() *rateLimiter {
+	l := &rateLimiter{clients: make(map[string]*clientBucket)}
+	global, burst := rateFromConfig("RATE_LIMIT")
+	if global > 0 {
+		l.global = rate.NewLimiter(global, burst)
+	}
+	l.perClient, l.clientBurst = rateFromConfig("RATE_LIMIT_PER_CLIENT")
+	if l.global == nil && l.perClient == 0 {
+		return nil
+	}
+	slog.Info("limiting request rate", "global", float64(global), "per_client", float64(l.perClient))
+	return l
+}
+
+// rateFromConfig returns the rate in $name and the burst in ${name}_BURST, or 0
+// if the rate is unset or invalid.
+func rateFromConfig(name string) (rate.Limit, int) {
+	v := config.String(name, "")
+	if v == "" {
+		return 0, 0
+	}
+	r, err := strconv.ParseFloat(v, 64)
+	if err != nil || r <= 0 {
+		slog.Warn("invalid "+name+"; not limiting", "value", v)
+		return 0, 0
+	}
+	burst := int(math.Ceil(r))
+	if v := config.String(name+"_BURST", ""); v != "" {
+		if b, err := strconv.Atoi(v); err == nil && b > 0 {
+			burst = b
+		} else {
+			slog.Warn("invalid "+name+"_BURST", "value", v, "using", burst)
+		}
+	}
+	return rate.Limit(r), burst
+}
+
+// reserve takes a token for a request from client from each bucket, and
+// returns 0 if the request may go ahead or how long the client should wait.
+// A rejected request takes no tokens.
+func (l *rateLimiter) reserve(client string) time.Duration {
+	now := time.Now()
+	var reservations []*rate.Reservation
+	if l.global != nil {
+		reservations = append(reservations, l.global.ReserveN(now, 1))
+	}
+	if b := l.bucket(client, now); b != nil {
+		reservations = append(reservations, b.ReserveN(now, 1))
+	}
+
+	var wait time.Duration
+	for _, r := range reservations {
+		wait = max(wait, r.DelayFrom(now))
+	}
+	if wait > 0 {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+	return wait
+}
+
+// bucket returns client's bucket, or nil if there is no per-client limit.
+// Buckets idle long enough to have refilled are dropped, since a new one is
+// the same.
+func (l *rateLimiter) bucket(client string, now time.Time) *clientBucket {
+	if l.perClient == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.swept) >= clientSweepInterval {
+		refill := time.Duration(float64(l.clientBurst) / float64(l.perClient) * float64(time.Second))
+		for c, b := range l.clients {
+			if now.Sub(b.seen) > refill {
+				delete(l.clients, c)
+			}
+		}
+		l.swept = now
+	}
+	b, ok := l.clients[client]
+	if !ok {
+		b = &clientBucket{Limiter: rate.NewLimiter(l.perClient, l.clientBurst)}
+		l.clients[client] = b
+	}
+	b.seen = now
+	return b
+}
+
+// limitRate wraps the handler registered for pattern to reject requests
+// over the configured rates with 429 Too Many Requests and a Retry-After
+// header. It runs after requireAuth, so authenticated clients are limited
+// by user rather than by address. Probes and scrapes are never limited.
+func (s *Server) limitRate(pattern string, h http.Handler) http.Handler {
+	if probePatterns[pattern] {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limits := s.limits.Load()
+		if limits == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		client := "user:"
+		if id := IdentityFrom(r.Context()); id != nil {
+			client += id.Username
+		} else {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			client = "ip:" + host
+		}
+		if wait := limits.reserve(client); wait > 0 {
+			slog.InfoContext(r.Context(), "rate limited", "client", client, "retry_after", wait.String())
+			WriteError(w, &Error{Status: http.StatusTooManyRequests, Message: "rate limit exceeded", RetryAfter: wait})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,68 @@
+package toolserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	// Slow enough that no token is refilled during the test.
+	t.Setenv("RATE_LIMIT", "0.01")
+	t.Setenv("RATE_LIMIT_BURST", "3")
+	t.Setenv("RATE_LIMIT_PER_CLIENT", "0.01")
+	t.Setenv("RATE_LIMIT_PER_CLIENT_BURST", "2")
+	s := newEchoServer()
+
+	call := func(addr, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"message":"hi"}`))
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+	steps := []struct {
+		addr   string
+		path   string
+		status int
+	}{
+		{"192.0.2.1:1000", "/echo", http.StatusOK},
+		{"192.0.2.1:1001", "/echo", http.StatusOK},
+		// The client's bucket is empty; the global one still has a token.
+		{"192.0.2.1:1002", "/echo", http.StatusTooManyRequests},
+		{"192.0.2.2:1000", "/echo", http.StatusOK},
+		// Now the global bucket is empty too.
+		{"192.0.2.3:1000", "/echo", http.StatusTooManyRequests},
+		{"192.0.2.3:1000", "/health", http.StatusOK},
+	}
+	for i, step := range steps {
+		rec := call(step.addr, step.path)
+		if rec.Code != step.status {
+			t.Fatalf("step %d: status = %d, want %d: %s", i, rec.Code, step.status, rec.Body)
+		}
+		if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "100" {
+			t.Errorf("step %d: Retry-After = %q, want 100", i, rec.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestRateLimitRejectedTakesNoTokens(t *testing.T) {
+	t.Setenv("RATE_LIMIT", "0.01")
+	t.Setenv("RATE_LIMIT_BURST", "1")
+	t.Setenv("RATE_LIMIT_PER_CLIENT", "0.01")
+	t.Setenv("RATE_LIMIT_PER_CLIENT_BURST", "1")
+	l := rateLimitFromConfig()
+
+	if wait := l.reserve("ip:a"); wait != 0 {
+		t.Fatalf("first request waits %v", wait)
+	}
+	// Rejected by the global bucket, so b's own token must be kept.
+	if wait := l.reserve("ip:b"); wait == 0 {
+		t.Fatal("request over the global limit was allowed")
+	}
+	l.global = nil
+	if wait := l.reserve("ip:b"); wait != 0 {
+		t.Errorf("b waits %v after a rejected request", wait)
+	}
+}
@@ -0,0 +1,163 @@
+package toolserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// rbacRecheckInterval is how often the RBAC self-check runs again once it
+// has passed, so a Role granted or revoked later takes effect.
+const rbacRecheckInterval = 5 * time.Minute
+
+// Permission is a Kubernetes RBAC permission an operation needs: a verb on
+// a resource of an API group, or on its subresource, in every namespace.
+type Permission struct {
+	Verb        string `json:"verb"`
+	Group       string `json:"group,omitempty"` // "" for the core group
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+}
+
+// String returns the permission as kubectl auth can-i takes it, e.g.
+// "get pods/log" or "list networkpolicies.networking.k8s.io".
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	return p.Verb + " " + resource
+}
+
+// parsePermission parses a permission written as String writes it.
+func parsePermission(s string) (Permission, error) {
+	verb, resource, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok || verb == "" || resource == "" || strings.Contains(resource, " ") {
+		return Permission{}, fmt.Errorf("permission %q is not \"verb resource[.group][/subresource]\"", s)
+	}
+	p := Permission{Verb: verb}
+	resource, p.Subresource, _ = strings.Cut(resource, "/")
+	p.Resource, p.Group, _ = strings.Cut(resource, ".")
+	return p, nil
+}
+
+// RBAC declares the Kubernetes permissions the operation needs, written as
+// for kubectl auth can-i: "list pods", "get pods/log",
+// "list poddisruptionbudgets.policy". /tools lists them, so the Role a tool
+// needs can be read off it, and with CheckRBAC the tool checks them at
+// startup. RBAC panics if a permission is malformed.
+func RBAC(permissions ...string) Option {
+	perms := make([]Permission, 0, len(permissions))
+	for _, s := range permissions {
+		p, err := parsePermission(s)
+		if err != nil {
+			panic(err)
+		}
+		perms = append(perms, p)
+	}
+	return func(op *operation) { op.permissions = append(op.permissions, perms...) }
+}
+
+// CheckRBAC makes the tool check at startup that it has the permissions its
+// operations declare with RBAC, asking allowed, such as kube.Client.Allowed,
+// which makes a SelfSubjectAccessReview. An operation missing any is logged
+// and marked unavailable in /tools, and its calls fail at once with 503
+// UNAVAILABLE naming what is missing, rather than with the API server's 403
+// partway through. The check runs in the background once the server
+// listens, retrying with backoff while the API server cannot answer, and
+// again every 5 minutes, so granting the permissions later makes the
+// operations available; /readyz reports it as the "rbac" warm-up. Call
+// CheckRBAC before Run.
+func (s *Server) CheckRBAC(allowed func(context.Context, Permission) (bool, error)) {
+	s.allowed = allowed
+	s.rbac = &warmup{name: "rbac", fn: s.checkRBAC, state: "deferred", always: true}
+	s.warmups = append(s.warmups, s.rbac)
+}
+
+// checkRBAC checks every permission the operations declare, once each, and
+// marks the operations missing any of them.
+func (s *Server) checkRBAC(ctx context.Context) error {
+	granted := make(map[Permission]bool)
+	for _, op := range s.ops {
+		for _, p := range op.permissions {
+			if _, ok := granted[p]; ok {
+				continue
+			}
+			ok, err := s.allowed(ctx, p)
+			if err != nil {
+				return fmt.Errorf("checking %s: %w", p, err)
+			}
+			granted[p] = ok
+		}
+	}
+	for _, op := range s.ops {
+		var missing []Permission
+		for _, p := range op.permissions {
+			if !granted[p] {
+				missing = append(missing, p)
+			}
+		}
+		if prev := op.missing.Swap(&missing); prev == nil && len(missing) == 0 || prev != nil && slices.Equal(*prev, missing) {
+			continue
+		}
+		if len(missing) > 0 {
+			slog.Warn("operation unavailable: missing RBAC", "tool", s.name, "operation", op.name, "missing", permissionList(missing))
+		} else {
+			slog.Info("operation available: RBAC granted", "tool", s.name, "operation", op.name)
+		}
+	}
+	return nil
+}
+
+// recheckRBAC runs the RBAC self-check every rbacRecheckInterval once it
+// has passed, until ctx is done.
+func (s *Server) recheckRBAC(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(rbacRecheckInterval):
+		}
+		if s.rbac.status().State != "done" {
+			continue // the startup check is still retrying
+		}
+		if err := s.checkRBAC(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("RBAC self-check failed; keeping the previous result", "tool", s.name, "err", err)
+		}
+	}
+}
+
+// unavailable returns why calls of op are refused for missing permissions,
+// or "".
+func (op *operation) unavailable() string {
+	if missing := op.missing.Load(); missing != nil && len(*missing) > 0 {
+		return "missing RBAC: " + permissionList(*missing)
+	}
+	return ""
+}
+
+// rbacMissing returns the error a call of op fails with if the RBAC
+// self-check found it missing permissions, or nil.
+func (op *operation) rbacMissing() *Error {
+	missing := op.missing.Load()
+	if missing == nil || len(*missing) == 0 {
+		return nil
+	}
+	e := NewError(CodeUnavailable, "%s is unavailable: missing RBAC: %s", op.name, permissionList(*missing))
+	e.Details = map[string]any{"missing": *missing}
+	return e
+}
+
+func permissionList(perms []Permission) string {
+	s := make([]string, len(perms))
+	for i, p := range perms {
+		s[i] = p.String()
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,111 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParsePermission(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Permission
+	}{
+		{"list pods", Permission{Verb: "list", Resource: "pods"}},
+		{"get pods/log", Permission{Verb: "get", Resource: "pods", Subresource: "log"}},
+		{"list networkpolicies.networking.k8s.io", Permission{Verb: "list", Group: "networking.k8s.io", Resource: "networkpolicies"}},
+		{"patch deployments.apps/scale", Permission{Verb: "patch", Group: "apps", Resource: "deployments", Subresource: "scale"}},
+	}
+	for _, tt := range tests {
+		got, err := parsePermission(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parsePermission(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+		if got.String() != tt.in {
+			t.Errorf("%+v.String() = %q, want %q", got, got.String(), tt.in)
+		}
+	}
+	for _, in := range []string{"", "pods", "list ", "list pods now"} {
+		if _, err := parsePermission(in); err == nil {
+			t.Errorf("parsePermission(%q) succeeded", in)
+		}
+	}
+}
+
+func TestCheckRBAC(t *testing.T) {
+	s := New("kube")
+	Register(s, "/pods", func(ctx context.Context, _ struct{}) (echoResponse, error) {
+		return echoResponse{Echo: "pods"}, nil
+	}, RBAC("list pods"))
+	Register(s, "/logs", func(ctx context.Context, _ struct{}) (echoResponse, error) {
+		return echoResponse{Echo: "logs"}, nil
+	}, RBAC("get pods", "get pods/log"))
+
+	granted := map[string]bool{"list pods": true, "get pods": true}
+	var unreachable error
+	var checks int
+	s.CheckRBAC(func(ctx context.Context, p Permission) (bool, error) {
+		checks++
+		return granted[p.String()], unreachable
+	})
+
+	tools := func() map[string]ToolInfo {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tools", nil))
+		var resp ToolsResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		byName := make(map[string]ToolInfo)
+		for _, tool := range resp.Tools {
+			byName[tool.Name] = tool
+		}
+		return byName
+	}
+	call := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec
+	}
+
+	// Before the check, the permissions are listed and calls are made.
+	if logs := tools()["logs"]; len(logs.Permissions) != 2 || logs.Permissions[1].Subresource != "log" || logs.Unavailable != "" {
+		t.Errorf("logs before the check = %+v", logs)
+	}
+
+	unreachable = errors.New("connection refused")
+	if err := s.checkRBAC(t.Context()); err == nil {
+		t.Error("checkRBAC passed while the API server is unreachable")
+	}
+	unreachable, checks = nil, 0
+	if err := s.checkRBAC(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if checks != 3 {
+		t.Errorf("checked %d permissions, want each of the 3 once", checks)
+	}
+	byName := tools()
+	if byName["pods"].Unavailable != "" || byName["logs"].Unavailable != "missing RBAC: get pods/log" {
+		t.Errorf("tools after the check = %+v", byName)
+	}
+	if rec := call("/pods"); rec.Code != http.StatusOK {
+		t.Errorf("/pods = %d %s", rec.Code, rec.Body)
+	}
+	rec := call("/logs")
+	var e ErrorResponse
+	json.Unmarshal(rec.Body.Bytes(), &e)
+	if rec.Code != http.StatusServiceUnavailable || e.Code != CodeUnavailable || !strings.Contains(e.Message, "missing RBAC: get pods/log") {
+		t.Errorf("/logs = %d %s, want 503 naming the missing permission", rec.Code, rec.Body)
+	}
+
+	// Granting the permission makes the operation available at the next check.
+	granted["get pods/log"] = true
+	if err := s.checkRBAC(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if rec := call("/logs"); rec.Code != http.StatusOK || tools()["logs"].Unavailable != "" {
+		t.Errorf("/logs after granting = %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,43 @@
+package toolserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// errInternal is reported for a recovered panic. The panic value may hold
+// anything, so it is only logged.
+var errInternal = Errorf(http.StatusInternalServerError, "internal error")
+
+// recovered logs a panic recovered in handler, an endpoint pattern or an
+// operation name, with its stack, counts it, and returns the error to
+// report instead.
+func recovered(ctx context.Context, handler string, v any) error {
+	panicsTotal.WithLabelValues(handler).Inc()
+	slog.ErrorContext(ctx, "panic", "handler", handler, "panic", fmt.Sprint(v), "stack", string(debug.Stack()))
+	return errInternal
+}
+
+// recoverPanics wraps the handler registered for pattern so that a panic is
+// answered with a 500 in the error envelope instead of a dropped
+// connection. Operations recover their own panics, whichever transport
+// calls them; this catches the rest. http.ErrAbortHandler is passed on, as
+// it is meant to abort the response.
+func recoverPanics(pattern string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			WriteError(w, recovered(r.Context(), pattern, v))
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,44 @@
+package toolserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverPanics(t *testing.T) {
+	s := New("fragile")
+	Register(s, "/walk", func(ctx context.Context, _ struct{}) (echoResponse, error) {
+		var schema *struct{ Fields map[string]string }
+		return echoResponse{Echo: schema.Fields["spec"]}, nil
+	})
+	s.HandleFunc("/raw", func(w http.ResponseWriter, r *http.Request) {
+		panic("broken")
+	})
+
+	for _, path := range []string{"/walk", "/raw"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != http.StatusInternalServerError || strings.TrimSpace(rec.Body.String()) != `{"code":"INTERNAL","message":"internal error","retryable":false,"error":"internal error"}` {
+			t.Errorf("%s = %d %s, want a 500 in the error envelope", path, rec.Code, rec.Body)
+		}
+	}
+
+	// Other transports call the operation directly and get the same error.
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rpc",
+		strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"walk"}`)))
+	if !strings.Contains(rec.Body.String(), `"message":"internal error"`) {
+		t.Errorf("/rpc = %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{`toolserver_panics_total{handler="/raw"} 1`, `toolserver_panics_total{handler="walk"} 2`} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("/metrics is missing %s", want)
+		}
+	}
+}
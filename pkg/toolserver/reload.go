@@ -0,0 +1,84 @@
+package toolserver
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// defaultReloadInterval is the default of $CONFIG_RELOAD_INTERVAL. The
+// kubelet takes up to a minute to update a mounted ConfigMap anyway.
+const defaultReloadInterval = 10 * time.Second
+
+// watchConfig takes up changes to the config file until ctx is done,
+// checking it every $CONFIG_RELOAD_INTERVAL (default 10s; 0 turns reloading
+// off).
+func (s *Server) watchConfig(ctx context.Context) {
+	interval := config.Duration("CONFIG_RELOAD_INTERVAL", defaultReloadInterval)
+	if interval <= 0 {
+		return
+	}
+	config.Watch(ctx, interval, s.reload)
+}
+
+// reload applies the settings that changed in the config file without a
+// restart: LOG_LEVEL, the RATE_LIMIT settings, the CORS settings,
+// AUDIT_READERS, the operations' NAME_CACHE_TTL, and READ_ONLY and FEATURES,
+// which gate write operations. Other settings are read once at start-up, so
+// changing them is logged as needing a restart. A file that cannot be read or
+// parsed leaves every setting as it was.
+func (s *Server) reload(changed []string, err error) {
+	if err != nil {
+		configReloads.WithLabelValues("error").Inc()
+		slog.Error("reloading config file; keeping the previous settings", "err", err)
+		return
+	}
+	var limits, cors, readers, caches bool
+	var restart []string
+	for _, name := range changed {
+		switch {
+		case name == "LOG_LEVEL":
+			setLogLevel()
+		case strings.HasPrefix(name, "RATE_LIMIT"):
+			limits = true
+		case strings.HasPrefix(name, "CORS_"):
+			cors = true
+		case name == "AUDIT_READERS":
+			readers = true
+		case strings.HasSuffix(name, "_CACHE_TTL"):
+			caches = true
+		case name == "READ_ONLY" || name == "FEATURES":
+			// Read on every call.
+		default:
+			restart = append(restart, name)
+		}
+	}
+	// Rebuilding the limiter refills every bucket, so it is only done when
+	// the limits change.
+	for _, t := range s.servers() {
+		if limits {
+			t.limits.Store(rateLimitFromConfig())
+		}
+		if cors {
+			t.cors.Store(corsFromConfig())
+		}
+		if readers {
+			t.audit.setReaders(config.List("AUDIT_READERS"))
+		}
+		if caches {
+			for _, op := range t.ops {
+				if op.cache != nil {
+					op.cache.configure(op.name)
+				}
+			}
+		}
+	}
+	configReloads.WithLabelValues("success").Inc()
+	slog.Info("config reloaded", "changed", changed)
+	if len(restart) > 0 {
+		slog.Warn("changed settings take effect on restart", "settings", restart)
+	}
+}
@@ -0,0 +1,77 @@
+package toolserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	s := New("reloading")
+	calls := 0
+	Register(s, "/echo", func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		calls++
+		return echoResponse{Echo: req.Message}, nil
+	}, Cache(time.Minute))
+	call := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/echo", strings.NewReader(`{"message":"hi"}`)))
+		return rec
+	}
+	call()
+	call()
+	if calls != 1 {
+		t.Fatalf("handler ran %d times before the reload, want 1", calls)
+	}
+
+	t.Cleanup(func() { logLevel.Set(slog.LevelInfo) })
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("RATE_LIMIT", "1")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "http://inspector.example")
+	t.Setenv("AUDIT_READERS", "auditors")
+	t.Setenv("ECHO_CACHE_TTL", "0s")
+	s.reload([]string{"AUDIT_READERS", "CORS_ALLOWED_ORIGINS", "ECHO_CACHE_TTL", "LOG_LEVEL", "PORT", "RATE_LIMIT"}, nil)
+
+	if got := logLevel.Level(); got != slog.LevelDebug {
+		t.Errorf("log level = %v, want debug", got)
+	}
+	if rec := call(); rec.Code != http.StatusOK || calls != 2 {
+		t.Errorf("call after the cache TTL was set to 0 = %d, handler ran %d times, want 200 and 2", rec.Code, calls)
+	}
+	if rec := call(); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("call over the reloaded rate limit = %d, want 429", rec.Code)
+	}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/v1/echo", nil)
+	req.Header.Set("Origin", "http://inspector.example")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	s.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "http://inspector.example" {
+		t.Errorf("Access-Control-Allow-Origin = %q after the reload, want the new origin", got)
+	}
+	if !s.audit.canReadAll(&Identity{Username: "carol", Groups: []string{"auditors"}}) {
+		t.Error("reloaded AUDIT_READERS were not applied")
+	}
+
+	// A broken file changes nothing.
+	s.reload(nil, errors.New("config.yaml: yaml: line 1: did not find expected node content"))
+	if s.limits.Load() == nil || s.cors.Load() == nil {
+		t.Error("a failed reload dropped the settings")
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`toolserver_config_reloads_total{result="success"} 1`,
+		`toolserver_config_reloads_total{result="error"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("/metrics is missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// codeResourceNotFound is the JSON-RPC error MCP uses for unknown resource
+// URIs.
+const codeResourceNotFound = -32002
+
+// Resource describes a document a tool serves to MCP clients as context,
+// such as a cluster summary or the kinds a schema documents, as opposed to
+// a tool the model calls.
+type Resource struct {
+	URI         string `json:"uri"` // e.g. k8s://cluster/summary
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+}
+
+// A ResourceFunc returns the current contents of a resource.
+type ResourceFunc func(ctx context.Context) (string, error)
+
+type resource struct {
+	Resource
+	read ResourceFunc
+}
+
+// resourceContents is an entry of a resources/read result.
+type resourceContents struct {
+	URI      string `json:"uri"`
+	MIMEType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// AddResource serves r, read with read, as an MCP resource: listed by
+// resources/list and read by URI with resources/read. Over REST, GET
+// /v1/resources lists the resources and GET /v1/resources/NAME returns one's
+// contents with its MIME type, which is what an MCPResource's operations
+// point at. MIMEType defaults to text/plain. Add resources before Run.
+func (s *Server) AddResource(r Resource, read ResourceFunc) {
+	if r.MIMEType == "" {
+		r.MIMEType = "text/plain"
+	}
+	s.resources = append(s.resources, &resource{Resource: r, read: read})
+}
+
+// resourceByURI returns the resource with the URI, or nil.
+func (s *Server) resourceByURI(uri string) *resource {
+	for _, r := range s.resources {
+		if r.URI == uri {
+			return r
+		}
+	}
+	return nil
+}
+
+// listResources answers resources/list.
+func (s *Server) listResources() any {
+	list := make([]Resource, 0, len(s.resources))
+	for _, r := range s.resources {
+		list = append(list, r.Resource)
+	}
+	return map[string]any{"resources": list}
+}
+
+// readResource answers resources/read.
+func (s *Server) readResource(ctx context.Context, params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params"}
+	}
+	r := s.resourceByURI(p.URI)
+	if r == nil {
+		return nil, &rpcError{Code: codeResourceNotFound, Message: fmt.Sprintf("resource not found: %s", p.URI), Data: map[string]string{"uri": p.URI}}
+	}
+	text, err := r.read(ctx)
+	if err != nil {
+		return nil, rpcErrorFor(err)
+	}
+	return map[string]any{"contents": []resourceContents{{URI: r.URI, MIMEType: r.MIMEType, Text: text}}}, nil
+}
+
+// handleResources lists the resources over REST.
+func (s *Server) handleResources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+	WriteJSON(w, http.StatusOK, s.listResources())
+}
+
+// handleResource serves the contents of the resource named in the path.
+func (s *Server) handleResource(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+	name := r.PathValue("name")
+	for _, res := range s.resources {
+		if res.Name != name {
+			continue
+		}
+		text, err := res.read(r.Context())
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", res.MIMEType)
+		w.Write([]byte(text))
+		return
+	}
+	WriteError(w, NewError(CodeNotFound, "resource %s not found", name))
+}
@@ -0,0 +1,48 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResources(t *testing.T) {
+	s := newEchoServer()
+	s.AddResource(Resource{URI: "k8s://cluster/summary", Name: "cluster-summary", MIMEType: "application/json"},
+		func(ctx context.Context) (string, error) { return `{"nodes":3}`, nil })
+	s.AddResource(Resource{URI: "k8s://cluster/broken", Name: "broken"},
+		func(ctx context.Context) (string, error) { return "", NewError(CodeUnavailable, "no cluster") })
+
+	init, _ := s.dispatch(context.Background(), "initialize", nil)
+	if caps := init.(map[string]any)["capabilities"].(map[string]any); caps["resources"] == nil || caps["prompts"] != nil {
+		t.Errorf("capabilities = %v, want resources but not prompts", caps)
+	}
+	list, _ := s.dispatch(context.Background(), "resources/list", nil)
+	if got, _ := json.Marshal(list); !strings.Contains(string(got), `"uri":"k8s://cluster/summary"`) || !strings.Contains(string(got), `"mimeType":"text/plain"`) {
+		t.Errorf("resources/list = %s", got)
+	}
+	read, rpcErr := s.dispatch(context.Background(), "resources/read", json.RawMessage(`{"uri":"k8s://cluster/summary"}`))
+	if got, _ := json.Marshal(read); rpcErr != nil || string(got) != `{"contents":[{"uri":"k8s://cluster/summary","mimeType":"application/json","text":"{\"nodes\":3}"}]}` {
+		t.Errorf("resources/read = %s, %v", got, rpcErr)
+	}
+	if _, rpcErr := s.dispatch(context.Background(), "resources/read", json.RawMessage(`{"uri":"k8s://nope"}`)); rpcErr == nil || rpcErr.Code != codeResourceNotFound {
+		t.Errorf("reading an unknown resource: %v, want code %d", rpcErr, codeResourceNotFound)
+	}
+	if _, rpcErr := s.dispatch(context.Background(), "resources/read", json.RawMessage(`{"uri":"k8s://cluster/broken"}`)); rpcErr == nil || rpcErr.Data.(rpcErrorData).ErrorCode != CodeUnavailable {
+		t.Errorf("reading a failing resource: %v, want UNAVAILABLE", rpcErr)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/resources/cluster-summary", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"nodes":3}` || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("GET /v1/resources/cluster-summary = %d %s %v", rec.Code, rec.Body, rec.Header())
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/resources/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /v1/resources/nope = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,133 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	codeInternalError = -32603
+	// codeServerError is the JSON-RPC code for handler errors other than
+	// bad input; the HTTP status they map to is in the error data.
+	codeServerError = -32000
+)
+
+// handleRPC serves /rpc, where each operation is a JSON-RPC 2.0 method named
+// like its MCP tool and takes the request body as params. Batches are run
+// concurrently and answered in order; notifications run but get no
+// response.
+func (s *Server) handleRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+	ctx, ok := s.restSession(w, r)
+	if !ok {
+		return
+	}
+	raw, batch, err := readMessages(w, r, s.maxBody)
+	if err != nil {
+		writeReadError(w, err, http.StatusOK)
+		return
+	}
+	if batch && len(raw) == 0 {
+		WriteJSON(w, http.StatusOK, &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+			Error: &rpcError{Code: codeInvalidRequest, Message: "invalid request"}})
+		return
+	}
+
+	responses := make([]*rpcResponse, len(raw))
+	done := make(chan struct{})
+	pending := 0
+	for i, m := range raw {
+		msg, errResp := decodeMessage(m)
+		if errResp != nil {
+			responses[i] = errResp
+			continue
+		}
+		if msg.Method == "" {
+			continue
+		}
+		pending++
+		go func() {
+			defer func() { done <- struct{}{} }()
+			resp := s.callRPC(ctx, msg)
+			if msg.ID != nil {
+				responses[i] = resp
+			}
+		}()
+	}
+	for range pending {
+		<-done
+	}
+
+	var out []*rpcResponse
+	for _, resp := range responses {
+		if resp != nil {
+			out = append(out, resp)
+		}
+	}
+	switch {
+	case len(out) == 0:
+		w.WriteHeader(http.StatusNoContent)
+	case batch:
+		WriteJSON(w, http.StatusOK, out)
+	default:
+		WriteJSON(w, http.StatusOK, out[0])
+	}
+}
+
+// callRPC runs the operation named by msg.Method.
+func (s *Server) callRPC(ctx context.Context, msg rpcMessage) *rpcResponse {
+	resp := &rpcResponse{JSONRPC: "2.0", ID: msg.ID}
+	op := s.lookup(msg.Method)
+	if op == nil {
+		resp.Error = &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", msg.Method)}
+		return resp
+	}
+
+	result, err := op.call(ctx, msg.Params)
+	if err != nil {
+		resp.Error = rpcErrorFor(err)
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+// rpcErrorFor maps a handler error to a JSON-RPC error: bad input is
+// invalid params, a plain error is an internal error, and any other *Error
+// is a server error. The data holds the error's code, whether it is
+// retryable and its details, as in the REST error envelope, with the HTTP
+// status the REST endpoint would have used and any failing fields.
+func rpcErrorFor(err error) *rpcError {
+	resp, status := errorResponse(err)
+	data := rpcErrorData{
+		Status:    status,
+		ErrorCode: resp.Code,
+		Retryable: resp.Retryable,
+		Details:   resp.Details,
+		Fields:    resp.Fields,
+	}
+
+	code := codeServerError
+	var e *Error
+	switch {
+	case status == http.StatusBadRequest:
+		code = codeInvalidParams
+	case !errors.As(err, &e):
+		code = codeInternalError
+	}
+	return &rpcError{Code: code, Message: err.Error(), Data: data}
+}
+
+type rpcErrorData struct {
+	Status    int            `json:"status"`
+	ErrorCode Code           `json:"code"`
+	Retryable bool           `json:"retryable"`
+	Details   map[string]any `json:"details,omitempty"`
+	Fields    []FieldError   `json:"fields,omitempty"`
+}
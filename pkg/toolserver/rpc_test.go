@@ -0,0 +1,59 @@
+package toolserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRPC(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   string
+	}{
+		{"call", `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"message":"hi"}}`, http.StatusOK,
+			`{"jsonrpc":"2.0","id":1,"result":{"echo":"hi"}}`},
+		{"validation error", `{"jsonrpc":"2.0","id":1,"method":"echo","params":{}}`, http.StatusOK,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"message is required","data":{"status":400,"code":"INVALID_ARGUMENT","retryable":false}}}`},
+		{"positional params", `{"jsonrpc":"2.0","id":1,"method":"echo","params":["hi"]}`, http.StatusOK,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid request body: expected object, got array","data":{"status":400,"code":"VALIDATION_FAILED","retryable":false,"fields":[{"field":"","message":"expected object, got array"}]}}}`},
+		{"internal error", `{"jsonrpc":"2.0","id":"a","method":"echo","params":{"message":"boom"}}`, http.StatusOK,
+			`{"jsonrpc":"2.0","id":"a","error":{"code":-32603,"message":"boom","data":{"status":500,"code":"INTERNAL","retryable":false}}}`},
+		{"unknown method", `{"jsonrpc":"2.0","id":1,"method":"nope"}`, http.StatusOK,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found: nope"}}`},
+		{"parse error", `{"jsonrpc"`, http.StatusOK,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`},
+		{"wrong version", `{"jsonrpc":"1.0","id":1,"method":"echo"}`, http.StatusOK,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"invalid request"}}`},
+		{"empty batch", `[]`, http.StatusOK,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request"}}`},
+		{"notification", `{"jsonrpc":"2.0","method":"echo","params":{"message":"hi"}}`, http.StatusNoContent, ``},
+		{"batch", `[
+			{"jsonrpc":"2.0","id":1,"method":"echo","params":{"message":"one"}},
+			{"jsonrpc":"2.0","method":"echo","params":{"message":"ignored"}},
+			1,
+			{"jsonrpc":"2.0","id":2,"method":"echo","params":{"message":"two"}}
+		]`, http.StatusOK,
+			`[{"jsonrpc":"2.0","id":1,"result":{"echo":"one"}},` +
+				`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request"}},` +
+				`{"jsonrpc":"2.0","id":2,"result":{"echo":"two"}}]`},
+	}
+
+	s := newEchoServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Errorf("body = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package toolserver
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	timeType       = reflect.TypeFor[time.Time]()
+)
+
+// inputSchema derives the JSON Schema MCP clients are given for a request
+// type from its exported fields and their json tags. Fields are optional:
+// handlers apply their own defaults and report missing values. Unknown
+// fields are not allowed, so misspellings are caught by validate.
+func inputSchema(t reflect.Type) map[string]any {
+	schema := (&schemaBuilder{seen: map[reflect.Type]bool{}, closed: true}).schema(t)
+	if schema["type"] != "object" {
+		// MCP requires an object; struct{} and non-struct requests take no
+		// arguments the schema can describe.
+		return map[string]any{"type": "object"}
+	}
+	return schema
+}
+
+// outputSchema derives the JSON Schema of a response type. Nil pointers,
+// slices and maps encode as null, so those also allow null.
+func outputSchema(t reflect.Type) map[string]any {
+	return (&schemaBuilder{seen: map[reflect.Type]bool{}, nullable: true}).schema(t)
+}
+
+type schemaBuilder struct {
+	seen     map[reflect.Type]bool // structs being built, to stop recursion
+	nullable bool                  // allow null for pointers, slices and maps
+	closed   bool                  // disallow properties a struct does not have
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	schema := b.nonNull(t)
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		if b.nullable && schema["type"] != nil {
+			schema["type"] = []any{schema["type"], "null"}
+		}
+	}
+	return schema
+}
+
+func (b *schemaBuilder) nonNull(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == rawMessageType:
+		return map[string]any{}
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if b.seen[t] {
+			return map[string]any{"type": "object"}
+		}
+		b.seen[t] = true
+		defer delete(b.seen, t)
+
+		properties := make(map[string]any)
+		b.addFields(t, properties)
+		schema := map[string]any{"type": "object", "properties": properties}
+		if b.closed {
+			schema["additionalProperties"] = false
+		}
+		return schema
+	default:
+		return map[string]any{}
+	}
+}
+
+// addFields adds t's fields to properties, flattening embedded structs the
+// way encoding/json does.
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]any) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.addFields(ft, properties)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = b.schema(f.Type)
+	}
+}
@@ -0,0 +1,509 @@
+// Package toolserver is the server shared by the example tools. It provides
+// the health probes, typed JSON handlers, a consistent error envelope and
+// PORT handling, and serves the same operations as MCP tools over stdio and
+// HTTP, so each tool only implements its operations. Settings such as $PORT
+// are read with package config, from flags, the environment or a config
+// file.
+package toolserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Server routes requests to a tool's operations.
+type Server struct {
+	name string
+	mux  *http.ServeMux
+	ops  []*operation
+
+	resources []*resource // MCP resources (see AddResource)
+	prompts   []Prompt    // MCP prompts (see AddPrompt)
+
+	sessions   sessionStore
+	websockets wsConns                     // open MCP connections over WebSocket
+	auth       *authenticator              // nil when requests need no token
+	certAuth   *clientCertAuth             // nil when requests need no client certificate
+	limits     atomic.Pointer[rateLimiter] // nil when requests are not rate limited
+	cors       atomic.Pointer[corsPolicy]  // nil when cross-origin requests are refused
+	timeout    time.Duration               // default deadline of operation calls
+	maxBody    int64                       // largest request body or MCP message read
+	strict     bool                        // whether unknown request fields are rejected
+	legacy     bool                        // whether endpoints are served unversioned too
+	compress   int                         // smallest response compressed; 0 for none
+	audit      *auditLog
+	checks     []*readinessCheck
+	warmups    []*warmup // run by Serve with $WARMUP (see AddWarmup)
+	rbac       *warmup   // the RBAC self-check; nil without CheckRBAC
+	allowed    func(context.Context, Permission) (bool, error)
+	startup    atomic.Int64 // nanoseconds from process start to serving
+	mounted    []*Server    // the tools served under path prefixes (see Mount)
+	prefix     string       // the path prefix the server is mounted under, if any
+
+	// halted is the base context of requests, and of calls that outlive
+	// their request; halt cancels it when a shutdown runs out of time.
+	halted context.Context
+	halt   context.CancelFunc
+}
+
+// New returns a server for the named tool with the probes /livez (also
+// served as /health) and /readyz, the dependency diagnostics
+// /healthz/verbose, Prometheus /metrics, the operation
+// listing /tools, the MCP resources and prompts on /resources and /prompts,
+// the JSON-RPC endpoint /rpc and the MCP endpoints
+// registered: Streamable HTTP on /mcp, WebSocket on /ws for interactive
+// clients, and HTTP+SSE on /sse and /messages for older clients. Every operation call is recorded in the audit log,
+// whose latest entries are served on /audit/recent. /openapi.json describes
+// the REST endpoints of the operations.
+func New(name string) *Server {
+	setupTracing(name)
+	s := &Server{name: name, mux: http.NewServeMux()}
+	s.halted, s.halt = context.WithCancel(context.Background())
+	s.auth = authFromConfig()
+	s.limits.Store(rateLimitFromConfig())
+	s.cors.Store(corsFromConfig())
+	s.timeout = timeoutSetting("REQUEST_TIMEOUT", defaultRequestTimeout)
+	s.maxBody, s.strict = maxBodySize(), strictJSON()
+	s.legacy = legacyPaths()
+	s.compress = compressMinSize()
+	if readOnly() {
+		slog.Info("running in read-only mode: write operations are disabled")
+	}
+	var err error
+	if s.audit, err = auditFromConfig(); err != nil {
+		slog.Error("opening audit log", "err", err)
+		os.Exit(1)
+	}
+	s.HandleFunc("/livez", handleLive)
+	s.HandleFunc("/health", handleLive)
+	s.HandleFunc("/readyz", s.handleReady)
+	s.HandleFunc(verboseHealthPath, s.handleVerboseHealth)
+	s.Handle("/metrics", promhttp.Handler())
+	s.HandleFunc("/tools", s.handleTools)
+	s.HandleFunc("/resources", s.handleResources)
+	s.HandleFunc("/resources/{name}", s.handleResource)
+	s.HandleFunc("/prompts", s.handlePrompts)
+	s.HandleFunc("/prompts/{name}", s.handlePrompt)
+	s.HandleFunc("/rpc", s.handleRPC)
+	s.HandleFunc("/mcp", s.handleMCP)
+	s.HandleFunc("/sse", s.handleSSE)
+	s.HandleFunc("/messages", s.handleMessages)
+	s.HandleFunc("/ws", s.handleWebSocket)
+	s.HandleFunc("/audit/recent", s.handleAuditRecent)
+	s.HandleFunc(openAPIPath, s.handleOpenAPI)
+	return s
+}
+
+// probePatterns are the endpoints polled by probes and scrapers, and the
+// diagnostics operators read alongside them. They are not traced, are
+// logged at debug level only, and need no authentication.
+var probePatterns = map[string]bool{"/livez": true, "/readyz": true, "/health": true, "/metrics": true, verboseHealthPath: true}
+
+// Name returns the tool name the server was created with.
+func (s *Server) Name() string { return s.name }
+
+// Handle registers a plain handler, for endpoints that stream or read raw
+// request bodies rather than JSON. Like every endpoint, it is instrumented
+// with request metrics, traced, logged, recovers from panics, compresses
+// large responses and, when configured, is opened to other origins,
+// authenticated and rate limited.
+//
+// Except for the probes and /metrics, the handler is served under /v1, so
+// Handle("/hash", h) serves /v1/hash. Unless $LEGACY_PATHS is false, it is
+// also served at the unversioned path with a Deprecation header, for
+// clients configured before versioning. Metrics and logs label both with
+// pattern.
+func (s *Server) Handle(pattern string, h http.Handler) {
+	h = s.requireAuth(pattern, s.limitRate(pattern, h))
+	h = instrument(pattern, traceHandler(pattern, logRequests(pattern, recoverPanics(pattern, s.handleCORS(negotiateVersion(cacheControl(compress(s.compress, h))))))))
+	path := versionedPath(pattern)
+	s.mux.Handle(path, h)
+	if path != pattern && s.legacy {
+		s.mux.Handle(pattern, deprecated(pattern, h))
+	}
+}
+
+// HandleFunc registers a plain handler function.
+func (s *Server) HandleFunc(pattern string, fn func(http.ResponseWriter, *http.Request)) {
+	s.Handle(pattern, http.HandlerFunc(fn))
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Run serves the tool over the transport selected by the --transport flag
+// or $TRANSPORT: "http" (the default), which serves the REST endpoints and
+// MCP over HTTP, or "stdio", which speaks MCP on stdin/stdout for local
+// clients such as Claude Desktop. Either way it takes up changes to the
+// config file as it runs (see Server.reload), and returns once SIGTERM or
+// SIGINT has shut the server down.
+func (s *Server) Run() error {
+	switch transport := config.String("TRANSPORT", "http"); transport {
+	case "http":
+		return s.ListenAndServe()
+	case "stdio":
+		if len(s.mounted) > 0 {
+			return errors.New("stdio serves a single tool; name one")
+		}
+		slog.Info("serving over MCP stdio", "tool", s.name)
+		s.audit.useStderr()
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+		go s.watchConfig(ctx)
+		return s.ServeStdio(ctx, os.Stdin, os.Stdout)
+	default:
+		return fmt.Errorf("unknown transport %q: use http or stdio", transport)
+	}
+}
+
+// ListenAndServe serves on $PORT, default 8080, until SIGTERM or SIGINT,
+// then shuts down gracefully (see Serve). It serves HTTPS, optionally
+// requiring client certificates, when $TLS_CERT_FILE is set (see tlsConfig).
+func (s *Server) ListenAndServe() error {
+	port := config.String("PORT", "8080")
+	cfg, err := s.tlsConfig()
+	if err != nil {
+		return fmt.Errorf("configuring TLS: %w", err)
+	}
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return err
+	}
+	if cfg != nil {
+		ln = tls.NewListener(ln, cfg)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	go s.watchConfig(ctx)
+	slog.Info("starting server", "tool", s.name, "port", port, "tls", cfg != nil,
+		"startup", time.Since(processStart).String(), "warmup", warmupEnabled())
+	return s.Serve(ctx, ln)
+}
+
+// Serve serves HTTP on ln, with $WARMUP running the warm-ups in the
+// background (see AddWarmup), until ctx is done, then shuts down: it stops
+// accepting connections, ends HTTP+SSE event streams so their clients
+// reconnect elsewhere, and waits up to $SHUTDOWN_TIMEOUT (default 25s, inside
+// Kubernetes' default 30s grace period) for in-flight requests and calls to
+// finish. Any still running then have their contexts cancelled, which
+// aborts their Kubernetes, registry and DNS requests.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: readHeaderTimeout,
+		BaseContext:       func(net.Listener) context.Context { return s.halted },
+	}
+	for _, t := range s.servers() {
+		srv.RegisterOnShutdown(t.sessions.closeLegacy)
+		srv.RegisterOnShutdown(t.websockets.closeAll)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	s.startup.Store(int64(time.Since(processStart)))
+	s.warmUp(ctx, warmupEnabled())
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	timeout := shutdownTimeout()
+	slog.Info("shutting down", "tool", s.name, "timeout", timeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	s.halt()
+	if err != nil {
+		slog.Warn("shutdown timed out; cancelled in-flight requests", "err", err)
+		return srv.Close()
+	}
+	return nil
+}
+
+const (
+	// readHeaderTimeout bounds slow clients; there is no overall write
+	// timeout, since event streams stay open.
+	readHeaderTimeout = 10 * time.Second
+
+	defaultShutdownTimeout = 25 * time.Second
+
+	defaultRequestTimeout = 30 * time.Second
+)
+
+// shutdownTimeout returns $SHUTDOWN_TIMEOUT, e.g. "10s", or the default.
+func shutdownTimeout() time.Duration {
+	return timeoutSetting("SHUTDOWN_TIMEOUT", defaultShutdownTimeout)
+}
+
+// timeoutSetting returns the named duration setting, or def if it is unset
+// or invalid.
+func timeoutSetting(name string, def time.Duration) time.Duration {
+	d := config.Duration(name, def)
+	if d < 0 {
+		slog.Warn("invalid "+name, "value", d.String(), "using", def.String())
+		return def
+	}
+	return d
+}
+
+// detach returns a context for calls that outlive their request: it keeps
+// ctx's values (request ID, trace) but not its cancellation, and is
+// cancelled instead when a shutdown runs out of time. Call cancel when the
+// calls are done.
+func (s *Server) detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(s.halted, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// HandlerFunc implements one operation: it receives the decoded request
+// body and returns the response to encode, or an error.
+type HandlerFunc[T, R any] func(ctx context.Context, req T) (R, error)
+
+// operation is a registered HandlerFunc with its MCP tool metadata.
+type operation struct {
+	path        string
+	name        string
+	description string
+	input       map[string]any // JSON Schema of the request
+	output      map[string]any // JSON Schema of the response
+	allowGet    bool
+	timeout     time.Duration                // 0 for no limit
+	maxBody     int64                        // largest request body or arguments accepted
+	sensitive   []string                     // parameters redacted in the audit log
+	cache       *responseCache               // nil unless responses are cached
+	stale       *staleStore                  // nil unless responses are served stale
+	feature     string                       // the write feature, if the operation changes the cluster
+	permissions []Permission                 // the RBAC permissions it needs (see RBAC)
+	missing     atomic.Pointer[[]Permission] // those the RBAC self-check found missing
+	idempotency *idempotencyStore            // results by idempotency key; nil unless feature is set
+	server      *Server                      // the server the operation is registered with
+
+	// call validates and decodes the JSON request body, which may be
+	// empty, and runs the handler.
+	call func(ctx context.Context, body []byte) (any, error)
+}
+
+// An Option configures an operation registered with Register.
+type Option func(*operation)
+
+// Name sets the MCP tool name. The default is the path with slashes turned
+// into dashes, e.g. "jwt-sign" for /jwt/sign.
+func Name(name string) Option {
+	return func(op *operation) { op.name = name }
+}
+
+// Describe sets the description MCP clients show for the tool.
+func Describe(description string) Option {
+	return func(op *operation) { op.description = description }
+}
+
+// Timeout overrides $REQUEST_TIMEOUT for the operation, e.g. for one that
+// streams large downloads. Zero means no limit.
+func Timeout(d time.Duration) Option {
+	return func(op *operation) { op.timeout = d }
+}
+
+// MaxBodySize overrides $MAX_BODY_SIZE for the operation's request body
+// over HTTP and its arguments over MCP, e.g. for one that takes whole
+// manifests. An MCP message still may not exceed $MAX_BODY_SIZE.
+func MaxBodySize(n int64) Option {
+	return func(op *operation) { op.maxBody = n }
+}
+
+// Sensitive names parameters whose values are redacted in the audit log,
+// besides the passwords, keys, tokens and secrets always redacted.
+func Sensitive(params ...string) Option {
+	return func(op *operation) {
+		for _, p := range params {
+			op.sensitive = append(op.sensitive, strings.ToLower(p))
+		}
+	}
+}
+
+// AllowGet also accepts GET over HTTP, with the request's string fields
+// taken from query parameters of the same name, for use from a browser.
+func AllowGet() Option {
+	return func(op *operation) { op.allowGet = true }
+}
+
+// errInvalidBody is returned for request bodies or tool arguments that are
+// not JSON or otherwise do not decode into the operation's request type.
+var errInvalidBody = BadRequest("invalid request body")
+
+// Register adds an operation at path. Over HTTP it accepts POST with a JSON
+// body, validated against the input schema derived from T and decoded into T
+// (an empty body leaves T zero), and encodes the result as JSON; errors are
+// written with WriteError. Over MCP it is a tool with that input schema and
+// an output schema derived from R.
+//
+// Every call runs with a deadline, $REQUEST_TIMEOUT (default 30s) unless
+// set with Timeout, after which its context is cancelled and it fails with
+// 504 Gateway Timeout.
+func Register[T, R any](s *Server, path string, fn HandlerFunc[T, R], opts ...Option) {
+	op := &operation{
+		path:    path,
+		name:    strings.ReplaceAll(strings.Trim(path, "/"), "/", "-"),
+		input:   inputSchema(reflect.TypeFor[T]()),
+		output:  outputSchema(reflect.TypeFor[R]()),
+		timeout: s.timeout,
+		maxBody: s.maxBody,
+		server:  s,
+	}
+	op.call = func(ctx context.Context, body []byte) (resp any, err error) {
+		if RequestID(ctx) == "" {
+			// Calls over stdio have no HTTP request to take an ID from.
+			ctx = withRequestID(ctx, "")
+		}
+		defer func(start time.Time) {
+			observeCall(op.name, start, err)
+			logCall(ctx, op.name, start, err)
+			s.audit.record(ctx, op, body, start, err)
+		}(time.Now())
+		ctx, span := StartSpan(ctx, op.name)
+		defer func() {
+			span.SetError(err)
+			span.End()
+		}()
+		span.SetAttr("tool.operation", op.name)
+		if op.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, op.timeout)
+			defer cancel()
+			defer func() {
+				if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					err = NewError(CodeDeadlineExceeded, "%s timed out after %s", op.name, op.timeout)
+				}
+			}()
+		}
+
+		defer func() {
+			if v := recover(); v != nil {
+				err = recovered(ctx, op.name, v)
+			}
+		}()
+
+		if err := op.writeDisabled(); err != nil {
+			return nil, err
+		}
+		if err := op.rbacMissing(); err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > op.maxBody {
+			return nil, errBodyTooLarge(op.maxBody)
+		}
+		var req T
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := validate(op.input, body, s.strict); err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				return nil, errInvalidBody
+			}
+		}
+		run := func() (any, error) { return fn(ctx, req) }
+		if op.idempotency != nil {
+			return op.idempotency.call(ctx, op.name, body, run)
+		}
+		if op.stale != nil {
+			handler := run
+			run = func() (any, error) { return op.stale.call(ctx, op.name, body, handler) }
+		}
+		if op.cache != nil {
+			return op.cache.call(ctx, op.name, body, run)
+		}
+		return run()
+	}
+	for _, opt := range opts {
+		opt(op)
+	}
+	if op.cache != nil {
+		op.cache.configure(op.name)
+	}
+	if op.feature != "" {
+		op.idempotency = newIdempotencyStore()
+	}
+	s.ops = append(s.ops, op)
+	s.Handle(path, op)
+}
+
+func (op *operation) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	switch {
+	case r.Method == http.MethodPost:
+		var err error
+		if body, err = readBody(w, r, op.maxBody); err != nil {
+			WriteError(w, err)
+			return
+		}
+	case r.Method == http.MethodGet && op.allowGet:
+		params := make(map[string]string)
+		for key, values := range r.URL.Query() {
+			params[key] = values[0]
+		}
+		body, _ = json.Marshal(params)
+	default:
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+
+	ctx, ok := op.server.restSession(w, r)
+	if !ok {
+		return
+	}
+	ctx = withIdempotencyKey(ctx, r.Header.Get(idempotencyHeader))
+	resp, err := op.call(ctx, body)
+	markReplayed(ctx, w)
+	if wantsContent(r) {
+		markStale(w, resp)
+		writeContent(w, op, resp, err)
+		return
+	}
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	markStale(w, resp)
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// lookup returns the operation registered under the MCP tool name.
+func (s *Server) lookup(name string) *operation {
+	for _, op := range s.ops {
+		if op.name == name {
+			return op
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes v as a JSON response with the given status.
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
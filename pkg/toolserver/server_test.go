@@ -0,0 +1,182 @@
+package toolserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type echoRequest struct {
+	Message string `json:"message"`
+}
+
+type echoResponse struct {
+	Echo string `json:"echo"`
+}
+
+func newEchoServer() *Server {
+	s := New("echo")
+	Register(s, "/echo", func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		switch req.Message {
+		case "":
+			return echoResponse{}, BadRequest("message is required")
+		case "boom":
+			return echoResponse{}, errors.New("boom")
+		}
+		return echoResponse{Echo: req.Message}, nil
+	})
+	return s
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		want   string
+	}{
+		{"ok", http.MethodPost, `{"message":"hi"}`, http.StatusOK, `{"echo":"hi"}`},
+		{"validation error", http.MethodPost, `{}`, http.StatusBadRequest, `{"code":"INVALID_ARGUMENT","message":"message is required","retryable":false,"error":"message is required"}`},
+		{"empty body", http.MethodPost, ``, http.StatusBadRequest, `{"code":"INVALID_ARGUMENT","message":"message is required","retryable":false,"error":"message is required"}`},
+		{"malformed body", http.MethodPost, `{`, http.StatusBadRequest, `{"code":"INVALID_ARGUMENT","message":"invalid request body","retryable":false,"error":"invalid request body"}`},
+		{"wrong field type", http.MethodPost, `{"message":1}`, http.StatusBadRequest,
+			`{"code":"VALIDATION_FAILED","message":"invalid request body: message: expected string, got number","retryable":false,"fields":[{"field":"message","message":"expected string, got number"}],"error":"invalid request body: message: expected string, got number"}`},
+		{"internal error", http.MethodPost, `{"message":"boom"}`, http.StatusInternalServerError, `{"code":"INTERNAL","message":"boom","retryable":false,"error":"boom"}`},
+		{"wrong method", http.MethodGet, ``, http.StatusMethodNotAllowed, `{"code":"METHOD_NOT_ALLOWED","message":"method not allowed","retryable":false,"error":"method not allowed"}`},
+	}
+
+	s := newEchoServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest(tt.method, "/echo", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+		})
+	}
+}
+
+func TestHealth(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newEchoServer().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"status":"healthy"}` {
+		t.Errorf("/health = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	t.Setenv("REQUEST_TIMEOUT", "20ms")
+	s := New("slow")
+	wait := func(ctx context.Context, _ struct{}) (echoResponse, error) {
+		<-ctx.Done()
+		return echoResponse{}, ctx.Err()
+	}
+	Register(s, "/wait", wait)
+	Register(s, "/wait-longer", wait, Timeout(50*time.Millisecond))
+
+	tests := []struct {
+		path    string
+		timeout time.Duration
+	}{
+		{"/wait", 20 * time.Millisecond},
+		{"/wait-longer", 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, nil))
+		if elapsed := time.Since(start); elapsed < tt.timeout {
+			t.Errorf("%s returned after %v, before its %v timeout", tt.path, elapsed, tt.timeout)
+		}
+		msg := strings.TrimPrefix(tt.path, "/") + " timed out after " + tt.timeout.String()
+		want := `{"code":"DEADLINE_EXCEEDED","message":"` + msg + `","retryable":true,"error":"` + msg + `"}`
+		if rec.Code != http.StatusGatewayTimeout || strings.TrimSpace(rec.Body.String()) != want {
+			t.Errorf("%s = %d %s, want 504 %s", tt.path, rec.Code, rec.Body, want)
+		}
+	}
+}
+
+func TestServeShutdown(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   string
+		cancelled bool // whether the in-flight call sees its context cancelled
+	}{
+		{"drained", "5s", false},
+		{"timed out", "50ms", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SHUTDOWN_TIMEOUT", tt.timeout)
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			s := New("echo")
+			Register(s, "/slow", func(ctx context.Context, _ struct{}) (echoResponse, error) {
+				close(started)
+				select {
+				case <-release:
+					return echoResponse{Echo: "done"}, nil
+				case <-ctx.Done():
+					return echoResponse{}, ctx.Err()
+				}
+			})
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, stop := context.WithCancel(context.Background())
+			served := make(chan error, 1)
+			go func() { served <- s.Serve(ctx, ln) }()
+
+			type result struct {
+				status int
+				err    error
+			}
+			results := make(chan result, 1)
+			go func() {
+				resp, err := http.Post("http://"+ln.Addr().String()+"/slow", "application/json", nil)
+				if err != nil {
+					results <- result{err: err}
+					return
+				}
+				resp.Body.Close()
+				results <- result{status: resp.StatusCode}
+			}()
+
+			<-started
+			stop()
+			if !tt.cancelled {
+				time.Sleep(50 * time.Millisecond)
+				close(release)
+			}
+
+			r := <-results
+			if tt.cancelled {
+				if r.err == nil && r.status == http.StatusOK {
+					t.Errorf("in-flight call completed after the shutdown timeout")
+				}
+			} else if r.err != nil || r.status != http.StatusOK {
+				t.Errorf("in-flight call = %d, %v; want it drained with 200", r.status, r.err)
+			}
+			if err := <-served; err != nil {
+				t.Errorf("Serve returned %v", err)
+			}
+		})
+	}
+}
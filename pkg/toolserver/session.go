@@ -0,0 +1,283 @@
+package toolserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+const (
+	// defaultSessionTTL is how long an unused session is kept, with its
+	// state, unless $SESSION_TTL says otherwise.
+	defaultSessionTTL = 30 * time.Minute
+	// maxSessionIDLength bounds the session IDs REST clients choose.
+	maxSessionIDLength = 128
+	// maxStreamsPerSession bounds the streams kept for resumption; the
+	// oldest are dropped first.
+	maxStreamsPerSession = 16
+)
+
+// sessionStore holds the sessions of the HTTP transports: MCP sessions, and
+// those REST and JSON-RPC clients name in Mcp-Session-Id.
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// session is one MCP client connection over HTTP, or one conversation of a
+// REST client.
+type session struct {
+	id    string
+	owner string // callerKey of the caller that started it
+	state State
+
+	mu         sync.Mutex
+	streams    []*stream
+	nextStream int
+	lastUsed   time.Time
+
+	// legacy is the event stream of a session opened with the HTTP+SSE
+	// transport; responses to its POSTs are sent there.
+	legacy *stream
+}
+
+// newSession returns a session with a random ID for owner.
+func newSession(owner string) *session {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return &session{id: hex.EncodeToString(b), owner: owner, lastUsed: time.Now()}
+}
+
+// create starts a session for owner. Sessions of the HTTP+SSE transport,
+// which last as long as their event stream, are created with legacy set.
+func (ss *sessionStore) create(owner string, legacy bool) *session {
+	sess := newSession(owner)
+	if legacy {
+		sess.legacy = &stream{changed: make(chan struct{})}
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.add(sess)
+	return sess
+}
+
+// open returns the session with the ID a REST client chose, starting it if
+// there is none. A session started by someone else is an error.
+func (ss *sessionStore) open(id, owner string) (*session, error) {
+	if !validSessionID(id) {
+		return nil, BadRequest("invalid %s header", sessionHeader)
+	}
+	if sess := ss.get(id, owner); sess != nil {
+		return sess, nil
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if sess := ss.sessions[id]; sess != nil {
+		if sess.owner != owner {
+			return nil, Errorf(http.StatusNotFound, "session not found")
+		}
+		return sess, nil // started concurrently
+	}
+	sess := &session{id: id, owner: owner, lastUsed: time.Now()}
+	ss.add(sess)
+	return sess, nil
+}
+
+// add stores sess, dropping sessions that have expired. ss.mu is held.
+func (ss *sessionStore) add(sess *session) {
+	if ss.sessions == nil {
+		ss.sessions = make(map[string]*session)
+	}
+	ttl := sessionTTL()
+	for id, old := range ss.sessions {
+		if old.expired(ttl) {
+			delete(ss.sessions, id)
+		}
+	}
+	ss.sessions[sess.id] = sess
+}
+
+// get returns owner's session with the given ID, or nil if there is none or
+// it has expired.
+func (ss *sessionStore) get(id, owner string) *session {
+	ss.mu.Lock()
+	sess := ss.sessions[id]
+	if sess != nil && sess.expired(sessionTTL()) {
+		delete(ss.sessions, id)
+		sess = nil
+	}
+	ss.mu.Unlock()
+	if sess == nil || sess.owner != owner {
+		return nil
+	}
+	sess.mu.Lock()
+	sess.lastUsed = time.Now()
+	sess.mu.Unlock()
+	return sess
+}
+
+// closeLegacy ends the event streams of HTTP+SSE sessions, which would
+// otherwise stay open through a shutdown. Their clients reconnect.
+func (ss *sessionStore) closeLegacy() {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for _, sess := range ss.sessions {
+		if sess.legacy != nil {
+			sess.legacy.close()
+		}
+	}
+}
+
+func (ss *sessionStore) delete(id string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.sessions, id)
+}
+
+// expired reports whether sess has been unused for longer than ttl.
+// Sessions of the HTTP+SSE transport end with their stream instead.
+func (sess *session) expired(ttl time.Duration) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.legacy == nil && time.Since(sess.lastUsed) > ttl
+}
+
+// sessionTTL is how long an unused session is kept.
+func sessionTTL() time.Duration {
+	return config.Duration("SESSION_TTL", defaultSessionTTL)
+}
+
+// validSessionID reports whether id can name a session: visible ASCII, as
+// MCP requires, and not too long.
+func validSessionID(id string) bool {
+	if id == "" || len(id) > maxSessionIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newStream starts a stream whose events can be replayed later.
+func (sess *session) newStream() *stream {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.nextStream++
+	st := &stream{id: strconv.Itoa(sess.nextStream), changed: make(chan struct{})}
+	sess.streams = append(sess.streams, st)
+	if len(sess.streams) > maxStreamsPerSession {
+		sess.streams = sess.streams[1:]
+	}
+	return st
+}
+
+// stream returns the retained stream with the given ID, or nil.
+func (sess *session) stream(id string) *stream {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	for _, st := range sess.streams {
+		if st.id == id {
+			return st
+		}
+	}
+	return nil
+}
+
+// sseEvent is one server-sent event.
+type sseEvent struct {
+	id   string
+	name string
+	data []byte
+}
+
+func writeEvent(w http.ResponseWriter, ev sseEvent) error {
+	if ev.id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", ev.id); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+	return err
+}
+
+// stream is a sequence of SSE messages. Events are kept after they are sent
+// so a client that loses the connection can resume with Last-Event-ID,
+// whose value is "<stream>-<seq>".
+type stream struct {
+	id string
+
+	mu      sync.Mutex
+	events  []sseEvent
+	done    bool
+	changed chan struct{} // closed and replaced whenever events or done change
+}
+
+// send appends a message event.
+func (st *stream) send(data []byte) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.events = append(st.events, sseEvent{
+		id:   fmt.Sprintf("%s-%d", st.id, len(st.events)+1),
+		name: "message",
+		data: data,
+	})
+	close(st.changed)
+	st.changed = make(chan struct{})
+}
+
+// close marks the stream complete once every message has been sent.
+func (st *stream) close() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.done = true
+	close(st.changed)
+	st.changed = make(chan struct{})
+}
+
+// len returns the number of events sent so far.
+func (st *stream) len() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return len(st.events)
+}
+
+// follow writes the events after the first n to w as they are sent, until
+// the stream is closed or ctx is done.
+func (st *stream) follow(ctx context.Context, w http.ResponseWriter, n int) {
+	flusher, _ := w.(http.Flusher)
+	for {
+		st.mu.Lock()
+		events, done, changed := st.events[n:], st.done, st.changed
+		st.mu.Unlock()
+
+		for _, ev := range events {
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+		}
+		n += len(events)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if done {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package toolserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// staleWarning is the Warning header of REST responses served stale.
+const staleWarning = `110 - "Response is Stale"`
+
+// ServeStale keeps the operation's last successful response to each
+// request for maxAge, and answers with it when a later call fails because
+// a dependency is unreachable, instead of failing: when the handler returns
+// a retryable error, such as UNAVAILABLE from a Kubernetes API server that
+// is restarting or UPSTREAM_TIMEOUT from a registry, or runs past its
+// timeout. The response then carries "stale": true and "asOf", when it was
+// made, and over REST a Warning: 110 header, so dashboards built on read
+// operations survive API server blips but can tell. Responses that are not
+// JSON objects are never served stale.
+//
+// Like Cache, responses are kept per caller and request, at most
+// $CACHE_MAX_ENTRIES per operation. $NAME_STALE_MAX_AGE overrides maxAge,
+// where NAME is the operation's name upper-cased with dashes as
+// underscores; 0 turns it off.
+func ServeStale(maxAge time.Duration) Option {
+	return func(op *operation) {
+		if n := config.Int("CACHE_MAX_ENTRIES", defaultCacheEntries); n > 0 && maxAge > 0 {
+			op.stale = &staleStore{defaultMaxAge: maxAge, max: n, entries: make(map[string]staleResponse)}
+		}
+	}
+}
+
+// staleResponse is a response served after the call that made it, at asOf.
+// It encodes as the response with the stale and asOf fields added.
+type staleResponse struct {
+	resp any
+	asOf time.Time
+}
+
+func (r staleResponse) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(r.resp)
+	if err != nil {
+		return nil, err
+	}
+	marker, _ := json.Marshal(struct {
+		Stale bool      `json:"stale"`
+		AsOf  time.Time `json:"asOf"`
+	}{true, r.asOf})
+	b = bytes.TrimSpace(b)
+	if len(b) == 2 { // {}
+		return marker, nil
+	}
+	// Splice the marker's fields in after the response's, so they win over
+	// any of its own of the same name.
+	return append(append(b[:len(b)-1:len(b)-1], ','), marker[1:]...), nil
+}
+
+// staleStore holds an operation's last successful responses by request.
+type staleStore struct {
+	defaultMaxAge time.Duration
+	max           int
+
+	mu      sync.Mutex
+	entries map[string]staleResponse
+}
+
+func (st *staleStore) maxAge(op string) time.Duration {
+	return config.Duration(settingName(op)+"_STALE_MAX_AGE", st.defaultMaxAge)
+}
+
+// call runs fn for a call of op and keeps its response, or, if it fails
+// because a dependency is unreachable, returns the last response kept for
+// the same request instead.
+func (st *staleStore) call(ctx context.Context, op string, body []byte, fn func() (any, error)) (any, error) {
+	key, ok := cacheKey(ctx, body)
+	maxAge := st.maxAge(op)
+	if !ok || maxAge <= 0 {
+		return fn()
+	}
+	resp, err := fn()
+	now := time.Now()
+	if err == nil {
+		if b, merr := json.Marshal(resp); merr == nil && len(b) > 0 && b[0] == '{' {
+			st.put(key, staleResponse{resp: resp, asOf: now}, now, maxAge)
+		}
+		return resp, nil
+	}
+	if !unreachable(ctx, err) {
+		return nil, err
+	}
+	st.mu.Lock()
+	kept, ok := st.entries[key]
+	st.mu.Unlock()
+	if !ok || now.Sub(kept.asOf) >= maxAge {
+		return nil, err
+	}
+	staleResponses.WithLabelValues(op).Inc()
+	return kept, nil
+}
+
+// put keeps r for key, making room if the store is full.
+func (st *staleStore) put(key string, r staleResponse, now time.Time, maxAge time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.entries[key]; !ok && len(st.entries) >= st.max {
+		for k, e := range st.entries {
+			if now.Sub(e.asOf) >= maxAge {
+				delete(st.entries, k)
+			}
+		}
+		for k := range st.entries {
+			if len(st.entries) < st.max {
+				break
+			}
+			delete(st.entries, k)
+		}
+	}
+	st.entries[key] = r
+}
+
+// unreachable reports whether err means a dependency could not be reached
+// or did not answer in time, rather than that the call itself is wrong.
+func unreachable(ctx context.Context, err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		// Only our own deadline; a caller that went away needs no answer.
+		return ctx.Err() == nil || errors.Is(ctx.Err(), context.DeadlineExceeded)
+	}
+	resp, _ := errorResponse(err)
+	return resp.Retryable
+}
+
+// markStale sets the Warning header on a REST response served stale.
+func markStale(w http.ResponseWriter, resp any) {
+	if _, ok := resp.(staleResponse); ok {
+		w.Header().Set("Warning", staleWarning)
+	}
+}
@@ -0,0 +1,57 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeStale(t *testing.T) {
+	var fail error
+	s := New("stale")
+	Register(s, "/nodes", func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		if fail != nil {
+			return echoResponse{}, fail
+		}
+		return echoResponse{Echo: req.Message}, nil
+	}, ServeStale(time.Minute))
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/nodes", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(`{"message":"a"}`); rec.Header().Get("Warning") != "" || strings.Contains(rec.Body.String(), "stale") {
+		t.Errorf("fresh response is marked stale: %s", rec.Body)
+	}
+	fail = NewError(CodeUnavailable, "kubernetes client not available")
+	rec := post(`{"message":"a"}`)
+	var got struct {
+		Echo  string    `json:"echo"`
+		Stale bool      `json:"stale"`
+		AsOf  time.Time `json:"asOf"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("while unavailable: %d %s", rec.Code, rec.Body)
+	}
+	if got.Echo != "a" || !got.Stale || time.Since(got.AsOf) > time.Minute || rec.Header().Get("Warning") != staleWarning {
+		t.Errorf("while unavailable: %s (Warning %q), want the earlier response marked stale", rec.Body, rec.Header().Get("Warning"))
+	}
+	if rec := post(`{"message":"b"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("a request never answered = %d, want the 503", rec.Code)
+	}
+	fail = BadRequest("no such node")
+	if rec := post(`{"message":"a"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("a call that is itself wrong = %d, want the 400", rec.Code)
+	}
+
+	fail = NewError(CodeUpstreamTimeout, "registry did not answer")
+	t.Setenv("NODES_STALE_MAX_AGE", "0s")
+	if rec := post(`{"message":"a"}`); rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("with NODES_STALE_MAX_AGE=0s = %d, want the 504", rec.Code)
+	}
+}
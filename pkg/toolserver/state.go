@@ -0,0 +1,103 @@
+package toolserver
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// State is what a session remembers between the calls of one agent
+// conversation: a selected namespace, a pinned kube context, intermediate
+// results worth keeping. It is dropped with the session, once the session
+// has been unused for $SESSION_TTL (default 30m).
+//
+// A call belongs to a session when it arrives over an MCP session, the
+// Streamable HTTP, HTTP+SSE or WebSocket transport, or over REST or /rpc
+// with an Mcp-Session-Id header naming one. REST clients choose the ID, up
+// to 128 visible ASCII characters; the session starts on first use and is
+// private to the caller that started it. Responses kept by Cache and
+// ServeStale are kept per session for calls that have one, so handlers may
+// answer from state.
+//
+// The methods of a nil State, that of a call without a session, do
+// nothing.
+type State struct {
+	mu     sync.Mutex
+	values map[string]any
+}
+
+type sessionKey struct{}
+
+// withSession returns ctx for calls in sess.
+func withSession(ctx context.Context, sess *session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sess)
+}
+
+// SessionState returns the state of the session the call ctx belongs to, or
+// nil if it has none.
+func SessionState(ctx context.Context) *State {
+	if sess, ok := ctx.Value(sessionKey{}).(*session); ok {
+		return &sess.state
+	}
+	return nil
+}
+
+// SessionID returns the ID of the session the call ctx belongs to, or "".
+func SessionID(ctx context.Context) string {
+	if sess, ok := ctx.Value(sessionKey{}).(*session); ok {
+		return sess.id
+	}
+	return ""
+}
+
+// Get returns the value stored under key, or nil.
+func (st *State) Get(key string) any {
+	if st == nil {
+		return nil
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.values[key]
+}
+
+// Set stores value under key for later calls in the session.
+func (st *State) Set(key string, value any) {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.values == nil {
+		st.values = make(map[string]any)
+	}
+	st.values[key] = value
+}
+
+// Delete removes the value stored under key.
+func (st *State) Delete(key string) {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.values, key)
+}
+
+// restSession returns r's context with the session named by its
+// Mcp-Session-Id header, if any, and echoes the header. It writes an error
+// and returns false if the header is invalid or names someone else's
+// session.
+func (s *Server) restSession(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	ctx := r.Context()
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		return ctx, true
+	}
+	sess, err := s.sessions.open(id, callerKey(ctx))
+	if err != nil {
+		WriteError(w, err)
+		return nil, false
+	}
+	w.Header().Set(sessionHeader, sess.id)
+	return withSession(ctx, sess), true
+}
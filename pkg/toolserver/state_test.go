@@ -0,0 +1,129 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countResponse struct {
+	Count int `json:"count"`
+}
+
+// newCountServer serves "count", which counts the calls in the caller's
+// session.
+func newCountServer() *Server {
+	s := New("count")
+	Register(s, "/count", func(ctx context.Context, _ struct{}) (countResponse, error) {
+		st := SessionState(ctx)
+		n, _ := st.Get("count").(int)
+		st.Set("count", n+1)
+		return countResponse{Count: n + 1}, nil
+	})
+	return s
+}
+
+func postCount(t *testing.T, srv *httptest.Server, session string) (int, *http.Response) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/count", strings.NewReader(`{}`))
+	if session != "" {
+		req.Header.Set(sessionHeader, session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out countResponse
+	json.NewDecoder(resp.Body).Decode(&out)
+	return out.Count, resp
+}
+
+func TestSessionStateREST(t *testing.T) {
+	srv := httptest.NewServer(newCountServer())
+	defer srv.Close()
+
+	for want := 1; want <= 3; want++ {
+		n, resp := postCount(t, srv, "conversation-a")
+		if n != want || resp.Header.Get(sessionHeader) != "conversation-a" {
+			t.Fatalf("call %d: count %d, session %q", want, n, resp.Header.Get(sessionHeader))
+		}
+	}
+	if n, _ := postCount(t, srv, "conversation-b"); n != 1 {
+		t.Errorf("other session: count %d, want 1", n)
+	}
+	for range 2 {
+		if n, resp := postCount(t, srv, ""); n != 1 || resp.Header.Get(sessionHeader) != "" {
+			t.Errorf("without a session: count %d, session %q", n, resp.Header.Get(sessionHeader))
+		}
+	}
+	if _, resp := postCount(t, srv, strings.Repeat("x", maxSessionIDLength+1)); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("long session ID: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestSessionStateMCP(t *testing.T) {
+	srv := httptest.NewServer(newCountServer())
+	defer srv.Close()
+
+	resp := postMCP(t, srv, "", "application/json", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	session := resp.Header.Get(sessionHeader)
+	call := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"count","arguments":{}}}`
+	for want := 1; want <= 2; want++ {
+		var out struct {
+			Result callResult `json:"result"`
+		}
+		json.NewDecoder(postMCP(t, srv, session, "application/json", call).Body).Decode(&out)
+		if got, _ := json.Marshal(out.Result.StructuredContent); string(got) != fmt.Sprintf(`{"count":%d}`, want) {
+			t.Errorf("call %d: structuredContent = %s", want, got)
+		}
+	}
+
+	// The MCP session's state is shared with REST calls naming it.
+	if n, _ := postCount(t, srv, session); n != 3 {
+		t.Errorf("REST call in the MCP session: count %d, want 3", n)
+	}
+}
+
+func TestSessionOwner(t *testing.T) {
+	var ss sessionStore
+	if _, err := ss.open("shared", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ss.open("shared", "bob"); errorStatus(err) != http.StatusNotFound {
+		t.Errorf("someone else's session: err = %v, want 404", err)
+	}
+	if ss.get("shared", "bob") != nil {
+		t.Error("get returned someone else's session")
+	}
+}
+
+func TestSessionTTL(t *testing.T) {
+	t.Setenv("SESSION_TTL", "10ms")
+	var ss sessionStore
+	sess, _ := ss.open("short", "")
+	sess.state.Set("namespace", "team-a")
+
+	time.Sleep(20 * time.Millisecond)
+	if ss.get("short", "") != nil {
+		t.Fatal("expired session still served")
+	}
+	sess, _ = ss.open("short", "")
+	if v := sess.state.Get("namespace"); v != nil {
+		t.Errorf("state outlived its session: %v", v)
+	}
+}
+
+func TestNilState(t *testing.T) {
+	st := SessionState(context.Background())
+	st.Set("k", 1)
+	st.Delete("k")
+	if st != nil || st.Get("k") != nil || SessionID(context.Background()) != "" {
+		t.Error("a call without a session has state")
+	}
+}
@@ -0,0 +1,298 @@
+package toolserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Headers of the MCP Streamable HTTP transport.
+const (
+	sessionHeader         = "Mcp-Session-Id"
+	protocolVersionHeader = "Mcp-Protocol-Version"
+	lastEventIDHeader     = "Last-Event-ID"
+)
+
+// supportedVersions are the revisions accepted in Mcp-Protocol-Version.
+var supportedVersions = []string{protocolVersion, "2025-03-26"}
+
+// handleMCP serves the MCP Streamable HTTP transport on /mcp. POST carries
+// client messages, answered with JSON or, when the client accepts it, an
+// SSE stream that can be resumed with GET and Last-Event-ID. DELETE ends
+// the session.
+func (s *Server) handleMCP(w http.ResponseWriter, r *http.Request) {
+	if !s.allowedOrigin(r) {
+		WriteError(w, Errorf(http.StatusForbidden, "origin not allowed"))
+		return
+	}
+	if v := r.Header.Get(protocolVersionHeader); v != "" && !slices.Contains(supportedVersions, v) {
+		WriteError(w, BadRequest("unsupported MCP protocol version: %s", v))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.postMCP(w, r)
+	case http.MethodGet:
+		s.resumeMCP(w, r)
+	case http.MethodDelete:
+		sess := s.requireSession(w, r)
+		if sess == nil {
+			return
+		}
+		s.sessions.delete(sess.id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+	}
+}
+
+func (s *Server) postMCP(w http.ResponseWriter, r *http.Request) {
+	raw, batch, err := readMessages(w, r, s.maxBody)
+	if err != nil {
+		writeReadError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var (
+		calls     []rpcMessage
+		responses []*rpcResponse
+	)
+	initialize := false
+	for _, m := range raw {
+		msg, errResp := decodeMessage(m)
+		switch {
+		case errResp != nil:
+			responses = append(responses, errResp)
+		case msg.isCall():
+			calls = append(calls, msg)
+			initialize = initialize || msg.Method == "initialize"
+		}
+	}
+
+	var sess *session
+	if initialize {
+		if len(raw) != 1 {
+			WriteError(w, BadRequest("initialize must be sent on its own"))
+			return
+		}
+		sess = s.sessions.create(callerKey(r.Context()), false)
+		w.Header().Set(sessionHeader, sess.id)
+	} else if sess = s.requireSession(w, r); sess == nil {
+		return
+	}
+
+	if len(calls) == 0 && len(responses) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// Calls outlive the request, so a client that drops the SSE stream can
+	// resume it and still receive their results.
+	ctx, cancel := s.detach(r.Context())
+	ctx = withSession(ctx, sess)
+
+	if !acceptsEventStream(r) {
+		defer cancel()
+		var mu sync.Mutex
+		s.runCalls(ctx, calls, func(resp *rpcResponse) {
+			mu.Lock()
+			defer mu.Unlock()
+			responses = append(responses, resp)
+		})
+		if batch {
+			WriteJSON(w, http.StatusOK, responses)
+		} else {
+			WriteJSON(w, http.StatusOK, responses[0])
+		}
+		return
+	}
+
+	st := sess.newStream()
+	for _, resp := range responses {
+		st.send(marshalResponse(resp))
+	}
+	go func() {
+		defer cancel()
+		s.runCalls(ctx, calls, func(resp *rpcResponse) { st.send(marshalResponse(resp)) })
+		st.close()
+	}()
+
+	startEventStream(w)
+	st.follow(r.Context(), w, 0)
+}
+
+// resumeMCP replays a stream after the event named by Last-Event-ID and
+// follows it to the end. The server sends no unsolicited messages, so a GET
+// without Last-Event-ID is refused as the transport allows.
+func (s *Server) resumeMCP(w http.ResponseWriter, r *http.Request) {
+	sess := s.requireSession(w, r)
+	if sess == nil {
+		return
+	}
+	lastID := r.Header.Get(lastEventIDHeader)
+	if lastID == "" {
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+
+	streamID, seq, _ := strings.Cut(lastID, "-")
+	n, err := strconv.Atoi(seq)
+	st := sess.stream(streamID)
+	if err != nil || st == nil || n < 0 || n > st.len() {
+		WriteError(w, Errorf(http.StatusNotFound, "unknown event ID: %s", lastID))
+		return
+	}
+
+	startEventStream(w)
+	st.follow(r.Context(), w, n)
+}
+
+// requireSession returns the session named by the Mcp-Session-Id header,
+// or writes an error and returns nil.
+func (s *Server) requireSession(w http.ResponseWriter, r *http.Request) *session {
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		WriteError(w, BadRequest("missing %s header", sessionHeader))
+		return nil
+	}
+	sess := s.sessions.get(id, callerKey(r.Context()))
+	if sess == nil {
+		WriteError(w, Errorf(http.StatusNotFound, "session not found"))
+	}
+	return sess
+}
+
+// handleSSE serves the HTTP+SSE transport of protocol revision 2024-11-05
+// for older clients. The stream's first event names the endpoint to POST
+// messages to; responses arrive on the stream.
+func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	if !s.allowedOrigin(r) {
+		WriteError(w, Errorf(http.StatusForbidden, "origin not allowed"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+
+	sess := s.sessions.create(callerKey(r.Context()), true)
+	defer s.sessions.delete(sess.id)
+
+	startEventStream(w)
+	// Relative, so the endpoint resolves under any path prefix the tool is
+	// served behind.
+	if err := writeEvent(w, sseEvent{name: "endpoint", data: []byte("messages?sessionId=" + sess.id)}); err != nil {
+		return
+	}
+	sess.legacy.follow(r.Context(), w, 0)
+}
+
+// handleMessages accepts client messages for an HTTP+SSE session.
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+	sess := s.sessions.get(r.URL.Query().Get("sessionId"), callerKey(r.Context()))
+	if sess == nil || sess.legacy == nil {
+		WriteError(w, Errorf(http.StatusNotFound, "session not found"))
+		return
+	}
+	raw, _, err := readMessages(w, r, s.maxBody)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	var calls []rpcMessage
+	for _, m := range raw {
+		msg, errResp := decodeMessage(m)
+		switch {
+		case errResp != nil:
+			sess.legacy.send(marshalResponse(errResp))
+		case msg.isCall():
+			calls = append(calls, msg)
+		}
+	}
+	ctx, cancel := s.detach(r.Context())
+	ctx = withSession(ctx, sess)
+	go func() {
+		defer cancel()
+		s.runCalls(ctx, calls, func(resp *rpcResponse) {
+			sess.legacy.send(marshalResponse(resp))
+		})
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// runCalls handles calls concurrently, passing each response to send, and
+// returns when all are done.
+func (s *Server) runCalls(ctx context.Context, calls []rpcMessage, send func(*rpcResponse)) {
+	var wg sync.WaitGroup
+	for _, msg := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			send(s.handleMessage(ctx, msg))
+		}()
+	}
+	wg.Wait()
+}
+
+// readMessages reads a JSON-RPC message or batch of at most limit bytes
+// from r's body. batch reports whether the body was an array.
+func readMessages(w http.ResponseWriter, r *http.Request, limit int64) (msgs []json.RawMessage, batch bool, err error) {
+	data, err := readBody(w, r, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &msgs); err != nil {
+			return nil, true, errInvalidBody
+		}
+		return msgs, true, nil
+	}
+	if !json.Valid(data) {
+		return nil, false, errInvalidBody
+	}
+	return []json.RawMessage{data}, false, nil
+}
+
+func marshalResponse(resp *rpcResponse) []byte {
+	data, _ := json.Marshal(resp)
+	return data
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+func startEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// allowedOrigin guards against DNS rebinding: a browser page from another
+// host must not be able to reach a tool listening on localhost unless its
+// origin is allowed by CORS_ALLOWED_ORIGINS.
+func (s *Server) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || s.cors.Load().allows(origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
@@ -0,0 +1,162 @@
+package toolserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type event struct {
+	id, name, data string
+}
+
+// readEvents parses server-sent events until the stream ends or n events
+// have been read.
+func readEvents(t *testing.T, r io.Reader, n int) []event {
+	t.Helper()
+	var events []event
+	var ev event
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		field, value, _ := strings.Cut(scanner.Text(), ": ")
+		switch field {
+		case "id":
+			ev.id = value
+		case "event":
+			ev.name = value
+		case "data":
+			ev.data = value
+		case "":
+			events = append(events, ev)
+			ev = event{}
+			if len(events) == n {
+				return events
+			}
+		}
+	}
+	return events
+}
+
+func postMCP(t *testing.T, srv *httptest.Server, session, accept, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/mcp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+	if session != "" {
+		req.Header.Set(sessionHeader, session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestStreamableHTTP(t *testing.T) {
+	srv := httptest.NewServer(newEchoServer())
+	defer srv.Close()
+
+	const both = "application/json, text/event-stream"
+	resp := postMCP(t, srv, "", "application/json", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	session := resp.Header.Get(sessionHeader)
+	if resp.StatusCode != http.StatusOK || session == "" {
+		t.Fatalf("initialize: status %d, session %q", resp.StatusCode, session)
+	}
+	var init rpcResponse
+	json.NewDecoder(resp.Body).Decode(&init)
+	if init.Error != nil || string(init.ID) != "1" {
+		t.Fatalf("initialize response = %+v", init)
+	}
+
+	if resp := postMCP(t, srv, session, both, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("notification: status %d, want 202", resp.StatusCode)
+	}
+	if resp := postMCP(t, srv, "", both, `{"jsonrpc":"2.0","id":2,"method":"ping"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("missing session: status %d, want 400", resp.StatusCode)
+	}
+	if resp := postMCP(t, srv, "nope", both, `{"jsonrpc":"2.0","id":2,"method":"ping"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown session: status %d, want 404", resp.StatusCode)
+	}
+
+	call := `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hi"}}}`
+	resp = postMCP(t, srv, session, both, call)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	events := readEvents(t, resp.Body, 0)
+	if len(events) != 1 || events[0].name != "message" || !strings.Contains(events[0].data, `"structuredContent":{"echo":"hi"}`) {
+		t.Fatalf("events = %+v", events)
+	}
+
+	// Resuming from before the first event replays it.
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/mcp", nil)
+	req.Header.Set(sessionHeader, session)
+	req.Header.Set(lastEventIDHeader, strings.Replace(events[0].id, "-1", "-0", 1))
+	replay, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Body.Close()
+	if got := readEvents(t, replay.Body, 0); len(got) != 1 || got[0] != events[0] {
+		t.Errorf("replayed events = %+v, want %+v", got, events)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, srv.URL+"/mcp", nil)
+	req.Header.Set(sessionHeader, session)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE: %v %v", resp, err)
+	}
+	if resp := postMCP(t, srv, session, both, call); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("after DELETE: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestStreamableHTTPOrigin(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`))
+	req.Header.Set("Origin", "http://evil.example")
+	newEchoServer().ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+}
+
+func TestHTTPSSE(t *testing.T) {
+	srv := httptest.NewServer(newEchoServer())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body := bufio.NewReader(resp.Body)
+
+	endpoint := readEvents(t, body, 1)
+	if len(endpoint) != 1 || endpoint[0].name != "endpoint" {
+		t.Fatalf("first event = %+v", endpoint)
+	}
+	base, _ := url.Parse(srv.URL + "/sse")
+	ref, _ := url.Parse(endpoint[0].data)
+
+	post, err := http.Post(base.ResolveReference(ref).String(), "application/json",
+		strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"tools/list"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	post.Body.Close()
+	if post.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST status = %d, want 202", post.StatusCode)
+	}
+
+	msg := readEvents(t, body, 1)
+	if len(msg) != 1 || msg[0].name != "message" || !strings.Contains(msg[0].data, `"id":7`) || !strings.Contains(msg[0].data, `"name":"echo"`) {
+		t.Errorf("message event = %+v", msg)
+	}
+}
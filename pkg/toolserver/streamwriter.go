@@ -0,0 +1,146 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ndjsonContentType is the media type of newline-delimited JSON.
+const ndjsonContentType = "application/x-ndjson"
+
+// A StreamWriter writes a streamed response, for endpoints that watch,
+// monitor or report bulk work as it progresses: newline-delimited JSON, one
+// value per line, or server-sent events when the client accepts
+// text/event-stream. Register such endpoints with Server.Handle. It is safe
+// for concurrent use, so several goroutines can send to one stream.
+type StreamWriter struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	ctx context.Context
+	sse bool
+
+	mu         sync.Mutex
+	flushEvery int // values to write before flushing; 1 flushes each
+	pending    int // values written since the last flush
+	sent       int
+	err        error // the first failed write; the client is gone
+}
+
+// NewStreamWriter starts a streamed response to r with status 200, in the
+// format r accepts. It fails, without writing anything, if w cannot be
+// flushed, so the caller can still report that with WriteError.
+func NewStreamWriter(w http.ResponseWriter, r *http.Request) (*StreamWriter, error) {
+	if _, ok := w.(http.Flusher); !ok {
+		return nil, fmt.Errorf("streaming not supported")
+	}
+	sw := &StreamWriter{w: w, rc: http.NewResponseController(w), ctx: r.Context(), sse: acceptsEventStream(r), flushEvery: 1}
+	if sw.sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", ndjsonContentType)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // or nginx holds the stream back
+	w.WriteHeader(http.StatusOK)
+	sw.err = sw.rc.Flush()
+	return sw, nil
+}
+
+// EventStream reports whether the stream is server-sent events rather
+// than newline-delimited JSON.
+func (sw *StreamWriter) EventStream() bool { return sw.sse }
+
+// Done is closed when the client disconnects or the server shuts down, so
+// a handler waiting for something to send can stop.
+func (sw *StreamWriter) Done() <-chan struct{} { return sw.ctx.Done() }
+
+// FlushEvery makes Send flush after every n values instead of each one, for
+// bulk endpoints that send many small values; Flush sends the rest.
+func (sw *StreamWriter) FlushEvery(n int) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.flushEvery = max(n, 1)
+}
+
+// Send writes v as JSON: a line of its own, or the data of a server-sent
+// event named event (unnamed if event is empty), which NDJSON leaves out,
+// so values a client must tell apart should say what they are themselves.
+// It fails once the client has disconnected, after which the handler
+// should return.
+func (sw *StreamWriter) Send(event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if err := sw.check(); err != nil {
+		return err
+	}
+	switch {
+	case !sw.sse:
+		_, err = fmt.Fprintf(sw.w, "%s\n", data)
+	case event != "":
+		_, err = fmt.Fprintf(sw.w, "event: %s\ndata: %s\n\n", event, data)
+	default:
+		_, err = fmt.Fprintf(sw.w, "data: %s\n\n", data)
+	}
+	if err != nil {
+		sw.err = err
+		return err
+	}
+	sw.sent++
+	if sw.pending++; sw.pending >= sw.flushEvery {
+		return sw.flush()
+	}
+	return nil
+}
+
+// Error sends err as the stream's last value, in the envelope WriteError
+// writes, as an "error" event over server-sent events. The status and
+// headers have been sent already, so this is how a handler reports a
+// failure partway through.
+func (sw *StreamWriter) Error(err error) error {
+	resp, _ := errorResponse(err)
+	if err := sw.Send("error", resp); err != nil {
+		return err
+	}
+	return sw.Flush()
+}
+
+// Flush sends the values written so far.
+func (sw *StreamWriter) Flush() error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if err := sw.check(); err != nil {
+		return err
+	}
+	return sw.flush()
+}
+
+// Sent returns the number of values sent.
+func (sw *StreamWriter) Sent() int {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.sent
+}
+
+func (sw *StreamWriter) flush() error {
+	sw.pending = 0
+	if err := sw.rc.Flush(); err != nil {
+		sw.err = err
+	}
+	return sw.err
+}
+
+// check returns why nothing more can be sent: a failed write or the client
+// disconnecting.
+func (sw *StreamWriter) check() error {
+	if sw.err == nil {
+		sw.err = sw.ctx.Err()
+	}
+	return sw.err
+}
@@ -0,0 +1,97 @@
+package toolserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamWriter(t *testing.T) {
+	stopped := make(chan error, 1)
+	s := New("test")
+	s.HandleFunc("/watch", func(w http.ResponseWriter, r *http.Request) {
+		sw, err := NewStreamWriter(w, r)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		if r.URL.Query().Has("forever") {
+			for {
+				if err := sw.Send("tick", map[string]int{"seq": sw.Sent()}); err != nil {
+					stopped <- err
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+		sw.FlushEvery(2)
+		for i := range 3 {
+			sw.Send("change", map[string]int{"seq": i})
+		}
+		sw.Error(NewError(CodeUnavailable, "watch expired"))
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	get := func(ctx context.Context, query, accept string) *http.Response {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/watch"+query, nil)
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := get(context.Background(), "", "application/x-ndjson")
+	if ct := resp.Header.Get("Content-Type"); ct != ndjsonContentType {
+		t.Errorf("Content-Type = %q, want NDJSON", ct)
+	}
+	var lines []map[string]any
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var v map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		lines = append(lines, v)
+	}
+	resp.Body.Close()
+	if len(lines) != 4 || lines[2]["seq"] != 2.0 || lines[3]["code"] != string(CodeUnavailable) {
+		t.Errorf("NDJSON lines = %v, want three values and the error", lines)
+	}
+
+	resp = get(context.Background(), "", "text/event-stream")
+	events := readEvents(t, resp.Body, 4)
+	resp.Body.Close()
+	if len(events) != 4 || events[0].name != "change" || events[0].data != `{"seq":0}` || events[3].name != "error" {
+		t.Errorf("events = %v, want three changes and an error", events)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp = get(ctx, "?forever", "text/event-stream")
+	readEvents(t, resp.Body, 2)
+	cancel()
+	resp.Body.Close()
+	select {
+	case err := <-stopped:
+		if err == nil {
+			t.Error("Send succeeded after the client disconnected")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler kept sending after the client disconnected")
+	}
+}
+
+func TestStreamWriterNotSupported(t *testing.T) {
+	var w struct{ http.ResponseWriter }
+	w.ResponseWriter = httptest.NewRecorder()
+	if _, err := NewStreamWriter(w, httptest.NewRequest(http.MethodGet, "/", strings.NewReader(""))); err == nil {
+		t.Error("NewStreamWriter succeeded with a writer that cannot flush")
+	}
+}
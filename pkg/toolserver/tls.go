@@ -0,0 +1,174 @@
+package toolserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// certCheckInterval is how often the serving certificate file is checked
+// for a renewed certificate, e.g. one rotated by cert-manager.
+const certCheckInterval = time.Minute
+
+// tlsConfig returns the TLS configuration for ListenAndServe, or nil to
+// serve plain HTTP, from the settings:
+//
+//	TLS_CERT_FILE, TLS_KEY_FILE  serving certificate and key (PEM)
+//	TLS_CLIENT_CA_FILE           CA bundle for client certificates; setting
+//	                             it requires one on every endpoint except
+//	                             the probes and /metrics
+//	TLS_CLIENT_ROLES             comma-separated identity=role pairs; an
+//	                             identity may be listed more than once
+//
+// With client certificates configured it also sets s.certAuth.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	certFile, keyFile := config.String("TLS_CERT_FILE", ""), config.String("TLS_KEY_FILE", "")
+	caFile := config.String("TLS_CLIENT_CA_FILE", "")
+	if certFile == "" && keyFile == "" {
+		if caFile != "" {
+			return nil, errors.New("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+
+	certs := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := certs.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: certs.GetCertificate}
+	if caFile == "" {
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in %s", caFile)
+	}
+	// Kubelet probes cannot present a certificate, so the handshake only
+	// verifies certificates that are given; requireAuth demands one.
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	cfg.ClientCAs = pool
+
+	s.certAuth = &clientCertAuth{roles: parseRoles(config.String("TLS_CLIENT_ROLES", ""))}
+	slog.Info("requiring client certificates", "ca", caFile, "identities", len(s.certAuth.roles))
+	return cfg, nil
+}
+
+// parseRoles parses identity=role pairs into the roles of each identity, or
+// returns nil if there are none.
+func parseRoles(s string) map[string][]string {
+	var roles map[string][]string
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			continue
+		}
+		if roles == nil {
+			roles = make(map[string][]string)
+		}
+		identity := strings.TrimSpace(pair[:i])
+		roles[identity] = append(roles[identity], strings.TrimSpace(pair[i+1:]))
+	}
+	return roles
+}
+
+// clientCertAuth authenticates callers by the client certificate verified
+// in the TLS handshake.
+type clientCertAuth struct {
+	// roles maps certificate identities to roles. When it is nil any
+	// verified certificate is accepted, with no roles.
+	roles map[string][]string
+}
+
+var errClientCertRequired = Errorf(http.StatusUnauthorized, "client certificate required")
+
+// authenticate returns the identity of a verified client certificate. The
+// username is the first URI SAN, as in SPIFFE IDs, or else the subject's
+// common name, and the groups are its organizations, as for Kubernetes
+// client certificates. Roles are collected for the common name and every
+// SAN.
+func (a *clientCertAuth) authenticate(cs *tls.ConnectionState) (*Identity, error) {
+	if cs == nil || len(cs.VerifiedChains) == 0 {
+		return nil, errClientCertRequired
+	}
+	cert := cs.VerifiedChains[0][0]
+	id := &Identity{
+		Issuer:   cert.Issuer.CommonName,
+		Subject:  cert.Subject.CommonName,
+		Username: cert.Subject.CommonName,
+		Groups:   cert.Subject.Organization,
+	}
+	names := []string{cert.Subject.CommonName}
+	for _, u := range cert.URIs {
+		names = append(names, u.String())
+	}
+	if len(cert.URIs) > 0 {
+		id.Username = cert.URIs[0].String()
+	}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+
+	if a.roles == nil {
+		return id, nil
+	}
+	for _, name := range names {
+		id.Roles = append(id.Roles, a.roles[name]...)
+	}
+	if len(id.Roles) == 0 {
+		return nil, Errorf(http.StatusForbidden, "client certificate %s has no role", id.Username)
+	}
+	slices.Sort(id.Roles)
+	id.Roles = slices.Compact(id.Roles)
+	return id, nil
+}
+
+// certReloader serves a certificate from files, reloading it when the
+// certificate file changes.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cert != nil && time.Since(c.checked) < certCheckInterval {
+		return c.cert, nil
+	}
+	c.checked = time.Now()
+
+	info, err := os.Stat(c.certFile)
+	if err == nil && c.cert != nil && info.ModTime().Equal(c.modTime) {
+		return c.cert, nil
+	}
+	if err == nil {
+		var cert tls.Certificate
+		if cert, err = tls.LoadX509KeyPair(c.certFile, c.keyFile); err == nil {
+			c.cert, c.modTime = &cert, info.ModTime()
+			return c.cert, nil
+		}
+	}
+	if c.cert == nil {
+		return nil, err
+	}
+	slog.Warn("reloading TLS certificate failed; keeping the current one", "err", err)
+	return c.cert, nil
+}
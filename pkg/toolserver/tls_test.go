@@ -0,0 +1,160 @@
+package toolserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for TestClientCertificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue returns a leaf certificate signed by the CA.
+func (ca *testCA) issue(t *testing.T, tmpl *x509.Certificate) tls.Certificate {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientCertificates(t *testing.T) {
+	ca := newTestCA(t, "clients")
+	dir := t.TempDir()
+	server := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "echo"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	serverKey, _ := x509.MarshalPKCS8PrivateKey(server.PrivateKey)
+	writePEM(t, filepath.Join(dir, "tls.crt"), "CERTIFICATE", server.Certificate[0])
+	writePEM(t, filepath.Join(dir, "tls.key"), "PRIVATE KEY", serverKey)
+	writePEM(t, filepath.Join(dir, "ca.crt"), "CERTIFICATE", ca.cert.Raw)
+
+	t.Setenv("TLS_CERT_FILE", filepath.Join(dir, "tls.crt"))
+	t.Setenv("TLS_KEY_FILE", filepath.Join(dir, "tls.key"))
+	t.Setenv("TLS_CLIENT_CA_FILE", filepath.Join(dir, "ca.crt"))
+	t.Setenv("TLS_CLIENT_ROLES", "spiffe://cluster.local/ns/mcp/sa/gateway=caller,ops=admin,spiffe://cluster.local/ns/mcp/sa/gateway=reader")
+
+	var seen *Identity
+	s := New("echo")
+	Register(s, "/whoami", func(ctx context.Context, _ struct{}) (echoResponse, error) {
+		seen = IdentityFrom(ctx)
+		return echoResponse{Echo: seen.Username}, nil
+	})
+	cfg, err := s.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go s.Serve(ctx, tls.NewListener(ln, cfg))
+
+	gateway := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "gateway", Organization: []string{"mcp"}},
+		URIs:        []*url.URL{{Scheme: "spiffe", Host: "cluster.local", Path: "/ns/mcp/sa/gateway"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	stranger := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "stranger"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	forged := newTestCA(t, "other").issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "ops"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	tests := []struct {
+		name   string
+		cert   *tls.Certificate
+		path   string
+		status int
+	}{
+		{"no certificate", nil, "/whoami", http.StatusUnauthorized},
+		{"probe", nil, "/health", http.StatusOK},
+		{"mapped", &gateway, "/whoami", http.StatusOK},
+		{"no role", &stranger, "/whoami", http.StatusForbidden},
+		// Go clients only offer certificates from CAs the server accepts;
+		// others would fail the handshake.
+		{"other CA", &forged, "/whoami", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsCfg := &tls.Config{RootCAs: roots}
+			if tt.cert != nil {
+				tlsCfg.Certificates = []tls.Certificate{*tt.cert}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
+			resp, err := client.Post("https://"+ln.Addr().String()+tt.path, "application/json", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+
+	want := "spiffe://cluster.local/ns/mcp/sa/gateway"
+	if seen == nil || seen.Username != want || seen.Subject != "gateway" || strings.Join(seen.Groups, ",") != "mcp" ||
+		strings.Join(seen.Roles, ",") != "caller,reader" || !seen.HasRole("caller") {
+		t.Errorf("identity = %+v", seen)
+	}
+}
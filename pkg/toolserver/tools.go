@@ -0,0 +1,54 @@
+package toolserver
+
+import "net/http"
+
+// ToolsResponse is the body of GET /tools.
+type ToolsResponse struct {
+	Name       string     `json:"name"`
+	APIVersion string     `json:"apiVersion"`
+	Tools      []ToolInfo `json:"tools"`
+}
+
+// ToolInfo describes one registered operation and how to call it over REST.
+type ToolInfo struct {
+	Name         string         `json:"name"`
+	Description  string         `json:"description,omitempty"`
+	Path         string         `json:"path"` // versioned, e.g. /v1/hash
+	Methods      []string       `json:"methods"`
+	InputSchema  map[string]any `json:"inputSchema"`
+	OutputSchema map[string]any `json:"outputSchema"`
+	Feature      string         `json:"feature,omitempty"`     // the write feature, for operations that change the cluster
+	Disabled     bool           `json:"disabled,omitempty"`    // whether calls are refused because the feature is off
+	Permissions  []Permission   `json:"permissions,omitempty"` // the Kubernetes RBAC permissions it needs
+	Unavailable  string         `json:"unavailable,omitempty"` // why calls are refused, e.g. "missing RBAC: list pods"
+}
+
+// handleTools lists the operations registered with Register, so the operator
+// and clients can discover them instead of hand-writing tool configs.
+func (s *Server) handleTools(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, Errorf(http.StatusMethodNotAllowed, "method not allowed"))
+		return
+	}
+
+	resp := ToolsResponse{Name: s.name, APIVersion: APIVersion, Tools: make([]ToolInfo, 0, len(s.ops))}
+	for _, op := range s.ops {
+		methods := []string{http.MethodPost}
+		if op.allowGet {
+			methods = append(methods, http.MethodGet)
+		}
+		resp.Tools = append(resp.Tools, ToolInfo{
+			Name:         op.name,
+			Description:  op.description,
+			Path:         s.prefix + versionedPath(op.path),
+			Methods:      methods,
+			InputSchema:  op.input,
+			OutputSchema: op.output,
+			Feature:      op.feature,
+			Disabled:     op.writeDisabled() != nil,
+			Permissions:  op.permissions,
+			Unavailable:  op.unavailable(),
+		})
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
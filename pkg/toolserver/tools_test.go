@@ -0,0 +1,23 @@
+package toolserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTools(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newEchoServer().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tools", nil))
+
+	want := `{"name":"echo","apiVersion":"v1","tools":[{"name":"echo","path":"/v1/echo","methods":["POST"],` +
+		`"inputSchema":{"additionalProperties":false,"properties":{"message":{"type":"string"}},"type":"object"},` +
+		`"outputSchema":{"properties":{"echo":{"type":"string"}},"type":"object"}}]}`
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d", rec.Code)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Errorf("body = %s\nwant %s", got, want)
+	}
+}
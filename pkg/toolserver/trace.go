@@ -0,0 +1,265 @@
+package toolserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span kinds, as numbered by OTLP.
+const (
+	spanKindInternal = 1
+	spanKindServer   = 2
+	spanKindClient   = 3
+)
+
+// Span is one timed operation in a trace. Spans are only created while
+// tracing is enabled (see setupTracing); otherwise StartSpan returns a nil
+// *Span, whose methods do nothing, and incoming trace context is passed
+// through to outgoing requests unchanged.
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	sampled  bool
+	remote   bool // extracted from traceparent; never ended or exported
+
+	name  string
+	kind  int
+	start time.Time
+
+	mu    sync.Mutex
+	end   time.Time
+	attrs []attribute
+	err   string
+}
+
+type attribute struct {
+	key   string
+	value any
+}
+
+type spanKey struct{}
+
+// StartSpan starts a span as a child of the one in ctx, if any, and returns
+// a context carrying it. Call End when the operation finishes.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return startSpan(ctx, name, spanKindInternal)
+}
+
+func startSpan(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	t := activeTracer()
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{name: name, kind: kind, start: time.Now()}
+	rand.Read(span.spanID[:])
+	if parent := spanFromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+		span.sampled = t.sampler.sampleChild(parent)
+	} else {
+		rand.Read(span.traceID[:])
+		span.sampled = t.sampler.sampleRoot(span.traceID)
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func spanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SetAttr records an attribute. value should be a string, bool, integer or
+// float64.
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attribute{key, value})
+}
+
+// SetError marks the span failed with err's message. A nil err is ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err.Error()
+}
+
+// End finishes the span and queues it for export if it was sampled.
+func (s *Span) End() {
+	if s == nil || s.remote {
+		return
+	}
+	s.mu.Lock()
+	s.end = time.Now()
+	s.mu.Unlock()
+	if t := activeTracer(); t != nil && s.sampled {
+		t.exporter.enqueue(s)
+	}
+}
+
+// traceparent formats the W3C Trace Context header for s.
+func (s *Span) traceparent() string {
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]), flags)
+}
+
+// extractTrace returns ctx carrying the remote parent named by the
+// traceparent header in h, if it is valid.
+func extractTrace(ctx context.Context, h http.Header) context.Context {
+	parts := strings.Split(strings.TrimSpace(h.Get("traceparent")), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return ctx
+	}
+	span := &Span{remote: true}
+	traceID, err1 := hex.DecodeString(parts[1])
+	spanID, err2 := hex.DecodeString(parts[2])
+	flags, err3 := hex.DecodeString(parts[3])
+	if err1 != nil || err2 != nil || err3 != nil || len(traceID) != 16 || len(spanID) != 8 || len(flags) != 1 {
+		return ctx
+	}
+	copy(span.traceID[:], traceID)
+	copy(span.spanID[:], spanID)
+	if span.traceID == [16]byte{} || span.spanID == [8]byte{} {
+		return ctx
+	}
+	span.sampled = flags[0]&1 == 1
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// injectTrace sets traceparent on h from the span in ctx, if any.
+func injectTrace(ctx context.Context, h http.Header) {
+	if span := spanFromContext(ctx); span != nil {
+		h.Set("traceparent", span.traceparent())
+	}
+}
+
+// traceHandler starts a server span for each request, continuing the trace
+// from an incoming traceparent header.
+func traceHandler(pattern string, h http.Handler) http.Handler {
+	if probePatterns[pattern] {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := extractTrace(r.Context(), r.Header)
+		if activeTracer() == nil {
+			h.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		ctx, span := startSpan(ctx, r.Method+" "+pattern, spanKindServer)
+		span.SetAttr("http.request.method", r.Method)
+		span.SetAttr("http.route", pattern)
+		span.SetAttr("url.path", r.URL.Path)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			span.SetAttr("http.response.status_code", rec.status)
+			if rec.status >= 500 {
+				span.SetError(errors.New(http.StatusText(rec.status)))
+			}
+			span.End()
+		}()
+		h.ServeHTTP(rec, r.WithContext(ctx))
+	})
+}
+
+// statusRecorder captures the response status for the server span. It keeps
+// event streams working by passing Flush through.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
+
+// tracingTransport starts a client span for each outgoing request and
+// propagates the trace and request ID in its headers.
+type tracingTransport struct {
+	client string
+	rt     http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := startSpan(req.Context(), req.Method+" "+t.client, spanKindClient)
+	requestID := RequestID(ctx)
+	if spanFromContext(ctx) == nil && requestID == "" {
+		return t.rt.RoundTrip(req)
+	}
+	// A RoundTripper must not modify the caller's request.
+	req = req.Clone(ctx)
+	injectTrace(ctx, req.Header)
+	if requestID != "" && req.Header.Get(requestIDHeader) == "" {
+		req.Header.Set(requestIDHeader, requestID)
+	}
+	if span == nil {
+		return t.rt.RoundTrip(req)
+	}
+
+	span.SetAttr("http.request.method", req.Method)
+	span.SetAttr("server.address", req.URL.Hostname())
+	span.SetAttr("url.path", req.URL.Path)
+	span.SetAttr("peer.service", t.client)
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+	} else {
+		span.SetAttr("http.response.status_code", resp.StatusCode)
+		if resp.StatusCode >= 500 {
+			span.SetError(errors.New(resp.Status))
+		}
+	}
+	span.End()
+	return resp, err
+}
+
+// sampler decides which traces are exported, following OTEL_TRACES_SAMPLER.
+type sampler struct {
+	ratio       float64 // of root traces to sample
+	parentBased bool    // follow the parent's decision when there is one
+}
+
+func (s sampler) sampleRoot(traceID [16]byte) bool {
+	switch {
+	case s.ratio >= 1:
+		return true
+	case s.ratio <= 0:
+		return false
+	}
+	// As in the OTel TraceIdRatioBased sampler, compare the low 63 bits of
+	// the trace ID's last 8 bytes with the ratio.
+	return binary.BigEndian.Uint64(traceID[8:])>>1 < uint64(s.ratio*(1<<63))
+}
+
+func (s sampler) sampleChild(parent *Span) bool {
+	if s.parentBased || !parent.remote {
+		return parent.sampled
+	}
+	return s.sampleRoot(parent.traceID)
+}
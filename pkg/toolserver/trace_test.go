@@ -0,0 +1,128 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	var received otlpRequest
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "Bearer x" {
+			t.Errorf("export to %s with Authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer collector.Close()
+
+	exp := &exporter{
+		endpoint: collector.URL + "/v1/traces",
+		headers:  map[string]string{"Authorization": "Bearer x"},
+		resource: map[string]any{"service.name": "echo"},
+		client:   http.DefaultClient,
+		queue:    make(chan *Span, maxQueuedSpans),
+	}
+	tracer.Store(&tracerConfig{sampler: sampler{ratio: 1, parentBased: true}, exporter: exp})
+	defer tracer.Store(nil)
+
+	var downstream string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Get("traceparent")
+	}))
+	defer backend.Close()
+
+	s := New("echo")
+	Register(s, "/fetch", func(ctx context.Context, _ struct{}) (echoResponse, error) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL, nil)
+		resp, err := (&http.Client{Transport: InstrumentTransport("backend", nil)}).Do(req)
+		if err != nil {
+			return echoResponse{}, err
+		}
+		resp.Body.Close()
+		return echoResponse{Echo: "ok"}, nil
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/fetch", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := exp.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(received.ResourceSpans) != 1 {
+		t.Fatalf("received %+v", received)
+	}
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+
+	// Spans end innermost first: client, operation, server.
+	want := []struct{ name, parent string }{
+		{"GET backend", "fetch"},
+		{"fetch", "POST /fetch"},
+		{"POST /fetch", ""},
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("got %d spans, want %d: %+v", len(spans), len(want), spans)
+	}
+	ids := map[string]string{"": "00f067aa0ba902b7"}
+	for _, s := range spans {
+		ids[s.Name] = s.SpanID
+	}
+	for i, w := range want {
+		s := spans[i]
+		if s.Name != w.name || s.TraceID != traceID || s.ParentSpanID != ids[w.parent] {
+			t.Errorf("span %d = %s (trace %s, parent %s), want %s under %s", i, s.Name, s.TraceID, s.ParentSpanID, w.name, w.parent)
+		}
+	}
+	if wantHeader := "00-" + traceID + "-" + ids["GET backend"] + "-01"; downstream != wantHeader {
+		t.Errorf("downstream traceparent = %q, want %q", downstream, wantHeader)
+	}
+}
+
+func TestTracePassThrough(t *testing.T) {
+	var downstream string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Get("traceparent")
+	}))
+	defer backend.Close()
+
+	s := New("echo")
+	Register(s, "/fetch", func(ctx context.Context, _ struct{}) (echoResponse, error) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL, nil)
+		resp, err := (&http.Client{Transport: InstrumentTransport("backend", nil)}).Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return echoResponse{}, err
+	})
+
+	// With tracing off, the caller's trace context reaches the backend.
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodPost, "/fetch", strings.NewReader(""))
+	req.Header.Set("traceparent", parent)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	if downstream != parent {
+		t.Errorf("downstream traceparent = %q, want %q", downstream, parent)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	var low, high [16]byte
+	high[8] = 0xff
+	half := sampler{ratio: 0.5}
+	if !half.sampleRoot(low) || half.sampleRoot(high) {
+		t.Errorf("ratio 0.5: low ID sampled %v, high ID sampled %v", half.sampleRoot(low), half.sampleRoot(high))
+	}
+
+	remoteOff := &Span{remote: true, sampled: false}
+	if (sampler{ratio: 1, parentBased: true}).sampleChild(remoteOff) {
+		t.Error("parent-based sampler ignored an unsampled parent")
+	}
+	if !(sampler{ratio: 1}).sampleChild(remoteOff) {
+		t.Error("always_on sampler followed an unsampled remote parent")
+	}
+}
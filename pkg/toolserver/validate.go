@@ -0,0 +1,144 @@
+package toolserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError is one request field that does not match the input schema.
+type FieldError struct {
+	Field   string `json:"field"` // e.g. "timezones[1]" or "request.name"; empty for the body itself
+	Message string `json:"message"`
+}
+
+// validate checks a request body against an input schema before it is
+// decoded, so type mismatches and, if strict, misspelt fields are reported
+// by name rather than silently ignored or collapsed into "invalid request
+// body".
+func validate(schema map[string]any, data []byte, strict bool) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return errInvalidBody
+	}
+
+	if dec.More() {
+		return BadRequest("invalid request body: data after the JSON value")
+	}
+
+	var fields []FieldError
+	checkValue(schema, v, "", strict, &fields)
+	if len(fields) == 0 {
+		return nil
+	}
+	slices.SortFunc(fields, func(a, b FieldError) int { return strings.Compare(a.Field, b.Field) })
+	msgs := make([]string, len(fields))
+	for i, f := range fields {
+		msgs[i] = f.Message
+		if f.Field != "" {
+			msgs[i] = f.Field + ": " + f.Message
+		}
+	}
+	return &Error{
+		Status:  http.StatusBadRequest,
+		Code:    CodeValidationFailed,
+		Message: "invalid request body: " + strings.Join(msgs, "; "),
+		Fields:  fields,
+	}
+}
+
+func checkValue(schema map[string]any, v any, path string, strict bool, fields *[]FieldError) {
+	fail := func(format string, args ...any) {
+		*fields = append(*fields, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	want, _ := schema["type"].(string)
+	// encoding/json decodes null into any field as its zero value.
+	if want == "" || v == nil {
+		return
+	}
+
+	var got string
+	switch v := v.(type) {
+	case string:
+		got = "string"
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				fail("expected an RFC 3339 date-time")
+				return
+			}
+		}
+	case bool:
+		got = "boolean"
+	case json.Number:
+		got = "number"
+		if want == "integer" {
+			if _, err := strconv.ParseInt(v.String(), 10, 64); err != nil {
+				fail("expected an integer, got %s", v)
+				return
+			}
+			got = "integer"
+		}
+	case []any:
+		got = "array"
+		if want == got {
+			items, _ := schema["items"].(map[string]any)
+			for i, item := range v {
+				checkValue(items, item, fmt.Sprintf("%s[%d]", path, i), strict, fields)
+			}
+		}
+	case map[string]any:
+		got = "object"
+		if want == got {
+			checkObject(schema, v, path, strict, fields)
+		}
+	}
+	if got != want {
+		fail("expected %s, got %s", want, got)
+	}
+}
+
+func checkObject(schema map[string]any, obj map[string]any, path string, strict bool, fields *[]FieldError) {
+	properties, _ := schema["properties"].(map[string]any)
+	extra, _ := schema["additionalProperties"].(map[string]any)
+	closed := strict && schema["additionalProperties"] == false
+
+	for key, v := range obj {
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		if prop := property(properties, key); prop != nil {
+			checkValue(prop, v, fieldPath, strict, fields)
+			continue
+		}
+		switch {
+		case extra != nil:
+			checkValue(extra, v, fieldPath, strict, fields)
+		case closed:
+			*fields = append(*fields, FieldError{Field: fieldPath, Message: "unknown field"})
+		}
+	}
+}
+
+// property finds the schema of key, matching case-insensitively as
+// encoding/json does.
+func property(properties map[string]any, key string) map[string]any {
+	if prop, ok := properties[key].(map[string]any); ok {
+		return prop
+	}
+	for name, prop := range properties {
+		if strings.EqualFold(name, key) {
+			prop, _ := prop.(map[string]any)
+			return prop
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package toolserver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	type target struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	type request struct {
+		Name    string            `json:"name"`
+		Count   int               `json:"count"`
+		Ratio   float64           `json:"ratio"`
+		Tags    []string          `json:"tags"`
+		Labels  map[string]string `json:"labels"`
+		Target  *target           `json:"target"`
+		Since   time.Time         `json:"since"`
+		Enabled bool              `json:"enabled"`
+	}
+	schema := inputSchema(reflect.TypeFor[request]())
+
+	tests := []struct {
+		name string
+		body string
+		want []FieldError
+	}{
+		{"valid", `{"name":"a","count":2,"ratio":0.5,"tags":["x"],"labels":{"k":"v"},"target":{"host":"h","port":1},"since":"2024-01-01T00:00:00Z","enabled":true}`, nil},
+		{"nulls", `{"name":null,"tags":null,"target":null}`, nil},
+		{"case-insensitive name", `{"Name":"a"}`, nil},
+		{"wrong type", `{"name":1}`, []FieldError{{"name", "expected string, got number"}}},
+		{"fractional integer", `{"count":1.5}`, []FieldError{{"count", "expected an integer, got 1.5"}}},
+		{"array item", `{"tags":["x",2]}`, []FieldError{{"tags[1]", "expected string, got number"}}},
+		{"map value", `{"labels":{"k":true}}`, []FieldError{{"labels.k", "expected string, got boolean"}}},
+		{"nested field", `{"target":{"host":"h","port":"80"}}`, []FieldError{{"target.port", "expected integer, got string"}}},
+		{"date-time", `{"since":"yesterday"}`, []FieldError{{"since", "expected an RFC 3339 date-time"}}},
+		{"unknown fields", `{"nmae":"a","target":{"hots":"h"}}`, []FieldError{{"nmae", "unknown field"}, {"target.hots", "unknown field"}}},
+		{"not an object", `["a"]`, []FieldError{{"", "expected object, got array"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(schema, []byte(tt.body), true)
+			var e *Error
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("validate = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.As(err, &e) || e.Status != 400 || !reflect.DeepEqual(e.Fields, tt.want) {
+				t.Errorf("validate = %#v, want fields %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := validate(schema, []byte(`{`), true); err != errInvalidBody {
+		t.Errorf("malformed body: validate = %v, want errInvalidBody", err)
+	}
+}
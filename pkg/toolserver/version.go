@@ -0,0 +1,66 @@
+package toolserver
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+// APIVersion is the version of the endpoints' request and response shapes.
+// Endpoints are served under /v1; a breaking change will add /v2 alongside
+// rather than change them.
+const APIVersion = "v1"
+
+// apiVersionHeader names the version a response follows, and lets clients
+// require one: a request for a version the tool does not serve fails with
+// 406 Not Acceptable.
+const apiVersionHeader = "API-Version"
+
+// apiVersions are the API versions a client may ask for.
+var apiVersions = []string{APIVersion}
+
+// versionedPath returns the path pattern is served under: /v1/pattern, or
+// pattern itself for the probes and /metrics, which Kubernetes and
+// Prometheus poll at fixed paths, and for /openapi.json, which describes
+// the versioned paths.
+func versionedPath(pattern string) string {
+	if probePatterns[pattern] || pattern == openAPIPath {
+		return pattern
+	}
+	return "/" + APIVersion + pattern
+}
+
+// legacyPaths returns $LEGACY_PATHS, default true: whether endpoints are
+// still served at their unversioned paths too.
+func legacyPaths() bool {
+	return config.Bool("LEGACY_PATHS", true)
+}
+
+// negotiateVersion wraps a handler to refuse requests for API versions the
+// tool does not serve and to name the version it does in responses.
+func negotiateVersion(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get(apiVersionHeader); v != "" && !slices.Contains(apiVersions, strings.ToLower(v)) {
+			WriteError(w, Errorf(http.StatusNotAcceptable, "unsupported API version %q: supported versions are %s",
+				v, strings.Join(apiVersions, ", ")))
+			return
+		}
+		w.Header().Set(apiVersionHeader, APIVersion)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// deprecated wraps the handler serving an unversioned path to mark its
+// responses with a Deprecation header and link to the versioned path, and
+// counts its requests so operators can tell when clients have moved.
+func deprecated(pattern string, h http.Handler) http.Handler {
+	link := "<" + versionedPath(pattern) + `>; rel="successor-version"`
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Add("Link", link)
+		deprecatedRequests.WithLabelValues(pattern).Inc()
+		h.ServeHTTP(w, r)
+	})
+}
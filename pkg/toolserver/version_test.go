@@ -0,0 +1,58 @@
+package toolserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVersionedPaths(t *testing.T) {
+	s := newEchoServer()
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		version    string
+		status     int
+		deprecated bool
+	}{
+		{"versioned", http.MethodPost, "/v1/echo", "", http.StatusOK, false},
+		{"legacy alias", http.MethodPost, "/echo", "", http.StatusOK, true},
+		{"requested version", http.MethodPost, "/v1/echo", "V1", http.StatusOK, false},
+		{"unsupported version", http.MethodPost, "/v1/echo", "v2", http.StatusNotAcceptable, false},
+		{"listing", http.MethodGet, "/v1/tools", "", http.StatusOK, false},
+		{"probe", http.MethodGet, "/livez", "", http.StatusOK, false},
+		{"versioned probe", http.MethodGet, "/v1/livez", "", http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"message":"hi"}`))
+			if tt.version != "" {
+				req.Header.Set("API-Version", tt.version)
+			}
+			s.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d %s, want %d", rec.Code, rec.Body, tt.status)
+			}
+			if got := rec.Header().Get("Deprecation") != ""; got != tt.deprecated {
+				t.Errorf("Deprecation = %q", rec.Header().Get("Deprecation"))
+			}
+			if tt.deprecated && rec.Header().Get("Link") != `</v1/echo>; rel="successor-version"` {
+				t.Errorf("Link = %q", rec.Header().Get("Link"))
+			}
+			if tt.status == http.StatusOK && rec.Header().Get("API-Version") != "v1" {
+				t.Errorf("API-Version = %q", rec.Header().Get("API-Version"))
+			}
+		})
+	}
+
+	t.Setenv("LEGACY_PATHS", "false")
+	s = newEchoServer()
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"message":"hi"}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unversioned path with LEGACY_PATHS=false = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,151 @@
+package toolserver
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+const (
+	// warmupTimeout bounds each attempt at a warm-up.
+	warmupTimeout = 30 * time.Second
+
+	// warmupMinBackoff and warmupMaxBackoff bound the wait between attempts
+	// at a failing warm-up, which doubles from one to the next.
+	warmupMinBackoff = time.Second
+	warmupMaxBackoff = 30 * time.Second
+)
+
+// processStart approximates when the process started, for the startup time
+// /readyz reports.
+var processStart = time.Now()
+
+// warmup prepares a dependency, such as a client or a parsed schema, ahead
+// of the first call that needs it, with the outcome for /readyz.
+type warmup struct {
+	name   string
+	fn     func(context.Context) error
+	always bool // whether it runs without $WARMUP, like the RBAC self-check
+
+	mu       sync.Mutex
+	state    string // "deferred", "running", "retrying" or "done"
+	attempts int
+	took     time.Duration // of the successful attempt
+	lastErr  string
+	doneAt   time.Time
+}
+
+// AddWarmup adds a warm-up: fn builds what the tool otherwise builds on
+// first use, such as its Kubernetes client or the cluster's OpenAPI models.
+// Tools start without it, so they listen at once and still come up while
+// the API server is unavailable. With $WARMUP set, Serve runs the warm-ups
+// in the background once it listens, retrying each with backoff until it
+// succeeds or the server shuts down, so the first calls need not wait.
+// /readyz reports each warm-up's state and timings; warm-ups do not make
+// the tool unready. Add warm-ups before Run.
+func (s *Server) AddWarmup(name string, fn func(context.Context) error) {
+	s.warmups = append(s.warmups, &warmup{name: name, fn: fn, state: "deferred"})
+}
+
+// warmUp starts the warm-ups of s and the servers mounted on it: all of
+// them, or only those that always run, and the RBAC rechecks.
+func (s *Server) warmUp(ctx context.Context, all bool) {
+	for _, t := range s.servers() {
+		for _, w := range t.warmups {
+			if all || w.always {
+				go w.run(ctx, t.name)
+			}
+		}
+		if t.rbac != nil {
+			go t.recheckRBAC(ctx)
+		}
+	}
+}
+
+// run calls the warm-up until it succeeds or ctx is done.
+func (w *warmup) run(ctx context.Context, tool string) {
+	backoff := warmupMinBackoff
+	for {
+		w.mu.Lock()
+		w.state = "running"
+		w.attempts++
+		w.mu.Unlock()
+
+		attemptCtx, cancel := context.WithTimeout(ctx, warmupTimeout)
+		start := time.Now()
+		err := w.fn(attemptCtx)
+		took := time.Since(start)
+		cancel()
+
+		w.mu.Lock()
+		if err == nil {
+			w.state, w.took, w.doneAt = "done", took, time.Now()
+			attempts := w.attempts
+			w.mu.Unlock()
+			slog.Info("warmed up", "tool", tool, "warmup", w.name, "duration", took.String(), "attempts", attempts)
+			return
+		}
+		w.state, w.lastErr = "retrying", err.Error()
+		w.mu.Unlock()
+		slog.Warn("warm-up failed; retrying", "tool", tool, "warmup", w.name, "err", err, "backoff", backoff.String())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, warmupMaxBackoff)
+	}
+}
+
+// warmupEnabled reports whether Serve runs the warm-ups.
+func warmupEnabled() bool {
+	return config.Bool("WARMUP", false)
+}
+
+// InitStatus is the state of a warm-up in /readyz: "deferred" to first use
+// unless $WARMUP is set or it is the RBAC self-check, then "running", "retrying" after a failed attempt,
+// or "done"; the attempts made; how long the successful one took, and when
+// it finished; and the latest error.
+type InitStatus struct {
+	State      string     `json:"state"`
+	Attempts   int        `json:"attempts,omitempty"`
+	DurationMS float64    `json:"duration_ms,omitempty"`
+	DoneAt     *time.Time `json:"done_at,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// initStatus returns the warm-ups of s and the servers mounted on it, those
+// of a mounted server named with its prefix, or nil if there are none.
+func (s *Server) initStatus() map[string]InitStatus {
+	var status map[string]InitStatus
+	for _, t := range s.servers() {
+		prefix := ""
+		if t != s {
+			prefix = strings.TrimPrefix(t.prefix, "/") + "/"
+		}
+		for _, w := range t.warmups {
+			if status == nil {
+				status = make(map[string]InitStatus)
+			}
+			status[prefix+w.name] = w.status()
+		}
+	}
+	return status
+}
+
+func (w *warmup) status() InitStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	st := InitStatus{State: w.state, Attempts: w.attempts, LastError: w.lastErr}
+	if w.state == "done" {
+		st.DurationMS = float64(w.took.Microseconds()) / 1000
+		t := w.doneAt
+		st.DoneAt = &t
+	}
+	return st
+}
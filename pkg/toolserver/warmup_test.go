@@ -0,0 +1,89 @@
+package toolserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWarmupDeferred(t *testing.T) {
+	s := newEchoServer()
+	var calls atomic.Int32
+	s.AddWarmup("kubernetes", func(ctx context.Context) error { calls.Add(1); return nil })
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var resp ReadinessResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if rec.Code != http.StatusOK || resp.Init["kubernetes"].State != "deferred" {
+		t.Errorf("/readyz = %d %s, want ready with the warm-up deferred", rec.Code, rec.Body)
+	}
+	if calls.Load() != 0 {
+		t.Error("the warm-up ran without WARMUP")
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	t.Setenv("WARMUP", "true")
+
+	// The API server is unavailable at boot: the first attempt fails, and
+	// the tool serves, and is ready, while the warm-up retries.
+	var attempts atomic.Int32
+	s := newEchoServer()
+	s.AddWarmup("kubernetes", func(ctx context.Context) error {
+		if attempts.Add(1) == 1 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	explain := newEchoServer()
+	explain.AddWarmup("openapi", func(ctx context.Context) error { return nil })
+	s.Mount("/explain", explain)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go s.Serve(ctx, ln)
+
+	ready := func() ReadinessResponse {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/readyz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("/readyz = %d while warming up", resp.StatusCode)
+		}
+		var r ReadinessResponse
+		json.NewDecoder(resp.Body).Decode(&r)
+		return r
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var resp ReadinessResponse
+	for resp = ready(); resp.Init["kubernetes"].State != "done"; resp = ready() {
+		if time.Now().After(deadline) {
+			t.Fatalf("warm-up not done: %+v", resp.Init)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	kube := resp.Init["kubernetes"]
+	if kube.Attempts != 2 || kube.LastError != "connection refused" || kube.DoneAt == nil {
+		t.Errorf("kubernetes warm-up = %+v, want done on the second attempt", kube)
+	}
+	if resp.StartupMS <= 0 {
+		t.Errorf("startup_ms = %v, want the time to start serving", resp.StartupMS)
+	}
+	if st := resp.Init["explain/openapi"]; st.State != "done" || st.Attempts != 1 {
+		t.Errorf("mounted warm-up = %+v, want done", st)
+	}
+}
@@ -0,0 +1,579 @@
+package toolserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atippey/kube-mcp/pkg/config"
+)
+
+const (
+	// wsSubprotocol is the WebSocket subprotocol of MCP, offered by clients
+	// in Sec-WebSocket-Protocol.
+	wsSubprotocol = "mcp"
+	wsGUID        = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	defaultWSPingInterval = 30 * time.Second // default of $WS_PING_INTERVAL
+	defaultWSIdleTimeout  = 5 * time.Minute  // default of $WS_IDLE_TIMEOUT
+)
+
+// WebSocket opcodes (RFC 6455, section 5.2).
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// WebSocket close codes (RFC 6455, section 7.4.1).
+const (
+	wsNormalClosure   = 1000
+	wsGoingAway       = 1001
+	wsProtocolError   = 1002
+	wsUnsupportedData = 1003
+	wsMessageTooBig   = 1009
+)
+
+// errWSClosed is returned by readMessage once the connection is closed.
+var errWSClosed = errors.New("websocket closed")
+
+// handleWebSocket serves MCP over a WebSocket on /ws, for interactive
+// clients that follow logs or watches: each text message is a JSON-RPC
+// message or batch, in either direction. Unlike /mcp, the server can send
+// notifications while a call runs (see Notify), and the client can cancel a
+// call with notifications/cancelled. The connection is its own session: no
+// Mcp-Session-Id is needed.
+//
+// The server pings the client every $WS_PING_INTERVAL (default 30s) and
+// drops the connection if nothing, not even a pong, arrives for two
+// intervals. A connection without calls in flight that sends no message for
+// $WS_IDLE_TIMEOUT (default 5m) is closed.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !s.allowedOrigin(r) {
+		WriteError(w, Errorf(http.StatusForbidden, "origin not allowed"))
+		return
+	}
+	c, err := upgradeWebSocket(w, r, s.maxBody)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	s.websockets.add(c)
+	defer s.websockets.remove(c)
+	websocketSessions.Inc()
+	defer websocketSessions.Dec()
+
+	ctx, cancel := s.detach(r.Context())
+	defer cancel()
+	sess := &wsSession{s: s, c: c, state: newSession(callerKey(ctx)), calls: make(map[string]context.CancelFunc)}
+	sess.serve(ctx, config.Duration("WS_PING_INTERVAL", defaultWSPingInterval), config.Duration("WS_IDLE_TIMEOUT", defaultWSIdleTimeout))
+}
+
+// wsSession is an MCP session over a WebSocket.
+type wsSession struct {
+	s     *Server
+	c     *wsConn
+	state *session // not in the store: it ends with the connection
+
+	mu       sync.Mutex
+	calls    map[string]context.CancelFunc // by request ID, to cancel them
+	inflight sync.WaitGroup
+	lastRead time.Time // of the last message from the client
+}
+
+// serve reads the client's messages until the connection closes, times out
+// or ctx is done, then waits for the calls in flight.
+func (sess *wsSession) serve(ctx context.Context, pingInterval, idleTimeout time.Duration) {
+	defer sess.c.close(wsNormalClosure, "")
+	defer sess.inflight.Wait()
+	defer sess.cancelAll()
+	stop := context.AfterFunc(ctx, func() { sess.c.close(wsGoingAway, "server shutting down") })
+	defer stop()
+
+	sess.touch()
+	done := make(chan struct{})
+	defer close(done)
+	if pingInterval > 0 {
+		go sess.keepalive(done, pingInterval, idleTimeout)
+	}
+	sess.c.readTimeout = 2 * pingInterval
+	for {
+		op, data, err := sess.c.readMessage()
+		if err != nil {
+			var ne net.Error
+			switch {
+			case errors.Is(err, errWSClosed), errors.Is(err, io.EOF), errors.Is(err, net.ErrClosed):
+			case errors.As(err, &ne) && ne.Timeout():
+				sess.c.close(wsGoingAway, "keepalive timed out")
+			default:
+				slog.Debug("websocket read failed", "err", err)
+				sess.c.close(closeCodeFor(err), err.Error())
+			}
+			return
+		}
+		if op != wsText {
+			sess.c.close(wsUnsupportedData, "messages must be text")
+			return
+		}
+		sess.touch()
+		sess.handle(ctx, data)
+	}
+}
+
+func (sess *wsSession) touch() {
+	sess.mu.Lock()
+	sess.lastRead = time.Now()
+	sess.mu.Unlock()
+}
+
+// keepalive pings the client every interval and closes an idle connection,
+// until done is closed.
+func (sess *wsSession) keepalive(done <-chan struct{}, interval, idleTimeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		sess.mu.Lock()
+		idle := idleTimeout > 0 && len(sess.calls) == 0 && time.Since(sess.lastRead) >= idleTimeout
+		sess.mu.Unlock()
+		if idle {
+			sess.c.close(wsNormalClosure, "idle timeout")
+			return
+		}
+		if err := sess.c.writeFrame(wsPing, nil); err != nil {
+			return
+		}
+	}
+}
+
+// handle dispatches one client message or batch. Calls run concurrently;
+// the responses to a batch are sent together when all are done.
+func (sess *wsSession) handle(ctx context.Context, data []byte) {
+	data = bytes.TrimSpace(data)
+	batch := len(data) > 0 && data[0] == '['
+	raw := []json.RawMessage{data}
+	if batch {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			sess.send(&rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: "parse error"}})
+			return
+		}
+	}
+
+	var (
+		calls     []rpcMessage
+		responses []*rpcResponse
+	)
+	for _, m := range raw {
+		msg, errResp := decodeMessage(m)
+		switch {
+		case errResp != nil:
+			responses = append(responses, errResp)
+		case msg.Method == "notifications/cancelled":
+			sess.cancel(msg.Params)
+		case msg.isCall():
+			calls = append(calls, msg)
+		}
+	}
+	if !batch {
+		for _, resp := range responses {
+			sess.send(resp)
+		}
+		for _, msg := range calls {
+			sess.run(ctx, msg, sess.send)
+		}
+		return
+	}
+	if len(calls) == 0 {
+		if len(responses) > 0 {
+			sess.send(responses)
+		}
+		return
+	}
+	var (
+		mu      sync.Mutex
+		pending = len(calls)
+	)
+	for _, msg := range calls {
+		sess.run(ctx, msg, func(resp any) {
+			mu.Lock()
+			defer mu.Unlock()
+			responses = append(responses, resp.(*rpcResponse))
+			if pending--; pending == 0 {
+				sess.send(responses)
+			}
+		})
+	}
+}
+
+// run handles a call in its own goroutine, with a context the client can
+// cancel and that carries the session for Notify and SessionState, and
+// passes the response
+// to reply.
+func (sess *wsSession) run(ctx context.Context, msg rpcMessage, reply func(any)) {
+	ctx, cancel := context.WithCancel(withNotifier(withSession(ctx, sess.state), sess.notify))
+	id := string(msg.ID)
+	sess.mu.Lock()
+	sess.calls[id] = cancel
+	sess.mu.Unlock()
+	sess.inflight.Add(1)
+	go func() {
+		defer sess.inflight.Done()
+		defer func() {
+			sess.mu.Lock()
+			delete(sess.calls, id)
+			sess.mu.Unlock()
+			cancel()
+		}()
+		reply(sess.s.handleMessage(ctx, msg))
+	}()
+}
+
+// cancel cancels the call named by a notifications/cancelled message.
+func (sess *wsSession) cancel(params json.RawMessage) {
+	var p struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if json.Unmarshal(params, &p) != nil {
+		return
+	}
+	sess.mu.Lock()
+	cancel := sess.calls[string(p.RequestID)]
+	sess.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+func (sess *wsSession) cancelAll() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	for _, cancel := range sess.calls {
+		cancel()
+	}
+}
+
+// send writes v as a text message.
+func (sess *wsSession) send(v any) {
+	data, _ := json.Marshal(v)
+	sess.c.writeFrame(wsText, data)
+}
+
+// notify sends a JSON-RPC notification.
+func (sess *wsSession) notify(method string, params any) error {
+	data, err := json.Marshal(struct {
+		JSONRPC string `json:"jsonrpc"`
+		Method  string `json:"method"`
+		Params  any    `json:"params,omitempty"`
+	}{"2.0", method, params})
+	if err != nil {
+		return err
+	}
+	return sess.c.writeFrame(wsText, data)
+}
+
+type notifierKey struct{}
+
+// withNotifier returns ctx carrying the function that sends notifications
+// to the client of the call.
+func withNotifier(ctx context.Context, notify func(method string, params any) error) context.Context {
+	return context.WithValue(ctx, notifierKey{}, notify)
+}
+
+// errNoNotifications is returned by Notify for calls over transports that
+// cannot carry notifications.
+var errNoNotifications = NewError(CodeUnprocessable, "notifications need the WebSocket transport")
+
+// Notify sends the JSON-RPC notification method with params to the MCP
+// client of the call ctx belongs to, e.g. notifications/message with a log
+// line while following logs. Only calls over the WebSocket transport can
+// send them; check with CanNotify. Notify fails once the connection is
+// closed.
+func Notify(ctx context.Context, method string, params any) error {
+	notify, ok := ctx.Value(notifierKey{}).(func(string, any) error)
+	if !ok {
+		return errNoNotifications
+	}
+	return notify(method, params)
+}
+
+// CanNotify reports whether the call ctx belongs to can send notifications
+// with Notify.
+func CanNotify(ctx context.Context) bool {
+	_, ok := ctx.Value(notifierKey{}).(func(string, any) error)
+	return ok
+}
+
+// wsConn is the server end of a WebSocket connection. Writes are safe for
+// concurrent use; reads are not.
+type wsConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	maxMessage  int64
+	readTimeout time.Duration // for each frame, pongs included; 0 for none
+
+	wmu    sync.Mutex
+	closed bool
+}
+
+// upgradeWebSocket completes the opening handshake of a WebSocket
+// connection, choosing the mcp subprotocol if the client offers it.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, maxMessage int64) (*wsConn, error) {
+	if r.Method != http.MethodGet {
+		return nil, Errorf(http.StatusMethodNotAllowed, "method not allowed")
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, NewError(CodeInvalidArgument, "%s needs a WebSocket upgrade", r.URL.Path)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, NewError(CodeInvalidArgument, "unsupported WebSocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, NewError(CodeInvalidArgument, "missing Sec-WebSocket-Key")
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, NewError(CodeInternal, "websocket upgrade: %v", err)
+	}
+	// Clear the deadlines the HTTP server set for the request.
+	conn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n")
+	if headerContains(r.Header, "Sec-WebSocket-Protocol", wsSubprotocol) {
+		b.WriteString("Sec-WebSocket-Protocol: " + wsSubprotocol + "\r\n")
+	}
+	b.WriteString("\r\n")
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, br: brw.Reader, maxMessage: maxMessage}, nil
+}
+
+// headerContains reports whether the comma-separated values of the header
+// include token, ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wsError is a violation of the protocol by the client, closed with code.
+type wsError struct {
+	code    uint16
+	message string
+}
+
+func (e *wsError) Error() string { return e.message }
+
+func closeCodeFor(err error) uint16 {
+	var e *wsError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return wsProtocolError
+}
+
+// readMessage returns the next data message, reassembled from its
+// fragments. It answers pings, and ends with errWSClosed when the client
+// closes the connection.
+func (c *wsConn) readMessage() (opcode byte, data []byte, err error) {
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			code := uint16(wsNormalClosure)
+			if len(payload) >= 2 {
+				code = binary.BigEndian.Uint16(payload)
+			}
+			c.close(code, "")
+			return 0, nil, errWSClosed
+		case wsText, wsBinary:
+			if opcode != 0 {
+				return 0, nil, &wsError{wsProtocolError, "expected a continuation frame"}
+			}
+			opcode = op
+		case wsContinuation:
+			if opcode == 0 {
+				return 0, nil, &wsError{wsProtocolError, "unexpected continuation frame"}
+			}
+		default:
+			return 0, nil, &wsError{wsProtocolError, fmt.Sprintf("unknown opcode %d", op)}
+		}
+		if int64(len(data)+len(payload)) > c.maxMessage {
+			return 0, nil, &wsError{wsMessageTooBig, fmt.Sprintf("messages must be at most %d bytes", c.maxMessage)}
+		}
+		data = append(data, payload...)
+		if fin {
+			return opcode, data, nil
+		}
+	}
+}
+
+// readFrame reads one frame and unmasks its payload.
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	if c.readTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode = head[0]&0x80 != 0, head[0]&0x0f
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, &wsError{wsProtocolError, "reserved bits set"}
+	}
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, &wsError{wsProtocolError, "client frames must be masked"}
+	}
+	n := int64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if opcode >= wsClose && (n > 125 || !fin) {
+		return false, 0, nil, &wsError{wsProtocolError, "invalid control frame"}
+	}
+	if n < 0 || n > c.maxMessage {
+		return false, 0, nil, &wsError{wsMessageTooBig, fmt.Sprintf("messages must be at most %d bytes", c.maxMessage)}
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// writeFrame writes a single unmasked frame.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return errWSClosed
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+func (c *wsConn) writeFrameLocked(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|opcode)
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, payload...)
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// close sends a close frame with code and reason, unless one was sent
+// already, and closes the connection.
+func (c *wsConn) close(code uint16, reason string) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	c.writeFrameLocked(wsClose, append(binary.BigEndian.AppendUint16(nil, code), reason...))
+	c.conn.Close()
+}
+
+// wsConns are a server's open WebSocket connections, closed when it shuts
+// down.
+type wsConns struct {
+	mu    sync.Mutex
+	conns map[*wsConn]struct{}
+}
+
+func (cs *wsConns) add(c *wsConn) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.conns == nil {
+		cs.conns = make(map[*wsConn]struct{})
+	}
+	cs.conns[c] = struct{}{}
+}
+
+func (cs *wsConns) remove(c *wsConn) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	delete(cs.conns, c)
+}
+
+// closeAll tells the clients the server is going away and closes their
+// connections.
+func (cs *wsConns) closeAll() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	for c := range cs.conns {
+		c.close(wsGoingAway, "server shutting down")
+	}
+}
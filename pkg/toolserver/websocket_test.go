@@ -0,0 +1,178 @@
+package toolserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsClient is the client end of a WebSocket connection for tests.
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, url string) (*wsClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	io.WriteString(conn, "GET /v1/ws HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Protocol: mcp\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wsClient{t: t, conn: conn, br: br}, resp
+}
+
+func (c *wsClient) send(opcode byte, payload string) {
+	frame := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	default:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	}
+	var mask [4]byte
+	rand.Read(mask[:])
+	frame = append(frame, mask[:]...)
+	for i := range len(payload) {
+		frame = append(frame, payload[i]^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *wsClient) read() (opcode byte, payload string) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	n := int(head[1] & 0x7f)
+	if n == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(c.br, data); err != nil {
+		c.t.Fatal(err)
+	}
+	return head[0] & 0x0f, string(data)
+}
+
+// readText returns the next text message, skipping pings.
+func (c *wsClient) readText() string {
+	for {
+		op, payload := c.read()
+		if op == wsText {
+			return payload
+		}
+		if op != wsPing {
+			c.t.Fatalf("got opcode %d (%q), want a text message", op, payload)
+		}
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	s := newEchoServer()
+	Register(s, "/follow", func(ctx context.Context, req echoRequest) (echoResponse, error) {
+		if err := Notify(ctx, "notifications/message", map[string]string{"data": "line 1"}); err != nil {
+			return echoResponse{}, err
+		}
+		if req.Message == "forever" {
+			<-ctx.Done()
+			return echoResponse{}, ctx.Err()
+		}
+		return echoResponse{Echo: "done"}, nil
+	})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	c, resp := dialWebSocket(t, srv.URL)
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" ||
+		resp.Header.Get("Sec-WebSocket-Protocol") != "mcp" {
+		t.Fatalf("handshake = %d %v", resp.StatusCode, resp.Header)
+	}
+
+	c.send(wsText, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hi"}}}`)
+	if got := c.readText(); !strings.Contains(got, `"id":1`) || !strings.Contains(got, `\"echo\":\"hi\"`) {
+		t.Errorf("echo = %s", got)
+	}
+
+	c.send(wsText, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"follow","arguments":{}}}`)
+	if got := c.readText(); got != `{"jsonrpc":"2.0","method":"notifications/message","params":{"data":"line 1"}}` {
+		t.Errorf("first message = %s, want the notification", got)
+	}
+	if got := c.readText(); !strings.Contains(got, `"id":2`) || !strings.Contains(got, "done") {
+		t.Errorf("second message = %s, want the result", got)
+	}
+
+	c.send(wsText, `{"jsonrpc":"2.0","id":"f","method":"tools/call","params":{"name":"follow","arguments":{"message":"forever"}}}`)
+	c.readText() // the notification
+	c.send(wsText, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"f"}}`)
+	var result struct {
+		ID     string
+		Result callResult
+	}
+	if err := json.Unmarshal([]byte(c.readText()), &result); err != nil || result.ID != "f" || !result.Result.IsError {
+		t.Errorf("cancelled call = %+v, %v", result, err)
+	}
+
+	c.send(wsPing, "hello")
+	if op, payload := c.read(); op != wsPong || payload != "hello" {
+		t.Errorf("ping answered with %d %q, want the pong", op, payload)
+	}
+	c.send(wsClose, "\x03\xe8")
+	if op, payload := c.read(); op != wsClose || binary.BigEndian.Uint16([]byte(payload)) != wsNormalClosure {
+		t.Errorf("close answered with %d %q", op, payload)
+	}
+}
+
+func TestWebSocketKeepalive(t *testing.T) {
+	t.Setenv("WS_PING_INTERVAL", "20ms")
+	t.Setenv("WS_IDLE_TIMEOUT", "100ms")
+	srv := httptest.NewServer(newEchoServer())
+	defer srv.Close()
+
+	c, _ := dialWebSocket(t, srv.URL)
+	pings := 0
+	for {
+		op, payload := c.read()
+		if op == wsPing {
+			pings++
+			c.send(wsPong, payload)
+			continue
+		}
+		if op != wsClose || binary.BigEndian.Uint16([]byte(payload)) != wsNormalClosure || payload[2:] != "idle timeout" {
+			t.Fatalf("got opcode %d %q, want the idle timeout close", op, payload)
+		}
+		break
+	}
+	if pings == 0 {
+		t.Error("the server sent no pings")
+	}
+
+	rec := httptest.NewRecorder()
+	newEchoServer().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/ws", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /v1/ws without an upgrade = %d, want 400", rec.Code)
+	}
+}
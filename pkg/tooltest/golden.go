@@ -0,0 +1,51 @@
+package tooltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write golden files from the responses tests get")
+
+// Golden compares got with testdata/NAME.golden, or writes it there when the
+// tests run with -update. JSON is compared, and written, with its keys
+// sorted and indented, so field order and spacing do not matter.
+func Golden(t testing.TB, name string, got []byte) {
+	t.Helper()
+	got = normalize(got)
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("%s does not exist; run the tests with -update to write it", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want = normalize(want); !bytes.Equal(got, want) {
+		t.Errorf("response does not match %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// normalize returns JSON with its keys sorted and indented, and anything
+// else as it is.
+func normalize(data []byte) []byte {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return data
+	}
+	out, _ := json.MarshalIndent(v, "", "  ")
+	return append(out, '\n')
+}
@@ -0,0 +1,32 @@
+package tooltest
+
+import (
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// Kube is the fake cluster of a test.
+type Kube struct {
+	Clientset *fake.Clientset
+	Dynamic   *dynamicfake.FakeDynamicClient
+}
+
+// FakeKube makes c, a tool's client, call fake clientsets holding objects
+// until the test ends, in place of a cluster. The typed and dynamic fakes
+// each start with objects but keep their own copies: a handler's changes
+// through one are not seen through the other. Use the fakes' reactors to
+// make calls fail.
+func FakeKube(t testing.TB, c *kube.Client, objects ...runtime.Object) *Kube {
+	k := &Kube{
+		Clientset: fake.NewClientset(objects...),
+		Dynamic:   dynamicfake.NewSimpleDynamicClient(scheme.Scheme, objects...),
+	}
+	c.SetClients(k.Clientset, k.Dynamic)
+	t.Cleanup(func() { c.SetClients(nil, nil) })
+	return k
+}
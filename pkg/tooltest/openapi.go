@@ -0,0 +1,28 @@
+package tooltest
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"io"
+	"testing"
+)
+
+//go:embed testdata/openapi-v1.27.json.gz
+var openAPIv2 []byte
+
+// OpenAPIv2 returns the OpenAPI v2 document a Kubernetes 1.27 API server
+// serves at /openapi/v2, as JSON, for tests of tools that read the
+// cluster's schema.
+func OpenAPIv2(tb testing.TB) []byte {
+	tb.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(openAPIv2))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,47 @@
+package tooltest
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Registry starts an in-memory OCI registry for the test and returns its
+// host, such as 127.0.0.1:41234. go-containerregistry talks to it over
+// plain HTTP, as it does to any loopback registry.
+func Registry(t testing.TB) string {
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+// PushImage pushes img, or a random image with one small layer if img is
+// nil, to ref, such as REGISTRY/team/app:v1, and returns its digest.
+func PushImage(t testing.TB, ref string, img v1.Image) v1.Hash {
+	t.Helper()
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img == nil {
+		if img, err = random.Image(256, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := remote.Write(r, img); err != nil {
+		t.Fatalf("pushing %s: %v", ref, err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return digest
+}
@@ -0,0 +1,52 @@
+package tooltest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// Case is a call of a table-driven handler test and what it should get.
+type Case struct {
+	Name string
+	Path string // of the operation, e.g. "/pods"
+	Body any    // the request, sent as JSON unless a string or []byte
+
+	Status int             // the HTTP status; default 200, or the status of Code
+	Code   toolserver.Code // the error code, for calls that should fail
+	Golden string          // the golden file under testdata the response must match
+	Check  func(t *testing.T, resp *Response)
+}
+
+// Run makes each call in a subtest and checks its response: the status,
+// the error code, the golden file and then Check, each if set.
+func Run(t *testing.T, s *Server, cases []Case) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			resp := s.Post(tc.Path, tc.Body)
+			status := tc.Status
+			if status == 0 {
+				status = http.StatusOK
+				if tc.Code != "" {
+					status = toolserver.NewError(tc.Code, "").Status
+				}
+			}
+			if resp.StatusCode != status {
+				t.Fatalf("status %d, want %d: %s", resp.StatusCode, status, resp.Body)
+			}
+			if tc.Code != "" {
+				if e := resp.Error(); e == nil || e.Code != tc.Code {
+					t.Fatalf("error %s, want code %s", resp.Body, tc.Code)
+				}
+			}
+			if tc.Golden != "" {
+				Golden(t, tc.Golden, resp.Body)
+			}
+			if tc.Check != nil {
+				tc.Check(t, resp)
+			}
+		})
+	}
+}
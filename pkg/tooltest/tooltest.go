@@ -0,0 +1,150 @@
+// Package tooltest helps test tools built on package toolserver. A Server
+// serves a tool with httptest and makes REST calls to it; Run checks a
+// table of calls against expected statuses, error codes and golden files;
+// FakeKube points a kube.Client at fake clientsets holding the test's
+// objects; and Registry starts an in-memory OCI registry to push images to.
+//
+// A tool's handler tests then read like:
+//
+//	func TestHandlers(t *testing.T) {
+//		tooltest.FakeKube(t, kubeClient, &corev1.Pod{...})
+//		s, _ := New()
+//		tooltest.Run(t, tooltest.NewServer(t, s), []tooltest.Case{
+//			{Name: "pods", Path: "/pods", Body: `{"namespace":"web"}`, Golden: "pods"},
+//			{Name: "missing pod", Path: "/logs", Body: `{"pod":"nope"}`, Code: toolserver.CodeNotFound},
+//		})
+//	}
+//
+// Run the tests with -update to write the golden files from the responses.
+package tooltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+)
+
+// Server is a tool served for a test.
+type Server struct {
+	URL string
+
+	// Header is sent with every call, e.g. Authorization, or Mcp-Session-Id
+	// to make calls in one session.
+	Header http.Header
+
+	t testing.TB
+}
+
+// NewServer serves h, usually a *toolserver.Server, until the test ends.
+func NewServer(t testing.TB, h http.Handler) *Server {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return &Server{URL: srv.URL, Header: make(http.Header), t: t}
+}
+
+// Post calls the operation registered at path, such as "/pods", which is
+// served under /v1. A body that is not a string or []byte is sent as JSON.
+func (s *Server) Post(path string, body any) *Response {
+	s.t.Helper()
+	var data []byte
+	switch b := body.(type) {
+	case nil:
+	case string:
+		data = []byte(b)
+	case []byte:
+		data = b
+	default:
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			s.t.Fatalf("encoding request to %s: %v", path, err)
+		}
+	}
+	req, err := http.NewRequest(http.MethodPost, s.URL+versioned(path), bytes.NewReader(data))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return s.Do(req)
+}
+
+// Get fetches path, which is served under /v1 unless it is a probe,
+// /metrics or /openapi.json.
+func (s *Server) Get(path string) *Response {
+	s.t.Helper()
+	req, err := http.NewRequest(http.MethodGet, s.URL+versioned(path), nil)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return s.Do(req)
+}
+
+// Do sends req with the server's Header and reads the response, failing
+// the test if it cannot.
+func (s *Server) Do(req *http.Request) *Response {
+	s.t.Helper()
+	for k, v := range s.Header {
+		if req.Header.Get(k) == "" {
+			req.Header[k] = v
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatalf("%s %s: reading response: %v", req.Method, req.URL.Path, err)
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body, t: s.t}
+}
+
+// unversioned are the endpoints served outside /v1.
+var unversioned = []string{"/livez", "/readyz", "/health", "/metrics", "/openapi.json"}
+
+func versioned(path string) string {
+	if strings.HasPrefix(path, "/v1/") {
+		return path
+	}
+	for _, p := range unversioned {
+		if strings.HasPrefix(path, p) {
+			return path
+		}
+	}
+	return "/v1" + path
+}
+
+// Response is a tool's answer to a call.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	t testing.TB
+}
+
+// Decode decodes the body into v, failing the test if it is not JSON.
+func (r *Response) Decode(v any) {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("decoding response %s: %v", r.Body, err)
+	}
+}
+
+// Error returns the error envelope of a failed call, or nil if the call
+// succeeded.
+func (r *Response) Error() *toolserver.ErrorResponse {
+	r.t.Helper()
+	if r.StatusCode < 400 {
+		return nil
+	}
+	var e toolserver.ErrorResponse
+	r.Decode(&e)
+	return &e
+}
@@ -0,0 +1,117 @@
+package tooltest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atippey/kube-mcp/pkg/kube"
+	"github.com/atippey/kube-mcp/pkg/toolserver"
+	"github.com/google/go-containerregistry/pkg/crane"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var client = kube.New("tooltest")
+
+type podsRequest struct {
+	Namespace string `json:"namespace"`
+}
+
+type podsResponse struct {
+	Pods []string `json:"pods"`
+}
+
+// newPodServer serves "/pods", which lists pod names with client.
+func newPodServer() *toolserver.Server {
+	s := toolserver.New("pods")
+	toolserver.Register(s, "/pods", func(ctx context.Context, req podsRequest) (podsResponse, error) {
+		if req.Namespace == "" {
+			return podsResponse{}, toolserver.BadRequest("namespace is required")
+		}
+		clientset, err := client.ForNamespace(req.Namespace)
+		if err != nil {
+			return podsResponse{}, err
+		}
+		pods, err := clientset.CoreV1().Pods(req.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return podsResponse{}, err
+		}
+		resp := podsResponse{Pods: []string{}}
+		for _, p := range pods.Items {
+			resp.Pods = append(resp.Pods, p.Name)
+		}
+		return resp, nil
+	})
+	return s
+}
+
+func pod(namespace, name string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+}
+
+func TestRun(t *testing.T) {
+	k := FakeKube(t, client, pod("web", "frontend"), pod("web", "backend"), pod("db", "postgres"))
+	Run(t, NewServer(t, newPodServer()), []Case{
+		{Name: "golden", Path: "/pods", Body: `{"namespace":"web"}`, Golden: "pods"},
+		{Name: "struct body", Path: "/pods", Body: podsRequest{Namespace: "db"}, Check: func(t *testing.T, resp *Response) {
+			var out podsResponse
+			resp.Decode(&out)
+			if len(out.Pods) != 1 || out.Pods[0] != "postgres" {
+				t.Errorf("pods = %v", out.Pods)
+			}
+		}},
+		{Name: "empty namespace", Path: "/pods", Body: `{"namespace":"empty"}`, Check: func(t *testing.T, resp *Response) {
+			if string(normalize(resp.Body)) != "{\n  \"pods\": []\n}\n" {
+				t.Errorf("body = %s", resp.Body)
+			}
+		}},
+		{Name: "bad request", Path: "/pods", Body: `{}`, Code: toolserver.CodeInvalidArgument},
+		{Name: "not found", Path: "/nope", Body: `{}`, Status: 404},
+	})
+
+	// The dynamic fake holds the same objects.
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	if _, err := k.Dynamic.Resource(gvr).Namespace("db").Get(context.Background(), "postgres", metav1.GetOptions{}); err != nil {
+		t.Errorf("dynamic client: %v", err)
+	}
+}
+
+func TestFakeKubeCleanup(t *testing.T) {
+	t.Run("fake", func(t *testing.T) {
+		FakeKube(t, client)
+		if d := client.Diagnostics(); d["source"] != "fake" {
+			t.Errorf("diagnostics = %v", d)
+		}
+	})
+	if d := client.Diagnostics(); d["state"] != "not loaded" {
+		t.Errorf("after the test: diagnostics = %v", d)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	host := Registry(t)
+	digest := PushImage(t, host+"/team/app:v1", nil)
+
+	got, err := crane.Digest(host + "/team/app:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != digest.String() {
+		t.Errorf("digest = %s, want %s", got, digest)
+	}
+}
+
+func TestVersioned(t *testing.T) {
+	for path, want := range map[string]string{
+		"/pods":            "/v1/pods",
+		"/v1/pods":         "/v1/pods",
+		"/readyz":          "/readyz",
+		"/healthz/verbose": "/healthz/verbose",
+		"/openapi.json":    "/openapi.json",
+	} {
+		if got := versioned(path); got != want {
+			t.Errorf("versioned(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
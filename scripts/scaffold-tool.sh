@@ -75,11 +75,19 @@ IFS='-' read -ra PARTS <<< "$ENDPOINT_PATH"
 for part in "${PARTS[@]}"; do
     ENDPOINT_NAME+="$(echo "${part:0:1}" | tr '[:lower:]' '[:upper:]')${part:1}"
 done
+# The description as a Go string literal body
+GO_DESC="${DESC//\\/\\\\}"
+GO_DESC="${GO_DESC//\"/\\\"}"
+# myEndpoint, for the operation function
+HANDLER_NAME="$(echo "${ENDPOINT_NAME:0:1}" | tr '[:upper:]' '[:lower:]')${ENDPOINT_NAME:1}"
+
+# Go package name: the tool name without dashes (time-tool -> timetool)
+PKG_NAME="${NAME//-/}"
 
 echo "Scaffolding MCP tool example:"
 echo "  Name:     ${NAME}"
 echo "  Endpoint: ${ENDPOINT}"
-echo "  Handler:  handle${ENDPOINT_NAME}"
+echo "  Handler:  ${HANDLER_NAME}"
 echo "  RBAC:     ${RBAC}"
 echo "  Output:   ${TOOL_DIR}"
 echo ""
@@ -87,16 +95,17 @@ echo ""
 # Create directory structure
 mkdir -p "${TOOL_DIR}/manifests/base"
 mkdir -p "${TOOL_DIR}/manifests/overlays/k3d"
+mkdir -p "${TOOL_DIR}/cmd/${NAME}"
 
 # --- main.go ---
 cat > "${TOOL_DIR}/main.go" << GOEOF
-package main
+// Package ${PKG_NAME} implements ${NAME}.
+package ${PKG_NAME}
 
 import (
-	"encoding/json"
-	"log"
-	"net/http"
-	"os"
+	"context"
+
+	"github.com/atippey/kube-mcp/pkg/toolserver"
 )
 
 type ${ENDPOINT_NAME}Request struct {
@@ -107,49 +116,48 @@ type ${ENDPOINT_NAME}Request struct {
 type ${ENDPOINT_NAME}Response struct {
 	// TODO: Add response fields
 	Result string \`json:"result"\`
-	Error  string \`json:"error,omitempty"\`
 }
 
-func main() {
-	http.HandleFunc("/health", handleHealth)
-	http.HandleFunc("${ENDPOINT}", handle${ENDPOINT_NAME})
+// New returns the ${NAME} server.
+func New() (*toolserver.Server, error) {
+	s := toolserver.New("${NAME}")
+	toolserver.Register(s, "${ENDPOINT}", ${HANDLER_NAME},
+		toolserver.Name("${NAME}"), toolserver.Describe("${GO_DESC}"))
+	return s, nil
+}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+func ${HANDLER_NAME}(ctx context.Context, req ${ENDPOINT_NAME}Request) (${ENDPOINT_NAME}Response, error) {
+	if req.Input == "" {
+		return ${ENDPOINT_NAME}Response{}, toolserver.BadRequest("input is required")
 	}
 
-	log.Printf("Starting ${NAME} server on :%s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
-	}
+	// TODO: Implement your tool logic here
+	return ${ENDPOINT_NAME}Response{Result: "not implemented"}, nil
 }
+GOEOF
 
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
-}
+# --- cmd/${NAME}/main.go ---
+cat > "${TOOL_DIR}/cmd/${NAME}/main.go" << GOEOF
+// Command ${NAME} serves ${NAME}.
+package main
 
-func handle${ENDPOINT_NAME}(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+import (
+	"log/slog"
+	"os"
 
-	if r.Method != http.MethodPost {
-		http.Error(w, \`{"error": "method not allowed"}\`, http.StatusMethodNotAllowed)
-		return
-	}
+	${PKG_NAME} "${NAME}"
+)
 
-	var req ${ENDPOINT_NAME}Request
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		json.NewEncoder(w).Encode(${ENDPOINT_NAME}Response{Error: "invalid request body"})
-		return
+func main() {
+	s, err := ${PKG_NAME}.New()
+	if err != nil {
+		slog.Error("starting ${NAME}", "err", err)
+		os.Exit(1)
 	}
-
-	// TODO: Implement your tool logic here
-	resp := ${ENDPOINT_NAME}Response{
-		Result: "not implemented",
+	if err := s.Run(); err != nil {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
 	}
-
-	json.NewEncoder(w).Encode(resp)
 }
 GOEOF
 
@@ -158,26 +166,33 @@ cat > "${TOOL_DIR}/go.mod" << MODEOF
 module ${NAME}
 
 go 1.25
+
+require github.com/atippey/kube-mcp/pkg v0.0.0
+
+replace github.com/atippey/kube-mcp/pkg => ../../pkg
 MODEOF
 
 # --- Dockerfile ---
-cat > "${TOOL_DIR}/Dockerfile" << 'DOCKEOF'
+cat > "${TOOL_DIR}/Dockerfile" << DOCKEOF
+# Build from the repository root so the shared pkg module is in context:
+#   docker build -f examples/${NAME}/Dockerfile .
 FROM golang:1.25-alpine AS builder
 
-WORKDIR /app
+# Shared packages, referenced by the replace directive in go.mod
+COPY pkg/ /src/pkg/
+
+WORKDIR /src/examples/${NAME}
 
 # Copy go mod files
-COPY go.mod go.sum* ./
+COPY examples/${NAME}/go.mod examples/${NAME}/go.sum* ./
 RUN go mod download
 
 # Copy source
-COPY *.go ./
+COPY examples/${NAME}/*.go ./
+COPY examples/${NAME}/cmd/ ./cmd/
 
 # Build static binary
-DOCKEOF
-
-cat >> "${TOOL_DIR}/Dockerfile" << DOCKEOF
-RUN CGO_ENABLED=0 GOOS=linux go build -ldflags="-w -s" -o /${NAME} .
+RUN CGO_ENABLED=0 GOOS=linux go build -ldflags="-w -s" -o /${NAME} ./cmd/${NAME}
 
 # Final minimal image
 FROM alpine:3.19
@@ -261,16 +276,17 @@ spec:
             - containerPort: 8080
           livenessProbe:
             httpGet:
-              path: /health
+              path: /livez
               port: 8080
             initialDelaySeconds: 5
             periodSeconds: 10
           readinessProbe:
             httpGet:
-              path: /health
+              path: /readyz
               port: 8080
             initialDelaySeconds: 5
             periodSeconds: 5
+            timeoutSeconds: 5
           resources:
             requests:
               memory: "64Mi"
@@ -321,16 +337,17 @@ spec:
             - containerPort: 8080
           livenessProbe:
             httpGet:
-              path: /health
+              path: /livez
               port: 8080
             initialDelaySeconds: 5
             periodSeconds: 10
           readinessProbe:
             httpGet:
-              path: /health
+              path: /readyz
               port: 8080
             initialDelaySeconds: 5
             periodSeconds: 5
+            timeoutSeconds: 5
           resources:
             requests:
               memory: "64Mi"
@@ -386,7 +403,7 @@ spec:
   service:
     name: ${NAME}-svc
     port: 8080
-    path: ${ENDPOINT}
+    path: /v1${ENDPOINT}
   inputSchema:
     type: object
     properties: {}
@@ -433,8 +450,8 @@ find "${TOOL_DIR}" -type f | sort | while read -r f; do
 done
 echo ""
 echo "Next steps:"
-echo "  1. cd examples/${NAME} && go build -o ${NAME} ."
+echo "  1. cd examples/${NAME} && go build -o ${NAME} ./cmd/${NAME}"
 echo "  2. Implement your tool logic in main.go"
-echo "  3. docker build -t localhost:5000/${NAME}:latest examples/${NAME}/"
+echo "  3. docker build -t localhost:5000/${NAME}:latest -f examples/${NAME}/Dockerfile ."
 echo "  4. docker push localhost:5000/${NAME}:latest"
 echo "  5. kubectl apply -k examples/${NAME}/manifests/overlays/k3d/"